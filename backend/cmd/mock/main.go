@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -13,6 +14,8 @@ import (
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/db"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
 	pb "github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
@@ -78,6 +81,7 @@ func main() {
 
 	var allStudents []seededUser
 	var output []string
+	advisorStudents := map[string][]seededUser{}
 
 	for _, adv := range advisors {
 		output = append(output, fmt.Sprintf("advisor,%s %s,%s,%s,%s", adv.first, adv.last, adv.email, adv.username, adv.password))
@@ -93,12 +97,24 @@ func main() {
 			allStudents = append(allStudents, stu)
 			advStudents = append(advStudents, stu)
 		}
-		// Create advisory chat room for this advisor and their students
-		createAdvisoryChatRoom(conn, adv, advStudents)
+		advisorStudents[adv.id] = advStudents
 	}
 
-	fmt.Println("Seeding cohort chat rooms...")
-	seedCohortChatRooms(conn, allStudents, advisors)
+	// Cohort and advisory rooms are provisioned like in production
+	fmt.Println("Syncing cohort and advisory chat rooms...")
+	if len(advisors) > 0 {
+		chat := services.NewChatService(repository.NewSQLChatRepository(conn), nil, cfg)
+		report, err := chat.SyncRooms(context.Background(), demoTenantID, advisors[0].id, false)
+		if err != nil {
+			fmt.Printf("ERROR syncing chat rooms: %v\n", err)
+		} else {
+			fmt.Printf("Created %d chat rooms with %d members\n", report.Created, report.Added)
+		}
+	}
+	for _, adv := range advisors {
+		seedAdvisoryMessages(conn, adv, advisorStudents[adv.id])
+	}
+	seedCohortMessages(conn)
 
 	fmt.Println("Seeding calendar events...")
 	seedCalendarEvents(conn, allStudents, advisors)
@@ -194,7 +210,7 @@ func createStudent(conn *sqlx.DB, advisorID string) seededUser {
 		VALUES ($1, $2, 'student', true) ON CONFLICT (user_id, tenant_id) DO NOTHING`, id, demoTenantID)
 
 	// Link student to advisor
-	conn.Exec(`INSERT INTO student_advisors (student_id,advisor_id) VALUES ($1,$2)`, id, advisorID)
+	conn.Exec(`INSERT INTO student_advisors (student_id,advisor_id,tenant_id) VALUES ($1,$2,$3)`, id, advisorID, demoTenantID)
 
 	// Insert profile submission data
 	profileData := fmt.Sprintf(`{"phone":"%s","program":"%s","specialty":"%s","cohort":"%s","department":"%s"}`,
@@ -264,30 +280,20 @@ func populateProgress(conn *sqlx.DB, userID, versionID string, worldOrder []stri
 	}
 }
 
-func createAdvisoryChatRoom(conn *sqlx.DB, advisor seededUser, students []seededUser) {
+func seedAdvisoryMessages(conn *sqlx.DB, advisor seededUser, students []seededUser) {
 	if len(students) == 0 {
 		return
 	}
 
-	roomName := fmt.Sprintf("Advisory: %s %s", advisor.first, advisor.last)
+	// The room itself comes from the room sync
 	var roomID string
-	err := conn.Get(&roomID, `INSERT INTO chat_rooms (name, type, created_by, created_by_role, tenant_id, meta)
-		VALUES ($1, 'advisory', $2, 'advisor', $3, '{}') RETURNING id`, roomName, advisor.id, demoTenantID)
+	err := conn.Get(&roomID, `SELECT id FROM chat_rooms WHERE tenant_id = $1 AND managed_kind = 'advisory' AND managed_ref = $2`,
+		demoTenantID, advisor.id)
 	if err != nil {
-		fmt.Printf("ERROR creating advisory chat room: %v\n", err)
+		fmt.Printf("ERROR finding advisory chat room: %v\n", err)
 		return
 	}
 
-	// Add advisor as admin
-	conn.Exec(`INSERT INTO chat_room_members (room_id, user_id, role_in_room, tenant_id)
-		VALUES ($1, $2, 'admin', $3)`, roomID, advisor.id, demoTenantID)
-
-	// Add students as members
-	for _, stu := range students {
-		conn.Exec(`INSERT INTO chat_room_members (room_id, user_id, role_in_room, tenant_id)
-			VALUES ($1, $2, 'member', $3)`, roomID, stu.id, demoTenantID)
-	}
-
 	// Add some messages
 	messages := []struct {
 		senderIdx int // -1 for advisor, 0+ for student index
@@ -328,38 +334,24 @@ func createAdvisoryChatRoom(conn *sqlx.DB, advisor seededUser, students []seeded
 	}
 }
 
-func seedCohortChatRooms(conn *sqlx.DB, students []seededUser, advisors []seededUser) {
-	cohorts := []string{"Cohort 2022", "Cohort 2023", "Cohort 2024"}
-
-	for _, cohortName := range cohorts {
-		if len(advisors) == 0 {
-			continue
-		}
-		creator := advisors[rand.Intn(len(advisors))]
+func seedCohortMessages(conn *sqlx.DB) {
+	var rooms []struct {
+		ID string `db:"id"`
+	}
+	err := conn.Select(&rooms, `SELECT id FROM chat_rooms WHERE tenant_id = $1 AND managed_kind = 'cohort' AND NOT is_archived`, demoTenantID)
+	if err != nil {
+		fmt.Printf("ERROR listing cohort chat rooms: %v\n", err)
+		return
+	}
 
-		var roomID string
-		err := conn.Get(&roomID, `INSERT INTO chat_rooms (name, type, created_by, created_by_role, tenant_id, meta)
-			VALUES ($1, 'cohort', $2, 'advisor', $3, '{}') RETURNING id`, cohortName+" Discussion", creator.id, demoTenantID)
-		if err != nil {
-			fmt.Printf("ERROR creating cohort chat room: %v\n", err)
+	for _, room := range rooms {
+		roomID := room.ID
+		var members []string
+		conn.Select(&members, `SELECT user_id FROM chat_room_members WHERE room_id = $1`, roomID)
+		if len(members) == 0 {
 			continue
 		}
 
-		// Add creator as admin
-		conn.Exec(`INSERT INTO chat_room_members (room_id, user_id, role_in_room, tenant_id)
-			VALUES ($1, $2, 'admin', $3)`, roomID, creator.id, demoTenantID)
-
-		// Add some random students
-		numMembers := rand.Intn(min(10, len(students))) + 3
-		shuffled := make([]seededUser, len(students))
-		copy(shuffled, students)
-		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
-
-		for i := 0; i < numMembers && i < len(shuffled); i++ {
-			conn.Exec(`INSERT INTO chat_room_members (room_id, user_id, role_in_room, tenant_id)
-				VALUES ($1, $2, 'member', $3) ON CONFLICT DO NOTHING`, roomID, shuffled[i].id, demoTenantID)
-		}
-
 		// Add cohort messages
 		cohortMessages := []string{
 			"Привет всем! Как продвигается работа?",
@@ -375,14 +367,7 @@ func seedCohortChatRooms(conn *sqlx.DB, students []seededUser, advisors []seeded
 
 		now := time.Now()
 		for i, msg := range cohortMessages {
-			senderIdx := rand.Intn(numMembers)
-			if senderIdx >= len(shuffled) {
-				senderIdx = 0
-			}
-			senderID := shuffled[senderIdx].id
-			if rand.Float32() < 0.2 {
-				senderID = creator.id
-			}
+			senderID := members[rand.Intn(len(members))]
 			createdAt := now.Add(-time.Duration(30-i*3) * 24 * time.Hour)
 			conn.Exec(`INSERT INTO chat_messages (room_id, sender_id, body, created_at, tenant_id)
 				VALUES ($1, $2, $3, $4, $5)`, roomID, senderID, msg, createdAt, demoTenantID)
@@ -0,0 +1,67 @@
+// Command mock_idp runs an OpenID provider and a SAML identity provider for
+// trying single sign-on locally. Both sign in the configured user without
+// asking for credentials.
+//
+//	go run ./cmd/mock_idp -addr :9400 -email student@example.edu
+//
+// For OIDC, configure the tenant with issuer http://localhost:9400, client
+// id "portal" and client secret "portal-secret" (or the -client-id and
+// -client-secret given). For SAML, paste the metadata served at
+// http://localhost:9400/saml/metadata; the user's eduPersonAffiliation is
+// -affiliation.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc/oidctest"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml/samltest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9400", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9400", "issuer URL the provider is reached at")
+	clientID := flag.String("client-id", "portal", "client id")
+	clientSecret := flag.String("client-secret", "portal-secret", "client secret")
+	email := flag.String("email", "student@example.edu", "email of the signed in user")
+	subject := flag.String("sub", "mock-user-1", "subject of the signed in user")
+	givenName := flag.String("given-name", "Mock", "given name of the signed in user")
+	familyName := flag.String("family-name", "Student", "family name of the signed in user")
+	affiliation := flag.String("affiliation", "student", "comma separated SAML affiliations of the signed in user")
+	flag.Parse()
+
+	p, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatalf("mock idp: %v", err)
+	}
+	p.User = oidctest.User{
+		Subject:       *subject,
+		Email:         *email,
+		EmailVerified: true,
+		GivenName:     *givenName,
+		FamilyName:    *familyName,
+	}
+
+	sp, err := samltest.New(strings.TrimSuffix(*issuer, "/") + "/saml")
+	if err != nil {
+		log.Fatalf("mock idp: %v", err)
+	}
+	sp.User = samltest.User{
+		NameID: *email,
+		Attributes: map[string][]string{
+			samltest.AttrMail:        {*email},
+			samltest.AttrGivenName:   {*givenName},
+			samltest.AttrSurname:     {*familyName},
+			samltest.AttrAffiliation: strings.Split(*affiliation, ","),
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/saml/", http.StripPrefix("/saml", sp))
+	mux.Handle("/", p)
+	log.Printf("Mock OpenID provider %s and SAML IdP %s signing in %s on %s", *issuer, sp.EntityID(), *email, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
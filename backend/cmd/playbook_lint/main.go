@@ -0,0 +1,66 @@
+// Command playbook_lint statically checks a playbook JSON file.
+//
+//	go run ./cmd/playbook_lint [-format json] [-strict] [-path] playbooks/playbook.json
+//
+// The playbook may be given with -path or as the only positional argument.
+// It exits with status 1 when the playbook has errors (or warnings with
+// -strict) and 2 when the file cannot be read.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+)
+
+func main() {
+	path := flag.String("path", "playbooks/playbook.json", "path to playbook.json")
+	format := flag.String("format", "text", "output format: text or json")
+	strict := flag.Bool("strict", false, "treat warnings as errors")
+	flag.Parse()
+
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		*path = flag.Arg(0)
+	default:
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %v (flags go before the playbook path)\n", flag.Args()[1:])
+		os.Exit(2)
+	}
+
+	raw, err := os.ReadFile(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read playbook: %v\n", err)
+		os.Exit(2)
+	}
+	report := playbook.Lint(raw)
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "encode report: %v\n", err)
+			os.Exit(2)
+		}
+	case "text":
+		for _, is := range report.Issues {
+			loc := is.Path
+			if loc == "" {
+				loc = "-"
+			}
+			fmt.Printf("%-7s %-22s %s: %s\n", is.Severity, is.Code, loc, is.Message)
+		}
+		fmt.Printf("%s: %d nodes, %d error(s), %d warning(s)\n", *path, report.Nodes, report.Errors, report.Warnings)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		os.Exit(2)
+	}
+
+	if report.HasErrors() || (*strict && report.Warnings > 0) {
+		os.Exit(1)
+	}
+}
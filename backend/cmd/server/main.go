@@ -2,20 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/logging"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/db"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/seed"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/mailer"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/worker"
+	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 
 	"github.com/gin-gonic/gin"
@@ -27,11 +34,13 @@ import (
 // Flags:
 //
 //	--seed : runs checklist seeding and exits
+//	--migrate-chat-files : copies chat files from UPLOAD_DIR to S3 and exits
 func main() {
 	_ = godotenv.Load()
 
 	seedFlag := flag.Bool("seed", false, "seed checklist and exit")
 	bootstrapAdmin := flag.Bool("bootstrap-admin", false, "create/update superadmin from ADMIN_EMAIL/ADMIN_PASSWORD and exit")
+	migrateChatFiles := flag.Bool("migrate-chat-files", false, "copy chat attachments from the upload directory to S3 and exit")
 	flag.Parse()
 
 	cfg := config.MustLoad()
@@ -61,6 +70,13 @@ func main() {
 		return
 	}
 
+	if *migrateChatFiles {
+		if err := runChatFileMigration(conn, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Ensure superadmin exists
 	if gen, err := seed.EnsureSuperAdmin(conn, cfg); err != nil {
 		log.Printf("superadmin ensure failed: %v", err)
@@ -99,6 +115,56 @@ func main() {
 		log.Println("S3 not configured, cleanup worker disabled")
 	}
 
+	// Reminder and escalation jobs; replicas share them through DB leases
+	schedulerRepo := repository.NewSQLSchedulerRepository(conn)
+	// Notifications created by jobs reach the live stream through the service
+	notificationStream := services.NewNotificationStream(repository.NewSQLNotificationEventRepository(conn), services.NewRedis(cfg.RedisURL))
+	notificationSvc := services.NewNotificationService(repository.NewSQLNotificationRepository(conn))
+	notificationSvc.SetStream(notificationStream)
+	adminSvc := services.NewAdminService(repository.NewSQLAdminRepository(conn), pbManager, cfg, nil)
+	adminSvc.SetNotifications(notificationSvc)
+	mailerSvc := mailer.NewMailer()
+	playbooks := playbook.NewRegistry(repository.NewSQLPlaybookRepository(conn), pbManager)
+	reminderWorker := worker.NewReminderWorker(
+		schedulerRepo,
+		notificationSvc,
+		mailerSvc,
+		playbooks,
+		cfg.FrontendBase,
+	)
+	// Rule deadlines of students who never opened their deadlines page
+	deadlineSvc := services.NewDeadlineService(repository.NewSQLDeadlineRepository(conn), pbManager)
+	deadlineSvc.SetPlaybooks(playbooks)
+	reminderWorker.SetDeadlines(deadlineSvc)
+
+	// Delivers messages enqueued in the outbox, e.g. on node state changes
+	outbox := worker.NewOutboxDispatcher(repository.NewSQLOutboxRepository(conn))
+	outbox.Handle(models.OutboxKindEmail, worker.EmailHandler(mailerSvc))
+	outbox.Handle(models.OutboxKindNotification, worker.NotificationHandler(notificationSvc))
+	outbox.Handle(models.OutboxKindAdvisorSubmission, worker.AdvisorSubmissionHandler(conn, adminSvc))
+
+	scheduler := worker.NewScheduler(schedulerRepo, "")
+	scheduler.Register(reminderWorker.Jobs()...)
+	scheduler.Register(outbox.Job())
+	scheduler.Register(worker.NotificationEventsPruneJob(notificationStream))
+	// Room retention policies; the job only reads rooms and deletes messages
+	scheduler.Register(worker.ChatRetentionJob(services.NewChatService(repository.NewSQLChatRepository(conn), nil, cfg)))
+	// Cohort and advisory rooms, for changes that do not sync on write
+	scheduler.Register(worker.ChatRoomSyncJob(services.NewChatService(repository.NewSQLChatRepository(conn), nil, cfg)))
+	// Text of uploaded DOCX and PDF files for search and reviewers
+	documentTexts := services.NewDocumentTextService(repository.NewSQLDocumentTextRepository(conn), cfg)
+	if s3Client != nil {
+		documentTexts.SetStorage(s3Client, services.HTTPObjectFetcher(&http.Client{Timeout: 5 * time.Minute}))
+	}
+	scheduler.Register(worker.DocumentTextJob(documentTexts))
+	// In-house similarity check of dissertation versions
+	scheduler.Register(worker.SimilarityJob(services.NewSimilarityService(repository.NewSQLSimilarityRepository(conn))))
+	schedulerDone := make(chan struct{})
+	go func() {
+		scheduler.Start(ctx)
+		close(schedulerDone)
+	}()
+
 	// Setup graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -112,6 +178,26 @@ func main() {
 
 	<-quit
 	log.Println("Shutting down server...")
-	cancel() // Stop cleanup worker
+	cancel() // Stop cleanup worker and scheduler
+	<-schedulerDone
 	log.Println("Server stopped")
 }
+
+// runChatFileMigration moves chat attachments saved on local disk before
+// chat used object storage. It is safe to run repeatedly.
+func runChatFileMigration(conn *sqlx.DB, cfg config.AppConfig) error {
+	s3Client, err := services.NewS3FromEnv()
+	if err != nil {
+		return err
+	}
+	if s3Client == nil {
+		return errors.New("S3 is not configured")
+	}
+	chatSvc := services.NewChatService(repository.NewSQLChatRepository(conn), nil, cfg)
+	chatSvc.SetStorage(s3Client, services.NewDocumentService(repository.NewSQLDocumentRepository(conn), cfg, s3Client))
+
+	report, err := chatSvc.MigrateLocalFiles(context.Background(), services.HTTPObjectPutter(&http.Client{Timeout: 5 * time.Minute}))
+	log.Printf("Chat file migration: %d messages, %d files migrated, %d missing, %d failed",
+		report.Messages, report.Migrated, report.Missing, report.Failed)
+	return err
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS playbook_activations;
+
+-- Keep only the default tenant's row before restoring the single-row table
+DELETE FROM playbook_active_version WHERE tenant_id <> '00000000-0000-0000-0000-000000000001';
+ALTER TABLE playbook_active_version DROP CONSTRAINT IF EXISTS playbook_active_version_pkey;
+ALTER TABLE playbook_active_version ADD COLUMN IF NOT EXISTS id boolean NOT NULL DEFAULT TRUE;
+ALTER TABLE playbook_active_version ADD PRIMARY KEY (id);
+
+ALTER TABLE playbook_versions DROP COLUMN IF EXISTS created_by;
+DROP INDEX IF EXISTS idx_playbook_versions_tenant_checksum;
+ALTER TABLE playbook_versions ADD CONSTRAINT playbook_versions_checksum_key UNIQUE (checksum);
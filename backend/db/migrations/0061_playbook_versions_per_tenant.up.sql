@@ -0,0 +1,22 @@
+-- Per-tenant playbook versions: each tenant keeps its own active version and
+-- activation history so admins can upload, activate and roll back playbooks.
+
+-- The same playbook file may be registered by several tenants
+ALTER TABLE playbook_versions DROP CONSTRAINT IF EXISTS playbook_versions_checksum_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_playbook_versions_tenant_checksum ON playbook_versions(tenant_id, checksum);
+ALTER TABLE playbook_versions ADD COLUMN IF NOT EXISTS created_by uuid REFERENCES users(id) ON DELETE SET NULL;
+
+-- One active version per tenant instead of a single global row
+ALTER TABLE playbook_active_version DROP CONSTRAINT IF EXISTS playbook_active_version_pkey;
+ALTER TABLE playbook_active_version DROP COLUMN IF EXISTS id;
+ALTER TABLE playbook_active_version ADD PRIMARY KEY (tenant_id);
+
+CREATE TABLE IF NOT EXISTS playbook_activations (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  playbook_version_id uuid NOT NULL REFERENCES playbook_versions(id) ON DELETE CASCADE,
+  activated_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  activated_at timestamptz NOT NULL DEFAULT now(),
+  rolled_back_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_playbook_activations_tenant ON playbook_activations(tenant_id, activated_at DESC);
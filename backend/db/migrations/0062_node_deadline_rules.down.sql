@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_node_deadlines_tenant_user;
+DROP INDEX IF EXISTS idx_node_deadlines_id;
+
+DELETE FROM node_deadlines WHERE created_by IS NULL;
+ALTER TABLE node_deadlines ALTER COLUMN created_by SET NOT NULL;
+ALTER TABLE node_deadlines DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE node_deadlines DROP COLUMN IF EXISTS source;
+ALTER TABLE node_deadlines DROP COLUMN IF EXISTS id;
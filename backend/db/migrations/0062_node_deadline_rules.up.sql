@@ -0,0 +1,10 @@
+-- Deadlines are now either derived from playbook rules or set by an admin.
+-- Rule-derived rows are recomputed; overrides are never replaced by rules.
+ALTER TABLE node_deadlines ADD COLUMN IF NOT EXISTS id uuid NOT NULL DEFAULT uuid_generate_v4();
+ALTER TABLE node_deadlines ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'override'
+  CHECK (source IN ('rule', 'override'));
+ALTER TABLE node_deadlines ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE node_deadlines ALTER COLUMN created_by DROP NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_node_deadlines_id ON node_deadlines(id);
+CREATE INDEX IF NOT EXISTS idx_node_deadlines_tenant_user ON node_deadlines(tenant_id, user_id);
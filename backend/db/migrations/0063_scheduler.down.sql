@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_node_instances_submitted;
+DROP INDEX IF EXISTS idx_reminders_pending;
+ALTER TABLE reminders DROP COLUMN IF EXISTS sent_at;
+DROP TABLE IF EXISTS escalation_log;
+DROP TABLE IF EXISTS job_leases;
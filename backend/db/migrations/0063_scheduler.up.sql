@@ -0,0 +1,23 @@
+-- Background jobs take a lease so that only one replica runs each job at a time.
+CREATE TABLE IF NOT EXISTS job_leases (
+  name text PRIMARY KEY,
+  holder text NOT NULL,
+  expires_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- One row per escalation tier already sent, so reminders are not repeated.
+-- anchor_at is the due date or the moment the review started waiting; when it
+-- moves, the tiers start over.
+CREATE TABLE IF NOT EXISTS escalation_log (
+  job text NOT NULL,
+  subject_id uuid NOT NULL,
+  anchor_at timestamptz NOT NULL,
+  tier text NOT NULL,
+  sent_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (job, subject_id, anchor_at, tier)
+);
+
+ALTER TABLE reminders ADD COLUMN IF NOT EXISTS sent_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_reminders_pending ON reminders(created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_node_instances_submitted ON node_instances(updated_at) WHERE state = 'submitted';
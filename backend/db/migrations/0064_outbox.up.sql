@@ -0,0 +1,22 @@
+-- Transactional outbox: messages are written in the same transaction as the
+-- change that causes them and delivered afterwards by the dispatcher.
+CREATE TABLE IF NOT EXISTS outbox_messages (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id uuid REFERENCES tenants(id) ON DELETE CASCADE,
+  kind text NOT NULL,
+  payload jsonb NOT NULL,
+  status text NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'processing', 'sent', 'dead')),
+  attempts int NOT NULL DEFAULT 0,
+  max_attempts int NOT NULL DEFAULT 8,
+  next_attempt_at timestamptz NOT NULL DEFAULT now(),
+  locked_until timestamptz,
+  last_error text,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  sent_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox_messages(next_attempt_at)
+  WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_outbox_tenant_status ON outbox_messages(tenant_id, status, created_at DESC);
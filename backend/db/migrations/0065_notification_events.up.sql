@@ -0,0 +1,14 @@
+-- Append-only log behind the live notification stream. The bigserial id is
+-- the SSE event id, so a reconnecting client resumes with Last-Event-ID.
+-- stream is 'user:<uuid>' for a user's notifications or 'admin' for the
+-- shared admin_notifications feed.
+CREATE TABLE IF NOT EXISTS notification_events (
+  id bigserial PRIMARY KEY,
+  stream text NOT NULL,
+  type text NOT NULL,
+  payload jsonb NOT NULL DEFAULT '{}'::jsonb,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_events_stream ON notification_events(stream, id);
+CREATE INDEX IF NOT EXISTS idx_notification_events_created ON notification_events(created_at);
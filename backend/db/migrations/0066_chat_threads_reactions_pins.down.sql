@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_chat_messages_pinned;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS pinned_by;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS pinned_at;
+DROP TABLE IF EXISTS chat_message_reactions;
+DROP TABLE IF EXISTS chat_thread_read_status;
+DROP INDEX IF EXISTS idx_chat_messages_parent;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,26 @@
+-- Threads: a reply points at a top-level message of the same room
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS parent_id uuid REFERENCES chat_messages(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_chat_messages_parent ON chat_messages(parent_id, created_at) WHERE parent_id IS NOT NULL;
+
+-- Per-user read position inside a thread; a row also means the user follows it
+CREATE TABLE IF NOT EXISTS chat_thread_read_status (
+  parent_id uuid NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  last_read_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (parent_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_chat_thread_read_status_user ON chat_thread_read_status(user_id);
+
+-- Emoji reactions, one row per user and emoji
+CREATE TABLE IF NOT EXISTS chat_message_reactions (
+  message_id uuid NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  emoji text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (message_id, user_id, emoji)
+);
+
+-- Pinned messages
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS pinned_at timestamptz;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS pinned_by uuid REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_chat_messages_pinned ON chat_messages(room_id, pinned_at DESC) WHERE pinned_at IS NOT NULL;
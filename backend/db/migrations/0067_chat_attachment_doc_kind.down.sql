@@ -0,0 +1,2 @@
+-- Enum values cannot be dropped in place; see 0013_add_node_slot_doc_kind.
+/* no-op */
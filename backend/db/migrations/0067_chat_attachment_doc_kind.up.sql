@@ -0,0 +1,7 @@
+DO $$
+BEGIN
+    -- Add chat_attachment kind for files shared in chat rooms
+    ALTER TYPE doc_kind ADD VALUE 'chat_attachment';
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END$$;
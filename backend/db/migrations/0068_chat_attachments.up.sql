@@ -0,0 +1,11 @@
+-- Chat files live in object storage as document versions; this binds each
+-- one to the room it was shared in so downloads can check membership.
+CREATE TABLE IF NOT EXISTS chat_attachments (
+  version_id uuid PRIMARY KEY REFERENCES document_versions(id) ON DELETE CASCADE,
+  document_id uuid NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+  room_id uuid NOT NULL REFERENCES chat_rooms(id) ON DELETE CASCADE,
+  uploaded_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  object_key text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_chat_attachments_room ON chat_attachments(room_id);
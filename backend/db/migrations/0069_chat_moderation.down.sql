@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS chat_messages_archive;
+DROP TABLE IF EXISTS chat_moderation_log;
+DROP TABLE IF EXISTS chat_room_bans;
+ALTER TABLE chat_room_members DROP COLUMN IF EXISTS muted_until;
+ALTER TABLE chat_rooms DROP COLUMN IF EXISTS retention_action;
+ALTER TABLE chat_rooms DROP COLUMN IF EXISTS retention_days;
+ALTER TABLE chat_rooms DROP COLUMN IF EXISTS post_policy;
@@ -0,0 +1,54 @@
+-- Who may post top-level messages and replies; 'moderators' makes a channel read-only for members
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS post_policy text NOT NULL DEFAULT 'everyone'
+  CHECK (post_policy IN ('everyone','moderators'));
+
+-- Messages older than retention_days are deleted or moved to chat_messages_archive
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS retention_days integer CHECK (retention_days > 0);
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS retention_action text NOT NULL DEFAULT 'delete'
+  CHECK (retention_action IN ('delete','archive'));
+
+-- A muted member can read but not post until muted_until
+ALTER TABLE chat_room_members ADD COLUMN IF NOT EXISTS muted_until timestamptz;
+
+-- Banned users are removed from the room and cannot be added back until unbanned
+CREATE TABLE IF NOT EXISTS chat_room_bans (
+  room_id uuid NOT NULL REFERENCES chat_rooms(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  banned_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  reason text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (room_id, user_id)
+);
+
+-- Every moderation action and retention run; actor_id is null for the retention job
+CREATE TABLE IF NOT EXISTS chat_moderation_log (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id uuid,
+  room_id uuid NOT NULL REFERENCES chat_rooms(id) ON DELETE CASCADE,
+  actor_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  action text NOT NULL,
+  target_user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  target_message_id uuid,
+  reason text NOT NULL DEFAULT '',
+  details jsonb NOT NULL DEFAULT '{}'::jsonb,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_chat_moderation_log_room ON chat_moderation_log(room_id, created_at DESC);
+
+-- Messages removed by an 'archive' retention policy
+CREATE TABLE IF NOT EXISTS chat_messages_archive (
+  id uuid PRIMARY KEY,
+  tenant_id uuid,
+  room_id uuid NOT NULL REFERENCES chat_rooms(id) ON DELETE CASCADE,
+  parent_id uuid,
+  sender_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  body text NOT NULL,
+  attachments jsonb,
+  importance text,
+  meta jsonb,
+  created_at timestamptz NOT NULL,
+  edited_at timestamptz,
+  deleted_at timestamptz,
+  archived_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_archive_room ON chat_messages_archive(room_id, created_at);
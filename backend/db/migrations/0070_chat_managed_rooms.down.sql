@@ -0,0 +1,5 @@
+ALTER TABLE chat_room_members DROP COLUMN IF EXISTS managed;
+DROP INDEX IF EXISTS idx_chat_rooms_managed;
+ALTER TABLE chat_rooms DROP CONSTRAINT IF EXISTS chat_rooms_managed_ref_check;
+ALTER TABLE chat_rooms DROP COLUMN IF EXISTS managed_ref;
+ALTER TABLE chat_rooms DROP COLUMN IF EXISTS managed_kind;
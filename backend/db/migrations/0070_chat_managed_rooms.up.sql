@@ -0,0 +1,12 @@
+-- Rooms provisioned by the room sync: one 'cohort' room per cohorts row and one
+-- 'advisory' room per advisor. managed_ref is the cohort id or the advisor's user id.
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS managed_kind text
+  CHECK (managed_kind IN ('cohort','advisory'));
+ALTER TABLE chat_rooms ADD COLUMN IF NOT EXISTS managed_ref uuid;
+ALTER TABLE chat_rooms ADD CONSTRAINT chat_rooms_managed_ref_check
+  CHECK ((managed_kind IS NULL) = (managed_ref IS NULL));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_rooms_managed
+  ON chat_rooms(tenant_id, managed_kind, managed_ref) WHERE managed_kind IS NOT NULL;
+
+-- Memberships added by the sync; it never removes members added by hand
+ALTER TABLE chat_room_members ADD COLUMN IF NOT EXISTS managed boolean NOT NULL DEFAULT false;
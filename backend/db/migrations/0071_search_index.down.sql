@@ -0,0 +1,8 @@
+ALTER TABLE contacts DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE events DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE node_instance_slot_attachments DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE users DROP COLUMN IF EXISTS search_tsv;
+DROP FUNCTION IF EXISTS portal_search_query(text);
+DROP FUNCTION IF EXISTS portal_search_vector(text);
+DROP TEXT SEARCH CONFIGURATION IF EXISTS public.kazakh;
@@ -0,0 +1,54 @@
+-- Full-text search. Kazakh has no snowball stemmer, so it is indexed unstemmed.
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'kazakh') THEN
+    CREATE TEXT SEARCH CONFIGURATION public.kazakh (COPY = pg_catalog.simple);
+  END IF;
+END $$;
+
+-- Texts are indexed in all three languages so a query matches whichever one it is written in
+CREATE OR REPLACE FUNCTION portal_search_vector(doc text) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT to_tsvector('pg_catalog.english'::regconfig, coalesce(doc, ''))
+      || to_tsvector('pg_catalog.russian'::regconfig, coalesce(doc, ''))
+      || to_tsvector('public.kazakh'::regconfig, coalesce(doc, ''))
+$$;
+
+-- terms is a to_tsquery expression such as 'thes:* & draft:*'
+CREATE OR REPLACE FUNCTION portal_search_query(terms text) RETURNS tsquery
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT to_tsquery('pg_catalog.english'::regconfig, terms)
+      || to_tsquery('pg_catalog.russian'::regconfig, terms)
+      || to_tsquery('public.kazakh'::regconfig, terms)
+$$;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+  setweight(portal_search_vector(coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'A')
+  || setweight(portal_search_vector(regexp_replace(coalesce(email, '') || ' ' || coalesce(username, ''), '[@._+-]+', ' ', 'g')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_users_search ON users USING gin(search_tsv);
+
+ALTER TABLE node_instance_slot_attachments ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+  portal_search_vector(regexp_replace(filename, '[_.-]+', ' ', 'g'))
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_node_instance_slot_attachments_search ON node_instance_slot_attachments USING gin(search_tsv);
+
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+  portal_search_vector(body)
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_chat_messages_search ON chat_messages USING gin(search_tsv);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+  setweight(portal_search_vector(title), 'A')
+  || setweight(portal_search_vector(description), 'B')
+  || setweight(portal_search_vector(coalesce(location, '') || ' ' || coalesce(physical_address, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_events_search ON events USING gin(search_tsv);
+
+-- Contact names and titles are {"ru": ..., "kz": ..., "en": ...}
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS search_tsv tsvector GENERATED ALWAYS AS (
+  setweight(portal_search_vector(jsonb_path_query_array(name, '$.*')::text), 'A')
+  || setweight(portal_search_vector(coalesce(jsonb_path_query_array(title, '$.*')::text, '')), 'B')
+  || setweight(portal_search_vector(regexp_replace(coalesce(email, ''), '[@._+-]+', ' ', 'g')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_contacts_search ON contacts USING gin(search_tsv);
@@ -0,0 +1,20 @@
+-- Plain text extracted from uploaded DOCX and PDF files, one row per
+-- document version once the extraction job has looked at it.
+CREATE TABLE IF NOT EXISTS document_version_texts (
+  version_id uuid PRIMARY KEY REFERENCES document_versions(id) ON DELETE CASCADE,
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  status text NOT NULL CHECK (status IN ('done', 'failed', 'unsupported')),
+  format text,
+  content text NOT NULL DEFAULT '',
+  page_count int,
+  word_count int,
+  error text,
+  attempts int NOT NULL DEFAULT 1,
+  extracted_at timestamptz,
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  -- A tsvector is capped at 1MB, so only the first 200k characters are indexed
+  search_tsv tsvector GENERATED ALWAYS AS (portal_search_vector(left(content, 200000))) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_version_texts_search ON document_version_texts USING gin(search_tsv);
+CREATE INDEX IF NOT EXISTS idx_document_version_texts_failed ON document_version_texts(updated_at) WHERE status = 'failed';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS similarity_reports;
+
+ALTER TABLE document_version_texts
+  DROP COLUMN IF EXISTS minhash,
+  DROP COLUMN IF EXISTS shingle_count;
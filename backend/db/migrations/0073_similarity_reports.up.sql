@@ -0,0 +1,24 @@
+-- MinHash fingerprints of extracted dissertation texts, filled in by the
+-- similarity check
+ALTER TABLE document_version_texts
+  ADD COLUMN IF NOT EXISTS minhash bytea,
+  ADD COLUMN IF NOT EXISTS shingle_count int;
+
+-- In-house similarity check of a dissertation version against earlier
+-- versions in its tenant, attached to the node instance it was submitted to
+CREATE TABLE IF NOT EXISTS similarity_reports (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  node_instance_id uuid NOT NULL REFERENCES node_instances(id) ON DELETE CASCADE,
+  version_id uuid NOT NULL UNIQUE REFERENCES document_versions(id) ON DELETE CASCADE,
+  -- Share of the text found in other students' submissions
+  score double precision NOT NULL,
+  -- Share of the text found in the student's own earlier versions
+  self_score double precision NOT NULL,
+  compared_count int NOT NULL,
+  sources jsonb NOT NULL DEFAULT '[]'::jsonb,
+  passages jsonb NOT NULL DEFAULT '[]'::jsonb,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_similarity_reports_instance ON similarity_reports(node_instance_id, created_at DESC);
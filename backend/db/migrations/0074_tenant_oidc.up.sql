@@ -0,0 +1,29 @@
+-- OpenID Connect identity provider of a tenant, used for single sign-on
+-- next to local passwords
+CREATE TABLE IF NOT EXISTS tenant_oidc_configs (
+  tenant_id uuid PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  issuer text NOT NULL,
+  client_id text NOT NULL,
+  client_secret text NOT NULL DEFAULT '',
+  scopes text[] NOT NULL DEFAULT ARRAY['openid', 'email', 'profile'],
+  enabled boolean NOT NULL DEFAULT true,
+  -- Create student accounts and memberships for unknown emails on first login
+  jit_provisioning boolean NOT NULL DEFAULT false,
+  -- Email domains the provider may sign in; empty allows any
+  allowed_domains text[] NOT NULL DEFAULT '{}',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Pending SSO logins between the redirect to the provider and its callback.
+-- Each row is used once.
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+  state text PRIMARY KEY,
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  nonce text NOT NULL,
+  code_verifier text NOT NULL,
+  redirect_path text NOT NULL DEFAULT '/',
+  expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires ON oidc_login_states(expires_at);
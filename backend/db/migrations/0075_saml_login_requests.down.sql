@@ -0,0 +1 @@
+DROP TABLE IF EXISTS saml_login_requests;
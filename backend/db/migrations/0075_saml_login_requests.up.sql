@@ -0,0 +1,12 @@
+-- AuthnRequests sent to tenants' SAML identity providers and not answered
+-- yet. The IdP posts its response cross-site, where cookies cannot tie it
+-- to the browser, so the response is matched to its request by ID instead.
+-- Each row is used once. Provider settings live in tenants.settings->'saml'.
+CREATE TABLE IF NOT EXISTS saml_login_requests (
+  id text PRIMARY KEY,
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  redirect_path text NOT NULL DEFAULT '/',
+  expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_saml_login_requests_expires ON saml_login_requests(expires_at);
@@ -0,0 +1,20 @@
+-- Sign-in sessions. Each holds the hash of its current refresh token; a
+-- refresh swaps in a new one and keeps the previous hash, so a replayed
+-- old token gives itself away and ends the session. Access tokens carry
+-- the session ID and stop working once the session is revoked.
+CREATE TABLE IF NOT EXISTS user_sessions (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id uuid REFERENCES tenants(id) ON DELETE CASCADE,
+  refresh_token_hash text NOT NULL UNIQUE,
+  previous_token_hash text UNIQUE,
+  user_agent text NOT NULL DEFAULT '',
+  ip text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_used_at timestamptz NOT NULL DEFAULT now(),
+  rotated_at timestamptz,
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,20 @@
+-- TOTP second factor. A row without enabled_at is an enrollment waiting
+-- for its first code. last_used_step is the time step of the last code
+-- accepted, so a code cannot be replayed within its window. Recovery
+-- codes are kept by hash and work once each.
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret text NOT NULL,
+  enabled_at timestamptz,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash text NOT NULL,
+  used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,20 @@
+-- API keys for integrations. A key acts in one tenant as the admin who
+-- created it, limited to its scopes, and only on the routes those scopes
+-- cover. The key itself is known only by hash; prefix identifies it in
+-- lists. Each use is recorded in activity_logs.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id uuid NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  prefix text NOT NULL,
+  key_hash text NOT NULL UNIQUE,
+  scopes text[] NOT NULL,
+  expires_at timestamptz NOT NULL,
+  last_used_at timestamptz,
+  last_used_ip text,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  revoked_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant_id) WHERE revoked_at IS NULL;
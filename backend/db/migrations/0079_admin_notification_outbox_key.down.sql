@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_admin_notifications_outbox;
+ALTER TABLE admin_notifications DROP COLUMN IF EXISTS outbox_message_id;
@@ -0,0 +1,6 @@
+-- The outbox message a notification was delivered from, so a retried
+-- delivery does not create it twice.
+ALTER TABLE admin_notifications ADD COLUMN IF NOT EXISTS outbox_message_id uuid;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admin_notifications_outbox
+  ON admin_notifications(outbox_message_id);
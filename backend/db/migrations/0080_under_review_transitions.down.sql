@@ -0,0 +1 @@
+DELETE FROM node_state_transitions WHERE from_state = 'under_review';
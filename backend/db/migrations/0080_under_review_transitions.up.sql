@@ -0,0 +1,7 @@
+-- Document review moves a node to under_review; reviewers close it or send
+-- it back from there just as from submitted.
+INSERT INTO node_state_transitions(from_state, to_state, allowed_roles) VALUES
+  ('under_review','needs_fixes', ARRAY['advisor','secretary','chair','admin']),
+  ('under_review','done', ARRAY['advisor','secretary','chair','admin'])
+ON CONFLICT (from_state, to_state)
+DO UPDATE SET allowed_roles = EXCLUDED.allowed_roles;
@@ -0,0 +1,4 @@
+DELETE FROM node_state_transitions WHERE from_state = 'active' AND to_state = 'done';
+
+UPDATE node_state_transitions SET allowed_roles = ARRAY['advisor','secretary','chair','admin']
+WHERE from_state = 'submitted' AND to_state = 'done';
@@ -0,0 +1,7 @@
+-- Nodes a student or the committee closes themselves go straight to done;
+-- who_can_complete on the node narrows which of these roles may do it.
+INSERT INTO node_state_transitions(from_state, to_state, allowed_roles) VALUES
+  ('active','done', ARRAY['student','secretary','chair','admin']),
+  ('submitted','done', ARRAY['student','advisor','secretary','chair','admin'])
+ON CONFLICT (from_state, to_state)
+DO UPDATE SET allowed_roles = EXCLUDED.allowed_roles;
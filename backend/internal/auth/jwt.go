@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -32,3 +33,71 @@ func GenerateJWTWithTenant(sub, role, tenantID string, isSuperadmin bool, secret
 	return t.SignedString(secret)
 }
 
+
+// GenerateAccessToken creates a short-lived signed JWT for a session. The
+// sid claim ties it to the session so it can be revoked before it expires.
+func GenerateAccessToken(sub, role, tenantID, sessionID string, isSuperadmin bool, secret []byte, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":           sub,
+		"role":          role,
+		"tenant_id":     tenantID,
+		"is_superadmin": isSuperadmin,
+		"sid":           sessionID,
+		"iat":           now.Unix(),
+		"exp":           now.Add(ttl).Unix(),
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString(secret)
+}
+
+// MFAToken is what a password sign-in proved while it waits for the second
+// factor. Enroll is set when the user must first set up an authenticator.
+type MFAToken struct {
+	Sub          string
+	Role         string
+	TenantID     string
+	IsSuperadmin bool
+	Enroll       bool
+}
+
+// GenerateMFAToken signs a short-lived token for the second step of a
+// sign-in. It has no sid claim, so AuthMiddleware does not accept it.
+func GenerateMFAToken(t MFAToken, secret []byte, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"typ":           "mfa",
+		"sub":           t.Sub,
+		"role":          t.Role,
+		"tenant_id":     t.TenantID,
+		"is_superadmin": t.IsSuperadmin,
+		"enroll":        t.Enroll,
+		"iat":           now.Unix(),
+		"exp":           now.Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseMFAToken verifies a token from GenerateMFAToken.
+func ParseMFAToken(token string, secret []byte) (*MFAToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if typ, _ := claims["typ"].(string); typ != "mfa" {
+		return nil, errors.New("not a second factor token")
+	}
+	t := &MFAToken{}
+	t.Sub, _ = claims["sub"].(string)
+	t.Role, _ = claims["role"].(string)
+	t.TenantID, _ = claims["tenant_id"].(string)
+	t.IsSuperadmin, _ = claims["is_superadmin"].(bool)
+	t.Enroll, _ = claims["enroll"].(bool)
+	if t.Sub == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return t, nil
+}
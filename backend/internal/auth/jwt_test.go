@@ -2,6 +2,7 @@ package auth
 
 import (
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
@@ -61,3 +62,44 @@ func TestGenerateJWTWithTenant(t *testing.T) {
 	assert.Equal(t, tenantID, claims["tenant_id"])
 	assert.Equal(t, isSuperadmin, claims["is_superadmin"])
 }
+
+func TestGenerateAccessToken(t *testing.T) {
+	secret := []byte("testsecret")
+
+	tokenString, err := GenerateAccessToken("u1", "advisor", "t1", "s1", false, secret, 15*time.Minute)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "s1", claims["sid"])
+	assert.Equal(t, "t1", claims["tenant_id"])
+	exp, err := claims.GetExpirationTime()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), exp.Time, 5*time.Second)
+}
+
+func TestMFAToken(t *testing.T) {
+	secret := []byte("testsecret")
+
+	token, err := GenerateMFAToken(MFAToken{Sub: "u1", Role: "admin", TenantID: "t1", Enroll: true}, secret, 5*time.Minute)
+	require.NoError(t, err)
+	got, err := ParseMFAToken(token, secret)
+	require.NoError(t, err)
+	assert.Equal(t, &MFAToken{Sub: "u1", Role: "admin", TenantID: "t1", Enroll: true}, got)
+
+	_, err = ParseMFAToken(token, []byte("other"))
+	assert.Error(t, err)
+
+	access, err := GenerateAccessToken("u1", "admin", "t1", "s1", false, secret, time.Minute)
+	require.NoError(t, err)
+	_, err = ParseMFAToken(access, secret)
+	assert.Error(t, err, "an access token is no second factor token")
+
+	expired, err := GenerateMFAToken(MFAToken{Sub: "u1"}, secret, -time.Minute)
+	require.NoError(t, err)
+	_, err = ParseMFAToken(expired, secret)
+	assert.Error(t, err)
+}
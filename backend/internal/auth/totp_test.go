@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// The SHA-1 vectors of RFC 6238 appendix B, cut to six digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "t=%d", unix)
+	}
+
+	_, err := TOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	now := time.Now()
+	step := TOTPStep(now)
+	code, err := TOTPCode(secret, step)
+	require.NoError(t, err)
+
+	got, ok := ValidateTOTP(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, step, got)
+
+	got, ok = ValidateTOTP(secret, code[:3]+" "+code[3:], now.Add(TOTPPeriod*time.Second), 1)
+	assert.True(t, ok, "a step late, with a space")
+	assert.Equal(t, step, got)
+
+	_, ok = ValidateTOTP(secret, code, now.Add(3*TOTPPeriod*time.Second), 1)
+	assert.False(t, ok, "too late")
+	_, ok = ValidateTOTP(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("PhD Portal", "john@example.com", "ABC")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/PhD%20Portal:john@example.com?"), uri)
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "ABC", u.Query().Get("secret"))
+	assert.Equal(t, "PhD Portal", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+}
@@ -4,56 +4,61 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 )
 
 // AppConfig holds env-driven configuration.
 type AppConfig struct {
 	RedisURL string
 
-	Port            string
-	Env             string
-	JWTSecret       string
-	JWTExpDays      int
-	DatabaseURL     string
-	UploadDir       string
-	FileUploadMaxMB int
-	SMTPHost        string
-	SMTPPort        string
-	SMTPUser        string
-	SMTPPass        string
-	SMTPFrom        string
-	FrontendBase    string
-	AdminEmail      string
-	AdminPassword   string
-	PlaybookPath    string
-	S3Endpoint      string
-	S3Bucket        string
-	ServerURL       string
+	Port               string
+	Env                string
+	JWTSecret          string
+	JWTExpDays         int
+	AccessTokenMinutes int
+	DatabaseURL        string
+	UploadDir          string
+	FileUploadMaxMB    int
+	SMTPHost           string
+	SMTPPort           string
+	SMTPUser           string
+	SMTPPass           string
+	SMTPFrom           string
+	StateChangeEmailTo string
+	FrontendBase       string
+	AdminEmail         string
+	AdminPassword      string
+	PlaybookPath       string
+	S3Endpoint         string
+	S3Bucket           string
+	ServerURL          string
 }
 
 // MustLoad loads configuration from environment variables.
 func MustLoad() AppConfig {
 	cfg := AppConfig{
-		RedisURL:        get("REDIS_URL", "redis://127.0.0.1:6379"),
-		Port:            get("APP_PORT", "8080"),
-		Env:             get("APP_ENV", "development"),
-		JWTSecret:       get("JWT_SECRET", "change-me"),
-		JWTExpDays:      atoi(get("JWT_EXP_DAYS", "180")),
-		DatabaseURL:     get("DATABASE_URL", ""),
-		UploadDir:       get("UPLOAD_DIR", "./uploads"),
-		FileUploadMaxMB: atoi(get("FILE_UPLOAD_MAX_MB", "25")),
-		SMTPHost:        get("SMTP_HOST", "localhost"),
-		SMTPPort:        get("SMTP_PORT", "1025"),
-		SMTPUser:        get("SMTP_USER", ""),
-		SMTPPass:        get("SMTP_PASS", ""),
-		SMTPFrom:        get("SMTP_FROM", "PhD Portal <no-reply@local>"),
-		FrontendBase:    get("FRONTEND_BASE", "http://localhost:5173"),
-		AdminEmail:      get("SUPERADMIN_EMAIL", get("ADMIN_EMAIL", "")),
-		AdminPassword:   get("SUPERADMIN_PASSWORD", get("ADMIN_PASSWORD", "")),
-		PlaybookPath:    get("PLAYBOOK_PATH", "../frontend/src/playbooks/playbook.json"),
-		S3Endpoint:      get("S3_ENDPOINT", ""),
-		S3Bucket:        get("S3_BUCKET", ""),
-		ServerURL:       get("SERVER_URL", "http://localhost:8080"),
+		RedisURL:           get("REDIS_URL", "redis://127.0.0.1:6379"),
+		Port:               get("APP_PORT", "8080"),
+		Env:                get("APP_ENV", "development"),
+		JWTSecret:          get("JWT_SECRET", "change-me"),
+		JWTExpDays:         atoi(get("JWT_EXP_DAYS", "30")),
+		AccessTokenMinutes: atoi(get("ACCESS_TOKEN_MINUTES", "15")),
+		DatabaseURL:        get("DATABASE_URL", ""),
+		UploadDir:          get("UPLOAD_DIR", "./uploads"),
+		FileUploadMaxMB:    atoi(get("FILE_UPLOAD_MAX_MB", "25")),
+		SMTPHost:           get("SMTP_HOST", "localhost"),
+		SMTPPort:           get("SMTP_PORT", "1025"),
+		SMTPUser:           get("SMTP_USER", ""),
+		SMTPPass:           get("SMTP_PASS", ""),
+		SMTPFrom:           get("SMTP_FROM", "PhD Portal <no-reply@local>"),
+		StateChangeEmailTo: get("STATE_CHANGE_EMAIL_TO", "admin@portal.kaznmu.kz"),
+		FrontendBase:       get("FRONTEND_BASE", "http://localhost:5173"),
+		AdminEmail:         get("SUPERADMIN_EMAIL", get("ADMIN_EMAIL", "")),
+		AdminPassword:      get("SUPERADMIN_PASSWORD", get("ADMIN_PASSWORD", "")),
+		PlaybookPath:       get("PLAYBOOK_PATH", "../frontend/src/playbooks/playbook.json"),
+		S3Endpoint:         get("S3_ENDPOINT", ""),
+		S3Bucket:           get("S3_BUCKET", ""),
+		ServerURL:          get("SERVER_URL", "http://localhost:8080"),
 	}
 	if cfg.DatabaseURL == "" {
 		log.Fatal("DATABASE_URL is required")
@@ -66,6 +71,23 @@ func MustLoad() AppConfig {
 	return cfg
 }
 
+// AccessTokenTTL is how long an access token is accepted.
+func (c AppConfig) AccessTokenTTL() time.Duration {
+	if c.AccessTokenMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.AccessTokenMinutes) * time.Minute
+}
+
+// SessionTTL is how long a sign-in session lasts; its refresh token keeps
+// renewing the access token until then.
+func (c AppConfig) SessionTTL() time.Duration {
+	if c.JWTExpDays <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.JWTExpDays) * 24 * time.Hour
+}
+
 func get(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type advisorAccessRepo struct {
+	MinimalMockAdminRepo
+}
+
+func (m *advisorAccessRepo) CheckAdvisorAccess(ctx context.Context, studentID, advisorID string) (bool, error) {
+	return advisorID == "adv-ok", nil
+}
+
+// overrideOnlyDeadlineRepo stores admin overrides in memory
+type overrideOnlyDeadlineRepo struct {
+	repository.DeadlineRepository
+	rows []models.NodeDeadline
+}
+
+func (m *overrideOnlyDeadlineRepo) ListByUser(ctx context.Context, tenantID, userID string) ([]models.NodeDeadline, error) {
+	return m.rows, nil
+}
+
+func (m *overrideOnlyDeadlineRepo) SetOverride(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time, note *string, actorID string) error {
+	m.rows = append(m.rows, models.NodeDeadline{UserID: userID, NodeID: nodeID, DueAt: dueAt, Note: note, Source: models.DeadlineSourceOverride})
+	return nil
+}
+
+func TestAdminHandler_StudentDeadlines_Unit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pbm := &playbook.Manager{Nodes: map[string]playbook.Node{"S1": {ID: "S1"}}}
+	deadlineRepo := &overrideOnlyDeadlineRepo{}
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm,
+		services.NewAdminService(&advisorAccessRepo{}, pbm, config.AppConfig{}, nil),
+		services.NewJourneyService(nil, pbm, config.AppConfig{}, nil, nil, nil),
+		services.NewDeadlineService(deadlineRepo, pbm))
+
+	setup := func(role, userID string) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("claims", jwt.MapClaims{"sub": userID, "role": role})
+			c.Set("tenant_id", "t1")
+			c.Next()
+		})
+		r.GET("/students/:id/deadlines", h.GetStudentDeadlines)
+		r.PUT("/students/:id/nodes/:nodeId/deadline", h.PutStudentDeadline)
+		return r
+	}
+	put := func(r *gin.Engine, nodeID, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/students/s1/nodes/"+nodeID+"/deadline", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	admin := setup("admin", "admin1")
+	assert.Equal(t, http.StatusOK, put(admin, "S1", `{"due_at": "2026-05-01", "note": "extension"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(admin, "S1", `{"due_at": "May 1st"}`).Code)
+	assert.Equal(t, http.StatusNotFound, put(admin, "NOPE", `{"due_at": "2026-05-01"}`).Code)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/students/s1/deadlines", nil)
+	admin.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []models.NodeDeadline
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, time.Date(2026, 5, 1, 23, 59, 59, 0, time.UTC), list[0].DueAt)
+	assert.Equal(t, "extension", *list[0].Note)
+
+	// Advisors only manage their own students
+	assert.Equal(t, http.StatusForbidden, put(setup("advisor", "adv-other"), "S1", `{"due_at": "2026-05-01"}`).Code)
+	assert.Equal(t, http.StatusOK, put(setup("advisor", "adv-ok"), "S1", `{"due_at": "2026-05-02T10:00:00Z"}`).Code)
+}
@@ -34,7 +34,7 @@ func (m *MinimalMockAdminRepo) GetDoneCountsForStudents(ctx context.Context, ids
 func (m *MinimalMockAdminRepo) GetLastUpdatesForStudents(ctx context.Context, ids []string) (map[string]time.Time, error) {
 	return nil, nil
 }
-func (m *MinimalMockAdminRepo) GetRPRequiredForStudents(ctx context.Context, ids []string) (map[string]bool, error) {
+func (m *MinimalMockAdminRepo) GetProfilesForStudents(ctx context.Context, ids []string) (map[string]map[string]any, error) {
 	return nil, nil
 }
 
@@ -45,7 +45,7 @@ func TestAdminHandler_MonitorStudents_Unit(t *testing.T) {
 	pbm := &playbook.Manager{}
 	svc := services.NewAdminService(mockRepo, pbm, config.AppConfig{}, nil)
 	jSvc := services.NewJourneyService(nil, pbm, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc, nil)
 
 	r := gin.New()
 	r.Use(func(c *gin.Context) {
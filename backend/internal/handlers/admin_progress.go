@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
@@ -22,10 +24,23 @@ type AdminHandler struct {
 	pb         *pb.Manager
 	svc        *services.AdminService
 	journeySvc *services.JourneyService
+	deadlines  *services.DeadlineService
+	similarity *services.SimilarityService
+	texts      *services.DocumentTextService
 }
 
-func NewAdminHandler(cfg config.AppConfig, pbm *pb.Manager, svc *services.AdminService, journeySvc *services.JourneyService) *AdminHandler {
-	return &AdminHandler{cfg: cfg, pb: pbm, svc: svc, journeySvc: journeySvc}
+func NewAdminHandler(cfg config.AppConfig, pbm *pb.Manager, svc *services.AdminService, journeySvc *services.JourneyService, deadlines *services.DeadlineService) *AdminHandler {
+	return &AdminHandler{cfg: cfg, pb: pbm, svc: svc, journeySvc: journeySvc, deadlines: deadlines}
+}
+
+// SetSimilarity enables the similarity reports of dissertation versions.
+func (h *AdminHandler) SetSimilarity(similarity *services.SimilarityService) {
+	h.similarity = similarity
+}
+
+// SetDocumentTexts enables diffs between the versions uploaded to a slot.
+func (h *AdminHandler) SetDocumentTexts(texts *services.DocumentTextService) {
+	h.texts = texts
 }
 
 type studentRow struct {
@@ -272,6 +287,69 @@ func (h *AdminHandler) ListStudentNodeFiles(c *gin.Context) {
 	c.JSON(200, files)
 }
 
+// ListStudentNodeSimilarity returns the in-house similarity reports of the
+// dissertation versions submitted to a student's node, newest first.
+// GET /api/admin/students/:id/nodes/:nodeId/similarity
+func (h *AdminHandler) ListStudentNodeSimilarity(c *gin.Context) {
+	studentID, nodeID := c.Param("id"), c.Param("nodeId")
+	if h.similarity == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "similarity check is not available"})
+		return
+	}
+	if !h.studentAccess(c, studentID) {
+		return
+	}
+	reports, err := h.similarity.ListNodeReports(c.Request.Context(), middleware.GetTenantID(c), studentID, nodeID)
+	if err != nil {
+		log.Printf("[ListStudentNodeSimilarity] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load similarity reports"})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// DiffStudentSlotVersions compares two uploads in the history of a node slot
+// paragraph by paragraph, so reviewers can check what changed after sending
+// the node back. ?from and ?to are version IDs, by default the previous and
+// the latest upload; ?context is how many unchanged paragraphs to keep
+// around each edit, -1 for all.
+// GET /api/admin/students/:id/nodes/:nodeId/slots/:slotKey/diff
+func (h *AdminHandler) DiffStudentSlotVersions(c *gin.Context) {
+	studentID, nodeID, slotKey := c.Param("id"), c.Param("nodeId"), c.Param("slotKey")
+	if h.texts == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "version diffs are not available"})
+		return
+	}
+	contextParagraphs := 2
+	if v := c.Query("context"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid context"})
+			return
+		}
+		contextParagraphs = n
+	}
+	if !h.studentAccess(c, studentID) {
+		return
+	}
+
+	diff, err := h.texts.DiffSlotVersions(c.Request.Context(), middleware.GetTenantID(c), studentID, nodeID, slotKey,
+		c.Query("from"), c.Query("to"), contextParagraphs)
+	switch {
+	case errors.Is(err, services.ErrDiffVersionNotFound), errors.Is(err, services.ErrDiffNeedsTwoVersions):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDiffTextPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDiffTextUnavailable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Printf("[DiffStudentSlotVersions] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare versions"})
+	default:
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
 // ReviewAttachment allows admin/advisors to approve or request fixes for an attachment.
 func (h *AdminHandler) ReviewAttachment(c *gin.Context) {
 	attachmentID := c.Param("attachmentId")
@@ -304,6 +382,14 @@ func (h *AdminHandler) ReviewAttachment(c *gin.Context) {
 			c.JSON(403, gin.H{"error": "forbidden"})
 			return
 		}
+		if errors.Is(err, services.ErrTransitionDenied) {
+			c.JSON(403, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrRequirementsNotMet) || errors.Is(err, services.ErrOutcomeNotDetermined) {
+			respondSubmissionError(c, err)
+			return
+		}
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(404, gin.H{"error": "not found"})
 			return
@@ -312,13 +398,7 @@ func (h *AdminHandler) ReviewAttachment(c *gin.Context) {
 		return
 	}
 	
-	// Check for side effects: Node Activation
-	// Service result includes StudentID/NodeID now
-	if res.State == "done" && res.StudentID != "" {
-		if err := h.journeySvc.ActivateNextNodes(c.Request.Context(), res.StudentID, res.NodeID, tenantID); err != nil {
-			log.Printf("[ReviewAttachment] Failed to activate next nodes: %v", err)
-		}
-	}
+	// Next nodes open as part of the review's completion in the service
 
 	result := gin.H{"status": res.Status, "node_state": res.State}
 	if res.ReviewNote != nil {
@@ -516,21 +596,97 @@ func (h *AdminHandler) AttachReviewedDocument(c *gin.Context) {
 	})
 }
 
-// Deadline represents a deadline for a student's node
-type Deadline struct {
-	ID        string  `db:"id" json:"id"`
-	NodeID    string  `db:"node_id" json:"node_id"`
-	DueAt     string  `db:"due_at" json:"due_at"`
-	CreatedBy string  `db:"created_by" json:"created_by"`
-	CreatedAt string  `db:"created_at" json:"created_at"`
-	Note      *string `db:"note" json:"note,omitempty"`
-}
-
-// GetStudentDeadlines returns deadlines for a specific student
+// GetStudentDeadlines returns deadlines for a specific student, derived from
+// the playbook's deadline rules or set by an admin
 // GET /api/admin/students/:id/deadlines
 func (h *AdminHandler) GetStudentDeadlines(c *gin.Context) {
-	// Return empty array for now - deadlines feature not fully implemented
-	// Future: query node_deadlines table for this student
-	c.JSON(http.StatusOK, []Deadline{})
+	studentID := c.Param("id")
+	if !h.studentAccess(c, studentID) {
+		return
+	}
+	deadlines, err := h.deadlines.List(c.Request.Context(), middleware.GetTenantID(c), studentID)
+	if err != nil {
+		log.Printf("[GetStudentDeadlines] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load deadlines"})
+		return
+	}
+	c.JSON(http.StatusOK, deadlines)
+}
+
+type putDeadlineReq struct {
+	DueAt string  `json:"due_at" binding:"required"`
+	Note  *string `json:"note"`
+}
+
+// PutStudentDeadline overrides the due date of one of the student's nodes
+// PUT /api/admin/students/:id/nodes/:nodeId/deadline
+func (h *AdminHandler) PutStudentDeadline(c *gin.Context) {
+	studentID, nodeID := c.Param("id"), c.Param("nodeId")
+	if !h.studentAccess(c, studentID) {
+		return
+	}
+	var req putDeadlineReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dueAt, err := parseDueAt(req.DueAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due_at must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+		return
+	}
+	err = h.deadlines.SetOverride(c.Request.Context(), middleware.GetTenantID(c), studentID, nodeID, dueAt, req.Note, userIDFromClaims(c))
+	if errors.Is(err, services.ErrUnknownDeadlineNode) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[PutStudentDeadline] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save deadline"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "node_id": nodeID, "due_at": dueAt})
+}
+
+// DeleteStudentDeadline removes an override so the playbook rule applies again
+// DELETE /api/admin/students/:id/nodes/:nodeId/deadline
+func (h *AdminHandler) DeleteStudentDeadline(c *gin.Context) {
+	studentID, nodeID := c.Param("id"), c.Param("nodeId")
+	if !h.studentAccess(c, studentID) {
+		return
+	}
+	err := h.deadlines.ClearOverride(c.Request.Context(), middleware.GetTenantID(c), studentID, nodeID)
+	if errors.Is(err, services.ErrDeadlineNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("[DeleteStudentDeadline] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear deadline"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// studentAccess aborts with 403 when an advisor is not assigned to the student
+func (h *AdminHandler) studentAccess(c *gin.Context, studentID string) bool {
+	err := h.svc.CheckStudentAccess(c.Request.Context(), studentID, roleFromContext(c), userIDFromClaims(c))
+	if err == nil {
+		return true
+	}
+	if err.Error() == "forbidden" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	return false
+}
+
+// parseDueAt accepts a calendar date (due at the end of that day, UTC) or an RFC 3339 timestamp
+func parseDueAt(s string) (time.Time, error) {
+	if d, err := time.Parse("2006-01-02", s); err == nil {
+		return d.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
 
@@ -62,7 +62,7 @@ func TestAdminHandler_StudentProgress(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -142,7 +142,7 @@ func TestAdminHandler_MonitorStudents(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -256,7 +256,7 @@ func TestAdminHandler_GetStudentDetails(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -325,7 +325,7 @@ func TestAdminHandler_StudentJourney(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -414,7 +414,7 @@ func TestAdminHandler_ListStudentNodeFiles(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -464,7 +464,7 @@ func TestAdminHandler_ListStudentNodeFiles_Forbidden(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -520,7 +520,7 @@ func TestAdminHandler_MonitorAnalytics(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -630,7 +630,7 @@ func TestAdminHandler_ReviewAttachment(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -698,7 +698,7 @@ func TestAdminHandler_ListStudentProgress(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -791,7 +791,7 @@ func TestAdminHandler_UploadReviewedDocument(t *testing.T) {
 	svc := services.NewAdminService(repo, pb, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pb, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pb, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pb, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -869,7 +869,7 @@ func TestAdminHandler_PatchStudentNodeState(t *testing.T) {
 	svc := services.NewAdminService(repo, pb, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pb, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pb, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pb, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -969,7 +969,7 @@ func TestAdminHandler_PresignReviewedDocumentUpload(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1073,7 +1073,7 @@ func TestAdminHandler_AttachReviewedDocument(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1127,7 +1127,7 @@ func TestAdminHandler_UploadReviewedDocument_Failures(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1214,7 +1214,7 @@ func TestAdminHandler_UploadReviewedDocument_Forbidden(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1275,7 +1275,7 @@ func TestAdminHandler_PostReminders_Extended(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1316,7 +1316,7 @@ func TestAdminHandler_PostReminders_Failures(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1363,7 +1363,7 @@ func TestAdminHandler_ReviewAttachment_Failures(t *testing.T) {
 	svc := services.NewAdminService(repo, &pb.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &pb.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &pb.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -1433,7 +1433,7 @@ func TestAdminHandler_PostReminders(t *testing.T) {
 	svc := services.NewAdminService(repo, pb, cfg, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pb, cfg, nil, nil, nil)
-	h := handlers.NewAdminHandler(cfg, pb, svc, jSvc)
+	h := handlers.NewAdminHandler(cfg, pb, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
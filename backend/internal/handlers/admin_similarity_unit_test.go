@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nodeReportsRepo struct {
+	repository.SimilarityRepository
+	tenantID, studentID, nodeID string
+}
+
+func (m *nodeReportsRepo) ListNodeReports(ctx context.Context, tenantID, studentID, nodeID string) ([]models.SimilarityReport, error) {
+	m.tenantID, m.studentID, m.nodeID = tenantID, studentID, nodeID
+	return []models.SimilarityReport{{ID: "rep-1", VersionID: "ver-1", Score: 0.3, Sources: models.SimilaritySources{}, Passages: models.SimilarityPassages{}}}, nil
+}
+
+func TestAdminHandler_ListStudentNodeSimilarity_Unit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pbm := &playbook.Manager{}
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm,
+		services.NewAdminService(&advisorAccessRepo{}, pbm, config.AppConfig{}, nil), nil, nil)
+
+	get := func(role, userID string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("claims", jwt.MapClaims{"sub": userID, "role": role})
+			c.Set("tenant_id", "t1")
+			c.Next()
+		})
+		r.GET("/students/:id/nodes/:nodeId/similarity", h.ListStudentNodeSimilarity)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/students/s1/nodes/S1_text_ready/similarity", nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, get("admin", "admin1").Code)
+
+	repo := &nodeReportsRepo{}
+	h.SetSimilarity(services.NewSimilarityService(repo))
+	w := get("admin", "admin1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var reports []models.SimilarityReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reports))
+	require.Len(t, reports, 1)
+	assert.Equal(t, 0.3, reports[0].Score)
+	assert.Equal(t, []string{"t1", "s1", "S1_text_ready"}, []string{repo.tenantID, repo.studentID, repo.nodeID})
+
+	// Advisors only see their own students
+	assert.Equal(t, http.StatusForbidden, get("advisor", "adv-other").Code)
+	assert.Equal(t, http.StatusOK, get("advisor", "adv-ok").Code)
+}
@@ -0,0 +1,81 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slotHistoryRepo struct {
+	repository.DocumentTextRepository
+	texts map[string]string
+}
+
+func (m *slotHistoryRepo) ListSlotVersions(ctx context.Context, tenantID, studentID, nodeID, slotKey string) ([]models.SlotVersion, error) {
+	return []models.SlotVersion{{VersionID: "v1"}, {VersionID: "v2"}, {VersionID: "v3"}}, nil
+}
+
+func (m *slotHistoryRepo) GetText(ctx context.Context, versionID string) (*models.DocumentText, error) {
+	content, ok := m.texts[versionID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &models.DocumentText{VersionID: versionID, Status: models.DocumentTextDone, Content: content}, nil
+}
+
+func TestAdminHandler_DiffStudentSlotVersions_Unit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pbm := &playbook.Manager{}
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm,
+		services.NewAdminService(&advisorAccessRepo{}, pbm, config.AppConfig{}, nil), nil, nil)
+	h.SetDocumentTexts(services.NewDocumentTextService(&slotHistoryRepo{texts: map[string]string{
+		"v1": "Aims\n\nWe enrolled 120 patients.",
+		"v2": "Aims\n\nWe enrolled 240 patients.",
+	}}, config.AppConfig{}))
+
+	get := func(role, userID, query string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("claims", jwt.MapClaims{"sub": userID, "role": role})
+			c.Set("tenant_id", "t1")
+			c.Next()
+		})
+		r.GET("/students/:id/nodes/:nodeId/slots/:slotKey/diff", h.DiffStudentSlotVersions)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/students/s1/nodes/S1_text_ready/slots/dissertation_draft_file/diff"+query, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("admin", "admin1", "?to=v2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var diff struct {
+		From   models.SlotVersion `json:"from"`
+		Stats  map[string]int     `json:"stats"`
+		Blocks []map[string]any   `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Equal(t, "v1", diff.From.VersionID)
+	assert.Equal(t, 1, diff.Stats["changed"])
+	require.Len(t, diff.Blocks, 2)
+	assert.Equal(t, "change", diff.Blocks[1]["op"])
+
+	assert.Equal(t, http.StatusConflict, get("admin", "admin1", "").Code, "v3 is not extracted yet")
+	assert.Equal(t, http.StatusNotFound, get("admin", "admin1", "?from=v9&to=v2").Code)
+	assert.Equal(t, http.StatusBadRequest, get("admin", "admin1", "?context=all").Code)
+	assert.Equal(t, http.StatusForbidden, get("advisor", "adv-other", "?to=v2").Code)
+}
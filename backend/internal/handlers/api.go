@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"strings"
@@ -25,45 +26,48 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 	// Clean FrontendBase in case env var has embedded quotes
 	cleanedFrontendBase := strings.Trim(cfg.FrontendBase, "\"' \t")
 	
-	r.Use(cors.New(cors.Config{
-		AllowOriginFunc: func(origin string) bool {
-			if origin == "" {
-				return false
-			}
-			// Log CORS check for debugging
-			log.Printf("[CORS] Origin=%q, FrontendBase=%q, Cleaned=%q", origin, cfg.FrontendBase, cleanedFrontendBase)
-			
-			if origin == cleanedFrontendBase {
-				return true
-			}
-			// allow any localhost/127.0.0.1 port for dev
-			if strings.HasPrefix(origin, "http://localhost:") || strings.HasPrefix(origin, "https://localhost:") {
-				return true
-			}
-			if strings.HasPrefix(origin, "http://127.0.0.1:") || strings.HasPrefix(origin, "https://127.0.0.1:") {
-				return true
-			}
-			// Allow *.localhost for local multitenancy testing
-			if strings.Contains(origin, ".localhost:") {
-				return true
-			}
-			// Allow subdomain-based tenant URLs (e.g., kaznmu.phd-portal.kz)
-			// Parse configured frontend base to extract the main domain
-			if cleanedFrontendBase != "" {
-				// Simple subdomain matching for production
-				// e.g., if FrontendBase is "https://phd-portal.kz", allow "*.phd-portal.kz"
-				mainDomain := strings.TrimPrefix(cleanedFrontendBase, "https://")
-				mainDomain = strings.TrimPrefix(mainDomain, "http://")
-				if strings.Contains(origin, "."+mainDomain) || strings.HasSuffix(origin, mainDomain) {
-					return true
-				}
-			}
-			// Allow all Vercel deployments (they use X-Tenant-Slug header for tenant resolution)
-			if strings.HasSuffix(origin, ".vercel.app") {
+	// allowOrigin is shared by CORS and the chat WebSocket handshake
+	allowOrigin := func(origin string) bool {
+		if origin == "" {
+			return false
+		}
+		// Log CORS check for debugging
+		log.Printf("[CORS] Origin=%q, FrontendBase=%q, Cleaned=%q", origin, cfg.FrontendBase, cleanedFrontendBase)
+		
+		if origin == cleanedFrontendBase {
+			return true
+		}
+		// allow any localhost/127.0.0.1 port for dev
+		if strings.HasPrefix(origin, "http://localhost:") || strings.HasPrefix(origin, "https://localhost:") {
+			return true
+		}
+		if strings.HasPrefix(origin, "http://127.0.0.1:") || strings.HasPrefix(origin, "https://127.0.0.1:") {
+			return true
+		}
+		// Allow *.localhost for local multitenancy testing
+		if strings.Contains(origin, ".localhost:") {
+			return true
+		}
+		// Allow subdomain-based tenant URLs (e.g., kaznmu.phd-portal.kz)
+		// Parse configured frontend base to extract the main domain
+		if cleanedFrontendBase != "" {
+			// Simple subdomain matching for production
+			// e.g., if FrontendBase is "https://phd-portal.kz", allow "*.phd-portal.kz"
+			mainDomain := strings.TrimPrefix(cleanedFrontendBase, "https://")
+			mainDomain = strings.TrimPrefix(mainDomain, "http://")
+			if strings.Contains(origin, "."+mainDomain) || strings.HasSuffix(origin, mainDomain) {
 				return true
 			}
-			return false
-		},
+		}
+		// Allow all Vercel deployments (they use X-Tenant-Slug header for tenant resolution)
+		if strings.HasSuffix(origin, ".vercel.app") {
+			return true
+		}
+		return false
+	}
+
+	r.Use(cors.New(cors.Config{
+		AllowOriginFunc:  allowOrigin,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Tenant-Slug"},
 		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
@@ -74,6 +78,11 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 	// Add tenant middleware for all API routes
 	// This resolves tenant from subdomain or X-Tenant-Slug header
 	r.Use(middleware.TenantMiddleware(db))
+
+	// Resolve each request tenant's active playbook (hot-reloaded from playbook_versions)
+	playbookRepo := repository.NewSQLPlaybookRepository(db)
+	playbookRegistry := pb.NewRegistry(playbookRepo, playbookManager)
+	r.Use(middleware.TenantPlaybook(playbookRegistry))
 	
 	api := r.Group("/api")
 	
@@ -109,17 +118,24 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 	// Repositories & Domain Services
 	userRepo := repository.NewSQLUserRepository(db)
 	userService := services.NewUserService(userRepo, rds, cfg, emailService, s3Svc)
-	authService := services.NewAuthService(userRepo, emailService, cfg)
+	authService := services.NewAuthService(userRepo, repository.NewSQLSessionRepository(db), rds, emailService, cfg)
+	userService.SetSessionRevoker(authService)
+	// Password sign-ins ask for a TOTP code when the user has 2FA on, or
+	// their tenant requires it for admins
+	mfaService := services.NewMFAService(repository.NewSQLMFARepository(db), authService, cfg)
+	authService.SetSecondFactor(mfaService)
 
 	// Auth routes (login and password reset)
 	auth := NewAuthHandler(authService, cfg, rds)
 	api.POST("/auth/login", auth.Login)
+	api.POST("/auth/refresh", auth.Refresh)
 	api.POST("/auth/logout", auth.Logout) // Added logout
 	api.POST("/auth/forgot-password", auth.ForgotPassword)
 	api.POST("/auth/reset-password", auth.ResetPassword)
 
 	users := NewUsersHandler(userService, cfg)
 	_ = users
+	sessionsHandler := NewSessionsHandler(authService, cfg)
 	
 	// Documents
 	docRepo := repository.NewSQLDocumentRepository(db)
@@ -132,18 +148,35 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 	journeyRepo := repository.NewSQLJourneyRepository(db)
 	journeyService := services.NewJourneyService(journeyRepo, playbookManager, cfg, mailerSvc, s3Svc, docService)
 
-	journey := NewJourneyHandler(journeyService)
+	deadlineService := services.NewDeadlineService(repository.NewSQLDeadlineRepository(db), playbookManager)
+	journeyService.SetDeadlines(deadlineService)
+	journey := NewJourneyHandler(journeyService, deadlineService)
 	_ = journey
 	nodeSubmission := NewNodeSubmissionHandler(journeyService)
 	_ = nodeSubmission
 	// Admin Service
 	adminRepo := repository.NewSQLAdminRepository(db)
 	adminService := services.NewAdminService(adminRepo, playbookManager, cfg, s3Svc)
-	adminHandler := NewAdminHandler(cfg, playbookManager, adminService, journeyService)
+	adminService.SetJourney(journeyService)
+	adminHandler := NewAdminHandler(cfg, playbookManager, adminService, journeyService, deadlineService)
+	adminHandler.SetSimilarity(services.NewSimilarityService(repository.NewSQLSimilarityRepository(db)))
+	adminHandler.SetDocumentTexts(services.NewDocumentTextService(repository.NewSQLDocumentTextRepository(db), cfg))
 	_ = adminHandler
+	playbookService := services.NewPlaybookService(playbookRepo, playbookRegistry)
+	playbookMigrations := services.NewPlaybookMigrationService(journeyRepo, journeyService, playbookService)
+	playbookHandler := NewPlaybookHandler(playbookService, playbookMigrations)
+	outboxHandler := NewOutboxHandler(services.NewOutboxService(repository.NewSQLOutboxRepository(db)))
 	chatRepo := repository.NewSQLChatRepository(db)
 	chatService := services.NewChatService(chatRepo, emailService, cfg)
 	chatHandler := NewChatHandler(chatService, cfg)
+	chatHub := services.NewChatHub(rds)
+	go chatHub.Run(context.Background())
+	chatService.SetHub(chatHub)
+	if s3Svc != nil {
+		chatService.SetStorage(s3Svc, docService)
+	}
+	chatRealtimeHandler := NewChatRealtimeHandler(chatService, chatHub, allowOrigin)
+	userService.SetRoomSync(chatService)
 	_ = chatHandler
 
 	// Calendar Module
@@ -164,12 +197,18 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 	// Dictionary Module
 	dictionaryRepo := repository.NewSQLDictionaryRepository(db)
 	dictionaryService := services.NewDictionaryService(dictionaryRepo)
+	dictionaryService.SetRoomSync(chatService)
 	dictionaryHandler := NewDictionaryHandler(dictionaryService)
 
 	// Notification Module
 	notificationRepo := repository.NewSQLNotificationRepository(db)
 	notificationService := services.NewNotificationService(notificationRepo)
 	notificationHandler := NewNotificationHandler(notificationService)
+	notificationStream := services.NewNotificationStream(repository.NewSQLNotificationEventRepository(db), rds)
+	go notificationStream.Run(context.Background())
+	notificationService.SetStream(notificationStream)
+	adminService.SetNotifications(notificationService)
+	notificationStreamHandler := NewNotificationStreamHandler(notificationStream, notificationService, adminService)
 
 	contactRepo := repository.NewSQLContactRepository(db)
 	contactService := services.NewContactService(contactRepo)
@@ -190,6 +229,26 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 	
 	superAdminRepo := repository.NewSQLSuperAdminRepository(db)
 	superAdminService := services.NewSuperAdminService(superAdminRepo)
+
+	// Single sign-on through the tenant's OpenID Connect provider
+	oidcService := services.NewOIDCService(repository.NewSQLOIDCRepository(db), userRepo, tenantRepo, authService, cfg)
+	oidcHandler := NewOIDCHandler(oidcService, cfg)
+	api.GET("/auth/oidc", oidcHandler.Status)
+	api.GET("/auth/oidc/login", oidcHandler.Login)
+	api.GET("/auth/oidc/callback", oidcHandler.Callback)
+
+	// ... and through its SAML identity provider
+	samlService := services.NewSAMLService(repository.NewSQLSAMLRepository(db), userRepo, tenantRepo, authService, cfg)
+	samlHandler := NewSAMLHandler(samlService, cfg)
+	api.GET("/auth/saml", samlHandler.Status)
+	api.GET("/auth/saml/metadata", samlHandler.Metadata)
+	api.GET("/auth/saml/login", samlHandler.Login)
+	api.POST("/auth/saml/acs", samlHandler.ACS)
+
+	// Second step of password sign-ins that need a TOTP code
+	mfaHandler := NewMFAHandler(mfaService, superAdminService, cfg, rds)
+	api.POST("/auth/2fa/enroll", mfaHandler.Enroll)
+	api.POST("/auth/2fa/verify", mfaHandler.Verify)
 	
 	// Update MeHandler with dependencies (UserService, TenantService)
 	// Note: NewMeHandler signature: (userSvc, tenantSvc, cfg)
@@ -208,6 +267,14 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 		meGroup.GET("", meHandler.Me)
 		meGroup.GET("/tenants", meHandler.MyTenants)
 	meGroup.GET("/tenant", meHandler.MyTenant)
+		meGroup.GET("/sessions", sessionsHandler.ListMine)
+		meGroup.DELETE("/sessions/:id", sessionsHandler.RevokeMine)
+		meGroup.POST("/sessions/revoke-all", sessionsHandler.RevokeAllMine)
+		meGroup.GET("/2fa", mfaHandler.Status)
+		meGroup.POST("/2fa/enroll", mfaHandler.BeginEnrollment)
+		meGroup.POST("/2fa/confirm", mfaHandler.ConfirmEnrollment)
+		meGroup.POST("/2fa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		meGroup.POST("/2fa/disable", mfaHandler.Disable)
 	}
 
 	// Authenticated Routes Group
@@ -231,6 +298,7 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 
 		// Search
 		protected.GET("/search", searchHandler.GlobalSearch)
+		protected.GET("/search/:type", searchHandler.Search)
 
 		// Dictionaries
 		dict := protected.Group("/dictionaries")
@@ -246,6 +314,7 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 		{
 			notif.GET("", notificationHandler.GetNotifications)
 			notif.GET("/unread", notificationHandler.GetUnread)
+			notif.GET("/stream", notificationStreamHandler.Stream)
 			notif.POST("/:id/read", notificationHandler.MarkAsRead)
 			notif.POST("/read-all", notificationHandler.MarkAllAsRead)
 		}
@@ -257,6 +326,7 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 			j.PUT("/state", journey.SetState)
 			j.POST("/reset", journey.Reset)
 			j.GET("/scoreboard", journey.GetScoreboard)
+			j.GET("/conditions", journey.GetConditions)
 
 			j.GET("/profile", nodeSubmission.GetProfile)
 			nodes := j.Group("/nodes/:nodeId")
@@ -284,7 +354,11 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 			adm.GET("/students/:id", adminHandler.GetStudentDetails)
 			adm.GET("/students/:id/journey", adminHandler.StudentJourney)
 			adm.GET("/students/:id/deadlines", adminHandler.GetStudentDeadlines)
+			adm.PUT("/students/:id/nodes/:nodeId/deadline", adminHandler.PutStudentDeadline)
+			adm.DELETE("/students/:id/nodes/:nodeId/deadline", adminHandler.DeleteStudentDeadline)
 			adm.GET("/students/:id/nodes/:nodeId/files", adminHandler.ListStudentNodeFiles)
+			adm.GET("/students/:id/nodes/:nodeId/similarity", adminHandler.ListStudentNodeSimilarity)
+			adm.GET("/students/:id/nodes/:nodeId/slots/:slotKey/diff", adminHandler.DiffStudentSlotVersions)
 			adm.PATCH("/students/:id/nodes/:nodeId/state", adminHandler.PatchStudentNodeState)
 			
 			// Review actions
@@ -301,6 +375,8 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 			adm.PUT("/users/:id", users.UpdateUser)
 			adm.PATCH("/users/:id/active", users.SetActive)
 			adm.POST("/users/:id/reset-password", users.ResetPasswordForUser)
+			adm.GET("/users/:id/sessions", sessionsHandler.ListUser)
+			adm.POST("/users/:id/sessions/revoke", sessionsHandler.RevokeUser)
 			
 			// Admin dictionaries
 			admDict := adm.Group("/dictionaries")
@@ -332,6 +408,7 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 				admNotif.GET("", notificationHandler.GetNotifications)
 				admNotif.GET("/unread", notificationHandler.GetUnread)
 				admNotif.GET("/unread-count", notificationHandler.GetUnread) // Alias for compatibility
+				admNotif.GET("/stream", notificationStreamHandler.Stream)
 				admNotif.POST("/:id/read", notificationHandler.MarkAsRead)
 				admNotif.POST("/read-all", notificationHandler.MarkAllAsRead)
 			}
@@ -341,6 +418,34 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 			adm.POST("/contacts", contactsHandler.Create)
 			adm.PUT("/contacts/:id", contactsHandler.Update)
 			adm.DELETE("/contacts/:id", contactsHandler.Delete)
+
+			// Playbook versions of the current tenant
+			admPlaybook := adm.Group("/playbook")
+			admPlaybook.Use(middleware.RequireRoles("admin", "superadmin"))
+			registerPlaybookRoutes(admPlaybook, playbookHandler)
+
+			// Outbox inspection and replay of failed deliveries
+			admOutbox := adm.Group("/outbox")
+			admOutbox.Use(middleware.RequireRoles("admin", "superadmin"))
+			admOutbox.GET("", outboxHandler.List)
+			admOutbox.POST("/replay", outboxHandler.ReplayDead)
+			admOutbox.GET("/:messageId", outboxHandler.Get)
+			admOutbox.POST("/:messageId/replay", outboxHandler.Replay)
+
+			// Cohort and advisory chat rooms kept in sync with the dictionaries
+			admRoomSync := adm.Group("/chat/room-sync")
+			admRoomSync.Use(middleware.RequireRoles("admin", "superadmin"))
+			admRoomSync.GET("", chatHandler.RoomSyncReport)
+			admRoomSync.POST("", chatHandler.SyncRooms)
+
+			// API keys for integrations such as the registrar's scripts
+			apiKeysHandler := NewAPIKeysHandler(services.NewAPIKeyService(repository.NewSQLAPIKeyRepository(db)))
+			admAPIKeys := adm.Group("/api-keys")
+			admAPIKeys.Use(middleware.RequireRoles("admin", "superadmin"))
+			admAPIKeys.GET("", apiKeysHandler.List)
+			admAPIKeys.GET("/scopes", apiKeysHandler.Scopes)
+			admAPIKeys.POST("", apiKeysHandler.Create)
+			admAPIKeys.DELETE("/:id", apiKeysHandler.Revoke)
 		}
 
 
@@ -352,8 +457,22 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 			chat.GET("/rooms/:roomId/messages", chatHandler.ListMessages)
 			chat.POST("/rooms/:roomId/messages", chatHandler.CreateMessage)
 			chat.POST("/rooms/:roomId/read", chatHandler.MarkAsRead)
+			chat.GET("/rooms/:roomId/threads", chatHandler.ListThreads)
+			chat.GET("/rooms/:roomId/pins", chatHandler.ListPinned)
+
+			// Live room events (messages, edits, deletions, read receipts, typing)
+			chat.GET("/rooms/:roomId/ws", chatRealtimeHandler.WebSocket)
+			chat.GET("/rooms/:roomId/events", chatRealtimeHandler.Stream)
+			chat.POST("/rooms/:roomId/typing", chatRealtimeHandler.Typing)
 			
-			// File upload/download - available to all chat members
+			// Files go to object storage: presign, PUT, then attach to get the
+			// message attachment; downloads hand out short-lived links
+			chat.POST("/rooms/:roomId/uploads/presign", chatHandler.PresignUpload)
+			chat.POST("/rooms/:roomId/uploads/attach", chatHandler.AttachUpload)
+			chat.GET("/attachments/:versionId", chatHandler.DownloadAttachment)
+
+			// Local-disk upload/download, used without object storage and for
+			// files not migrated yet
 			chat.POST("/rooms/:roomId/upload", chatHandler.UploadFile)
 			chat.GET("/rooms/:roomId/files/:filename", chatHandler.DownloadFile)
 
@@ -373,6 +492,25 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 			// Message editing/deletion
 			chat.PATCH("/messages/:messageId", chatHandler.UpdateMessage)
 			chat.DELETE("/messages/:messageId", chatHandler.DeleteMessage)
+
+			// Threads, reactions and pins; pinning needs a room admin or moderator
+			chat.GET("/messages/:messageId/replies", chatHandler.ListReplies)
+			chat.POST("/messages/:messageId/replies", chatHandler.CreateReply)
+			chat.POST("/messages/:messageId/thread/read", chatHandler.MarkThreadAsRead)
+			chat.POST("/messages/:messageId/reactions", chatHandler.AddReaction)
+			chat.DELETE("/messages/:messageId/reactions/:emoji", chatHandler.RemoveReaction)
+			chat.POST("/messages/:messageId/pin", chatHandler.PinMessage)
+			chat.DELETE("/messages/:messageId/pin", chatHandler.UnpinMessage)
+
+			// Moderation; the service checks the caller's room role
+			chat.POST("/messages/:messageId/remove", chatHandler.RemoveMessage)
+			chat.POST("/rooms/:roomId/mutes", chatHandler.MuteMember)
+			chat.DELETE("/rooms/:roomId/mutes/:userId", chatHandler.UnmuteMember)
+			chat.GET("/rooms/:roomId/bans", chatHandler.ListBans)
+			chat.POST("/rooms/:roomId/bans", chatHandler.BanMember)
+			chat.DELETE("/rooms/:roomId/bans/:userId", chatHandler.UnbanMember)
+			chat.PUT("/rooms/:roomId/settings", chatHandler.UpdateRoomSettings)
+			chat.GET("/rooms/:roomId/moderation-log", chatHandler.ListModerationLog)
 		}
 
 		// Analytics
@@ -402,6 +540,15 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 		superadmin.DELETE("/tenants/:id", superadminTenantsHandler.DeleteTenant)
 		superadmin.POST("/tenants/:id/logo", superadminTenantsHandler.UploadLogo)
 		superadmin.PUT("/tenants/:id/services", superadminTenantsHandler.UpdateTenantServices)
+		registerPlaybookRoutes(superadmin.Group("/tenants/:id/playbook"), playbookHandler)
+		superadmin.GET("/tenants/:id/oidc", oidcHandler.GetConfig)
+		superadmin.PUT("/tenants/:id/oidc", oidcHandler.SaveConfig)
+		superadmin.DELETE("/tenants/:id/oidc", oidcHandler.DeleteConfig)
+		superadmin.GET("/tenants/:id/saml", samlHandler.GetConfig)
+		superadmin.PUT("/tenants/:id/saml", samlHandler.SaveConfig)
+		superadmin.DELETE("/tenants/:id/saml", samlHandler.DeleteConfig)
+		superadmin.GET("/tenants/:id/2fa", mfaHandler.GetPolicy)
+		superadmin.PUT("/tenants/:id/2fa", mfaHandler.SavePolicy)
 
 		// Admins management
 		superadmin.GET("/admins", superadminAdminsHandler.ListAdmins)
@@ -410,6 +557,7 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 		superadmin.PUT("/admins/:id", superadminAdminsHandler.UpdateAdmin)
 		superadmin.DELETE("/admins/:id", superadminAdminsHandler.DeleteAdmin)
 		superadmin.POST("/admins/:id/reset-password", superadminAdminsHandler.ResetPassword)
+		superadmin.DELETE("/users/:id/2fa", mfaHandler.Reset)
 
 		// Activity logs
 		superadmin.GET("/logs", superadminLogsHandler.ListLogs)
@@ -428,3 +576,16 @@ func BuildAPI(r *gin.Engine, db *sqlx.DB, cfg config.AppConfig, playbookManager
 
 	return r
 }
+
+// registerPlaybookRoutes mounts the playbook version management endpoints
+func registerPlaybookRoutes(g *gin.RouterGroup, h *PlaybookHandler) {
+	g.GET("/versions", h.ListVersions)
+	g.POST("/versions", h.Upload)
+	g.GET("/versions/:versionId", h.GetVersion)
+	g.GET("/versions/:versionId/diff", h.Diff)
+	g.POST("/versions/:versionId/activate", h.Activate)
+	g.POST("/versions/:versionId/migrate", h.Migrate)
+	g.POST("/validate", h.Validate)
+	g.POST("/rollback", h.Rollback)
+	g.GET("/activations", h.ListActivations)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeysHandler lets tenant admins issue and revoke API keys for
+// integrations.
+type APIKeysHandler struct {
+	svc *services.APIKeyService
+}
+
+func NewAPIKeysHandler(svc *services.APIKeyService) *APIKeysHandler {
+	return &APIKeysHandler{svc: svc}
+}
+
+// Scopes lists the scopes a key can be given.
+// GET /api/admin/api-keys/scopes
+func (h *APIKeysHandler) Scopes(c *gin.Context) {
+	type scopeView struct {
+		Scope       string `json:"scope"`
+		Description string `json:"description"`
+	}
+	scopes := make([]scopeView, 0, len(models.APIKeyScopes))
+	for scope, desc := range models.APIKeyScopes {
+		scopes = append(scopes, scopeView{Scope: scope, Description: desc})
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].Scope < scopes[j].Scope })
+	c.JSON(http.StatusOK, gin.H{"scopes": scopes})
+}
+
+// List returns the tenant's keys, without the keys themselves.
+// GET /api/admin/api-keys
+func (h *APIKeysHandler) List(c *gin.Context) {
+	keys, err := h.svc.List(c.Request.Context(), middleware.GetTenantID(c))
+	if err != nil {
+		log.Printf("[APIKeysHandler.List] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// Create issues a key acting as the caller. The key is in the response
+// this once and cannot be retrieved later.
+// POST /api/admin/api-keys
+func (h *APIKeysHandler) Create(c *gin.Context) {
+	var req services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	role, _ := sessionRole(c)
+	key, token, err := h.svc.Create(c.Request.Context(), middleware.GetTenantID(c), c.GetString("userID"), role, req)
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrAPIKeyInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("[APIKeysHandler.Create] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API key"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"api_key": key, "token": token})
+}
+
+// Revoke disables a key at once.
+// DELETE /api/admin/api-keys/:id
+func (h *APIKeysHandler) Revoke(c *gin.Context) {
+	id := c.Param("id")
+	err := h.svc.Revoke(c.Request.Context(), middleware.GetTenantID(c), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[APIKeysHandler.Revoke] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API key"})
+		return
+	}
+	log.Printf("[APIKeysHandler.Revoke] admin=%s revoked API key=%s", c.GetString("userID"), id)
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeysHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &MemAPIKeyRepository{}
+	h := handlers.NewAPIKeysHandler(services.NewAPIKeyService(store))
+
+	role := "admin"
+	r := gin.New()
+	// Stands in for AuthMiddleware and TenantMiddleware
+	adm := r.Group("/api/admin/api-keys", func(c *gin.Context) {
+		c.Set("userID", "u1")
+		c.Set(middleware.TenantIDContextKey, "t1")
+		c.Set("claims", jwt.MapClaims{"sub": "u1", "role": role, "tenant_id": "t1"})
+	})
+	adm.GET("", h.List)
+	adm.GET("/scopes", h.Scopes)
+	adm.POST("", h.Create)
+	adm.DELETE("/:id", h.Revoke)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/admin/api-keys/scopes", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"scope":"students:read"`)
+
+	w = do(http.MethodPost, "/api/admin/api-keys", `{"name":"registrar","scopes":["students:admin"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPost, "/api/admin/api-keys", `{"name":"registrar","scopes":["students:read","journey:read"],"expires_in_days":30}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Key   models.APIKey `json:"api_key"`
+		Token string        `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Token, models.APIKeyPrefix))
+	assert.Equal(t, "t1", created.Key.TenantID)
+	assert.NotContains(t, w.Body.String(), "key_hash")
+
+	// The key itself is never listed
+	w = do(http.MethodGet, "/api/admin/api-keys", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"registrar"`)
+	assert.NotContains(t, w.Body.String(), created.Token)
+
+	w = do(http.MethodDelete, "/api/admin/api-keys/k1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do(http.MethodDelete, "/api/admin/api-keys/k1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Superadmins have no tenant role for the key to act with
+	role = "superadmin"
+	w = do(http.MethodPost, "/api/admin/api-keys", `{"name":"registrar","scopes":["students:read"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -61,7 +63,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	tenant := middleware.GetTenant(c)
 
 	// Delegate to Service
-	resp, err := h.authService.Login(c.Request.Context(), req.Username, req.Password, tenantID)
+	resp, err := h.authService.Login(sessionContext(c), req.Username, req.Password, tenantID)
 	if err != nil {
 		// Log error for debugging but don't expose detail unless it's safe
 		// Service returns "invalid credentials" which is safe.
@@ -77,15 +79,64 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Reset rate limit on success
 	h.rateLimiter.Reset(c.Request.Context(), req.Username)
 
-	// Set HttpOnly Cookie
-	maxAge := h.cfg.JWTExpDays * 24 * 60 * 60
-	isSecure := strings.HasPrefix(h.cfg.ServerURL, "https")
+	// The password was right, but the session waits for the second factor
+	// (POST /api/auth/2fa/verify)
+	if resp.MFA != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":        true,
+			"mfa_token":           resp.MFA.Token,
+			"enrollment_required": resp.MFA.Enroll,
+		})
+		return
+	}
+
+	setSessionCookie(c, h.cfg, resp)
+
+	// Build response with tenant info but WITHOUT token in body
+	response := gin.H{
+		"message":       "Login successful",
+		"role":          resp.Role,
+		"is_superadmin": resp.IsSuperadmin,
+	}
+	if tenant != nil {
+		response["tenant"] = gin.H{
+			"id":   tenant.ID,
+			"slug": tenant.Slug,
+			"name": tenant.Name,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// refreshCookie holds the refresh token. It is only sent to the auth
+// routes, which renew and end sessions.
+const (
+	refreshCookie     = "refresh_token"
+	refreshCookiePath = "/api/auth"
+)
+
+// sessionContext is the request context carrying the signing-in device,
+// for the session a login opens.
+func sessionContext(c *gin.Context) context.Context {
+	return services.WithSessionClient(c.Request.Context(), services.SessionClient{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+}
+
+// setSessionCookie stores the access token in the HttpOnly cookie read by
+// AuthMiddleware and, when there is a new one, the refresh token in its
+// own cookie. Password and SSO logins and refreshes share it.
+func setSessionCookie(c *gin.Context, cfg config.AppConfig, session *services.LoginResponse) {
+	maxAge := int(cfg.AccessTokenTTL().Seconds())
+	isSecure := strings.HasPrefix(cfg.ServerURL, "https")
 	sameSite := http.SameSiteLaxMode
 	
 	// For localhost development, use Lax mode (works with HTTP)
 	// SameSite=None requires Secure=true (HTTPS), which breaks on http://localhost
 	// Only use None+Secure for cross-domain production deployments
-	if h.cfg.Env != "development" && isSecure {
+	if cfg.Env != "development" && isSecure {
 		// Production with HTTPS: allow cross-domain (if needed)
 		sameSite = http.SameSiteNoneMode
 	}
@@ -102,25 +153,36 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// For localhost development with subdomains, we stick to host-only cookies (domain="")
 	// as this is the most compatible across different subdomains on the same port.
-	log.Printf("[AuthHandler.Login] Attempting set cookie. host=%s, domain=%q, isSecure=%v, sameSite=%v", host, cookieDomain, isSecure, sameSite)
+	log.Printf("[setSessionCookie] Attempting set cookie. host=%s, domain=%q, isSecure=%v, sameSite=%v", host, cookieDomain, isSecure, sameSite)
 
-	c.SetCookie("jwt_token", resp.Token, maxAge, "/", cookieDomain, isSecure, true)
+	c.SetCookie("jwt_token", session.Token, maxAge, "/", cookieDomain, isSecure, true)
+	if session.RefreshToken != "" {
+		c.SetCookie(refreshCookie, session.RefreshToken, int(cfg.SessionTTL().Seconds()), refreshCookiePath, cookieDomain, isSecure, true)
+	}
+}
 
-	// Build response with tenant info but WITHOUT token in body
-	response := gin.H{
-		"message":       "Login successful",
-		"role":          resp.Role,
-		"is_superadmin": resp.IsSuperadmin,
+// Refresh renews the access token with the refresh token cookie, which is
+// replaced in turn. A refresh token works once.
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	token, _ := c.Cookie(refreshCookie)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing refresh token"})
+		return
 	}
-	if tenant != nil {
-		response["tenant"] = gin.H{
-			"id":   tenant.ID,
-			"slug": tenant.Slug,
-			"name": tenant.Name,
-		}
+	resp, err := h.authService.Refresh(c.Request.Context(), token)
+	if errors.Is(err, services.ErrSessionInvalid) {
+		clearSessionCookies(c, h.cfg)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
 	}
-
-	c.JSON(http.StatusOK, response)
+	if err != nil {
+		log.Printf("[AuthHandler.Refresh] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		return
+	}
+	setSessionCookie(c, h.cfg, resp)
+	c.JSON(http.StatusOK, gin.H{"role": resp.Role, "is_superadmin": resp.IsSuperadmin})
 }
 
 
@@ -156,18 +218,23 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Ideally we invalidate cache here too, but we need UserID for that.
-	// Service reset password doesn't return UserID currently. 
-	// We can add it if needed, but Redis cache usually expires naturally or handled on next login.
-	// Handlers previous logic did `rds.Del(..., "user:"+userID)`. 
-	// I'll accept this drawback for now or improve Service later.
-
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
-// Logout clears the http-only cookie
+// Logout ends the session of the refresh token cookie and clears the
+// http-only cookies
 func (h *AuthHandler) Logout(c *gin.Context) {
-	isSecure := strings.HasPrefix(h.cfg.ServerURL, "https")
+	token, _ := c.Cookie(refreshCookie)
+	if err := h.authService.Logout(c.Request.Context(), token); err != nil {
+		log.Printf("[AuthHandler.Logout] failed to end session: %v", err)
+	}
+	clearSessionCookies(c, h.cfg)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// clearSessionCookies removes the access and refresh token cookies
+func clearSessionCookies(c *gin.Context, cfg config.AppConfig) {
+	isSecure := strings.HasPrefix(cfg.ServerURL, "https")
 	sameSite := http.SameSiteLaxMode
 	
 	origin := c.Request.Header.Get("Origin")
@@ -177,17 +244,17 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	}
 	
 	// Match Login cookie settings logic exactly
-	if h.cfg.Env != "development" && isSecure {
+	if cfg.Env != "development" && isSecure {
 		sameSite = http.SameSiteNoneMode
 	}
 	
-	log.Printf("[AuthHandler.Logout] Attempting logout. host=%s, origin=%s, isSecure=%v, sameSite=%v", c.Request.Host, origin, isSecure, sameSite)
+	log.Printf("[clearSessionCookies] Clearing cookies. host=%s, origin=%s, isSecure=%v, sameSite=%v", c.Request.Host, origin, isSecure, sameSite)
 	
 	// Debug: log all received cookies to identify which one we need to clear
 	cookies := c.Request.Cookies()
-	log.Printf("[AuthHandler.Logout] Received %d cookies", len(cookies))
+	log.Printf("[clearSessionCookies] Received %d cookies", len(cookies))
 	for _, cookie := range cookies {
-		log.Printf("[AuthHandler.Logout]   Cookie in request: name=%s, len(value)=%d", cookie.Name, len(cookie.Value))
+		log.Printf("[clearSessionCookies]   Cookie in request: name=%s, len(value)=%d", cookie.Name, len(cookie.Value))
 	}
 	
 	c.SetSameSite(sameSite)
@@ -208,26 +275,28 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		}
 	}
 
-	log.Printf("[AuthHandler.Logout] Clearing jwt_token for domains: %v", domainsToClear)
+	log.Printf("[clearSessionCookies] Clearing session cookies for domains: %v", domainsToClear)
 	
+	sessionCookies := [][2]string{{"jwt_token", "/"}, {refreshCookie, refreshCookiePath}}
 	for _, d := range domainsToClear {
-		// Try Lax/Insecure (common for dev)
-		c.SetSameSite(http.SameSiteLaxMode)
-		c.SetCookie("jwt_token", "", -1, "/", d, false, true)
-		
-		// Try None/Secure (what the user's dump shows)
-		c.SetSameSite(http.SameSiteNoneMode)
-		c.SetCookie("jwt_token", "", -1, "/", d, true, true)
-		
-		// Try Strict
-		c.SetSameSite(http.SameSiteStrictMode)
-		c.SetCookie("jwt_token", "", -1, "/", d, false, true)
+		for _, cookie := range sessionCookies {
+			name, path := cookie[0], cookie[1]
+			// Try Lax/Insecure (common for dev)
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(name, "", -1, path, d, false, true)
+
+			// Try None/Secure (what the user's dump shows)
+			c.SetSameSite(http.SameSiteNoneMode)
+			c.SetCookie(name, "", -1, path, d, true, true)
+
+			// Try Strict
+			c.SetSameSite(http.SameSiteStrictMode)
+			c.SetCookie(name, "", -1, path, d, false, true)
+		}
 	}
 	
 	// Reset SameSite for the final response just in case
 	c.SetSameSite(sameSite)
-	
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
 
 // Keeping Deprecated NewAuthHandler signature support requires adapter in api.go,
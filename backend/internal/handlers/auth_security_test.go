@@ -43,7 +43,7 @@ func TestAuthHandler_Login_Security(t *testing.T) {
 	rds := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
 	
 	repo := repository.NewSQLUserRepository(db)
-	authService := services.NewAuthService(repo, services.NewEmailService(), cfg)
+	authService := services.NewAuthService(repo, repository.NewSQLSessionRepository(db), nil, services.NewEmailService(), cfg)
 	authHandler := NewAuthHandler(authService, cfg, rds)
 
 	// Clean limit for this user
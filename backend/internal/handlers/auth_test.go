@@ -37,7 +37,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1}
 	
 	repo := repository.NewSQLUserRepository(db)
-	authService := services.NewAuthService(repo, services.NewEmailService(), cfg)
+	authService := services.NewAuthService(repo, repository.NewSQLSessionRepository(db), nil, services.NewEmailService(), cfg)
 	h := handlers.NewAuthHandler(authService, cfg, nil)
 
 	// Setup Gin
@@ -166,7 +166,7 @@ func TestAuthHandler_PasswordReset(t *testing.T) {
 	cfg := config.AppConfig{JWTSecret: "secret"}
 	
 	repo := repository.NewSQLUserRepository(db)
-	authService := services.NewAuthService(repo, services.NewEmailService(), cfg)
+	authService := services.NewAuthService(repo, repository.NewSQLSessionRepository(db), nil, services.NewEmailService(), cfg)
 	h := handlers.NewAuthHandler(authService, cfg, nil)
 
 	r := gin.New()
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -175,8 +176,7 @@ func (h *ChatHandler) CreateMessage(c *gin.Context) {
 	
 	msg, err := h.svc.CreateMessage(c.Request.Context(), roomID, uid, req.Body, req.Attachments, importance, req.Meta)
 	if err != nil {
-		log.Printf("Failed to create message: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create message"})
+		respondChatError(c, err, "failed to create message")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": msg})
@@ -233,6 +233,10 @@ func (h *ChatHandler) AddMember(c *gin.Context) {
 		return
 	}
 	if err := h.svc.AddMember(c.Request.Context(), roomID, req.UserID, role); err != nil {
+		if errors.Is(err, services.ErrChatBanned) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("Failed to add member: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add member"})
 		return
@@ -320,9 +324,15 @@ func (h *ChatHandler) RemoveRoomMembersBatch(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"removed_count": count})
 }
 
-// UploadFile handles multipart file upload.
+// UploadFile handles multipart file upload to the local upload directory.
+// It is only used when object storage is not configured; otherwise clients
+// go through PresignUpload/AttachUpload.
 func (h *ChatHandler) UploadFile(c *gin.Context) {
 	roomID := c.Param("roomId")
+	if h.svc.StorageEnabled() {
+		c.JSON(http.StatusGone, gin.H{"error": "direct uploads are disabled; use uploads/presign and uploads/attach"})
+		return
+	}
 	
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -345,7 +355,8 @@ func (h *ChatHandler) UploadFile(c *gin.Context) {
 	})
 }
 
-// DownloadFile handles file download.
+// DownloadFile serves files uploaded before chat moved to object storage
+// that have not been migrated yet.
 func (h *ChatHandler) DownloadFile(c *gin.Context) {
 	roomID := c.Param("roomId")
 	filename := c.Param("filename")
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RemoveMessage (room admin/moderator): deletes another member's message.
+func (h *ChatHandler) RemoveMessage(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.RemoveMessage(c.Request.Context(), c.Param("messageId"), uid, req.Reason); err != nil {
+		respondChatError(c, err, "failed to remove message")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// MuteMember (room admin/moderator): stops a member from posting for a while.
+func (h *ChatHandler) MuteMember(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req struct {
+		UserID  string `json:"user_id" binding:"required"`
+		Minutes int    `json:"minutes" binding:"required,min=1"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	until, err := h.svc.MuteMember(c.Request.Context(), c.Param("roomId"), uid, req.UserID, time.Duration(req.Minutes)*time.Minute, req.Reason)
+	if err != nil {
+		respondChatError(c, err, "failed to mute member")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"muted_until": until})
+}
+
+// UnmuteMember (room admin/moderator): lifts a mute.
+func (h *ChatHandler) UnmuteMember(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.svc.UnmuteMember(c.Request.Context(), c.Param("roomId"), uid, c.Param("userId")); err != nil {
+		respondChatError(c, err, "failed to unmute member")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ListBans (room admin/moderator): returns the room's bans.
+func (h *ChatHandler) ListBans(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	bans, err := h.svc.ListBans(c.Request.Context(), c.Param("roomId"), uid)
+	if err != nil {
+		respondChatError(c, err, "failed to list bans")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bans": bans})
+}
+
+// BanMember (room admin/moderator): removes a user and keeps them out.
+func (h *ChatHandler) BanMember(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ban, err := h.svc.BanMember(c.Request.Context(), c.Param("roomId"), uid, req.UserID, req.Reason)
+	if err != nil {
+		respondChatError(c, err, "failed to ban member")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ban": ban})
+}
+
+// UnbanMember (room admin/moderator): lifts a ban.
+func (h *ChatHandler) UnbanMember(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.svc.UnbanMember(c.Request.Context(), c.Param("roomId"), uid, c.Param("userId")); err != nil {
+		respondChatError(c, err, "failed to unban member")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// UpdateRoomSettings (room admin): sets the post policy and retention.
+func (h *ChatHandler) UpdateRoomSettings(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req services.ChatRoomSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	room, err := h.svc.UpdateRoomSettings(c.Request.Context(), c.Param("roomId"), uid, req)
+	if err != nil {
+		respondChatError(c, err, "failed to update room settings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"room": room})
+}
+
+// ListModerationLog (room admin/moderator): returns the room's moderation
+// log, newest first.
+func (h *ChatHandler) ListModerationLog(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	before, err := parseTimePtr(c.Query("before"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'before' timestamp"})
+		return
+	}
+	entries, err := h.svc.ListModerationLog(c.Request.Context(), c.Param("roomId"), uid, parseLimit(c.Query("limit"), 50), before)
+	if err != nil {
+		respondChatError(c, err, "failed to list moderation log")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
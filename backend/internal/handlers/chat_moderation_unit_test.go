@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatModerationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := NewMemChatRepository(map[string]models.ChatRoomMemberRole{
+		"owner": models.ChatRoomMemberRoleAdmin,
+		"mod":   models.ChatRoomMemberRoleModerator,
+		"alice": models.ChatRoomMemberRoleMember,
+	})
+	h := handlers.NewChatHandler(services.NewChatService(repo, nil, config.AppConfig{}), config.AppConfig{})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	r.POST("/chat/rooms/:roomId/messages", h.CreateMessage)
+	r.POST("/chat/rooms/:roomId/mutes", h.MuteMember)
+	r.PUT("/chat/rooms/:roomId/settings", h.UpdateRoomSettings)
+	r.POST("/chat/messages/:messageId/remove", h.RemoveMessage)
+
+	do := func(method, user, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Mute", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "alice", "/chat/rooms/r1/mutes", `{"user_id":"mod","minutes":10}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "mod", "/chat/rooms/r1/mutes", `{"user_id":"alice"}`).Code)
+
+		w := do(http.MethodPost, "mod", "/chat/rooms/r1/mutes", `{"user_id":"alice","minutes":10,"reason":"spam"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			MutedUntil time.Time `json:"muted_until"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), resp.MutedUntil, 5*time.Second)
+		assert.Equal(t, []string{models.ChatModMute}, repo.log)
+
+		w = do(http.MethodPost, "alice", "/chat/rooms/r1/messages", `{"body":"hello"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "muted")
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPut, "mod", "/chat/rooms/r1/settings", `{"retention_days":30}`).Code)
+		w := do(http.MethodPut, "owner", "/chat/rooms/r1/settings", `{"post_policy":"moderators"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "only channels")
+	})
+
+	t.Run("Remove message", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "mod", "/chat/messages/gone/remove", "").Code)
+	})
+}
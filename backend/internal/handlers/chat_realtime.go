@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxFrameSize   = 4 << 10
+	sseHeartbeatTick = 25 * time.Second
+)
+
+// ChatRealtimeHandler streams room events (new, edited and deleted
+// messages, read receipts, typing) to room members over WebSocket or SSE.
+type ChatRealtimeHandler struct {
+	svc      *services.ChatService
+	hub      *services.ChatHub
+	upgrader websocket.Upgrader
+}
+
+// NewChatRealtimeHandler builds the gateway. allowOrigin applies the same
+// origin policy as CORS to browser WebSocket handshakes; nil keeps the
+// library's same-host check.
+func NewChatRealtimeHandler(svc *services.ChatService, hub *services.ChatHub, allowOrigin func(origin string) bool) *ChatRealtimeHandler {
+	h := &ChatRealtimeHandler{svc: svc, hub: hub}
+	if allowOrigin != nil {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || allowOrigin(origin)
+		}
+	}
+	return h
+}
+
+// chatClientFrame is what a WebSocket client may send: {"type":"typing"}
+// or {"type":"read"}.
+type chatClientFrame struct {
+	Type string `json:"type"`
+}
+
+// WebSocket upgrades GET /chat/rooms/:roomId/ws for a room member.
+func (h *ChatRealtimeHandler) WebSocket(c *gin.Context) {
+	roomID, uid, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written the handshake error
+		log.Printf("[ChatWS] Upgrade failed for room %s: %v", roomID, err)
+		return
+	}
+	defer conn.Close()
+
+	sub := h.hub.Subscribe(roomID)
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(wsMaxFrameSize)
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var frame chatClientFrame
+			if json.Unmarshal(data, &frame) != nil {
+				continue
+			}
+			switch frame.Type {
+			case services.ChatEventTyping:
+				h.svc.Typing(ctx, roomID, uid)
+			case "read":
+				if err := h.svc.MarkRoomAsRead(ctx, roomID, uid); err != nil {
+					log.Printf("[ChatWS] Failed to mark room %s as read: %v", roomID, err)
+				}
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				closeWS(conn, websocket.CloseTryAgainLater, "subscriber fell behind")
+				return
+			}
+			if !deliverChatEvent(ev, uid) {
+				continue
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+			if removedFromRoom(ev, uid) {
+				closeWS(conn, websocket.ClosePolicyViolation, "removed from room")
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Stream serves GET /chat/rooms/:roomId/events as Server-Sent Events for
+// clients that cannot use WebSockets. Typing goes through POST .../typing.
+func (h *ChatRealtimeHandler) Stream(c *gin.Context) {
+	roomID, uid, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	sub := h.hub.Subscribe(roomID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", gin.H{"room_id": roomID})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatTick)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case ev, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			if deliverChatEvent(ev, uid) {
+				c.SSEvent(ev.Type, ev)
+			}
+			return !removedFromRoom(ev, uid)
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"at": time.Now().UTC()})
+			return true
+		}
+	})
+}
+
+// Typing handles POST /chat/rooms/:roomId/typing.
+func (h *ChatRealtimeHandler) Typing(c *gin.Context) {
+	roomID, uid, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	h.svc.Typing(c.Request.Context(), roomID, uid)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// authorize resolves the caller and checks room membership before any
+// connection is upgraded or streamed.
+func (h *ChatRealtimeHandler) authorize(c *gin.Context) (roomID, uid string, ok bool) {
+	uid = userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", "", false
+	}
+	roomID = c.Param("roomId")
+	isMember, err := h.svc.IsMember(c.Request.Context(), roomID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "membership check failed"})
+		return "", "", false
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this room"})
+		return "", "", false
+	}
+	return roomID, uid, true
+}
+
+// deliverChatEvent skips echoing a user's own typing indicator back to them.
+func deliverChatEvent(ev services.ChatEvent, uid string) bool {
+	return !(ev.Type == services.ChatEventTyping && ev.UserID == uid)
+}
+
+func removedFromRoom(ev services.ChatEvent, uid string) bool {
+	return ev.Type == services.ChatEventMemberRemoved && ev.UserID == uid
+}
+
+func closeWS(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+}
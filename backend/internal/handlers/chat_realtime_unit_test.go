@@ -0,0 +1,177 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRealtimeServer(t *testing.T) (*httptest.Server, *services.ChatService, *services.ChatHub, *MemChatRepository) {
+	gin.SetMode(gin.TestMode)
+	repo := NewMemChatRepository(map[string]models.ChatRoomMemberRole{
+		"alice": models.ChatRoomMemberRoleMember,
+		"bob":   models.ChatRoomMemberRoleMember,
+	})
+	hub := services.NewChatHub(nil)
+	svc := services.NewChatService(repo, nil, config.AppConfig{})
+	svc.SetHub(hub)
+	h := handlers.NewChatRealtimeHandler(svc, hub, func(origin string) bool {
+		return origin == "https://portal.example"
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	r.GET("/chat/rooms/:roomId/ws", h.WebSocket)
+	r.GET("/chat/rooms/:roomId/events", h.Stream)
+	r.POST("/chat/rooms/:roomId/typing", h.Typing)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, svc, hub, repo
+}
+
+func waitSubscribers(t *testing.T, hub *services.ChatHub, roomID string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return hub.Subscribers(roomID) == n }, time.Second, 5*time.Millisecond)
+}
+
+func dialChat(srv *httptest.Server, user, origin string) (*websocket.Conn, *http.Response, error) {
+	header := http.Header{"X-Test-User": {user}}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/chat/rooms/r1/ws", header)
+}
+
+func TestChatRealtime_WebSocket(t *testing.T) {
+	srv, svc, hub, repo := newRealtimeServer(t)
+
+	t.Run("Non-member is rejected before upgrade", func(t *testing.T) {
+		_, resp, err := dialChat(srv, "mallory", "")
+		require.Error(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Foreign origin is rejected", func(t *testing.T) {
+		_, resp, err := dialChat(srv, "alice", "https://evil.example")
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Member receives messages and typing of others", func(t *testing.T) {
+		alice, _, err := dialChat(srv, "alice", "https://portal.example")
+		require.NoError(t, err)
+		defer alice.Close()
+		bob, _, err := dialChat(srv, "bob", "")
+		require.NoError(t, err)
+		defer bob.Close()
+		waitSubscribers(t, hub, "r1", 2)
+
+		_, err = svc.CreateMessage(context.Background(), "r1", "bob", "hi", nil, nil, nil)
+		require.NoError(t, err)
+		var ev services.ChatEvent
+		require.NoError(t, alice.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, alice.ReadJSON(&ev))
+		assert.Equal(t, services.ChatEventMessageCreated, ev.Type)
+		assert.Equal(t, "hi", ev.Message.Body)
+
+		require.NoError(t, bob.WriteJSON(map[string]string{"type": "typing"}))
+		require.NoError(t, alice.ReadJSON(&ev))
+		assert.Equal(t, services.ChatEventTyping, ev.Type)
+		assert.Equal(t, "bob", ev.UserID)
+
+		require.NoError(t, alice.WriteJSON(map[string]string{"type": "read"}))
+		require.NoError(t, bob.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, bob.ReadJSON(&ev))
+		assert.Equal(t, "bob", ev.UserID, "bob got his own message first")
+		require.NoError(t, bob.ReadJSON(&ev))
+		assert.Equal(t, services.ChatEventRead, ev.Type, "own typing is not echoed")
+		assert.Equal(t, []string{"alice"}, repo.read)
+	})
+
+	t.Run("Removed member is disconnected", func(t *testing.T) {
+		waitSubscribers(t, hub, "r1", 0)
+		bob, _, err := dialChat(srv, "bob", "")
+		require.NoError(t, err)
+		defer bob.Close()
+		waitSubscribers(t, hub, "r1", 1)
+
+		require.NoError(t, svc.RemoveMember(context.Background(), "r1", "bob"))
+		var ev services.ChatEvent
+		require.NoError(t, bob.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, bob.ReadJSON(&ev))
+		assert.Equal(t, services.ChatEventMemberRemoved, ev.Type)
+		_, _, err = bob.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "got %v", err)
+	})
+}
+
+func TestChatRealtime_SSE(t *testing.T) {
+	srv, svc, hub, _ := newRealtimeServer(t)
+
+	get := func(user string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/chat/rooms/r1/events", nil)
+		req.Header.Set("X-Test-User", user)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get("mallory")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = get("alice")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	waitSubscribers(t, hub, "r1", 1)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/chat/rooms/r1/typing", nil)
+	req.Header.Set("X-Test-User", "bob")
+	typing, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	typing.Body.Close()
+	assert.Equal(t, http.StatusOK, typing.StatusCode)
+	_, err = svc.CreateMessage(context.Background(), "r1", "bob", "hi", nil, nil, nil)
+	require.NoError(t, err)
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	var events []string
+	for len(events) < 3 {
+		select {
+		case line := <-lines:
+			if strings.HasPrefix(line, "event:") {
+				events = append(events, strings.TrimSpace(strings.TrimPrefix(line, "event:")))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("stream stalled after %v", events)
+		}
+	}
+	assert.Equal(t, []string{"ready", services.ChatEventTyping, services.ChatEventMessageCreated}, events)
+}
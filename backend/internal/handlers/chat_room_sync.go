@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoomSyncReport (admin): shows what syncing the tenant's cohort and
+// advisory rooms would change, without changing anything.
+func (h *ChatHandler) RoomSyncReport(c *gin.Context) {
+	h.syncRooms(c, true)
+}
+
+// SyncRooms (admin): reconciles the tenant's cohort and advisory rooms now.
+// ?dry_run=true only reports, like RoomSyncReport.
+func (h *ChatHandler) SyncRooms(c *gin.Context) {
+	h.syncRooms(c, c.Query("dry_run") == "true")
+}
+
+func (h *ChatHandler) syncRooms(c *gin.Context, dryRun bool) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	tenantID := c.GetString("tenant_id")
+	if tenantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenant context required"})
+		return
+	}
+	report, err := h.svc.SyncRooms(c.Request.Context(), tenantID, uid, dryRun)
+	if err != nil && report == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sync rooms"})
+		return
+	}
+	if err != nil {
+		// Some rooms failed; the report still shows what was attempted
+		c.JSON(http.StatusMultiStatus, gin.H{"report": report, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
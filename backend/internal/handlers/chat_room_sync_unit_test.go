@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roomSyncRepo has one cohort with one student and no managed rooms yet.
+type roomSyncRepo struct {
+	repository.ChatRepository
+	created []string
+}
+
+func (r *roomSyncRepo) ListCohortRoomSpecs(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	return []models.ChatManagedRoom{{
+		Kind: models.ChatManagedCohort, Ref: "c1", Name: "Cohort 2024",
+		Members: []models.ChatManagedMember{{UserID: "s1", Role: models.ChatRoomMemberRoleMember}},
+	}}, nil
+}
+
+func (r *roomSyncRepo) ListAdvisoryRoomSpecs(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	return nil, nil
+}
+
+func (r *roomSyncRepo) ListManagedRooms(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	return nil, nil
+}
+
+func (r *roomSyncRepo) ListAdoptableRooms(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	return nil, nil
+}
+
+func (r *roomSyncRepo) CreateManagedRoom(ctx context.Context, tenantID string, room models.ChatManagedRoom, ownerID string) (string, error) {
+	r.created = append(r.created, room.Ref)
+	return "r1", nil
+}
+
+func (r *roomSyncRepo) AddManagedMember(ctx context.Context, tenantID, roomID, userID string, role models.ChatRoomMemberRole) error {
+	return nil
+}
+
+func TestChatRoomSyncHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &roomSyncRepo{}
+	h := handlers.NewChatHandler(services.NewChatService(repo, nil, config.AppConfig{}), config.AppConfig{})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": "admin"})
+		if tenant := c.GetHeader("X-Tenant"); tenant != "" {
+			c.Set("tenant_id", tenant)
+		}
+		c.Next()
+	})
+	r.GET("/admin/chat/room-sync", h.RoomSyncReport)
+	r.POST("/admin/chat/room-sync", h.SyncRooms)
+
+	do := func(method, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/chat/room-sync", nil)
+		req.Header.Set("X-Tenant", tenant)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	var resp struct {
+		Report services.ChatRoomSyncReport `json:"report"`
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "").Code)
+
+	w := do(http.MethodGet, "t1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Report.DryRun)
+	assert.Equal(t, 1, resp.Report.Created)
+	assert.Empty(t, repo.created, "the report changes nothing")
+
+	w = do(http.MethodPost, "t1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Report.DryRun)
+	assert.Equal(t, "r1", resp.Report.Rooms[0].RoomID)
+	assert.Equal(t, []string{"c1"}, repo.created)
+}
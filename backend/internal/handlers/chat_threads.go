@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ListThreads returns the room's threads the caller follows with unread counts.
+func (h *ChatHandler) ListThreads(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	roomID := c.Param("roomId")
+	isMember, err := h.svc.IsMember(c.Request.Context(), roomID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "membership check failed"})
+		return
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this room"})
+		return
+	}
+	threads, err := h.svc.ListThreads(c.Request.Context(), roomID, uid)
+	if err != nil {
+		log.Printf("[ListThreads] Failed for room %s: %v", roomID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list threads"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"threads": threads})
+}
+
+// ListReplies returns a thread: the parent message and its replies.
+func (h *ChatHandler) ListReplies(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	after, err := parseTimePtr(c.Query("after"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'after' timestamp"})
+		return
+	}
+	parent, replies, err := h.svc.GetThread(c.Request.Context(), c.Param("messageId"), uid, parseLimit(c.Query("limit"), 50), after)
+	if err != nil {
+		respondChatError(c, err, "failed to load thread")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"parent": parent, "replies": replies})
+}
+
+// CreateReply posts a reply in a message's thread.
+func (h *ChatHandler) CreateReply(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req struct {
+		Body        string                 `json:"body"`
+		Attachments models.ChatAttachments `json:"attachments"`
+		Meta        json.RawMessage        `json:"meta"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Body == "" && len(req.Attachments) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message must have body or attachments"})
+		return
+	}
+	reply, err := h.svc.CreateReply(c.Request.Context(), c.Param("messageId"), uid, req.Body, req.Attachments, req.Meta)
+	if err != nil {
+		respondChatError(c, err, "failed to create reply")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": reply})
+}
+
+// MarkThreadAsRead clears the caller's unread replies in a thread.
+func (h *ChatHandler) MarkThreadAsRead(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.svc.MarkThreadAsRead(c.Request.Context(), c.Param("messageId"), uid); err != nil {
+		respondChatError(c, err, "failed to mark as read")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// AddReaction adds the caller's emoji to a message.
+func (h *ChatHandler) AddReaction(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req struct {
+		Emoji string `json:"emoji" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reactions, err := h.svc.AddReaction(c.Request.Context(), c.Param("messageId"), uid, req.Emoji)
+	if err != nil {
+		respondChatError(c, err, "failed to add reaction")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reactions": reactions})
+}
+
+// RemoveReaction takes the caller's emoji off a message.
+func (h *ChatHandler) RemoveReaction(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	reactions, err := h.svc.RemoveReaction(c.Request.Context(), c.Param("messageId"), uid, c.Param("emoji"))
+	if err != nil {
+		respondChatError(c, err, "failed to remove reaction")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reactions": reactions})
+}
+
+// PinMessage (room admin/moderator): pins a message to its room.
+func (h *ChatHandler) PinMessage(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	msg, err := h.svc.PinMessage(c.Request.Context(), c.Param("messageId"), uid)
+	if err != nil {
+		respondChatError(c, err, "failed to pin message")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": msg})
+}
+
+// UnpinMessage (room admin/moderator): removes a message from the pins.
+func (h *ChatHandler) UnpinMessage(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	msg, err := h.svc.UnpinMessage(c.Request.Context(), c.Param("messageId"), uid)
+	if err != nil {
+		respondChatError(c, err, "failed to unpin message")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": msg})
+}
+
+// ListPinned returns the room's pinned messages.
+func (h *ChatHandler) ListPinned(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	roomID := c.Param("roomId")
+	isMember, err := h.svc.IsMember(c.Request.Context(), roomID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "membership check failed"})
+		return
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this room"})
+		return
+	}
+	msgs, err := h.svc.ListPinned(c.Request.Context(), roomID)
+	if err != nil {
+		log.Printf("[ListPinned] Failed for room %s: %v", roomID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list pinned messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": msgs})
+}
+
+// respondChatError maps chat service errors to HTTP responses; anything
+// unexpected is logged and reported with the fallback message.
+func respondChatError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrChatMessageNotFound), errors.Is(err, services.ErrChatFileNotFound),
+		errors.Is(err, services.ErrChatMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChatNotMember), errors.Is(err, services.ErrChatPinForbidden),
+		errors.Is(err, services.ErrChatModerationForbidden), errors.Is(err, services.ErrChatMuted),
+		errors.Is(err, services.ErrChatReadOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChatBanned):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChatNestedReply), errors.Is(err, services.ErrChatInvalidReaction),
+		errors.Is(err, services.ErrChatInvalidUpload), errors.Is(err, services.ErrChatInvalidSettings):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChatStorageUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		log.Printf("[Chat] %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
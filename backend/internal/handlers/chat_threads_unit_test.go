@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatThreadsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parent := "p1"
+	repo := NewMemChatRepository(map[string]models.ChatRoomMemberRole{
+		"alice": models.ChatRoomMemberRoleMember,
+		"mod":   models.ChatRoomMemberRoleModerator,
+	})
+	repo.messages["p1"] = &models.ChatMessage{ID: "p1", RoomID: "r1"}
+	repo.messages["c1"] = &models.ChatMessage{ID: "c1", RoomID: "r1", ParentID: &parent}
+	h := handlers.NewChatHandler(services.NewChatService(repo, nil, config.AppConfig{}), config.AppConfig{})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	r.POST("/chat/messages/:messageId/replies", h.CreateReply)
+	r.POST("/chat/messages/:messageId/reactions", h.AddReaction)
+	r.POST("/chat/messages/:messageId/pin", h.PinMessage)
+
+	do := func(user, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Pinning needs a moderator or room admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do("alice", "/chat/messages/p1/pin", "").Code)
+		assert.Equal(t, http.StatusForbidden, do("eve", "/chat/messages/p1/pin", "").Code)
+		assert.Nil(t, repo.messages["p1"].PinnedBy)
+
+		w := do("mod", "/chat/messages/p1/pin", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Message models.ChatMessage `json:"message"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "mod", *resp.Message.PinnedBy)
+	})
+
+	t.Run("Reactions", func(t *testing.T) {
+		w := do("alice", "/chat/messages/p1/reactions", `{"emoji":"👍"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"reactions":[{"emoji":"👍","count":1,"user_ids":["alice"]}]}`, w.Body.String())
+
+		assert.Equal(t, http.StatusBadRequest, do("alice", "/chat/messages/p1/reactions", `{"emoji":"not an emoji"}`).Code)
+		assert.Equal(t, http.StatusNotFound, do("alice", "/chat/messages/zz/reactions", `{"emoji":"👍"}`).Code)
+		assert.Equal(t, http.StatusForbidden, do("eve", "/chat/messages/p1/reactions", `{"emoji":"👍"}`).Code)
+	})
+
+	t.Run("Threads are one level deep", func(t *testing.T) {
+		w := do("alice", "/chat/messages/c1/replies", `{"body":"nested"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "top-level")
+		assert.Equal(t, http.StatusBadRequest, do("alice", "/chat/messages/p1/replies", `{}`).Code)
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type chatUploadPresignReq struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	SizeBytes   int64  `json:"size_bytes" binding:"required"`
+}
+
+// PresignUpload returns a URL to PUT a chat file to object storage.
+// POST /api/chat/rooms/:roomId/uploads/presign
+func (h *ChatHandler) PresignUpload(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req chatUploadPresignReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ticket, err := h.svc.PresignUpload(c.Request.Context(), middleware.GetTenantID(c), c.Param("roomId"), uid, req.Filename, req.ContentType, req.SizeBytes)
+	if err != nil {
+		respondChatError(c, err, "failed to presign upload")
+		return
+	}
+	c.JSON(http.StatusOK, ticket)
+}
+
+type chatUploadAttachReq struct {
+	DocumentID  string `json:"document_id" binding:"required"`
+	ObjectKey   string `json:"object_key" binding:"required"`
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	SizeBytes   int64  `json:"size_bytes" binding:"required"`
+}
+
+// AttachUpload confirms an uploaded chat file and returns the attachment to
+// send with the message.
+// POST /api/chat/rooms/:roomId/uploads/attach
+func (h *ChatHandler) AttachUpload(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req chatUploadAttachReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	att, err := h.svc.AttachUpload(c.Request.Context(), middleware.GetTenantID(c), c.Param("roomId"), uid,
+		req.DocumentID, req.ObjectKey, req.Filename, req.ContentType, req.SizeBytes)
+	if err != nil {
+		respondChatError(c, err, "failed to attach upload")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"attachment": att})
+}
+
+// DownloadAttachment returns a short-lived link to a stored chat file.
+// GET /api/chat/attachments/:versionId
+func (h *ChatHandler) DownloadAttachment(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	url, expiresAt, err := h.svc.DownloadURL(c.Request.Context(), c.Param("versionId"), uid)
+	if err != nil {
+		respondChatError(c, err, "failed to presign download")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": url, "expires_at": expiresAt})
+}
@@ -61,7 +61,7 @@ func TestDataIsolation_BetweenTenants(t *testing.T) {
 	svc := services.NewAdminService(repo, &playbook.Manager{}, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, &playbook.Manager{}, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, &playbook.Manager{}, svc, jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, &playbook.Manager{}, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 
@@ -10,14 +10,16 @@ import (
 )
 
 type JourneyHandler struct {
-	svc *services.JourneyService
+	svc       *services.JourneyService
+	deadlines *services.DeadlineService
 }
 
-func NewJourneyHandler(svc *services.JourneyService) *JourneyHandler {
-	return &JourneyHandler{svc: svc}
+func NewJourneyHandler(svc *services.JourneyService, deadlines *services.DeadlineService) *JourneyHandler {
+	return &JourneyHandler{svc: svc, deadlines: deadlines}
 }
 
 // GET /api/journey/state -> map[node_id]state
+// GET /api/journey/state?include=due_dates -> {"states": map[node_id]state, "due_dates": map[node_id]time}
 func (h *JourneyHandler) GetState(c *gin.Context) {
 	u := userIDFromClaims(c)
 	tenantID := middleware.GetTenantID(c)
@@ -31,7 +33,32 @@ func (h *JourneyHandler) GetState(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "service error"})
 		return
 	}
-	c.JSON(http.StatusOK, state)
+	if c.Query("include") != "due_dates" || h.deadlines == nil {
+		c.JSON(http.StatusOK, state)
+		return
+	}
+	due, err := h.deadlines.DueDates(c.Request.Context(), tenantID, u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "service error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"states": state, "due_dates": due})
+}
+
+// GET /api/journey/conditions -> evaluated playbook conditions and hidden nodes
+func (h *JourneyHandler) GetConditions(c *gin.Context) {
+	u := userIDFromClaims(c)
+	if u == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	res, err := h.svc.GetConditions(c.Request.Context(), u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "service error"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
 }
 
 type setStateReq struct {
@@ -46,7 +46,7 @@ func TestJourneyHandler_GetScoreboard(t *testing.T) {
 	cfg := config.AppConfig{}
 	repo := repository.NewSQLJourneyRepository(db)
 	svc := services.NewJourneyService(repo, pbManager, cfg, nil, nil, nil)
-	h := handlers.NewJourneyHandler(svc)
+	h := handlers.NewJourneyHandler(svc, nil)
 
 	gin.SetMode(gin.TestMode)
 	
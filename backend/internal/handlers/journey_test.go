@@ -37,7 +37,7 @@ func TestJourneyHandler_GetState(t *testing.T) {
 	
 	repo := repository.NewSQLJourneyRepository(db)
 	svc := services.NewJourneyService(repo, pb, cfg, nil, nil, nil)
-	h := handlers.NewJourneyHandler(svc)
+	h := handlers.NewJourneyHandler(svc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -74,7 +74,7 @@ func TestJourneyHandler_SetState(t *testing.T) {
 	
 	repo := repository.NewSQLJourneyRepository(db)
 	svc := services.NewJourneyService(repo, pb, cfg, nil, nil, nil)
-	h := handlers.NewJourneyHandler(svc)
+	h := handlers.NewJourneyHandler(svc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -140,7 +140,7 @@ func TestJourneyHandler_Reset(t *testing.T) {
 
 	repo := repository.NewSQLJourneyRepository(db)
 	svc := services.NewJourneyService(repo, pb, cfg, nil, nil, nil)
-	h := handlers.NewJourneyHandler(svc)
+	h := handlers.NewJourneyHandler(svc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// MFAHandler serves TOTP two-factor authentication: the second step of a
+// password sign-in, users managing their authenticator, and superadmins
+// resetting it and setting the tenants' policy.
+type MFAHandler struct {
+	svc         *services.MFAService
+	adminSvc    *services.SuperAdminService
+	cfg         config.AppConfig
+	rateLimiter *middleware.LoginRateLimiter
+}
+
+// NewMFAHandler builds the handler; adminSvc records superadmin resets in
+// the activity log and may be nil.
+func NewMFAHandler(svc *services.MFAService, adminSvc *services.SuperAdminService, cfg config.AppConfig, rds *redis.Client) *MFAHandler {
+	return &MFAHandler{svc: svc, adminSvc: adminSvc, cfg: cfg, rateLimiter: middleware.NewLoginRateLimiter(rds)}
+}
+
+type mfaChallengeReq struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code"`
+}
+
+type mfaCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Enroll starts the enrollment a sign-in demands when the tenant requires
+// 2FA and the user has none yet.
+// POST /api/auth/2fa/enroll
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	var req mfaChallengeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	enrollment, err := h.svc.BeginChallengeEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		h.fail(c, "Enroll", err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Verify completes a sign-in with a code from the authenticator or a
+// recovery code, and sets the session cookies. When it confirmed a new
+// enrollment the recovery codes are returned, this once.
+// POST /api/auth/2fa/verify
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req mfaChallengeReq
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token and code are required"})
+		return
+	}
+	userID, err := h.svc.ChallengeUser(req.MFAToken)
+	if err != nil {
+		h.fail(c, "Verify", err)
+		return
+	}
+	if !h.allowed(c, userID) {
+		return
+	}
+	resp, codes, err := h.svc.CompleteLogin(sessionContext(c), req.MFAToken, req.Code)
+	h.record(c, userID, err)
+	if err != nil {
+		h.fail(c, "Verify", err)
+		return
+	}
+	setSessionCookie(c, h.cfg, resp)
+	body := gin.H{
+		"message":       "Login successful",
+		"role":          resp.Role,
+		"is_superadmin": resp.IsSuperadmin,
+	}
+	if codes != nil {
+		body["recovery_codes"] = codes
+	}
+	if tenant := middleware.GetTenant(c); tenant != nil {
+		body["tenant"] = gin.H{"id": tenant.ID, "slug": tenant.Slug, "name": tenant.Name}
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// Status returns the caller's 2FA state.
+// GET /api/me/2fa
+func (h *MFAHandler) Status(c *gin.Context) {
+	role, tenantID := sessionRole(c)
+	status, err := h.svc.Status(c.Request.Context(), c.GetString("userID"), role, tenantID)
+	if err != nil {
+		h.fail(c, "Status", err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// BeginEnrollment generates a secret for the caller's authenticator.
+// POST /api/me/2fa/enroll
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	enrollment, err := h.svc.BeginEnrollment(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		h.fail(c, "BeginEnrollment", err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmEnrollment enables 2FA with the first code and returns the
+// recovery codes.
+// POST /api/me/2fa/confirm
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	h.withCode(c, "ConfirmEnrollment", func(userID, code string) (gin.H, error) {
+		codes, err := h.svc.ConfirmEnrollment(c.Request.Context(), userID, code)
+		return gin.H{"recovery_codes": codes}, err
+	})
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes.
+// POST /api/me/2fa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	h.withCode(c, "RegenerateRecoveryCodes", func(userID, code string) (gin.H, error) {
+		codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), userID, code)
+		return gin.H{"recovery_codes": codes}, err
+	})
+}
+
+// Disable turns the caller's 2FA off, unless their role requires it.
+// POST /api/me/2fa/disable
+func (h *MFAHandler) Disable(c *gin.Context) {
+	role, tenantID := sessionRole(c)
+	h.withCode(c, "Disable", func(userID, code string) (gin.H, error) {
+		err := h.svc.Disable(c.Request.Context(), userID, code, role, tenantID)
+		return gin.H{"message": "two-factor authentication disabled"}, err
+	})
+}
+
+// Reset removes a user's second factor, for a user who lost their
+// authenticator and recovery codes.
+// DELETE /api/superadmin/users/:id/2fa
+func (h *MFAHandler) Reset(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.Reset(c.Request.Context(), id); err != nil {
+		h.fail(c, "Reset", err)
+		return
+	}
+	log.Printf("[MFAHandler.Reset] superadmin=%s reset two-factor authentication of user=%s", c.GetString("userID"), id)
+	if h.adminSvc != nil {
+		_ = h.adminSvc.LogActivity(c.Request.Context(), models.ActivityLogParams{
+			UserID:      strPtr(c.GetString("userID")),
+			Action:      "update",
+			EntityType:  "user",
+			EntityID:    id,
+			Description: "Reset two-factor authentication",
+			IPAddress:   c.ClientIP(),
+			UserAgent:   c.Request.UserAgent(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication reset"})
+}
+
+// GetPolicy returns the tenant's 2FA policy.
+// GET /api/superadmin/tenants/:id/2fa
+func (h *MFAHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.svc.GetTenantPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, "GetPolicy", err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SavePolicy sets the tenant's 2FA policy. Admins without 2FA are asked to
+// enroll at their next sign-in.
+// PUT /api/superadmin/tenants/:id/2fa
+func (h *MFAHandler) SavePolicy(c *gin.Context) {
+	var policy models.TenantMFAPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.SaveTenantPolicy(c.Request.Context(), c.Param("id"), &policy); err != nil {
+		h.fail(c, "SavePolicy", err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// withCode runs a step of the caller's that needs a current code, counting
+// wrong codes against the same limit as sign-ins.
+func (h *MFAHandler) withCode(c *gin.Context, op string, fn func(userID, code string) (gin.H, error)) {
+	var req mfaCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.GetString("userID")
+	if !h.allowed(c, userID) {
+		return
+	}
+	body, err := fn(userID, req.Code)
+	h.record(c, userID, err)
+	if err != nil {
+		h.fail(c, op, err)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+func (h *MFAHandler) allowed(c *gin.Context, userID string) bool {
+	allowed, ttl, err := h.rateLimiter.CheckAllowed(c.Request.Context(), "2fa:"+userID)
+	if err != nil {
+		log.Printf("[MFAHandler] rate limit error: %v", err)
+	}
+	if !allowed {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("Too many failed attempts. Try again in %d minutes.", int(ttl.Minutes())),
+		})
+	}
+	return allowed
+}
+
+func (h *MFAHandler) record(c *gin.Context, userID string, err error) {
+	switch {
+	case err == nil:
+		h.rateLimiter.Reset(c.Request.Context(), "2fa:"+userID)
+	case errors.Is(err, services.ErrMFAInvalidCode):
+		h.rateLimiter.RecordFailure(c.Request.Context(), "2fa:"+userID)
+	}
+}
+
+func (h *MFAHandler) fail(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, services.ErrMFAChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFAInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFARequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	default:
+		log.Printf("[MFAHandler.%s] error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "two-factor authentication failed"})
+	}
+}
+
+// sessionRole is the role and tenant the caller's access token was issued
+// for.
+func sessionRole(c *gin.Context) (string, string) {
+	val, ok := c.Get("claims")
+	if !ok {
+		return "", ""
+	}
+	claims, _ := val.(jwt.MapClaims)
+	role, _ := claims["role"].(string)
+	tenantID, _ := claims["tenant_id"].(string)
+	return role, tenantID
+}
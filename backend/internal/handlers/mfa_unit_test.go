@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFA_LoginFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash, _ := auth.HashPassword("pass")
+	users := NewMemUserRepository(&models.User{ID: "u1", Username: "john", PasswordHash: hash, Role: models.RoleStudent, IsActive: true})
+	store := &MemMFARepository{}
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, AccessTokenMinutes: 15, Env: "development"}
+	authService := services.NewAuthService(users, NewMemSessionRepository(), nil, nil, cfg)
+	mfaService := services.NewMFAService(store, authService, cfg)
+	authService.SetSecondFactor(mfaService)
+	h := handlers.NewAuthHandler(authService, cfg, nil)
+	mh := handlers.NewMFAHandler(mfaService, nil, cfg, nil)
+
+	r := gin.New()
+	r.POST("/api/auth/login", h.Login)
+	r.POST("/api/auth/2fa/verify", mh.Verify)
+	// Stands in for AuthMiddleware, which needs the database
+	me := r.Group("/api/me", func(c *gin.Context) {
+		c.Set("userID", "u1")
+		c.Set("claims", jwt.MapClaims{"sub": "u1", "role": "student", "tenant_id": "t1"})
+	})
+	me.GET("/2fa", mh.Status)
+	me.POST("/2fa/enroll", mh.BeginEnrollment)
+	me.POST("/2fa/confirm", mh.ConfirmEnrollment)
+	me.POST("/2fa/disable", mh.Disable)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	hasCookie := func(w *httptest.ResponseRecorder, name string) bool {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name && c.MaxAge > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	code := func(step int64) string {
+		c, err := auth.TOTPCode(store.totp.Secret, auth.TOTPStep(time.Now())+step)
+		require.NoError(t, err)
+		return c
+	}
+
+	// Enroll
+	w := do(http.MethodPost, "/api/me/2fa/enroll", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"otpauth_url":"otpauth://totp/`)
+	w = do(http.MethodPost, "/api/me/2fa/confirm", `{"code":"000000"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(http.MethodPost, "/api/me/2fa/confirm", `{"code":"`+code(-1)+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	assert.Len(t, confirmed.RecoveryCodes, services.RecoveryCodeCount)
+
+	// The password alone no longer signs in
+	w = do(http.MethodPost, "/api/auth/login", `{"username":"john","password":"pass"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, hasCookie(w, "jwt_token"))
+	var challenge struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+		Enroll      bool   `json:"enrollment_required"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	assert.True(t, challenge.MFARequired)
+	assert.False(t, challenge.Enroll)
+
+	w = do(http.MethodPost, "/api/auth/2fa/verify", `{"mfa_token":"`+challenge.MFAToken+`","code":"000000"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(http.MethodPost, "/api/auth/2fa/verify", `{"mfa_token":"forged","code":"000000"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = do(http.MethodPost, "/api/auth/2fa/verify", `{"mfa_token":"`+challenge.MFAToken+`","code":"`+code(0)+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, hasCookie(w, "jwt_token"))
+	assert.True(t, hasCookie(w, "refresh_token"))
+	assert.NotContains(t, w.Body.String(), "recovery_codes")
+
+	// Students may turn it off where only admins must use it
+	store.policy.RequireForAdmins = true
+	w = do(http.MethodGet, "/api/me/2fa", "")
+	assert.JSONEq(t, `false`, mustField(t, w, "required"), "students are not required")
+	w = do(http.MethodPost, "/api/me/2fa/disable", `{"code":"`+code(1)+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, store.totp)
+}
+
+func mustField(t *testing.T, w *httptest.ResponseRecorder, key string) string {
+	t.Helper()
+	var body map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return string(body[key])
+}
@@ -0,0 +1,551 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+// In-memory repository fakes shared by the handler unit tests.
+
+// MemChatRepository holds one group room with its members, messages and
+// reactions, and records moderation actions and read receipts.
+type MemChatRepository struct {
+	repository.ChatRepository
+	members   map[string]*models.ChatRoomMember
+	messages  map[string]*models.ChatMessage
+	reactions map[string][]string
+	log       []string
+	read      []string
+}
+
+func NewMemChatRepository(roles map[string]models.ChatRoomMemberRole) *MemChatRepository {
+	m := &MemChatRepository{
+		members:   map[string]*models.ChatRoomMember{},
+		messages:  map[string]*models.ChatMessage{},
+		reactions: map[string][]string{},
+	}
+	for userID, role := range roles {
+		m.members[userID] = &models.ChatRoomMember{UserID: userID, RoleInRoom: role}
+	}
+	return m
+}
+
+func (m *MemChatRepository) IsMember(ctx context.Context, roomID, userID string) (bool, error) {
+	_, ok := m.members[userID]
+	return ok, nil
+}
+
+func (m *MemChatRepository) GetMember(ctx context.Context, roomID, userID string) (*models.ChatRoomMember, error) {
+	member, ok := m.members[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return member, nil
+}
+
+func (m *MemChatRepository) GetMemberRole(ctx context.Context, roomID, userID string) (models.ChatRoomMemberRole, error) {
+	member, err := m.GetMember(ctx, roomID, userID)
+	if err != nil {
+		return "", err
+	}
+	return member.RoleInRoom, nil
+}
+
+func (m *MemChatRepository) RemoveMember(ctx context.Context, roomID, userID string) error {
+	return nil
+}
+
+func (m *MemChatRepository) GetRoom(ctx context.Context, roomID string) (*models.ChatRoom, error) {
+	return &models.ChatRoom{ID: roomID, Type: models.ChatRoomTypeGroup, PostPolicy: models.ChatPostPolicyEveryone}, nil
+}
+
+func (m *MemChatRepository) CreateMessage(ctx context.Context, roomID, senderID, body string, attachments models.ChatAttachments, importance *string, meta json.RawMessage) (*models.ChatMessage, error) {
+	return &models.ChatMessage{ID: "m1", RoomID: roomID, SenderID: senderID, Body: body}, nil
+}
+
+func (m *MemChatRepository) GetMessage(ctx context.Context, id string) (*models.ChatMessage, error) {
+	msg, ok := m.messages[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *msg
+	return &copied, nil
+}
+
+func (m *MemChatRepository) MarkRoomAsRead(ctx context.Context, roomID, userID string) error {
+	m.read = append(m.read, userID)
+	return nil
+}
+
+func (m *MemChatRepository) SetMuted(ctx context.Context, roomID, userID string, until *time.Time) error {
+	m.members[userID].MutedUntil = until
+	return nil
+}
+
+func (m *MemChatRepository) CreateModerationEntry(ctx context.Context, entry *models.ChatModerationEntry) error {
+	m.log = append(m.log, entry.Action)
+	return nil
+}
+
+func (m *MemChatRepository) SetPinned(ctx context.Context, msgID string, pinnedBy *string) error {
+	m.messages[msgID].PinnedBy = pinnedBy
+	return nil
+}
+
+func (m *MemChatRepository) AddReaction(ctx context.Context, msgID, userID, emoji string) (bool, error) {
+	m.reactions[msgID+emoji] = append(m.reactions[msgID+emoji], userID)
+	return true, nil
+}
+
+func (m *MemChatRepository) ListReactions(ctx context.Context, msgIDs []string) (map[string][]models.ChatReaction, error) {
+	out := map[string][]models.ChatReaction{}
+	for key, users := range m.reactions {
+		for _, id := range msgIDs {
+			if strings.HasPrefix(key, id) {
+				out[id] = append(out[id], models.ChatReaction{Emoji: strings.TrimPrefix(key, id), Count: len(users), UserIDs: users})
+			}
+		}
+	}
+	return out, nil
+}
+
+// MemUserRepository finds a fixed set of users by id, username or email.
+type MemUserRepository struct {
+	repository.UserRepository
+	users []*models.User
+}
+
+func NewMemUserRepository(users ...*models.User) *MemUserRepository {
+	return &MemUserRepository{users: users}
+}
+
+func (m *MemUserRepository) find(match func(u *models.User) bool) (*models.User, error) {
+	for _, u := range m.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *MemUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return m.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (m *MemUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return m.find(func(u *models.User) bool { return u.Username == username })
+}
+
+func (m *MemUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (m *MemUserRepository) EmailExists(ctx context.Context, email, exclude string) (bool, error) {
+	_, err := m.find(func(u *models.User) bool { return u.Email == email && u.ID != exclude })
+	return err == nil, nil
+}
+
+func (m *MemUserRepository) GetTenantRole(ctx context.Context, userID, tenantID string) (string, error) {
+	u, err := m.GetByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return string(u.Role), nil
+}
+
+// MemAPIKeyRepository keeps one tenant's API keys in memory.
+type MemAPIKeyRepository struct {
+	keys []models.APIKey
+}
+
+func (s *MemAPIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	k.ID = "k1"
+	s.keys = append(s.keys, *k)
+	return nil
+}
+
+func (s *MemAPIKeyRepository) List(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *MemAPIKeyRepository) Revoke(ctx context.Context, tenantID, id string) error {
+	for i, k := range s.keys {
+		if k.ID == id {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// MemSessionRepository keeps sign-in sessions in memory.
+type MemSessionRepository struct {
+	sessions map[string]*models.UserSession
+}
+
+func NewMemSessionRepository() *MemSessionRepository {
+	return &MemSessionRepository{sessions: map[string]*models.UserSession{}}
+}
+
+func (s *MemSessionRepository) Create(ctx context.Context, sess *models.UserSession) error {
+	sess.ID = fmt.Sprintf("s%d", len(s.sessions)+1)
+	c := *sess
+	s.sessions[sess.ID] = &c
+	return nil
+}
+
+func (s *MemSessionRepository) GetByTokenHash(ctx context.Context, hash string) (*models.UserSession, error) {
+	for _, sess := range s.sessions {
+		if sess.RefreshTokenHash == hash || (sess.PreviousTokenHash != nil && *sess.PreviousTokenHash == hash) {
+			c := *sess
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *MemSessionRepository) Rotate(ctx context.Context, id, oldHash, newHash string) error {
+	sess, ok := s.sessions[id]
+	if !ok || sess.RefreshTokenHash != oldHash || sess.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	sess.PreviousTokenHash, sess.RefreshTokenHash, sess.RotatedAt = &oldHash, newHash, &now
+	return nil
+}
+
+func (s *MemSessionRepository) ListActive(ctx context.Context, userID string) ([]models.UserSession, error) {
+	out := []models.UserSession{}
+	for _, sess := range s.sessions {
+		if sess.UserID == userID && sess.Active(time.Now()) {
+			out = append(out, *sess)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemSessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	sess, ok := s.sessions[id]
+	if !ok || sess.UserID != userID || sess.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	sess.RevokedAt = &now
+	return nil
+}
+
+func (s *MemSessionRepository) RevokeAll(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	for _, sess := range s.sessions {
+		if sess.UserID == userID && sess.RevokedAt == nil {
+			now := time.Now()
+			sess.RevokedAt = &now
+			ids = append(ids, sess.ID)
+		}
+	}
+	return ids, nil
+}
+
+// MemMFARepository keeps one user's TOTP secret in memory, without recovery codes.
+type MemMFARepository struct {
+	repository.MFARepository
+	totp   *models.UserTOTP
+	policy models.TenantMFAPolicy
+}
+
+func (m *MemMFARepository) GetTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	if m.totp == nil {
+		return nil, repository.ErrNotFound
+	}
+	c := *m.totp
+	return &c, nil
+}
+
+func (m *MemMFARepository) SavePendingTOTP(ctx context.Context, userID, secret string) error {
+	m.totp = &models.UserTOTP{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (m *MemMFARepository) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	now := time.Now()
+	m.totp.EnabledAt, m.totp.LastUsedStep = &now, step
+	return nil
+}
+
+func (m *MemMFARepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	if m.totp.LastUsedStep >= step {
+		return repository.ErrNotFound
+	}
+	m.totp.LastUsedStep = step
+	return nil
+}
+
+func (m *MemMFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	return repository.ErrNotFound
+}
+
+func (m *MemMFARepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	return 0, nil
+}
+
+func (m *MemMFARepository) DeleteTOTP(ctx context.Context, userID string) error {
+	m.totp = nil
+	return nil
+}
+
+func (m *MemMFARepository) GetTenantPolicy(ctx context.Context, tenantID string) (*models.TenantMFAPolicy, error) {
+	p := m.policy
+	return &p, nil
+}
+
+// MemOIDCRepository keeps one tenant's provider configuration and pending logins.
+type MemOIDCRepository struct {
+	cfg    *models.TenantOIDCConfig
+	states map[string]*models.OIDCLoginState
+}
+
+func (r *MemOIDCRepository) GetConfig(ctx context.Context, tenantID string) (*models.TenantOIDCConfig, error) {
+	if r.cfg == nil || r.cfg.TenantID != tenantID {
+		return nil, repository.ErrNotFound
+	}
+	return r.cfg, nil
+}
+
+func (r *MemOIDCRepository) SaveConfig(ctx context.Context, cfg *models.TenantOIDCConfig) error {
+	cfg.ClientSecretSet = true
+	r.cfg = cfg
+	return nil
+}
+
+func (r *MemOIDCRepository) DeleteConfig(ctx context.Context, tenantID string) error {
+	r.cfg = nil
+	return nil
+}
+
+func (r *MemOIDCRepository) CreateState(ctx context.Context, st *models.OIDCLoginState) error {
+	r.states[st.State] = st
+	return nil
+}
+
+func (r *MemOIDCRepository) ConsumeState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	st, ok := r.states[state]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(r.states, state)
+	return st, nil
+}
+
+// StubTenantRepository returns any tenant asked for.
+type StubTenantRepository struct {
+	repository.TenantRepository
+}
+
+func (StubTenantRepository) GetByID(ctx context.Context, id string) (*models.Tenant, error) {
+	return &models.Tenant{ID: id, Slug: "kaznmu"}, nil
+}
+
+// MemSAMLRepository keeps one tenant's IdP configuration and pending logins.
+type MemSAMLRepository struct {
+	cfg      *models.TenantSAMLConfig
+	requests map[string]*models.SAMLLoginRequest
+}
+
+func (r *MemSAMLRepository) GetConfig(ctx context.Context, tenantID string) (*models.TenantSAMLConfig, error) {
+	if r.cfg == nil || tenantID != "t1" {
+		return nil, repository.ErrNotFound
+	}
+	return r.cfg, nil
+}
+
+func (r *MemSAMLRepository) SaveConfig(ctx context.Context, tenantID string, cfg *models.TenantSAMLConfig) error {
+	r.cfg = cfg
+	return nil
+}
+
+func (r *MemSAMLRepository) DeleteConfig(ctx context.Context, tenantID string) error {
+	r.cfg = nil
+	return nil
+}
+
+func (r *MemSAMLRepository) CreateRequest(ctx context.Context, req *models.SAMLLoginRequest) error {
+	r.requests[req.ID] = req
+	return nil
+}
+
+func (r *MemSAMLRepository) ConsumeRequest(ctx context.Context, id string) (*models.SAMLLoginRequest, error) {
+	req, ok := r.requests[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(r.requests, id)
+	return req, nil
+}
+
+// MemOutboxRepository keeps outbox messages of any tenant in memory.
+type MemOutboxRepository struct {
+	repository.OutboxRepository
+	msgs       []models.OutboxMessage
+	lastFilter models.OutboxFilter
+}
+
+func (m *MemOutboxRepository) find(tenantID, id string) *models.OutboxMessage {
+	for i := range m.msgs {
+		if m.msgs[i].ID == id && m.msgs[i].TenantID != nil && *m.msgs[i].TenantID == tenantID {
+			return &m.msgs[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemOutboxRepository) List(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error) {
+	m.lastFilter = filter
+	var out []models.OutboxMessage
+	for _, msg := range m.msgs {
+		if *msg.TenantID == filter.TenantID && (filter.Status == "" || msg.Status == filter.Status) {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemOutboxRepository) Get(ctx context.Context, tenantID, id string) (*models.OutboxMessage, error) {
+	if msg := m.find(tenantID, id); msg != nil {
+		cp := *msg
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m *MemOutboxRepository) Replay(ctx context.Context, tenantID, id string) (bool, error) {
+	msg := m.find(tenantID, id)
+	if msg == nil || msg.Status != models.OutboxStatusDead {
+		return false, nil
+	}
+	msg.Status, msg.Attempts = models.OutboxStatusPending, 0
+	return true, nil
+}
+
+func (m *MemOutboxRepository) ReplayDead(ctx context.Context, tenantID, kind string) (int64, error) {
+	var n int64
+	for i := range m.msgs {
+		msg := &m.msgs[i]
+		if *msg.TenantID == tenantID && msg.Status == models.OutboxStatusDead && (kind == "" || msg.Kind == kind) {
+			msg.Status, msg.Attempts = models.OutboxStatusPending, 0
+			n++
+		}
+	}
+	return n, nil
+}
+
+// MemNotificationEventRepository is an in-memory notification event log; Prune drops
+// everything to simulate retention.
+type MemNotificationEventRepository struct {
+	mu     sync.Mutex
+	events []models.NotificationEvent
+	nextID int64
+}
+
+func (m *MemNotificationEventRepository) Append(ctx context.Context, stream, eventType string, payload json.RawMessage) (*models.NotificationEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	ev := models.NotificationEvent{ID: m.nextID, Stream: stream, Type: eventType, Payload: payload, CreatedAt: time.Now()}
+	m.events = append(m.events, ev)
+	return &ev, nil
+}
+
+// reserve takes the next id for an insert that commits later, as a
+// concurrent transaction would.
+func (m *MemNotificationEventRepository) reserve() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	return m.nextID
+}
+
+// commit stores an event under an id taken earlier with reserve.
+func (m *MemNotificationEventRepository) commit(id int64, stream, eventType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, models.NotificationEvent{ID: id, Stream: stream, Type: eventType, Payload: json.RawMessage(`{}`), CreatedAt: time.Now()})
+}
+
+func (m *MemNotificationEventRepository) ListSince(ctx context.Context, streams []string, afterID int64, limit int) ([]models.NotificationEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.NotificationEvent
+	for _, ev := range m.events {
+		for _, s := range streams {
+			if ev.Stream == s && ev.ID > afterID && len(out) < limit {
+				out = append(out, ev)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *MemNotificationEventRepository) ListRecent(ctx context.Context, streams []string, window time.Duration, maxID int64, limit int) ([]models.NotificationEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.NotificationEvent
+	for _, ev := range m.events {
+		for _, s := range streams {
+			if ev.Stream == s && ev.ID <= maxID && time.Since(ev.CreatedAt) <= window && len(out) < limit {
+				out = append(out, ev)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *MemNotificationEventRepository) Bounds(ctx context.Context) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.events) == 0 {
+		return 0, 0, nil
+	}
+	return m.events[0].ID, m.events[len(m.events)-1].ID, nil
+}
+
+func (m *MemNotificationEventRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.events)
+	m.events = nil
+	return int64(n), nil
+}
+
+// MemNotificationRepository counts unread notifications per recipient.
+type MemNotificationRepository struct {
+	repository.NotificationRepository
+	unread map[string]int
+}
+
+func (m *MemNotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	m.unread[n.RecipientID]++
+	return nil
+}
+
+func (m *MemNotificationRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	return m.unread[userID], nil
+}
+
+// StubAdminRepository reports a fixed admin unread count.
+type StubAdminRepository struct {
+	repository.AdminRepository
+}
+
+func (StubAdminRepository) GetAdminUnreadCount(ctx context.Context) (int, error) { return 7, nil }
+
+func (StubAdminRepository) MarkAdminNotificationRead(ctx context.Context, id string) error {
+	return nil
+}
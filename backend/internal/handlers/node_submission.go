@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
@@ -92,7 +93,7 @@ func (h *NodeSubmissionHandler) PutSubmission(c *gin.Context) {
 	err := h.svc.PutSubmission(c.Request.Context(), tenantID, uid, role, nodeID, localePtr, req.State, []byte(req.Data))
 	if err != nil {
 		log.Printf("[NodeSubmission] Put error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Bad Request usually for state/val errors
+		respondSubmissionError(c, err)
 		return
 	}
 	
@@ -105,6 +106,17 @@ func (h *NodeSubmissionHandler) PutSubmission(c *gin.Context) {
 	c.JSON(http.StatusOK, dto)
 }
 
+// respondSubmissionError reports state/validation errors as 400, including the
+// per-field details when the form failed playbook validation.
+func respondSubmissionError(c *gin.Context, err error) {
+	var fieldErr *services.FieldValidationError
+	if errors.As(err, &fieldErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": fieldErr.Fields})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
 type nodeUploadPresignReq struct {
 	SlotKey     string `json:"slot_key" binding:"required"`
 	Filename    string `json:"filename" binding:"required"`
@@ -203,7 +215,7 @@ func (h *NodeSubmissionHandler) PatchState(c *gin.Context) {
 	err := h.svc.PatchState(c.Request.Context(), tenantID, uid, role, nodeID, req.State)
 	if err != nil {
 		log.Printf("[NodeSubmission] PatchState error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondSubmissionError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// notificationReplayBatch is how many stored events are read per query
+	notificationReplayBatch = 500
+	// notificationPollInterval re-reads the log even without a wake-up, so
+	// events from processes without a shared Redis still arrive
+	notificationPollInterval = 15 * time.Second
+	// notificationLookback is re-read on every delivery. Event ids are taken
+	// before the insert commits, so a higher id can become visible first; the
+	// window delivers the lower one once it commits. It must exceed the poll
+	// interval.
+	notificationLookback = time.Minute
+)
+
+// Snapshot events tell the client the current unread counts; they are sent
+// on a fresh connection and when a resume point is no longer retained.
+const (
+	notificationEventSnapshot = "snapshot"
+	notificationEventReset    = "reset"
+)
+
+// NotificationStreamHandler serves a user's live notification events,
+// merging their own notifications with the shared admin feed for staff.
+type NotificationStreamHandler struct {
+	stream *services.NotificationStream
+	notifs *services.NotificationService
+	admin  *services.AdminService
+}
+
+func NewNotificationStreamHandler(stream *services.NotificationStream, notifs *services.NotificationService, admin *services.AdminService) *NotificationStreamHandler {
+	return &NotificationStreamHandler{stream: stream, notifs: notifs, admin: admin}
+}
+
+// notificationCounts is the data of snapshot and reset events.
+type notificationCounts struct {
+	UnreadCount      int  `json:"unread_count"`
+	AdminUnreadCount *int `json:"admin_unread_count,omitempty"`
+}
+
+// Stream handles GET /notifications/stream. A client reconnecting with the
+// Last-Event-ID header (or ?last_event_id=) first receives what it missed;
+// events from the lookback window may arrive again, so clients skip ids they
+// have already handled.
+func (h *NotificationStreamHandler) Stream(c *gin.Context) {
+	uid := userIDFromClaims(c)
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	staff := isStaffRole(roleFromContext(c))
+	streams := []string{services.UserNotificationStream(uid)}
+	if staff {
+		streams = append(streams, services.AdminNotificationStream)
+	}
+	ctx := c.Request.Context()
+
+	// Subscribe before reading the log so nothing slips in between
+	sub := h.stream.Subscribe(streams...)
+	defer sub.Close()
+
+	oldest, latest, err := h.stream.Bounds(ctx)
+	if err != nil {
+		log.Printf("[NotificationStream] Failed to read bounds: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open notification stream"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	last, resumed := lastEventID(c)
+	sent := map[int64]bool{}
+	counts := ""
+	switch {
+	case !resumed || last > latest:
+		counts = notificationEventSnapshot
+	case last < oldest-1:
+		// Part of the gap was pruned; the client must refetch its lists
+		counts = notificationEventReset
+	}
+	if counts != "" {
+		// The counts include every event visible now, so the lookback
+		// window only has to deliver those still to commit
+		last = latest
+		if sent, err = h.visible(ctx, streams, last); err != nil {
+			log.Printf("[NotificationStream] Failed to read recent events for %s: %v", uid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open notification stream"})
+			return
+		}
+		if !h.sendCounts(c, counts, uid, staff, latest) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open notification stream"})
+			return
+		}
+	} else if last, err = h.replay(ctx, c, streams, last, sent); err != nil {
+		log.Printf("[NotificationStream] Replay failed for %s: %v", uid, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open notification stream"})
+		return
+	}
+	c.Writer.Flush()
+
+	poll := time.NewTicker(notificationPollInterval)
+	defer poll.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case _, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+		case <-poll.C:
+			// Comment line keeps proxies from closing an idle stream
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+		}
+		if last, err = h.replay(ctx, c, streams, last, sent); err != nil {
+			log.Printf("[NotificationStream] Delivery failed for %s: %v", uid, err)
+			return false
+		}
+		return true
+	})
+}
+
+// replay writes the events of the lookback window that were not sent yet,
+// then every stored event after last, and returns the new position. sent
+// holds the ids written on this connection that the window can still return.
+func (h *NotificationStreamHandler) replay(ctx context.Context, c *gin.Context, streams []string, last int64, sent map[int64]bool) (int64, error) {
+	late, err := h.stream.Recent(ctx, streams, notificationLookback, last, notificationReplayBatch)
+	if err != nil {
+		return last, err
+	}
+	keep := make(map[int64]bool, len(late))
+	for _, ev := range late {
+		if !sent[ev.ID] {
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(ev.ID, 10), Event: ev.Type, Data: ev})
+		}
+		keep[ev.ID] = true
+	}
+	for {
+		events, err := h.stream.Since(ctx, streams, last, notificationReplayBatch)
+		if err != nil {
+			return last, err
+		}
+		for _, ev := range events {
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(ev.ID, 10), Event: ev.Type, Data: ev})
+			keep[ev.ID] = true
+			last = ev.ID
+		}
+		if len(events) < notificationReplayBatch {
+			break
+		}
+	}
+	// Ids that left the window are not read again
+	clear(sent)
+	for id := range keep {
+		sent[id] = true
+	}
+	return last, nil
+}
+
+// visible returns the ids of the lookback window up to last, which the client
+// already accounts for.
+func (h *NotificationStreamHandler) visible(ctx context.Context, streams []string, last int64) (map[int64]bool, error) {
+	events, err := h.stream.Recent(ctx, streams, notificationLookback, last, notificationReplayBatch)
+	if err != nil {
+		return nil, err
+	}
+	sent := make(map[int64]bool, len(events))
+	for _, ev := range events {
+		sent[ev.ID] = true
+	}
+	return sent, nil
+}
+
+func (h *NotificationStreamHandler) sendCounts(c *gin.Context, event, uid string, staff bool, id int64) bool {
+	ctx := c.Request.Context()
+	var counts notificationCounts
+	var err error
+	if counts.UnreadCount, err = h.notifs.UnreadCount(ctx, uid); err != nil {
+		log.Printf("[NotificationStream] Failed to count unread for %s: %v", uid, err)
+		return false
+	}
+	if staff && h.admin != nil {
+		n, err := h.admin.GetUnreadNotificationCount(ctx)
+		if err != nil {
+			log.Printf("[NotificationStream] Failed to count admin unread: %v", err)
+			return false
+		}
+		counts.AdminUnreadCount = &n
+	}
+	c.Render(-1, sse.Event{Id: strconv.FormatInt(id, 10), Event: event, Data: counts})
+	return true
+}
+
+// lastEventID reads the resume position sent by EventSource on reconnect.
+func lastEventID(c *gin.Context) (int64, bool) {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+func isStaffRole(role string) bool {
+	return role == "admin" || role == "advisor" || role == "superadmin"
+}
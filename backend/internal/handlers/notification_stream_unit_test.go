@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// readSSE parses events from an open stream onto a channel.
+func readSSE(resp *http.Response) <-chan sseEvent {
+	out := make(chan sseEvent, 16)
+	go func() {
+		defer close(out)
+		sc := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev.event != "" {
+					out <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, "id:"):
+				ev.id = strings.TrimSpace(line[3:])
+			case strings.HasPrefix(line, "event:"):
+				ev.event = strings.TrimSpace(line[6:])
+			case strings.HasPrefix(line, "data:"):
+				ev.data = strings.TrimSpace(line[5:])
+			}
+		}
+	}()
+	return out
+}
+
+func nextSSE(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+func TestNotificationStream_SSE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eventLog := &MemNotificationEventRepository{}
+	stream := services.NewNotificationStream(eventLog, nil)
+	notifRepo := &MemNotificationRepository{unread: map[string]int{"stud": 2}}
+	notifs := services.NewNotificationService(notifRepo)
+	notifs.SetStream(stream)
+	admin := services.NewAdminService(StubAdminRepository{}, nil, config.AppConfig{}, nil)
+	admin.SetNotifications(notifs)
+	h := handlers.NewNotificationStreamHandler(stream, notifs, admin)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": c.GetHeader("X-Test-User"), "role": c.GetHeader("X-Test-Role")})
+		c.Next()
+	})
+	r.GET("/notifications/stream", h.Stream)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	open := func(t *testing.T, user, role, lastEventID string) (*http.Response, <-chan sseEvent) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/notifications/stream", nil)
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Role", role)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp, readSSE(resp)
+	}
+	ctx := context.Background()
+
+	var resumeFrom string
+	t.Run("Fresh connection gets counts, then live events", func(t *testing.T) {
+		_, events := open(t, "stud", "student", "")
+		snap := nextSSE(t, events)
+		assert.Equal(t, "snapshot", snap.event)
+		assert.Equal(t, "0", snap.id)
+		assert.JSONEq(t, `{"unread_count":2}`, snap.data)
+
+		require.Eventually(t, func() bool { return stream.Subscribers("user:stud") == 1 }, time.Second, 5*time.Millisecond)
+		require.NoError(t, notifs.CreateNotification(ctx, &models.Notification{RecipientID: "stud", Title: "Reviewed"}))
+		require.NoError(t, notifs.CreateNotification(ctx, &models.Notification{RecipientID: "other", Title: "Not yours"}))
+		require.NoError(t, notifs.CreateNotification(ctx, &models.Notification{RecipientID: "stud", Title: "Again"}))
+
+		ev := nextSSE(t, events)
+		assert.Equal(t, models.NotificationEventCreated, ev.event)
+		assert.Equal(t, "1", ev.id)
+		var stored models.NotificationEvent
+		require.NoError(t, json.Unmarshal([]byte(ev.data), &stored))
+		var payload models.NotificationEventPayload
+		require.NoError(t, json.Unmarshal(stored.Payload, &payload))
+		assert.Equal(t, "Reviewed", payload.Notification.Title)
+		assert.Equal(t, 3, payload.UnreadCount)
+
+		ev = nextSSE(t, events)
+		assert.Equal(t, "3", ev.id, "other users' events are not delivered")
+		resumeFrom = "1"
+	})
+
+	t.Run("Resume replays what was missed", func(t *testing.T) {
+		_, events := open(t, "stud", "student", resumeFrom)
+		// The lookback window is sent again; the client skips ids it has seen
+		ev := nextSSE(t, events)
+		assert.Equal(t, "1", ev.id)
+		ev = nextSSE(t, events)
+		assert.Equal(t, "3", ev.id)
+		assert.Equal(t, models.NotificationEventCreated, ev.event)
+	})
+
+	t.Run("Staff also follow the admin feed", func(t *testing.T) {
+		_, events := open(t, "adv", "advisor", "")
+		snap := nextSSE(t, events)
+		assert.JSONEq(t, `{"unread_count":0,"admin_unread_count":7}`, snap.data)
+
+		require.Eventually(t, func() bool { return stream.Subscribers(services.AdminNotificationStream) == 1 }, time.Second, 5*time.Millisecond)
+		require.NoError(t, admin.MarkNotificationAsRead(ctx, "an1"))
+		ev := nextSSE(t, events)
+		assert.Equal(t, models.AdminNotificationEventRead, ev.event)
+	})
+
+	t.Run("Resume point past retention asks for a reset", func(t *testing.T) {
+		_, _ = eventLog.Prune(ctx, time.Now())
+		require.NoError(t, notifs.CreateNotification(ctx, &models.Notification{RecipientID: "stud"}))
+		_, events := open(t, "stud", "student", "2")
+		ev := nextSSE(t, events)
+		assert.Equal(t, "reset", ev.event)
+		assert.Equal(t, "5", ev.id)
+	})
+
+	t.Run("Event committed after a later one is still delivered", func(t *testing.T) {
+		_, events := open(t, "stud", "student", "")
+		snap := nextSSE(t, events)
+		assert.Equal(t, "5", snap.id)
+		require.Eventually(t, func() bool { return stream.Subscribers("user:stud") == 1 }, time.Second, 5*time.Millisecond)
+
+		slow := eventLog.reserve()
+		require.NoError(t, notifs.CreateNotification(ctx, &models.Notification{RecipientID: "stud", Title: "Fast"}))
+		assert.Equal(t, "7", nextSSE(t, events).id)
+
+		eventLog.commit(slow, "user:stud", models.NotificationEventCreated)
+		require.NoError(t, notifs.CreateNotification(ctx, &models.Notification{RecipientID: "stud", Title: "Next"}))
+		assert.Equal(t, "6", nextSSE(t, events).id)
+		assert.Equal(t, "8", nextSSE(t, events).id)
+	})
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie ties an SSO callback to the browser that started the
+// login
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler serves single sign-on through the tenant's OpenID Connect
+// provider, and its configuration for superadmins.
+type OIDCHandler struct {
+	svc *services.OIDCService
+	cfg config.AppConfig
+}
+
+func NewOIDCHandler(svc *services.OIDCService, cfg config.AppConfig) *OIDCHandler {
+	return &OIDCHandler{svc: svc, cfg: cfg}
+}
+
+// Status tells the login page whether to offer SSO, and where to send the
+// browser for it.
+// GET /api/auth/oidc
+func (h *OIDCHandler) Status(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	if tenantID == "" || !h.svc.Enabled(c.Request.Context(), tenantID) {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "login_url": h.svc.LoginURL(middleware.GetTenantSlug(c))})
+}
+
+// Login sends the browser to the tenant's provider. The optional redirect
+// query is the frontend path to land on afterwards.
+// GET /api/auth/oidc/login?tenant=slug&redirect=/path
+func (h *OIDCHandler) Login(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	if tenantID == "" {
+		h.fail(c, services.ErrSSONotConfigured)
+		return
+	}
+	authURL, state, err := h.svc.BeginLogin(c.Request.Context(), tenantID, c.Query("redirect"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.setStateCookie(c, state, int(services.OIDCLoginTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes the login the provider redirects back with, sets the
+// session cookie and returns the browser to the frontend.
+// GET /api/auth/oidc/callback?tenant=slug
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	state := c.Query("state")
+	cookie, _ := c.Cookie(oidcStateCookie)
+	if state == "" || cookie != state {
+		h.fail(c, services.ErrSSOLoginExpired)
+		return
+	}
+	h.setStateCookie(c, "", -1)
+
+	result, err := h.svc.CompleteLogin(sessionContext(c), middleware.GetTenantID(c), state, c.Query("code"), c.Query("error"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	ssoFinish(c, h.cfg, http.StatusFound, result)
+}
+
+func (h *OIDCHandler) fail(c *gin.Context, err error) {
+	ssoFail(c, h.cfg, http.StatusFound, err)
+}
+
+func (h *OIDCHandler) setStateCookie(c *gin.Context, state string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/api/auth/oidc", "", strings.HasPrefix(h.cfg.ServerURL, "https"), true)
+}
+
+type oidcConfigReq struct {
+	Issuer          string   `json:"issuer" binding:"required"`
+	ClientID        string   `json:"client_id" binding:"required"`
+	ClientSecret    string   `json:"client_secret"`
+	Scopes          []string `json:"scopes"`
+	Enabled         *bool    `json:"enabled"`
+	JITProvisioning bool     `json:"jit_provisioning"`
+	AllowedDomains  []string `json:"allowed_domains"`
+}
+
+// GetConfig returns a tenant's provider configuration, without the client
+// secret.
+// GET /superadmin/tenants/:id/oidc
+func (h *OIDCHandler) GetConfig(c *gin.Context) {
+	cfg, err := h.svc.GetConfig(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		ssoConfigError(c, err, "load")
+		return
+	}
+	h.respondConfig(c, cfg)
+}
+
+// SaveConfig creates or replaces a tenant's provider configuration. Leaving
+// client_secret out keeps the stored one.
+// PUT /superadmin/tenants/:id/oidc
+func (h *OIDCHandler) SaveConfig(c *gin.Context) {
+	var req oidcConfigReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg := &models.TenantOIDCConfig{
+		TenantID:        c.Param("id"),
+		Issuer:          req.Issuer,
+		ClientID:        req.ClientID,
+		ClientSecret:    req.ClientSecret,
+		Scopes:          req.Scopes,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		JITProvisioning: req.JITProvisioning,
+		AllowedDomains:  req.AllowedDomains,
+	}
+	if err := h.svc.SaveConfig(c.Request.Context(), cfg); err != nil {
+		if errors.Is(err, services.ErrOIDCInvalidConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save sso configuration"})
+		return
+	}
+	h.respondConfig(c, cfg)
+}
+
+// DeleteConfig turns SSO off for a tenant.
+// DELETE /superadmin/tenants/:id/oidc
+func (h *OIDCHandler) DeleteConfig(c *gin.Context) {
+	if err := h.svc.DeleteConfig(c.Request.Context(), c.Param("id")); err != nil {
+		ssoConfigError(c, err, "delete")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sso configuration deleted"})
+}
+
+// respondConfig returns the configuration with the callback URL to
+// register with the provider.
+func (h *OIDCHandler) respondConfig(c *gin.Context, cfg *models.TenantOIDCConfig) {
+	redirectURI, err := h.svc.RedirectURI(c.Request.Context(), cfg.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tenant"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "redirect_uri": redirectURI})
+}
@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc/oidctest"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// challengeAll asks every sign-in for a second factor.
+type challengeAll struct{}
+
+func (challengeAll) Challenge(ctx context.Context, user *models.User, role, tenantID string) (*services.MFAChallenge, error) {
+	return &services.MFAChallenge{Token: "mfa-token", Enroll: true}, nil
+}
+
+func TestOIDCHandler_Flow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	idp := oidctest.NewServer("portal", "s3cret")
+	defer idp.Close()
+	idp.User.Email = "advisor@example.edu"
+
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, ServerURL: "http://localhost:8080", FrontendBase: "http://localhost:5173", Env: "development"}
+	repo := &MemOIDCRepository{states: map[string]*models.OIDCLoginState{}}
+	users := NewMemUserRepository(&models.User{ID: "adv-1", Email: "advisor@example.edu", Role: models.RoleAdvisor, IsActive: true})
+	authService := services.NewAuthService(users, NewMemSessionRepository(), nil, nil, cfg)
+	svc := services.NewOIDCService(repo, users, StubTenantRepository{}, authService, cfg)
+	h := handlers.NewOIDCHandler(svc, cfg)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenant_id", "t1")
+		c.Set("tenant_slug", "kaznmu")
+		c.Next()
+	})
+	r.GET("/api/auth/oidc", h.Status)
+	r.GET("/api/auth/oidc/login", h.Login)
+	r.GET("/api/auth/oidc/callback", h.Callback)
+	r.PUT("/superadmin/tenants/:id/oidc", h.SaveConfig)
+
+	get := func(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// atIdP follows the login redirect to the provider and returns the
+	// callback it sends the browser to
+	atIdP := func(t *testing.T, w *httptest.ResponseRecorder) *url.URL {
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(w.Header().Get("Location"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		callback, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		return callback
+	}
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	t.Run("Not configured", func(t *testing.T) {
+		w := get("/api/auth/oidc")
+		assert.JSONEq(t, `{"enabled":false}`, w.Body.String())
+
+		w = get("/api/auth/oidc/login")
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "http://localhost:5173/login?sso_error=not_configured", w.Header().Get("Location"))
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		body := `{"issuer":"` + idp.Issuer + `","client_id":"portal","client_secret":"s3cret"}`
+		req := httptest.NewRequest(http.MethodPut, "/superadmin/tenants/t1/oidc", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"redirect_uri":"http://localhost:8080/api/auth/oidc/callback?tenant=kaznmu"`)
+		assert.Contains(t, w.Body.String(), `"client_secret_set":true`)
+		assert.NotContains(t, w.Body.String(), "s3cret")
+
+		w = get("/api/auth/oidc")
+		assert.JSONEq(t, `{"enabled":true,"login_url":"http://localhost:8080/api/auth/oidc/login?tenant=kaznmu"}`, w.Body.String())
+	})
+
+	t.Run("Login through the provider", func(t *testing.T) {
+		w := get("/api/auth/oidc/login?tenant=kaznmu&redirect=/advisor")
+		require.Equal(t, http.StatusFound, w.Code)
+		state := cookie(w, "oidc_state")
+		require.NotNil(t, state)
+		assert.True(t, state.HttpOnly)
+
+		callback := atIdP(t, w)
+		assert.Equal(t, state.Value, callback.Query().Get("state"))
+
+		t.Run("Another browser's callback is refused", func(t *testing.T) {
+			w := get(callback.RequestURI())
+			assert.Equal(t, "http://localhost:5173/login?sso_error=expired", w.Header().Get("Location"))
+			assert.Nil(t, cookie(w, "jwt_token"))
+		})
+
+		w = get(callback.RequestURI(), state)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "http://localhost:5173/advisor", w.Header().Get("Location"))
+		session := cookie(w, "jwt_token")
+		require.NotNil(t, session)
+		assert.NotEmpty(t, session.Value)
+		assert.True(t, session.HttpOnly)
+
+		w = get(callback.RequestURI(), state)
+		assert.Equal(t, "http://localhost:5173/login?sso_error=expired", w.Header().Get("Location"), "a callback works once")
+	})
+
+	t.Run("Second factor on the login page", func(t *testing.T) {
+		authService.SetSecondFactor(challengeAll{})
+		defer authService.SetSecondFactor(nil)
+		w := get("/api/auth/oidc/login?redirect=/advisor")
+		state := cookie(w, "oidc_state")
+		callback := atIdP(t, w)
+
+		w = get(callback.RequestURI(), state)
+		require.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "http://localhost:5173/login?enrollment_required=1&mfa_token=mfa-token&redirect=%2Fadvisor", w.Header().Get("Location"))
+		assert.Nil(t, cookie(w, "jwt_token"), "no session before the code")
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		idp.User.Email = "stranger@example.edu"
+		w := get("/api/auth/oidc/login")
+		state := cookie(w, "oidc_state")
+		callback := atIdP(t, w)
+
+		w = get(callback.RequestURI(), state)
+		assert.Equal(t, "http://localhost:5173/login?sso_error=no_account", w.Header().Get("Location"))
+	})
+}
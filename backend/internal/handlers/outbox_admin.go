@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// OutboxHandler lets admins inspect their tenant's outbox and replay dead messages.
+type OutboxHandler struct {
+	svc *services.OutboxService
+}
+
+func NewOutboxHandler(svc *services.OutboxService) *OutboxHandler {
+	return &OutboxHandler{svc: svc}
+}
+
+// GET /admin/outbox?status=dead&kind=email&limit=50&offset=0
+func (h *OutboxHandler) List(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	msgs, err := h.svc.List(c.Request.Context(), models.OutboxFilter{
+		TenantID: middleware.GetTenantID(c),
+		Status:   c.Query("status"),
+		Kind:     c.Query("kind"),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, msgs)
+}
+
+// GET /admin/outbox/:messageId
+func (h *OutboxHandler) Get(c *gin.Context) {
+	msg, err := h.svc.Get(c.Request.Context(), middleware.GetTenantID(c), c.Param("messageId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// POST /admin/outbox/:messageId/replay
+func (h *OutboxHandler) Replay(c *gin.Context) {
+	msg, err := h.svc.Replay(c.Request.Context(), middleware.GetTenantID(c), c.Param("messageId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// POST /admin/outbox/replay?kind=email requeues every dead message
+func (h *OutboxHandler) ReplayDead(c *gin.Context) {
+	n, err := h.svc.ReplayDead(c.Request.Context(), middleware.GetTenantID(c), c.Query("kind"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": n})
+}
+
+func (h *OutboxHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOutboxMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOutboxNotReplayable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("[Outbox] Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "outbox request failed"})
+	}
+}
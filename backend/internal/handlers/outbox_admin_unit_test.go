@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxHandler_Unit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t1, t2 := "t1", "t2"
+	repo := &MemOutboxRepository{msgs: []models.OutboxMessage{
+		{ID: "m1", TenantID: &t1, Kind: "email", Status: models.OutboxStatusDead, Attempts: 8},
+		{ID: "m2", TenantID: &t1, Kind: "email", Status: models.OutboxStatusSent, Attempts: 1},
+		{ID: "m3", TenantID: &t1, Kind: "notification", Status: models.OutboxStatusDead, Attempts: 8},
+		{ID: "m4", TenantID: &t2, Kind: "email", Status: models.OutboxStatusDead, Attempts: 8},
+	}}
+	h := handlers.NewOutboxHandler(services.NewOutboxService(repo))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenant_id", "t1")
+		c.Next()
+	})
+	r.GET("/outbox", h.List)
+	r.POST("/outbox/replay", h.ReplayDead)
+	r.GET("/outbox/:messageId", h.Get)
+	r.POST("/outbox/:messageId/replay", h.Replay)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	t.Run("List dead messages of the tenant", func(t *testing.T) {
+		w := do("GET", "/outbox?status=dead&limit=10&offset=5")
+		require.Equal(t, http.StatusOK, w.Code)
+		var msgs []models.OutboxMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msgs))
+		assert.Len(t, msgs, 2)
+		assert.Equal(t, models.OutboxFilter{TenantID: "t1", Status: "dead", Limit: 10, Offset: 5}, repo.lastFilter)
+	})
+
+	t.Run("Other tenant's message is not found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do("GET", "/outbox/m4").Code)
+		assert.Equal(t, http.StatusNotFound, do("POST", "/outbox/m4/replay").Code)
+	})
+
+	t.Run("Replay dead message", func(t *testing.T) {
+		w := do("POST", "/outbox/m1/replay")
+		require.Equal(t, http.StatusOK, w.Code)
+		var msg models.OutboxMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+		assert.Equal(t, models.OutboxStatusPending, msg.Status)
+	})
+
+	t.Run("Delivered message cannot be replayed", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, do("POST", "/outbox/m2/replay").Code)
+	})
+
+	t.Run("Replay all dead of a kind", func(t *testing.T) {
+		w := do("POST", "/outbox/replay?kind=notification")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"replayed":1}`, w.Body.String())
+		assert.Equal(t, models.OutboxStatusDead, repo.msgs[3].Status, "other tenant untouched")
+	})
+}
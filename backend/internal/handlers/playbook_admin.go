@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPlaybookUploadBytes caps the size of an uploaded playbook document
+const maxPlaybookUploadBytes = 5 << 20
+
+// PlaybookHandler lets admins manage their tenant's playbook versions and
+// superadmins manage any tenant's (via the :id route parameter).
+type PlaybookHandler struct {
+	svc        *services.PlaybookService
+	migrations *services.PlaybookMigrationService
+}
+
+func NewPlaybookHandler(svc *services.PlaybookService, migrations *services.PlaybookMigrationService) *PlaybookHandler {
+	return &PlaybookHandler{svc: svc, migrations: migrations}
+}
+
+// tenantID is the tenant from the superadmin route, or the request tenant for admin routes
+func (h *PlaybookHandler) tenantID(c *gin.Context) string {
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	return middleware.GetTenantID(c)
+}
+
+// readPlaybook reads the playbook JSON from a multipart "file" field or the raw request body
+func readPlaybook(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPlaybookUploadBytes)
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+	return io.ReadAll(c.Request.Body)
+}
+
+// GET /playbook/versions
+func (h *PlaybookHandler) ListVersions(c *gin.Context) {
+	versions, err := h.svc.ListVersions(c.Request.Context(), h.tenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list playbook versions"})
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// GET /playbook/versions/:versionId
+func (h *PlaybookHandler) GetVersion(c *gin.Context) {
+	v, err := h.svc.GetVersion(c.Request.Context(), h.tenantID(c), c.Param("versionId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// POST /playbook/validate
+func (h *PlaybookHandler) Validate(c *gin.Context) {
+	raw, err := readPlaybook(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.svc.Validate(raw))
+}
+
+// POST /playbook/versions
+func (h *PlaybookHandler) Upload(c *gin.Context) {
+	raw, err := readPlaybook(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v, err := h.svc.Upload(c.Request.Context(), h.tenantID(c), userIDFromClaims(c), raw)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+// GET /playbook/versions/:versionId/diff?against=<versionId>
+// Without "against" the version is compared to the active playbook.
+func (h *PlaybookHandler) Diff(c *gin.Context) {
+	d, err := h.svc.Diff(c.Request.Context(), h.tenantID(c), c.Query("against"), c.Param("versionId"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// POST /playbook/versions/:versionId/activate
+func (h *PlaybookHandler) Activate(c *gin.Context) {
+	tenantID := h.tenantID(c)
+	versionID := c.Param("versionId")
+	if err := h.svc.Activate(c.Request.Context(), tenantID, versionID, userIDFromClaims(c)); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	log.Printf("[Playbook] Tenant %s activated version %s", tenantID, versionID)
+	c.JSON(http.StatusOK, gin.H{"ok": true, "active_version_id": versionID})
+}
+
+// POST /playbook/rollback
+func (h *PlaybookHandler) Rollback(c *gin.Context) {
+	tenantID := h.tenantID(c)
+	versionID, err := h.svc.Rollback(c.Request.Context(), tenantID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	log.Printf("[Playbook] Tenant %s rolled back to version %s", tenantID, versionID)
+	c.JSON(http.StatusOK, gin.H{"ok": true, "active_version_id": versionID})
+}
+
+// GET /playbook/activations
+func (h *PlaybookHandler) ListActivations(c *gin.Context) {
+	acts, err := h.svc.ListActivations(c.Request.Context(), h.tenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list playbook activations"})
+		return
+	}
+	c.JSON(http.StatusOK, acts)
+}
+
+type migrateRequest struct {
+	FromVersionID string            `json:"from_version_id"`
+	Mappings      map[string]string `json:"mappings"`
+	DryRun        *bool             `json:"dry_run"`
+}
+
+// POST /playbook/versions/:versionId/migrate
+// Moves students from from_version_id (default: the active version) to this
+// version. Requests are dry runs unless dry_run is explicitly false.
+func (h *PlaybookHandler) Migrate(c *gin.Context) {
+	var req migrateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	ctx := c.Request.Context()
+	tenantID := h.tenantID(c)
+	versionID := c.Param("versionId")
+
+	var report *services.MigrationReport
+	var err error
+	if req.DryRun != nil && !*req.DryRun {
+		report, err = h.migrations.Apply(ctx, tenantID, req.FromVersionID, versionID, userIDFromClaims(c), req.Mappings)
+	} else {
+		report, err = h.migrations.Plan(ctx, tenantID, req.FromVersionID, versionID, req.Mappings)
+	}
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if report.Applied {
+		log.Printf("[Playbook] Tenant %s migrated %d students to version %s", tenantID, len(report.Students), versionID)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *PlaybookHandler) respondError(c *gin.Context, err error) {
+	var invalid *services.PlaybookValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid playbook", "errors": invalid.Errors})
+	case errors.Is(err, services.ErrPlaybookVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSameMigrationVersion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoPlaybookRollback):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("[Playbook] Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
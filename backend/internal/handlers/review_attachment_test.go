@@ -163,7 +163,8 @@ func TestReviewAttachment_AdvisorApproves(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc)
+	svc.SetJourney(jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -223,7 +224,8 @@ func TestReviewAttachment_AdvisorApprovesWithComments(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc)
+	svc.SetJourney(jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc, nil)
 
 	r := gin.New()
 	r.Use(func(c *gin.Context) {
@@ -281,7 +283,8 @@ func TestReviewAttachment_AdvisorUnassignedStudent_Forbidden(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc)
+	svc.SetJourney(jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc, nil)
 
 	r := gin.New()
 	r.Use(func(c *gin.Context) {
@@ -301,6 +304,66 @@ func TestReviewAttachment_AdvisorUnassignedStudent_Forbidden(t *testing.T) {
 	assert.Equal(t, http.StatusForbidden, w.Code, "Unassigned advisor should get 403")
 }
 
+func TestReviewAttachment_AdvisorApprovesSecretaryNode_Forbidden(t *testing.T) {
+	db, teardown := testutils.SetupTestDB()
+	defer teardown()
+
+	f := reviewTestFixtures{
+		TenantID:    uuid.New().String(),
+		StudentID:   uuid.New().String(),
+		AdvisorID:   uuid.New().String(),
+		PVVersionID: uuid.New().String(),
+		NodeInstID:  uuid.New().String(),
+		SlotID:      uuid.New().String(),
+		DocID:       uuid.New().String(),
+	}
+
+	tenantSlug := "secretary-node-" + f.TenantID[:8]
+	db.Exec(`INSERT INTO tenants (id, slug, name, tenant_type, is_active) VALUES ($1, $2, 'T', 'university', true)`, f.TenantID, tenantSlug)
+	db.Exec(`INSERT INTO users (id, username, email, first_name, last_name, role, password_hash, is_active) VALUES ($1, 'stu', 's@t.com', 'S', 'T', 'student', 'h', true), ($2, 'adv', 'a@t.com', 'A', 'D', 'advisor', 'h', true)`, f.StudentID, f.AdvisorID)
+	db.Exec(`INSERT INTO student_advisors (student_id, advisor_id, tenant_id) VALUES ($1, $2, $3)`, f.StudentID, f.AdvisorID, f.TenantID)
+	db.Exec(`INSERT INTO playbook_versions (id, tenant_id, version, checksum, raw_json) VALUES ($1, $2, 'v1', 'c', '{}')`, f.PVVersionID, f.TenantID)
+	db.Exec(`INSERT INTO node_instances (id, tenant_id, node_id, user_id, state, playbook_version_id) VALUES ($1, $2, 'D3', $3, 'submitted', $4)`, f.NodeInstID, f.TenantID, f.StudentID, f.PVVersionID)
+	db.Exec(`INSERT INTO node_instance_slots (id, tenant_id, node_instance_id, slot_key) VALUES ($1, $2, $3, 'doc')`, f.SlotID, f.TenantID, f.NodeInstID)
+	db.Exec(`INSERT INTO documents (id, tenant_id, user_id, title, kind) VALUES ($1, $2, $3, 'Test', 'other')`, f.DocID, f.TenantID, f.StudentID)
+	db.QueryRow(`INSERT INTO document_versions (tenant_id, document_id, storage_path, mime_type, size_bytes, uploaded_by) VALUES ($1, $2, 'p', 'pdf', 100, $3) RETURNING id`, f.TenantID, f.DocID, f.StudentID).Scan(&f.DocVersionID)
+	db.QueryRow(`INSERT INTO node_instance_slot_attachments (slot_id, document_version_id, is_active, status, filename, size_bytes, attached_by) VALUES ($1, $2, true, 'submitted', 't.pdf', 100, $3) RETURNING id`, f.SlotID, f.DocVersionID, f.StudentID).Scan(&f.AttachmentID)
+
+	pbm := &pb.Manager{VersionID: f.PVVersionID, Nodes: map[string]pb.Node{"D3": {ID: "D3", WhoCanComplete: []string{"secretary"}}}}
+	repo := repository.NewSQLAdminRepository(db)
+	svc := services.NewAdminService(repo, pbm, config.AppConfig{}, nil)
+	jRepo := repository.NewSQLJourneyRepository(db)
+	jSvc := services.NewJourneyService(jRepo, pbm, config.AppConfig{}, nil, nil, nil)
+	svc.SetJourney(jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": f.AdvisorID, "role": "advisor"})
+		c.Next()
+	})
+	r.PATCH("/admin/attachments/:attachmentId/review", h.ReviewAttachment)
+
+	body := map[string]string{"status": "approved"}
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("PATCH", "/admin/attachments/"+f.AttachmentID+"/review", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code, "Advisor should not close a secretary-only node")
+
+	// Neither the attachment nor the node changed, and the denial is logged
+	var status, state string
+	require.NoError(t, db.Get(&status, `SELECT status FROM node_instance_slot_attachments WHERE id=$1`, f.AttachmentID))
+	assert.Equal(t, "submitted", status)
+	require.NoError(t, db.Get(&state, `SELECT state FROM node_instances WHERE id=$1`, f.NodeInstID))
+	assert.Equal(t, "submitted", state)
+	var denials int
+	require.NoError(t, db.Get(&denials, `SELECT COUNT(*) FROM node_events WHERE node_instance_id=$1 AND event_type='transition_denied'`, f.NodeInstID))
+	assert.Equal(t, 1, denials)
+}
+
 func TestReviewAttachment_AdminApprovesAnyStudent(t *testing.T) {
 	db, teardown := testutils.SetupTestDB()
 	defer teardown()
@@ -331,7 +394,8 @@ func TestReviewAttachment_AdminApprovesAnyStudent(t *testing.T) {
 	svc := services.NewAdminService(repo, pbm, config.AppConfig{}, nil)
 	jRepo := repository.NewSQLJourneyRepository(db)
 	jSvc := services.NewJourneyService(jRepo, pbm, config.AppConfig{}, nil, nil, nil)
-	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc)
+	svc.SetJourney(jSvc)
+	h := handlers.NewAdminHandler(config.AppConfig{}, pbm, svc, jSvc, nil)
 
 	r := gin.New()
 	r.Use(func(c *gin.Context) {
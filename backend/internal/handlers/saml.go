@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSAMLPostBytes bounds the form an IdP posts to the ACS
+const maxSAMLPostBytes = 1 << 20
+
+// SAMLHandler serves single sign-on through the tenant's SAML identity
+// provider, the SP metadata to register with it, and its configuration for
+// superadmins.
+type SAMLHandler struct {
+	svc *services.SAMLService
+	cfg config.AppConfig
+}
+
+func NewSAMLHandler(svc *services.SAMLService, cfg config.AppConfig) *SAMLHandler {
+	return &SAMLHandler{svc: svc, cfg: cfg}
+}
+
+// Status tells the login page whether to offer SAML sign-on, and where to
+// send the browser for it.
+// GET /api/auth/saml
+func (h *SAMLHandler) Status(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	if tenantID == "" || !h.svc.Enabled(c.Request.Context(), tenantID) {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "login_url": h.svc.LoginURL(middleware.GetTenantSlug(c))})
+}
+
+// Metadata returns the SP metadata for the tenant's IdP. It is available
+// before the IdP is configured, as universities usually want it first.
+// GET /api/auth/saml/metadata?tenant=slug
+func (h *SAMLHandler) Metadata(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	if tenantID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant is required"})
+		return
+	}
+	sp, err := h.svc.ServiceProvider(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tenant"})
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", sp.Metadata())
+}
+
+// Login sends the browser to the tenant's IdP. The optional redirect query
+// is the frontend path to land on afterwards.
+// GET /api/auth/saml/login?tenant=slug&redirect=/path
+func (h *SAMLHandler) Login(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	if tenantID == "" {
+		ssoFail(c, h.cfg, http.StatusFound, services.ErrSSONotConfigured)
+		return
+	}
+	authURL, err := h.svc.BeginLogin(c.Request.Context(), tenantID, c.Query("redirect"))
+	if err != nil {
+		ssoFail(c, h.cfg, http.StatusFound, err)
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ACS is the assertion consumer service: it completes the login the IdP
+// posts back, sets the session cookie and returns the browser to the
+// frontend.
+// POST /api/auth/saml/acs?tenant=slug
+func (h *SAMLHandler) ACS(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSAMLPostBytes)
+	result, err := h.svc.CompleteLogin(sessionContext(c), middleware.GetTenantID(c), c.PostForm("SAMLResponse"), c.PostForm("RelayState"))
+	if err != nil {
+		ssoFail(c, h.cfg, http.StatusSeeOther, err)
+		return
+	}
+	ssoFinish(c, h.cfg, http.StatusSeeOther, result)
+}
+
+// GetConfig returns a tenant's IdP configuration.
+// GET /superadmin/tenants/:id/saml
+func (h *SAMLHandler) GetConfig(c *gin.Context) {
+	cfg, err := h.svc.GetConfig(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		ssoConfigError(c, err, "load")
+		return
+	}
+	// Stored metadata was valid when saved; nil only leaves out the summary
+	idp, _ := saml.ParseIdPMetadata([]byte(cfg.IdPMetadata))
+	h.respondConfig(c, c.Param("id"), cfg, idp)
+}
+
+type samlConfigReq struct {
+	IdPMetadata        string            `json:"idp_metadata" binding:"required"`
+	Enabled            *bool             `json:"enabled"`
+	EmailAttribute     string            `json:"email_attribute"`
+	FirstNameAttribute string            `json:"first_name_attribute"`
+	LastNameAttribute  string            `json:"last_name_attribute"`
+	RoleAttribute      string            `json:"role_attribute"`
+	RoleMap            map[string]string `json:"role_map"`
+	DefaultRole        string            `json:"default_role"`
+	SyncRole           bool              `json:"sync_role"`
+	JITProvisioning    bool              `json:"jit_provisioning"`
+	AllowedDomains     []string          `json:"allowed_domains"`
+}
+
+// SaveConfig creates or replaces a tenant's IdP configuration.
+// PUT /superadmin/tenants/:id/saml
+func (h *SAMLHandler) SaveConfig(c *gin.Context) {
+	var req samlConfigReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg := &models.TenantSAMLConfig{
+		Enabled:            req.Enabled == nil || *req.Enabled,
+		IdPMetadata:        req.IdPMetadata,
+		EmailAttribute:     req.EmailAttribute,
+		FirstNameAttribute: req.FirstNameAttribute,
+		LastNameAttribute:  req.LastNameAttribute,
+		RoleAttribute:      req.RoleAttribute,
+		RoleMap:            req.RoleMap,
+		DefaultRole:        req.DefaultRole,
+		SyncRole:           req.SyncRole,
+		JITProvisioning:    req.JITProvisioning,
+		AllowedDomains:     req.AllowedDomains,
+	}
+	idp, err := h.svc.SaveConfig(c.Request.Context(), c.Param("id"), cfg)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSAMLInvalidConfig):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save sso configuration"})
+		}
+		return
+	}
+	h.respondConfig(c, c.Param("id"), cfg, idp)
+}
+
+// DeleteConfig turns SAML sign-on off for a tenant.
+// DELETE /superadmin/tenants/:id/saml
+func (h *SAMLHandler) DeleteConfig(c *gin.Context) {
+	if err := h.svc.DeleteConfig(c.Request.Context(), c.Param("id")); err != nil {
+		ssoConfigError(c, err, "delete")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sso configuration deleted"})
+}
+
+// respondConfig returns the configuration with what was read from the IdP
+// metadata and the SP details to register with the IdP.
+func (h *SAMLHandler) respondConfig(c *gin.Context, tenantID string, cfg *models.TenantSAMLConfig, idp *saml.IdentityProvider) {
+	sp, err := h.svc.ServiceProvider(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tenant"})
+		return
+	}
+	resp := gin.H{
+		"config": cfg,
+		"sp":     gin.H{"entity_id": sp.EntityID, "acs_url": sp.ACSURL, "metadata_url": sp.EntityID},
+	}
+	if idp != nil {
+		resp["idp"] = gin.H{
+			"entity_id":              idp.EntityID,
+			"sso_url":                idp.SSOURL,
+			"certificates_expire_at": idp.CertificatesExpireAt(),
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
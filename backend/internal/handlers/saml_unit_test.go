@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml/samltest"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSAMLHandler_Flow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	idp, err := samltest.New("https://idp.example.edu/saml")
+	require.NoError(t, err)
+	idp.User.NameID = "advisor@example.edu"
+	idp.User.Attributes[samltest.AttrMail] = []string{"advisor@example.edu"}
+
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, ServerURL: "http://localhost:8080", FrontendBase: "http://localhost:5173", Env: "development"}
+	users := NewMemUserRepository(&models.User{ID: "adv-1", Email: "advisor@example.edu", Role: models.RoleAdvisor, IsActive: true})
+	svc := services.NewSAMLService(&MemSAMLRepository{requests: map[string]*models.SAMLLoginRequest{}}, users, StubTenantRepository{}, services.NewAuthService(users, NewMemSessionRepository(), nil, nil, cfg), cfg)
+	h := handlers.NewSAMLHandler(svc, cfg)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenant_id", "t1")
+		c.Set("tenant_slug", "kaznmu")
+		c.Next()
+	})
+	r.GET("/api/auth/saml", h.Status)
+	r.GET("/api/auth/saml/metadata", h.Metadata)
+	r.GET("/api/auth/saml/login", h.Login)
+	r.POST("/api/auth/saml/acs", h.ACS)
+	r.PUT("/superadmin/tenants/:id/saml", h.SaveConfig)
+
+	do := func(method, target string, body string, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// atIdP follows the login redirect to the IdP and returns the form it
+	// posts to the ACS
+	atIdP := func(t *testing.T, w *httptest.ResponseRecorder) url.Values {
+		require.Equal(t, http.StatusFound, w.Code)
+		u, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		acs, form, err := idp.Respond(u.Query())
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/api/auth/saml/acs?tenant=kaznmu", acs)
+		return form
+	}
+
+	t.Run("SP metadata before configuration", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/auth/saml/metadata?tenant=kaznmu", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/samlmetadata+xml", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `entityID="http://localhost:8080/api/auth/saml/metadata?tenant=kaznmu"`)
+
+		w = do(http.MethodGet, "/api/auth/saml", "", "")
+		assert.JSONEq(t, `{"enabled":false}`, w.Body.String())
+		w = do(http.MethodGet, "/api/auth/saml/login", "", "")
+		assert.Equal(t, "http://localhost:5173/login?sso_error=not_configured", w.Header().Get("Location"))
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"idp_metadata":   string(idp.Metadata()),
+			"role_attribute": samltest.AttrAffiliation,
+			"role_map":       map[string]string{"faculty": "advisor"},
+		})
+		w := do(http.MethodPut, "/superadmin/tenants/t1/saml", string(body), "application/json")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"entity_id":"https://idp.example.edu/saml/metadata"`)
+		assert.Contains(t, w.Body.String(), `"acs_url":"http://localhost:8080/api/auth/saml/acs?tenant=kaznmu"`)
+
+		w = do(http.MethodPut, "/superadmin/tenants/t1/saml", `{"idp_metadata":"<nope/>"}`, "application/json")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(http.MethodGet, "/api/auth/saml", "", "")
+		assert.JSONEq(t, `{"enabled":true,"login_url":"http://localhost:8080/api/auth/saml/login?tenant=kaznmu"}`, w.Body.String())
+	})
+
+	t.Run("Login through the IdP", func(t *testing.T) {
+		form := atIdP(t, do(http.MethodGet, "/api/auth/saml/login?tenant=kaznmu&redirect=/advisor", "", ""))
+
+		w := do(http.MethodPost, "/api/auth/saml/acs?tenant=kaznmu", form.Encode(), "application/x-www-form-urlencoded")
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "http://localhost:5173/advisor", w.Header().Get("Location"))
+		var session *http.Cookie
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "jwt_token" {
+				session = c
+			}
+		}
+		require.NotNil(t, session)
+		assert.NotEmpty(t, session.Value)
+		assert.True(t, session.HttpOnly)
+
+		w = do(http.MethodPost, "/api/auth/saml/acs?tenant=kaznmu", form.Encode(), "application/x-www-form-urlencoded")
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "http://localhost:5173/login?sso_error=expired", w.Header().Get("Location"), "a response is accepted once")
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		idp.User.Attributes[samltest.AttrMail] = []string{"stranger@example.edu"}
+		form := atIdP(t, do(http.MethodGet, "/api/auth/saml/login", "", ""))
+		w := do(http.MethodPost, "/api/auth/saml/acs?tenant=kaznmu", form.Encode(), "application/x-www-form-urlencoded")
+		assert.Equal(t, "http://localhost:5173/login?sso_error=no_account", w.Header().Get("Location"))
+	})
+}
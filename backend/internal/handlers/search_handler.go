@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -19,18 +22,25 @@ func NewSearchHandler(svc *services.SearchService, cfg config.AppConfig) *Search
 	return &SearchHandler{svc: svc, cfg: cfg}
 }
 
+// searchScope is the caller's tenant, user and role.
+func searchScope(c *gin.Context) repository.SearchScope {
+	return repository.SearchScope{
+		TenantID: c.GetString("tenant_id"),
+		UserID:   userIDFromClaims(c),
+		Role:     roleFromContext(c),
+	}
+}
+
+// GlobalSearch returns the best few matches of every type for the
+// type-ahead.
 func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 	query := strings.TrimSpace(c.Query("q"))
-	if len(query) < 2 {
+	if len([]rune(query)) < 2 {
 		c.JSON(http.StatusOK, []models.SearchResult{})
 		return
 	}
 
-	// RBAC
-	role := roleFromContext(c)
-	userID := userIDFromClaims(c)
-
-	results, err := h.svc.GlobalSearch(c.Request.Context(), query, role, userID)
+	results, err := h.svc.GlobalSearch(c.Request.Context(), searchScope(c), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -38,3 +48,26 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 
 	c.JSON(http.StatusOK, results)
 }
+
+// Search returns a page of matches of one type:
+// GET /search/:type?q=...&limit=20&offset=0
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if len([]rune(query)) < 2 {
+		c.JSON(http.StatusOK, models.SearchPage{Type: c.Param("type"), Query: query, Results: []models.SearchResult{}, Limit: limit, Offset: offset})
+		return
+	}
+
+	page, err := h.svc.Search(c.Request.Context(), searchScope(c), c.Param("type"), query, limit, offset)
+	if errors.Is(err, services.ErrSearchUnknownType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "types": services.SearchTypes})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -29,11 +29,14 @@ func TestSearchHandler_GlobalSearch(t *testing.T) {
 		($1, 'admin', 'admin@ex.com', 'Admin', 'User', 'admin', 'hash', true),
 		($2, 'student', 'student@ex.com', 'Student', 'User', 'student', 'hash', true)`, adminID, studentID)
 	require.NoError(t, err)
+	defaultTenantID := "00000000-0000-0000-0000-000000000001"
+	_, err = db.Exec(`INSERT INTO user_tenant_memberships (user_id, tenant_id, role, is_primary)
+		VALUES ($1, $3, 'admin', true), ($2, $3, 'student', true)`, adminID, studentID, defaultTenantID)
+	require.NoError(t, err)
 
 	// Seed document (needs node instance structure)
 	// 1. Create playbook version
 	var pvID string
-	defaultTenantID := "00000000-0000-0000-0000-000000000001"
 	err = db.QueryRow(`INSERT INTO playbook_versions (version, checksum, raw_json, created_at, tenant_id) VALUES ('v1', 'sum', '{}', NOW(), $1) RETURNING id`, defaultTenantID).Scan(&pvID)
 	require.NoError(t, err)
 
@@ -74,6 +77,7 @@ func TestSearchHandler_GlobalSearch(t *testing.T) {
 		// Mock auth middleware setting role and userID
 		role := c.GetHeader("X-Role")
 		uid := c.GetHeader("X-User-ID")
+		c.Set("tenant_id", defaultTenantID)
 		if role != "" {
 			c.Set("role", role)
 			c.Set("claims", jwt.MapClaims{"sub": uid, "role": role})
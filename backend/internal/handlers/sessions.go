@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/middleware"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionsHandler lets users see and end their sign-in sessions, and
+// admins sign a user out everywhere.
+type SessionsHandler struct {
+	authService *services.AuthService
+	cfg         config.AppConfig
+}
+
+func NewSessionsHandler(authService *services.AuthService, cfg config.AppConfig) *SessionsHandler {
+	return &SessionsHandler{authService: authService, cfg: cfg}
+}
+
+// sessionView is a session as listed, marking the one making the request.
+type sessionView struct {
+	models.UserSession
+	Current bool `json:"current"`
+}
+
+// ListMine returns the caller's active sessions.
+// GET /api/me/sessions
+func (h *SessionsHandler) ListMine(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		log.Printf("[SessionsHandler.ListMine] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	current := middleware.GetSessionID(c)
+	views := make([]sessionView, len(sessions))
+	for i, s := range sessions {
+		views[i] = sessionView{UserSession: s, Current: s.ID == current}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": views})
+}
+
+// RevokeMine signs one of the caller's sessions out.
+// DELETE /api/me/sessions/:id
+func (h *SessionsHandler) RevokeMine(c *gin.Context) {
+	id := c.Param("id")
+	err := h.authService.RevokeSession(c.Request.Context(), c.GetString("userID"), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("[SessionsHandler.RevokeMine] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if id == middleware.GetSessionID(c) {
+		clearSessionCookies(c, h.cfg)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeAllMine signs the caller out everywhere, including here.
+// POST /api/me/sessions/revoke-all
+func (h *SessionsHandler) RevokeAllMine(c *gin.Context) {
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), c.GetString("userID")); err != nil {
+		log.Printf("[SessionsHandler.RevokeAllMine] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	clearSessionCookies(c, h.cfg)
+	c.JSON(http.StatusOK, gin.H{"message": "signed out everywhere"})
+}
+
+// ListUser returns a user's active sessions for an admin.
+// GET /api/admin/users/:id/sessions
+func (h *SessionsHandler) ListUser(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		log.Printf("[SessionsHandler.ListUser] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeUser signs a user out everywhere; their access tokens stop
+// working at once.
+// POST /api/admin/users/:id/sessions/revoke
+func (h *SessionsHandler) RevokeUser(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), id); err != nil {
+		log.Printf("[SessionsHandler.RevokeUser] error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	log.Printf("[SessionsHandler.RevokeUser] admin=%s revoked sessions of user=%s", c.GetString("userID"), id)
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked"})
+}
@@ -0,0 +1,161 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/handlers"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_Flow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash, _ := auth.HashPassword("pass")
+	users := NewMemUserRepository(&models.User{ID: "u1", Username: "john", PasswordHash: hash, Role: models.RoleStudent, IsActive: true})
+	store := NewMemSessionRepository()
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, AccessTokenMinutes: 15, Env: "development"}
+	authService := services.NewAuthService(users, store, nil, nil, cfg)
+	h := handlers.NewAuthHandler(authService, cfg, nil)
+	sh := handlers.NewSessionsHandler(authService, cfg)
+
+	r := gin.New()
+	r.POST("/api/auth/login", h.Login)
+	r.POST("/api/auth/refresh", h.Refresh)
+	r.POST("/api/auth/logout", h.Logout)
+	// Stands in for AuthMiddleware, which needs the database
+	me := r.Group("/api/me", func(c *gin.Context) {
+		c.Set("userID", "u1")
+		c.Set("session_id", c.GetHeader("X-Session"))
+	})
+	me.GET("/sessions", sh.ListMine)
+	me.DELETE("/sessions/:id", sh.RevokeMine)
+	me.POST("/sessions/revoke-all", sh.RevokeAllMine)
+	r.POST("/api/admin/users/:id/sessions/revoke", sh.RevokeUser)
+
+	do := func(method, target, body, refresh, session string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Firefox")
+		if refresh != "" {
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refresh})
+		}
+		if session != "" {
+			req.Header.Set("X-Session", session)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+	login := func(t *testing.T) *http.Cookie {
+		t.Helper()
+		w := do(http.MethodPost, "/api/auth/login", `{"username":"john","password":"pass"}`, "", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		access, refresh := cookie(w, "jwt_token"), cookie(w, "refresh_token")
+		require.NotNil(t, access)
+		require.NotNil(t, refresh)
+		assert.Equal(t, 15*60, access.MaxAge)
+		assert.Equal(t, "/api/auth", refresh.Path)
+		assert.Equal(t, 24*60*60, refresh.MaxAge)
+		assert.True(t, refresh.HttpOnly)
+		return refresh
+	}
+
+	t.Run("Refresh rotates the cookie", func(t *testing.T) {
+		refresh := login(t)
+		w := do(http.MethodPost, "/api/auth/refresh", "", refresh.Value, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"role":"student","is_superadmin":false}`, w.Body.String())
+		next := cookie(w, "refresh_token")
+		require.NotNil(t, next)
+		assert.NotEqual(t, refresh.Value, next.Value)
+		assert.NotNil(t, cookie(w, "jwt_token"))
+
+		w = do(http.MethodPost, "/api/auth/refresh", "", "", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = do(http.MethodPost, "/api/auth/refresh", "", "forged", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, -1, cookie(w, "refresh_token").MaxAge, "a dead session's cookies are cleared")
+	})
+
+	t.Run("My sessions", func(t *testing.T) {
+		require.NoError(t, authService.RevokeAllSessions(context.Background(), "u1"))
+		first, second := login(t), login(t)
+		current, err := store.GetByTokenHash(context.Background(), hashOf(second.Value))
+		require.NoError(t, err)
+
+		w := do(http.MethodGet, "/api/me/sessions", "", "", current.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		var body struct {
+			Sessions []struct {
+				ID        string `json:"id"`
+				UserAgent string `json:"user_agent"`
+				Current   bool   `json:"current"`
+			} `json:"sessions"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		require.Len(t, body.Sessions, 2)
+		for _, s := range body.Sessions {
+			assert.Equal(t, "Firefox", s.UserAgent)
+			assert.Equal(t, s.ID == current.ID, s.Current)
+		}
+		assert.NotContains(t, w.Body.String(), "hash")
+
+		other, err := store.GetByTokenHash(context.Background(), hashOf(first.Value))
+		require.NoError(t, err)
+		w = do(http.MethodDelete, "/api/me/sessions/"+other.ID, "", "", current.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, cookie(w, "jwt_token"), "revoking another device keeps this one signed in")
+		w = do(http.MethodPost, "/api/auth/refresh", "", first.Value, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = do(http.MethodDelete, "/api/me/sessions/"+other.ID, "", "", current.ID)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do(http.MethodPost, "/api/me/sessions/revoke-all", "", "", current.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, -1, cookie(w, "jwt_token").MaxAge)
+		w = do(http.MethodPost, "/api/auth/refresh", "", second.Value, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Logout ends the session", func(t *testing.T) {
+		refresh := login(t)
+		w := do(http.MethodPost, "/api/auth/logout", "", refresh.Value, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, -1, cookie(w, "refresh_token").MaxAge)
+		w = do(http.MethodPost, "/api/auth/refresh", "", refresh.Value, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Admin revocation", func(t *testing.T) {
+		refresh := login(t)
+		w := do(http.MethodPost, "/api/admin/users/u1/sessions/revoke", "", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		w = do(http.MethodPost, "/api/auth/refresh", "", refresh.Value, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func hashOf(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
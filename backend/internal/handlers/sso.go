@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ssoFail returns the browser to the frontend login page with an error code
+// it can show. status is the redirect status; logins answering a POST use
+// 303 so the browser follows with a GET.
+func ssoFail(c *gin.Context, cfg config.AppConfig, status int, err error) {
+	code := "failed"
+	switch {
+	case errors.Is(err, services.ErrSSONotConfigured):
+		code = "not_configured"
+	case errors.Is(err, services.ErrSSOLoginExpired):
+		code = "expired"
+	case errors.Is(err, services.ErrSSODeclined):
+		code = "declined"
+	case errors.Is(err, services.ErrSSOEmailNotAllowed):
+		code = "email_not_allowed"
+	case errors.Is(err, services.ErrSSONoAccount):
+		code = "no_account"
+	case errors.Is(err, services.ErrSSOAccountInactive):
+		code = "inactive"
+	case errors.Is(err, services.ErrSSOAccessDenied):
+		code = "access_denied"
+	default:
+		log.Printf("[SSO] Login failed: %v", err)
+	}
+	c.Redirect(status, frontendURL(cfg, "/login", url.Values{"sso_error": {code}}))
+}
+
+func frontendURL(cfg config.AppConfig, path string, q url.Values) string {
+	u := strings.TrimSuffix(strings.Trim(cfg.FrontendBase, "\"' \t"), "/") + services.SafeRedirectPath(path)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// ssoFinish sets the session cookie of a completed SSO login and returns
+// the browser to the frontend. A login that needs a second factor goes to
+// the login page instead, which asks for the code and then redirects.
+func ssoFinish(c *gin.Context, cfg config.AppConfig, status int, result *services.SSOLoginResult) {
+	if result.MFA != nil {
+		q := url.Values{"mfa_token": {result.MFA.Token}, "redirect": {services.SafeRedirectPath(result.RedirectPath)}}
+		if result.MFA.Enroll {
+			q.Set("enrollment_required", "1")
+		}
+		c.Redirect(status, frontendURL(cfg, "/login", q))
+		return
+	}
+	setSessionCookie(c, cfg, &result.LoginResponse)
+	c.Redirect(status, frontendURL(cfg, result.RedirectPath, nil))
+}
+
+// ssoConfigError answers a failed load or delete of a tenant's SSO
+// configuration.
+func ssoConfigError(c *gin.Context, err error, action string) {
+	if errors.Is(err, services.ErrSSONotConfigured) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action + " sso configuration"})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	dbx := sqlx.NewDb(db, "sqlmock")
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(TenantIDContextKey, c.GetHeader("X-Tenant")) })
+	adm := r.Group("/api/admin")
+	adm.Use(AuthMiddleware([]byte("secret"), dbx, nil))
+	adm.Use(RequireAdminOrAdvisor())
+	adm.GET("/users", func(c *gin.Context) {
+		claims := c.MustGet("claims").(jwt.MapClaims)
+		c.JSON(http.StatusOK, gin.H{"user": c.GetString("userID"), "role": claims["role"], "key": GetAPIKeyID(c)})
+	})
+	adm.GET("/users/:id/sessions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const token = models.APIKeyPrefix + "0123456789abcdef"
+	do := func(path, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Tenant", tenant)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	keyRow := func(scopes string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "tenant_id", "user_id", "scopes", "role"}).AddRow("k1", "t1", "u1", scopes, "admin")
+	}
+	lookup := `FROM api_keys k\s+JOIN users u ON u.id = k.user_id AND u.is_active\s+JOIN user_tenant_memberships m`
+
+	t.Run("Routes outside the scope table refuse keys", func(t *testing.T) {
+		w := do("/api/admin/users/u2/sessions", "t1")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("A key acts as its creator and its use is logged", func(t *testing.T) {
+		mock.ExpectQuery(lookup).WithArgs(models.HashAPIKey(token)).WillReturnRows(keyRow("{users:read}"))
+		mock.ExpectQuery(`FROM users WHERE id=\$1 AND is_active=true`).WithArgs("u1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role"}).AddRow("u1", "registrar.admin", "admin"))
+		mock.ExpectExec(`UPDATE api_keys SET last_used_at = now\(\), last_used_ip = \$2 WHERE id = \$1`).
+			WithArgs("k1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO activity_logs .* VALUES \(\$1, \$2, 'api_key', 'api_key', \$3, \$4`).
+			WithArgs("u1", "t1", "k1", "GET /api/admin/users", sqlmock.AnyArg(), sqlmock.AnyArg(), `{"path":"/api/admin/users","scope":"users:read","status":200}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		w := do("/api/admin/users", "t1")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"user":"u1","role":"admin","key":"k1"}`, w.Body.String())
+	})
+
+	t.Run("Scope and tenant are checked", func(t *testing.T) {
+		mock.ExpectQuery(lookup).WithArgs(models.HashAPIKey(token)).WillReturnRows(keyRow("{students:read}"))
+		w := do("/api/admin/users", "t1")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "users:read")
+
+		mock.ExpectQuery(lookup).WithArgs(models.HashAPIKey(token)).WillReturnRows(keyRow("{users:read}"))
+		w = do("/api/admin/users", "t2")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Unknown, expired or revoked keys", func(t *testing.T) {
+		mock.ExpectQuery(lookup).WithArgs(models.HashAPIKey(token)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		w := do("/api/admin/users", "t1")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -179,17 +179,55 @@ func HydrateUserFromClaims(c *gin.Context, dbx *sqlx.DB, rds interface{}) {
 	c.Set("userRole", u.Role)
 }
 
+// sessionActive tells whether the session an access token was issued for
+// is still live. Live sessions are cached briefly; revoking one drops its
+// cache key, so revocation takes effect on the next request.
+func sessionActive(c *gin.Context, dbx *sqlx.DB, rc *redis.Client, sid, sub string) bool {
+	key := "session:" + sid
+	if s, err := db.CacheGet(rc, key); err == nil && s == sub {
+		return true
+	}
+	var active bool
+	err := dbx.GetContext(c.Request.Context(), &active, `
+		SELECT revoked_at IS NULL AND expires_at > now()
+		FROM user_sessions WHERE id = $1 AND user_id = $2`, sid, sub)
+	if err != nil || !active {
+		log.Printf("[AuthMiddleware] session not active: sid=%s, sub=%s, err=%v", sid, sub, err)
+		return false
+	}
+	db.CacheSet(rc, key, sub, time.Minute)
+	return true
+}
+
+// GetSessionID returns the session of the request's access token.
+func GetSessionID(c *gin.Context) string {
+	return c.GetString("session_id")
+}
+
 func AuthMiddleware(secret []byte, dbx *sqlx.DB, rds *redis.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Printf("[AuthMiddleware] Starting for path=%s", c.Request.URL.Path)
-		
+
+		// Integrations authenticate with an API key instead of a session
+		if key, ok := bearerAPIKey(c); ok {
+			authenticateAPIKey(c, dbx, rds, key)
+			return
+		}
+
 		// Validate JWT without calling c.Next()
 		claims, ok := validateJWT(c, secret)
 		if !ok {
 			log.Printf("[AuthMiddleware] JWT validation failed for path=%s", c.Request.URL.Path)
 			return
 		}
+		sid, _ := claims["sid"].(string)
+		sub, _ := claims["sub"].(string)
+		if sid == "" || !sessionActive(c, dbx, rds, sid, sub) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session expired", "details": "the session was revoked or has ended - refresh or sign in again"})
+			return
+		}
 		c.Set("claims", claims)
+		c.Set("session_id", sid)
 		
 		// Extract superadmin and tenant claims from JWT
 		if isSuperadmin, ok := claims["is_superadmin"].(bool); ok {
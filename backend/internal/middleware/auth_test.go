@@ -232,18 +232,23 @@ func TestAuthMiddleware_FullFlow(t *testing.T) {
 		VALUES ($1, 'testuser', 'test@example.com', 'Test', 'User', 'student', 'hash', true)
 		ON CONFLICT (id) DO NOTHING`, userID)
 	require.NoError(t, err)
+	var sid string
+	err = db.Get(&sid, `INSERT INTO user_sessions (user_id, refresh_token_hash, expires_at)
+		VALUES ($1, 'full-flow', now() + interval '1 day') RETURNING id`, userID)
+	require.NoError(t, err)
 
 	router := gin.New()
 	router.Use(AuthMiddleware(secret, db, nil))
 	router.GET("/test", func(c *gin.Context) {
 		id := c.GetString("userID")
 		role := c.GetString("userRole")
-		c.JSON(200, gin.H{"userID": id, "role": role})
+		c.JSON(200, gin.H{"userID": id, "role": role, "sid": GetSessionID(c)})
 	})
 
 	token := createTestToken(secret, jwt.MapClaims{
 		"sub":  userID,
 		"role": "student",
+		"sid":  sid,
 		"exp":  time.Now().Add(time.Hour).Unix(),
 	})
 
@@ -255,6 +260,41 @@ func TestAuthMiddleware_FullFlow(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Contains(t, w.Body.String(), userID)
 	assert.Contains(t, w.Body.String(), "student")
+	assert.Contains(t, w.Body.String(), sid)
+
+	// A revoked session's access token stops working at once
+	_, err = db.Exec(`UPDATE user_sessions SET revoked_at = now() WHERE id = $1`, sid)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "session expired")
+}
+
+func TestAuthMiddleware_TokenWithoutSession(t *testing.T) {
+	db, teardown := testutils.SetupTestDB()
+	defer teardown()
+
+	secret := []byte("test-secret")
+	router := gin.New()
+	router.Use(AuthMiddleware(secret, db, nil))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	// Long-lived tokens from before sessions cannot be revoked
+	token := createTestToken(secret, jwt.MapClaims{
+		"sub":  "123e4567-e89b-12d3-a456-426614174099",
+		"role": "student",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	})
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "session expired")
 }
 
 func TestAuthMiddleware_UserNotInDB(t *testing.T) {
@@ -272,6 +312,47 @@ func TestAuthMiddleware_UserNotInDB(t *testing.T) {
 	token := createTestToken(secret, jwt.MapClaims{
 		"sub":  "nonexistent-user-id",
 		"role": "student",
+		"sid":  "123e4567-e89b-12d3-a456-426614174097",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Sessions belong to existing users, so the session check turns it away
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotContains(t, w.Body.String(), `"ok"`)
+}
+
+func TestAuthMiddleware_InactiveUserWithSession(t *testing.T) {
+	db, teardown := testutils.SetupTestDB()
+	defer teardown()
+
+	secret := []byte("test-secret")
+
+	router := gin.New()
+	router.Use(AuthMiddleware(secret, db, nil))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	// A deactivated user whose session is still live
+	userID := "123e4567-e89b-12d3-a456-426614174098"
+	_, err := db.Exec(`INSERT INTO users (id, username, email, first_name, last_name, role, password_hash, is_active) 
+		VALUES ($1, 'inactiveuser', 'inactive@example.com', 'Test', 'User', 'student', 'hash', false)
+		ON CONFLICT (id) DO NOTHING`, userID)
+	require.NoError(t, err)
+	var sid string
+	err = db.Get(&sid, `INSERT INTO user_sessions (user_id, refresh_token_hash, expires_at)
+		VALUES ($1, 'not-in-db', now() + interval '1 day') RETURNING id`, userID)
+	require.NoError(t, err)
+
+	token := createTestToken(secret, jwt.MapClaims{
+		"sub":  userID,
+		"role": "student",
+		"sid":  sid,
 		"exp":  time.Now().Add(time.Hour).Unix(),
 	})
 
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"log"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/gin-gonic/gin"
+)
+
+// PlaybookResolver returns the active playbook for a tenant
+type PlaybookResolver interface {
+	ForTenant(ctx context.Context, tenantID string) (*playbook.Manager, error)
+}
+
+// TenantPlaybook attaches the request tenant's active playbook to the request
+// context. Should be used after TenantMiddleware. If the playbook cannot be
+// resolved, services fall back to the playbook loaded at startup.
+func TenantPlaybook(resolver PlaybookResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := GetTenantID(c)
+		if tenantID != "" {
+			mgr, err := resolver.ForTenant(c.Request.Context(), tenantID)
+			if err != nil {
+				log.Printf("[Playbook] Failed to resolve playbook for tenant %s: %v", tenantID, err)
+			} else if mgr != nil {
+				c.Request = c.Request.WithContext(playbook.WithManager(c.Request.Context(), mgr))
+			}
+		}
+		c.Next()
+	}
+}
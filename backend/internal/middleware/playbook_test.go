@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubPlaybookResolver map[string]*playbook.Manager
+
+func (s stubPlaybookResolver) ForTenant(ctx context.Context, tenantID string) (*playbook.Manager, error) {
+	if m, ok := s[tenantID]; ok {
+		return m, nil
+	}
+	return nil, errors.New("unknown tenant")
+}
+
+func TestTenantPlaybook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resolver := stubPlaybookResolver{"t1": {VersionID: "v-t1"}}
+
+	setup := func(tenantID string) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if tenantID != "" {
+				c.Set(TenantIDContextKey, tenantID)
+			}
+			c.Next()
+		})
+		r.Use(TenantPlaybook(resolver))
+		r.GET("/test", func(c *gin.Context) {
+			m, ok := playbook.FromContext(c.Request.Context())
+			if !ok {
+				c.String(200, "none")
+				return
+			}
+			c.String(200, m.VersionID)
+		})
+		return r
+	}
+
+	for _, tc := range []struct{ tenant, want string }{
+		{"t1", "v-t1"},
+		{"t2", "none"},
+		{"", "none"},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		setup(tc.tenant).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tc.want, w.Body.String(), tc.tenant)
+	}
+}
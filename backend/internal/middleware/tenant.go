@@ -61,13 +61,19 @@ func TenantMiddleware(db *sqlx.DB) gin.HandlerFunc {
 }
 
 // resolveTenantSlug extracts tenant slug from request
-// Priority: 1) X-Tenant-Slug header 2) Subdomain
+// Priority: 1) X-Tenant-Slug header 2) tenant query parameter 3) Subdomain
 func resolveTenantSlug(c *gin.Context) string {
 	// Check header first (useful for dev/testing)
 	if header := c.GetHeader("X-Tenant-Slug"); header != "" {
 		return strings.ToLower(strings.TrimSpace(header))
 	}
 
+	// Browser redirects (SSO login and its callback) cannot set headers,
+	// so they name the tenant in the query
+	if q := c.Query("tenant"); q != "" {
+		return strings.ToLower(strings.TrimSpace(q))
+	}
+
 	// Extract from subdomain
 	host := c.Request.Host
 
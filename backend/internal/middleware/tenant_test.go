@@ -267,4 +267,13 @@ func TestResolveTenantSlug(t *testing.T) {
 		c.Request.Host = "app.phd-portal.kz"
 		assert.Equal(t, "", resolveTenantSlug(c))
 	})
+	t.Run("Query parameter", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/api/auth/oidc/callback?tenant=KazNMU&code=x", nil)
+		c.Request.Host = "api.phd-portal.kz"
+		assert.Equal(t, "kaznmu", resolveTenantSlug(c))
+
+		c.Request.Header.Set("X-Tenant-Slug", "other")
+		assert.Equal(t, "other", resolveTenantSlug(c))
+	})
 }
@@ -138,6 +138,15 @@ type NodeFile struct {
 	ReviewedAt          *string `json:"-" db:"reviewed_at"`
 	
 	IsActive bool `json:"is_active" db:"is_active"`
+
+	// Extracted text, filled in by the text extraction job
+	TextStatus *string `json:"text_status,omitempty" db:"text_status"`
+	PageCount  *int    `json:"page_count,omitempty" db:"page_count"`
+	WordCount  *int    `json:"word_count,omitempty" db:"word_count"`
+
+	// In-house similarity check of dissertation versions
+	SimilarityScore    *float64 `json:"similarity_score,omitempty" db:"similarity_score"`
+	SimilarityReportID *string  `json:"similarity_report_id,omitempty" db:"similarity_report_id"`
 	
 	// Computed Fields
 	DownloadURL string `json:"download_url"`
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyPrefix starts every API key, telling it apart from a JWT.
+const APIKeyPrefix = "phdk_"
+
+// API key scopes. A key may only call the routes of its scopes.
+const (
+	ScopeStudentsRead = "students:read"
+	ScopeJourneyRead  = "journey:read"
+	ScopeUsersRead    = "users:read"
+	ScopeUsersWrite   = "users:write"
+)
+
+// APIKeyScopes describes the scopes a key can be given.
+var APIKeyScopes = map[string]string{
+	ScopeStudentsRead: "List students and read their progress and deadlines",
+	ScopeJourneyRead:  "Read a student's journey and submitted files",
+	ScopeUsersRead:    "List users",
+	ScopeUsersWrite:   "Create and update users, activate and deactivate them",
+}
+
+// APIKey lets an integration call the API in one tenant as the admin who
+// created it, limited to its scopes.
+type APIKey struct {
+	ID       string `db:"id" json:"id"`
+	TenantID string `db:"tenant_id" json:"tenant_id"`
+	UserID   string `db:"user_id" json:"user_id"`
+	Name     string `db:"name" json:"name"`
+	// Prefix is the start of the key, to recognise it by
+	Prefix     string         `db:"prefix" json:"prefix"`
+	KeyHash    string         `db:"key_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt  time.Time      `db:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	LastUsedIP *string        `db:"last_used_ip" json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// HasScope tells whether the key was given the scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIKey is the hash a key is stored and looked up by.
+func HashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -17,19 +17,39 @@ const (
 )
 
 type ChatRoom struct {
-	ID            string          `db:"id" json:"id"`
-	TenantID      string          `db:"tenant_id" json:"tenant_id"` // Added for multitenancy
-	Name          string          `db:"name" json:"name"`
-	Type          ChatRoomType    `db:"type" json:"type"`
-	CreatedBy     string          `db:"created_by" json:"created_by"`
-	CreatedByRole Role            `db:"created_by_role" json:"created_by_role"`
-	IsArchived    bool            `db:"is_archived" json:"is_archived"`
-	Meta          json.RawMessage `db:"meta" json:"meta,omitempty"`
-	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
-	UnreadCount   int             `db:"unread_count" json:"unread_count"`
-	LastMessageAt *time.Time      `db:"last_message_at" json:"last_message_at,omitempty"`
+	ID              string              `db:"id" json:"id"`
+	TenantID        string              `db:"tenant_id" json:"tenant_id"` // Added for multitenancy
+	Name            string              `db:"name" json:"name"`
+	Type            ChatRoomType        `db:"type" json:"type"`
+	CreatedBy       string              `db:"created_by" json:"created_by"`
+	CreatedByRole   Role                `db:"created_by_role" json:"created_by_role"`
+	IsArchived      bool                `db:"is_archived" json:"is_archived"`
+	Meta            json.RawMessage     `db:"meta" json:"meta,omitempty"`
+	CreatedAt       time.Time           `db:"created_at" json:"created_at"`
+	UnreadCount     int                 `db:"unread_count" json:"unread_count"`
+	LastMessageAt   *time.Time          `db:"last_message_at" json:"last_message_at,omitempty"`
+	PostPolicy      ChatPostPolicy      `db:"post_policy" json:"post_policy,omitempty"`
+	RetentionDays   *int                `db:"retention_days" json:"retention_days,omitempty"`
+	RetentionAction ChatRetentionAction `db:"retention_action" json:"retention_action,omitempty"`
 }
 
+// ChatPostPolicy decides who may post in a room.
+type ChatPostPolicy string
+
+const (
+	ChatPostPolicyEveryone ChatPostPolicy = "everyone"
+	// ChatPostPolicyModerators makes a channel read-only for plain members
+	ChatPostPolicyModerators ChatPostPolicy = "moderators"
+)
+
+// ChatRetentionAction is what happens to messages past a room's retention.
+type ChatRetentionAction string
+
+const (
+	ChatRetentionDelete  ChatRetentionAction = "delete"
+	ChatRetentionArchive ChatRetentionAction = "archive"
+)
+
 type ChatRoomMemberRole string
 
 const (
@@ -45,6 +65,12 @@ type ChatRoomMember struct {
 	RoleInRoom ChatRoomMemberRole `db:"role_in_room" json:"role_in_room"`
 	JoinedAt   time.Time          `db:"joined_at" json:"joined_at"`
 	LastReadAt *time.Time         `db:"last_read_at" json:"last_read_at"`
+	MutedUntil *time.Time         `db:"muted_until" json:"muted_until,omitempty"`
+}
+
+// IsMuted reports whether the member is muted at t.
+func (m ChatRoomMember) IsMuted(t time.Time) bool {
+	return m.MutedUntil != nil && m.MutedUntil.After(t)
 }
 
 type MemberWithUser struct {
@@ -58,6 +84,7 @@ type MemberWithUser struct {
 	LastName   string             `db:"last_name" json:"last_name"`
 	Email      string             `db:"email" json:"email"`
 	Username   string             `db:"username" json:"username"`
+	MutedUntil *time.Time         `db:"muted_until" json:"muted_until,omitempty"`
 }
 
 // UserInfo holds partial user details for notifications/displays.
@@ -82,6 +109,25 @@ type ChatMessage struct {
 	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
 	EditedAt    *time.Time      `db:"edited_at" json:"edited_at,omitempty"`
 	DeletedAt   *time.Time      `db:"deleted_at" json:"deleted_at,omitempty"`
+	ParentID    *string         `db:"parent_id" json:"parent_id,omitempty"`
+	ReplyCount  int             `db:"reply_count" json:"reply_count"`
+	LastReplyAt *time.Time      `db:"last_reply_at" json:"last_reply_at,omitempty"`
+	PinnedAt    *time.Time      `db:"pinned_at" json:"pinned_at,omitempty"`
+	PinnedBy    *string         `db:"pinned_by" json:"pinned_by,omitempty"`
+	Reactions   []ChatReaction  `db:"-" json:"reactions,omitempty"`
+}
+
+// ChatReaction aggregates one emoji on a message.
+type ChatReaction struct {
+	Emoji   string   `json:"emoji"`
+	Count   int      `json:"count"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// ChatThread is a parent message with the viewer's unread reply count.
+type ChatThread struct {
+	ChatMessage
+	UnreadCount int `db:"unread_count" json:"unread_count"`
 }
 
 type ChatAttachment struct {
@@ -89,6 +135,87 @@ type ChatAttachment struct {
 	Type string `json:"type"` // "image", "document", etc.
 	Name string `json:"name"`
 	Size int64  `json:"size"`
+	// Set for files kept in object storage; URL then points at the
+	// membership-checked download endpoint
+	DocumentID string `json:"document_id,omitempty"`
+	VersionID  string `json:"version_id,omitempty"`
+}
+
+// ChatFile binds a stored document version to the room it was shared in.
+type ChatFile struct {
+	VersionID  string    `db:"version_id" json:"version_id"`
+	DocumentID string    `db:"document_id" json:"document_id"`
+	RoomID     string    `db:"room_id" json:"room_id"`
+	UploadedBy *string   `db:"uploaded_by" json:"uploaded_by,omitempty"`
+	ObjectKey  string    `db:"object_key" json:"object_key"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// Moderation log actions.
+const (
+	ChatModDeleteMessage    = "delete_message"
+	ChatModMute             = "mute"
+	ChatModUnmute           = "unmute"
+	ChatModBan              = "ban"
+	ChatModUnban            = "unban"
+	ChatModSettings         = "settings"
+	ChatModRetentionDelete  = "retention_delete"
+	ChatModRetentionArchive = "retention_archive"
+)
+
+// ChatModerationEntry is one row of a room's moderation log.
+type ChatModerationEntry struct {
+	ID              string          `db:"id" json:"id"`
+	TenantID        *string         `db:"tenant_id" json:"tenant_id,omitempty"`
+	RoomID          string          `db:"room_id" json:"room_id"`
+	ActorID         *string         `db:"actor_id" json:"actor_id,omitempty"`
+	ActorName       *string         `db:"actor_name" json:"actor_name,omitempty"`
+	Action          string          `db:"action" json:"action"`
+	TargetUserID    *string         `db:"target_user_id" json:"target_user_id,omitempty"`
+	TargetMessageID *string         `db:"target_message_id" json:"target_message_id,omitempty"`
+	Reason          string          `db:"reason" json:"reason"`
+	Details         json.RawMessage `db:"details" json:"details,omitempty"`
+	CreatedAt       time.Time       `db:"created_at" json:"created_at"`
+}
+
+// ChatRoomBan keeps a user out of a room.
+type ChatRoomBan struct {
+	RoomID    string    `db:"room_id" json:"room_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	BannedBy  *string   `db:"banned_by" json:"banned_by,omitempty"`
+	Reason    string    `db:"reason" json:"reason"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// ChatManagedKind is the source a managed room is kept in sync with.
+type ChatManagedKind string
+
+const (
+	// ChatManagedCohort rooms follow a cohorts row; Ref is the cohort id
+	ChatManagedCohort ChatManagedKind = "cohort"
+	// ChatManagedAdvisory rooms follow an advisor's students; Ref is the advisor id
+	ChatManagedAdvisory ChatManagedKind = "advisory"
+)
+
+// ChatManagedRoom is a room provisioned by the room sync, either as it
+// exists or as the dictionaries say it should.
+type ChatManagedRoom struct {
+	ID         string              `db:"id" json:"id,omitempty"`
+	Kind       ChatManagedKind     `db:"managed_kind" json:"kind"`
+	Ref        string              `db:"managed_ref" json:"ref"`
+	Name       string              `db:"name" json:"name"`
+	IsArchived bool                `db:"is_archived" json:"is_archived"`
+	Members    []ChatManagedMember `db:"-" json:"members,omitempty"`
+}
+
+// ChatManagedMember is a member of a managed room. Managed is false for
+// members added by hand, which the sync leaves alone; Banned marks users
+// banned from the room rather than members.
+type ChatManagedMember struct {
+	UserID  string             `db:"user_id" json:"user_id"`
+	Role    ChatRoomMemberRole `db:"role_in_room" json:"role_in_room"`
+	Managed bool               `db:"managed" json:"-"`
+	Banned  bool               `db:"banned" json:"-"`
 }
 
 // Make ChatAttachment implement sql.Scanner/driver.Valuer for JSONB if needed,
@@ -35,3 +35,52 @@ type DocumentWithVersion struct {
 	Document
 	CurrentVersion *DocumentVersion `json:"current_version,omitempty"`
 }
+
+// DocumentTextStatus is the outcome of extracting a version's text.
+type DocumentTextStatus string
+
+const (
+	DocumentTextDone   DocumentTextStatus = "done"
+	DocumentTextFailed DocumentTextStatus = "failed"
+	// DocumentTextUnsupported marks files that are not DOCX or PDF, are
+	// encrypted or too large
+	DocumentTextUnsupported DocumentTextStatus = "unsupported"
+)
+
+// DocumentText is the plain text extracted from a document version.
+type DocumentText struct {
+	VersionID   string             `db:"version_id" json:"version_id"`
+	TenantID    string             `db:"tenant_id" json:"tenant_id"`
+	Status      DocumentTextStatus `db:"status" json:"status"`
+	Format      *string            `db:"format" json:"format,omitempty"`
+	Content     string             `db:"content" json:"content"`
+	PageCount   *int               `db:"page_count" json:"page_count,omitempty"`
+	WordCount   *int               `db:"word_count" json:"word_count,omitempty"`
+	Error       *string            `db:"error" json:"error,omitempty"`
+	Attempts    int                `db:"attempts" json:"attempts"`
+	ExtractedAt *time.Time         `db:"extracted_at" json:"extracted_at,omitempty"`
+	UpdatedAt   time.Time          `db:"updated_at" json:"updated_at"`
+}
+
+// PendingDocumentText is a version waiting for its text to be extracted.
+type PendingDocumentText struct {
+	VersionID   string         `db:"version_id"`
+	TenantID    string         `db:"tenant_id"`
+	StoragePath string         `db:"storage_path"`
+	ObjectKey   sql.NullString `db:"object_key"`
+	MimeType    string         `db:"mime_type"`
+	SizeBytes   int64          `db:"size_bytes"`
+	Attempts    int            `db:"attempts"`
+}
+
+// SlotVersion is one upload in the history of a node slot.
+type SlotVersion struct {
+	AttachmentID string    `db:"attachment_id" json:"attachment_id"`
+	VersionID    string    `db:"version_id" json:"version_id"`
+	Filename     string    `db:"filename" json:"filename"`
+	Status       string    `db:"status" json:"status"`
+	IsActive     bool      `db:"is_active" json:"is_active"`
+	AttachedAt   time.Time `db:"attached_at" json:"attached_at"`
+	TextStatus   *string   `db:"text_status" json:"text_status,omitempty"`
+	WordCount    *int      `db:"word_count" json:"word_count,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// UserTOTP is a user's authenticator app. It only guards sign-ins once
+// EnabledAt is set, after the first code was confirmed.
+type UserTOTP struct {
+	UserID       string     `db:"user_id" json:"user_id"`
+	Secret       string     `db:"secret" json:"-"`
+	EnabledAt    *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	LastUsedStep int64      `db:"last_used_step" json:"-"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Enabled tells whether sign-ins need a code.
+func (t *UserTOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TenantMFAPolicy is a tenant's two-factor requirement, kept under the
+// "mfa" key of tenants.settings.
+type TenantMFAPolicy struct {
+	// RequireForAdmins makes admins signing in to the tenant use a second
+	// factor, enrolling one first if they have none. Superadmins always
+	// need one.
+	RequireForAdmins bool `json:"require_for_admins"`
+}
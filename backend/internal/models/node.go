@@ -22,13 +22,30 @@ type NodeInstance struct {
 }
 
 
-// NodeDeadline represents a deadline overriding the playbook default
+// Deadline sources
+const (
+	DeadlineSourceRule     = "rule"     // derived from the playbook's deadline rule
+	DeadlineSourceOverride = "override" // set by an admin for the student
+)
+
+// NodeDeadline is a student's due date for a node, either derived from the
+// playbook or overriding it
 type NodeDeadline struct {
 	ID        string    `db:"id" json:"id"`
 	UserID    string    `db:"user_id" json:"user_id"`
 	NodeID    string    `db:"node_id" json:"node_id"`
 	DueAt     time.Time `db:"due_at" json:"due_at"`
+	Source    string    `db:"source" json:"source"`
+	Note      *string   `db:"note" json:"note,omitempty"`
+	CreatedBy *string   `db:"created_by" json:"created_by,omitempty"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// DeadlineStudent is a student whose deadlines are kept in sync.
+type DeadlineStudent struct {
+	TenantID string `db:"tenant_id"`
+	UserID   string `db:"user_id"`
 }
 
 // NodeInstanceSlot represents a data slot for a node instance
@@ -115,6 +132,12 @@ type SubmissionSlotDTO struct {
 	Attachments  []SubmissionAttachmentDTO `json:"attachments"`
 }
 
+// JourneyConditions reports playbook condition results for a student
+type JourneyConditions struct {
+	Conditions  map[string]bool `json:"conditions"`
+	HiddenNodes []string        `json:"hidden_nodes"`
+}
+
 type ScoreboardEntry struct {
 	UserID     string `json:"user_id"`
 	Name       string `json:"name"`
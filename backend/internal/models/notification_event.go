@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Live notification stream event types
+const (
+	NotificationEventCreated      = "notification.created"
+	NotificationEventRead         = "notification.read"
+	NotificationEventReadAll      = "notification.read_all"
+	AdminNotificationEventCreated = "admin_notification.created"
+	AdminNotificationEventRead    = "admin_notification.read"
+	AdminNotificationEventReadAll = "admin_notification.read_all"
+)
+
+// NotificationEvent is one entry of a user's live notification stream.
+// ID doubles as the SSE event id.
+type NotificationEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	Stream    string          `db:"stream" json:"-"`
+	Type      string          `db:"type" json:"type"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// NotificationEventPayload carries the changed notification (if any) and
+// the unread count after the change so badges update without a refetch.
+type NotificationEventPayload struct {
+	Notification      *Notification      `json:"notification,omitempty"`
+	AdminNotification *AdminNotification `json:"admin_notification,omitempty"`
+	ID                string             `json:"id,omitempty"`
+	UnreadCount       int                `json:"unread_count"`
+}
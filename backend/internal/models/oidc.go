@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TenantOIDCConfig is the OpenID Connect provider a tenant's users can sign
+// in with.
+type TenantOIDCConfig struct {
+	TenantID     string `db:"tenant_id" json:"tenant_id"`
+	Issuer       string `db:"issuer" json:"issuer"`
+	ClientID     string `db:"client_id" json:"client_id"`
+	ClientSecret string `db:"client_secret" json:"-"`
+	// ClientSecretSet tells whether a secret is stored without revealing it
+	ClientSecretSet bool           `db:"-" json:"client_secret_set"`
+	Scopes          pq.StringArray `db:"scopes" json:"scopes"`
+	Enabled         bool           `db:"enabled" json:"enabled"`
+	// JITProvisioning creates student accounts for unknown emails
+	JITProvisioning bool `db:"jit_provisioning" json:"jit_provisioning"`
+	// AllowedDomains limits the emails the provider may sign in; empty
+	// allows any email the provider marks verified
+	AllowedDomains pq.StringArray `db:"allowed_domains" json:"allowed_domains"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// OIDCLoginState is an SSO login between the redirect to the provider and
+// its callback.
+type OIDCLoginState struct {
+	State        string    `db:"state"`
+	TenantID     string    `db:"tenant_id"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	RedirectPath string    `db:"redirect_path"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox message kinds
+const (
+	OutboxKindEmail             = "email"              // EmailPayload
+	OutboxKindNotification      = "notification"       // Notification
+	OutboxKindAdvisorSubmission = "advisor_submission" // AdvisorSubmissionPayload
+)
+
+// Outbox message statuses
+const (
+	OutboxStatusPending    = "pending"
+	OutboxStatusProcessing = "processing"
+	OutboxStatusSent       = "sent"
+	OutboxStatusDead       = "dead" // gave up after max_attempts; can be replayed
+)
+
+// OutboxMessage is a side effect recorded together with the change that
+// caused it and delivered later by the outbox dispatcher.
+type OutboxMessage struct {
+	ID            string          `db:"id" json:"id"`
+	TenantID      *string         `db:"tenant_id" json:"tenant_id,omitempty"`
+	Kind          string          `db:"kind" json:"kind"`
+	Payload       json.RawMessage `db:"payload" json:"payload"`
+	Status        string          `db:"status" json:"status"`
+	Attempts      int             `db:"attempts" json:"attempts"`
+	MaxAttempts   int             `db:"max_attempts" json:"max_attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   *time.Time      `db:"locked_until" json:"locked_until,omitempty"`
+	LastError     *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at" json:"updated_at"`
+	SentAt        *time.Time      `db:"sent_at" json:"sent_at,omitempty"`
+}
+
+// EmailPayload is the payload of an OutboxKindEmail message.
+type EmailPayload struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// AdvisorSubmissionPayload is the payload of an OutboxKindAdvisorSubmission message.
+type AdvisorSubmissionPayload struct {
+	StudentID      string `json:"student_id"`
+	NodeID         string `json:"node_id"`
+	NodeInstanceID string `json:"node_instance_id"`
+	Message        string `json:"message,omitempty"`
+}
+
+// OutboxFilter narrows the admin outbox listing.
+type OutboxFilter struct {
+	TenantID string
+	Status   string
+	Kind     string
+	Limit    int
+	Offset   int
+}
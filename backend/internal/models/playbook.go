@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PlaybookVersion is an uploaded playbook JSON registered for a tenant
+type PlaybookVersion struct {
+	ID        string          `db:"id" json:"id"`
+	TenantID  string          `db:"tenant_id" json:"tenant_id"`
+	Version   string          `db:"version" json:"version"`
+	Checksum  string          `db:"checksum" json:"checksum"`
+	RawJSON   json.RawMessage `db:"raw_json" json:"raw_json,omitempty"`
+	CreatedBy *string         `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	IsActive  bool            `db:"is_active" json:"is_active"`
+}
+
+// PlaybookActivation records a version becoming active for a tenant
+type PlaybookActivation struct {
+	ID                string     `db:"id" json:"id"`
+	TenantID          string     `db:"tenant_id" json:"tenant_id"`
+	PlaybookVersionID string     `db:"playbook_version_id" json:"playbook_version_id"`
+	Version           string     `db:"version" json:"version"`
+	ActivatedBy       *string    `db:"activated_by" json:"activated_by,omitempty"`
+	ActivatedAt       time.Time  `db:"activated_at" json:"activated_at"`
+	RolledBackAt      *time.Time `db:"rolled_back_at" json:"rolled_back_at,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// TenantSAMLConfig is the SAML identity provider a tenant's users can sign
+// in with. It is kept under the "saml" key of the tenant's settings.
+type TenantSAMLConfig struct {
+	Enabled bool `json:"enabled"`
+	// IdPMetadata is the IdP's metadata XML, as published by the IdP
+	IdPMetadata string `json:"idp_metadata"`
+	// Attribute names to read the user from; empty uses the usual
+	// eduPerson, Azure AD and ADFS names
+	EmailAttribute     string `json:"email_attribute,omitempty"`
+	FirstNameAttribute string `json:"first_name_attribute,omitempty"`
+	LastNameAttribute  string `json:"last_name_attribute,omitempty"`
+	// RoleAttribute holds values such as eduPersonAffiliation that
+	// RoleMap turns into portal roles: student, advisor or chair
+	RoleAttribute string            `json:"role_attribute,omitempty"`
+	RoleMap       map[string]string `json:"role_map,omitempty"`
+	// DefaultRole applies when no attribute value maps to a role; empty
+	// denies such users
+	DefaultRole string `json:"default_role,omitempty"`
+	// SyncRole updates the role of existing members other than admins on
+	// every login
+	SyncRole bool `json:"sync_role"`
+	// JITProvisioning creates accounts and memberships for unknown users
+	// with a mapped role
+	JITProvisioning bool `json:"jit_provisioning"`
+	// AllowedDomains limits the emails the IdP may sign in; empty allows
+	// any
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+// SAMLLoginRequest is an AuthnRequest sent to a tenant's IdP that has not
+// been answered yet.
+type SAMLLoginRequest struct {
+	ID           string    `db:"id"`
+	TenantID     string    `db:"tenant_id"`
+	RedirectPath string    `db:"redirect_path"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DueDeadline is a student's node deadline that is not done yet.
+type DueDeadline struct {
+	ID       string    `db:"id" json:"id"`
+	TenantID string    `db:"tenant_id" json:"tenant_id"`
+	UserID   string    `db:"user_id" json:"user_id"`
+	NodeID   string    `db:"node_id" json:"node_id"`
+	DueAt    time.Time `db:"due_at" json:"due_at"`
+}
+
+// StaleReview is a submitted node instance still waiting for the advisor.
+type StaleReview struct {
+	InstanceID   string    `db:"id" json:"id"`
+	TenantID     string    `db:"tenant_id" json:"tenant_id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	NodeID       string    `db:"node_id" json:"node_id"`
+	WaitingSince time.Time `db:"waiting_since" json:"waiting_since"`
+}
+
+// PendingReminder is a reminder created by an admin that was not delivered yet.
+type PendingReminder struct {
+	ID        string     `db:"id" json:"id"`
+	TenantID  string     `db:"tenant_id" json:"tenant_id"`
+	StudentID string     `db:"student_id" json:"student_id"`
+	Title     string     `db:"title" json:"title"`
+	Message   *string    `db:"message" json:"message,omitempty"`
+	DueAt     *time.Time `db:"due_at" json:"due_at,omitempty"`
+	CreatedBy string     `db:"created_by" json:"created_by"`
+}
+
+// Recipient is who a scheduled notification goes to.
+type Recipient struct {
+	ID    string `db:"id" json:"id"`
+	Name  string `db:"name" json:"name"`
+	Email string `db:"email" json:"email"`
+}
@@ -1,11 +1,26 @@
 package models
 
+import "time"
+
 type SearchResult struct {
-	Type        string `json:"type"`                // "student", "document", "message"
-	ID          string `json:"id"`                  // ID to navigate to
-	Title       string `json:"title"`               // Display title (Name, Filename, etc.)
-	Subtitle    string `json:"subtitle"`            // Secondary info (Email, Node Name, etc.)
-	Description string `json:"description"`         // Context (Message snippet, etc.)
-	Link        string `json:"link"`                // Frontend route
-	Metadata    any    `json:"metadata,omitempty"`
+	Type        string     `json:"type"`                // "student", "document", "message", "event", "contact"
+	ID          string     `json:"id"`                  // ID to navigate to
+	Title       string     `json:"title"`               // Display title (Name, Filename, etc.)
+	Subtitle    string     `json:"subtitle"`            // Secondary info (Email, Node Name, etc.)
+	Description string     `json:"description"`         // Context (Message snippet, etc.)
+	Link        string     `json:"link"`                // Frontend route
+	Snippet     string     `json:"snippet,omitempty"`   // Matched text, HTML-escaped with <mark> highlights
+	Rank        float64    `json:"rank,omitempty"`      // Relevance; higher is better
+	At          *time.Time `json:"at,omitempty"`        // When the item was created or takes place
+	Metadata    any        `json:"metadata,omitempty"`
+}
+
+// SearchPage is one page of results of a single type.
+type SearchPage struct {
+	Type    string         `json:"type"`
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
 }
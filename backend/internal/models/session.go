@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserSession is one sign-in of a user on one device. Its refresh token
+// is known only by hash.
+type UserSession struct {
+	ID                string     `db:"id" json:"id"`
+	UserID            string     `db:"user_id" json:"user_id"`
+	TenantID          *string    `db:"tenant_id" json:"tenant_id,omitempty"`
+	RefreshTokenHash  string     `db:"refresh_token_hash" json:"-"`
+	PreviousTokenHash *string    `db:"previous_token_hash" json:"-"`
+	UserAgent         string     `db:"user_agent" json:"user_agent"`
+	IP                string     `db:"ip" json:"ip"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt        time.Time  `db:"last_used_at" json:"last_used_at"`
+	RotatedAt         *time.Time `db:"rotated_at" json:"-"`
+	ExpiresAt         time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt         *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// Active tells whether the session can still be used at t.
+func (s *UserSession) Active(t time.Time) bool {
+	return s.RevokedAt == nil && t.Before(s.ExpiresAt)
+}
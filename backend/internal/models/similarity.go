@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// SimilarityReport is the in-house similarity check of a dissertation
+// version against earlier versions in its tenant.
+type SimilarityReport struct {
+	ID             string `db:"id" json:"id"`
+	TenantID       string `db:"tenant_id" json:"-"`
+	NodeInstanceID string `db:"node_instance_id" json:"node_instance_id"`
+	VersionID      string `db:"version_id" json:"version_id"`
+	// Score is the share of the text found in other students' submissions
+	Score float64 `db:"score" json:"score"`
+	// SelfScore is the share of the text found in the student's own
+	// earlier versions
+	SelfScore     float64            `db:"self_score" json:"self_score"`
+	ComparedCount int                `db:"compared_count" json:"compared_count"`
+	Sources       SimilaritySources  `db:"sources" json:"sources"`
+	Passages      SimilarityPassages `db:"passages" json:"passages"`
+	CreatedAt     time.Time          `db:"created_at" json:"created_at"`
+}
+
+// SimilaritySource is an earlier version that shares text with the checked
+// one.
+type SimilaritySource struct {
+	VersionID   string  `json:"version_id"`
+	Filename    string  `json:"filename"`
+	StudentID   string  `json:"student_id"`
+	StudentName string  `json:"student_name"`
+	Own         bool    `json:"own"`
+	Score       float64 `json:"score"`
+	Passages    int     `json:"passages"`
+}
+
+// SimilarityPassage is a stretch of the checked text that also occurs in a
+// source.
+type SimilarityPassage struct {
+	SourceVersionID string `json:"source_version_id"`
+	Text            string `json:"text"`
+	SourceText      string `json:"source_text"`
+	Words           int    `json:"words"`
+}
+
+type SimilaritySources []SimilaritySource
+
+func (s SimilaritySources) Value() (driver.Value, error) {
+	if s == nil {
+		s = SimilaritySources{}
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *SimilaritySources) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, s)
+}
+
+type SimilarityPassages []SimilarityPassage
+
+func (p SimilarityPassages) Value() (driver.Value, error) {
+	if p == nil {
+		p = SimilarityPassages{}
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (p *SimilarityPassages) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, p)
+}
+
+// SimilarityCandidate is a dissertation version: one waiting for its report,
+// or an earlier one it is compared with.
+type SimilarityCandidate struct {
+	VersionID      string    `db:"version_id"`
+	TenantID       string    `db:"tenant_id"`
+	NodeInstanceID string    `db:"node_instance_id"`
+	StudentID      string    `db:"student_id"`
+	StudentName    string    `db:"student_name"`
+	Filename       string    `db:"filename"`
+	CreatedAt      time.Time `db:"created_at"`
+	Minhash        []byte    `db:"minhash"`
+	ShingleCount   int       `db:"shingle_count"`
+}
@@ -0,0 +1,124 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signIn follows the authorization URL to the provider and returns the
+// query it redirects back with.
+func signIn(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return loc.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewServer("portal", "s3cret")
+	defer idp.Close()
+	ctx := context.Background()
+
+	p, err := oidc.Discover(ctx, nil, idp.Issuer)
+	require.NoError(t, err)
+	cfg := oidc.Config{ClientID: "portal", ClientSecret: "s3cret", RedirectURL: "http://localhost:8080/callback", Scopes: []string{"email"}}
+
+	t.Run("Success", func(t *testing.T) {
+		authURL := p.AuthCodeURL(cfg, "st-1", "n-1", "verifier")
+		assert.Contains(t, authURL, "scope=openid+email")
+		assert.Contains(t, authURL, "code_challenge_method=S256")
+
+		q := signIn(t, authURL)
+		assert.Equal(t, "st-1", q.Get("state"))
+		tok, err := p.Exchange(ctx, cfg, q.Get("code"), "verifier")
+		require.NoError(t, err)
+
+		claims, err := p.Verify(ctx, "portal", tok.IDToken, "n-1")
+		require.NoError(t, err)
+		assert.Equal(t, "mock-user-1", claims.Subject)
+		assert.Equal(t, "student@example.edu", claims.Email)
+		require.NotNil(t, claims.EmailVerified)
+		assert.True(t, bool(*claims.EmailVerified))
+		assert.Equal(t, "Mock", claims.GivenName)
+
+		_, err = p.Exchange(ctx, cfg, q.Get("code"), "verifier")
+		assert.ErrorContains(t, err, "invalid_grant", "codes are single use")
+	})
+
+	t.Run("Wrong verifier", func(t *testing.T) {
+		q := signIn(t, p.AuthCodeURL(cfg, "st", "n", "verifier"))
+		_, err := p.Exchange(ctx, cfg, q.Get("code"), "another-verifier")
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		q := signIn(t, p.AuthCodeURL(cfg, "st-2", "n-2", "verifier"))
+		tok, err := p.Exchange(ctx, cfg, q.Get("code"), "verifier")
+		require.NoError(t, err)
+		_, err = p.Verify(ctx, "portal", tok.IDToken, "other")
+		assert.ErrorIs(t, err, oidc.ErrNonce)
+		_, err = p.Verify(ctx, "other-client", tok.IDToken, "n-2")
+		assert.ErrorIs(t, err, oidc.ErrInvalidToken)
+	})
+
+	t.Run("Expired or foreign tokens", func(t *testing.T) {
+		defer func() { idp.Claims = nil }()
+		for name, change := range map[string]func(jwt.MapClaims){
+			"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			"other issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example" },
+			"other audience": func(c jwt.MapClaims) { c["aud"] = []string{"portal", "other"}; c["azp"] = "other" },
+		} {
+			idp.Claims = change
+			q := signIn(t, p.AuthCodeURL(cfg, "st", "n", "verifier"))
+			tok, err := p.Exchange(ctx, cfg, q.Get("code"), "verifier")
+			require.NoError(t, err)
+			_, err = p.Verify(ctx, "portal", tok.IDToken, "n")
+			assert.ErrorIs(t, err, oidc.ErrInvalidToken, name)
+		}
+	})
+
+	t.Run("Wrong client secret", func(t *testing.T) {
+		q := signIn(t, p.AuthCodeURL(cfg, "st", "n", "verifier"))
+		bad := cfg
+		bad.ClientSecret = "guess"
+		_, err := p.Exchange(ctx, bad, q.Get("code"), "verifier")
+		assert.ErrorContains(t, err, "invalid_client")
+	})
+
+	t.Run("User declines", func(t *testing.T) {
+		idp.Error = "access_denied"
+		defer func() { idp.Error = "" }()
+		q := signIn(t, p.AuthCodeURL(cfg, "st", "n", "verifier"))
+		assert.Equal(t, "access_denied", q.Get("error"))
+		assert.Empty(t, q.Get("code"))
+	})
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer("portal", "")
+	defer idp.Close()
+	issuer := idp.Issuer
+	idp.Issuer = "https://idp.example"
+	_, err := oidc.Discover(context.Background(), nil, issuer)
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestChallenge(t *testing.T) {
+	// base64url of the SHA-256 of "verifier", without padding
+	assert.Equal(t, "iMnq5o6zALKXGivsnlom_0F5_WYda32GHkxlV7mq7hQ", oidc.Challenge("verifier"))
+	assert.Len(t, oidc.NewVerifier(), 43)
+}
@@ -0,0 +1,255 @@
+// Package oidctest is an OpenID provider for tests and local development.
+// It signs in a fixed user without asking for credentials, but checks the
+// client, redirect URI and PKCE verifier like a real provider would.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// codeTTL is how long an authorization code can be exchanged
+const codeTTL = time.Minute
+
+// User is who the provider signs in.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// Provider is the mock identity provider. Its fields may be changed between
+// logins.
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	User         User
+	// Error, when set, is returned to the client instead of a code, as if
+	// the user had declined
+	Error string
+	// Claims, when set, may change ID token claims before they are signed
+	Claims func(jwt.MapClaims)
+
+	key   *rsa.PrivateKey
+	kid   string
+	mux   *http.ServeMux
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+type grant struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+	expiresAt   time.Time
+}
+
+// New returns a provider serving the given issuer URL.
+func New(issuer, clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		User: User{
+			Subject:       "mock-user-1",
+			Email:         "student@example.edu",
+			EmailVerified: true,
+			GivenName:     "Mock",
+			FamilyName:    "Student",
+		},
+		key:   key,
+		kid:   oidc.RandomString()[:8],
+		mux:   http.NewServeMux(),
+		codes: map[string]grant{},
+	}
+	p.mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("GET /jwks", p.jwks)
+	p.mux.HandleFunc("GET /authorize", p.authorize)
+	p.mux.HandleFunc("POST /token", p.token)
+	return p, nil
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// Server is a provider listening on a local test server.
+type Server struct {
+	*Provider
+	srv *httptest.Server
+}
+
+// NewServer starts a provider on a local port; its issuer is the server's
+// URL.
+func NewServer(clientID, clientSecret string) *Server {
+	p, err := New("", clientID, clientSecret)
+	if err != nil {
+		panic(err)
+	}
+	srv := httptest.NewServer(p)
+	p.Issuer = srv.URL
+	return &Server{Provider: p, srv: srv}
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize signs the user in at once and redirects back with a code.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" || q.Get("client_id") != p.ClientID {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	back := target.Query()
+	back.Set("state", q.Get("state"))
+	switch {
+	case p.Error != "":
+		back.Set("error", p.Error)
+	case q.Get("response_type") != "code":
+		back.Set("error", "unsupported_response_type")
+	case q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "":
+		back.Set("error", "invalid_request")
+	default:
+		code := oidc.RandomString()
+		p.mu.Lock()
+		p.codes[code] = grant{
+			redirectURI: redirectURI,
+			nonce:       q.Get("nonce"),
+			challenge:   q.Get("code_challenge"),
+			expiresAt:   time.Now().Add(codeTTL),
+		}
+		p.mu.Unlock()
+		back.Set("code", code)
+	}
+	target.RawQuery = back.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token exchanges a code for an ID token. Codes are single use.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || secret != p.ClientSecret {
+		w.Header().Set("WWW-Authenticate", "Basic")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	g, found := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	if !found || time.Now().After(g.expiresAt) || g.redirectURI != r.PostForm.Get("redirect_uri") ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer,
+		"sub":            p.User.Subject,
+		"aud":            p.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"email":          p.User.Email,
+		"email_verified": p.User.EmailVerified,
+		"given_name":     p.User.GivenName,
+		"family_name":    p.User.FamilyName,
+		"name":           p.User.GivenName + " " + p.User.FamilyName,
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+	if p.Claims != nil {
+		p.Claims(claims)
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = p.kid
+	idToken, err := t.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": oidc.RandomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -22,7 +22,7 @@ type AdminRepository interface {
 	GetAdvisorsForStudents(ctx context.Context, studentIDs []string) (map[string][]models.AdvisorSummary, error)
 	GetDoneCountsForStudents(ctx context.Context, studentIDs []string) (map[string]int, error)
 	GetLastUpdatesForStudents(ctx context.Context, studentIDs []string) (map[string]time.Time, error)
-	GetRPRequiredForStudents(ctx context.Context, studentIDs []string) (map[string]bool, error)
+	GetProfilesForStudents(ctx context.Context, studentIDs []string) (map[string]map[string]any, error)
 	
 	// Single student graph
 	GetStudentNodeInstances(ctx context.Context, studentID string) ([]models.NodeInstance, error) 
@@ -278,36 +278,53 @@ func (r *SQLAdminRepository) GetLastUpdatesForStudents(ctx context.Context, stud
 	return result, nil
 }
 
-func (r *SQLAdminRepository) GetRPRequiredForStudents(ctx context.Context, studentIDs []string) (map[string]bool, error) {
+// GetProfilesForStudents returns each student's profile form data: the legacy
+// profile_submissions row overlaid with the latest S1_profile form revision.
+func (r *SQLAdminRepository) GetProfilesForStudents(ctx context.Context, studentIDs []string) (map[string]map[string]any, error) {
 	if len(studentIDs) == 0 {
 		return nil, nil
 	}
-	query, args, _ := sqlx.In("SELECT user_id, form_data FROM profile_submissions WHERE user_id IN (?)", studentIDs)
-	query = r.db.Rebind(query)
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	
-	out := make(map[string]bool)
-	for rows.Next() {
-		var uid string
-		var raw []byte
-		if err := rows.Scan(&uid, &raw); err == nil {
-			// Check JSON: years_since_graduation > 3
-			// Quick string check or unmarshal? Unmarshal safer.
-			// Optimization: query it inside SQL directly using ->> if predictable.
-			// Handler logic: y > 3. 
-			// Let's rely on Service to parse, or do it here. 
-			// Doing it here matches "Batch Loader" pattern.
-			var m map[string]interface{}
-			if json.Unmarshal(raw, &m) == nil {
-				if y, ok := m["years_since_graduation"].(float64); ok && y > 3 {
-					out[uid] = true
-				}
+	out := make(map[string]map[string]any)
+	merge := func(query string) error {
+		query, args, err := sqlx.In(query, studentIDs)
+		if err != nil {
+			return err
+		}
+		query = r.db.Rebind(query)
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var uid string
+			var raw []byte
+			if err := rows.Scan(&uid, &raw); err != nil {
+				return err
+			}
+			var m map[string]any
+			if json.Unmarshal(raw, &m) != nil {
+				continue
+			}
+			if out[uid] == nil {
+				out[uid] = make(map[string]any)
+			}
+			for k, v := range m {
+				out[uid][k] = v
 			}
 		}
+		return rows.Err()
+	}
+
+	if err := merge(`SELECT user_id, form_data FROM profile_submissions WHERE user_id IN (?)`); err != nil {
+		return nil, err
+	}
+	if err := merge(`SELECT DISTINCT ON (ni.user_id) ni.user_id, r.form_data
+		FROM node_instances ni
+		JOIN node_instance_form_revisions r ON r.node_instance_id = ni.id AND r.rev = ni.current_rev
+		WHERE ni.node_id = 'S1_profile' AND ni.user_id IN (?)
+		ORDER BY ni.user_id, ni.updated_at DESC`); err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -516,10 +533,14 @@ func (r *SQLAdminRepository) GetNodeFiles(ctx context.Context, studentID, nodeID
 		a.reviewed_document_version_id as reviewed_doc_id,
 		to_char(a.reviewed_at, 'YYYY-MM-DD"T"HH24:MI:SSZ') as reviewed_at,
 		rdv.mime_type AS reviewed_mime_type,
-		COALESCE(ru.first_name||' '||ru.last_name,'') AS reviewed_by_name
+		COALESCE(ru.first_name||' '||ru.last_name,'') AS reviewed_by_name,
+		dt.status AS text_status, dt.page_count, dt.word_count,
+		sr.score AS similarity_score, sr.id AS similarity_report_id
 		FROM node_instance_slots s
 		JOIN node_instance_slot_attachments a ON a.slot_id=s.id
 		JOIN document_versions dv ON dv.id=a.document_version_id
+		LEFT JOIN document_version_texts dt ON dt.version_id=dv.id
+		LEFT JOIN similarity_reports sr ON sr.version_id=dv.id
 		LEFT JOIN users u ON u.id=a.attached_by
 		LEFT JOIN document_versions rdv ON rdv.id=a.reviewed_document_version_id
 		LEFT JOIN users ru ON ru.id=a.reviewed_by
@@ -101,3 +101,71 @@ func TestSQLAdminRepository_GetAttachmentCounts_Unit(t *testing.T) {
 		assert.Equal(t, 1, rejected)
 	})
 }
+
+func TestSQLAdminRepository_GetProfilesForStudents_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLAdminRepository(sqlx.NewDb(db, "sqlmock"))
+
+	mock.ExpectQuery(`SELECT user_id, form_data FROM profile_submissions WHERE user_id IN \(\?, \?\)`).
+		WithArgs("s1", "s2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "form_data"}).
+			AddRow("s1", []byte(`{"program":"PhD","years_since_graduation":1}`)))
+	mock.ExpectQuery(`SELECT DISTINCT ON \(ni.user_id\) ni.user_id, r.form_data (.+) WHERE ni.node_id = 'S1_profile'`).
+		WithArgs("s1", "s2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "form_data"}).
+			AddRow("s1", []byte(`{"graduation_date":"2019-01-01"}`)).
+			AddRow("s2", []byte(`{"years_since_graduation":5}`)))
+
+	profiles, err := repo.GetProfilesForStudents(context.Background(), []string{"s1", "s2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "PhD", profiles["s1"]["program"])
+	assert.Equal(t, "2019-01-01", profiles["s1"]["graduation_date"])
+	assert.Equal(t, 5.0, profiles["s2"]["years_since_graduation"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	empty, err := repo.GetProfilesForStudents(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, empty)
+}
+
+func TestSQLAdminRepository_GetNodeFiles_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLAdminRepository(sqlxDB)
+
+	mock.ExpectQuery(`SELECT id FROM node_instances`).
+		WithArgs("student-1", "S1_antiplag").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("inst-1"))
+	mock.ExpectQuery(`LEFT JOIN document_version_texts dt ON dt.version_id=dv.id`).
+		WithArgs("inst-1").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"slot_key", "attachment_id", "filename", "size_bytes", "status", "is_active", "version_id", "mime_type", "uploaded_by",
+			"text_status", "page_count", "word_count", "similarity_score", "similarity_report_id",
+		}).
+			AddRow("dissertation_draft_file", "att-1", "draft.docx", 2048, "submitted", true, "ver-1", "application/octet-stream", "Ann Lee", "done", 142, 38120, 0.12, "rep-1").
+			AddRow("dissertation_draft_file", "att-2", "scan.png", 1024, "submitted", true, "ver-2", "image/png", "Ann Lee", nil, nil, nil, nil, nil))
+
+	files, err := repo.GetNodeFiles(context.Background(), "student-1", "S1_antiplag")
+	assert.NoError(t, err)
+	if assert.Len(t, files, 2) {
+		assert.Equal(t, "/api/documents/versions/ver-1/download", files[0].DownloadURL)
+		assert.Equal(t, 142, *files[0].PageCount)
+		assert.Equal(t, 38120, *files[0].WordCount)
+		assert.Equal(t, "done", *files[0].TextStatus)
+		assert.Equal(t, 0.12, *files[0].SimilarityScore)
+		assert.Equal(t, "rep-1", *files[0].SimilarityReportID)
+		assert.Nil(t, files[1].PageCount, "not extracted yet")
+		assert.Nil(t, files[1].SimilarityScore)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
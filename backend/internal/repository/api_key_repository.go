@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// APIKeyRepository stores tenants' API keys. AuthMiddleware looks keys up
+// by hash itself.
+type APIKeyRepository interface {
+	// Create stores a key, setting its ID and creation time.
+	Create(ctx context.Context, k *models.APIKey) error
+	// List returns the tenant's keys that were not revoked, newest first.
+	List(ctx context.Context, tenantID string) ([]models.APIKey, error)
+	// Revoke disables one of the tenant's keys.
+	Revoke(ctx context.Context, tenantID, id string) error
+}
+
+type SQLAPIKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLAPIKeyRepository(db *sqlx.DB) *SQLAPIKeyRepository {
+	return &SQLAPIKeyRepository{db: db}
+}
+
+func (r *SQLAPIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO api_keys (tenant_id, user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		k.TenantID, k.UserID, k.Name, k.Prefix, k.KeyHash, k.Scopes, k.ExpiresAt).Scan(&k.ID, &k.CreatedAt)
+}
+
+func (r *SQLAPIKeyRepository) List(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.db.SelectContext(ctx, &keys, `
+		SELECT id, tenant_id, user_id, name, prefix, key_hash, scopes, expires_at,
+			last_used_at, last_used_ip, created_at, revoked_at
+		FROM api_keys
+		WHERE tenant_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`, tenantID)
+	return keys, err
+}
+
+func (r *SQLAPIKeyRepository) Revoke(ctx context.Context, tenantID, id string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLAPIKeyRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLAPIKeyRepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Create", func(t *testing.T) {
+		k := &models.APIKey{TenantID: "t1", UserID: "u1", Name: "registrar", Prefix: "phdk_abcd1234", KeyHash: "h1",
+			Scopes: pq.StringArray{"students:read"}, ExpiresAt: now.Add(time.Hour)}
+		mock.ExpectQuery(`INSERT INTO api_keys \(tenant_id, user_id, name, prefix, key_hash, scopes, expires_at\)`).
+			WithArgs("t1", "u1", "registrar", "phdk_abcd1234", "h1", k.Scopes, k.ExpiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("k1", now))
+		require.NoError(t, repo.Create(ctx, k))
+		assert.Equal(t, "k1", k.ID)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		cols := []string{"id", "tenant_id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "last_used_ip", "created_at", "revoked_at"}
+		mock.ExpectQuery(`FROM api_keys\s+WHERE tenant_id = \$1 AND revoked_at IS NULL`).WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("k1", "t1", "u1", "registrar", "phdk_abcd1234", "h1", "{students:read,journey:read}", now, now, "10.0.0.1", now, nil))
+		keys, err := repo.List(ctx, "t1")
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.True(t, keys[0].HasScope("journey:read"))
+		assert.False(t, keys[0].HasScope("users:write"))
+	})
+
+	t.Run("Revoke stays in the tenant", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE api_keys SET revoked_at = now\(\)\s+WHERE id = \$1 AND tenant_id = \$2 AND revoked_at IS NULL`).
+			WithArgs("k1", "t2").WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.Revoke(ctx, "t2", "k1"), ErrNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // ChatRepository defines data access for chat functionality.
@@ -31,8 +32,56 @@ type ChatRepository interface {
 	CreateMessage(ctx context.Context, roomID, senderID, body string, attachments models.ChatAttachments, importance *string, meta json.RawMessage) (*models.ChatMessage, error)
 	ListMessages(ctx context.Context, roomID string, limit int, before, after *time.Time) ([]models.ChatMessage, error)
 	UpdateMessage(ctx context.Context, msgID, userID, newBody string) (*models.ChatMessage, error)
-	DeleteMessage(ctx context.Context, msgID, userID string) error
+	DeleteMessage(ctx context.Context, msgID, userID string) (string, error)
 	MarkRoomAsRead(ctx context.Context, roomID, userID string) error
+	GetMessage(ctx context.Context, msgID string) (*models.ChatMessage, error)
+	GetMemberRole(ctx context.Context, roomID, userID string) (models.ChatRoomMemberRole, error)
+
+	// Threads
+	CreateReply(ctx context.Context, parentID, senderID, body string, attachments models.ChatAttachments, meta json.RawMessage) (*models.ChatMessage, error)
+	ListReplies(ctx context.Context, parentID string, limit int, after *time.Time) ([]models.ChatMessage, error)
+	ListThreads(ctx context.Context, roomID, userID string) ([]models.ChatThread, error)
+	MarkThreadAsRead(ctx context.Context, parentID, userID string) error
+
+	// Reactions and pins
+	AddReaction(ctx context.Context, msgID, userID, emoji string) (bool, error)
+	RemoveReaction(ctx context.Context, msgID, userID, emoji string) (bool, error)
+	ListReactions(ctx context.Context, msgIDs []string) (map[string][]models.ChatReaction, error)
+	SetPinned(ctx context.Context, msgID string, pinnedBy *string) error
+	ListPinned(ctx context.Context, roomID string) ([]models.ChatMessage, error)
+
+	// Stored files
+	CreateFile(ctx context.Context, file *models.ChatFile) error
+	GetFile(ctx context.Context, versionID string) (*models.ChatFile, error)
+	ListLocalFileMessages(ctx context.Context) ([]models.ChatMessage, error)
+	UpdateAttachments(ctx context.Context, msgID string, attachments models.ChatAttachments) error
+
+	// Moderation
+	GetMember(ctx context.Context, roomID, userID string) (*models.ChatRoomMember, error)
+	SetMuted(ctx context.Context, roomID, userID string, until *time.Time) error
+	BanMember(ctx context.Context, ban *models.ChatRoomBan) error
+	UnbanMember(ctx context.Context, roomID, userID string) (bool, error)
+	IsBanned(ctx context.Context, roomID, userID string) (bool, error)
+	ListBans(ctx context.Context, roomID string) ([]models.ChatRoomBan, error)
+	RemoveMessage(ctx context.Context, msgID string) error
+	UpdateRoomSettings(ctx context.Context, roomID string, policy models.ChatPostPolicy, retentionDays *int, action models.ChatRetentionAction) (*models.ChatRoom, error)
+	CreateModerationEntry(ctx context.Context, entry *models.ChatModerationEntry) error
+	ListModerationLog(ctx context.Context, roomID string, limit int, before *time.Time) ([]models.ChatModerationEntry, error)
+
+	// Retention
+	ListRetentionRooms(ctx context.Context) ([]models.ChatRoom, error)
+	PurgeExpiredMessages(ctx context.Context, roomID string, before time.Time, archive bool, limit int) (int, error)
+
+	// Managed rooms
+	ListCohortRoomSpecs(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	ListAdvisoryRoomSpecs(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	ListManagedRooms(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	ListAdoptableRooms(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	AdoptManagedRoom(ctx context.Context, roomID string, kind models.ChatManagedKind, ref string, memberIDs []string) error
+	CreateManagedRoom(ctx context.Context, tenantID string, room models.ChatManagedRoom, ownerID string) (string, error)
+	AddManagedMember(ctx context.Context, tenantID, roomID, userID string, role models.ChatRoomMemberRole) error
+	RemoveManagedMember(ctx context.Context, roomID, userID string) (bool, error)
+	ListManagedRoomTenants(ctx context.Context) ([]string, error)
 	
 	// Batch helpers
 	GetUsersByFilters(ctx context.Context, filters map[string]string) ([]string, error)
@@ -61,7 +110,8 @@ func (r *SQLChatRepository) CreateRoom(ctx context.Context, tenantID, name strin
 		), new_room AS (
 			INSERT INTO chat_rooms (tenant_id, name, type, created_by, created_by_role, meta)
 			SELECT $1, $2, $3, c.id, c.role, $5 FROM creator c
-			RETURNING id, tenant_id, name, type, created_by, created_by_role, is_archived, meta, created_at
+			RETURNING id, tenant_id, name, type, created_by, created_by_role, is_archived, meta, created_at,
+				post_policy, retention_days, retention_action
 		), add_creator AS (
 			INSERT INTO chat_room_members (tenant_id, room_id, user_id, role_in_room)
 			SELECT $1, nr.id, $4, 'admin' FROM new_room nr
@@ -84,7 +134,8 @@ func (r *SQLChatRepository) UpdateRoom(ctx context.Context, roomID string, name
 			name = COALESCE($2, name),
 			is_archived = COALESCE($3, is_archived)
 		WHERE id = $1
-		RETURNING id, name, type, created_by, created_by_role, is_archived, meta, created_at
+		RETURNING id, tenant_id, name, type, created_by, created_by_role, is_archived, meta, created_at,
+			post_policy, retention_days, retention_action
 	`, roomID, name, archived).StructScan(&room)
 	if err != nil {
 		return nil, err
@@ -96,7 +147,8 @@ func (r *SQLChatRepository) UpdateRoom(ctx context.Context, roomID string, name
 func (r *SQLChatRepository) GetRoom(ctx context.Context, roomID string) (*models.ChatRoom, error) {
 	var room models.ChatRoom
 	err := r.db.GetContext(ctx, &room, `
-		SELECT id, name, type, created_by, created_by_role, is_archived, meta, created_at
+		SELECT id, tenant_id, name, type, created_by, created_by_role, is_archived, meta, created_at,
+			post_policy, retention_days, retention_action
 		FROM chat_rooms
 		WHERE id = $1
 	`, roomID)
@@ -112,6 +164,7 @@ func (r *SQLChatRepository) ListRoomsForUser(ctx context.Context, userID, tenant
 	err := r.db.SelectContext(ctx, &rooms, `
 		SELECT 
 			r.id, r.name, r.type, r.created_by, r.created_by_role, r.is_archived, r.meta, r.created_at,
+			r.post_policy, r.retention_days, r.retention_action,
 			COALESCE(unread.count, 0) AS unread_count,
 			last_msg.last_message_at
 		FROM chat_rooms r
@@ -126,6 +179,7 @@ func (r *SQLChatRepository) ListRoomsForUser(ctx context.Context, userID, tenant
 			FROM chat_messages cm
 			WHERE cm.room_id = r.id 
 				AND cm.deleted_at IS NULL
+				AND cm.parent_id IS NULL
 				AND cm.created_at > COALESCE(
 					(SELECT last_read_at FROM chat_room_read_status WHERE room_id = r.id AND user_id = $1),
 					'1970-01-01'::timestamptz
@@ -143,6 +197,7 @@ func (r *SQLChatRepository) ListRoomsForTenant(ctx context.Context, tenantID str
 	err := r.db.SelectContext(ctx, &rooms, `
 		SELECT 
 			r.id, r.name, r.type, r.created_by, r.created_by_role, r.is_archived, r.meta, r.created_at,
+			r.post_policy, r.retention_days, r.retention_action,
 			COALESCE(member_count.count, 0) AS unread_count,
 			last_msg.last_message_at
 		FROM chat_rooms r
@@ -197,7 +252,7 @@ func (r *SQLChatRepository) ListMembers(ctx context.Context, roomID string) ([]m
 	var members []models.MemberWithUser
 	err := r.db.SelectContext(ctx, &members, `
 		SELECT 
-			m.tenant_id, m.room_id, m.user_id, m.role_in_room, m.joined_at, rs.last_read_at,
+			m.tenant_id, m.room_id, m.user_id, m.role_in_room, m.joined_at, rs.last_read_at, m.muted_until,
 			u.first_name, u.last_name, u.email, u.username
 		FROM chat_room_members m
 		INNER JOIN users u ON u.id = m.user_id
@@ -242,23 +297,9 @@ func (r *SQLChatRepository) ListMessages(ctx context.Context, roomID string, lim
 		limit = 50
 	}
 
-	query := `
-		SELECT 
-			m.id,
-			m.room_id,
-			m.sender_id,
-			m.body,
-			m.attachments,
-			m.importance,
-			m.meta,
-			m.created_at,
-			m.edited_at,
-			m.deleted_at,
-			COALESCE(NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), ''), u.email, u.username) AS sender_name,
-			u.role AS sender_role
-		FROM chat_messages m
-		INNER JOIN users u ON u.id = m.sender_id
-		WHERE m.room_id = $1
+	// Thread replies are listed through ListReplies, not the room timeline
+	query := chatMessageSelect + `
+		WHERE m.room_id = $1 AND m.parent_id IS NULL
 	`
 	args := []interface{}{roomID}
 	argPos := 2
@@ -297,37 +338,717 @@ func (r *SQLChatRepository) UpdateMessage(ctx context.Context, msgID, userID, ne
 	return &msg, nil
 }
 
-// DeleteMessage soft deletes a message.
-func (r *SQLChatRepository) DeleteMessage(ctx context.Context, msgID, userID string) error {
-	res, err := r.db.ExecContext(ctx, `
+// DeleteMessage soft deletes a message and returns the room it belonged to.
+func (r *SQLChatRepository) DeleteMessage(ctx context.Context, msgID, userID string) (string, error) {
+	var roomID string
+	err := r.db.QueryRowxContext(ctx, `
 		UPDATE chat_messages
 		SET deleted_at = NOW()
 		WHERE id = $1 AND sender_id = $2 AND deleted_at IS NULL
-	`, msgID, userID)
+		RETURNING room_id
+	`, msgID, userID).Scan(&roomID)
 	if err != nil {
-		return err
+		return "", err
+	}
+	return roomID, nil
+}
+
+// MarkRoomAsRead sets read status.
+func (r *SQLChatRepository) MarkRoomAsRead(ctx context.Context, roomID, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_room_read_status (room_id, user_id, last_read_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (room_id, user_id)
+		DO UPDATE SET last_read_at = NOW()
+	`, roomID, userID)
+	return err
+}
+
+// chatMessageSelect reads messages with their sender and thread summary.
+// Callers append the WHERE clause.
+const chatMessageSelect = `
+	SELECT
+		m.id, m.tenant_id, m.room_id, m.parent_id, m.sender_id, m.body, m.attachments, m.importance, m.meta,
+		m.created_at, m.edited_at, m.deleted_at, m.pinned_at, m.pinned_by,
+		COALESCE(NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), ''), u.email, u.username) AS sender_name,
+		u.role AS sender_role,
+		th.reply_count,
+		th.last_reply_at
+	FROM chat_messages m
+	INNER JOIN users u ON u.id = m.sender_id
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS reply_count, MAX(r.created_at) AS last_reply_at
+		FROM chat_messages r
+		WHERE r.parent_id = m.id AND r.deleted_at IS NULL
+	) th ON true
+`
+
+// GetMessage returns a single message.
+func (r *SQLChatRepository) GetMessage(ctx context.Context, msgID string) (*models.ChatMessage, error) {
+	var msg models.ChatMessage
+	err := r.db.GetContext(ctx, &msg, chatMessageSelect+" WHERE m.id = $1", msgID)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// GetMemberRole returns the user's role in the room, or sql.ErrNoRows if
+// they are not a member.
+func (r *SQLChatRepository) GetMemberRole(ctx context.Context, roomID, userID string) (models.ChatRoomMemberRole, error) {
+	var role models.ChatRoomMemberRole
+	err := r.db.QueryRowContext(ctx, `
+		SELECT role_in_room FROM chat_room_members WHERE room_id = $1 AND user_id = $2
+	`, roomID, userID).Scan(&role)
+	return role, err
+}
+
+// CreateReply posts a reply in the thread of a top-level message. The reply
+// inherits the parent's room; sql.ErrNoRows means the parent is missing,
+// deleted or itself a reply.
+func (r *SQLChatRepository) CreateReply(ctx context.Context, parentID, senderID, body string, attachments models.ChatAttachments, meta json.RawMessage) (*models.ChatMessage, error) {
+	if len(meta) == 0 {
+		meta = json.RawMessage("{}")
+	}
+	if attachments == nil {
+		attachments = models.ChatAttachments{}
+	}
+
+	var msg models.ChatMessage
+	err := r.db.QueryRowxContext(ctx, `
+		WITH parent AS (
+			SELECT tenant_id, room_id FROM chat_messages
+			WHERE id = $1 AND parent_id IS NULL AND deleted_at IS NULL
+		), ins AS (
+			INSERT INTO chat_messages (tenant_id, room_id, parent_id, sender_id, body, attachments, meta)
+			SELECT p.tenant_id, p.room_id, $1, $2, $3, $4, $5 FROM parent p
+			RETURNING id, tenant_id, room_id, parent_id, sender_id, body, attachments, importance, meta, created_at, edited_at, deleted_at
+		)
+		SELECT 
+			i.id, i.tenant_id, i.room_id, i.parent_id, i.sender_id, i.body, i.attachments, i.importance, i.meta, i.created_at, i.edited_at, i.deleted_at,
+			COALESCE(NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), ''), u.email, u.username) AS sender_name,
+			u.role AS sender_role
+		FROM ins i
+		INNER JOIN users u ON u.id = i.sender_id
+	`, parentID, senderID, body, attachments, string(meta)).StructScan(&msg)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// ListReplies returns a thread's replies, oldest first.
+func (r *SQLChatRepository) ListReplies(ctx context.Context, parentID string, limit int, after *time.Time) ([]models.ChatMessage, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	query := chatMessageSelect + " WHERE m.parent_id = $1"
+	args := []interface{}{parentID}
+	if after != nil {
+		query += " AND m.created_at > $2"
+		args = append(args, *after)
+	}
+	query += " ORDER BY m.created_at ASC LIMIT $" + strconv.Itoa(len(args)+1)
+	args = append(args, limit)
+
+	var replies []models.ChatMessage
+	err := r.db.SelectContext(ctx, &replies, query, args...)
+	return replies, err
+}
+
+// ListThreads returns the room's threads the user follows, i.e. started,
+// replied to or opened, with the replies from others they have not read.
+func (r *SQLChatRepository) ListThreads(ctx context.Context, roomID, userID string) ([]models.ChatThread, error) {
+	var threads []models.ChatThread
+	err := r.db.SelectContext(ctx, &threads, `
+		SELECT msg.*, COALESCE(unread.unread_count, 0) AS unread_count
+		FROM (`+chatMessageSelect+` WHERE m.room_id = $1 AND m.parent_id IS NULL) msg
+		LEFT JOIN chat_thread_read_status ts ON ts.parent_id = msg.id AND ts.user_id = $2
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS unread_count
+			FROM chat_messages r
+			WHERE r.parent_id = msg.id
+				AND r.deleted_at IS NULL
+				AND r.sender_id <> $2
+				AND r.created_at > COALESCE(ts.last_read_at, '1970-01-01'::timestamptz)
+		) unread ON true
+		WHERE msg.reply_count > 0 AND (msg.sender_id = $2 OR ts.user_id IS NOT NULL)
+		ORDER BY msg.last_reply_at DESC
+	`, roomID, userID)
+	return threads, err
+}
+
+// MarkThreadAsRead sets the user's read position in a thread.
+func (r *SQLChatRepository) MarkThreadAsRead(ctx context.Context, parentID, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_thread_read_status (parent_id, user_id, last_read_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (parent_id, user_id)
+		DO UPDATE SET last_read_at = NOW()
+	`, parentID, userID)
+	return err
+}
+
+// AddReaction records a user's emoji on a message. It reports false when the
+// user had already reacted with that emoji.
+func (r *SQLChatRepository) AddReaction(ctx context.Context, msgID, userID, emoji string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_message_reactions (message_id, user_id, emoji)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+	`, msgID, userID, emoji)
+	if err != nil {
+		return false, err
 	}
-	rows, err := res.RowsAffected()
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RemoveReaction deletes a user's emoji from a message and reports whether
+// there was one.
+func (r *SQLChatRepository) RemoveReaction(ctx context.Context, msgID, userID, emoji string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM chat_message_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+	`, msgID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListReactions groups the reactions of the given messages by emoji, in the
+// order each emoji was first used.
+func (r *SQLChatRepository) ListReactions(ctx context.Context, msgIDs []string) (map[string][]models.ChatReaction, error) {
+	out := make(map[string][]models.ChatReaction)
+	if len(msgIDs) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT message_id, emoji, COUNT(*), array_agg(user_id::text ORDER BY created_at)
+		FROM chat_message_reactions
+		WHERE message_id = ANY($1)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at)
+	`, pq.Array(msgIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var msgID string
+		var reaction models.ChatReaction
+		if err := rows.Scan(&msgID, &reaction.Emoji, &reaction.Count, pq.Array(&reaction.UserIDs)); err != nil {
+			return nil, err
+		}
+		out[msgID] = append(out[msgID], reaction)
+	}
+	return out, rows.Err()
+}
+
+// SetPinned pins a message on behalf of pinnedBy, or unpins it when pinnedBy
+// is nil.
+func (r *SQLChatRepository) SetPinned(ctx context.Context, msgID string, pinnedBy *string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE chat_messages
+		SET pinned_by = $2, pinned_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE NOW() END
+		WHERE id = $1 AND deleted_at IS NULL
+	`, msgID, pinnedBy)
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
 		return sql.ErrNoRows
 	}
 	return nil
 }
 
-// MarkRoomAsRead sets read status.
-func (r *SQLChatRepository) MarkRoomAsRead(ctx context.Context, roomID, userID string) error {
+// ListPinned returns a room's pinned messages, most recently pinned first.
+func (r *SQLChatRepository) ListPinned(ctx context.Context, roomID string) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	err := r.db.SelectContext(ctx, &messages, chatMessageSelect+`
+		WHERE m.room_id = $1 AND m.pinned_at IS NOT NULL AND m.deleted_at IS NULL
+		ORDER BY m.pinned_at DESC
+	`, roomID)
+	return messages, err
+}
+
+// CreateFile records a stored file shared in a room.
+func (r *SQLChatRepository) CreateFile(ctx context.Context, file *models.ChatFile) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO chat_attachments (version_id, document_id, room_id, uploaded_by, object_key)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, file.VersionID, file.DocumentID, file.RoomID, file.UploadedBy, file.ObjectKey).Scan(&file.CreatedAt)
+}
+
+// GetFile returns the stored file of a document version.
+func (r *SQLChatRepository) GetFile(ctx context.Context, versionID string) (*models.ChatFile, error) {
+	var file models.ChatFile
+	err := r.db.GetContext(ctx, &file, `
+		SELECT version_id, document_id, room_id, uploaded_by, object_key, created_at
+		FROM chat_attachments
+		WHERE version_id = $1
+	`, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListLocalFileMessages returns messages still linking to files on the
+// server's upload directory, oldest first.
+func (r *SQLChatRepository) ListLocalFileMessages(ctx context.Context) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	err := r.db.SelectContext(ctx, &messages, `
+		SELECT id, tenant_id, room_id, sender_id, body, attachments, created_at
+		FROM chat_messages
+		WHERE attachments::text LIKE '%"/uploads/chat/%'
+		ORDER BY created_at ASC
+	`)
+	return messages, err
+}
+
+// UpdateAttachments replaces a message's attachment list.
+func (r *SQLChatRepository) UpdateAttachments(ctx context.Context, msgID string, attachments models.ChatAttachments) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO chat_room_read_status (room_id, user_id, last_read_at)
-		VALUES ($1, $2, NOW())
+		UPDATE chat_messages SET attachments = $2 WHERE id = $1
+	`, msgID, attachments)
+	return err
+}
+
+// GetMember returns a user's membership in a room; sql.ErrNoRows if there is none.
+func (r *SQLChatRepository) GetMember(ctx context.Context, roomID, userID string) (*models.ChatRoomMember, error) {
+	var member models.ChatRoomMember
+	err := r.db.GetContext(ctx, &member, `
+		SELECT m.tenant_id, m.room_id, m.user_id, m.role_in_room, m.joined_at, rs.last_read_at, m.muted_until
+		FROM chat_room_members m
+		LEFT JOIN chat_room_read_status rs ON rs.room_id = m.room_id AND rs.user_id = m.user_id
+		WHERE m.room_id = $1 AND m.user_id = $2
+	`, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// SetMuted mutes a member until the given time, or unmutes with nil.
+// Returns sql.ErrNoRows when the user is not a member.
+func (r *SQLChatRepository) SetMuted(ctx context.Context, roomID, userID string, until *time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE chat_room_members SET muted_until = $3 WHERE room_id = $1 AND user_id = $2
+	`, roomID, userID, until)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// BanMember removes the user from the room and records the ban. Banning an
+// already banned user refreshes the ban; banning anyone else who is not a
+// member returns sql.ErrNoRows.
+func (r *SQLChatRepository) BanMember(ctx context.Context, ban *models.ChatRoomBan) error {
+	return r.db.QueryRowxContext(ctx, `
+		WITH removed AS (
+			DELETE FROM chat_room_members WHERE room_id = $1 AND user_id = $2
+			RETURNING user_id
+		)
+		INSERT INTO chat_room_bans (room_id, user_id, banned_by, reason)
+		SELECT $1, $2, $3, $4
+		WHERE EXISTS (SELECT 1 FROM removed)
+			OR EXISTS (SELECT 1 FROM chat_room_bans WHERE room_id = $1 AND user_id = $2)
 		ON CONFLICT (room_id, user_id)
-		DO UPDATE SET last_read_at = NOW()
+		DO UPDATE SET banned_by = EXCLUDED.banned_by, reason = EXCLUDED.reason, created_at = NOW()
+		RETURNING created_at
+	`, ban.RoomID, ban.UserID, ban.BannedBy, ban.Reason).Scan(&ban.CreatedAt)
+}
+
+// UnbanMember lifts a ban and reports whether there was one.
+func (r *SQLChatRepository) UnbanMember(ctx context.Context, roomID, userID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM chat_room_bans WHERE room_id = $1 AND user_id = $2
 	`, roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// IsBanned checks whether a user is banned from a room.
+func (r *SQLChatRepository) IsBanned(ctx context.Context, roomID, userID string) (bool, error) {
+	var banned bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM chat_room_bans WHERE room_id = $1 AND user_id = $2)
+	`, roomID, userID).Scan(&banned)
+	return banned, err
+}
+
+// ListBans returns a room's bans, newest first.
+func (r *SQLChatRepository) ListBans(ctx context.Context, roomID string) ([]models.ChatRoomBan, error) {
+	var bans []models.ChatRoomBan
+	err := r.db.SelectContext(ctx, &bans, `
+		SELECT room_id, user_id, banned_by, reason, created_at
+		FROM chat_room_bans
+		WHERE room_id = $1
+		ORDER BY created_at DESC
+	`, roomID)
+	return bans, err
+}
+
+// RemoveMessage soft deletes any live message; moderators use it on other
+// members' messages. Returns sql.ErrNoRows when nothing was deleted.
+func (r *SQLChatRepository) RemoveMessage(ctx context.Context, msgID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE chat_messages SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+	`, msgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateRoomSettings sets a room's post policy and retention.
+func (r *SQLChatRepository) UpdateRoomSettings(ctx context.Context, roomID string, policy models.ChatPostPolicy, retentionDays *int, action models.ChatRetentionAction) (*models.ChatRoom, error) {
+	var room models.ChatRoom
+	err := r.db.QueryRowxContext(ctx, `
+		UPDATE chat_rooms
+		SET post_policy = $2, retention_days = $3, retention_action = $4
+		WHERE id = $1
+		RETURNING id, tenant_id, name, type, created_by, created_by_role, is_archived, meta, created_at,
+			post_policy, retention_days, retention_action
+	`, roomID, policy, retentionDays, action).StructScan(&room)
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// CreateModerationEntry appends to a room's moderation log; the tenant is
+// taken from the room.
+func (r *SQLChatRepository) CreateModerationEntry(ctx context.Context, entry *models.ChatModerationEntry) error {
+	details := entry.Details
+	if len(details) == 0 {
+		details = json.RawMessage("{}")
+	}
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO chat_moderation_log (tenant_id, room_id, actor_id, action, target_user_id, target_message_id, reason, details)
+		SELECT r.tenant_id, r.id, $2, $3, $4, $5, $6, $7 FROM chat_rooms r WHERE r.id = $1
+		RETURNING id, tenant_id, created_at
+	`, entry.RoomID, entry.ActorID, entry.Action, entry.TargetUserID, entry.TargetMessageID, entry.Reason, string(details)).
+		Scan(&entry.ID, &entry.TenantID, &entry.CreatedAt)
+}
+
+// ListModerationLog returns a room's moderation log, newest first.
+func (r *SQLChatRepository) ListModerationLog(ctx context.Context, roomID string, limit int, before *time.Time) ([]models.ChatModerationEntry, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var entries []models.ChatModerationEntry
+	err := r.db.SelectContext(ctx, &entries, `
+		SELECT
+			l.id, l.tenant_id, l.room_id, l.actor_id, l.action, l.target_user_id, l.target_message_id,
+			l.reason, l.details, l.created_at,
+			NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '') AS actor_name
+		FROM chat_moderation_log l
+		LEFT JOIN users u ON u.id = l.actor_id
+		WHERE l.room_id = $1 AND ($2::timestamptz IS NULL OR l.created_at < $2)
+		ORDER BY l.created_at DESC
+		LIMIT $3
+	`, roomID, before, limit)
+	return entries, err
+}
+
+// ListRetentionRooms returns the rooms that have a retention policy.
+func (r *SQLChatRepository) ListRetentionRooms(ctx context.Context) ([]models.ChatRoom, error) {
+	var rooms []models.ChatRoom
+	err := r.db.SelectContext(ctx, &rooms, `
+		SELECT id, tenant_id, name, type, created_by, created_by_role, is_archived, meta, created_at,
+			post_policy, retention_days, retention_action
+		FROM chat_rooms
+		WHERE retention_days IS NOT NULL
+		ORDER BY created_at
+	`)
+	return rooms, err
+}
+
+// PurgeExpiredMessages removes up to limit top-level messages of a room whose
+// thread saw no activity since before, together with their replies. Threads
+// with a pinned message, top-level or reply, are kept whole. With archive the rows are copied to
+// chat_messages_archive first. It returns the number of messages removed.
+func (r *SQLChatRepository) PurgeExpiredMessages(ctx context.Context, roomID string, before time.Time, archive bool, limit int) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		WITH expired AS (
+			SELECT m.id FROM chat_messages m
+			WHERE m.room_id = $1 AND m.parent_id IS NULL AND m.pinned_at IS NULL AND m.created_at < $2
+				AND NOT EXISTS (
+					SELECT 1 FROM chat_messages c
+					WHERE c.parent_id = m.id AND (c.created_at >= $2 OR c.pinned_at IS NOT NULL)
+				)
+			ORDER BY m.created_at
+			LIMIT $4
+		), doomed AS (
+			SELECT id FROM expired
+			UNION ALL
+			SELECT c.id FROM chat_messages c JOIN expired e ON c.parent_id = e.id
+		), archived AS (
+			INSERT INTO chat_messages_archive (id, tenant_id, room_id, parent_id, sender_id, body, attachments, importance, meta, created_at, edited_at, deleted_at)
+			SELECT id, tenant_id, room_id, parent_id, sender_id, body, attachments, importance, meta, created_at, edited_at, deleted_at
+			FROM chat_messages
+			WHERE $3 AND id IN (SELECT id FROM doomed)
+			ON CONFLICT (id) DO NOTHING
+		), deleted AS (
+			DELETE FROM chat_messages WHERE id IN (SELECT id FROM doomed)
+			RETURNING 1
+		)
+		SELECT COUNT(*) FROM deleted
+	`, roomID, before, archive, limit).Scan(&n)
+	return n, err
+}
+
+// managedRoomRow is one room/member pair of a managed room spec query.
+type managedRoomRow struct {
+	Ref        string         `db:"managed_ref"`
+	Name       string         `db:"name"`
+	IsArchived bool           `db:"is_archived"`
+	UserID     sql.NullString `db:"user_id"`
+}
+
+// groupManagedRooms folds rows ordered by room into specs with plain members.
+func groupManagedRooms(kind models.ChatManagedKind, rows []managedRoomRow) []models.ChatManagedRoom {
+	var rooms []models.ChatManagedRoom
+	for _, row := range rows {
+		if len(rooms) == 0 || rooms[len(rooms)-1].Ref != row.Ref {
+			rooms = append(rooms, models.ChatManagedRoom{Kind: kind, Ref: row.Ref, Name: row.Name, IsArchived: row.IsArchived})
+		}
+		if row.UserID.Valid {
+			last := &rooms[len(rooms)-1]
+			last.Members = append(last.Members, models.ChatManagedMember{UserID: row.UserID.String, Role: models.ChatRoomMemberRoleMember})
+		}
+	}
+	return rooms
+}
+
+// ListCohortRoomSpecs returns the cohort rooms a tenant should have: one per
+// cohort, archived with it, holding the tenant's active students whose
+// cohort has that name.
+func (r *SQLChatRepository) ListCohortRoomSpecs(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	var rows []managedRoomRow
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT c.id AS managed_ref, c.name, NOT c.is_active AS is_archived, u.id AS user_id
+		FROM cohorts c
+		LEFT JOIN users u ON u.cohort = c.name AND u.is_active
+			AND EXISTS (
+				SELECT 1 FROM user_tenant_memberships tm
+				WHERE tm.user_id = u.id AND tm.tenant_id = c.tenant_id AND tm.role = 'student'
+			)
+		WHERE c.tenant_id = $1
+		ORDER BY c.id, u.id
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return groupManagedRooms(models.ChatManagedCohort, rows), nil
+}
+
+// ListAdvisoryRoomSpecs returns the advisory rooms a tenant should have: one
+// per active advisor with active students, holding those students. The
+// advisor is not listed as a member; callers add them as room admin.
+func (r *SQLChatRepository) ListAdvisoryRoomSpecs(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	var rows []managedRoomRow
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT a.id AS managed_ref, 'Advisory: ' || concat_ws(' ', a.first_name, a.last_name) AS name,
+			false AS is_archived, s.id AS user_id
+		FROM student_advisors sa
+		JOIN users a ON a.id = sa.advisor_id AND a.is_active
+		JOIN users s ON s.id = sa.student_id AND s.is_active
+		WHERE sa.tenant_id = $1
+		ORDER BY a.id, s.id
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return groupManagedRooms(models.ChatManagedAdvisory, rows), nil
+}
+
+// ListManagedRooms returns a tenant's managed rooms with their members and,
+// flagged as Banned, the users banned from them.
+func (r *SQLChatRepository) ListManagedRooms(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	var rooms []models.ChatManagedRoom
+	err := r.db.SelectContext(ctx, &rooms, `
+		SELECT id, managed_kind, managed_ref, name, is_archived
+		FROM chat_rooms
+		WHERE tenant_id = $1 AND managed_kind IS NOT NULL
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil || len(rooms) == 0 {
+		return rooms, err
+	}
+
+	return rooms, r.attachRoomMembers(ctx, tenantID, rooms, "r.managed_kind IS NOT NULL")
+}
+
+// ListAdoptableRooms returns a tenant's cohort and advisory rooms that the
+// room sync does not manage yet, such as rooms made by hand, with their
+// members and bans. Kind is the room's type and Ref is empty.
+func (r *SQLChatRepository) ListAdoptableRooms(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+	var rooms []models.ChatManagedRoom
+	err := r.db.SelectContext(ctx, &rooms, `
+		SELECT id, type AS managed_kind, '' AS managed_ref, name, is_archived
+		FROM chat_rooms
+		WHERE tenant_id = $1 AND managed_kind IS NULL AND type IN ('cohort', 'advisory')
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil || len(rooms) == 0 {
+		return rooms, err
+	}
+	return rooms, r.attachRoomMembers(ctx, tenantID, rooms, "r.managed_kind IS NULL AND r.type IN ('cohort', 'advisory')")
+}
+
+// attachRoomMembers loads the members and bans of the tenant's rooms that
+// match filter, a condition on chat_rooms r, into rooms.
+func (r *SQLChatRepository) attachRoomMembers(ctx context.Context, tenantID string, rooms []models.ChatManagedRoom, filter string) error {
+	var members []struct {
+		RoomID string `db:"room_id"`
+		models.ChatManagedMember
+	}
+	err := r.db.SelectContext(ctx, &members, `
+		SELECT m.room_id, m.user_id, m.role_in_room, m.managed, false AS banned
+		FROM chat_room_members m
+		JOIN chat_rooms r ON r.id = m.room_id
+		WHERE r.tenant_id = $1 AND `+filter+`
+		UNION ALL
+		SELECT b.room_id, b.user_id, 'member', false, true
+		FROM chat_room_bans b
+		JOIN chat_rooms r ON r.id = b.room_id
+		WHERE r.tenant_id = $1 AND `+filter+`
+	`, tenantID)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int, len(rooms))
+	for i, room := range rooms {
+		index[room.ID] = i
+	}
+	for _, m := range members {
+		if i, ok := index[m.RoomID]; ok {
+			rooms[i].Members = append(rooms[i].Members, m.ChatManagedMember)
+		}
+	}
+	return nil
+}
+
+// AdoptManagedRoom turns an unmanaged room into the managed room for kind
+// and ref. The memberships of memberIDs become managed, so the sync updates
+// and removes them from now on; everyone else stays as added by hand. It
+// returns ErrNotFound when the room is gone or another sync adopted it.
+func (r *SQLChatRepository) AdoptManagedRoom(ctx context.Context, roomID string, kind models.ChatManagedKind, ref string, memberIDs []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE chat_rooms SET managed_kind = $2, managed_ref = $3
+		WHERE id = $1 AND managed_kind IS NULL
+	`, roomID, string(kind), ref)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	if len(memberIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE chat_room_members SET managed = true
+			WHERE room_id = $1 AND user_id = ANY($2)
+		`, roomID, pq.Array(memberIDs))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateManagedRoom creates the managed room for room.Kind and room.Ref, or
+// renames the one a concurrent sync created, and returns its id. The room is
+// owned by ownerID, or by the tenant's oldest admin when ownerID is empty.
+// Nobody is added as a member.
+func (r *SQLChatRepository) CreateManagedRoom(ctx context.Context, tenantID string, room models.ChatManagedRoom, ownerID string) (string, error) {
+	var id string
+	err := r.db.QueryRowContext(ctx, `
+		WITH owner AS (
+			SELECT id, role FROM (
+				SELECT u.id, u.role, 0 AS pref, u.created_at FROM users u WHERE u.id::text = $6
+				UNION ALL
+				SELECT u.id, u.role, 1, tm.created_at
+				FROM user_tenant_memberships tm
+				JOIN users u ON u.id = tm.user_id AND u.is_active
+				WHERE tm.tenant_id = $1 AND tm.role IN ('admin', 'superadmin')
+			) o
+			ORDER BY pref, created_at
+			LIMIT 1
+		)
+		INSERT INTO chat_rooms (tenant_id, name, type, created_by, created_by_role, is_archived, meta, managed_kind, managed_ref)
+		SELECT $1, $2, $3, o.id, o.role, $4, '{}', $3, $5 FROM owner o
+		ON CONFLICT (tenant_id, managed_kind, managed_ref) WHERE managed_kind IS NOT NULL
+		DO UPDATE SET name = EXCLUDED.name, is_archived = EXCLUDED.is_archived
+		RETURNING id
+	`, tenantID, room.Name, string(room.Kind), room.IsArchived, room.Ref, ownerID).Scan(&id)
+	return id, err
+}
+
+// AddManagedMember adds a member on behalf of the room sync. A managed
+// membership gets its role updated; one added by hand is left untouched.
+func (r *SQLChatRepository) AddManagedMember(ctx context.Context, tenantID, roomID, userID string, role models.ChatRoomMemberRole) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_room_members (tenant_id, room_id, user_id, role_in_room, managed)
+		VALUES ($1, $2, $3, $4, true)
+		ON CONFLICT (room_id, user_id) DO UPDATE SET role_in_room = EXCLUDED.role_in_room
+		WHERE chat_room_members.managed
+	`, tenantID, roomID, userID, role)
 	return err
 }
 
+// RemoveManagedMember removes a membership the room sync added. It reports
+// whether one was removed.
+func (r *SQLChatRepository) RemoveManagedMember(ctx context.Context, roomID, userID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM chat_room_members WHERE room_id = $1 AND user_id = $2 AND managed
+	`, roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListManagedRoomTenants returns the tenants that have, or should have,
+// managed rooms.
+func (r *SQLChatRepository) ListManagedRoomTenants(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids, `
+		SELECT tenant_id FROM cohorts WHERE tenant_id IS NOT NULL
+		UNION
+		SELECT tenant_id FROM student_advisors
+		UNION
+		SELECT tenant_id FROM chat_rooms WHERE managed_kind IS NOT NULL AND tenant_id IS NOT NULL
+	`)
+	return ids, err
+}
+
 // GetUsersByFilters is a helper for batch operations. 
 func (r *SQLChatRepository) GetUsersByFilters(ctx context.Context, filters map[string]string) ([]string, error) {
 	query := `SELECT id FROM users WHERE is_active=true`
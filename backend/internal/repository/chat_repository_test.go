@@ -223,8 +223,9 @@ func TestChatRepository_DeleteMessage(t *testing.T) {
 	msg, err := repo.CreateMessage(ctx, room.ID, userID, "To be deleted", nil, nil, nil)
 	require.NoError(t, err)
 
-	err = repo.DeleteMessage(ctx, msg.ID, userID)
+	roomID, err := repo.DeleteMessage(ctx, msg.ID, userID)
 	require.NoError(t, err)
+	assert.Equal(t, room.ID, roomID)
 
 	// Messages should now be empty (soft deleted)
 	// Note: ListMessages in repo DOES NOT filter deleted messages currently if we look closely at repo?
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -131,3 +133,340 @@ func TestSQLChatRepository_MarkRoomAsRead_Unit(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestSQLChatRepository_DeleteMessage_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLChatRepository(sqlxDB)
+
+	t.Run("Returns room", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE chat_messages\s+SET deleted_at = NOW\(\).*RETURNING room_id`).
+			WithArgs("msg-1", "user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"room_id"}).AddRow("room-1"))
+
+		roomID, err := repo.DeleteMessage(context.Background(), "msg-1", "user-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "room-1", roomID)
+	})
+
+	t.Run("Not sender", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE chat_messages`).
+			WithArgs("msg-1", "user-2").
+			WillReturnRows(sqlmock.NewRows([]string{"room_id"}))
+
+		_, err := repo.DeleteMessage(context.Background(), "msg-1", "user-2")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestSQLChatRepository_CreateReply_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLChatRepository(sqlx.NewDb(db, "sqlmock"))
+	cols := []string{"id", "tenant_id", "room_id", "parent_id", "sender_id", "body", "attachments", "importance", "meta", "created_at", "edited_at", "deleted_at", "sender_name", "sender_role"}
+
+	t.Run("Inherits the parent's room", func(t *testing.T) {
+		mock.ExpectQuery(`WITH parent AS`).
+			WithArgs("p1", "u1", "hi", "[]", "{}").
+			WillReturnRows(sqlmock.NewRows(cols).
+				AddRow("c1", "t1", "r1", "p1", "u1", "hi", []byte("[]"), nil, []byte("{}"), time.Now(), nil, nil, "User One", "student"))
+
+		reply, err := repo.CreateReply(context.Background(), "p1", "u1", "hi", nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "r1", reply.RoomID)
+		assert.Equal(t, "p1", *reply.ParentID)
+	})
+
+	t.Run("Parent is a reply", func(t *testing.T) {
+		mock.ExpectQuery(`WITH parent AS`).WillReturnRows(sqlmock.NewRows(cols))
+
+		_, err := repo.CreateReply(context.Background(), "c1", "u1", "hi", nil, nil)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLChatRepository_Reactions_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLChatRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+
+	t.Run("Add is idempotent", func(t *testing.T) {
+		mock.ExpectExec(`INSERT INTO chat_message_reactions`).
+			WithArgs("m1", "u1", "👍").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO chat_message_reactions`).
+			WithArgs("m1", "u1", "👍").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		added, err := repo.AddReaction(ctx, "m1", "u1", "👍")
+		assert.NoError(t, err)
+		assert.True(t, added)
+		added, err = repo.AddReaction(ctx, "m1", "u1", "👍")
+		assert.NoError(t, err)
+		assert.False(t, added)
+	})
+
+	t.Run("List groups by message", func(t *testing.T) {
+		mock.ExpectQuery(`FROM chat_message_reactions`).
+			WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "user_ids"}).
+				AddRow("m1", "👍", 2, "{u1,u2}").
+				AddRow("m1", "🎉", 1, "{u2}").
+				AddRow("m2", "👍", 1, "{u3}"))
+
+		reactions, err := repo.ListReactions(ctx, []string{"m1", "m2"})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ChatReaction{
+			{Emoji: "👍", Count: 2, UserIDs: []string{"u1", "u2"}},
+			{Emoji: "🎉", Count: 1, UserIDs: []string{"u2"}},
+		}, reactions["m1"])
+		assert.Len(t, reactions["m2"], 1)
+	})
+
+	t.Run("List without messages skips the query", func(t *testing.T) {
+		reactions, err := repo.ListReactions(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, reactions)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLChatRepository_SetPinned_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLChatRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+	mod := "mod-1"
+
+	mock.ExpectExec(`UPDATE chat_messages\s+SET pinned_by`).
+		WithArgs("m1", &mod).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.SetPinned(ctx, "m1", &mod))
+
+	mock.ExpectExec(`UPDATE chat_messages\s+SET pinned_by`).
+		WithArgs("gone", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.SetPinned(ctx, "gone", nil), sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLChatRepository_Files_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLChatRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+	uploader := "u1"
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO chat_attachments`).
+		WithArgs("v1", "d1", "r1", &uploader, "documents/d1/x.pdf").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+	file := &models.ChatFile{VersionID: "v1", DocumentID: "d1", RoomID: "r1", UploadedBy: &uploader, ObjectKey: "documents/d1/x.pdf"}
+	assert.NoError(t, repo.CreateFile(ctx, file))
+	assert.Equal(t, now, file.CreatedAt)
+
+	mock.ExpectQuery(`FROM chat_attachments\s+WHERE version_id = \$1`).
+		WithArgs("v1").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "document_id", "room_id", "uploaded_by", "object_key", "created_at"}).
+			AddRow("v1", "d1", "r1", "u1", "documents/d1/x.pdf", now))
+	got, err := repo.GetFile(ctx, "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "r1", got.RoomID)
+
+	mock.ExpectQuery(`FROM chat_attachments`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetFile(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLChatRepository_Moderation_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLChatRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+	until := time.Now().Add(time.Hour)
+
+	t.Run("Mute needs a membership", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE chat_room_members SET muted_until`).
+			WithArgs("r1", "u1", &until).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.SetMuted(ctx, "r1", "u1", &until))
+
+		mock.ExpectExec(`UPDATE chat_room_members SET muted_until`).
+			WithArgs("r1", "gone", nil).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.SetMuted(ctx, "r1", "gone", nil), sql.ErrNoRows)
+	})
+
+	t.Run("Ban removes the membership", func(t *testing.T) {
+		by := "mod"
+		now := time.Now()
+		mock.ExpectQuery(`DELETE FROM chat_room_members .*INSERT INTO chat_room_bans`).
+			WithArgs("r1", "u1", &by, "spam").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+		ban := &models.ChatRoomBan{RoomID: "r1", UserID: "u1", BannedBy: &by, Reason: "spam"}
+		assert.NoError(t, repo.BanMember(ctx, ban))
+		assert.Equal(t, now, ban.CreatedAt)
+
+		mock.ExpectQuery(`DELETE FROM chat_room_members .*WHERE EXISTS \(SELECT 1 FROM removed\)`).
+			WithArgs("r1", "stranger", &by, "").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+		err := repo.BanMember(ctx, &models.ChatRoomBan{RoomID: "r1", UserID: "stranger", BannedBy: &by})
+		assert.ErrorIs(t, err, sql.ErrNoRows, "only members can be banned")
+	})
+
+	t.Run("Moderation entries take the room's tenant", func(t *testing.T) {
+		actor, target := "mod", "u1"
+		mock.ExpectQuery(`INSERT INTO chat_moderation_log .*FROM chat_rooms r WHERE r.id = \$1`).
+			WithArgs("r1", &actor, models.ChatModMute, &target, nil, "spam", "{}").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "created_at"}).AddRow("e1", "t1", time.Now()))
+		entry := &models.ChatModerationEntry{RoomID: "r1", ActorID: &actor, Action: models.ChatModMute, TargetUserID: &target, Reason: "spam"}
+		assert.NoError(t, repo.CreateModerationEntry(ctx, entry))
+		assert.Equal(t, "e1", entry.ID)
+		assert.Equal(t, "t1", *entry.TenantID)
+	})
+
+	t.Run("Purge archives and deletes whole threads", func(t *testing.T) {
+		cutoff := time.Now().AddDate(0, 0, -30)
+		mock.ExpectQuery(`(?s)WITH expired AS .*pinned_at IS NULL.*c.pinned_at IS NOT NULL.*INSERT INTO chat_messages_archive.*DELETE FROM chat_messages`).
+			WithArgs("r1", cutoff, true, 500).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+		n, err := repo.PurgeExpiredMessages(ctx, "r1", cutoff, true, 500)
+		assert.NoError(t, err)
+		assert.Equal(t, 12, n)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLChatRepository_ManagedRooms_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLChatRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+
+	t.Run("Cohort specs are grouped per cohort", func(t *testing.T) {
+		mock.ExpectQuery(`(?s)FROM cohorts c.*LEFT JOIN users u ON u.cohort = c.name`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"managed_ref", "name", "is_archived", "user_id"}).
+				AddRow("c1", "Cohort 2024", false, "s1").
+				AddRow("c1", "Cohort 2024", false, "s2").
+				AddRow("c2", "Cohort 2019", true, nil))
+		rooms, err := repo.ListCohortRoomSpecs(ctx, "t1")
+		assert.NoError(t, err)
+		if assert.Len(t, rooms, 2) {
+			assert.Equal(t, models.ChatManagedCohort, rooms[0].Kind)
+			assert.Len(t, rooms[0].Members, 2)
+			assert.True(t, rooms[1].IsArchived)
+			assert.Empty(t, rooms[1].Members, "a cohort without students still gets a room")
+		}
+	})
+
+	t.Run("Managed rooms carry members and bans", func(t *testing.T) {
+		mock.ExpectQuery(`FROM chat_rooms\s+WHERE tenant_id = \$1 AND managed_kind IS NOT NULL`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "managed_kind", "managed_ref", "name", "is_archived"}).
+				AddRow("r1", "cohort", "c1", "Cohort 2024", false))
+		mock.ExpectQuery(`(?s)FROM chat_room_members m.*UNION ALL.*FROM chat_room_bans b`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"room_id", "user_id", "role_in_room", "managed", "banned"}).
+				AddRow("r1", "s1", "member", true, false).
+				AddRow("r1", "s3", "member", false, true))
+		rooms, err := repo.ListManagedRooms(ctx, "t1")
+		assert.NoError(t, err)
+		if assert.Len(t, rooms, 1) && assert.Len(t, rooms[0].Members, 2) {
+			assert.True(t, rooms[0].Members[0].Managed)
+			assert.True(t, rooms[0].Members[1].Banned)
+		}
+	})
+
+	t.Run("Hand-made cohort and advisory rooms can be adopted", func(t *testing.T) {
+		mock.ExpectQuery(`(?s)SELECT id, type AS managed_kind.*WHERE tenant_id = \$1 AND managed_kind IS NULL AND type IN \('cohort', 'advisory'\)`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "managed_kind", "managed_ref", "name", "is_archived"}).
+				AddRow("r5", "advisory", "", "Ann's students", false))
+		mock.ExpectQuery(`(?s)FROM chat_room_members m.*r.managed_kind IS NULL.*UNION ALL.*FROM chat_room_bans b`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"room_id", "user_id", "role_in_room", "managed", "banned"}).
+				AddRow("r5", "a1", "admin", false, false))
+		rooms, err := repo.ListAdoptableRooms(ctx, "t1")
+		assert.NoError(t, err)
+		if assert.Len(t, rooms, 1) && assert.Len(t, rooms[0].Members, 1) {
+			assert.Equal(t, models.ChatManagedAdvisory, rooms[0].Kind)
+			assert.False(t, rooms[0].Members[0].Managed)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE chat_rooms SET managed_kind = \$2, managed_ref = \$3\s+WHERE id = \$1 AND managed_kind IS NULL`).
+			WithArgs("r5", "advisory", "a1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE chat_room_members SET managed = true`).
+			WithArgs("r5", pq.Array([]string{"a1"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		assert.NoError(t, repo.AdoptManagedRoom(ctx, "r5", models.ChatManagedAdvisory, "a1", []string{"a1"}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE chat_rooms SET managed_kind`).
+			WithArgs("r5", "advisory", "a1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		err = repo.AdoptManagedRoom(ctx, "r5", models.ChatManagedAdvisory, "a1", []string{"a1"})
+		assert.ErrorIs(t, err, ErrNotFound, "another sync adopted it first")
+	})
+
+	t.Run("Create falls back to a tenant admin", func(t *testing.T) {
+		mock.ExpectQuery(`(?s)WITH owner AS .*tm.role IN \('admin', 'superadmin'\).*ON CONFLICT \(tenant_id, managed_kind, managed_ref\)`).
+			WithArgs("t1", "Cohort 2024", "cohort", false, "c1", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("r1"))
+		id, err := repo.CreateManagedRoom(ctx, "t1", models.ChatManagedRoom{Kind: models.ChatManagedCohort, Ref: "c1", Name: "Cohort 2024"}, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "r1", id)
+	})
+
+	t.Run("Only managed memberships are touched", func(t *testing.T) {
+		mock.ExpectExec(`(?s)INSERT INTO chat_room_members .*WHERE chat_room_members.managed`).
+			WithArgs("t1", "r1", "a1", models.ChatRoomMemberRoleAdmin).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.AddManagedMember(ctx, "t1", "r1", "a1", models.ChatRoomMemberRoleAdmin))
+
+		mock.ExpectExec(`DELETE FROM chat_room_members WHERE room_id = \$1 AND user_id = \$2 AND managed`).
+			WithArgs("r1", "head").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		removed, err := repo.RemoveManagedMember(ctx, "r1", "head")
+		assert.NoError(t, err)
+		assert.False(t, removed)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
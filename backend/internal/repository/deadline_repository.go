@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type DeadlineRepository interface {
+	ListByUser(ctx context.Context, tenantID, userID string) ([]models.NodeDeadline, error)
+	UpsertRule(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time) error
+	SetOverride(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time, note *string, actorID string) error
+	ClearOverride(ctx context.Context, tenantID, userID, nodeID string) (bool, error)
+	ActivationTimes(ctx context.Context, tenantID, userID string) (map[string]time.Time, error)
+	CohortStart(ctx context.Context, tenantID, userID string) (*time.Time, error)
+	ListStudents(ctx context.Context) ([]models.DeadlineStudent, error)
+}
+
+type SQLDeadlineRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLDeadlineRepository(db *sqlx.DB) *SQLDeadlineRepository {
+	return &SQLDeadlineRepository{db: db}
+}
+
+// ListByUser returns the student's deadlines ordered by due date.
+// Rows written before deadlines were tenant-scoped have no tenant and are included.
+func (r *SQLDeadlineRepository) ListByUser(ctx context.Context, tenantID, userID string) ([]models.NodeDeadline, error) {
+	var out []models.NodeDeadline
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT id, user_id, node_id, due_at, source, note, created_by, created_at, updated_at
+		FROM node_deadlines
+		WHERE user_id=$1 AND (tenant_id=$2 OR tenant_id IS NULL)
+		ORDER BY due_at, node_id`, userID, tenantID)
+	return out, err
+}
+
+// UpsertRule stores a rule-derived deadline. Admin overrides are left untouched.
+func (r *SQLDeadlineRepository) UpsertRule(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO node_deadlines (tenant_id, user_id, node_id, due_at, source)
+		VALUES ($1, $2, $3, $4, 'rule')
+		ON CONFLICT (user_id, node_id) DO UPDATE
+		SET due_at=EXCLUDED.due_at, tenant_id=EXCLUDED.tenant_id, updated_at=now()
+		WHERE node_deadlines.source='rule' AND node_deadlines.due_at IS DISTINCT FROM EXCLUDED.due_at`,
+		tenantID, userID, nodeID, dueAt)
+	return err
+}
+
+// SetOverride sets an admin deadline for the student, replacing any rule-derived one.
+func (r *SQLDeadlineRepository) SetOverride(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time, note *string, actorID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO node_deadlines (tenant_id, user_id, node_id, due_at, note, created_by, source)
+		VALUES ($1, $2, $3, $4, $5, $6, 'override')
+		ON CONFLICT (user_id, node_id) DO UPDATE
+		SET due_at=EXCLUDED.due_at, note=EXCLUDED.note, created_by=EXCLUDED.created_by,
+		    tenant_id=EXCLUDED.tenant_id, source='override', updated_at=now()`,
+		tenantID, userID, nodeID, dueAt, note, actorID)
+	return err
+}
+
+// ClearOverride removes an admin deadline. It reports whether one existed.
+func (r *SQLDeadlineRepository) ClearOverride(ctx context.Context, tenantID, userID, nodeID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM node_deadlines
+		WHERE user_id=$1 AND node_id=$2 AND source='override' AND (tenant_id=$3 OR tenant_id IS NULL)`,
+		userID, nodeID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ActivationTimes returns when each of the student's nodes was opened.
+func (r *SQLDeadlineRepository) ActivationTimes(ctx context.Context, tenantID, userID string) (map[string]time.Time, error) {
+	var rows []struct {
+		NodeID   string    `db:"node_id"`
+		OpenedAt time.Time `db:"opened_at"`
+	}
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT node_id, MAX(opened_at) AS opened_at
+		FROM node_instances
+		WHERE user_id=$1 AND tenant_id=$2
+		GROUP BY node_id`, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		out[row.NodeID] = row.OpenedAt
+	}
+	return out, nil
+}
+
+// CohortStart returns the start date of the student's cohort, or nil when the
+// student has no cohort or it has no start date.
+func (r *SQLDeadlineRepository) CohortStart(ctx context.Context, tenantID, userID string) (*time.Time, error) {
+	var start sql.NullTime
+	err := r.db.QueryRowxContext(ctx, `
+		SELECT c.start_date
+		FROM users u
+		JOIN cohorts c ON c.name = u.cohort AND c.tenant_id = $2
+		WHERE u.id = $1`, userID, tenantID).Scan(&start)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !start.Valid {
+		return nil, nil
+	}
+	return &start.Time, nil
+}
+
+// ListStudents returns every active student membership across tenants.
+func (r *SQLDeadlineRepository) ListStudents(ctx context.Context) ([]models.DeadlineStudent, error) {
+	var out []models.DeadlineStudent
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT utm.tenant_id, utm.user_id
+		FROM user_tenant_memberships utm
+		JOIN users u ON u.id = utm.user_id AND u.is_active
+		WHERE utm.role='student'
+		ORDER BY utm.tenant_id, utm.user_id`)
+	return out, err
+}
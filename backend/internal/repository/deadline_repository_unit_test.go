@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLDeadlineRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLDeadlineRepository(sqlxDB)
+	ctx := context.Background()
+	due := time.Date(2026, 5, 1, 23, 59, 59, 0, time.UTC)
+
+	t.Run("ListByUser", func(t *testing.T) {
+		mock.ExpectQuery(`FROM node_deadlines\s+WHERE user_id=\$1 AND \(tenant_id=\$2 OR tenant_id IS NULL\)`).
+			WithArgs("u1", "t1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "node_id", "due_at", "source", "note", "created_by", "created_at", "updated_at"}).
+				AddRow("d1", "u1", "S1", due, "rule", nil, nil, due, due))
+
+		list, err := repo.ListByUser(ctx, "t1", "u1")
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, "rule", list[0].Source)
+			assert.Nil(t, list[0].CreatedBy)
+		}
+	})
+
+	t.Run("UpsertRule keeps overrides", func(t *testing.T) {
+		mock.ExpectExec(`INSERT INTO node_deadlines .+ 'rule'\)\s+ON CONFLICT \(user_id, node_id\) DO UPDATE .+ WHERE node_deadlines.source='rule'`).
+			WithArgs("t1", "u1", "S1", due).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.UpsertRule(ctx, "t1", "u1", "S1", due))
+	})
+
+	t.Run("SetOverride", func(t *testing.T) {
+		note := "extension"
+		mock.ExpectExec(`INSERT INTO node_deadlines .+ 'override'\)`).
+			WithArgs("t1", "u1", "S1", due, &note, "admin").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.SetOverride(ctx, "t1", "u1", "S1", due, &note, "admin"))
+	})
+
+	t.Run("ListStudents", func(t *testing.T) {
+		mock.ExpectQuery(`FROM user_tenant_memberships utm\s+JOIN users u ON u.id = utm.user_id AND u.is_active\s+WHERE utm.role='student'`).
+			WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "user_id"}).AddRow("t1", "u1").AddRow("t2", "u2"))
+		students, err := repo.ListStudents(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, students, 2)
+	})
+
+	t.Run("ClearOverride", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM node_deadlines\s+WHERE user_id=\$1 AND node_id=\$2 AND source='override'`).
+			WithArgs("u1", "S1", "t1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		found, err := repo.ClearOverride(ctx, "t1", "u1", "S1")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("ActivationTimes", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT node_id, MAX\(opened_at\) AS opened_at\s+FROM node_instances`).
+			WithArgs("u1", "t1").
+			WillReturnRows(sqlmock.NewRows([]string{"node_id", "opened_at"}).AddRow("S1", due))
+		times, err := repo.ActivationTimes(ctx, "t1", "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, due, times["S1"])
+	})
+
+	t.Run("CohortStart", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT c.start_date\s+FROM users u\s+JOIN cohorts c`).
+			WithArgs("u1", "t1").
+			WillReturnRows(sqlmock.NewRows([]string{"start_date"}).AddRow(due))
+		start, err := repo.CohortStart(ctx, "t1", "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, due, *start)
+
+		mock.ExpectQuery(`SELECT c.start_date`).
+			WithArgs("u2", "t1").
+			WillReturnError(sql.ErrNoRows)
+		start, err = repo.CohortStart(ctx, "t1", "u2")
+		assert.NoError(t, err)
+		assert.Nil(t, start)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
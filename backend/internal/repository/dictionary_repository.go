@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
 
@@ -327,8 +329,39 @@ func (r *SQLDictionaryRepository) UpdateCohort(ctx context.Context, tenantID, id
 	args = append(args, id, tenantID)
 	query := "UPDATE cohorts SET " + strings.Join(setParts, ", ") + " WHERE id = $" + itoa(argId) + " AND tenant_id = $" + itoa(argId+1)
 
-	_, err := r.db.ExecContext(ctx, query, args...)
-	return err
+	if name == "" {
+		_, err := r.db.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	// Students refer to their cohort by name, so a rename carries over to them
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	err = tx.GetContext(ctx, &oldName, `SELECT name FROM cohorts WHERE id = $1 AND tenant_id = $2 FOR UPDATE`, id, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if oldName != name {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users SET cohort = $1, updated_at = now()
+			WHERE cohort = $2 AND id IN (SELECT user_id FROM user_tenant_memberships WHERE tenant_id = $3)
+		`, name, oldName, tenantID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *SQLDictionaryRepository) DeleteCohort(ctx context.Context, tenantID, id string) error {
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// DocumentTextRepository stores the text extracted from document versions.
+type DocumentTextRepository interface {
+	// ListPendingTexts returns versions without extracted text, newest
+	// first, and failed ones due for another attempt.
+	ListPendingTexts(ctx context.Context, limit, maxAttempts int) ([]models.PendingDocumentText, error)
+	// SaveText records the outcome of an extraction, counting the attempt.
+	SaveText(ctx context.Context, text *models.DocumentText) error
+	// GetText returns the extraction outcome of a version, or sql.ErrNoRows
+	// while it is pending.
+	GetText(ctx context.Context, versionID string) (*models.DocumentText, error)
+	// ListSlotVersions returns the versions attached to a slot of the
+	// student's latest instance of the node, oldest first.
+	ListSlotVersions(ctx context.Context, tenantID, studentID, nodeID, slotKey string) ([]models.SlotVersion, error)
+}
+
+type SQLDocumentTextRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLDocumentTextRepository(db *sqlx.DB) *SQLDocumentTextRepository {
+	return &SQLDocumentTextRepository{db: db}
+}
+
+func (r *SQLDocumentTextRepository) ListPendingTexts(ctx context.Context, limit, maxAttempts int) ([]models.PendingDocumentText, error) {
+	var pending []models.PendingDocumentText
+	// Failed versions back off ten minutes per attempt
+	err := r.db.SelectContext(ctx, &pending, `
+		SELECT dv.id AS version_id, dv.tenant_id, dv.storage_path, dv.object_key, dv.mime_type, dv.size_bytes,
+			COALESCE(t.attempts, 0) AS attempts
+		FROM document_versions dv
+		LEFT JOIN document_version_texts t ON t.version_id = dv.id
+		WHERE t.version_id IS NULL
+			OR (t.status = 'failed' AND t.attempts < $2 AND t.updated_at < now() - t.attempts * interval '10 minutes')
+		ORDER BY dv.created_at DESC
+		LIMIT $1`, limit, maxAttempts)
+	return pending, err
+}
+
+func (r *SQLDocumentTextRepository) SaveText(ctx context.Context, text *models.DocumentText) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO document_version_texts
+			(version_id, tenant_id, status, format, content, page_count, word_count, error, extracted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (version_id) DO UPDATE SET
+			status = EXCLUDED.status, format = EXCLUDED.format, content = EXCLUDED.content,
+			page_count = EXCLUDED.page_count, word_count = EXCLUDED.word_count, error = EXCLUDED.error,
+			extracted_at = EXCLUDED.extracted_at, attempts = document_version_texts.attempts + 1, updated_at = now(),
+			minhash = NULL, shingle_count = NULL`,
+		text.VersionID, text.TenantID, text.Status, text.Format, text.Content,
+		text.PageCount, text.WordCount, text.Error, text.ExtractedAt)
+	return err
+}
+
+func (r *SQLDocumentTextRepository) GetText(ctx context.Context, versionID string) (*models.DocumentText, error) {
+	var text models.DocumentText
+	err := r.db.GetContext(ctx, &text, `
+		SELECT version_id, tenant_id, status, format, content, page_count, word_count, error, attempts,
+			extracted_at, updated_at
+		FROM document_version_texts WHERE version_id=$1`, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return &text, nil
+}
+
+func (r *SQLDocumentTextRepository) ListSlotVersions(ctx context.Context, tenantID, studentID, nodeID, slotKey string) ([]models.SlotVersion, error) {
+	var versions []models.SlotVersion
+	err := r.db.SelectContext(ctx, &versions, `
+		SELECT a.id AS attachment_id, a.document_version_id AS version_id, a.filename, a.status, a.is_active,
+			a.attached_at, t.status AS text_status, t.word_count
+		FROM node_instance_slot_attachments a
+		JOIN node_instance_slots s ON s.id = a.slot_id
+		LEFT JOIN document_version_texts t ON t.version_id = a.document_version_id
+		WHERE s.slot_key = $4 AND s.node_instance_id = (
+			SELECT id FROM node_instances
+			WHERE tenant_id=$1 AND user_id=$2 AND node_id=$3
+			ORDER BY updated_at DESC LIMIT 1)
+		ORDER BY a.attached_at, a.id`, tenantID, studentID, nodeID, slotKey)
+	return versions, err
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLDocumentTextRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLDocumentTextRepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("GetText", func(t *testing.T) {
+		mock.ExpectQuery(`FROM document_version_texts WHERE version_id=\$1`).
+			WithArgs("ver-1").
+			WillReturnRows(sqlmock.NewRows([]string{"version_id", "tenant_id", "status", "content", "attempts", "updated_at"}).
+				AddRow("ver-1", "t1", "done", "Aims", 1, now))
+		text, err := repo.GetText(ctx, "ver-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Aims", text.Content)
+
+		mock.ExpectQuery(`FROM document_version_texts`).WithArgs("ver-2").WillReturnError(sql.ErrNoRows)
+		_, err = repo.GetText(ctx, "ver-2")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("ListSlotVersions", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE s.slot_key = \$4 AND s.node_instance_id = \(\s+SELECT id FROM node_instances`).
+			WithArgs("t1", "s1", "S1_text_ready", "dissertation_draft_file").
+			WillReturnRows(sqlmock.NewRows([]string{"attachment_id", "version_id", "filename", "status", "is_active", "attached_at", "text_status", "word_count"}).
+				AddRow("att-1", "ver-1", "draft.docx", "rejected", false, now, "done", 41000).
+				AddRow("att-2", "ver-2", "draft-fixed.docx", "submitted", true, now.Add(time.Hour), nil, nil))
+		history, err := repo.ListSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file")
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, 41000, *history[0].WordCount)
+		assert.Nil(t, history[1].TextStatus)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
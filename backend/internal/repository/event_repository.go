@@ -64,9 +64,14 @@ func (r *SQLEventRepository) CreateEvent(ctx context.Context, event *models.Even
 	return tx.Commit()
 }
 
+// eventColumns are the events columns models.Event maps; search_tsv is left out.
+const eventColumns = `e.id, e.tenant_id, e.creator_id, e.title, COALESCE(e.description, '') AS description,
+	e.start_time, e.end_time, e.event_type, COALESCE(e.location, '') AS location, e.meeting_type, e.meeting_url,
+	e.physical_address, e.color, e.created_at, e.updated_at`
+
 func (r *SQLEventRepository) GetEvents(ctx context.Context, userID, tenantID string, start, end time.Time) ([]models.Event, error) {
 	query := `
-		SELECT e.* FROM events e
+		SELECT `+eventColumns+` FROM events e
 		LEFT JOIN event_attendees ea ON e.id = ea.event_id
 		WHERE (e.creator_id = $1 OR ea.user_id = $1)
 		AND e.tenant_id = $2
@@ -82,7 +87,7 @@ func (r *SQLEventRepository) GetEvents(ctx context.Context, userID, tenantID str
 }
 
 func (r *SQLEventRepository) GetEvent(ctx context.Context, eventID string) (*models.Event, error) {
-	query := `SELECT * FROM events WHERE id = $1`
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = $1`
 	var event models.Event
 	err := r.db.GetContext(ctx, &event, query, eventID)
 	if err != nil {
@@ -29,6 +29,9 @@ type JourneyRepository interface {
 	CreateNodeInstance(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error)
 	UpdateNodeInstanceState(ctx context.Context, instanceID, oldState, newState string) error
 	GetAllowedTransitionRoles(ctx context.Context, fromState, toState string) ([]string, error)
+	ListVersionInstances(ctx context.Context, tenantID, versionID string) ([]models.NodeInstance, error)
+	RemapNodeInstance(ctx context.Context, instanceID, nodeID, versionID string) error
+	RenameJourneyStateNode(ctx context.Context, userID, tenantID, fromNodeID, toNodeID string) error
 	
 	// Submissions
 	GetNodeInstanceSlots(ctx context.Context, instanceID string) ([]models.NodeInstanceSlot, error)
@@ -42,6 +45,7 @@ type JourneyRepository interface {
 	
 	// Events
 	LogNodeEvent(ctx context.Context, instanceID, eventType, actorID string, payload map[string]any) error
+	EnqueueOutbox(ctx context.Context, msg *models.OutboxMessage) error
 	
 	// Slots/Attachments
 	CreateSlot(ctx context.Context, instanceID, slotKey, tenantID string, required bool, multiplicity string, mime []string) (string, error)
@@ -198,6 +202,39 @@ func (r *SQLJourneyRepository) GetAllowedTransitionRoles(ctx context.Context, fr
 	return []string(roles), nil
 }
 
+// ListVersionInstances returns every node instance of the tenant pinned to a playbook version
+func (r *SQLJourneyRepository) ListVersionInstances(ctx context.Context, tenantID, versionID string) ([]models.NodeInstance, error) {
+	var insts []models.NodeInstance
+	err := sqlx.SelectContext(ctx, r.q(), &insts, `SELECT * FROM node_instances WHERE tenant_id=$1 AND playbook_version_id=$2 ORDER BY user_id, node_id`, tenantID, versionID)
+	return insts, err
+}
+
+// RemapNodeInstance moves an instance to another playbook version and node id
+func (r *SQLJourneyRepository) RemapNodeInstance(ctx context.Context, instanceID, nodeID, versionID string) error {
+	res, err := r.q().ExecContext(ctx, `UPDATE node_instances SET node_id=$1, playbook_version_id=$2, updated_at=now() WHERE id=$3`, nodeID, versionID, instanceID)
+	if err != nil {
+		return err
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RenameJourneyStateNode moves a student's journey state to a renamed node.
+// An existing state for the new node id wins over the old one.
+func (r *SQLJourneyRepository) RenameJourneyStateNode(ctx context.Context, userID, tenantID, fromNodeID, toNodeID string) error {
+	_, err := r.q().ExecContext(ctx, `UPDATE journey_states SET node_id=$3, updated_at=now()
+		WHERE user_id=$1 AND tenant_id=$4 AND node_id=$2
+		AND NOT EXISTS (SELECT 1 FROM journey_states WHERE user_id=$1 AND node_id=$3)`, userID, fromNodeID, toNodeID, tenantID)
+	if err != nil {
+		return err
+	}
+	_, err = r.q().ExecContext(ctx, `DELETE FROM journey_states WHERE user_id=$1 AND tenant_id=$2 AND node_id=$3`, userID, tenantID, fromNodeID)
+	return err
+}
+
 func (r *SQLJourneyRepository) GetNodeInstanceSlots(ctx context.Context, instanceID string) ([]models.NodeInstanceSlot, error) {
 	var slots []models.NodeInstanceSlot
 	err := sqlx.SelectContext(ctx, r.q(), &slots, `SELECT * FROM node_instance_slots WHERE node_instance_id=$1`, instanceID)
@@ -356,6 +393,12 @@ func (r *SQLJourneyRepository) LogNodeEvent(ctx context.Context, instanceID, eve
 	return err
 }
 
+// EnqueueOutbox records a message for the outbox dispatcher. Inside WithTx it
+// is committed, or rolled back, together with the rest of the transaction.
+func (r *SQLJourneyRepository) EnqueueOutbox(ctx context.Context, msg *models.OutboxMessage) error {
+	return insertOutbox(ctx, r.q(), msg)
+}
+
 func (r *SQLJourneyRepository) CreateSlot(ctx context.Context, instanceID, slotKey, tenantID string, required bool, multiplicity string, mime []string) (string, error) {
 	var id string
 	mimeArr := pq.Array(mime)
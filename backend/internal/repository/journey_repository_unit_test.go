@@ -112,3 +112,53 @@ func TestSQLJourneyRepository_WithTx_Unit(t *testing.T) {
 		assert.Equal(t, sql.ErrConnDone, err)
 	})
 }
+
+func TestSQLJourneyRepository_PlaybookMigration_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLJourneyRepository(sqlxDB)
+	ctx := context.Background()
+
+	t.Run("ListVersionInstances", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \* FROM node_instances WHERE tenant_id=\$1 AND playbook_version_id=\$2`).
+			WithArgs("t1", "v1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "node_id", "state"}).
+				AddRow("i1", "u1", "S1", "done").
+				AddRow("i2", "u1", "S2", "active"))
+
+		insts, err := repo.ListVersionInstances(ctx, "t1", "v1")
+		assert.NoError(t, err)
+		if assert.Len(t, insts, 2) {
+			assert.Equal(t, "S2", insts[1].NodeID)
+		}
+	})
+
+	t.Run("RemapNodeInstance", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE node_instances SET node_id=\$1, playbook_version_id=\$2`).
+			WithArgs("S3", "v2", "i1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.RemapNodeInstance(ctx, "i1", "S3", "v2"))
+
+		mock.ExpectExec(`UPDATE node_instances`).
+			WithArgs("S3", "v2", "missing").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.RemapNodeInstance(ctx, "missing", "S3", "v2"), sql.ErrNoRows)
+	})
+
+	t.Run("RenameJourneyStateNode", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE journey_states SET node_id=\$3`).
+			WithArgs("u1", "S2", "S3", "t1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM journey_states WHERE user_id=\$1 AND tenant_id=\$2 AND node_id=\$3`).
+			WithArgs("u1", "t1", "S2").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, repo.RenameJourneyStateNode(ctx, "u1", "t1", "S2", "S3"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// MFARepository stores users' TOTP secrets and recovery codes, and the
+// tenants' two-factor policy under the "mfa" key of tenants.settings.
+type MFARepository interface {
+	GetTOTP(ctx context.Context, userID string) (*models.UserTOTP, error)
+	// SavePendingTOTP starts an enrollment, replacing an unconfirmed one.
+	// It returns ErrNotFound when the user's 2FA is already enabled.
+	SavePendingTOTP(ctx context.Context, userID, secret string) error
+	// EnableTOTP confirms a pending enrollment with the step of its first
+	// code and replaces the recovery codes.
+	EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error
+	// UseTOTPStep records the step of an accepted code, provided it is
+	// later than the last one, so each code works once.
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	// UseRecoveryCode marks an unused recovery code as used.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	// DeleteTOTP removes the user's secret and recovery codes.
+	DeleteTOTP(ctx context.Context, userID string) error
+	// GetTenantPolicy returns the tenant's policy, the zero one if none
+	// was set.
+	GetTenantPolicy(ctx context.Context, tenantID string) (*models.TenantMFAPolicy, error)
+	SaveTenantPolicy(ctx context.Context, tenantID string, policy *models.TenantMFAPolicy) error
+}
+
+type SQLMFARepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLMFARepository(db *sqlx.DB) *SQLMFARepository {
+	return &SQLMFARepository{db: db}
+}
+
+func (r *SQLMFARepository) GetTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	var t models.UserTOTP
+	err := r.db.GetContext(ctx, &t, `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at
+		FROM user_totp WHERE user_id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *SQLMFARepository) SavePendingTOTP(ctx context.Context, userID, secret string) error {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+		WHERE user_totp.enabled_at IS NULL`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLMFARepository) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_totp SET enabled_at = now(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL`, userID, step)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLMFARepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLMFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLMFARepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID)
+	return n, err
+}
+
+func (r *SQLMFARepository) DeleteTOTP(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLMFARepository) GetTenantPolicy(ctx context.Context, tenantID string) (*models.TenantMFAPolicy, error) {
+	var raw []byte
+	err := r.db.QueryRowxContext(ctx, `SELECT settings->'mfa' FROM tenants WHERE id = $1`, tenantID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy := &models.TenantMFAPolicy{}
+	if raw == nil {
+		return policy, nil
+	}
+	if err := json.Unmarshal(raw, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (r *SQLMFARepository) SaveTenantPolicy(ctx context.Context, tenantID string, policy *models.TenantMFAPolicy) error {
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tenants
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{mfa}', $2::jsonb), updated_at = now()
+		WHERE id = $1`, tenantID, string(raw))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
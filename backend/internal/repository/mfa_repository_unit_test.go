@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLMFARepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLMFARepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("GetTOTP", func(t *testing.T) {
+		mock.ExpectQuery(`FROM user_totp WHERE user_id = \$1`).WithArgs("u1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "enabled_at", "last_used_step", "created_at"}).AddRow("u1", "ABC", now, 42, now))
+		totp, err := repo.GetTOTP(ctx, "u1")
+		require.NoError(t, err)
+		assert.True(t, totp.Enabled())
+		assert.Equal(t, int64(42), totp.LastUsedStep)
+
+		mock.ExpectQuery(`FROM user_totp`).WithArgs("u2").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+		_, err = repo.GetTOTP(ctx, "u2")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("SavePendingTOTP keeps an enabled secret", func(t *testing.T) {
+		mock.ExpectExec(`ON CONFLICT \(user_id\) DO UPDATE.*WHERE user_totp.enabled_at IS NULL`).
+			WithArgs("u1", "ABC").WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.SavePendingTOTP(ctx, "u1", "ABC"), ErrNotFound)
+	})
+
+	t.Run("EnableTOTP replaces recovery codes", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE user_totp SET enabled_at = now\(\), last_used_step = \$2\s+WHERE user_id = \$1 AND enabled_at IS NULL`).
+			WithArgs("u1", int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM user_recovery_codes WHERE user_id = \$1`).WithArgs("u1").WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`INSERT INTO user_recovery_codes`).WithArgs("u1", "h1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO user_recovery_codes`).WithArgs("u1", "h2").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		require.NoError(t, repo.EnableTOTP(ctx, "u1", 7, []string{"h1", "h2"}))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE user_totp`).WithArgs("u1", int64(7)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		assert.ErrorIs(t, repo.EnableTOTP(ctx, "u1", 7, nil), ErrNotFound, "nothing pending")
+	})
+
+	t.Run("Codes work once", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE user_totp SET last_used_step = \$2\s+WHERE user_id = \$1 AND enabled_at IS NOT NULL AND last_used_step < \$2`).
+			WithArgs("u1", int64(7)).WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.UseTOTPStep(ctx, "u1", 7), ErrNotFound)
+
+		mock.ExpectExec(`UPDATE user_recovery_codes SET used_at = now\(\)\s+WHERE user_id = \$1 AND code_hash = \$2 AND used_at IS NULL`).
+			WithArgs("u1", "h1").WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, repo.UseRecoveryCode(ctx, "u1", "h1"))
+	})
+
+	t.Run("Tenant policy", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT settings->'mfa' FROM tenants WHERE id = \$1`).WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"mfa"}).AddRow(nil))
+		policy, err := repo.GetTenantPolicy(ctx, "t1")
+		require.NoError(t, err)
+		assert.False(t, policy.RequireForAdmins, "unset means not required")
+
+		mock.ExpectQuery(`SELECT settings->'mfa'`).WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"mfa"}).AddRow([]byte(`{"require_for_admins":true}`)))
+		policy, err = repo.GetTenantPolicy(ctx, "t1")
+		require.NoError(t, err)
+		assert.True(t, policy.RequireForAdmins)
+
+		mock.ExpectExec(`SET settings = jsonb_set\(COALESCE\(settings, '\{\}'::jsonb\), '\{mfa\}', \$2::jsonb\)`).
+			WithArgs("t1", `{"require_for_admins":true}`).WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, repo.SaveTenantPolicy(ctx, "t1", &models.TenantMFAPolicy{RequireForAdmins: true}))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
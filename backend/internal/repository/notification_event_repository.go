@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// NotificationEventRepository stores the append-only log that backs the
+// live notification stream.
+type NotificationEventRepository interface {
+	Append(ctx context.Context, stream, eventType string, payload json.RawMessage) (*models.NotificationEvent, error)
+	// ListSince returns events of the given streams with id > afterID, oldest first.
+	ListSince(ctx context.Context, streams []string, afterID int64, limit int) ([]models.NotificationEvent, error)
+	// ListRecent returns events of the given streams with id <= maxID that
+	// were stored within the last window, oldest first.
+	ListRecent(ctx context.Context, streams []string, window time.Duration, maxID int64, limit int) ([]models.NotificationEvent, error)
+	// Bounds returns the oldest and newest retained event ids (0, 0 when empty).
+	Bounds(ctx context.Context) (oldest, latest int64, err error)
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+type SQLNotificationEventRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLNotificationEventRepository(db *sqlx.DB) *SQLNotificationEventRepository {
+	return &SQLNotificationEventRepository{db: db}
+}
+
+func (r *SQLNotificationEventRepository) Append(ctx context.Context, stream, eventType string, payload json.RawMessage) (*models.NotificationEvent, error) {
+	var ev models.NotificationEvent
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO notification_events (stream, type, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id, stream, type, payload, created_at`,
+		stream, eventType, []byte(payload)).StructScan(&ev)
+	if err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+func (r *SQLNotificationEventRepository) ListSince(ctx context.Context, streams []string, afterID int64, limit int) ([]models.NotificationEvent, error) {
+	var events []models.NotificationEvent
+	err := r.db.SelectContext(ctx, &events, `
+		SELECT id, stream, type, payload, created_at
+		FROM notification_events
+		WHERE stream = ANY($1) AND id > $2
+		ORDER BY id
+		LIMIT $3`,
+		pq.Array(streams), afterID, limit)
+	return events, err
+}
+
+func (r *SQLNotificationEventRepository) ListRecent(ctx context.Context, streams []string, window time.Duration, maxID int64, limit int) ([]models.NotificationEvent, error) {
+	var events []models.NotificationEvent
+	err := r.db.SelectContext(ctx, &events, `
+		SELECT id, stream, type, payload, created_at
+		FROM notification_events
+		WHERE stream = ANY($1) AND id <= $2 AND created_at >= now() - $3 * interval '1 millisecond'
+		ORDER BY id
+		LIMIT $4`,
+		pq.Array(streams), maxID, window.Milliseconds(), limit)
+	return events, err
+}
+
+func (r *SQLNotificationEventRepository) Bounds(ctx context.Context) (int64, int64, error) {
+	var b struct {
+		Oldest int64 `db:"oldest"`
+		Latest int64 `db:"latest"`
+	}
+	err := r.db.GetContext(ctx, &b, `SELECT COALESCE(MIN(id), 0) AS oldest, COALESCE(MAX(id), 0) AS latest FROM notification_events`)
+	return b.Oldest, b.Latest, err
+}
+
+func (r *SQLNotificationEventRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM notification_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
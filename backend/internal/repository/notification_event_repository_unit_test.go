@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLNotificationEventRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSQLNotificationEventRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+	now := time.Now()
+	cols := []string{"id", "stream", "type", "payload", "created_at"}
+
+	t.Run("Append", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO notification_events \(stream, type, payload\)`).
+			WithArgs("user:u1", "notification.read", []byte(`{"id":"n1"}`)).
+			WillReturnRows(sqlmock.NewRows(cols).AddRow(42, "user:u1", "notification.read", []byte(`{"id":"n1"}`), now))
+		ev, err := repo.Append(ctx, "user:u1", "notification.read", json.RawMessage(`{"id":"n1"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), ev.ID)
+	})
+
+	t.Run("ListSince", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE stream = ANY\(\$1\) AND id > \$2\s+ORDER BY id\s+LIMIT \$3`).
+			WithArgs(pq.Array([]string{"user:u1", "admin"}), int64(40), 500).
+			WillReturnRows(sqlmock.NewRows(cols).AddRow(42, "user:u1", "notification.read", []byte(`{}`), now))
+		events, err := repo.ListSince(ctx, []string{"user:u1", "admin"}, 40, 500)
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("ListRecent", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE stream = ANY\(\$1\) AND id <= \$2 AND created_at >= now\(\) - \$3 \* interval '1 millisecond'\s+ORDER BY id\s+LIMIT \$4`).
+			WithArgs(pq.Array([]string{"user:u1"}), int64(42), int64(60000), 500).
+			WillReturnRows(sqlmock.NewRows(cols).AddRow(41, "user:u1", "notification.created", []byte(`{}`), now))
+		events, err := repo.ListRecent(ctx, []string{"user:u1"}, time.Minute, 42, 500)
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COALESCE\(MIN\(id\), 0\) AS oldest, COALESCE\(MAX\(id\), 0\) AS latest`).
+			WillReturnRows(sqlmock.NewRows([]string{"oldest", "latest"}).AddRow(10, 42))
+		oldest, latest, err := repo.Bounds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), oldest)
+		assert.Equal(t, int64(42), latest)
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM notification_events WHERE created_at < \$1`).
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		n, err := repo.Prune(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -11,6 +11,7 @@ import (
 type NotificationRepository interface {
 	Create(ctx context.Context, notif *models.Notification) error
 	GetUnread(ctx context.Context, userID string) ([]models.Notification, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
 	MarkAsRead(ctx context.Context, notificationID, userID string) error
 	MarkAllAsRead(ctx context.Context, userID string) error
 	ListByRecipient(ctx context.Context, userID string, limit int) ([]models.Notification, error)
@@ -55,6 +56,12 @@ func (r *SQLNotificationRepository) GetUnread(ctx context.Context, userID string
 	return notifs, nil
 }
 
+func (r *SQLNotificationRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM notifications WHERE recipient_id = $1 AND is_read = FALSE`, userID)
+	return count, err
+}
+
 func (r *SQLNotificationRepository) MarkAsRead(ctx context.Context, notificationID, userID string) error {
 	query := `UPDATE notifications SET is_read = TRUE WHERE id = $1 AND recipient_id = $2`
 	res, err := r.db.ExecContext(ctx, query, notificationID, userID)
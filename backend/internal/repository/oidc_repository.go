@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// OIDCRepository stores tenants' OpenID Connect providers and pending SSO
+// logins.
+type OIDCRepository interface {
+	GetConfig(ctx context.Context, tenantID string) (*models.TenantOIDCConfig, error)
+	// SaveConfig creates or replaces a tenant's provider. An empty client
+	// secret keeps the stored one.
+	SaveConfig(ctx context.Context, cfg *models.TenantOIDCConfig) error
+	DeleteConfig(ctx context.Context, tenantID string) error
+	// CreateState stores a pending login and drops expired ones.
+	CreateState(ctx context.Context, st *models.OIDCLoginState) error
+	// ConsumeState removes and returns an unexpired pending login, so each
+	// can be completed once.
+	ConsumeState(ctx context.Context, state string) (*models.OIDCLoginState, error)
+}
+
+type SQLOIDCRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLOIDCRepository(db *sqlx.DB) *SQLOIDCRepository {
+	return &SQLOIDCRepository{db: db}
+}
+
+func (r *SQLOIDCRepository) GetConfig(ctx context.Context, tenantID string) (*models.TenantOIDCConfig, error) {
+	var cfg models.TenantOIDCConfig
+	err := r.db.GetContext(ctx, &cfg, `
+		SELECT tenant_id, issuer, client_id, client_secret, scopes, enabled,
+			jit_provisioning, allowed_domains, created_at, updated_at
+		FROM tenant_oidc_configs
+		WHERE tenant_id = $1`, tenantID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.ClientSecretSet = cfg.ClientSecret != ""
+	return &cfg, nil
+}
+
+func (r *SQLOIDCRepository) SaveConfig(ctx context.Context, cfg *models.TenantOIDCConfig) error {
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO tenant_oidc_configs (tenant_id, issuer, client_id, client_secret, scopes, enabled,
+			jit_provisioning, allowed_domains)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			issuer = EXCLUDED.issuer,
+			client_id = EXCLUDED.client_id,
+			client_secret = COALESCE(NULLIF(EXCLUDED.client_secret, ''), tenant_oidc_configs.client_secret),
+			scopes = EXCLUDED.scopes,
+			enabled = EXCLUDED.enabled,
+			jit_provisioning = EXCLUDED.jit_provisioning,
+			allowed_domains = EXCLUDED.allowed_domains,
+			updated_at = now()
+		RETURNING client_secret <> '', created_at, updated_at`,
+		cfg.TenantID, cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.Scopes, cfg.Enabled,
+		cfg.JITProvisioning, cfg.AllowedDomains,
+	).Scan(&cfg.ClientSecretSet, &cfg.CreatedAt, &cfg.UpdatedAt)
+	return err
+}
+
+func (r *SQLOIDCRepository) DeleteConfig(ctx context.Context, tenantID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tenant_oidc_configs WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLOIDCRepository) CreateState(ctx context.Context, st *models.OIDCLoginState) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH expired AS (DELETE FROM oidc_login_states WHERE expires_at < now())
+		INSERT INTO oidc_login_states (state, tenant_id, nonce, code_verifier, redirect_path, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		st.State, st.TenantID, st.Nonce, st.CodeVerifier, st.RedirectPath, st.ExpiresAt)
+	return err
+}
+
+func (r *SQLOIDCRepository) ConsumeState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	var st models.OIDCLoginState
+	err := r.db.GetContext(ctx, &st, `
+		DELETE FROM oidc_login_states
+		WHERE state = $1
+		RETURNING state, tenant_id, nonce, code_verifier, redirect_path, expires_at`, state)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if st.ExpiresAt.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &st, nil
+}
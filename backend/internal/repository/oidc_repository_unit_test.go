@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLOIDCRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLOIDCRepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("GetConfig", func(t *testing.T) {
+		mock.ExpectQuery(`FROM tenant_oidc_configs\s+WHERE tenant_id = \$1`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "issuer", "client_id", "client_secret", "scopes", "enabled", "jit_provisioning", "allowed_domains", "created_at", "updated_at"}).
+				AddRow("t1", "https://idp.example", "portal", "s3cret", "{openid,email}", true, true, "{example.edu}", now, now))
+
+		cfg, err := repo.GetConfig(ctx, "t1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"openid", "email"}, []string(cfg.Scopes))
+		assert.Equal(t, []string{"example.edu"}, []string(cfg.AllowedDomains))
+		assert.True(t, cfg.ClientSecretSet)
+
+		mock.ExpectQuery(`FROM tenant_oidc_configs`).WithArgs("t2").
+			WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}))
+		_, err = repo.GetConfig(ctx, "t2")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("SaveConfig keeps the stored secret", func(t *testing.T) {
+		cfg := &models.TenantOIDCConfig{
+			TenantID: "t1", Issuer: "https://idp.example", ClientID: "portal",
+			Scopes: pq.StringArray{"openid"}, Enabled: true, AllowedDomains: pq.StringArray{},
+		}
+		mock.ExpectQuery(`INSERT INTO tenant_oidc_configs .* ON CONFLICT \(tenant_id\) DO UPDATE SET .*client_secret = COALESCE\(NULLIF\(EXCLUDED.client_secret, ''\), tenant_oidc_configs.client_secret\)`).
+			WithArgs("t1", "https://idp.example", "portal", "", cfg.Scopes, true, false, cfg.AllowedDomains).
+			WillReturnRows(sqlmock.NewRows([]string{"secret_set", "created_at", "updated_at"}).AddRow(true, now, now))
+
+		require.NoError(t, repo.SaveConfig(ctx, cfg))
+		assert.True(t, cfg.ClientSecretSet)
+		assert.Equal(t, now, cfg.UpdatedAt)
+	})
+
+	t.Run("ConsumeState", func(t *testing.T) {
+		cols := []string{"state", "tenant_id", "nonce", "code_verifier", "redirect_path", "expires_at"}
+		mock.ExpectQuery(`DELETE FROM oidc_login_states\s+WHERE state = \$1\s+RETURNING`).
+			WithArgs("st-1").
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("st-1", "t1", "n", "v", "/journey", time.Now().Add(time.Minute)))
+		st, err := repo.ConsumeState(ctx, "st-1")
+		require.NoError(t, err)
+		assert.Equal(t, "/journey", st.RedirectPath)
+
+		mock.ExpectQuery(`DELETE FROM oidc_login_states`).
+			WithArgs("st-2").
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("st-2", "t1", "n", "v", "/", time.Now().Add(-time.Minute)))
+		_, err = repo.ConsumeState(ctx, "st-2")
+		assert.ErrorIs(t, err, ErrNotFound, "expired logins cannot be completed")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
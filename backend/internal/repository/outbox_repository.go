@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxRepository stores and hands out outbox messages. Messages that must
+// be atomic with a journey change are enqueued through JourneyRepository
+// inside its transaction instead.
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, msg *models.OutboxMessage) error
+
+	// Dispatcher
+	Claim(ctx context.Context, limit int, lockFor time.Duration) ([]models.OutboxMessage, error)
+	MarkSent(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time, dead bool) error
+
+	// Admin
+	List(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error)
+	Get(ctx context.Context, tenantID, id string) (*models.OutboxMessage, error)
+	Replay(ctx context.Context, tenantID, id string) (bool, error)
+	ReplayDead(ctx context.Context, tenantID, kind string) (int64, error)
+}
+
+type SQLOutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLOutboxRepository(db *sqlx.DB) *SQLOutboxRepository {
+	return &SQLOutboxRepository{db: db}
+}
+
+const outboxColumns = `id, tenant_id, kind, payload, status, attempts, max_attempts, next_attempt_at,
+	locked_until, last_error, created_at, updated_at, sent_at`
+
+// insertOutbox writes msg through q, so that it joins the caller's transaction.
+func insertOutbox(ctx context.Context, q sqlx.ExtContext, msg *models.OutboxMessage) error {
+	maxAttempts := msg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	return sqlx.GetContext(ctx, q, msg, `
+		INSERT INTO outbox_messages (tenant_id, kind, payload, max_attempts)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+outboxColumns,
+		msg.TenantID, msg.Kind, []byte(msg.Payload), maxAttempts)
+}
+
+func (r *SQLOutboxRepository) Enqueue(ctx context.Context, msg *models.OutboxMessage) error {
+	return insertOutbox(ctx, r.db, msg)
+}
+
+// Claim locks up to limit due messages for lockFor. Messages whose previous
+// claim expired (the dispatcher died mid-delivery) are claimed again.
+func (r *SQLOutboxRepository) Claim(ctx context.Context, limit int, lockFor time.Duration) ([]models.OutboxMessage, error) {
+	var out []models.OutboxMessage
+	err := r.db.SelectContext(ctx, &out, `
+		UPDATE outbox_messages
+		SET status='processing', locked_until=now() + $2 * interval '1 millisecond', updated_at=now()
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE (status='pending' AND next_attempt_at <= now())
+			   OR (status='processing' AND locked_until < now())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+outboxColumns, limit, lockFor.Milliseconds())
+	return out, err
+}
+
+func (r *SQLOutboxRepository) MarkSent(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_messages
+		SET status='sent', attempts=attempts+1, sent_at=now(), locked_until=NULL, last_error=NULL, updated_at=now()
+		WHERE id=$1`, id)
+	return err
+}
+
+// MarkFailed records a failed attempt and either schedules the next one or,
+// when dead is set, moves the message to the dead letters.
+func (r *SQLOutboxRepository) MarkFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := models.OutboxStatusPending
+	if dead {
+		status = models.OutboxStatusDead
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_messages
+		SET status=$2, attempts=attempts+1, last_error=$3, next_attempt_at=$4, locked_until=NULL, updated_at=now()
+		WHERE id=$1`, id, status, lastError, nextAttemptAt)
+	return err
+}
+
+func (r *SQLOutboxRepository) List(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error) {
+	var conds []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	add("tenant_id=$%d", filter.TenantID)
+	if filter.Status != "" {
+		add("status=$%d", filter.Status)
+	}
+	if filter.Kind != "" {
+		add("kind=$%d", filter.Kind)
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	args = append(args, limit, filter.Offset)
+
+	var out []models.OutboxMessage
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT `+outboxColumns+`
+		FROM outbox_messages
+		WHERE `+strings.Join(conds, " AND ")+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	return out, err
+}
+
+func (r *SQLOutboxRepository) Get(ctx context.Context, tenantID, id string) (*models.OutboxMessage, error) {
+	var msg models.OutboxMessage
+	err := r.db.GetContext(ctx, &msg, `SELECT `+outboxColumns+` FROM outbox_messages WHERE id=$1 AND tenant_id=$2`, id, tenantID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Replay puts a dead message back in the queue with a fresh set of attempts.
+// It reports false when the message does not exist or is not dead.
+func (r *SQLOutboxRepository) Replay(ctx context.Context, tenantID, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_messages
+		SET status='pending', attempts=0, next_attempt_at=now(), updated_at=now()
+		WHERE id=$1 AND tenant_id=$2 AND status='dead'`, id, tenantID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ReplayDead requeues every dead message of the tenant, optionally of one kind.
+func (r *SQLOutboxRepository) ReplayDead(ctx context.Context, tenantID, kind string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_messages
+		SET status='pending', attempts=0, next_attempt_at=now(), updated_at=now()
+		WHERE tenant_id=$1 AND status='dead' AND ($2='' OR kind=$2)`, tenantID, kind)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var outboxRowColumns = []string{"id", "tenant_id", "kind", "payload", "status", "attempts", "max_attempts", "next_attempt_at",
+	"locked_until", "last_error", "created_at", "updated_at", "sent_at"}
+
+func TestSQLOutboxRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLOutboxRepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tenant := "t1"
+
+	t.Run("Enqueue", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO outbox_messages \(tenant_id, kind, payload, max_attempts\)`).
+			WithArgs(&tenant, "email", []byte(`{"to":"a@b.c"}`), 8).
+			WillReturnRows(sqlmock.NewRows(outboxRowColumns).
+				AddRow("m1", "t1", "email", []byte(`{"to":"a@b.c"}`), "pending", 0, 8, now, nil, nil, now, now, nil))
+		msg := &models.OutboxMessage{TenantID: &tenant, Kind: "email", Payload: []byte(`{"to":"a@b.c"}`)}
+		assert.NoError(t, repo.Enqueue(ctx, msg))
+		assert.Equal(t, "m1", msg.ID)
+		assert.Equal(t, "pending", msg.Status)
+	})
+
+	t.Run("Claim", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE outbox_messages\s+SET status='processing'.*FOR UPDATE SKIP LOCKED`).
+			WithArgs(10, int64(60000)).
+			WillReturnRows(sqlmock.NewRows(outboxRowColumns).
+				AddRow("m1", "t1", "email", []byte(`{}`), "processing", 0, 8, now, now, nil, now, now, nil))
+		msgs, err := repo.Claim(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Len(t, msgs, 1)
+	})
+
+	t.Run("MarkFailed dead", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE outbox_messages\s+SET status=\$2, attempts=attempts\+1`).
+			WithArgs("m1", "dead", "smtp down", now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.MarkFailed(ctx, "m1", "smtp down", now, true))
+	})
+
+	t.Run("List filters", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE tenant_id=\$1 AND status=\$2 AND kind=\$3\s+ORDER BY created_at DESC, id\s+LIMIT \$4 OFFSET \$5`).
+			WithArgs("t1", "dead", "email", 50, 0).
+			WillReturnRows(sqlmock.NewRows(outboxRowColumns))
+		_, err := repo.List(ctx, models.OutboxFilter{TenantID: "t1", Status: "dead", Kind: "email"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Replay only dead", func(t *testing.T) {
+		mock.ExpectExec(`SET status='pending', attempts=0.*WHERE id=\$1 AND tenant_id=\$2 AND status='dead'`).
+			WithArgs("m1", "t1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		ok, err := repo.Replay(ctx, "t1", "m1")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Journey EnqueueOutbox joins the transaction", func(t *testing.T) {
+		journey := NewSQLJourneyRepository(sqlxDB)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE node_instances SET state=\$1`).
+			WithArgs("submitted", "i1", "active").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO outbox_messages`).
+			WillReturnRows(sqlmock.NewRows(outboxRowColumns).
+				AddRow("m2", "t1", "advisor_submission", []byte(`{}`), "pending", 0, 8, now, nil, nil, now, now, nil))
+		mock.ExpectCommit()
+
+		err := journey.WithTx(ctx, func(tx JourneyRepository) error {
+			if err := tx.UpdateNodeInstanceState(ctx, "i1", "active", "submitted"); err != nil {
+				return err
+			}
+			return tx.EnqueueOutbox(ctx, &models.OutboxMessage{TenantID: &tenant, Kind: "advisor_submission", Payload: []byte(`{}`)})
+		})
+		assert.NoError(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type PlaybookRepository interface {
+	ListVersions(ctx context.Context, tenantID string) ([]models.PlaybookVersion, error)
+	GetVersion(ctx context.Context, versionID string) (*models.PlaybookVersion, error)
+	GetVersionJSON(ctx context.Context, versionID string) ([]byte, error)
+	GetVersionByChecksum(ctx context.Context, tenantID, checksum string) (*models.PlaybookVersion, error)
+	CreateVersion(ctx context.Context, v *models.PlaybookVersion) (string, error)
+	GetActiveVersionID(ctx context.Context, tenantID string) (string, error)
+	ListActivations(ctx context.Context, tenantID string) ([]models.PlaybookActivation, error)
+	ActivateVersion(ctx context.Context, tenantID, versionID, actorID string) error
+	RollbackActivation(ctx context.Context, tenantID string) (string, error)
+}
+
+type SQLPlaybookRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLPlaybookRepository(db *sqlx.DB) *SQLPlaybookRepository {
+	return &SQLPlaybookRepository{db: db}
+}
+
+// ListVersions returns the tenant's versions, newest first, without their raw JSON
+func (r *SQLPlaybookRepository) ListVersions(ctx context.Context, tenantID string) ([]models.PlaybookVersion, error) {
+	var versions []models.PlaybookVersion
+	err := r.db.SelectContext(ctx, &versions, `
+		SELECT pv.id, pv.tenant_id, pv.version, pv.checksum, pv.created_by, pv.created_at,
+		       (pav.playbook_version_id IS NOT NULL) AS is_active
+		FROM playbook_versions pv
+		LEFT JOIN playbook_active_version pav ON pav.playbook_version_id = pv.id AND pav.tenant_id = pv.tenant_id
+		WHERE pv.tenant_id = $1
+		ORDER BY pv.created_at DESC`, tenantID)
+	return versions, err
+}
+
+// GetVersion returns a version with its raw JSON, or nil when it does not exist
+func (r *SQLPlaybookRepository) GetVersion(ctx context.Context, versionID string) (*models.PlaybookVersion, error) {
+	var v models.PlaybookVersion
+	err := r.db.GetContext(ctx, &v, `
+		SELECT pv.id, pv.tenant_id, pv.version, pv.checksum, pv.raw_json, pv.created_by, pv.created_at,
+		       (pav.playbook_version_id IS NOT NULL) AS is_active
+		FROM playbook_versions pv
+		LEFT JOIN playbook_active_version pav ON pav.playbook_version_id = pv.id AND pav.tenant_id = pv.tenant_id
+		WHERE pv.id = $1`, versionID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *SQLPlaybookRepository) GetVersionJSON(ctx context.Context, versionID string) ([]byte, error) {
+	var raw []byte
+	err := r.db.QueryRowxContext(ctx, `SELECT raw_json FROM playbook_versions WHERE id = $1`, versionID).Scan(&raw)
+	return raw, err
+}
+
+func (r *SQLPlaybookRepository) GetVersionByChecksum(ctx context.Context, tenantID, checksum string) (*models.PlaybookVersion, error) {
+	var v models.PlaybookVersion
+	err := r.db.GetContext(ctx, &v, `
+		SELECT id, tenant_id, version, checksum, created_by, created_at
+		FROM playbook_versions WHERE tenant_id = $1 AND checksum = $2`, tenantID, checksum)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *SQLPlaybookRepository) CreateVersion(ctx context.Context, v *models.PlaybookVersion) (string, error) {
+	var id string
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO playbook_versions (tenant_id, version, checksum, raw_json, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		v.TenantID, v.Version, v.Checksum, []byte(v.RawJSON), v.CreatedBy).Scan(&id)
+	return id, err
+}
+
+// GetActiveVersionID returns the tenant's active version, or sql.ErrNoRows when none is set
+func (r *SQLPlaybookRepository) GetActiveVersionID(ctx context.Context, tenantID string) (string, error) {
+	var id string
+	err := r.db.QueryRowxContext(ctx, `SELECT playbook_version_id FROM playbook_active_version WHERE tenant_id = $1`, tenantID).Scan(&id)
+	return id, err
+}
+
+func (r *SQLPlaybookRepository) ListActivations(ctx context.Context, tenantID string) ([]models.PlaybookActivation, error) {
+	var acts []models.PlaybookActivation
+	err := r.db.SelectContext(ctx, &acts, `
+		SELECT a.id, a.tenant_id, a.playbook_version_id, pv.version, a.activated_by, a.activated_at, a.rolled_back_at
+		FROM playbook_activations a
+		JOIN playbook_versions pv ON pv.id = a.playbook_version_id
+		WHERE a.tenant_id = $1
+		ORDER BY a.activated_at DESC`, tenantID)
+	return acts, err
+}
+
+// ActivateVersion makes the version active for the tenant and records it in the activation history
+func (r *SQLPlaybookRepository) ActivateVersion(ctx context.Context, tenantID, versionID, actorID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setActivePlaybookVersion(ctx, tx, tenantID, versionID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO playbook_activations (tenant_id, playbook_version_id, activated_by)
+		VALUES ($1, $2, NULLIF($3, '')::uuid)`, tenantID, versionID, actorID)
+	if err != nil {
+		return fmt.Errorf("record activation: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RollbackActivation undoes the latest activation and re-activates the version
+// that was active before it. It returns the re-activated version, or
+// sql.ErrNoRows when there is nothing to roll back to.
+func (r *SQLPlaybookRepository) RollbackActivation(ctx context.Context, tenantID string) (string, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var current struct {
+		ID        string `db:"id"`
+		VersionID string `db:"playbook_version_id"`
+	}
+	err = tx.GetContext(ctx, &current, `
+		SELECT id, playbook_version_id FROM playbook_activations
+		WHERE tenant_id = $1 AND rolled_back_at IS NULL
+		ORDER BY activated_at DESC LIMIT 1
+		FOR UPDATE`, tenantID)
+	if err != nil {
+		return "", err
+	}
+
+	var previousID string
+	err = tx.QueryRowxContext(ctx, `
+		SELECT playbook_version_id FROM playbook_activations
+		WHERE tenant_id = $1 AND rolled_back_at IS NULL AND id <> $2 AND playbook_version_id <> $3
+		ORDER BY activated_at DESC LIMIT 1`, tenantID, current.ID, current.VersionID).Scan(&previousID)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE playbook_activations SET rolled_back_at = now() WHERE id = $1`, current.ID); err != nil {
+		return "", err
+	}
+	if err := setActivePlaybookVersion(ctx, tx, tenantID, previousID); err != nil {
+		return "", err
+	}
+	return previousID, tx.Commit()
+}
+
+func setActivePlaybookVersion(ctx context.Context, tx *sqlx.Tx, tenantID, versionID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO playbook_active_version (tenant_id, playbook_version_id)
+		VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET playbook_version_id = EXCLUDED.playbook_version_id, updated_at = now()`,
+		tenantID, versionID)
+	if err != nil {
+		return fmt.Errorf("update active playbook: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLPlaybookRepository_ActivateVersion_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewSQLPlaybookRepository(sqlx.NewDb(db, "sqlmock"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO playbook_active_version (.+) ON CONFLICT \(tenant_id\)`).
+		WithArgs("t1", "v2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO playbook_activations`).
+		WithArgs("t1", "v2", "admin1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.ActivateVersion(context.Background(), "t1", "v2", "admin1")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLPlaybookRepository_RollbackActivation_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	repo := NewSQLPlaybookRepository(sqlx.NewDb(db, "sqlmock"))
+
+	t.Run("Restores previous version", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, playbook_version_id FROM playbook_activations (.+) FOR UPDATE`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "playbook_version_id"}).AddRow("act2", "v2"))
+		mock.ExpectQuery(`SELECT playbook_version_id FROM playbook_activations`).
+			WithArgs("t1", "act2", "v2").
+			WillReturnRows(sqlmock.NewRows([]string{"playbook_version_id"}).AddRow("v1"))
+		mock.ExpectExec(`UPDATE playbook_activations SET rolled_back_at = now\(\) WHERE id = \$1`).
+			WithArgs("act2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO playbook_active_version`).
+			WithArgs("t1", "v1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		versionID, err := repo.RollbackActivation(context.Background(), "t1")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", versionID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Nothing to roll back to", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, playbook_version_id FROM playbook_activations`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "playbook_version_id"}).AddRow("act1", "v1"))
+		mock.ExpectQuery(`SELECT playbook_version_id FROM playbook_activations`).
+			WithArgs("t1", "act1", "v1").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.RollbackActivation(context.Background(), "t1")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
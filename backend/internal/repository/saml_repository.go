@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// SAMLRepository stores tenants' SAML identity providers, under the "saml"
+// key of tenants.settings, and pending SAML logins.
+type SAMLRepository interface {
+	GetConfig(ctx context.Context, tenantID string) (*models.TenantSAMLConfig, error)
+	SaveConfig(ctx context.Context, tenantID string, cfg *models.TenantSAMLConfig) error
+	DeleteConfig(ctx context.Context, tenantID string) error
+	// CreateRequest stores a pending login and drops expired ones.
+	CreateRequest(ctx context.Context, req *models.SAMLLoginRequest) error
+	// ConsumeRequest removes and returns an unexpired pending login, so
+	// each AuthnRequest can be answered once.
+	ConsumeRequest(ctx context.Context, id string) (*models.SAMLLoginRequest, error)
+}
+
+type SQLSAMLRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLSAMLRepository(db *sqlx.DB) *SQLSAMLRepository {
+	return &SQLSAMLRepository{db: db}
+}
+
+func (r *SQLSAMLRepository) GetConfig(ctx context.Context, tenantID string) (*models.TenantSAMLConfig, error) {
+	var raw []byte
+	err := r.db.QueryRowxContext(ctx, `SELECT settings->'saml' FROM tenants WHERE id = $1`, tenantID).Scan(&raw)
+	if err == sql.ErrNoRows || (err == nil && raw == nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg models.TenantSAMLConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (r *SQLSAMLRepository) SaveConfig(ctx context.Context, tenantID string, cfg *models.TenantSAMLConfig) error {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tenants
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{saml}', $2::jsonb), updated_at = now()
+		WHERE id = $1`, tenantID, string(raw))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLSAMLRepository) DeleteConfig(ctx context.Context, tenantID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tenants
+		SET settings = settings - 'saml', updated_at = now()
+		WHERE id = $1 AND settings ? 'saml'`, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLSAMLRepository) CreateRequest(ctx context.Context, req *models.SAMLLoginRequest) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH expired AS (DELETE FROM saml_login_requests WHERE expires_at < now())
+		INSERT INTO saml_login_requests (id, tenant_id, redirect_path, expires_at)
+		VALUES ($1, $2, $3, $4)`,
+		req.ID, req.TenantID, req.RedirectPath, req.ExpiresAt)
+	return err
+}
+
+func (r *SQLSAMLRepository) ConsumeRequest(ctx context.Context, id string) (*models.SAMLLoginRequest, error) {
+	var req models.SAMLLoginRequest
+	err := r.db.GetContext(ctx, &req, `
+		DELETE FROM saml_login_requests
+		WHERE id = $1
+		RETURNING id, tenant_id, redirect_path, expires_at`, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt.Before(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &req, nil
+}
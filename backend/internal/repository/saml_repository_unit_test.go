@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLSAMLRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLSAMLRepository(sqlxDB)
+	ctx := context.Background()
+
+	t.Run("GetConfig", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT settings->'saml' FROM tenants WHERE id = \$1`).
+			WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"saml"}).
+				AddRow([]byte(`{"enabled":true,"idp_metadata":"<md/>","role_map":{"faculty":"advisor"}}`)))
+		cfg, err := repo.GetConfig(ctx, "t1")
+		require.NoError(t, err)
+		assert.True(t, cfg.Enabled)
+		assert.Equal(t, "advisor", cfg.RoleMap["faculty"])
+
+		mock.ExpectQuery(`FROM tenants`).WithArgs("t2").
+			WillReturnRows(sqlmock.NewRows([]string{"saml"}).AddRow(nil))
+		_, err = repo.GetConfig(ctx, "t2")
+		assert.ErrorIs(t, err, ErrNotFound, "tenant without a saml key")
+	})
+
+	t.Run("SaveConfig sets only the saml key", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE tenants\s+SET settings = jsonb_set\(COALESCE\(settings, '\{\}'::jsonb\), '\{saml\}', \$2::jsonb\)`).
+			WithArgs("t1", `{"enabled":true,"idp_metadata":"\u003cmd/\u003e","sync_role":false,"jit_provisioning":false,"allowed_domains":["example.edu"]}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, repo.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{Enabled: true, IdPMetadata: "<md/>", AllowedDomains: []string{"example.edu"}}))
+
+		mock.ExpectExec(`UPDATE tenants`).WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.SaveConfig(ctx, "missing", &models.TenantSAMLConfig{}), ErrNotFound)
+	})
+
+	t.Run("DeleteConfig", func(t *testing.T) {
+		mock.ExpectExec(`SET settings = settings - 'saml'.*WHERE id = \$1 AND settings \? 'saml'`).
+			WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.DeleteConfig(ctx, "t1"), ErrNotFound)
+	})
+
+	t.Run("ConsumeRequest", func(t *testing.T) {
+		cols := []string{"id", "tenant_id", "redirect_path", "expires_at"}
+		mock.ExpectQuery(`DELETE FROM saml_login_requests\s+WHERE id = \$1\s+RETURNING`).
+			WithArgs("_r1").
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("_r1", "t1", "/journey", time.Now().Add(time.Minute)))
+		req, err := repo.ConsumeRequest(ctx, "_r1")
+		require.NoError(t, err)
+		assert.Equal(t, "/journey", req.RedirectPath)
+
+		mock.ExpectQuery(`DELETE FROM saml_login_requests`).
+			WithArgs("_r2").
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("_r2", "t1", "/", time.Now().Add(-time.Minute)))
+		_, err = repo.ConsumeRequest(ctx, "_r2")
+		assert.ErrorIs(t, err, ErrNotFound, "expired logins cannot be completed")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// SchedulerRepository backs the background reminder and escalation jobs.
+type SchedulerRepository interface {
+	// Leases
+	AcquireLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, job, holder string) error
+
+	// Work queues
+	ListOpenDeadlines(ctx context.Context, from, to time.Time) ([]models.DueDeadline, error)
+	ListStaleReviews(ctx context.Context, before time.Time) ([]models.StaleReview, error)
+	ListPendingReminders(ctx context.Context, limit int) ([]models.PendingReminder, error)
+	MarkReminderSent(ctx context.Context, id string) error
+
+	// ClaimEscalation records that a tier was sent for subject and anchor. It
+	// returns false when it had already been recorded.
+	ClaimEscalation(ctx context.Context, job, subjectID string, anchor time.Time, tier string) (bool, error)
+
+	// Recipients
+	GetRecipient(ctx context.Context, userID string) (*models.Recipient, error)
+	ListStudentAdvisors(ctx context.Context, studentID string) ([]models.Recipient, error)
+	ListTenantAdmins(ctx context.Context, tenantID string) ([]models.Recipient, error)
+}
+
+type SQLSchedulerRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLSchedulerRepository(db *sqlx.DB) *SQLSchedulerRepository {
+	return &SQLSchedulerRepository{db: db}
+}
+
+// AcquireLease takes or renews the lease on job. It succeeds when nobody holds
+// the lease, the previous lease expired, or holder already owns it.
+func (r *SQLSchedulerRepository) AcquireLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO job_leases (name, holder, expires_at, updated_at)
+		VALUES ($1, $2, now() + $3 * interval '1 millisecond', now())
+		ON CONFLICT (name) DO UPDATE
+		SET holder=EXCLUDED.holder, expires_at=EXCLUDED.expires_at, updated_at=now()
+		WHERE job_leases.holder=EXCLUDED.holder OR job_leases.expires_at < now()`,
+		job, holder, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ReleaseLease gives up the lease if holder still owns it.
+func (r *SQLSchedulerRepository) ReleaseLease(ctx context.Context, job, holder string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM job_leases WHERE name=$1 AND holder=$2`, job, holder)
+	return err
+}
+
+// tenantOr resolves the tenant of a row. Rows written before tenant scoping
+// have none and fall back to the primary tenant of the user in userColumn;
+// the result is empty when that is unknown too.
+func tenantOr(tenantColumn, userColumn string) string {
+	return fmt.Sprintf(`COALESCE(%s::text, (SELECT utm.tenant_id::text FROM user_tenant_memberships utm
+		WHERE utm.user_id = %s ORDER BY utm.is_primary DESC LIMIT 1), '')`, tenantColumn, userColumn)
+}
+
+// ListOpenDeadlines returns deadlines due between from and to whose node the
+// student has not finished.
+func (r *SQLSchedulerRepository) ListOpenDeadlines(ctx context.Context, from, to time.Time) ([]models.DueDeadline, error) {
+	var out []models.DueDeadline
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT d.id, `+tenantOr("d.tenant_id", "d.user_id")+` AS tenant_id,
+		       d.user_id, d.node_id, d.due_at
+		FROM node_deadlines d
+		JOIN users u ON u.id = d.user_id AND u.is_active
+		WHERE d.due_at BETWEEN $1 AND $2
+		  AND NOT EXISTS (
+		    SELECT 1 FROM node_instances ni
+		    WHERE ni.user_id = d.user_id AND ni.node_id = d.node_id AND ni.state = 'done')
+		ORDER BY d.due_at, d.user_id`, from, to)
+	return out, err
+}
+
+// ListStaleReviews returns submissions that have been waiting since before.
+func (r *SQLSchedulerRepository) ListStaleReviews(ctx context.Context, before time.Time) ([]models.StaleReview, error) {
+	var out []models.StaleReview
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT ni.id, ni.tenant_id, ni.user_id, ni.node_id, ni.updated_at AS waiting_since
+		FROM node_instances ni
+		WHERE ni.state = 'submitted' AND ni.updated_at <= $1
+		ORDER BY ni.updated_at`, before)
+	return out, err
+}
+
+// ListPendingReminders returns undelivered reminders, oldest first.
+func (r *SQLSchedulerRepository) ListPendingReminders(ctx context.Context, limit int) ([]models.PendingReminder, error) {
+	var out []models.PendingReminder
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT r.id, `+tenantOr("r.tenant_id", "r.student_id")+` AS tenant_id,
+		       r.student_id, r.title, r.message, r.due_at, r.created_by
+		FROM reminders r
+		WHERE r.status = 'pending'
+		ORDER BY r.created_at
+		LIMIT $1`, limit)
+	return out, err
+}
+
+func (r *SQLSchedulerRepository) MarkReminderSent(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE reminders SET status='sent', sent_at=now() WHERE id=$1 AND status='pending'`, id)
+	return err
+}
+
+func (r *SQLSchedulerRepository) ClaimEscalation(ctx context.Context, job, subjectID string, anchor time.Time, tier string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO escalation_log (job, subject_id, anchor_at, tier)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`, job, subjectID, anchor, tier)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const recipientColumns = `u.id, COALESCE(NULLIF(TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '')), ''), u.username) AS name,
+	COALESCE(u.email, '') AS email`
+
+func (r *SQLSchedulerRepository) GetRecipient(ctx context.Context, userID string) (*models.Recipient, error) {
+	var out []models.Recipient
+	err := r.db.SelectContext(ctx, &out, `SELECT `+recipientColumns+` FROM users u WHERE u.id=$1 AND u.is_active`, userID)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+func (r *SQLSchedulerRepository) ListStudentAdvisors(ctx context.Context, studentID string) ([]models.Recipient, error) {
+	var out []models.Recipient
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT `+recipientColumns+`
+		FROM student_advisors sa
+		JOIN users u ON u.id = sa.advisor_id AND u.is_active
+		WHERE sa.student_id=$1
+		ORDER BY u.id`, studentID)
+	return out, err
+}
+
+func (r *SQLSchedulerRepository) ListTenantAdmins(ctx context.Context, tenantID string) ([]models.Recipient, error) {
+	var out []models.Recipient
+	err := r.db.SelectContext(ctx, &out, `
+		SELECT `+recipientColumns+`
+		FROM user_tenant_memberships utm
+		JOIN users u ON u.id = utm.user_id AND u.is_active
+		WHERE utm.tenant_id=$1 AND utm.role='admin'
+		ORDER BY u.id`, tenantID)
+	return out, err
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLSchedulerRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLSchedulerRepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("AcquireLease", func(t *testing.T) {
+		mock.ExpectExec(`INSERT INTO job_leases .* ON CONFLICT \(name\) DO UPDATE .* WHERE job_leases.holder=EXCLUDED.holder OR job_leases.expires_at < now\(\)`).
+			WithArgs("stale_reviews", "host-1", int64(120000)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		ok, err := repo.AcquireLease(ctx, "stale_reviews", "host-1", 2*time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		// Held by another replica: the conditional upsert touches nothing
+		mock.ExpectExec(`INSERT INTO job_leases`).
+			WithArgs("stale_reviews", "host-2", int64(120000)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		ok, err = repo.AcquireLease(ctx, "stale_reviews", "host-2", 2*time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("ReleaseLease", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM job_leases WHERE name=\$1 AND holder=\$2`).
+			WithArgs("stale_reviews", "host-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.ReleaseLease(ctx, "stale_reviews", "host-1"))
+	})
+
+	t.Run("ListOpenDeadlines", func(t *testing.T) {
+		mock.ExpectQuery(`FROM node_deadlines d .* WHERE d.due_at BETWEEN \$1 AND \$2\s+AND NOT EXISTS`).
+			WithArgs(now.Add(-time.Hour), now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "user_id", "node_id", "due_at"}).
+				AddRow("d1", "t1", "u1", "S1", now))
+		list, err := repo.ListOpenDeadlines(ctx, now.Add(-time.Hour), now)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, "t1", list[0].TenantID)
+		}
+	})
+
+	t.Run("ListPendingReminders", func(t *testing.T) {
+		mock.ExpectQuery(`FROM reminders r\s+WHERE r.status = 'pending'`).
+			WithArgs(50).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "student_id", "title", "message", "due_at", "created_by"}).
+				AddRow("r1", "", "u1", "Draft", nil, nil, "adm"))
+		list, err := repo.ListPendingReminders(ctx, 50)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Nil(t, list[0].Message)
+			assert.Equal(t, "", list[0].TenantID)
+		}
+	})
+
+	t.Run("MarkReminderSent", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE reminders SET status='sent', sent_at=now\(\) WHERE id=\$1 AND status='pending'`).
+			WithArgs("r1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		assert.NoError(t, repo.MarkReminderSent(ctx, "r1"))
+	})
+
+	t.Run("ClaimEscalation", func(t *testing.T) {
+		mock.ExpectExec(`INSERT INTO escalation_log .* ON CONFLICT DO NOTHING`).
+			WithArgs("deadline_reminders", "d1", now, "overdue").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		ok, err := repo.ClaimEscalation(ctx, "deadline_reminders", "d1", now, "overdue")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		mock.ExpectExec(`INSERT INTO escalation_log`).
+			WithArgs("deadline_reminders", "d1", now, "overdue").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		ok, err = repo.ClaimEscalation(ctx, "deadline_reminders", "d1", now, "overdue")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("GetRecipient missing", func(t *testing.T) {
+		mock.ExpectQuery(`FROM users u WHERE u.id=\$1 AND u.is_active`).
+			WithArgs("ghost").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}))
+		r, err := repo.GetRecipient(ctx, "ghost")
+		assert.NoError(t, err)
+		assert.Nil(t, r)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
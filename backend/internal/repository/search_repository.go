@@ -3,14 +3,37 @@ package repository
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 	"github.com/jmoiron/sqlx"
 )
 
+// SearchScope is who is searching. Results are limited to the scope's tenant
+// and to what its role may see.
+type SearchScope struct {
+	TenantID string
+	UserID   string
+	Role     string
+}
+
+// staff may see every student of the tenant.
+func (s SearchScope) staff() bool {
+	return s.Role == "admin" || s.Role == "superadmin" || s.Role == "chair"
+}
+
+// SearchRepository runs ranked full-text searches over the search_tsv
+// columns. Each search returns one page of results, best match first, and
+// the total number of matches.
 type SearchRepository interface {
-	SearchUsers(ctx context.Context, query string, role string, userID string, limit int) ([]models.SearchResult, error)
-	SearchDocuments(ctx context.Context, query string, role string, userID string, limit int) ([]models.SearchResult, error)
+	SearchUsers(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error)
+	SearchDocuments(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error)
+	SearchMessages(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error)
+	SearchEvents(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error)
+	SearchContacts(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error)
 }
 
 type SQLSearchRepository struct {
@@ -21,113 +44,330 @@ func NewSQLSearchRepository(db *sqlx.DB) *SQLSearchRepository {
 	return &SQLSearchRepository{db: db}
 }
 
-func (r *SQLSearchRepository) SearchUsers(ctx context.Context, query string, role string, userID string, limit int) ([]models.SearchResult, error) {
-	// Only admins/staff can search users
-	if role != "admin" && role != "superadmin" && role != "chair" && role != "advisor" {
-		return []models.SearchResult{}, nil
+const (
+	// maxSearchTerms bounds the words of one query
+	maxSearchTerms = 8
+	// Highlights are marked with control characters so the text around them
+	// can be escaped before they become <mark> tags
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+	// headlineOptions configure ts_headline snippets
+	headlineOptions = "StartSel=\x02, StopSel=\x03, MaxWords=30, MinWords=12, ShortWord=2, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+// SearchTerms turns user input into a prefix query for portal_search_query:
+// every word has to start a word of the text. It returns "" when the input
+// has nothing to search for.
+func SearchTerms(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
 	}
+	for i, w := range words {
+		words[i] = strings.ToLower(w) + ":*"
+	}
+	return strings.Join(words, " & ")
+}
 
-	sqlQuery := `
-		SELECT id, first_name, last_name, email, role 
-		FROM users 
-		WHERE (first_name ILIKE $1 OR last_name ILIKE $1 OR email ILIKE $1)
-	`
-	args := []any{"%" + query + "%"}
+// highlight escapes a ts_headline snippet for HTML and turns its markers
+// into <mark> tags.
+func highlight(snippet string) string {
+	s := html.EscapeString(snippet)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
+
+// plainSnippet drops the highlight markers of a snippet.
+func plainSnippet(snippet string) string {
+	return strings.NewReplacer(highlightStart, "", highlightStop, "").Replace(snippet)
+}
+
+// searchRow is one hit. Ref and Extra carry what the result's link needs,
+// e.g. the owner and node of a document.
+type searchRow struct {
+	ID       string     `db:"id"`
+	Title    string     `db:"title"`
+	Subtitle string     `db:"subtitle"`
+	Snippet  string     `db:"snippet"`
+	Ref      string     `db:"ref"`
+	Extra    string     `db:"extra"`
+	At       *time.Time `db:"at"`
+	Rank     float64    `db:"rank"`
+	Total    int        `db:"total"`
+}
 
-	if role == "advisor" {
-		sqlQuery += ` AND (role != 'student' OR id IN (SELECT student_id FROM student_advisors WHERE advisor_id = $2))`
-		args = append(args, userID)
+// search runs a query whose first placeholder is the search terms and whose
+// last two are limit and offset.
+func (r *SQLSearchRepository) search(ctx context.Context, query string, limit, offset int, args ...any) ([]searchRow, int, error) {
+	var rows []searchRow
+	args = append(args, limit, offset)
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return rows, 0, nil
 	}
+	return rows, rows[0].Total, nil
+}
 
-	sqlQuery += ` LIMIT $` + fmt.Sprint(len(args)+1)
-	args = append(args, limit)
+// SearchUsers finds users by name, email and username. Only staff and
+// advisors search users; advisors see staff and their own students.
+func (r *SQLSearchRepository) SearchUsers(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	terms := SearchTerms(query)
+	if terms == "" || (!scope.staff() && scope.Role != "advisor") {
+		return []models.SearchResult{}, 0, nil
+	}
 
-	rows, err := r.db.QueryxContext(ctx, sqlQuery, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []models.SearchResult
-	for rows.Next() {
-		var u struct {
-			ID        string `db:"id"`
-			FirstName string `db:"first_name"`
-			LastName  string `db:"last_name"`
-			Email     string `db:"email"`
-			Role      string `db:"role"`
-		}
-		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Role); err != nil {
-			continue
-		}
+	sqlQuery := `
+		WITH q AS (SELECT portal_search_query($1) AS q)
+		SELECT u.id, u.first_name || ' ' || u.last_name AS title, u.email AS subtitle, '' AS snippet,
+			tm.role::text AS ref, '' AS extra, NULL::timestamptz AS at,
+			ts_rank_cd(u.search_tsv, q.q) AS rank, COUNT(*) OVER () AS total
+		FROM users u
+		JOIN user_tenant_memberships tm ON tm.user_id = u.id AND tm.tenant_id = $2
+		CROSS JOIN q
+		WHERE u.search_tsv @@ q.q AND u.is_active
+	`
+	args := []any{terms, scope.TenantID}
+	if !scope.staff() {
+		sqlQuery += ` AND (tm.role <> 'student' OR u.id IN (
+			SELECT student_id FROM student_advisors WHERE advisor_id = $3 AND tenant_id = $2))`
+		args = append(args, scope.UserID)
+	}
+	sqlQuery += fmt.Sprintf(` ORDER BY rank DESC, title LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
 
-		link := fmt.Sprintf("/admin/students/%s", u.ID)
-		if u.Role != "student" {
+	rows, total, err := r.search(ctx, sqlQuery, limit, offset, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]models.SearchResult, 0, len(rows))
+	for _, row := range rows {
+		link := "/admin/students-monitor/" + row.ID
+		if row.Ref != "student" {
 			link = "/admin/users"
 		}
-
 		results = append(results, models.SearchResult{
 			Type:        "student",
-			ID:          u.ID,
-			Title:       fmt.Sprintf("%s %s", u.FirstName, u.LastName),
-			Subtitle:    u.Email,
-			Description: u.Role,
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    row.Subtitle,
+			Description: row.Ref,
 			Link:        link,
+			Rank:        row.Rank,
 		})
 	}
-	return results, nil
+	return results, total, nil
 }
 
-func (r *SQLSearchRepository) SearchDocuments(ctx context.Context, query string, role string, userID string, limit int) ([]models.SearchResult, error) {
-	sqlQuery := `
-		SELECT a.id, a.filename, u.first_name, u.last_name, ni.node_id
-		FROM node_instance_slot_attachments a
-		JOIN node_instance_slots s ON a.slot_id = s.id
-		JOIN node_instances ni ON s.node_instance_id = ni.id
-		JOIN users u ON ni.user_id = u.id
-		WHERE a.filename ILIKE $1 AND a.is_active = true
-	`
-	args := []any{"%" + query + "%"}
+// SearchDocuments finds active journey attachments by file name and by the
+// text extracted from them. Students see their own, advisors their
+// students' and every other role, secretaries included, the whole tenant's.
+func (r *SQLSearchRepository) SearchDocuments(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	terms := SearchTerms(query)
+	if terms == "" {
+		return []models.SearchResult{}, 0, nil
+	}
 
-	if role == "student" {
-		sqlQuery += ` AND ni.user_id = $2`
-		args = append(args, userID)
-	} else if role == "advisor" {
-		sqlQuery += ` AND ni.user_id IN (SELECT student_id FROM student_advisors WHERE advisor_id = $2)`
-		args = append(args, userID)
+	args := []any{terms, scope.TenantID, headlineOptions}
+	visible := ``
+	switch scope.Role {
+	case "student":
+		visible = ` AND ni.user_id = $4`
+		args = append(args, scope.UserID)
+	case "advisor":
+		visible = ` AND ni.user_id IN (SELECT student_id FROM student_advisors WHERE advisor_id = $4 AND tenant_id = $2)`
+		args = append(args, scope.UserID)
+	}
+	// A file name match outranks the same words in the body
+	rows, total, err := r.search(ctx, fmt.Sprintf(`
+		WITH q AS (SELECT portal_search_query($1) AS q), hits AS (
+			SELECT a.id, a.filename AS title, u.first_name || ' ' || u.last_name AS subtitle,
+				ni.user_id AS ref, ni.node_id AS extra, a.attached_at AS at, a.document_version_id,
+				2 * ts_rank_cd(a.search_tsv, q.q) + COALESCE(ts_rank_cd(t.search_tsv, q.q), 0) AS rank,
+				COUNT(*) OVER () AS total
+			FROM node_instance_slot_attachments a
+			JOIN node_instance_slots s ON a.slot_id = s.id
+			JOIN node_instances ni ON s.node_instance_id = ni.id
+			JOIN users u ON ni.user_id = u.id
+			LEFT JOIN document_version_texts t ON t.version_id = a.document_version_id
+			CROSS JOIN q
+			WHERE (a.search_tsv @@ q.q OR t.search_tsv @@ q.q) AND a.is_active = true AND ni.tenant_id = $2%s
+			ORDER BY rank DESC, a.attached_at DESC
+			LIMIT $%d OFFSET $%d
+		)
+		SELECT h.id, h.title, h.subtitle,
+			CASE WHEN t.search_tsv @@ q.q
+				THEN ts_headline('pg_catalog.russian'::regconfig, left(t.content, 200000), q.q, $3)
+				ELSE '' END AS snippet,
+			h.ref, h.extra, h.at, h.rank, h.total
+		FROM hits h
+		CROSS JOIN q
+		LEFT JOIN document_version_texts t ON t.version_id = h.document_version_id
+		ORDER BY h.rank DESC, h.at DESC
+	`, visible, len(args)+1, len(args)+2), limit, offset, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]models.SearchResult, 0, len(rows))
+	for _, row := range rows {
+		link := fmt.Sprintf("/admin/students-monitor/%s?node=%s", row.Ref, row.Extra)
+		if row.Ref == scope.UserID {
+			link = "/journey?node=" + row.Extra
+		}
+		results = append(results, models.SearchResult{
+			Type:        "document",
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    fmt.Sprintf("Owner: %s", row.Subtitle),
+			Description: fmt.Sprintf("Node: %s", row.Extra),
+			Snippet:     highlight(row.Snippet),
+			Link:        link,
+			Rank:        row.Rank,
+			At:          row.At,
+		})
 	}
-	// Admin/Chair see all
+	return results, total, nil
+}
 
-	sqlQuery += ` LIMIT $` + fmt.Sprint(len(args)+1)
-	args = append(args, limit)
+// SearchMessages finds chat messages in the rooms the user is a member of,
+// whatever their role.
+func (r *SQLSearchRepository) SearchMessages(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	terms := SearchTerms(query)
+	if terms == "" {
+		return []models.SearchResult{}, 0, nil
+	}
 
-	rows, err := r.db.QueryxContext(ctx, sqlQuery, args...)
+	// Snippets are only built for the page, not for every match
+	rows, total, err := r.search(ctx, `
+		WITH q AS (SELECT portal_search_query($1) AS q), hits AS (
+			SELECT m.id, m.body, r.name AS title, COALESCE(s.first_name || ' ' || s.last_name, '') AS subtitle,
+				m.room_id::text AS ref, COALESCE(m.parent_id::text, '') AS extra, m.created_at AS at,
+				ts_rank_cd(m.search_tsv, q.q) AS rank, COUNT(*) OVER () AS total
+			FROM chat_messages m
+			JOIN chat_rooms r ON r.id = m.room_id
+			JOIN chat_room_members rm ON rm.room_id = m.room_id AND rm.user_id = $3
+			LEFT JOIN users s ON s.id = m.sender_id
+			CROSS JOIN q
+			WHERE m.search_tsv @@ q.q AND m.deleted_at IS NULL AND m.tenant_id = $2
+			ORDER BY rank DESC, m.created_at DESC
+			LIMIT $5 OFFSET $6
+		)
+		SELECT h.id, h.title, h.subtitle, ts_headline('pg_catalog.russian'::regconfig, h.body, q.q, $4) AS snippet,
+			h.ref, h.extra, h.at, h.rank, h.total
+		FROM hits h CROSS JOIN q
+		ORDER BY h.rank DESC, h.at DESC
+	`, limit, offset, terms, scope.TenantID, scope.UserID, headlineOptions)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []models.SearchResult
-	for rows.Next() {
-		var d struct {
-			ID        string `db:"id"`
-			Filename  string `db:"filename"`
-			FirstName string `db:"first_name"`
-			LastName  string `db:"last_name"`
-			NodeID    string `db:"node_id"`
-		}
-		if err := rows.Scan(&d.ID, &d.Filename, &d.FirstName, &d.LastName, &d.NodeID); err != nil {
-			continue
+		return nil, 0, err
+	}
+	results := make([]models.SearchResult, 0, len(rows))
+	for _, row := range rows {
+		link := fmt.Sprintf("/chat?room=%s&message=%s", row.Ref, row.ID)
+		if row.Extra != "" {
+			link += "&thread=" + row.Extra
 		}
+		results = append(results, models.SearchResult{
+			Type:        "message",
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    row.Subtitle,
+			Description: plainSnippet(row.Snippet),
+			Snippet:     highlight(row.Snippet),
+			Link:        link,
+			Rank:        row.Rank,
+			At:          row.At,
+		})
+	}
+	return results, total, nil
+}
 
+// SearchEvents finds calendar events. Staff see the tenant's events, others
+// the ones they created or attend.
+func (r *SQLSearchRepository) SearchEvents(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	terms := SearchTerms(query)
+	if terms == "" {
+		return []models.SearchResult{}, 0, nil
+	}
+
+	args := []any{terms, scope.TenantID, headlineOptions}
+	visible := ``
+	if !scope.staff() {
+		visible = ` AND (e.creator_id = $4 OR EXISTS (
+				SELECT 1 FROM event_attendees ea WHERE ea.event_id = e.id AND ea.user_id = $4))`
+		args = append(args, scope.UserID)
+	}
+	rows, total, err := r.search(ctx, fmt.Sprintf(`
+		WITH q AS (SELECT portal_search_query($1) AS q), hits AS (
+			SELECT e.id, e.title, COALESCE(e.description, '') AS body, COALESCE(e.location, '') AS subtitle,
+				e.event_type AS ref, '' AS extra, e.start_time AS at,
+				ts_rank_cd(e.search_tsv, q.q) AS rank, COUNT(*) OVER () AS total
+			FROM events e
+			CROSS JOIN q
+			WHERE e.search_tsv @@ q.q AND e.tenant_id = $2%s
+			ORDER BY rank DESC, e.start_time DESC
+			LIMIT $%d OFFSET $%d
+		)
+		SELECT h.id, h.title, h.subtitle, ts_headline('pg_catalog.russian'::regconfig, h.body, q.q, $3) AS snippet,
+			h.ref, h.extra, h.at, h.rank, h.total
+		FROM hits h CROSS JOIN q
+		ORDER BY h.rank DESC, h.at DESC
+	`, visible, len(args)+1, len(args)+2), limit, offset, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]models.SearchResult, 0, len(rows))
+	for _, row := range rows {
 		results = append(results, models.SearchResult{
-			Type:        "document",
-			ID:          d.ID,
-			Title:       d.Filename,
-			Subtitle:    fmt.Sprintf("Owner: %s %s", d.FirstName, d.LastName),
-			Description: fmt.Sprintf("Node: %s", d.NodeID),
-			Link:        "#", // Placeholder
+			Type:        "event",
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    row.Subtitle,
+			Description: plainSnippet(row.Snippet),
+			Snippet:     highlight(row.Snippet),
+			Link:        "/calendar?event=" + row.ID,
+			Rank:        row.Rank,
+			At:          row.At,
+		})
+	}
+	return results, total, nil
+}
+
+// SearchContacts finds the tenant's active contacts by name, title and
+// email. Everyone sees them.
+func (r *SQLSearchRepository) SearchContacts(ctx context.Context, scope SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	terms := SearchTerms(query)
+	if terms == "" {
+		return []models.SearchResult{}, 0, nil
+	}
+
+	rows, total, err := r.search(ctx, `
+		WITH q AS (SELECT portal_search_query($1) AS q)
+		SELECT c.id, COALESCE(c.name->>'ru', c.name->>'en', c.name->>'kz', '') AS title,
+			COALESCE(c.title->>'ru', c.title->>'en', c.title->>'kz', '') AS subtitle, '' AS snippet,
+			COALESCE(c.email, '') AS ref, COALESCE(c.phone, '') AS extra, NULL::timestamptz AS at,
+			ts_rank_cd(c.search_tsv, q.q) AS rank, COUNT(*) OVER () AS total
+		FROM contacts c
+		CROSS JOIN q
+		WHERE c.search_tsv @@ q.q AND c.tenant_id = $2 AND c.is_active
+		ORDER BY rank DESC, c.sort_order
+		LIMIT $3 OFFSET $4
+	`, limit, offset, terms, scope.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]models.SearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, models.SearchResult{
+			Type:        "contact",
+			ID:          row.ID,
+			Title:       row.Title,
+			Subtitle:    row.Subtitle,
+			Description: strings.TrimSpace(row.Ref + " " + row.Extra),
+			Link:        "/contacts",
+			Rank:        row.Rank,
 		})
 	}
-	return results, nil
+	return results, total, nil
 }
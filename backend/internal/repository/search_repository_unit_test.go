@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var searchColumns = []string{"id", "title", "subtitle", "snippet", "ref", "extra", "at", "rank", "total"}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, "thesis:* & draft:*", SearchTerms("Thesis_Draft"))
+	assert.Equal(t, "диссертация:* & 2024:*", SearchTerms("  Диссертация, 2024! "))
+	assert.Equal(t, "", SearchTerms("&|!:*()"))
+	assert.Equal(t, "a:* & b:* & c:* & d:* & e:* & f:* & g:* & h:*", SearchTerms("a b c d e f g h i j"))
+}
+
+func TestHighlight(t *testing.T) {
+	snippet := "use \x02<script>\x03 & \x02tags\x03"
+	assert.Equal(t, "use <mark>&lt;script&gt;</mark> &amp; <mark>tags</mark>", highlight(snippet))
+	assert.Equal(t, "use <script> & tags", plainSnippet(snippet))
+}
+
+func TestSQLSearchRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewSQLSearchRepository(sqlx.NewDb(db, "sqlmock"))
+	ctx := context.Background()
+
+	t.Run("Students do not search users", func(t *testing.T) {
+		results, total, err := repo.SearchUsers(ctx, SearchScope{TenantID: "t1", UserID: "s1", Role: "student"}, "ann", 5, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Zero(t, total)
+	})
+
+	t.Run("Advisors see their own students", func(t *testing.T) {
+		mock.ExpectQuery(`FROM users u.*student_advisors WHERE advisor_id = \$3.*LIMIT \$4 OFFSET \$5`).
+			WithArgs("ann:*", "t1", "a1", 5, 0).
+			WillReturnRows(sqlmock.NewRows(searchColumns).
+				AddRow("s1", "Ann Lee", "ann@uni.kz", "", "student", "", nil, 0.5, 2).
+				AddRow("a2", "Anna Kim", "anna@uni.kz", "", "advisor", "", nil, 0.3, 2))
+
+		results, total, err := repo.SearchUsers(ctx, SearchScope{TenantID: "t1", UserID: "a1", Role: "advisor"}, "Ann", 5, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 2, total)
+		require.Len(t, results, 2)
+		assert.Equal(t, "/admin/students-monitor/s1", results[0].Link)
+		assert.Equal(t, "/admin/users", results[1].Link)
+	})
+
+	t.Run("Students search their own documents", func(t *testing.T) {
+		at := time.Now()
+		mock.ExpectQuery(`FROM node_instance_slot_attachments a.*document_version_texts t.*AND ni.user_id = \$4`).
+			WithArgs("thesis:*", "t1", headlineOptions, "s1", 20, 20).
+			WillReturnRows(sqlmock.NewRows(searchColumns).
+				AddRow("att1", "Thesis.pdf", "Ann Lee", "my \x02thesis\x03 <draft>", "s1", "S1_antiplag", at, 0.1, 21))
+
+		results, total, err := repo.SearchDocuments(ctx, SearchScope{TenantID: "t1", UserID: "s1", Role: "student"}, "thesis", 20, 20)
+		require.NoError(t, err)
+		assert.Equal(t, 21, total)
+		require.Len(t, results, 1)
+		assert.Equal(t, "/journey?node=S1_antiplag", results[0].Link)
+		assert.Equal(t, "my <mark>thesis</mark> &lt;draft&gt;", results[0].Snippet)
+	})
+
+	t.Run("Secretaries search the whole tenant's documents", func(t *testing.T) {
+		mock.ExpectQuery(`AND a.is_active = true AND ni.tenant_id = \$2\s+ORDER BY rank DESC, a.attached_at DESC\s+LIMIT \$4 OFFSET \$5`).
+			WithArgs("thesis:*", "t1", headlineOptions, 10, 0).
+			WillReturnRows(sqlmock.NewRows(searchColumns).
+				AddRow("att2", "Thesis.pdf", "Ann Lee", "", "s1", "S1_antiplag", time.Now(), 0.1, 1))
+
+		results, total, err := repo.SearchDocuments(ctx, SearchScope{TenantID: "t1", UserID: "sec1", Role: "secretary"}, "thesis", 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, total)
+		require.Len(t, results, 1)
+		assert.Equal(t, "/admin/students-monitor/s1?node=S1_antiplag", results[0].Link)
+	})
+
+	t.Run("Messages come with escaped highlights", func(t *testing.T) {
+		mock.ExpectQuery(`FROM chat_messages m.*ts_headline`).
+			WithArgs("deadline:*", "t1", "u1", headlineOptions, 5, 0).
+			WillReturnRows(sqlmock.NewRows(searchColumns).
+				AddRow("m1", "Cohort 2024", "Ann Lee", "the \x02deadline\x03 <is> friday", "r1", "p1", time.Now(), 0.2, 1))
+
+		results, _, err := repo.SearchMessages(ctx, SearchScope{TenantID: "t1", UserID: "u1", Role: "student"}, "deadline", 5, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "the <mark>deadline</mark> &lt;is&gt; friday", results[0].Snippet)
+		assert.Equal(t, "the deadline <is> friday", results[0].Description)
+		assert.Equal(t, "/chat?room=r1&message=m1&thread=p1", results[0].Link)
+	})
+
+	t.Run("Staff see every event", func(t *testing.T) {
+		mock.ExpectQuery(`FROM events e`).
+			WithArgs("defense:*", "t1", headlineOptions, 5, 0).
+			WillReturnRows(sqlmock.NewRows(searchColumns))
+
+		results, total, err := repo.SearchEvents(ctx, SearchScope{TenantID: "t1", UserID: "u1", Role: "admin"}, "defense", 5, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Zero(t, total)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// SessionRepository stores users' sign-in sessions and the hashes of their
+// refresh tokens.
+type SessionRepository interface {
+	// Create stores a new session, setting its ID, and drops the user's
+	// expired ones.
+	Create(ctx context.Context, s *models.UserSession) error
+	// GetByTokenHash finds the session whose current or previous refresh
+	// token has the hash.
+	GetByTokenHash(ctx context.Context, hash string) (*models.UserSession, error)
+	// Rotate replaces the session's refresh token, provided it is still
+	// oldHash and the session is not revoked.
+	Rotate(ctx context.Context, id, oldHash, newHash string) error
+	ListActive(ctx context.Context, userID string) ([]models.UserSession, error)
+	// Revoke ends one of the user's live sessions.
+	Revoke(ctx context.Context, userID, id string) error
+	// RevokeAll ends all of the user's live sessions and returns their IDs.
+	RevokeAll(ctx context.Context, userID string) ([]string, error)
+}
+
+type SQLSessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLSessionRepository(db *sqlx.DB) *SQLSessionRepository {
+	return &SQLSessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, tenant_id, refresh_token_hash, previous_token_hash,
+	user_agent, ip, created_at, last_used_at, rotated_at, expires_at, revoked_at`
+
+func (r *SQLSessionRepository) Create(ctx context.Context, s *models.UserSession) error {
+	return r.db.QueryRowxContext(ctx, `
+		WITH expired AS (DELETE FROM user_sessions WHERE user_id = $1 AND expires_at < now())
+		INSERT INTO user_sessions (user_id, tenant_id, refresh_token_hash, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, last_used_at`,
+		s.UserID, s.TenantID, s.RefreshTokenHash, s.UserAgent, s.IP, s.ExpiresAt,
+	).Scan(&s.ID, &s.CreatedAt, &s.LastUsedAt)
+}
+
+func (r *SQLSessionRepository) GetByTokenHash(ctx context.Context, hash string) (*models.UserSession, error) {
+	var s models.UserSession
+	err := r.db.GetContext(ctx, &s, `SELECT `+sessionColumns+`
+		FROM user_sessions
+		WHERE refresh_token_hash = $1 OR previous_token_hash = $1`, hash)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SQLSessionRepository) Rotate(ctx context.Context, id, oldHash, newHash string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET previous_token_hash = refresh_token_hash, refresh_token_hash = $3,
+			rotated_at = now(), last_used_at = now()
+		WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL`, id, oldHash, newHash)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLSessionRepository) ListActive(ctx context.Context, userID string) ([]models.UserSession, error) {
+	sessions := []models.UserSession{}
+	err := r.db.SelectContext(ctx, &sessions, `SELECT `+sessionColumns+`
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC`, userID)
+	return sessions, err
+}
+
+func (r *SQLSessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLSessionRepository) RevokeAll(ctx context.Context, userID string) ([]string, error) {
+	ids := []string{}
+	err := r.db.SelectContext(ctx, &ids, `
+		UPDATE user_sessions SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING id`, userID)
+	return ids, err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLSessionRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLSessionRepository(sqlxDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Create drops expired sessions", func(t *testing.T) {
+		tenantID := "t1"
+		s := &models.UserSession{UserID: "u1", TenantID: &tenantID, RefreshTokenHash: "h1", UserAgent: "Firefox", IP: "10.0.0.1", ExpiresAt: now.Add(time.Hour)}
+		mock.ExpectQuery(`WITH expired AS \(DELETE FROM user_sessions WHERE user_id = \$1 AND expires_at < now\(\)\)\s+INSERT INTO user_sessions`).
+			WithArgs("u1", &tenantID, "h1", "Firefox", "10.0.0.1", s.ExpiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "last_used_at"}).AddRow("s1", now, now))
+		require.NoError(t, repo.Create(ctx, s))
+		assert.Equal(t, "s1", s.ID)
+	})
+
+	t.Run("GetByTokenHash matches the previous token too", func(t *testing.T) {
+		cols := []string{"id", "user_id", "tenant_id", "refresh_token_hash", "previous_token_hash", "user_agent", "ip", "created_at", "last_used_at", "rotated_at", "expires_at", "revoked_at"}
+		mock.ExpectQuery(`FROM user_sessions\s+WHERE refresh_token_hash = \$1 OR previous_token_hash = \$1`).
+			WithArgs("h0").
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("s1", "u1", nil, "h1", "h0", "", "", now, now, now, now.Add(time.Hour), nil))
+		s, err := repo.GetByTokenHash(ctx, "h0")
+		require.NoError(t, err)
+		assert.Equal(t, "h1", s.RefreshTokenHash)
+		assert.True(t, s.Active(now))
+
+		mock.ExpectQuery(`FROM user_sessions`).WithArgs("nope").WillReturnRows(sqlmock.NewRows(cols))
+		_, err = repo.GetByTokenHash(ctx, "nope")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Rotate only from the current token", func(t *testing.T) {
+		mock.ExpectExec(`SET previous_token_hash = refresh_token_hash, refresh_token_hash = \$3.*WHERE id = \$1 AND refresh_token_hash = \$2 AND revoked_at IS NULL`).
+			WithArgs("s1", "h1", "h2").WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, repo.Rotate(ctx, "s1", "h1", "h2"))
+
+		mock.ExpectExec(`UPDATE user_sessions`).WithArgs("s1", "h1", "h3").WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.Rotate(ctx, "s1", "h1", "h3"), ErrNotFound)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE user_sessions SET revoked_at = now\(\)\s+WHERE id = \$1 AND user_id = \$2 AND revoked_at IS NULL`).
+			WithArgs("s1", "u2").WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, repo.Revoke(ctx, "u2", "s1"), ErrNotFound, "another user's session")
+
+		mock.ExpectQuery(`UPDATE user_sessions SET revoked_at = now\(\)\s+WHERE user_id = \$1 AND revoked_at IS NULL\s+RETURNING id`).
+			WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("s1").AddRow("s2"))
+		ids, err := repo.RevokeAll(ctx, "u1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"s1", "s2"}, ids)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
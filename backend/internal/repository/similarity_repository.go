@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SimilarityRepository stores fingerprints of dissertation texts and the
+// similarity reports built from them. Dissertation versions are those
+// attached to one of the given upload slots.
+type SimilarityRepository interface {
+	// ListUnfingerprinted returns extracted dissertation texts that have no
+	// fingerprint yet; only VersionID and Content are set.
+	ListUnfingerprinted(ctx context.Context, slotKeys []string, limit int) ([]models.DocumentText, error)
+	SaveFingerprint(ctx context.Context, versionID string, minhash []byte, shingles int) error
+	// ListPendingReports returns fingerprinted dissertation versions
+	// without a report, oldest first.
+	ListPendingReports(ctx context.Context, slotKeys []string, limit int) ([]models.SimilarityCandidate, error)
+	// ListEarlierVersions returns the fingerprinted dissertation versions of
+	// the tenant submitted before the given one.
+	ListEarlierVersions(ctx context.Context, v models.SimilarityCandidate, slotKeys []string) ([]models.SimilarityCandidate, error)
+	GetText(ctx context.Context, versionID string) (string, error)
+	SaveReport(ctx context.Context, report *models.SimilarityReport) error
+	// ListNodeReports returns the reports attached to a student's node,
+	// newest first.
+	ListNodeReports(ctx context.Context, tenantID, studentID, nodeID string) ([]models.SimilarityReport, error)
+}
+
+type SQLSimilarityRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLSimilarityRepository(db *sqlx.DB) *SQLSimilarityRepository {
+	return &SQLSimilarityRepository{db: db}
+}
+
+// dissertationVersions selects one row per fingerprinted dissertation
+// version, with the node instance it was last attached to.
+const dissertationVersions = `
+	SELECT DISTINCT ON (dv.id) dv.id AS version_id, dv.tenant_id, s.node_instance_id,
+		ni.user_id AS student_id, COALESCE(u.first_name||' '||u.last_name,'') AS student_name,
+		a.filename, dv.created_at, t.minhash, t.shingle_count
+	FROM document_versions dv
+	JOIN document_version_texts t ON t.version_id = dv.id
+	JOIN node_instance_slot_attachments a ON a.document_version_id = dv.id
+	JOIN node_instance_slots s ON s.id = a.slot_id
+	JOIN node_instances ni ON ni.id = s.node_instance_id
+	LEFT JOIN users u ON u.id = ni.user_id
+	WHERE t.status = 'done' AND t.minhash IS NOT NULL AND s.slot_key = ANY($1)`
+
+func (r *SQLSimilarityRepository) ListUnfingerprinted(ctx context.Context, slotKeys []string, limit int) ([]models.DocumentText, error) {
+	var texts []models.DocumentText
+	err := r.db.SelectContext(ctx, &texts, `
+		SELECT t.version_id, t.content
+		FROM document_version_texts t
+		WHERE t.status = 'done' AND t.minhash IS NULL
+			AND EXISTS (
+				SELECT 1 FROM node_instance_slot_attachments a
+				JOIN node_instance_slots s ON s.id = a.slot_id
+				WHERE a.document_version_id = t.version_id AND s.slot_key = ANY($1))
+		ORDER BY t.extracted_at
+		LIMIT $2`, pq.Array(slotKeys), limit)
+	return texts, err
+}
+
+func (r *SQLSimilarityRepository) SaveFingerprint(ctx context.Context, versionID string, minhash []byte, shingles int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE document_version_texts SET minhash=$2, shingle_count=$3 WHERE version_id=$1`,
+		versionID, minhash, shingles)
+	return err
+}
+
+func (r *SQLSimilarityRepository) ListPendingReports(ctx context.Context, slotKeys []string, limit int) ([]models.SimilarityCandidate, error) {
+	var pending []models.SimilarityCandidate
+	err := r.db.SelectContext(ctx, &pending, `
+		SELECT * FROM (`+dissertationVersions+`
+			AND NOT EXISTS (SELECT 1 FROM similarity_reports r WHERE r.version_id = dv.id)
+			ORDER BY dv.id, a.attached_at DESC
+		) v
+		ORDER BY created_at
+		LIMIT $2`, pq.Array(slotKeys), limit)
+	return pending, err
+}
+
+func (r *SQLSimilarityRepository) ListEarlierVersions(ctx context.Context, v models.SimilarityCandidate, slotKeys []string) ([]models.SimilarityCandidate, error) {
+	var earlier []models.SimilarityCandidate
+	err := r.db.SelectContext(ctx, &earlier, dissertationVersions+`
+			AND dv.tenant_id = $2 AND (dv.created_at, dv.id) < ($3, $4)
+		ORDER BY dv.id, a.attached_at DESC`,
+		pq.Array(slotKeys), v.TenantID, v.CreatedAt, v.VersionID)
+	return earlier, err
+}
+
+func (r *SQLSimilarityRepository) GetText(ctx context.Context, versionID string) (string, error) {
+	var content string
+	err := r.db.GetContext(ctx, &content, `SELECT content FROM document_version_texts WHERE version_id=$1`, versionID)
+	return content, err
+}
+
+func (r *SQLSimilarityRepository) SaveReport(ctx context.Context, report *models.SimilarityReport) error {
+	var saved struct {
+		ID        string    `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err := r.db.GetContext(ctx, &saved, `
+		INSERT INTO similarity_reports
+			(tenant_id, node_instance_id, version_id, score, self_score, compared_count, sources, passages)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (version_id) DO UPDATE SET
+			node_instance_id = EXCLUDED.node_instance_id, score = EXCLUDED.score, self_score = EXCLUDED.self_score,
+			compared_count = EXCLUDED.compared_count, sources = EXCLUDED.sources, passages = EXCLUDED.passages,
+			created_at = now()
+		RETURNING id, created_at`,
+		report.TenantID, report.NodeInstanceID, report.VersionID, report.Score, report.SelfScore,
+		report.ComparedCount, report.Sources, report.Passages)
+	if err != nil {
+		return err
+	}
+	report.ID, report.CreatedAt = saved.ID, saved.CreatedAt
+	return nil
+}
+
+func (r *SQLSimilarityRepository) ListNodeReports(ctx context.Context, tenantID, studentID, nodeID string) ([]models.SimilarityReport, error) {
+	reports := []models.SimilarityReport{}
+	err := r.db.SelectContext(ctx, &reports, `
+		SELECT r.id, r.tenant_id, r.node_instance_id, r.version_id, r.score, r.self_score, r.compared_count,
+			r.sources, r.passages, r.created_at
+		FROM similarity_reports r
+		JOIN node_instances ni ON ni.id = r.node_instance_id
+		WHERE r.tenant_id = $1 AND ni.user_id = $2 AND ni.node_id = $3
+		ORDER BY r.created_at DESC`, tenantID, studentID, nodeID)
+	return reports, err
+}
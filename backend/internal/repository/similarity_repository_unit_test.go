@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLSimilarityRepository_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewSQLSimilarityRepository(sqlxDB)
+	ctx := context.Background()
+	keys := []string{"dissertation_draft_file"}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("ListEarlierVersions", func(t *testing.T) {
+		v := models.SimilarityCandidate{VersionID: "ver-2", TenantID: "t1", CreatedAt: now}
+		mock.ExpectQuery(`SELECT DISTINCT ON \(dv.id\) .* s.slot_key = ANY\(\$1\)\s+AND dv.tenant_id = \$2 AND \(dv.created_at, dv.id\) < \(\$3, \$4\)`).
+			WithArgs(pq.Array(keys), "t1", now, "ver-2").
+			WillReturnRows(sqlmock.NewRows([]string{"version_id", "tenant_id", "node_instance_id", "student_id", "student_name", "filename", "created_at", "minhash", "shingle_count"}).
+				AddRow("ver-1", "t1", "ni-1", "s1", "Ann Lee", "draft.docx", now.Add(-time.Hour), []byte{1, 2}, 900))
+
+		earlier, err := repo.ListEarlierVersions(ctx, v, keys)
+		require.NoError(t, err)
+		require.Len(t, earlier, 1)
+		assert.Equal(t, "Ann Lee", earlier[0].StudentName)
+		assert.Equal(t, 900, earlier[0].ShingleCount)
+	})
+
+	t.Run("SaveReport", func(t *testing.T) {
+		report := &models.SimilarityReport{
+			TenantID: "t1", NodeInstanceID: "ni-2", VersionID: "ver-2", Score: 0.25, ComparedCount: 3,
+			Sources: models.SimilaritySources{{VersionID: "ver-1", Score: 0.25, Passages: 1}},
+		}
+		mock.ExpectQuery(`INSERT INTO similarity_reports .* ON CONFLICT \(version_id\) DO UPDATE`).
+			WithArgs("t1", "ni-2", "ver-2", 0.25, 0.0, 3,
+				`[{"version_id":"ver-1","filename":"","student_id":"","student_name":"","own":false,"score":0.25,"passages":1}]`, `[]`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("rep-1", now))
+
+		require.NoError(t, repo.SaveReport(ctx, report))
+		assert.Equal(t, "rep-1", report.ID)
+		assert.Equal(t, now, report.CreatedAt)
+	})
+
+	t.Run("ListNodeReports", func(t *testing.T) {
+		mock.ExpectQuery(`FROM similarity_reports r\s+JOIN node_instances ni`).
+			WithArgs("t1", "s1", "S1_text_ready").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "node_instance_id", "version_id", "score", "self_score", "compared_count", "sources", "passages", "created_at"}).
+				AddRow("rep-1", "t1", "ni-2", "ver-2", 0.25, 0.5, 3, []byte(`[{"version_id":"ver-1","own":true}]`),
+					[]byte(`[{"source_version_id":"ver-1","text":"a b c","source_text":"a b c","words":3}]`), now))
+
+		reports, err := repo.ListNodeReports(ctx, "t1", "s1", "S1_text_ready")
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		assert.True(t, reports[0].Sources[0].Own)
+		assert.Equal(t, 3, reports[0].Passages[0].Words)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,137 @@
+package saml
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// canonicalize returns the exclusive canonical form, without comments, of
+// the subtree at e (https://www.w3.org/TR/xml-exc-c14n/). inclusive is the
+// InclusiveNamespaces PrefixList, "#default" naming the default namespace.
+// skip, if set, is left out along with everything below it; that is how
+// the enveloped-signature transform drops the signature.
+func canonicalize(e *element, inclusive []string, skip *element) []byte {
+	c := &canonicalizer{inclusive: map[string]bool{}, skip: skip}
+	for _, p := range inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		c.inclusive[p] = true
+	}
+	c.element(e, map[string]string{})
+	return c.buf.Bytes()
+}
+
+type canonicalizer struct {
+	buf       bytes.Buffer
+	inclusive map[string]bool
+	skip      *element
+}
+
+// element writes e. rendered holds the namespace declarations in effect
+// from the output ancestors of e.
+func (c *canonicalizer) element(e *element, rendered map[string]string) {
+	// Namespaces visibly utilized by e, plus the inclusive ones in scope
+	used := map[string]bool{e.prefix: true}
+	for _, a := range e.attrs {
+		if a.prefix != "" && a.prefix != "xmlns" {
+			used[a.prefix] = true
+		}
+	}
+	for p := range c.inclusive {
+		if _, ok := e.lookupNS(p); ok {
+			used[p] = true
+		}
+	}
+
+	type nsDecl struct{ prefix, uri string }
+	var decls []nsDecl
+	scope := rendered
+	for p := range used {
+		if p == "xml" {
+			continue
+		}
+		uri, ok := e.lookupNS(p)
+		if !ok && p != "" {
+			continue
+		}
+		prev, seen := rendered[p]
+		if prev == uri && (seen || p == "") {
+			// Already rendered by an output ancestor, or the default
+			// namespace left empty
+			continue
+		}
+		decls = append(decls, nsDecl{p, uri})
+	}
+	if len(decls) > 0 {
+		scope = make(map[string]string, len(rendered)+len(decls))
+		for k, v := range rendered {
+			scope[k] = v
+		}
+		for _, d := range decls {
+			scope[d.prefix] = d.uri
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].prefix < decls[j].prefix })
+
+	type attrOut struct{ uri, name, local, value string }
+	var attrs []attrOut
+	for _, a := range e.attrs {
+		if a.prefix == "xmlns" || (a.prefix == "" && a.local == "xmlns") {
+			continue
+		}
+		out := attrOut{name: a.local, local: a.local, value: a.value}
+		if a.prefix != "" {
+			out.uri, _ = e.lookupNS(a.prefix)
+			out.name = a.prefix + ":" + a.local
+		}
+		attrs = append(attrs, out)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].local < attrs[j].local
+	})
+
+	name := e.local
+	if e.prefix != "" {
+		name = e.prefix + ":" + e.local
+	}
+	c.buf.WriteString("<" + name)
+	for _, d := range decls {
+		if d.prefix == "" {
+			c.buf.WriteString(` xmlns="`)
+		} else {
+			c.buf.WriteString(" xmlns:" + d.prefix + `="`)
+		}
+		c.buf.WriteString(attrEscaper.Replace(d.uri) + `"`)
+	}
+	for _, a := range attrs {
+		c.buf.WriteString(" " + a.name + `="` + attrEscaper.Replace(a.value) + `"`)
+	}
+	c.buf.WriteString(">")
+	for _, n := range e.children {
+		switch n := n.(type) {
+		case *element:
+			if n != c.skip {
+				c.element(n, scope)
+			}
+		case charData:
+			c.buf.WriteString(textEscaper.Replace(string(n)))
+		case procInst:
+			c.buf.WriteString("<?" + n.target)
+			if n.inst != "" {
+				c.buf.WriteString(" " + n.inst)
+			}
+			c.buf.WriteString("?>")
+		}
+	}
+	c.buf.WriteString("</" + name + ">")
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
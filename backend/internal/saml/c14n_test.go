@@ -0,0 +1,60 @@
+package saml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	find := func(t *testing.T, doc, local string) *element {
+		root, err := parseXML([]byte(doc))
+		require.NoError(t, err)
+		var found *element
+		root.walk(func(e *element) {
+			if e.local == local && found == nil {
+				found = e
+			}
+		})
+		require.NotNil(t, found)
+		return found
+	}
+
+	t.Run("Namespaces are pushed down to where they are used", func(t *testing.T) {
+		// The example of section 2.2 of the exclusive c14n recommendation
+		doc := `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"/></n1:elem2></n0:local>`
+		got := canonicalize(find(t, doc, "elem2"), nil, nil)
+		assert.Equal(t, `<n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`, string(got))
+
+		got = canonicalize(find(t, doc, "elem2"), []string{"n3"}, nil)
+		assert.Equal(t, `<n1:elem2 xmlns:n1="http://example.net" xmlns:n3="ftp://example.org" xml:lang="en"><n3:stuff></n3:stuff></n1:elem2>`, string(got))
+	})
+
+	t.Run("Attributes sorted, text and attributes escaped", func(t *testing.T) {
+		doc := `<?xml version="1.0"?>
+<!-- comment --><r xmlns="urn:d" xmlns:b="urn:b" xmlns:a="urn:a" z="1" b:y="2" a:x="3" c="&quot;&#9;"><!-- gone -->a &amp; b &lt; c &gt; d</r>`
+		got := canonicalize(find(t, doc, "r"), nil, nil)
+		assert.Equal(t, `<r xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b" c="&quot;&#x9;" z="1" a:x="3" b:y="2">a &amp; b &lt; c &gt; d</r>`, string(got))
+	})
+
+	t.Run("Default namespace undeclared for unqualified children", func(t *testing.T) {
+		doc := `<r xmlns="urn:d"><c xmlns=""><d/></c></r>`
+		assert.Equal(t, `<r xmlns="urn:d"><c xmlns=""><d></d></c></r>`, string(canonicalize(find(t, doc, "r"), nil, nil)))
+		assert.Equal(t, `<c><d></d></c>`, string(canonicalize(find(t, doc, "c"), nil, nil)))
+	})
+
+	t.Run("Skipped element", func(t *testing.T) {
+		doc := `<r ID="x"><a/><sig/><b/></r>`
+		r := find(t, doc, "r")
+		assert.Equal(t, `<r ID="x"><a></a><b></b></r>`, string(canonicalize(r, nil, r.children[1].(*element))))
+	})
+}
+
+func TestParseXML_RefusesDTD(t *testing.T) {
+	_, err := parseXML([]byte(`<!DOCTYPE r [<!ENTITY x "y">]><r>&x;</r>`))
+	assert.Error(t, err)
+
+	_, err = parseXML([]byte(`<r><a></b></r>`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const nsXML = "http://www.w3.org/XML/1998/namespace"
+
+// The signature checks need the document exactly as it was signed, prefixes
+// and all, which encoding/xml's unmarshalling does not keep. Documents are
+// therefore read into this small tree and only ever read from it.
+
+// element is an XML element with its attributes as written, namespace
+// declarations included.
+type element struct {
+	prefix   string
+	local    string
+	attrs    []attr
+	children []node
+	parent   *element
+}
+
+type attr struct {
+	// prefix is "xmlns" for a prefixed namespace declaration; the default
+	// namespace is declared by an unprefixed "xmlns" attribute
+	prefix string
+	local  string
+	value  string
+}
+
+type node interface{}
+
+// charData is text, with entities already resolved.
+type charData string
+
+type procInst struct {
+	target string
+	inst   string
+}
+
+// parseXML reads a document into a tree. DTDs are refused: SAML has no use
+// for them and they are the usual vehicle for entity tricks.
+func parseXML(data []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root, cur *element
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("saml: malformed xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{prefix: t.Name.Space, local: t.Name.Local, parent: cur}
+			for _, a := range t.Attr {
+				e.attrs = append(e.attrs, attr{prefix: a.Name.Space, local: a.Name.Local, value: a.Value})
+			}
+			if cur == nil {
+				if root != nil {
+					return nil, errors.New("saml: more than one root element")
+				}
+				root = e
+			} else {
+				cur.children = append(cur.children, e)
+			}
+			cur = e
+		case xml.EndElement:
+			if cur == nil || t.Name.Space != cur.prefix || t.Name.Local != cur.local {
+				return nil, errors.New("saml: malformed xml: mismatched end element")
+			}
+			cur = cur.parent
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, charData(t))
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("saml: malformed xml: text outside the root element")
+			}
+		case xml.ProcInst:
+			if cur != nil {
+				cur.children = append(cur.children, procInst{target: t.Target, inst: string(t.Inst)})
+			}
+		case xml.Directive:
+			return nil, errors.New("saml: documents with a DTD are not accepted")
+		}
+	}
+	if root == nil || cur != nil {
+		return nil, errors.New("saml: malformed xml: incomplete document")
+	}
+	return root, nil
+}
+
+// lookupNS resolves a prefix, "" being the default namespace, in the scope
+// of e.
+func (e *element) lookupNS(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for el := e; el != nil; el = el.parent {
+		for _, a := range el.attrs {
+			if (prefix == "" && a.prefix == "" && a.local == "xmlns") || (prefix != "" && a.prefix == "xmlns" && a.local == prefix) {
+				return a.value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// space is the namespace URI of e.
+func (e *element) space() string {
+	ns, _ := e.lookupNS(e.prefix)
+	return ns
+}
+
+func (e *element) is(space, local string) bool {
+	return e.local == local && e.space() == space
+}
+
+// attr returns the value of an unprefixed attribute.
+func (e *element) attr(local string) string {
+	for _, a := range e.attrs {
+		if a.prefix == "" && a.local == local {
+			return a.value
+		}
+	}
+	return ""
+}
+
+func (e *element) hasAttr(local string) bool {
+	for _, a := range e.attrs {
+		if a.prefix == "" && a.local == local {
+			return true
+		}
+	}
+	return false
+}
+
+// childElements returns the child elements named space:local.
+func (e *element) childElements(space, local string) []*element {
+	var out []*element
+	for _, n := range e.children {
+		if c, ok := n.(*element); ok && c.is(space, local) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// child returns the first child element named space:local, or nil.
+func (e *element) child(space, local string) *element {
+	if cs := e.childElements(space, local); len(cs) > 0 {
+		return cs[0]
+	}
+	return nil
+}
+
+// text returns the element's own text, trimmed.
+func (e *element) text() string {
+	var b strings.Builder
+	for _, n := range e.children {
+		if t, ok := n.(charData); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// walk calls fn for e and every element below it, in document order.
+func (e *element) walk(fn func(*element)) {
+	fn(e)
+	for _, n := range e.children {
+		if c, ok := n.(*element); ok {
+			c.walk(fn)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	BindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	BindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	NameIDFormatEmail       = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	NameIDFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+)
+
+// ErrInvalidMetadata means IdP metadata could not be used.
+var ErrInvalidMetadata = errors.New("saml: invalid identity provider metadata")
+
+// IdentityProvider is what the SP needs to know about an IdP, read from its
+// metadata.
+type IdentityProvider struct {
+	EntityID string
+	// SSOURL receives AuthnRequests over the HTTP-Redirect binding
+	SSOURL string
+	// Certificates are the keys the IdP signs with; there are several
+	// while it rolls over to a new one
+	Certificates []*x509.Certificate
+}
+
+// ParseIdPMetadata reads an EntityDescriptor, or the first IdP of an
+// EntitiesDescriptor. The metadata is trusted as given: it is pasted in by
+// a superadmin, not fetched.
+func ParseIdPMetadata(data []byte) (*IdentityProvider, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	var entity, idpsso *element
+	root.walk(func(e *element) {
+		if idpsso == nil && e.is(nsMetadata, "IDPSSODescriptor") && e.parent != nil && e.parent.is(nsMetadata, "EntityDescriptor") {
+			entity, idpsso = e.parent, e
+		}
+	})
+	if idpsso == nil {
+		return nil, fmt.Errorf("%w: no IDPSSODescriptor", ErrInvalidMetadata)
+	}
+	idp := &IdentityProvider{EntityID: entity.attr("entityID")}
+	if idp.EntityID == "" {
+		return nil, fmt.Errorf("%w: no entityID", ErrInvalidMetadata)
+	}
+	for _, sso := range idpsso.childElements(nsMetadata, "SingleSignOnService") {
+		if sso.attr("Binding") == BindingHTTPRedirect {
+			idp.SSOURL = sso.attr("Location")
+			break
+		}
+	}
+	if idp.SSOURL == "" {
+		return nil, fmt.Errorf("%w: no SingleSignOnService with the HTTP-Redirect binding", ErrInvalidMetadata)
+	}
+	for _, kd := range idpsso.childElements(nsMetadata, "KeyDescriptor") {
+		if use := kd.attr("use"); use != "" && use != "signing" {
+			continue
+		}
+		kd.walk(func(e *element) {
+			if !e.is(nsDSig, "X509Certificate") {
+				return
+			}
+			if der, err := decodeBase64(e.text()); err == nil {
+				if cert, err := x509.ParseCertificate(der); err == nil {
+					idp.Certificates = append(idp.Certificates, cert)
+				}
+			}
+		})
+	}
+	if len(idp.Certificates) == 0 {
+		return nil, fmt.Errorf("%w: no signing certificate", ErrInvalidMetadata)
+	}
+	return idp, nil
+}
+
+// CertificatesExpireAt is when the last of the IdP's certificates expires.
+// Expiry is not enforced, as IdPs commonly sign with long-expired
+// self-signed certificates, but it is worth showing to whoever configures
+// the tenant.
+func (idp *IdentityProvider) CertificatesExpireAt() time.Time {
+	var last time.Time
+	for _, c := range idp.Certificates {
+		if c.NotAfter.After(last) {
+			last = c.NotAfter
+		}
+	}
+	return last
+}
+
+// ServiceProvider is the portal as one tenant's IdP sees it.
+type ServiceProvider struct {
+	EntityID string
+	// ACSURL is the assertion consumer service, where the IdP posts
+	// responses
+	ACSURL string
+}
+
+type spMetadata struct {
+	XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID string   `xml:"entityID,attr"`
+	SP       struct {
+		AuthnRequestsSigned  bool     `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned bool     `xml:"WantAssertionsSigned,attr"`
+		Protocols            string   `xml:"protocolSupportEnumeration,attr"`
+		NameIDFormats        []string `xml:"NameIDFormat"`
+		ACS                  struct {
+			Binding   string `xml:"Binding,attr"`
+			Location  string `xml:"Location,attr"`
+			Index     int    `xml:"index,attr"`
+			IsDefault bool   `xml:"isDefault,attr"`
+		} `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+// Metadata is the SP metadata to register with the IdP.
+func (sp ServiceProvider) Metadata() []byte {
+	var m spMetadata
+	m.EntityID = sp.EntityID
+	m.SP.WantAssertionsSigned = true
+	m.SP.Protocols = nsProtocol
+	m.SP.NameIDFormats = []string{NameIDFormatEmail, NameIDFormatUnspecified}
+	m.SP.ACS.Binding = BindingHTTPPost
+	m.SP.ACS.Location = sp.ACSURL
+	m.SP.ACS.IsDefault = true
+	out, _ := xml.MarshalIndent(m, "", "  ")
+	return append([]byte(xml.Header), out...)
+}
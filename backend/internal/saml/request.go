@@ -0,0 +1,60 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"time"
+)
+
+// NewRequestID returns a random ID for an AuthnRequest. IDs are xs:ID, which
+// may not start with a digit.
+func NewRequestID() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "_" + hex.EncodeToString(b)
+}
+
+// AuthnRequestURL returns the IdP URL that asks the user to sign in, with
+// an unsigned AuthnRequest over the HTTP-Redirect binding. The IdP posts its
+// response back to the ACS along with relayState.
+func (sp ServiceProvider) AuthnRequestURL(idp *IdentityProvider, requestID, relayState string, now time.Time) (string, error) {
+	req := `<samlp:AuthnRequest xmlns:samlp="` + nsProtocol + `" xmlns:saml="` + nsAssertion + `"` +
+		` ID="` + attrEscaper.Replace(requestID) + `" Version="2.0"` +
+		` IssueInstant="` + now.UTC().Format(timeFormat) + `"` +
+		` Destination="` + attrEscaper.Replace(idp.SSOURL) + `"` +
+		` AssertionConsumerServiceURL="` + attrEscaper.Replace(sp.ACSURL) + `"` +
+		` ProtocolBinding="` + BindingHTTPPost + `">` +
+		`<saml:Issuer>` + textEscaper.Replace(sp.EntityID) + `</saml:Issuer>` +
+		`<samlp:NameIDPolicy Format="` + NameIDFormatUnspecified + `" AllowCreate="true"/>` +
+		`</samlp:AuthnRequest>`
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write([]byte(req)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(idp.SSOURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		q.Set("RelayState", relayState)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
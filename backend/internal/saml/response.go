@@ -0,0 +1,262 @@
+// Package saml is the service provider side of SAML 2.0 web browser SSO:
+// IdP metadata, AuthnRequests over the HTTP-Redirect binding, and
+// validation of signed responses posted to the assertion consumer service.
+// Signatures are checked with exclusive canonicalization only, which every
+// IdP we have met supports; SHA-1 is not accepted.
+package saml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	timeFormat = "2006-01-02T15:04:05Z"
+
+	statusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	confirmationBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	// maxResponseSize bounds a posted response before it is parsed
+	maxResponseSize = 512 << 10
+	// clockSkew is how far the IdP's clock may be off from ours
+	clockSkew = 2 * time.Minute
+)
+
+var (
+	// ErrInvalidResponse means a response is malformed or not meant for
+	// this SP, or its assertion is outside its validity window.
+	ErrInvalidResponse = errors.New("saml: invalid response")
+	// ErrEncryptedAssertion means the IdP encrypts assertions, which the
+	// portal does not support; the IdP has to be told not to for this SP.
+	ErrEncryptedAssertion = errors.New("saml: encrypted assertions are not supported")
+)
+
+// StatusError is a response in which the IdP reports that the login did not
+// succeed, e.g. because the user cancelled it.
+type StatusError struct {
+	Code    string
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("saml: identity provider returned status %s %s", e.Code, e.Message)
+}
+
+// Assertion is the verified statement of who signed in.
+type Assertion struct {
+	Issuer       string
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	// Attributes are keyed by attribute Name and, where given,
+	// FriendlyName
+	Attributes map[string][]string
+}
+
+// Attribute returns the first value of the first of names present.
+// Attribute names are matched ignoring case.
+func (a *Assertion) Attribute(names ...string) string {
+	if vs := a.AttributeValues(names...); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// AttributeValues returns the values of the first of names present.
+func (a *Assertion) AttributeValues(names ...string) []string {
+	for _, name := range names {
+		for k, vs := range a.Attributes {
+			if strings.EqualFold(k, name) && len(vs) > 0 {
+				return vs
+			}
+		}
+	}
+	return nil
+}
+
+// ParseResponse validates a base64 SAMLResponse posted to the ACS in answer
+// to the AuthnRequest requestID and returns its assertion. Either the
+// assertion or the whole response must be signed by the IdP; everything
+// returned is read from the signed part.
+func (sp ServiceProvider) ParseResponse(encoded string, idp *IdentityProvider, requestID string, now time.Time) (*Assertion, error) {
+	if len(encoded) > maxResponseSize {
+		return nil, fmt.Errorf("%w: too large", ErrInvalidResponse)
+	}
+	raw, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not base64", ErrInvalidResponse)
+	}
+	resp, err := parseXML(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.is(nsProtocol, "Response") {
+		return nil, fmt.Errorf("%w: not a Response", ErrInvalidResponse)
+	}
+	// Signature references are by ID; a second element with the same ID is
+	// how signed content gets swapped for unsigned content
+	ids := map[string]bool{}
+	var dup bool
+	resp.walk(func(e *element) {
+		if id := e.attr("ID"); id != "" {
+			dup = dup || ids[id]
+			ids[id] = true
+		}
+	})
+	if dup {
+		return nil, fmt.Errorf("%w: duplicate IDs", ErrInvalidResponse)
+	}
+
+	if dest := resp.attr("Destination"); dest != "" && dest != sp.ACSURL {
+		return nil, fmt.Errorf("%w: destination %q", ErrInvalidResponse, dest)
+	}
+	if irt := resp.attr("InResponseTo"); irt != "" && irt != requestID {
+		return nil, fmt.Errorf("%w: not in response to this request", ErrInvalidResponse)
+	}
+	if iss := resp.child(nsAssertion, "Issuer"); iss != nil && iss.text() != idp.EntityID {
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidResponse, iss.text())
+	}
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	responseSigned := false
+	if err := verifySignature(resp, idp.Certificates); err == nil {
+		responseSigned = true
+	} else if !errors.Is(err, ErrNotSigned) {
+		return nil, err
+	}
+	if len(resp.childElements(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, ErrEncryptedAssertion
+	}
+	assertions := resp.childElements(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: expected one assertion, found %d", ErrInvalidResponse, len(assertions))
+	}
+	a := assertions[0]
+	if err := verifySignature(a, idp.Certificates); errors.Is(err, ErrNotSigned) {
+		if !responseSigned {
+			return nil, ErrNotSigned
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return sp.readAssertion(a, idp, requestID, now)
+}
+
+func checkStatus(resp *element) error {
+	st := resp.child(nsProtocol, "Status")
+	if st == nil {
+		return fmt.Errorf("%w: no status", ErrInvalidResponse)
+	}
+	code := st.child(nsProtocol, "StatusCode")
+	if code == nil {
+		return fmt.Errorf("%w: no status code", ErrInvalidResponse)
+	}
+	if code.attr("Value") == statusSuccess {
+		return nil
+	}
+	serr := &StatusError{Code: code.attr("Value")}
+	// The second-level code says why, e.g. AuthnFailed
+	if sub := code.child(nsProtocol, "StatusCode"); sub != nil {
+		serr.Code = sub.attr("Value")
+	}
+	if msg := st.child(nsProtocol, "StatusMessage"); msg != nil {
+		serr.Message = msg.text()
+	}
+	return serr
+}
+
+// readAssertion checks a signed assertion's conditions and reads it.
+func (sp ServiceProvider) readAssertion(a *element, idp *IdentityProvider, requestID string, now time.Time) (*Assertion, error) {
+	out := &Assertion{Attributes: map[string][]string{}}
+	if iss := a.child(nsAssertion, "Issuer"); iss != nil {
+		out.Issuer = iss.text()
+	}
+	if out.Issuer != idp.EntityID {
+		return nil, fmt.Errorf("%w: assertion issuer %q", ErrInvalidResponse, out.Issuer)
+	}
+
+	subject := a.child(nsAssertion, "Subject")
+	if subject == nil {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidResponse)
+	}
+	if id := subject.child(nsAssertion, "NameID"); id != nil {
+		out.NameID, out.NameIDFormat = id.text(), id.attr("Format")
+	}
+	confirmed := false
+	for _, sc := range subject.childElements(nsAssertion, "SubjectConfirmation") {
+		if sc.attr("Method") != confirmationBearer {
+			continue
+		}
+		data := sc.child(nsAssertion, "SubjectConfirmationData")
+		if data == nil || data.attr("Recipient") != sp.ACSURL || data.attr("InResponseTo") != requestID {
+			continue
+		}
+		if notOnOrAfter, err := parseTime(data.attr("NotOnOrAfter")); err != nil || !now.Before(notOnOrAfter.Add(clockSkew)) {
+			continue
+		}
+		confirmed = true
+		break
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("%w: no bearer confirmation for this request", ErrInvalidResponse)
+	}
+
+	cond := a.child(nsAssertion, "Conditions")
+	if cond == nil {
+		return nil, fmt.Errorf("%w: no conditions", ErrInvalidResponse)
+	}
+	if cond.hasAttr("NotBefore") {
+		t, err := parseTime(cond.attr("NotBefore"))
+		if err != nil || now.Add(clockSkew).Before(t) {
+			return nil, fmt.Errorf("%w: assertion not yet valid", ErrInvalidResponse)
+		}
+	}
+	if cond.hasAttr("NotOnOrAfter") {
+		t, err := parseTime(cond.attr("NotOnOrAfter"))
+		if err != nil || !now.Before(t.Add(clockSkew)) {
+			return nil, fmt.Errorf("%w: assertion expired", ErrInvalidResponse)
+		}
+	}
+	restrictions := cond.childElements(nsAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, fmt.Errorf("%w: no audience restriction", ErrInvalidResponse)
+	}
+	// Every restriction must name us
+	for _, r := range restrictions {
+		ok := false
+		for _, aud := range r.childElements(nsAssertion, "Audience") {
+			ok = ok || aud.text() == sp.EntityID
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: assertion is for another audience", ErrInvalidResponse)
+		}
+	}
+
+	if authn := a.child(nsAssertion, "AuthnStatement"); authn != nil {
+		out.SessionIndex = authn.attr("SessionIndex")
+	}
+	for _, st := range a.childElements(nsAssertion, "AttributeStatement") {
+		for _, at := range st.childElements(nsAssertion, "Attribute") {
+			var values []string
+			for _, v := range at.childElements(nsAssertion, "AttributeValue") {
+				if t := v.text(); t != "" {
+					values = append(values, t)
+				}
+			}
+			for _, key := range []string{at.attr("Name"), at.attr("FriendlyName")} {
+				if key != "" {
+					out.Attributes[key] = append(out.Attributes[key], values...)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,187 @@
+package saml_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml/samltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIdPMetadata(t *testing.T) {
+	p, err := samltest.New("https://idp.example.edu/saml")
+	require.NoError(t, err)
+
+	idp, err := saml.ParseIdPMetadata(p.Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example.edu/saml/metadata", idp.EntityID)
+	assert.Equal(t, "https://idp.example.edu/saml/sso", idp.SSOURL)
+	require.Len(t, idp.Certificates, 1)
+	assert.True(t, idp.CertificatesExpireAt().After(time.Now()))
+
+	_, err = saml.ParseIdPMetadata([]byte(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="x"/>`))
+	assert.ErrorIs(t, err, saml.ErrInvalidMetadata)
+	_, err = saml.ParseIdPMetadata([]byte(`not xml`))
+	assert.ErrorIs(t, err, saml.ErrInvalidMetadata)
+}
+
+func TestServiceProvider_Metadata(t *testing.T) {
+	sp := saml.ServiceProvider{EntityID: "https://portal.example/api/auth/saml/metadata?tenant=kaznmu", ACSURL: "https://portal.example/api/auth/saml/acs?tenant=kaznmu"}
+	md := string(sp.Metadata())
+	assert.Contains(t, md, `entityID="https://portal.example/api/auth/saml/metadata?tenant=kaznmu"`)
+	assert.Contains(t, md, `Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://portal.example/api/auth/saml/acs?tenant=kaznmu"`)
+	assert.Contains(t, md, `WantAssertionsSigned="true"`)
+}
+
+func TestAuthnRequestURL(t *testing.T) {
+	sp := saml.ServiceProvider{EntityID: "https://portal.example/sp", ACSURL: "https://portal.example/acs"}
+	idp := &saml.IdentityProvider{EntityID: "https://idp.example", SSOURL: "https://idp.example/sso?realm=uni"}
+
+	u, err := sp.AuthnRequestURL(idp, "_abc", "_abc", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	parsed, err := url.Parse(u)
+	require.NoError(t, err)
+	assert.Equal(t, "uni", parsed.Query().Get("realm"))
+	assert.Equal(t, "_abc", parsed.Query().Get("RelayState"))
+
+	raw, err := base64.StdEncoding.DecodeString(parsed.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+	xml, err := io.ReadAll(flate.NewReader(bytes.NewReader(raw)))
+	require.NoError(t, err)
+	assert.Contains(t, string(xml), `ID="_abc"`)
+	assert.Contains(t, string(xml), `IssueInstant="2026-03-01T10:00:00Z"`)
+	assert.Contains(t, string(xml), `AssertionConsumerServiceURL="https://portal.example/acs"`)
+	assert.Contains(t, string(xml), `<saml:Issuer>https://portal.example/sp</saml:Issuer>`)
+}
+
+func TestParseResponse(t *testing.T) {
+	p, err := samltest.New("https://idp.example.edu/saml")
+	require.NoError(t, err)
+	idp, err := saml.ParseIdPMetadata(p.Metadata())
+	require.NoError(t, err)
+	sp := saml.ServiceProvider{EntityID: "https://portal.example/sp", ACSURL: "https://portal.example/acs"}
+
+	// respond runs a login at the IdP and returns the decoded response
+	respond := func(t *testing.T, requestID string) string {
+		t.Helper()
+		authURL, err := sp.AuthnRequestURL(idp, requestID, requestID, time.Now())
+		require.NoError(t, err)
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		acs, form, err := p.Respond(u.Query())
+		require.NoError(t, err)
+		assert.Equal(t, sp.ACSURL, acs)
+		assert.Equal(t, requestID, form.Get("RelayState"))
+		raw, err := base64.StdEncoding.DecodeString(form.Get("SAMLResponse"))
+		require.NoError(t, err)
+		return string(raw)
+	}
+	parse := func(doc, requestID string) (*saml.Assertion, error) {
+		return sp.ParseResponse(base64.StdEncoding.EncodeToString([]byte(doc)), idp, requestID, time.Now())
+	}
+
+	t.Run("Signed assertion", func(t *testing.T) {
+		a, err := parse(respond(t, "_r1"), "_r1")
+		require.NoError(t, err)
+		assert.Equal(t, idp.EntityID, a.Issuer)
+		assert.Equal(t, "student@example.edu", a.NameID)
+		assert.Equal(t, saml.NameIDFormatEmail, a.NameIDFormat)
+		assert.NotEmpty(t, a.SessionIndex)
+		assert.Equal(t, "Mock", a.Attribute("givenName", samltest.AttrGivenName))
+		assert.Equal(t, []string{"student"}, a.AttributeValues(strings.ToUpper(samltest.AttrAffiliation)))
+	})
+
+	t.Run("Signed response", func(t *testing.T) {
+		p.SignResponse = true
+		defer func() { p.SignResponse = false }()
+		doc := respond(t, "_r2")
+		a, err := parse(doc, "_r2")
+		require.NoError(t, err)
+		assert.Equal(t, "student@example.edu", a.NameID)
+
+		_, err = parse(strings.Replace(doc, "student@example.edu", "admin@example.edu", 1), "_r2")
+		assert.ErrorIs(t, err, saml.ErrBadSignature)
+	})
+
+	t.Run("Tampered assertion", func(t *testing.T) {
+		doc := respond(t, "_r3")
+		_, err := parse(strings.Replace(doc, ">student<", ">admin<", 1), "_r3")
+		assert.ErrorIs(t, err, saml.ErrBadSignature)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		doc := respond(t, "_r4")
+		start := strings.Index(doc, "<ds:Signature")
+		end := strings.Index(doc, "</ds:Signature>") + len("</ds:Signature>")
+		_, err := parse(doc[:start]+doc[end:], "_r4")
+		assert.ErrorIs(t, err, saml.ErrNotSigned)
+	})
+
+	t.Run("Signed by someone else", func(t *testing.T) {
+		other, err := samltest.New("https://idp.example.edu/saml")
+		require.NoError(t, err)
+		u, err := sp.AuthnRequestURL(idp, "_r5", "", time.Now())
+		require.NoError(t, err)
+		parsed, _ := url.Parse(u)
+		_, form, err := other.Respond(parsed.Query())
+		require.NoError(t, err)
+		_, err = sp.ParseResponse(form.Get("SAMLResponse"), idp, "_r5", time.Now())
+		assert.ErrorIs(t, err, saml.ErrBadSignature)
+	})
+
+	t.Run("Wrapped assertion", func(t *testing.T) {
+		// A signed assertion moved aside for an unsigned forged one
+		doc := respond(t, "_r6")
+		start := strings.Index(doc, "<saml:Assertion")
+		end := strings.Index(doc, "</saml:Assertion>") + len("</saml:Assertion>")
+		signed := doc[start:end]
+		forged := strings.Replace(signed, "student@example.edu", "admin@example.edu", -1)
+		forged = forged[:strings.Index(forged, "<ds:Signature")] + forged[strings.Index(forged, "</ds:Signature>")+len("</ds:Signature>"):]
+
+		_, err := parse(doc[:start]+forged+signed+doc[end:], "_r6")
+		assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+
+		extensions := `<samlp:Extensions>` + signed + `</samlp:Extensions>`
+		forged = strings.Replace(forged, `ID="`, `ID="x`, 1)
+		_, err = parse(doc[:start]+extensions+forged+doc[end:], "_r6")
+		assert.ErrorIs(t, err, saml.ErrNotSigned)
+	})
+
+	t.Run("Answer to another request", func(t *testing.T) {
+		_, err := parse(respond(t, "_r7"), "_other")
+		assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+	})
+
+	t.Run("Another audience", func(t *testing.T) {
+		p.Assertion = func(a string) string {
+			return strings.Replace(a, "<saml:Audience>https://portal.example/sp<", "<saml:Audience>https://elsewhere.example<", 1)
+		}
+		defer func() { p.Assertion = nil }()
+		_, err := parse(respond(t, "_r8"), "_r8")
+		assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		doc := respond(t, "_r9")
+		_, err := sp.ParseResponse(base64.StdEncoding.EncodeToString([]byte(doc)), idp, "_r9", time.Now().Add(10*time.Minute))
+		assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+	})
+
+	t.Run("Login failed at the IdP", func(t *testing.T) {
+		p.Status = "urn:oasis:names:tc:SAML:2.0:status:AuthnFailed"
+		defer func() { p.Status = "" }()
+		_, err := parse(respond(t, "_r10"), "_r10")
+		var serr *saml.StatusError
+		require.True(t, errors.As(err, &serr))
+		assert.Equal(t, "urn:oasis:names:tc:SAML:2.0:status:AuthnFailed", serr.Code)
+	})
+}
@@ -0,0 +1,267 @@
+// Package samltest is a SAML identity provider for tests and local
+// development. It signs in a fixed user without asking for credentials and
+// answers AuthnRequests with signed responses, like a real IdP would.
+package samltest
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml"
+)
+
+// Common attribute names, as eduPerson IdPs send them.
+const (
+	AttrMail        = "urn:oid:0.9.2342.19200300.100.1.3"
+	AttrGivenName   = "urn:oid:2.5.4.42"
+	AttrSurname     = "urn:oid:2.5.4.4"
+	AttrAffiliation = "urn:oid:1.3.6.1.4.1.5923.1.1.1.1"
+)
+
+// User is who the provider signs in.
+type User struct {
+	NameID     string
+	Attributes map[string][]string
+}
+
+// Provider is the mock identity provider. Its fields may be changed between
+// logins.
+type Provider struct {
+	// BaseURL is where the provider is served; its entity ID is
+	// BaseURL/metadata and it takes AuthnRequests at BaseURL/sso
+	BaseURL string
+	User    User
+	// Status, when set, is returned instead of a successful login, e.g.
+	// "urn:oasis:names:tc:SAML:2.0:status:AuthnFailed"
+	Status string
+	// SignResponse signs the whole response rather than the assertion
+	SignResponse bool
+	// Assertion, when set, may change the assertion XML before it is signed
+	Assertion func(string) string
+
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+	mux  *http.ServeMux
+}
+
+// New returns a provider served at baseURL.
+func New(baseURL string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "samltest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{
+		BaseURL: baseURL,
+		User: User{
+			NameID: "student@example.edu",
+			Attributes: map[string][]string{
+				AttrMail:        {"student@example.edu"},
+				AttrGivenName:   {"Mock"},
+				AttrSurname:     {"Student"},
+				AttrAffiliation: {"student"},
+			},
+		},
+		key:  key,
+		cert: cert,
+		mux:  http.NewServeMux(),
+	}
+	p.mux.HandleFunc("GET /metadata", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/samlmetadata+xml")
+		_, _ = w.Write(p.Metadata())
+	})
+	p.mux.HandleFunc("GET /sso", p.sso)
+	return p, nil
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// Server is a provider listening on a local test server.
+type Server struct {
+	*Provider
+	srv *httptest.Server
+}
+
+// NewServer starts a provider on a local port.
+func NewServer() *Server {
+	p, err := New("")
+	if err != nil {
+		panic(err)
+	}
+	srv := httptest.NewServer(p)
+	p.BaseURL = srv.URL
+	return &Server{Provider: p, srv: srv}
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// EntityID is the provider's SAML entity ID.
+func (p *Provider) EntityID() string {
+	return p.BaseURL + "/metadata"
+}
+
+// Metadata is the provider's IdP metadata.
+func (p *Provider) Metadata() []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + esc(p.EntityID()) + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(p.cert.Raw) + `</ds:X509Certificate></ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:NameIDFormat>urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress</md:NameIDFormat>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="` + esc(p.BaseURL+"/sso") + `"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>
+`)
+}
+
+type authnRequest struct {
+	ID     string `xml:"ID,attr"`
+	ACSURL string `xml:"AssertionConsumerServiceURL,attr"`
+	Issuer string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+}
+
+// Respond answers the query of an HTTP-Redirect AuthnRequest. It returns
+// where the browser has to post the form, and the form.
+func (p *Provider) Respond(query url.Values) (acsURL string, form url.Values, err error) {
+	raw, err := base64.StdEncoding.DecodeString(query.Get("SAMLRequest"))
+	if err != nil {
+		return "", nil, fmt.Errorf("samltest: bad SAMLRequest: %w", err)
+	}
+	inflated, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(raw)), 1<<20))
+	if err != nil {
+		return "", nil, fmt.Errorf("samltest: bad SAMLRequest: %w", err)
+	}
+	var req authnRequest
+	if err := xml.Unmarshal(inflated, &req); err != nil {
+		return "", nil, fmt.Errorf("samltest: bad SAMLRequest: %w", err)
+	}
+	if req.ID == "" || req.ACSURL == "" || req.Issuer == "" {
+		return "", nil, errors.New("samltest: AuthnRequest lacks ID, ACS URL or issuer")
+	}
+
+	resp, err := p.response(req, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	form = url.Values{"SAMLResponse": {base64.StdEncoding.EncodeToString(resp)}}
+	if rs := query.Get("RelayState"); rs != "" {
+		form.Set("RelayState", rs)
+	}
+	return req.ACSURL, form, nil
+}
+
+func (p *Provider) response(req authnRequest, now time.Time) ([]byte, error) {
+	responseID, assertionID := saml.NewRequestID(), saml.NewRequestID()
+	instant := now.UTC().Format(time.RFC3339)
+	status := `<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>`
+	if p.Status != "" {
+		status = `<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Responder">` +
+			`<samlp:StatusCode Value="` + esc(p.Status) + `"/></samlp:StatusCode></samlp:Status>`
+	}
+
+	var assertion string
+	if p.Status == "" {
+		var attrs strings.Builder
+		for name, values := range p.User.Attributes {
+			attrs.WriteString(`<saml:Attribute Name="` + esc(name) + `" NameFormat="urn:oasis:names:tc:SAML:2.0:attrname-format:uri">`)
+			for _, v := range values {
+				attrs.WriteString(`<saml:AttributeValue xsi:type="xs:string">` + esc(v) + `</saml:AttributeValue>`)
+			}
+			attrs.WriteString(`</saml:Attribute>`)
+		}
+		assertion = `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"` +
+			` ID="` + assertionID + `" Version="2.0" IssueInstant="` + instant + `">` +
+			`<saml:Issuer>` + esc(p.EntityID()) + `</saml:Issuer>` +
+			`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">` + esc(p.User.NameID) + `</saml:NameID>` +
+			`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">` +
+			`<saml:SubjectConfirmationData InResponseTo="` + esc(req.ID) + `" Recipient="` + esc(req.ACSURL) + `" NotOnOrAfter="` + now.Add(5*time.Minute).UTC().Format(time.RFC3339) + `"/>` +
+			`</saml:SubjectConfirmation></saml:Subject>` +
+			`<saml:Conditions NotBefore="` + now.Add(-time.Minute).UTC().Format(time.RFC3339) + `" NotOnOrAfter="` + now.Add(5*time.Minute).UTC().Format(time.RFC3339) + `">` +
+			`<saml:AudienceRestriction><saml:Audience>` + esc(req.Issuer) + `</saml:Audience></saml:AudienceRestriction></saml:Conditions>` +
+			`<saml:AuthnStatement AuthnInstant="` + instant + `" SessionIndex="` + assertionID + `">` +
+			`<saml:AuthnContext><saml:AuthnContextClassRef>urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport</saml:AuthnContextClassRef></saml:AuthnContext></saml:AuthnStatement>` +
+			`<saml:AttributeStatement>` + attrs.String() + `</saml:AttributeStatement>` +
+			`</saml:Assertion>`
+		if p.Assertion != nil {
+			assertion = p.Assertion(assertion)
+		}
+		if !p.SignResponse {
+			signed, err := saml.Sign([]byte(assertion), assertionID, p.key, p.cert)
+			if err != nil {
+				return nil, err
+			}
+			assertion = string(signed)
+		}
+	}
+
+	resp := []byte(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"` +
+		` ID="` + responseID + `" Version="2.0" IssueInstant="` + instant + `" Destination="` + esc(req.ACSURL) + `" InResponseTo="` + esc(req.ID) + `">` +
+		`<saml:Issuer>` + esc(p.EntityID()) + `</saml:Issuer>` + status + assertion + `</samlp:Response>`)
+	if p.SignResponse {
+		return saml.Sign(resp, responseID, p.key, p.cert)
+	}
+	return resp, nil
+}
+
+var postForm = template.Must(template.New("post").Parse(`<!DOCTYPE html>
+<html><body onload="document.forms[0].submit()">
+<form method="post" action="{{.URL}}">
+{{range $k, $v := .Form}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<noscript><button type="submit">Continue</button></noscript>
+</form></body></html>
+`))
+
+// sso signs the user in at once and posts the response to the SP with the
+// HTTP-POST binding.
+func (p *Provider) sso(w http.ResponseWriter, r *http.Request) {
+	acsURL, form, err := p.Respond(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = postForm.Execute(w, map[string]interface{}{"URL": acsURL, "Form": form})
+}
+
+func esc(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
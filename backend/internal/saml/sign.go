@@ -0,0 +1,69 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// Sign adds an enveloped rsa-sha256 signature to the element with the given
+// ID, right after its Issuer as the SAML schema wants it, and returns the
+// document in canonical form. The portal itself signs nothing; the test
+// identity provider uses this to produce responses like a real one.
+func Sign(doc []byte, id string, key *rsa.PrivateKey, cert *x509.Certificate) ([]byte, error) {
+	root, err := parseXML(doc)
+	if err != nil {
+		return nil, err
+	}
+	var target *element
+	root.walk(func(e *element) {
+		if e.attr("ID") == id && target == nil {
+			target = e
+		}
+	})
+	if target == nil {
+		return nil, fmt.Errorf("saml: no element with ID %q", id)
+	}
+
+	digest := crypto.SHA256.New()
+	digest.Write(canonicalize(target, nil, nil))
+	signedInfo := `<ds:SignedInfo xmlns:ds="` + nsDSig + `">` +
+		`<ds:CanonicalizationMethod Algorithm="` + nsExcC14N + `"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>` +
+		`<ds:Reference URI="#` + attrEscaper.Replace(id) + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="` + algEnveloped + `"></ds:Transform>` +
+		`<ds:Transform Algorithm="` + nsExcC14N + `"></ds:Transform>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest.Sum(nil)) + `</ds:DigestValue></ds:Reference></ds:SignedInfo>`
+	si, err := parseXML([]byte(signedInfo))
+	if err != nil {
+		return nil, err
+	}
+	hashed := crypto.SHA256.New()
+	hashed.Write(canonicalize(si, nil, nil))
+	value, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := parseXML([]byte(`<ds:Signature xmlns:ds="` + nsDSig + `">` + signedInfo +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(value) + `</ds:SignatureValue>` +
+		`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(cert.Raw) +
+		`</ds:X509Certificate></ds:X509Data></ds:KeyInfo></ds:Signature>`))
+	if err != nil {
+		return nil, err
+	}
+	sig.parent = target
+	at := 0
+	for i, n := range target.children {
+		if c, ok := n.(*element); ok && c.local == "Issuer" {
+			at = i + 1
+			break
+		}
+	}
+	target.children = append(target.children[:at], append([]node{sig}, target.children[at:]...)...)
+	return canonicalize(root, nil, nil), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
@@ -17,6 +18,8 @@ type AdminService struct {
 	pb      *pb.Manager
 	cfg     config.AppConfig
 	storage StorageClient
+	notifs  *NotificationService
+	journey *JourneyService
 }
 
 func NewAdminService(repo repository.AdminRepository, pbm *pb.Manager, cfg config.AppConfig, storage StorageClient) *AdminService {
@@ -28,13 +31,33 @@ func NewAdminService(repo repository.AdminRepository, pbm *pb.Manager, cfg confi
 	}
 }
 
+// SetNotifications routes notification events through the live stream.
+func (s *AdminService) SetNotifications(notifs *NotificationService) {
+	s.notifs = notifs
+}
+
+// SetJourney routes nodes closed by document review through the journey's
+// state transitions, so who_can_complete and outcomes apply to them.
+func (s *AdminService) SetJourney(journey *JourneyService) {
+	s.journey = journey
+}
+
+// manager returns the playbook resolved for the request's tenant, falling
+// back to the one the service was built with.
+func (s *AdminService) manager(ctx context.Context) *pb.Manager {
+	if m, ok := pb.FromContext(ctx); ok {
+		return m
+	}
+	return s.pb
+}
+
 func (s *AdminService) ListStudentProgress(ctx context.Context, tenantID string) ([]models.StudentProgressSummary, error) {
-	summaries, err := s.repo.ListStudentProgress(ctx, tenantID, s.pb.VersionID)
+	summaries, err := s.repo.ListStudentProgress(ctx, tenantID, s.manager(ctx).VersionID)
 	if err != nil {
 		return nil, err
 	}
 	
-	totalNodes := len(s.pb.Nodes)
+	totalNodes := len(s.manager(ctx).Nodes)
 	for i := range summaries {
 		summaries[i].TotalNodes = totalNodes
 		if totalNodes > 0 {
@@ -63,15 +86,15 @@ func (s *AdminService) MonitorStudents(ctx context.Context, filter models.Filter
 	advisors, _ := s.repo.GetAdvisorsForStudents(ctx, ids)
 	doneCounts, _ := s.repo.GetDoneCountsForStudents(ctx, ids)
 	lastUpdates, _ := s.repo.GetLastUpdatesForStudents(ctx, ids)
-	rpRequired, _ := s.repo.GetRPRequiredForStudents(ctx, ids)
+	rpRequired := s.rpRequiredForStudents(ctx, ids)
 
 	// DEBUG: Log playbook info
-	fmt.Printf("[MonitorStudents] PlaybookVersionID=%s, TotalNodes=%d, StudentCount=%d\n", s.pb.VersionID, len(s.pb.Nodes), len(rows))
+	fmt.Printf("[MonitorStudents] PlaybookVersionID=%s, TotalNodes=%d, StudentCount=%d\n", s.manager(ctx).VersionID, len(s.manager(ctx).Nodes), len(rows))
 	fmt.Printf("[MonitorStudents] DoneCounts map: %+v\n", doneCounts)
 
 	// 3. Merge and Compute
-	totalNodes := len(s.pb.Nodes)
-	_, worldNodes := s.getWorlds()
+	totalNodes := len(s.manager(ctx).Nodes)
+	_, worldNodes := s.getWorlds(ctx)
 	w3Count := len(worldNodes["W3"])
 
 	enriched := make([]models.StudentMonitorRow, 0, len(rows))
@@ -116,7 +139,7 @@ func (s *AdminService) MonitorStudents(ctx context.Context, filter models.Filter
 			nodeID = *r.CurrentNodeID
 		}
 		if r.CurrentNodeID != nil && *r.CurrentNodeID != "" {
-			r.CurrentStage = s.pb.NodeWorldID(*r.CurrentNodeID)
+			r.CurrentStage = s.manager(ctx).NodeWorldID(*r.CurrentNodeID)
 		}
 		if r.CurrentStage == "" {
 			r.CurrentStage = "W1" // Default to first stage
@@ -132,9 +155,35 @@ func (s *AdminService) MonitorStudents(ctx context.Context, filter models.Filter
 }
 
 // Helpers
-func (s *AdminService) getWorlds() ([]string, map[string][]string) {
+
+// rpConditionID is the playbook condition that gates the RP stage (world W3).
+const rpConditionID = "rp_required"
+
+// rpRequiredForStudents evaluates the playbook's rp_required condition against each student's profile.
+func (s *AdminService) rpRequiredForStudents(ctx context.Context, ids []string) map[string]bool {
+	out := make(map[string]bool, len(ids))
+	pbm := s.manager(ctx)
+	if pbm == nil {
+		return out
+	}
+	if _, ok := pbm.Conditions[rpConditionID]; !ok {
+		return out
+	}
+	profiles, err := s.repo.GetProfilesForStudents(ctx, ids)
+	if err != nil {
+		return out
+	}
+	for _, id := range ids {
+		required, _ := pbm.EvaluateCondition(rpConditionID, pb.NewEnv(profiles[id], nil))
+		out[id] = required
+	}
+	return out
+}
+
+func (s *AdminService) getWorlds(ctx context.Context) ([]string, map[string][]string) {
 	// Simplified logic to extract world nodes. Using playbook manager if available.
-	if s.pb == nil {
+	pbm := s.manager(ctx)
+	if pbm == nil {
 		return []string{}, map[string][]string{}
 	}
 	// Note: keys in NodeWorlds are NodeIDs, values are WorldIDs.
@@ -142,9 +191,9 @@ func (s *AdminService) getWorlds() ([]string, map[string][]string) {
 	out := make(map[string][]string)
 	// Hardcoded worlds order? Or pb.Raw parsing.
 	// For simplicty:
-	out["W1"] = s.pb.GetNodesByWorld("W1")
-	out["W2"] = s.pb.GetNodesByWorld("W2")
-	out["W3"] = s.pb.GetNodesByWorld("W3")
+	out["W1"] = pbm.GetNodesByWorld("W1")
+	out["W2"] = pbm.GetNodesByWorld("W2")
+	out["W3"] = pbm.GetNodesByWorld("W3")
 	return []string{"W1", "W2", "W3"}, out
 }
 
@@ -173,7 +222,7 @@ func (s *AdminService) MonitorAnalytics(ctx context.Context, filter models.Filte
 	if filter.RPRequired {
 		res.RPRequiredCount = len(ids)
 	} else {
-		rpMap, _ := s.repo.GetRPRequiredForStudents(ctx, ids)
+		rpMap := s.rpRequiredForStudents(ctx, ids)
 		count := 0
 		for _, v := range rpMap {
 			if v {
@@ -209,22 +258,22 @@ func (s *AdminService) MonitorAnalytics(ctx context.Context, filter models.Filte
 	}
 
 	// 3. Antiplag
-	if apCount, err := s.repo.GetAntiplagCount(ctx, ids, s.pb.VersionID); err == nil {
+	if apCount, err := s.repo.GetAntiplagCount(ctx, ids, s.manager(ctx).VersionID); err == nil {
 		res.AntiplagDonePercent = float64(apCount) * 100.0 / float64(len(ids))
 	}
 	
 	// 4. Bottleneck
 	startOfMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Now().Location())
-	if nid, cnt, err := s.repo.GetBottleneck(ctx, ids, s.pb.VersionID, startOfMonth); err == nil {
+	if nid, cnt, err := s.repo.GetBottleneck(ctx, ids, s.manager(ctx).VersionID, startOfMonth); err == nil {
 		res.BottleneckNodeID = nid
 		res.BottleneckCount = cnt
 	}
 
 	// 5. W2 Median
-	_, worlds := s.getWorlds()
+	_, worlds := s.getWorlds(ctx)
 	w2Nodes := worlds["W2"]
 	if len(w2Nodes) > 0 {
-		if durs, err := s.repo.GetW2Durations(ctx, ids, s.pb.VersionID, w2Nodes); err == nil && len(durs) > 0 {
+		if durs, err := s.repo.GetW2Durations(ctx, ids, s.manager(ctx).VersionID, w2Nodes); err == nil && len(durs) > 0 {
 			// Sort and median
 			// Bubble sort for small N or standard sort
 			for i := 1; i < len(durs); i++ {
@@ -264,7 +313,7 @@ func (s *AdminService) GetStudentDetails(ctx context.Context, studentID, tenantI
 	}
 	
 	// 3. RP
-	rpMap, _ := s.repo.GetRPRequiredForStudents(ctx, []string{studentID})
+	rpMap := s.rpRequiredForStudents(ctx, []string{studentID})
 	details.RPRequired = rpMap[studentID]
 	
 	// 4. Progress
@@ -274,9 +323,9 @@ func (s *AdminService) GetStudentDetails(ctx context.Context, studentID, tenantI
 	instances, _ := s.repo.GetStudentNodeInstances(ctx, studentID) // Returns map of latest instances
 	
 	// Computed logic
-	totalNodes := len(s.pb.Nodes)
+	totalNodes := len(s.manager(ctx).Nodes)
 	details.TotalNodes = totalNodes
-	_, worldNodes := s.getWorlds()
+	_, worldNodes := s.getWorlds(ctx)
 	w3Count := len(worldNodes["W3"])
 	
 	totalRequired := totalNodes
@@ -290,7 +339,7 @@ func (s *AdminService) GetStudentDetails(ctx context.Context, studentID, tenantI
 	var lastUpdate time.Time
 	
 	// DEBUG: Log instances info
-	fmt.Printf("[GetStudentDetails] studentID=%s, PlaybookVersionID=%s, InstancesCount=%d\n", studentID, s.pb.VersionID, len(instances))
+	fmt.Printf("[GetStudentDetails] studentID=%s, PlaybookVersionID=%s, InstancesCount=%d\n", studentID, s.manager(ctx).VersionID, len(instances))
 	for _, inst := range instances {
 		fmt.Printf("[GetStudentDetails]   Instance: NodeID=%s, State=%s, Version=%s\n", inst.NodeID, inst.State, inst.PlaybookVersionID)
 	}
@@ -304,7 +353,7 @@ func (s *AdminService) GetStudentDetails(ctx context.Context, studentID, tenantI
 		// Or just count state='done' and version match?
 		// Handler line 585: `WHERE user_id=$1 AND playbook_version_id=$2 AND state='done'`
 		// So stats specific to active version.
-		if inst.PlaybookVersionID == s.pb.VersionID {
+		if inst.PlaybookVersionID == s.manager(ctx).VersionID {
 			if inst.State == "done" {
 				doneCount++
 			}
@@ -325,7 +374,7 @@ func (s *AdminService) GetStudentDetails(ctx context.Context, studentID, tenantI
 	}
 	
 	// Stage logic
-	stage := s.pb.NodeWorldID(lastNodeID)
+	stage := s.manager(ctx).NodeWorldID(lastNodeID)
 	fmt.Printf("[GetStudentDetails] NodeWorldID(%s)=%s\n", lastNodeID, stage)
 	if stage == "" { stage = "W1" }
 	details.CurrentStage = stage
@@ -339,7 +388,7 @@ func (s *AdminService) GetStudentDetails(ctx context.Context, studentID, tenantI
 		stageNodeSet[nid] = true
 	}
 	for _, inst := range instances {
-		if inst.PlaybookVersionID == s.pb.VersionID && inst.State == "done" && stageNodeSet[inst.NodeID] {
+		if inst.PlaybookVersionID == s.manager(ctx).VersionID && inst.State == "done" && stageNodeSet[inst.NodeID] {
 			stageDone++
 		}
 	}
@@ -380,6 +429,21 @@ func (s *AdminService) GetStudentJourney(ctx context.Context, studentID, role, c
 	return s.repo.GetStudentJourneyNodes(ctx, studentID)
 }
 
+// CheckStudentAccess returns "forbidden" when an advisor asks for a student they do not advise
+func (s *AdminService) CheckStudentAccess(ctx context.Context, studentID, role, callerID string) error {
+	if role != "advisor" {
+		return nil
+	}
+	allowed, err := s.repo.CheckAdvisorAccess(ctx, studentID, callerID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("forbidden")
+	}
+	return nil
+}
+
 // ListStudentNodeFiles returns files for a specific node
 func (s *AdminService) ListStudentNodeFiles(ctx context.Context, studentID, nodeID, role, callerID string) ([]models.NodeFile, error) {
 	// RBAC
@@ -425,6 +489,14 @@ func (s *AdminService) ReviewAttachment(ctx context.Context, attachmentID, statu
 			return nil, errors.New("forbidden")
 		}
 	}
+
+	// An approval may close the node, which only its completing roles can do
+	approving := status == "approved" || status == "approved_with_comments"
+	if approving && meta.State != "done" && s.journey != nil {
+		if err := s.journey.CheckReviewCompletion(ctx, meta.InstanceID, actorID, role); err != nil {
+			return nil, err
+		}
+	}
 	
 	// Update Attachment
 	err = s.repo.UpdateAttachmentStatus(ctx, attachmentID, status, note, actorID)
@@ -467,7 +539,15 @@ func (s *AdminService) ReviewAttachment(ctx context.Context, attachmentID, statu
 		}
 	}
 	
-	if newState != meta.State {
+	if newState == "done" && meta.State != "done" && s.journey != nil {
+		// Completion selects and records the node's outcome with the state change
+		if err := s.journey.CompleteReviewed(ctx, tenantID, meta.InstanceID, actorID, role); err != nil {
+			return nil, err
+		}
+		if err := s.repo.UpdateAllNodeInstances(ctx, meta.StudentID, meta.NodeID, meta.InstanceID, newState); err != nil {
+			// Warning
+		}
+	} else if newState != meta.State {
 		// Update Instance
 		if err := s.repo.UpdateNodeInstanceState(ctx, meta.InstanceID, newState); err != nil {
 			return nil, err
@@ -480,11 +560,6 @@ func (s *AdminService) ReviewAttachment(ctx context.Context, attachmentID, statu
 		_ = s.repo.UpsertJourneyState(ctx, meta.TenantID, meta.StudentID, meta.NodeID, newState)
 		
 		_ = s.repo.LogNodeEvent(ctx, meta.InstanceID, "state_changed", actorID, map[string]any{"from": meta.State, "to": newState})
-		
-		// Activate Next Nodes
-		if newState == "done" {
-			// TODO: Implement ActiveNextNodes logic in Service or Helper, or let Handler do it
-		}
 	}
 	
 	// Notifications
@@ -500,7 +575,12 @@ func (s *AdminService) ReviewAttachment(ctx context.Context, attachmentID, statu
 	}
 	// Create notification
 	if meta.TenantID != "" {
-		_ = s.repo.CreateNotification(ctx, meta.StudentID, title, msg, "/journey", "document_review", meta.TenantID)
+		if err := s.repo.CreateNotification(ctx, meta.StudentID, title, msg, "/journey", "document_review", meta.TenantID); err == nil && s.notifs != nil {
+			link := "/journey"
+			s.notifs.NotifyCreated(ctx, &models.Notification{
+				TenantID: meta.TenantID, RecipientID: meta.StudentID, Title: title, Message: msg, Link: &link, Type: "document_review",
+			})
+		}
 	}
 
 	// Return result
@@ -616,9 +696,35 @@ func (s *AdminService) GetUnreadNotificationCount(ctx context.Context) (int, err
 }
 
 func (s *AdminService) MarkNotificationAsRead(ctx context.Context, id string) error {
-	return s.repo.MarkAdminNotificationRead(ctx, id)
+	if err := s.repo.MarkAdminNotificationRead(ctx, id); err != nil {
+		return err
+	}
+	s.emitAdminNotification(ctx, models.AdminNotificationEventRead, id)
+	return nil
 }
 
 func (s *AdminService) MarkAllNotificationsAsRead(ctx context.Context) error {
-	return s.repo.MarkAllAdminNotificationsRead(ctx)
+	if err := s.repo.MarkAllAdminNotificationsRead(ctx); err != nil {
+		return err
+	}
+	s.emitAdminNotification(ctx, models.AdminNotificationEventReadAll, "")
+	return nil
+}
+
+// AdminNotificationCreated announces a new admin notification, e.g. one
+// written by NotifyAdvisorsOnSubmission.
+func (s *AdminService) AdminNotificationCreated(ctx context.Context) {
+	s.emitAdminNotification(ctx, models.AdminNotificationEventCreated, "")
+}
+
+func (s *AdminService) emitAdminNotification(ctx context.Context, eventType, id string) {
+	if s.notifs == nil {
+		return
+	}
+	count, err := s.repo.GetAdminUnreadCount(ctx)
+	if err != nil {
+		log.Printf("[AdminService] Failed to count unread admin notifications: %v", err)
+		return
+	}
+	s.notifs.PublishAdminEvent(ctx, eventType, models.NotificationEventPayload{ID: id, UnreadCount: count})
 }
@@ -94,6 +94,191 @@ func TestAdminService_ReviewAttachment_Unit(t *testing.T) {
 	})
 }
 
+func TestAdminService_ReviewAttachment_WhoCanComplete_Unit(t *testing.T) {
+	pbm := &pb.Manager{
+		Nodes: map[string]pb.Node{"D3": {ID: "D3", WhoCanComplete: []string{"secretary"}}},
+	}
+	adminRepo := NewHandwrittenMockAdminRepository()
+	adminRepo.GetAttachmentMetaFunc = func(ctx context.Context, attachmentID string) (*models.AttachmentMeta, error) {
+		return &models.AttachmentMeta{InstanceID: "inst_d3", StudentID: "s1", NodeID: "D3", State: "submitted", TenantID: "t1"}, nil
+	}
+	adminRepo.CheckAdvisorAccessFunc = func(ctx context.Context, studentID, advisorID string) (bool, error) {
+		return true, nil
+	}
+	reviewed := false
+	adminRepo.UpdateAttachmentStatusFunc = func(ctx context.Context, attachmentID, status, note, actorID string) error {
+		reviewed = true
+		return nil
+	}
+
+	journeyRepo := NewMockJourneyRepository()
+	journeyRepo.GetNodeInstanceByIDFunc = func(ctx context.Context, id string) (*models.NodeInstance, error) {
+		return &models.NodeInstance{ID: "inst_d3", UserID: "s1", NodeID: "D3", State: "submitted"}, nil
+	}
+	var events []string
+	journeyRepo.LogNodeEventFunc = func(ctx context.Context, instanceID, eventType, actorID string, payload map[string]any) error {
+		events = append(events, eventType)
+		return nil
+	}
+	updated := false
+	journeyRepo.UpdateNodeInstanceStateFunc = func(ctx context.Context, instanceID, oldState, newState string) error {
+		updated = true
+		return nil
+	}
+
+	svc := services.NewAdminService(adminRepo, pbm, config.AppConfig{}, nil)
+	svc.SetJourney(services.NewJourneyService(journeyRepo, pbm, config.AppConfig{}, nil, nil, nil))
+
+	_, err := svc.ReviewAttachment(context.Background(), "att1", "approved", "", "adv1", "advisor", "t1")
+	assert.ErrorIs(t, err, services.ErrTransitionDenied)
+	assert.Contains(t, err.Error(), "cannot complete D3")
+	assert.False(t, reviewed)
+	assert.False(t, updated)
+	assert.Equal(t, []string{"transition_denied"}, events)
+}
+
+func TestAdminService_ReviewAttachment_Requirements_Unit(t *testing.T) {
+	pbm := &pb.Manager{
+		Nodes: map[string]pb.Node{"VI2": {ID: "VI2", Requirements: &pb.Requirements{Fields: []pb.FieldRequirement{
+			{Key: "chk_print", Type: "boolean", Required: true},
+		}}}},
+	}
+	adminRepo := NewHandwrittenMockAdminRepository()
+	adminRepo.GetAttachmentMetaFunc = func(ctx context.Context, attachmentID string) (*models.AttachmentMeta, error) {
+		return &models.AttachmentMeta{InstanceID: "inst_vi2", StudentID: "s1", NodeID: "VI2", State: "submitted", TenantID: "t1"}, nil
+	}
+	reviewed := false
+	adminRepo.UpdateAttachmentStatusFunc = func(ctx context.Context, attachmentID, status, note, actorID string) error {
+		reviewed = true
+		return nil
+	}
+
+	journeyRepo := NewMockJourneyRepository()
+	journeyRepo.GetNodeInstanceByIDFunc = func(ctx context.Context, id string) (*models.NodeInstance, error) {
+		return &models.NodeInstance{ID: "inst_vi2", UserID: "s1", NodeID: "VI2", State: "submitted", CurrentRev: 1}, nil
+	}
+	journeyRepo.GetFormRevisionFunc = func(ctx context.Context, instanceID string, rev int) ([]byte, error) {
+		return []byte(`{}`), nil
+	}
+	updated := false
+	journeyRepo.UpdateNodeInstanceStateFunc = func(ctx context.Context, instanceID, oldState, newState string) error {
+		updated = true
+		return nil
+	}
+
+	svc := services.NewAdminService(adminRepo, pbm, config.AppConfig{}, nil)
+	svc.SetJourney(services.NewJourneyService(journeyRepo, pbm, config.AppConfig{}, nil, nil, nil))
+
+	_, err := svc.ReviewAttachment(context.Background(), "att1", "approved", "", "admin1", "admin", "t1")
+	assert.ErrorIs(t, err, services.ErrRequirementsNotMet)
+	var fieldErr *services.FieldValidationError
+	assert.ErrorAs(t, err, &fieldErr)
+	assert.False(t, reviewed)
+	assert.False(t, updated)
+}
+
+func TestAdminService_ReviewAttachment_Outcomes_Unit(t *testing.T) {
+	pbm := &pb.Manager{
+		Nodes: map[string]pb.Node{
+			"E3": {ID: "E3", Next: []string{"RP1", "D1"}, Outcomes: []pb.Outcome{
+				{Value: "proceed_rp", When: "form.remarks_exist == true && form.remarks_resolved == true", Next: []string{"RP1"}},
+				{Value: "proceed_direct", When: "form.remarks_exist == false", Next: []string{"D1"}},
+				{Value: "needs_resolution", When: "form.remarks_exist == true && form.remarks_resolved != true", Next: []string{"E3"}},
+			}},
+			"RP1": {ID: "RP1", Prerequisites: []string{"E3"}},
+			"D1":  {ID: "D1", Prerequisites: []string{"E3"}},
+		},
+	}
+
+	type fixture struct {
+		svc       *services.AdminService
+		reviewed  bool
+		outcomes  []models.NodeOutcome
+		activated map[string]bool
+	}
+	setup := func(form string) *fixture {
+		f := &fixture{activated: map[string]bool{}}
+		adminRepo := NewHandwrittenMockAdminRepository()
+		adminRepo.GetAttachmentMetaFunc = func(ctx context.Context, attachmentID string) (*models.AttachmentMeta, error) {
+			return &models.AttachmentMeta{InstanceID: "inst_e3", StudentID: "s1", NodeID: "E3", State: "submitted", TenantID: "t1", Filename: "report.pdf"}, nil
+		}
+		adminRepo.UpdateAttachmentStatusFunc = func(ctx context.Context, attachmentID, status, note, actorID string) error {
+			f.reviewed = true
+			return nil
+		}
+		adminRepo.LogNodeEventFunc = func(ctx context.Context, instanceID, eventType, actorID string, payload map[string]any) error {
+			return nil
+		}
+		adminRepo.GetLatestAttachmentStatusFunc = func(ctx context.Context, instanceID string) (string, error) {
+			return "approved", nil
+		}
+		adminRepo.GetAttachmentCountsFunc = func(ctx context.Context, instanceID string) (int, int, int, error) {
+			return 0, 1, 0, nil
+		}
+		adminRepo.UpdateAllNodeInstancesFunc = func(ctx context.Context, studentID, nodeID, instanceID, state string) error {
+			return nil
+		}
+		adminRepo.CreateNotificationFunc = func(ctx context.Context, recipientID, title, message, link, nType, tenantID string) error {
+			return nil
+		}
+
+		journeyRepo := NewMockJourneyRepository()
+		e3 := &models.NodeInstance{ID: "inst_e3", UserID: "s1", NodeID: "E3", State: "submitted", CurrentRev: 1}
+		journeyRepo.GetNodeInstanceFunc = func(ctx context.Context, userID, nodeID string) (*models.NodeInstance, error) {
+			if nodeID == "E3" {
+				return e3, nil
+			}
+			return nil, nil
+		}
+		journeyRepo.GetNodeInstanceByIDFunc = func(ctx context.Context, id string) (*models.NodeInstance, error) {
+			return e3, nil
+		}
+		journeyRepo.GetFormRevisionFunc = func(ctx context.Context, instanceID string, rev int) ([]byte, error) {
+			return []byte(form), nil
+		}
+		journeyRepo.UpdateNodeInstanceStateFunc = func(ctx context.Context, instanceID, oldState, newState string) error {
+			e3.State = newState
+			return nil
+		}
+		journeyRepo.InsertOutcomeFunc = func(ctx context.Context, instanceID, value, decidedBy, note string) error {
+			f.outcomes = append([]models.NodeOutcome{{OutcomeValue: value, DecidedBy: decidedBy}}, f.outcomes...)
+			return nil
+		}
+		journeyRepo.GetNodeOutcomesFunc = func(ctx context.Context, instanceID string) ([]models.NodeOutcome, error) {
+			return f.outcomes, nil
+		}
+		journeyRepo.CreateNodeInstanceFunc = func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error) {
+			f.activated[nodeID] = true
+			return "inst_" + nodeID, nil
+		}
+
+		f.svc = services.NewAdminService(adminRepo, pbm, config.AppConfig{}, nil)
+		f.svc.SetJourney(services.NewJourneyService(journeyRepo, pbm, config.AppConfig{}, nil, nil, nil))
+		return f
+	}
+
+	t.Run("Approval records the outcome and opens its next node", func(t *testing.T) {
+		f := setup(`{"remarks_exist": false}`)
+		result, err := f.svc.ReviewAttachment(context.Background(), "att1", "approved", "", "admin1", "admin", "t1")
+		assert.NoError(t, err)
+		assert.Equal(t, "done", result.State)
+		assert.Len(t, f.outcomes, 1)
+		assert.Equal(t, "proceed_direct", f.outcomes[0].OutcomeValue)
+		assert.Equal(t, "admin1", f.outcomes[0].DecidedBy)
+		assert.True(t, f.activated["D1"])
+		assert.False(t, f.activated["RP1"])
+	})
+
+	t.Run("Approval is refused when the outcome sends the student back", func(t *testing.T) {
+		f := setup(`{"remarks_exist": true}`)
+		_, err := f.svc.ReviewAttachment(context.Background(), "att1", "approved", "", "admin1", "admin", "t1")
+		assert.ErrorIs(t, err, services.ErrOutcomeNotDetermined)
+		assert.Contains(t, err.Error(), "needs_resolution")
+		assert.False(t, f.reviewed)
+		assert.Empty(t, f.outcomes)
+	})
+}
+
 func TestAdminService_MonitorStudents_Unit(t *testing.T) {
 	mockRepo := NewHandwrittenMockAdminRepository()
 	mockRepo.ListStudentsForMonitorFunc = func(ctx context.Context, f models.FilterParams) ([]models.StudentMonitorRow, error) {
@@ -161,8 +346,11 @@ func TestAdminService_MonitorAnalytics_Unit(t *testing.T) {
 	mockRepo.ListStudentsForMonitorFunc = func(ctx context.Context, f models.FilterParams) ([]models.StudentMonitorRow, error) {
 		return []models.StudentMonitorRow{{ID: "s1"}, {ID: "s2"}}, nil
 	}
-	mockRepo.GetRPRequiredForStudentsFunc = func(ctx context.Context, ids []string) (map[string]bool, error) {
-		return map[string]bool{"s1": true, "s2": false}, nil
+	mockRepo.GetProfilesForStudentsFunc = func(ctx context.Context, ids []string) (map[string]map[string]any, error) {
+		return map[string]map[string]any{
+			"s1": {"years_since_graduation": 5.0},
+			"s2": {"years_since_graduation": 1.0},
+		}, nil
 	}
 	mockRepo.GetAntiplagCountFunc = func(ctx context.Context, ids []string, vid string) (int, error) {
 		return 1, nil // 50%
@@ -178,6 +366,9 @@ func TestAdminService_MonitorAnalytics_Unit(t *testing.T) {
 		VersionID: "v1",
 		Nodes:     map[string]pb.Node{"n1": {}},
 		NodeWorlds: map[string]string{"n1": "W2"},
+		Conditions: map[string]pb.Condition{
+			"rp_required": {ID: "rp_required", Expr: "profile.years_since_graduation > 3"},
+		},
 	}
 	svc := services.NewAdminService(mockRepo, pbm, config.AppConfig{}, nil)
 	
@@ -192,8 +383,11 @@ func TestAdminService_MonitorAnalytics_Unit(t *testing.T) {
 		mockRepo.ListStudentsForMonitorFunc = func(ctx context.Context, f models.FilterParams) ([]models.StudentMonitorRow, error) {
 			return []models.StudentMonitorRow{{ID: "s1"}, {ID: "s2"}}, nil
 		}
-		mockRepo.GetRPRequiredForStudentsFunc = func(ctx context.Context, ids []string) (map[string]bool, error) {
-			return map[string]bool{"s1": true, "s2": true}, nil
+		mockRepo.GetProfilesForStudentsFunc = func(ctx context.Context, ids []string) (map[string]map[string]any, error) {
+			return map[string]map[string]any{
+				"s1": {"years_since_graduation": 4.0},
+				"s2": {"years_since_graduation": "7"},
+			}, nil
 		}
 		mockRepo.GetW2DurationsFunc = func(ctx context.Context, ids []string, vid string, nodes []string) ([]float64, error) {
 			return []float64{10.0, 20.0}, nil // Median = (10+20)/2 = 15
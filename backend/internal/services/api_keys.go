@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+const (
+	// DefaultAPIKeyDays is how long a key lasts when no expiry is given
+	DefaultAPIKeyDays = 90
+	// MaxAPIKeyDays bounds the lifetime of a key
+	MaxAPIKeyDays = 365
+)
+
+var (
+	ErrAPIKeyInvalid = errors.New("invalid API key request")
+	// ErrAPIKeyNotAdmin is returned when someone other than a tenant admin
+	// creates a key; keys act with their creator's tenant role
+	ErrAPIKeyNotAdmin = errors.New("only tenant admins can create API keys")
+)
+
+// CreateAPIKeyRequest describes a new key.
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// APIKeyService issues and revokes tenants' API keys.
+type APIKeyService struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// Create issues a key acting as the admin creating it in the tenant. The
+// returned token is the key itself; only its hash is kept, so it cannot be
+// shown again.
+func (s *APIKeyService) Create(ctx context.Context, tenantID, userID, role string, req CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	if role != string(models.RoleAdmin) {
+		return nil, "", ErrAPIKeyNotAdmin
+	}
+	name := strings.TrimSpace(req.Name)
+	if tenantID == "" || name == "" {
+		return nil, "", fmt.Errorf("%w: name and tenant are required", ErrAPIKeyInvalid)
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = DefaultAPIKeyDays
+	}
+	if days < 1 || days > MaxAPIKeyDays {
+		return nil, "", fmt.Errorf("%w: expires_in_days must be between 1 and %d", ErrAPIKeyInvalid, MaxAPIKeyDays)
+	}
+
+	token, hash, err := newAPIKeyToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &models.APIKey{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(models.APIKeyPrefix)+8],
+		KeyHash:   hash,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	log.Printf("[APIKeyService] Created API key=%s tenantID=%s userID=%s scopes=%v", key.ID, tenantID, userID, scopes)
+	return key, token, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	return s.repo.List(ctx, tenantID)
+}
+
+// Revoke disables a key at once.
+func (s *APIKeyService) Revoke(ctx context.Context, tenantID, id string) error {
+	return s.repo.Revoke(ctx, tenantID, id)
+}
+
+// normalizeScopes checks the scopes are known and drops duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if _, ok := models.APIKeyScopes[scope]; !ok {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrAPIKeyInvalid, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			out = append(out, scope)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrAPIKeyInvalid)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// newAPIKeyToken returns a random key and the hash it is stored and looked
+// up by.
+func newAPIKeyToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := models.APIKeyPrefix + hex.EncodeToString(b)
+	return token, models.HashAPIKey(token), nil
+}
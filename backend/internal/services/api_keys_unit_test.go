@@ -0,0 +1,53 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService_Create(t *testing.T) {
+	ctx := context.Background()
+	repo := &MemAPIKeyRepository{}
+	svc := services.NewAPIKeyService(repo)
+	req := services.CreateAPIKeyRequest{Name: " registrar ", Scopes: []string{"students:read", "journey:read", "students:read"}}
+
+	_, _, err := svc.Create(ctx, "t1", "u1", "advisor", req)
+	assert.ErrorIs(t, err, services.ErrAPIKeyNotAdmin)
+
+	key, token, err := svc.Create(ctx, "t1", "u1", "admin", req)
+	require.NoError(t, err)
+	assert.Equal(t, "registrar", key.Name)
+	assert.Equal(t, []string{"journey:read", "students:read"}, []string(key.Scopes))
+	assert.True(t, strings.HasPrefix(token, models.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(token, key.Prefix))
+	assert.Equal(t, models.HashAPIKey(token), key.KeyHash)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, services.DefaultAPIKeyDays), key.ExpiresAt, time.Minute)
+	assert.True(t, key.HasScope("journey:read"))
+	assert.False(t, key.HasScope("users:write"))
+
+	for name, bad := range map[string]services.CreateAPIKeyRequest{
+		"unknown scope": {Name: "x", Scopes: []string{"students:delete"}},
+		"no scopes":     {Name: "x", Scopes: []string{}},
+		"too long":      {Name: "x", Scopes: []string{"users:read"}, ExpiresInDays: services.MaxAPIKeyDays + 1},
+		"no name":       {Name: " ", Scopes: []string{"users:read"}},
+	} {
+		_, _, err := svc.Create(ctx, "t1", "u1", "admin", bad)
+		assert.ErrorIs(t, err, services.ErrAPIKeyInvalid, name)
+	}
+
+	keys, err := svc.List(ctx, "t1")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.ErrorIs(t, svc.Revoke(ctx, "t2", key.ID), repository.ErrNotFound, "another tenant's key")
+	require.NoError(t, svc.Revoke(ctx, "t1", key.ID))
+	keys, _ = svc.List(ctx, "t1")
+	assert.Empty(t, keys)
+}
@@ -11,28 +11,49 @@ import (
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/redis/go-redis/v9"
 )
 
 type AuthService struct {
-	repo  repository.UserRepository
-	email EmailSender
-	cfg   config.AppConfig
+	repo     repository.UserRepository
+	sessions repository.SessionRepository
+	rds      *redis.Client
+	email    EmailSender
+	cfg      config.AppConfig
+	// secondFactor, when set, can hold back a password sign-in's session
+	secondFactor SecondFactor
 }
 
-func NewAuthService(repo repository.UserRepository, email EmailSender, cfg config.AppConfig) *AuthService {
+func NewAuthService(repo repository.UserRepository, sessions repository.SessionRepository, rds *redis.Client, email EmailSender, cfg config.AppConfig) *AuthService {
 	return &AuthService{
-		repo:  repo,
-		email: email,
-		cfg:   cfg,
+		repo:     repo,
+		sessions: sessions,
+		rds:      rds,
+		email:    email,
+		cfg:      cfg,
 	}
 }
 
+// SetSecondFactor makes password sign-ins ask for a second factor when it
+// says so.
+func (s *AuthService) SetSecondFactor(f SecondFactor) {
+	s.secondFactor = f
+}
+
+// LoginResponse is a new sign-in session: a short-lived access token and
+// the refresh token that renews it. RefreshToken is empty when a refresh
+// only renewed the access token. When MFA is set the password was right
+// but there is no session yet: it starts once the second factor is checked.
 type LoginResponse struct {
 	Token        string
+	RefreshToken string
+	SessionID    string
 	Role         string
 	IsSuperadmin bool
 	UserID       string
+	MFA          *MFAChallenge
 }
 
 func (s *AuthService) Login(ctx context.Context, username, password string, tenantID string) (*LoginResponse, error) {
@@ -57,43 +78,51 @@ func (s *AuthService) Login(ctx context.Context, username, password string, tena
 	log.Printf("[AuthService.Login] Password verified for user=%s", username)
 
 	// Verify Tenant Access
-	var role string
-	if user.Role == "superadmin" {
-		role = "superadmin"
-		log.Printf("[AuthService.Login] User is superadmin, bypassing tenant check")
-		// Superadmin has access to everything effectively, but token needs a role.
-		// If tenantID is provided, we should check if they are "operating as" superadmin?
-		// Existing logic: "Verify user has access to this tenant (unless superadmin)"
-		// If superadmin, role stays superadmin.
-	} else if tenantID != "" {
-		// Check membership
-		log.Printf("[AuthService.Login] Checking tenant membership: userID=%s, tenantID=%s", user.ID, tenantID)
-		tenantRole, err := s.repo.GetTenantRole(ctx, user.ID, tenantID)
-		if err != nil {
-			log.Printf("[AuthService.Login] Tenant access denied: userID=%s, tenantID=%s, error=%v", user.ID, tenantID, err)
-			return nil, errors.New("access denied to this portal")
-		}
-		role = tenantRole
-		log.Printf("[AuthService.Login] Tenant access granted: role=%s", role)
-	} else {
-		// No tenant context (e.g. platform admin login? or just resolving user role)
-		role = string(user.Role)
-		log.Printf("[AuthService.Login] No tenant context, using user role=%s", role)
+	role, err := s.roleFor(ctx, user, tenantID)
+	if err != nil {
+		log.Printf("[AuthService.Login] Tenant access denied: userID=%s, tenantID=%s, error=%v", user.ID, tenantID, err)
+		return nil, errors.New("access denied to this portal")
 	}
+	log.Printf("[AuthService.Login] Access granted: role=%s, tenantID=%s", role, tenantID)
 
-	token, err := s.GenerateToken(user.ID, role, tenantID, user.Role == "superadmin")
+	resp, err := s.beginSession(ctx, user, role, tenantID)
 	if err != nil {
-		log.Printf("[AuthService.Login] Token generation failed: %v", err)
+		log.Printf("[AuthService.Login] Session creation failed: %v", err)
 		return nil, err
 	}
-	log.Printf("[AuthService.Login] Login successful: userID=%s, role=%s, tenantID=%s", user.ID, role, tenantID)
-	
-	return &LoginResponse{
-		Token:        token,
-		UserID:       user.ID,
-		Role:         role,
-		IsSuperadmin: user.Role == "superadmin", 
-	}, nil
+	log.Printf("[AuthService.Login] Login successful: userID=%s, role=%s, tenantID=%s, mfa=%v", user.ID, role, tenantID, resp.MFA != nil)
+
+	return resp, nil
+}
+
+// beginSession starts the session of a user who proved who they are, or
+// holds it back when the second factor asks for a code first. Password and
+// SSO logins share it.
+func (s *AuthService) beginSession(ctx context.Context, user *models.User, role, tenantID string) (*LoginResponse, error) {
+	isSuperadmin := user.Role == models.RoleSuperAdmin
+	if s.secondFactor != nil {
+		challenge, err := s.secondFactor.Challenge(ctx, user, role, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if challenge != nil {
+			log.Printf("[AuthService] Second factor required: userID=%s, enroll=%v", user.ID, challenge.Enroll)
+			return &LoginResponse{Role: role, IsSuperadmin: isSuperadmin, UserID: user.ID, MFA: challenge}, nil
+		}
+	}
+	return s.StartSession(ctx, user.ID, role, tenantID, isSuperadmin)
+}
+
+// roleFor is the role a user signs in to the tenant with. Superadmins have
+// access everywhere; without a tenant the user's own role applies.
+func (s *AuthService) roleFor(ctx context.Context, user *models.User, tenantID string) (string, error) {
+	if user.Role == models.RoleSuperAdmin {
+		return string(models.RoleSuperAdmin), nil
+	}
+	if tenantID == "" {
+		return string(user.Role), nil
+	}
+	return s.repo.GetTenantRole(ctx, user.ID, tenantID)
 }
 
 
@@ -140,9 +169,12 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword stri
 		return err
 	}
 
-	return s.repo.DeletePasswordResetToken(ctx, tokenHash)
-}
-
-func (s *AuthService) GenerateToken(userID, role, tenantID string, isSuperadmin bool) (string, error) {
-	return auth.GenerateJWTWithTenant(userID, role, tenantID, isSuperadmin, []byte(s.cfg.JWTSecret), s.cfg.JWTExpDays)
+	if err := s.repo.DeletePasswordResetToken(ctx, tokenHash); err != nil {
+		return err
+	}
+	// Whoever knew the old password is signed out too
+	if s.rds != nil {
+		s.rds.Del(ctx, "user:"+userID)
+	}
+	return s.RevokeAllSessions(ctx, userID)
 }
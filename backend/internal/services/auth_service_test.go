@@ -42,7 +42,7 @@ func TestAuthService_Login(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockEmail := new(MockEmailSender)
 	cfg := config.AppConfig{JWTSecret: "testsec", JWTExpDays: 1}
-	svc := services.NewAuthService(mockRepo, mockEmail, cfg)
+	svc := services.NewAuthService(mockRepo, NewMemSessionRepository(), nil, mockEmail, cfg)
 
 	ctx := context.Background()
 	password := "securepass"
@@ -121,7 +121,7 @@ func TestAuthService_RequestPasswordReset(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockEmail := new(MockEmailSender)
 	cfg := config.AppConfig{}
-	svc := services.NewAuthService(mockRepo, mockEmail, cfg)
+	svc := services.NewAuthService(mockRepo, NewMemSessionRepository(), nil, mockEmail, cfg)
 
 	ctx := context.Background()
 
@@ -146,7 +146,7 @@ func TestAuthService_ResetPassword(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockEmail := new(MockEmailSender)
 	cfg := config.AppConfig{}
-	svc := services.NewAuthService(mockRepo, mockEmail, cfg)
+	svc := services.NewAuthService(mockRepo, NewMemSessionRepository(), nil, mockEmail, cfg)
 
 	ctx := context.Background()
 	token := "valid-token"
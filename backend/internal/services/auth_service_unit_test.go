@@ -17,7 +17,7 @@ import (
 func TestAuthService_Login_Unit(t *testing.T) {
 	mockRepo := NewHandwrittenMockUserRepository()
 	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1}
-	svc := services.NewAuthService(mockRepo, nil, cfg)
+	svc := services.NewAuthService(mockRepo, NewMemSessionRepository(), nil, nil, cfg)
 	ctx := context.Background()
 
 	pw, _ := auth.HashPassword("pass")
@@ -104,7 +104,7 @@ func TestAuthService_PasswordReset_Unit(t *testing.T) {
 		return nil
 	}
 	
-	svc := services.NewAuthService(mockRepo, mockEmail, config.AppConfig{})
+	svc := services.NewAuthService(mockRepo, NewMemSessionRepository(), nil, mockEmail, config.AppConfig{})
 	
 	// Request reset
 	err := svc.RequestPasswordReset(context.Background(), "test@test.com")
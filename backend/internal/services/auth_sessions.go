@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+const (
+	// RefreshReuseGrace is how long a refresh token that was just rotated
+	// still renews the access token, for tabs that refreshed at once. In that
+	// window whoever replays the previous token also gets an access token,
+	// though not a refresh token, so the window is kept to a few seconds.
+	// The token lives only in an HttpOnly cookie.
+	RefreshReuseGrace = 10 * time.Second
+	// maxUserAgentLen bounds the user agent kept with a session
+	maxUserAgentLen = 512
+)
+
+// ErrSessionInvalid is returned for a refresh token whose session is
+// unknown, expired or revoked.
+var ErrSessionInvalid = errors.New("session expired or revoked")
+
+// SessionRevoker ends all of a user's sessions; AuthService implements it.
+type SessionRevoker interface {
+	RevokeAllSessions(ctx context.Context, userID string) error
+}
+
+// SessionClient is the device a sign-in comes from, as shown in the
+// user's list of sessions.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+type sessionClientKey struct{}
+
+// WithSessionClient attaches the signing-in device to ctx, for the
+// session a login started with ctx records it.
+func WithSessionClient(ctx context.Context, client SessionClient) context.Context {
+	return context.WithValue(ctx, sessionClientKey{}, client)
+}
+
+// StartSession opens a session for a user who has just signed in to the
+// tenant with the role, and issues its first tokens.
+func (s *AuthService) StartSession(ctx context.Context, userID, role, tenantID string, isSuperadmin bool) (*LoginResponse, error) {
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	client, _ := ctx.Value(sessionClientKey{}).(SessionClient)
+	if len(client.UserAgent) > maxUserAgentLen {
+		client.UserAgent = client.UserAgent[:maxUserAgentLen]
+	}
+	sess := &models.UserSession{
+		UserID:           userID,
+		RefreshTokenHash: hash,
+		UserAgent:        client.UserAgent,
+		IP:               client.IP,
+		ExpiresAt:        time.Now().Add(s.cfg.SessionTTL()),
+	}
+	if tenantID != "" {
+		sess.TenantID = &tenantID
+	}
+	if err := s.sessions.Create(ctx, sess); err != nil {
+		return nil, err
+	}
+	token, err := s.accessToken(userID, role, tenantID, sess.ID, isSuperadmin)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: refresh,
+		SessionID:    sess.ID,
+		Role:         role,
+		IsSuperadmin: isSuperadmin,
+		UserID:       userID,
+	}, nil
+}
+
+// Refresh trades a refresh token for a new access token and a new refresh
+// token. A token that was already traded is taken as stolen and ends its
+// session, unless it was traded within RefreshReuseGrace, presumably by a
+// concurrent request; then only the access token is renewed. The user's active state and tenant
+// role are checked again, so the new token reflects any change.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*LoginResponse, error) {
+	hash := hashRefreshToken(refreshToken)
+	sess, err := s.sessions.GetByTokenHash(ctx, hash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !sess.Active(now) {
+		return nil, ErrSessionInvalid
+	}
+	current := sess.RefreshTokenHash == hash
+	if !current && (sess.RotatedAt == nil || now.Sub(*sess.RotatedAt) > RefreshReuseGrace) {
+		log.Printf("[AuthService.Refresh] Reused refresh token, revoking session=%s userID=%s", sess.ID, sess.UserID)
+		s.endSession(ctx, sess.UserID, sess.ID)
+		return nil, ErrSessionInvalid
+	}
+
+	user, err := s.repo.GetByID(ctx, sess.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrSessionInvalid
+	}
+	tenantID := ""
+	if sess.TenantID != nil {
+		tenantID = *sess.TenantID
+	}
+	role, err := s.roleFor(ctx, user, tenantID)
+	if err != nil {
+		log.Printf("[AuthService.Refresh] Tenant access gone, revoking session=%s userID=%s: %v", sess.ID, user.ID, err)
+		s.endSession(ctx, user.ID, sess.ID)
+		return nil, ErrSessionInvalid
+	}
+
+	resp := &LoginResponse{
+		SessionID:    sess.ID,
+		Role:         role,
+		IsSuperadmin: user.Role == models.RoleSuperAdmin,
+		UserID:       user.ID,
+	}
+	if current {
+		refresh, newHash, err := newRefreshToken()
+		if err != nil {
+			return nil, err
+		}
+		err = s.sessions.Rotate(ctx, sess.ID, hash, newHash)
+		if errors.Is(err, repository.ErrNotFound) {
+			// Revoked or rotated by another request since it was read
+			return nil, ErrSessionInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp.RefreshToken = refresh
+	}
+	resp.Token, err = s.accessToken(user.ID, role, tenantID, sess.ID, resp.IsSuperadmin)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Logout ends the session of a refresh token. Unknown tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	sess, err := s.sessions.GetByTokenHash(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = s.RevokeSession(ctx, sess.UserID, sess.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ListSessions returns the user's live sessions, most recently used first.
+func (s *AuthService) ListSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	return s.sessions.ListActive(ctx, userID)
+}
+
+// RevokeSession ends one of the user's sessions. Its access tokens stop
+// working at once.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if err := s.sessions.Revoke(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	s.forgetSessions(ctx, sessionID)
+	return nil
+}
+
+// RevokeAllSessions signs the user out everywhere.
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID string) error {
+	ids, err := s.sessions.RevokeAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		log.Printf("[AuthService] Revoked %d session(s) of userID=%s", len(ids), userID)
+	}
+	s.forgetSessions(ctx, ids...)
+	return nil
+}
+
+// endSession revokes a session on behalf of the server; failures are only
+// logged as the caller is refusing the request anyway.
+func (s *AuthService) endSession(ctx context.Context, userID, sessionID string) {
+	if err := s.RevokeSession(ctx, userID, sessionID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("[AuthService] Failed to revoke session=%s: %v", sessionID, err)
+	}
+}
+
+// forgetSessions drops the cached state AuthMiddleware checks access
+// tokens against.
+func (s *AuthService) forgetSessions(ctx context.Context, ids ...string) {
+	if s.rds == nil || len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = "session:" + id
+	}
+	s.rds.Del(ctx, keys...)
+}
+
+func (s *AuthService) accessToken(userID, role, tenantID, sessionID string, isSuperadmin bool) (string, error) {
+	return auth.GenerateAccessToken(userID, role, tenantID, sessionID, isSuperadmin, []byte(s.cfg.JWTSecret), s.cfg.AccessTokenTTL())
+}
+
+// newRefreshToken returns a random refresh token and the hash it is
+// stored by.
+func newRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,162 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_Sessions(t *testing.T) {
+	users := NewHandwrittenMockUserRepository()
+	sessions := NewMemSessionRepository()
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, AccessTokenMinutes: 5}
+	svc := services.NewAuthService(users, sessions, nil, nil, cfg)
+	ctx := services.WithSessionClient(context.Background(), services.SessionClient{UserAgent: "Firefox", IP: "10.0.0.1"})
+
+	pw, _ := auth.HashPassword("pass")
+	user := &models.User{ID: "u1", Username: "john", PasswordHash: pw, IsActive: true, Role: models.RoleStudent}
+	users.GetByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) { return user, nil }
+	users.GetByIDFunc = func(ctx context.Context, id string) (*models.User, error) { return user, nil }
+	tenantRole := "student"
+	users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+		if tenantRole == "" {
+			return "", repository.ErrNotFound
+		}
+		return tenantRole, nil
+	}
+	claimsOf := func(t *testing.T, token string) jwt.MapClaims {
+		t.Helper()
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+		require.NoError(t, err)
+		return claims
+	}
+
+	t.Run("Login opens a session", func(t *testing.T) {
+		res, err := svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+		require.NotEmpty(t, res.RefreshToken)
+		claims := claimsOf(t, res.Token)
+		assert.Equal(t, res.SessionID, claims["sid"])
+		exp, _ := claims.GetExpirationTime()
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), exp.Time, 5*time.Second)
+
+		s := sessions.sessions[res.SessionID]
+		assert.Equal(t, "Firefox", s.UserAgent)
+		assert.Equal(t, "10.0.0.1", s.IP)
+		assert.Equal(t, "t1", *s.TenantID)
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), s.ExpiresAt, 5*time.Second)
+		assert.NotContains(t, s.RefreshTokenHash, res.RefreshToken)
+	})
+
+	t.Run("Refresh rotates the token", func(t *testing.T) {
+		login, err := svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+
+		tenantRole = "advisor"
+		defer func() { tenantRole = "student" }()
+		res, err := svc.Refresh(ctx, login.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, login.SessionID, res.SessionID)
+		assert.NotEqual(t, login.RefreshToken, res.RefreshToken)
+		assert.Equal(t, "advisor", claimsOf(t, res.Token)["role"], "the tenant role is read again")
+
+		// A concurrent request with the old token only renews the access token
+		again, err := svc.Refresh(ctx, login.RefreshToken)
+		require.NoError(t, err)
+		assert.Empty(t, again.RefreshToken)
+		assert.NotEmpty(t, again.Token)
+
+		next, err := svc.Refresh(ctx, res.RefreshToken)
+		require.NoError(t, err)
+		assert.NotEmpty(t, next.RefreshToken)
+	})
+
+	t.Run("A reused token ends the session", func(t *testing.T) {
+		login, err := svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+		res, err := svc.Refresh(ctx, login.RefreshToken)
+		require.NoError(t, err)
+		past := time.Now().Add(-services.RefreshReuseGrace - time.Second)
+		sessions.sessions[login.SessionID].RotatedAt = &past
+
+		_, err = svc.Refresh(ctx, login.RefreshToken)
+		assert.ErrorIs(t, err, services.ErrSessionInvalid)
+		_, err = svc.Refresh(ctx, res.RefreshToken)
+		assert.ErrorIs(t, err, services.ErrSessionInvalid, "the thief and the user are both signed out")
+	})
+
+	t.Run("Refresh checks the account", func(t *testing.T) {
+		login, err := svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+
+		tenantRole = ""
+		_, err = svc.Refresh(ctx, login.RefreshToken)
+		tenantRole = "student"
+		assert.ErrorIs(t, err, services.ErrSessionInvalid)
+		assert.NotNil(t, sessions.sessions[login.SessionID].RevokedAt, "removed from the tenant")
+
+		login, err = svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+		user.IsActive = false
+		_, err = svc.Refresh(ctx, login.RefreshToken)
+		user.IsActive = true
+		assert.ErrorIs(t, err, services.ErrSessionInvalid)
+
+		_, err = svc.Refresh(ctx, "unknown")
+		assert.ErrorIs(t, err, services.ErrSessionInvalid)
+	})
+
+	t.Run("Logout and revocation", func(t *testing.T) {
+		a, err := svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+		b, err := svc.Login(ctx, "john", "pass", "t1")
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Logout(ctx, a.RefreshToken))
+		require.NoError(t, svc.Logout(ctx, a.RefreshToken), "logging out twice is harmless")
+		_, err = svc.Refresh(ctx, a.RefreshToken)
+		assert.ErrorIs(t, err, services.ErrSessionInvalid)
+
+		assert.ErrorIs(t, svc.RevokeSession(ctx, "someone-else", b.SessionID), repository.ErrNotFound)
+		active, err := svc.ListSessions(ctx, "u1")
+		require.NoError(t, err)
+		assert.NotEmpty(t, active)
+
+		require.NoError(t, svc.RevokeAllSessions(ctx, "u1"))
+		active, err = svc.ListSessions(ctx, "u1")
+		require.NoError(t, err)
+		assert.Empty(t, active)
+		_, err = svc.Refresh(ctx, b.RefreshToken)
+		assert.ErrorIs(t, err, services.ErrSessionInvalid)
+	})
+}
+
+func TestUserService_SetActiveRevokesSessions(t *testing.T) {
+	users := NewHandwrittenMockUserRepository()
+	sessions := NewMemSessionRepository()
+	authSvc := services.NewAuthService(users, sessions, nil, nil, config.AppConfig{JWTSecret: "secret"})
+	userSvc := services.NewUserService(users, nil, config.AppConfig{}, nil, nil)
+	userSvc.SetSessionRevoker(authSvc)
+
+	ctx := context.Background()
+	_, err := authSvc.StartSession(ctx, "u1", "student", "t1", false)
+	require.NoError(t, err)
+
+	require.NoError(t, userSvc.SetActive(ctx, "u1", true))
+	active, _ := authSvc.ListSessions(ctx, "u1")
+	assert.Len(t, active, 1)
+
+	require.NoError(t, userSvc.SetActive(ctx, "u1", false))
+	active, _ = authSvc.ListSessions(ctx, "u1")
+	assert.Empty(t, active)
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+)
+
+var (
+	ErrChatMuted               = errors.New("you are muted in this room")
+	ErrChatReadOnly            = errors.New("only moderators can post in this channel")
+	ErrChatBanned              = errors.New("user is banned from this room")
+	ErrChatModerationForbidden = errors.New("your room role does not allow this action")
+	ErrChatMemberNotFound      = errors.New("user is not a member of this room")
+	ErrChatInvalidSettings     = errors.New("invalid room settings")
+)
+
+const (
+	// maxChatMute bounds a mute; longer exclusions are bans
+	maxChatMute = 30 * 24 * time.Hour
+	// maxChatRetentionDays is the longest retention a room can set
+	maxChatRetentionDays = 3650
+	// chatRetentionBatch is how many threads a retention pass removes per query
+	chatRetentionBatch = 500
+)
+
+// ChatRoomSettings are the moderation settings of a room.
+type ChatRoomSettings struct {
+	PostPolicy      models.ChatPostPolicy      `json:"post_policy"`
+	RetentionDays   *int                       `json:"retention_days"`
+	RetentionAction models.ChatRetentionAction `json:"retention_action"`
+}
+
+// roomRank orders room roles; a moderator may only act on members ranked
+// below them.
+func roomRank(role models.ChatRoomMemberRole) int {
+	switch role {
+	case models.ChatRoomMemberRoleAdmin:
+		return 2
+	case models.ChatRoomMemberRoleModerator:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// moderatorRole returns the actor's room role if it is admin or moderator.
+func (s *ChatService) moderatorRole(ctx context.Context, roomID, actorID string) (models.ChatRoomMemberRole, error) {
+	role, err := s.repo.GetMemberRole(ctx, roomID, actorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrChatNotMember
+	}
+	if err != nil {
+		return "", err
+	}
+	if roomRank(role) == 0 {
+		return "", ErrChatModerationForbidden
+	}
+	return role, nil
+}
+
+// checkOutranks makes sure the actor ranks above the target in the room.
+// Users who are no longer members rank as plain members.
+func (s *ChatService) checkOutranks(ctx context.Context, roomID string, actorRole models.ChatRoomMemberRole, targetID string) error {
+	role, err := s.repo.GetMemberRole(ctx, roomID, targetID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if roomRank(role) >= roomRank(actorRole) {
+		return ErrChatModerationForbidden
+	}
+	return nil
+}
+
+// checkCanPost rejects messages from non-members, muted members and plain
+// members of read-only channels.
+func (s *ChatService) checkCanPost(ctx context.Context, roomID, userID string) error {
+	member, err := s.repo.GetMember(ctx, roomID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrChatNotMember
+	}
+	if err != nil {
+		return err
+	}
+	if member.IsMuted(time.Now()) {
+		return ErrChatMuted
+	}
+	if roomRank(member.RoleInRoom) > 0 {
+		return nil
+	}
+	room, err := s.repo.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if room.PostPolicy == models.ChatPostPolicyModerators {
+		return ErrChatReadOnly
+	}
+	return nil
+}
+
+// audit records a moderation action. Like publish it does not fail the
+// action, which has already taken effect.
+func (s *ChatService) audit(ctx context.Context, entry models.ChatModerationEntry) {
+	if err := s.repo.CreateModerationEntry(ctx, &entry); err != nil {
+		log.Printf("[Chat] Failed to record %s in room %s: %v", entry.Action, entry.RoomID, err)
+	}
+}
+
+// RemoveMessage lets a room admin or moderator delete another member's
+// message. Messages of equal or higher ranked members are off limits.
+func (s *ChatService) RemoveMessage(ctx context.Context, msgID, actorID, reason string) error {
+	msg, err := s.repo.GetMessage(ctx, msgID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && msg.DeletedAt != nil) {
+		return ErrChatMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	role, err := s.moderatorRole(ctx, msg.RoomID, actorID)
+	if err != nil {
+		return err
+	}
+	if msg.SenderID != actorID {
+		if err := s.checkOutranks(ctx, msg.RoomID, role, msg.SenderID); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.RemoveMessage(ctx, msgID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChatMessageNotFound
+		}
+		return err
+	}
+	s.audit(ctx, models.ChatModerationEntry{
+		RoomID:          msg.RoomID,
+		ActorID:         &actorID,
+		Action:          models.ChatModDeleteMessage,
+		TargetUserID:    &msg.SenderID,
+		TargetMessageID: &msgID,
+		Reason:          reason,
+	})
+	s.publish(ctx, ChatEvent{Type: ChatEventMessageDeleted, RoomID: msg.RoomID, UserID: actorID, MessageID: msgID})
+	return nil
+}
+
+// MuteMember stops a member from posting for the given duration.
+func (s *ChatService) MuteMember(ctx context.Context, roomID, actorID, targetID string, duration time.Duration, reason string) (time.Time, error) {
+	if duration < time.Minute || duration > maxChatMute {
+		return time.Time{}, fmt.Errorf("%w: mute must last between 1 minute and %d days", ErrChatInvalidSettings, int(maxChatMute/(24*time.Hour)))
+	}
+	role, err := s.moderatorRole(ctx, roomID, actorID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := s.checkOutranks(ctx, roomID, role, targetID); err != nil {
+		return time.Time{}, err
+	}
+	until := time.Now().Add(duration).UTC()
+	if err := s.repo.SetMuted(ctx, roomID, targetID, &until); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrChatMemberNotFound
+		}
+		return time.Time{}, err
+	}
+	details, _ := json.Marshal(map[string]time.Time{"muted_until": until})
+	s.audit(ctx, models.ChatModerationEntry{
+		RoomID:       roomID,
+		ActorID:      &actorID,
+		Action:       models.ChatModMute,
+		TargetUserID: &targetID,
+		Reason:       reason,
+		Details:      details,
+	})
+	s.publish(ctx, ChatEvent{Type: ChatEventMemberMuted, RoomID: roomID, UserID: targetID})
+	return until, nil
+}
+
+// UnmuteMember lifts a mute early.
+func (s *ChatService) UnmuteMember(ctx context.Context, roomID, actorID, targetID string) error {
+	role, err := s.moderatorRole(ctx, roomID, actorID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkOutranks(ctx, roomID, role, targetID); err != nil {
+		return err
+	}
+	if err := s.repo.SetMuted(ctx, roomID, targetID, nil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChatMemberNotFound
+		}
+		return err
+	}
+	s.audit(ctx, models.ChatModerationEntry{
+		RoomID:       roomID,
+		ActorID:      &actorID,
+		Action:       models.ChatModUnmute,
+		TargetUserID: &targetID,
+	})
+	s.publish(ctx, ChatEvent{Type: ChatEventMemberUnmuted, RoomID: roomID, UserID: targetID})
+	return nil
+}
+
+// BanMember removes a member from the room and keeps them out until
+// unbanned.
+func (s *ChatService) BanMember(ctx context.Context, roomID, actorID, targetID, reason string) (*models.ChatRoomBan, error) {
+	role, err := s.moderatorRole(ctx, roomID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOutranks(ctx, roomID, role, targetID); err != nil {
+		return nil, err
+	}
+	ban := &models.ChatRoomBan{RoomID: roomID, UserID: targetID, BannedBy: &actorID, Reason: reason}
+	if err := s.repo.BanMember(ctx, ban); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChatMemberNotFound
+		}
+		return nil, err
+	}
+	s.audit(ctx, models.ChatModerationEntry{
+		RoomID:       roomID,
+		ActorID:      &actorID,
+		Action:       models.ChatModBan,
+		TargetUserID: &targetID,
+		Reason:       reason,
+	})
+	// Live connections of the banned user are closed like on removal
+	s.publish(ctx, ChatEvent{Type: ChatEventMemberRemoved, RoomID: roomID, UserID: targetID})
+	return ban, nil
+}
+
+// UnbanMember lifts a ban; the user still has to be added back to the room.
+// A banned user is no longer a member, so the ban also keeps the rank of
+// whoever placed it: only they or someone at least as senior may lift it.
+func (s *ChatService) UnbanMember(ctx context.Context, roomID, actorID, targetID string) error {
+	role, err := s.moderatorRole(ctx, roomID, actorID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkOutranks(ctx, roomID, role, targetID); err != nil {
+		return err
+	}
+	bans, err := s.repo.ListBans(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	for _, ban := range bans {
+		if ban.UserID != targetID || ban.BannedBy == nil || *ban.BannedBy == actorID {
+			continue
+		}
+		bannerRole, err := s.repo.GetMemberRole(ctx, roomID, *ban.BannedBy)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if roomRank(bannerRole) > roomRank(role) {
+			return ErrChatModerationForbidden
+		}
+	}
+	found, err := s.repo.UnbanMember(ctx, roomID, targetID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrChatMemberNotFound
+	}
+	s.audit(ctx, models.ChatModerationEntry{
+		RoomID:       roomID,
+		ActorID:      &actorID,
+		Action:       models.ChatModUnban,
+		TargetUserID: &targetID,
+	})
+	return nil
+}
+
+// ListBans returns a room's bans to its admins and moderators.
+func (s *ChatService) ListBans(ctx context.Context, roomID, actorID string) ([]models.ChatRoomBan, error) {
+	if _, err := s.moderatorRole(ctx, roomID, actorID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListBans(ctx, roomID)
+}
+
+// ListModerationLog returns a room's moderation log to its admins and
+// moderators, newest first.
+func (s *ChatService) ListModerationLog(ctx context.Context, roomID, actorID string, limit int, before *time.Time) ([]models.ChatModerationEntry, error) {
+	if _, err := s.moderatorRole(ctx, roomID, actorID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListModerationLog(ctx, roomID, limit, before)
+}
+
+// UpdateRoomSettings changes who may post and how long messages are kept.
+// Only room admins may do it, and only channels can be made read-only.
+func (s *ChatService) UpdateRoomSettings(ctx context.Context, roomID, actorID string, settings ChatRoomSettings) (*models.ChatRoom, error) {
+	role, err := s.moderatorRole(ctx, roomID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.ChatRoomMemberRoleAdmin {
+		return nil, ErrChatModerationForbidden
+	}
+	room, err := s.repo.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.PostPolicy == "" {
+		settings.PostPolicy = models.ChatPostPolicyEveryone
+	}
+	if settings.RetentionAction == "" {
+		settings.RetentionAction = models.ChatRetentionDelete
+	}
+	switch {
+	case settings.PostPolicy != models.ChatPostPolicyEveryone && settings.PostPolicy != models.ChatPostPolicyModerators:
+		return nil, fmt.Errorf("%w: unknown post_policy %q", ErrChatInvalidSettings, settings.PostPolicy)
+	case settings.PostPolicy == models.ChatPostPolicyModerators && room.Type != models.ChatRoomTypeChannel:
+		return nil, fmt.Errorf("%w: only channels can be read-only", ErrChatInvalidSettings)
+	case settings.RetentionAction != models.ChatRetentionDelete && settings.RetentionAction != models.ChatRetentionArchive:
+		return nil, fmt.Errorf("%w: unknown retention_action %q", ErrChatInvalidSettings, settings.RetentionAction)
+	case settings.RetentionDays != nil && (*settings.RetentionDays < 1 || *settings.RetentionDays > maxChatRetentionDays):
+		return nil, fmt.Errorf("%w: retention_days must be between 1 and %d", ErrChatInvalidSettings, maxChatRetentionDays)
+	}
+
+	updated, err := s.repo.UpdateRoomSettings(ctx, roomID, settings.PostPolicy, settings.RetentionDays, settings.RetentionAction)
+	if err != nil {
+		return nil, err
+	}
+	details, _ := json.Marshal(settings)
+	s.audit(ctx, models.ChatModerationEntry{
+		RoomID:  roomID,
+		ActorID: &actorID,
+		Action:  models.ChatModSettings,
+		Details: details,
+	})
+	s.publish(ctx, ChatEvent{Type: ChatEventRoomUpdated, RoomID: roomID, UserID: actorID})
+	return updated, nil
+}
+
+// ApplyRetention removes messages past each room's retention, deleting or
+// archiving them per the room's policy. A thread expires as a whole once
+// neither the parent nor any reply is newer than the cutoff; pinned messages
+// are kept. It returns the number of messages removed; a failing room does
+// not stop the others.
+func (s *ChatService) ApplyRetention(ctx context.Context, now time.Time) (int, error) {
+	rooms, err := s.repo.ListRetentionRooms(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	var errs []error
+	for _, room := range rooms {
+		if room.RetentionDays == nil {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -*room.RetentionDays)
+		archive := room.RetentionAction == models.ChatRetentionArchive
+		removed := 0
+		for {
+			n, err := s.repo.PurgeExpiredMessages(ctx, room.ID, cutoff, archive, chatRetentionBatch)
+			removed += n
+			if err != nil {
+				errs = append(errs, fmt.Errorf("room %s: %w", room.ID, err))
+				break
+			}
+			if n == 0 || ctx.Err() != nil {
+				break
+			}
+		}
+		if removed == 0 {
+			continue
+		}
+		total += removed
+		action := models.ChatModRetentionDelete
+		if archive {
+			action = models.ChatModRetentionArchive
+		}
+		details, _ := json.Marshal(map[string]interface{}{"messages": removed, "before": cutoff})
+		s.audit(ctx, models.ChatModerationEntry{RoomID: room.ID, Action: action, Details: details})
+	}
+	return total, errors.Join(errs...)
+}
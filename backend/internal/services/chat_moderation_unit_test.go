@@ -0,0 +1,329 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModerationRepo serves a room with an admin, two moderators and a member.
+func newModerationRepo(roomType models.ChatRoomType) (*MockChatRepository, map[string]*models.ChatRoomMember, *[]models.ChatModerationEntry) {
+	members := map[string]*models.ChatRoomMember{
+		"owner": {UserID: "owner", RoleInRoom: models.ChatRoomMemberRoleAdmin},
+		"mod":   {UserID: "mod", RoleInRoom: models.ChatRoomMemberRoleModerator},
+		"mod2":  {UserID: "mod2", RoleInRoom: models.ChatRoomMemberRoleModerator},
+		"alice": {UserID: "alice", RoleInRoom: models.ChatRoomMemberRoleMember},
+	}
+	room := &models.ChatRoom{ID: "r1", Type: roomType, PostPolicy: models.ChatPostPolicyEveryone}
+	var log []models.ChatModerationEntry
+
+	repo := NewMockChatRepository()
+	repo.GetRoomFunc = func(ctx context.Context, r string) (*models.ChatRoom, error) {
+		copied := *room
+		return &copied, nil
+	}
+	repo.GetMemberFunc = func(ctx context.Context, r, u string) (*models.ChatRoomMember, error) {
+		if m, ok := members[u]; ok {
+			copied := *m
+			return &copied, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+	repo.IsMemberFunc = func(ctx context.Context, r, u string) (bool, error) {
+		_, ok := members[u]
+		return ok, nil
+	}
+	repo.GetMemberRoleFunc = func(ctx context.Context, r, u string) (models.ChatRoomMemberRole, error) {
+		if m, ok := members[u]; ok {
+			return m.RoleInRoom, nil
+		}
+		return "", sql.ErrNoRows
+	}
+	repo.SetMutedFunc = func(ctx context.Context, r, u string, until *time.Time) error {
+		m, ok := members[u]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		m.MutedUntil = until
+		return nil
+	}
+	repo.UpdateRoomSettingsFunc = func(ctx context.Context, r string, p models.ChatPostPolicy, d *int, a models.ChatRetentionAction) (*models.ChatRoom, error) {
+		room.PostPolicy, room.RetentionDays, room.RetentionAction = p, d, a
+		copied := *room
+		return &copied, nil
+	}
+	repo.CreateModerationEntryFunc = func(ctx context.Context, e *models.ChatModerationEntry) error {
+		log = append(log, *e)
+		return nil
+	}
+	return repo, members, &log
+}
+
+func TestChatService_MuteAndPostPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo, members, log := newModerationRepo(models.ChatRoomTypeChannel)
+	svc, sub := newThreadTestService(repo)
+	defer sub.Close()
+
+	t.Run("Members cannot moderate and moderators cannot mute their peers", func(t *testing.T) {
+		_, err := svc.MuteMember(ctx, "r1", "alice", "mod", time.Hour, "")
+		assert.ErrorIs(t, err, services.ErrChatModerationForbidden)
+		_, err = svc.MuteMember(ctx, "r1", "mod", "mod2", time.Hour, "")
+		assert.ErrorIs(t, err, services.ErrChatModerationForbidden)
+		_, err = svc.MuteMember(ctx, "r1", "mod", "owner", time.Hour, "")
+		assert.ErrorIs(t, err, services.ErrChatModerationForbidden)
+		_, err = svc.MuteMember(ctx, "r1", "stranger", "alice", time.Hour, "")
+		assert.ErrorIs(t, err, services.ErrChatNotMember)
+		_, err = svc.MuteMember(ctx, "r1", "mod", "alice", 0, "")
+		assert.ErrorIs(t, err, services.ErrChatInvalidSettings)
+		_, err = svc.MuteMember(ctx, "r1", "mod", "alice", 30*time.Second, "")
+		assert.ErrorIs(t, err, services.ErrChatInvalidSettings, "mutes last at least a minute")
+		assert.Empty(t, *log)
+	})
+
+	t.Run("A muted member cannot post until the mute ends", func(t *testing.T) {
+		until, err := svc.MuteMember(ctx, "r1", "mod", "alice", time.Hour, "spam")
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Second)
+		assert.Equal(t, services.ChatEventMemberMuted, (<-sub.C).Type)
+
+		_, err = svc.CreateMessage(ctx, "r1", "alice", "hi", nil, nil, nil)
+		assert.ErrorIs(t, err, services.ErrChatMuted)
+		_, err = svc.CreateReply(ctx, "p1", "alice", "hi", nil, nil)
+		assert.ErrorIs(t, err, services.ErrChatMuted)
+
+		past := time.Now().Add(-time.Minute)
+		members["alice"].MutedUntil = &past
+		_, err = svc.CreateMessage(ctx, "r1", "alice", "hi", nil, nil, nil)
+		assert.NoError(t, err, "expired mutes do not count")
+		<-sub.C
+
+		require.Len(t, *log, 1)
+		assert.Equal(t, models.ChatModMute, (*log)[0].Action)
+		assert.Equal(t, "alice", *(*log)[0].TargetUserID)
+		assert.Equal(t, "spam", (*log)[0].Reason)
+	})
+
+	t.Run("Read-only channels", func(t *testing.T) {
+		_, err := svc.UpdateRoomSettings(ctx, "r1", "mod", services.ChatRoomSettings{PostPolicy: models.ChatPostPolicyModerators})
+		assert.ErrorIs(t, err, services.ErrChatModerationForbidden, "settings are for room admins")
+
+		room, err := svc.UpdateRoomSettings(ctx, "r1", "owner", services.ChatRoomSettings{PostPolicy: models.ChatPostPolicyModerators})
+		require.NoError(t, err)
+		assert.Equal(t, models.ChatRetentionDelete, room.RetentionAction)
+		assert.Equal(t, services.ChatEventRoomUpdated, (<-sub.C).Type)
+
+		_, err = svc.CreateMessage(ctx, "r1", "alice", "hi", nil, nil, nil)
+		assert.ErrorIs(t, err, services.ErrChatReadOnly)
+		_, err = svc.CreateMessage(ctx, "r1", "mod", "announcement", nil, nil, nil)
+		assert.NoError(t, err)
+		<-sub.C
+		_, err = svc.CreateMessage(ctx, "r1", "stranger", "hi", nil, nil, nil)
+		assert.ErrorIs(t, err, services.ErrChatNotMember)
+	})
+
+	t.Run("Settings are validated", func(t *testing.T) {
+		zero, tooLong := 0, 5000
+		for _, bad := range []services.ChatRoomSettings{
+			{PostPolicy: "nobody"},
+			{RetentionDays: &zero},
+			{RetentionDays: &tooLong},
+			{RetentionAction: "shred"},
+		} {
+			_, err := svc.UpdateRoomSettings(ctx, "r1", "owner", bad)
+			assert.ErrorIs(t, err, services.ErrChatInvalidSettings)
+		}
+
+		groupRepo, _, _ := newModerationRepo(models.ChatRoomTypeGroup)
+		group := services.NewChatService(groupRepo, nil, config.AppConfig{})
+		_, err := group.UpdateRoomSettings(ctx, "r1", "owner", services.ChatRoomSettings{PostPolicy: models.ChatPostPolicyModerators})
+		assert.ErrorIs(t, err, services.ErrChatInvalidSettings, "only channels can be read-only")
+	})
+}
+
+func TestChatService_RemoveMessageAndBans(t *testing.T) {
+	ctx := context.Background()
+	repo, _, log := newModerationRepo(models.ChatRoomTypeGroup)
+	senders := map[string]string{"m-alice": "alice", "m-mod2": "mod2", "m-mod": "mod"}
+	repo.GetMessageFunc = func(ctx context.Context, id string) (*models.ChatMessage, error) {
+		sender, ok := senders[id]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		return &models.ChatMessage{ID: id, RoomID: "r1", SenderID: sender}, nil
+	}
+	var removed []string
+	repo.RemoveMessageFunc = func(ctx context.Context, id string) error {
+		removed = append(removed, id)
+		return nil
+	}
+	var banned []string
+	repo.BanMemberFunc = func(ctx context.Context, b *models.ChatRoomBan) error {
+		if b.UserID == "stranger" {
+			return sql.ErrNoRows
+		}
+		banned = append(banned, b.UserID)
+		return nil
+	}
+	repo.IsBannedFunc = func(ctx context.Context, r, u string) (bool, error) {
+		for _, b := range banned {
+			if b == u {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	svc, sub := newThreadTestService(repo)
+	defer sub.Close()
+
+	assert.ErrorIs(t, svc.RemoveMessage(ctx, "m-alice", "alice2", ""), services.ErrChatNotMember)
+	assert.ErrorIs(t, svc.RemoveMessage(ctx, "m-mod2", "mod", ""), services.ErrChatModerationForbidden)
+	assert.ErrorIs(t, svc.RemoveMessage(ctx, "nope", "mod", ""), services.ErrChatMessageNotFound)
+	require.NoError(t, svc.RemoveMessage(ctx, "m-alice", "mod", "off topic"))
+	require.NoError(t, svc.RemoveMessage(ctx, "m-mod2", "owner", ""))
+	require.NoError(t, svc.RemoveMessage(ctx, "m-mod", "mod", ""), "own messages are always fair game")
+	assert.Equal(t, []string{"m-alice", "m-mod2", "m-mod"}, removed)
+	ev := <-sub.C
+	assert.Equal(t, services.ChatEventMessageDeleted, ev.Type)
+	assert.Equal(t, "m-alice", ev.MessageID)
+	<-sub.C
+	<-sub.C
+
+	_, err := svc.BanMember(ctx, "r1", "mod", "stranger", "")
+	assert.ErrorIs(t, err, services.ErrChatMemberNotFound)
+	_, err = svc.BanMember(ctx, "r1", "mod", "alice", "abuse")
+	require.NoError(t, err)
+	ev = <-sub.C
+	assert.Equal(t, services.ChatEventMemberRemoved, ev.Type, "banned users are disconnected")
+	assert.Equal(t, "alice", ev.UserID)
+
+	assert.ErrorIs(t, svc.AddMember(ctx, "r1", "alice", models.ChatRoomMemberRoleMember), services.ErrChatBanned)
+	var added []string
+	repo.AddMemberFunc = func(ctx context.Context, r, u string, rl models.ChatRoomMemberRole) error {
+		added = append(added, u)
+		return nil
+	}
+	n, err := svc.AddRoomMembersBatch(ctx, "r1", []string{"alice", "bob"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"bob"}, added)
+
+	actions := make([]string, 0, len(*log))
+	for _, e := range *log {
+		actions = append(actions, e.Action)
+	}
+	assert.Equal(t, []string{models.ChatModDeleteMessage, models.ChatModDeleteMessage, models.ChatModDeleteMessage, models.ChatModBan}, actions)
+	assert.Equal(t, "m-alice", *(*log)[0].TargetMessageID)
+}
+
+func TestChatService_LiftingRespectsRank(t *testing.T) {
+	ctx := context.Background()
+	repo, members, _ := newModerationRepo(models.ChatRoomTypeGroup)
+	var bans []models.ChatRoomBan
+	repo.BanMemberFunc = func(ctx context.Context, b *models.ChatRoomBan) error {
+		delete(members, b.UserID)
+		bans = append(bans, *b)
+		return nil
+	}
+	repo.ListBansFunc = func(ctx context.Context, r string) ([]models.ChatRoomBan, error) {
+		return bans, nil
+	}
+	repo.UnbanMemberFunc = func(ctx context.Context, r, u string) (bool, error) {
+		for i, b := range bans {
+			if b.UserID == u {
+				bans = append(bans[:i], bans[i+1:]...)
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	svc, sub := newThreadTestService(repo)
+	defer sub.Close()
+
+	t.Run("Moderators cannot unmute their peers", func(t *testing.T) {
+		until := time.Now().Add(time.Hour)
+		members["mod2"].MutedUntil = &until
+		assert.ErrorIs(t, svc.UnmuteMember(ctx, "r1", "mod", "mod2"), services.ErrChatModerationForbidden)
+		assert.NotNil(t, members["mod2"].MutedUntil)
+		require.NoError(t, svc.UnmuteMember(ctx, "r1", "owner", "mod2"))
+		assert.Nil(t, members["mod2"].MutedUntil)
+		<-sub.C
+	})
+
+	t.Run("Moderators cannot lift a ban placed by an admin", func(t *testing.T) {
+		_, err := svc.BanMember(ctx, "r1", "owner", "mod2", "")
+		require.NoError(t, err)
+		<-sub.C
+		assert.ErrorIs(t, svc.UnbanMember(ctx, "r1", "mod", "mod2"), services.ErrChatModerationForbidden)
+		assert.Len(t, bans, 1)
+		require.NoError(t, svc.UnbanMember(ctx, "r1", "owner", "mod2"))
+		assert.Empty(t, bans)
+	})
+
+	t.Run("Moderators lift their own bans", func(t *testing.T) {
+		_, err := svc.BanMember(ctx, "r1", "mod", "alice", "")
+		require.NoError(t, err)
+		<-sub.C
+		require.NoError(t, svc.UnbanMember(ctx, "r1", "mod", "alice"))
+		assert.Empty(t, bans)
+	})
+}
+
+func TestChatService_ApplyRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	thirty, seven := 30, 7
+	repo := NewMockChatRepository()
+	repo.ListRetentionRoomsFunc = func(ctx context.Context) ([]models.ChatRoom, error) {
+		return []models.ChatRoom{
+			{ID: "purge", RetentionDays: &thirty, RetentionAction: models.ChatRetentionDelete},
+			{ID: "archive", RetentionDays: &seven, RetentionAction: models.ChatRetentionArchive},
+			{ID: "broken", RetentionDays: &seven, RetentionAction: models.ChatRetentionDelete},
+			{ID: "quiet", RetentionDays: &seven, RetentionAction: models.ChatRetentionDelete},
+		}, nil
+	}
+	// "purge" has 700 expired messages, "archive" 3
+	left := map[string]int{"purge": 700, "archive": 3}
+	var calls []string
+	repo.PurgeExpiredMessagesFunc = func(ctx context.Context, r string, before time.Time, archive bool, limit int) (int, error) {
+		calls = append(calls, r)
+		switch r {
+		case "purge":
+			assert.Equal(t, now.AddDate(0, 0, -30), before)
+			assert.False(t, archive)
+		case "archive":
+			assert.Equal(t, now.AddDate(0, 0, -7), before)
+			assert.True(t, archive)
+		case "broken":
+			return 0, errors.New("db down")
+		}
+		n := min(left[r], limit)
+		left[r] -= n
+		return n, nil
+	}
+	var log []models.ChatModerationEntry
+	repo.CreateModerationEntryFunc = func(ctx context.Context, e *models.ChatModerationEntry) error {
+		log = append(log, *e)
+		return nil
+	}
+	svc := services.NewChatService(repo, nil, config.AppConfig{})
+
+	n, err := svc.ApplyRetention(ctx, now)
+	assert.ErrorContains(t, err, "broken")
+	assert.Equal(t, 703, n, "a failing room does not stop the others")
+	assert.Equal(t, []string{"purge", "purge", "purge", "archive", "archive", "broken", "quiet"}, calls)
+
+	require.Len(t, log, 2, "rooms with nothing to remove are not logged")
+	assert.Equal(t, models.ChatModRetentionDelete, log[0].Action)
+	assert.Nil(t, log[0].ActorID)
+	assert.JSONEq(t, `{"messages":700,"before":"2026-01-30T12:00:00Z"}`, string(log[0].Details))
+	assert.Equal(t, models.ChatModRetentionArchive, log[1].Action)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Chat event types pushed to connected clients.
+const (
+	ChatEventMessageCreated  = "message.created"
+	ChatEventMessageUpdated  = "message.updated"
+	ChatEventMessageDeleted  = "message.deleted"
+	ChatEventRead            = "room.read"
+	ChatEventTyping          = "typing"
+	ChatEventMemberRemoved   = "member.removed"
+	ChatEventReactionAdded   = "reaction.added"
+	ChatEventReactionRemoved = "reaction.removed"
+	ChatEventMessagePinned   = "message.pinned"
+	ChatEventMessageUnpinned = "message.unpinned"
+	ChatEventMemberMuted     = "member.muted"
+	ChatEventMemberUnmuted   = "member.unmuted"
+	ChatEventRoomUpdated     = "room.updated"
+)
+
+// chatChannelPrefix namespaces the per-room Redis pub/sub channels.
+const chatChannelPrefix = "chat:room:"
+
+// ChatEvent is a single real-time update for a room.
+type ChatEvent struct {
+	Type      string              `json:"type"`
+	RoomID    string              `json:"room_id"`
+	UserID    string              `json:"user_id,omitempty"`
+	MessageID string              `json:"message_id,omitempty"`
+	Message   *models.ChatMessage `json:"message,omitempty"`
+	Emoji     string              `json:"emoji,omitempty"`
+	At        time.Time           `json:"at"`
+}
+
+// ChatSubscription receives the events of one room.
+type ChatSubscription = Subscription[ChatEvent]
+
+// ChatHub fans chat events out to the connections subscribed to a room,
+// across replicas when Redis is configured.
+type ChatHub struct {
+	events *eventHub[ChatEvent]
+}
+
+func NewChatHub(rds *redis.Client) *ChatHub {
+	return &ChatHub{events: newEventHub[ChatEvent](rds, chatChannelPrefix, "ChatHub")}
+}
+
+// Subscribe registers a subscriber for a room's events.
+func (h *ChatHub) Subscribe(roomID string) *ChatSubscription {
+	return h.events.subscribe(roomID)
+}
+
+// Publish delivers an event to every subscriber of its room.
+func (h *ChatHub) Publish(ctx context.Context, ev ChatEvent) error {
+	if ev.At.IsZero() {
+		ev.At = time.Now().UTC()
+	}
+	return h.events.publish(ctx, ev.RoomID, ev)
+}
+
+// Run relays events published by other replicas until ctx is cancelled.
+func (h *ChatHub) Run(ctx context.Context) {
+	h.events.run(ctx)
+}
+
+// Subscribers reports how many local connections follow a room.
+func (h *ChatHub) Subscribers(roomID string) int {
+	return h.events.subscribers(roomID)
+}
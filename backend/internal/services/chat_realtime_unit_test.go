@@ -0,0 +1,117 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nextEvent(t *testing.T, sub *services.ChatSubscription) services.ChatEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event delivered")
+		return services.ChatEvent{}
+	}
+}
+
+func TestChatHub_LocalFanOut(t *testing.T) {
+	hub := services.NewChatHub(nil)
+	ctx := context.Background()
+
+	a1, a2, b := hub.Subscribe("room-a"), hub.Subscribe("room-a"), hub.Subscribe("room-b")
+	defer b.Close()
+	assert.Equal(t, 2, hub.Subscribers("room-a"))
+
+	require.NoError(t, hub.Publish(ctx, services.ChatEvent{Type: services.ChatEventTyping, RoomID: "room-a", UserID: "u1"}))
+	for _, sub := range []*services.ChatSubscription{a1, a2} {
+		ev := nextEvent(t, sub)
+		assert.Equal(t, "u1", ev.UserID)
+		assert.False(t, ev.At.IsZero())
+	}
+	assert.Empty(t, b.C, "other rooms do not see the event")
+
+	a1.Close()
+	a1.Close()
+	_, open := <-a1.C
+	assert.False(t, open)
+	assert.Equal(t, 1, hub.Subscribers("room-a"))
+	a2.Close()
+	assert.Zero(t, hub.Subscribers("room-a"))
+}
+
+func TestChatHub_DropsSlowSubscriber(t *testing.T) {
+	hub := services.NewChatHub(nil)
+	sub := hub.Subscribe("r1")
+	for i := 0; i < 100; i++ {
+		_ = hub.Publish(context.Background(), services.ChatEvent{Type: services.ChatEventTyping, RoomID: "r1"})
+	}
+	assert.Zero(t, hub.Subscribers("r1"))
+
+	n := 0
+	for range sub.C {
+		n++
+	}
+	assert.Equal(t, 64, n, "buffered events drain, then the channel is closed")
+}
+
+func TestChatService_PublishesRoomEvents(t *testing.T) {
+	repo := NewMockChatRepository()
+	repo.CreateMessageFunc = func(ctx context.Context, roomID, senderID, body string, att models.ChatAttachments, imp *string, meta json.RawMessage) (*models.ChatMessage, error) {
+		return &models.ChatMessage{ID: "m1", RoomID: roomID, SenderID: senderID, Body: body}, nil
+	}
+	repo.UpdateMessageFunc = func(ctx context.Context, msgID, userID, body string) (*models.ChatMessage, error) {
+		return &models.ChatMessage{ID: msgID, RoomID: "r1", SenderID: userID, Body: body}, nil
+	}
+	repo.DeleteMessageFunc = func(ctx context.Context, msgID, userID string) (string, error) {
+		return "r1", nil
+	}
+	hub := services.NewChatHub(nil)
+	svc := services.NewChatService(repo, NewManualEmailSender(), config.AppConfig{})
+	svc.SetHub(hub)
+	sub := hub.Subscribe("r1")
+	defer sub.Close()
+	ctx := context.Background()
+
+	_, err := svc.CreateMessage(ctx, "r1", "u1", "hello", nil, nil, nil)
+	require.NoError(t, err)
+	ev := nextEvent(t, sub)
+	assert.Equal(t, services.ChatEventMessageCreated, ev.Type)
+	require.NotNil(t, ev.Message)
+	assert.Equal(t, "hello", ev.Message.Body)
+
+	_, err = svc.UpdateMessage(ctx, "m1", "u1", "edited")
+	require.NoError(t, err)
+	ev = nextEvent(t, sub)
+	assert.Equal(t, services.ChatEventMessageUpdated, ev.Type)
+	assert.Equal(t, "edited", ev.Message.Body)
+
+	require.NoError(t, svc.DeleteMessage(ctx, "m1", "u1"))
+	ev = nextEvent(t, sub)
+	assert.Equal(t, services.ChatEventMessageDeleted, ev.Type)
+	assert.Equal(t, "m1", ev.MessageID)
+	assert.Nil(t, ev.Message)
+
+	require.NoError(t, svc.MarkRoomAsRead(ctx, "r1", "u2"))
+	ev = nextEvent(t, sub)
+	assert.Equal(t, services.ChatEventRead, ev.Type)
+	assert.Equal(t, "u2", ev.UserID)
+
+	svc.Typing(ctx, "r1", "u2")
+	assert.Equal(t, services.ChatEventTyping, nextEvent(t, sub).Type)
+
+	require.NoError(t, svc.RemoveMember(ctx, "r1", "u2"))
+	ev = nextEvent(t, sub)
+	assert.Equal(t, services.ChatEventMemberRemoved, ev.Type)
+	assert.Equal(t, "u2", ev.UserID)
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+)
+
+// RoomSyncer keeps managed chat rooms in step with the users, cohorts and
+// advisor links they are built from. Services that change those call it
+// after a successful write.
+type RoomSyncer interface {
+	SyncTenantRooms(ctx context.Context, tenantID string)
+}
+
+// ChatRoomSyncChange is what a sync does, or would do, to one managed room.
+type ChatRoomSyncChange struct {
+	RoomID  string                     `json:"room_id,omitempty"`
+	Kind    models.ChatManagedKind     `json:"kind"`
+	Ref     string                     `json:"ref"`
+	Name    string                     `json:"name"`
+	Create  bool                       `json:"create,omitempty"`
+	Adopt   bool                       `json:"adopt,omitempty"`
+	Rename  bool                       `json:"rename,omitempty"`
+	Archive *bool                      `json:"archive,omitempty"`
+	Add     []models.ChatManagedMember `json:"add,omitempty"`
+	Remove  []string                   `json:"remove,omitempty"`
+
+	// adopted are the current members of an adopted room that become managed
+	adopted []string
+}
+
+// ChatRoomSyncReport lists the changes of a sync. With DryRun nothing was
+// applied.
+type ChatRoomSyncReport struct {
+	TenantID string               `json:"tenant_id"`
+	DryRun   bool                 `json:"dry_run"`
+	Rooms    []ChatRoomSyncChange `json:"rooms"`
+	Created  int                  `json:"created"`
+	Adopted  int                  `json:"adopted"`
+	Added    int                  `json:"added"`
+	Removed  int                  `json:"removed"`
+}
+
+// SyncRooms reconciles a tenant's managed rooms with its cohorts and advisor
+// links: every cohort gets a room of its active students, archived with the
+// cohort, and every advisor a room of their students with the advisor as
+// admin. Members added by hand are never removed and banned users are never
+// added back. Rooms whose cohort or advisor is gone are archived, not
+// deleted. A cohort or advisory room made by hand is adopted rather than
+// duplicated when it has the wanted room's name or mostly its members, and
+// an advisory room also the advisor. Cohort rooms created here are owned by
+// actorID, or by a tenant admin when it is empty.
+func (s *ChatService) SyncRooms(ctx context.Context, tenantID, actorID string, dryRun bool) (*ChatRoomSyncReport, error) {
+	plan, err := s.planRoomSync(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	report := &ChatRoomSyncReport{TenantID: tenantID, DryRun: dryRun, Rooms: plan}
+	if report.Rooms == nil {
+		report.Rooms = []ChatRoomSyncChange{}
+	}
+	for _, change := range plan {
+		if change.Create {
+			report.Created++
+		}
+		if change.Adopt {
+			report.Adopted++
+		}
+		report.Added += len(change.Add)
+		report.Removed += len(change.Remove)
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	var errs []error
+	for i := range report.Rooms {
+		if err := s.applyRoomSync(ctx, tenantID, actorID, &report.Rooms[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s room %s: %w", report.Rooms[i].Kind, report.Rooms[i].Ref, err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// SyncTenantRooms applies a sync and only logs failures, so a chat problem
+// never fails the change that triggered it.
+func (s *ChatService) SyncTenantRooms(ctx context.Context, tenantID string) {
+	if tenantID == "" {
+		return
+	}
+	report, err := s.SyncRooms(ctx, tenantID, "", false)
+	if err != nil {
+		log.Printf("[ChatRoomSync] tenant %s: %v", tenantID, err)
+	}
+	if report != nil && len(report.Rooms) > 0 {
+		log.Printf("[ChatRoomSync] tenant %s: %d rooms changed, %d created, %d adopted, %d members added, %d removed",
+			tenantID, len(report.Rooms), report.Created, report.Adopted, report.Added, report.Removed)
+	}
+}
+
+// SyncAllRooms syncs the rooms of every tenant that has cohorts, advisor
+// links or managed rooms.
+func (s *ChatService) SyncAllRooms(ctx context.Context) error {
+	tenants, err := s.repo.ListManagedRoomTenants(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tenantID := range tenants {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.SyncTenantRooms(ctx, tenantID)
+	}
+	return nil
+}
+
+// planRoomSync diffs the rooms the tenant should have against its managed
+// rooms, falling back to its unmanaged cohort and advisory rooms, and
+// returns the rooms that need a change.
+func (s *ChatService) planRoomSync(ctx context.Context, tenantID string) ([]ChatRoomSyncChange, error) {
+	cohorts, err := s.repo.ListCohortRoomSpecs(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	advisory, err := s.repo.ListAdvisoryRoomSpecs(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.ListManagedRooms(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	adoptable, err := s.repo.ListAdoptableRooms(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.ChatManagedRoom, len(existing))
+	for i := range existing {
+		byKey[string(existing[i].Kind)+"/"+existing[i].Ref] = &existing[i]
+	}
+
+	for i := range advisory {
+		advisory[i].Members = append([]models.ChatManagedMember{{UserID: advisory[i].Ref, Role: models.ChatRoomMemberRoleAdmin}}, advisory[i].Members...)
+	}
+
+	var plan []ChatRoomSyncChange
+	for _, want := range append(cohorts, advisory...) {
+		key := string(want.Kind) + "/" + want.Ref
+		have := byKey[key]
+		delete(byKey, key)
+
+		change := ChatRoomSyncChange{Kind: want.Kind, Ref: want.Ref, Name: want.Name}
+		wanted := map[string]bool{}
+		for _, m := range want.Members {
+			wanted[m.UserID] = true
+		}
+		if have == nil {
+			if have = takeAdoptableRoom(&adoptable, want); have != nil {
+				// Members the sync would have added become its own
+				change.Adopt = true
+				for i, m := range have.Members {
+					if wanted[m.UserID] && !m.Banned {
+						have.Members[i].Managed = true
+						change.adopted = append(change.adopted, m.UserID)
+					}
+				}
+			}
+		}
+
+		current := map[string]models.ChatManagedMember{}
+		if have == nil {
+			change.Create = true
+			if want.IsArchived {
+				archived := true
+				change.Archive = &archived
+			}
+		} else {
+			change.RoomID = have.ID
+			change.Rename = have.Name != want.Name
+			if have.IsArchived != want.IsArchived {
+				archived := want.IsArchived
+				change.Archive = &archived
+			}
+			for _, m := range have.Members {
+				current[m.UserID] = m
+			}
+		}
+
+		for _, m := range want.Members {
+			cur, ok := current[m.UserID]
+			switch {
+			case !ok:
+				change.Add = append(change.Add, m)
+			case cur.Banned:
+			case cur.Managed && cur.Role != m.Role:
+				change.Add = append(change.Add, m)
+			}
+		}
+		if have != nil {
+			for _, m := range have.Members {
+				if m.Managed && !m.Banned && !wanted[m.UserID] {
+					change.Remove = append(change.Remove, m.UserID)
+				}
+			}
+		}
+
+		if change.Create || change.Adopt || change.Rename || change.Archive != nil || len(change.Add) > 0 || len(change.Remove) > 0 {
+			plan = append(plan, change)
+		}
+	}
+
+	// Whatever is left lost its cohort or advisor
+	for _, room := range existing {
+		if _, orphaned := byKey[string(room.Kind)+"/"+room.Ref]; !orphaned || room.IsArchived {
+			continue
+		}
+		archived := true
+		plan = append(plan, ChatRoomSyncChange{RoomID: room.ID, Kind: room.Kind, Ref: room.Ref, Name: room.Name, Archive: &archived})
+	}
+	return plan, nil
+}
+
+// takeAdoptableRoom removes and returns the unmanaged room that stands for
+// want, preferring one with the same name, or nil when there is none.
+func takeAdoptableRoom(rooms *[]models.ChatManagedRoom, want models.ChatManagedRoom) *models.ChatManagedRoom {
+	pick := -1
+	for i, room := range *rooms {
+		if room.Kind != want.Kind {
+			continue
+		}
+		if room.Name == want.Name {
+			pick = i
+			break
+		}
+		if pick < 0 && sharesMembers(room, want) {
+			pick = i
+		}
+	}
+	if pick < 0 {
+		return nil
+	}
+	room := (*rooms)[pick]
+	*rooms = append((*rooms)[:pick], (*rooms)[pick+1:]...)
+	return &room
+}
+
+// sharesMembers reports whether most members of room are wanted in want,
+// and for an advisory room whether the advisor is one of them.
+func sharesMembers(room, want models.ChatManagedRoom) bool {
+	wanted := map[string]bool{}
+	for _, m := range want.Members {
+		wanted[m.UserID] = true
+	}
+	members, shared, advisor := 0, 0, false
+	for _, m := range room.Members {
+		if m.Banned {
+			continue
+		}
+		members++
+		if wanted[m.UserID] {
+			shared++
+		}
+		advisor = advisor || m.UserID == want.Ref
+	}
+	if want.Kind == models.ChatManagedAdvisory && !advisor {
+		return false
+	}
+	return shared > 0 && shared*2 > members
+}
+
+// applyRoomSync carries out one planned change, filling in the room id of a
+// created room.
+func (s *ChatService) applyRoomSync(ctx context.Context, tenantID, actorID string, change *ChatRoomSyncChange) error {
+	if change.Adopt {
+		if err := s.repo.AdoptManagedRoom(ctx, change.RoomID, change.Kind, change.Ref, change.adopted); err != nil {
+			return fmt.Errorf("adopt: %w", err)
+		}
+	}
+	if change.Create {
+		owner := actorID
+		if change.Kind == models.ChatManagedAdvisory {
+			owner = change.Ref
+		}
+		archived := change.Archive != nil && *change.Archive
+		id, err := s.repo.CreateManagedRoom(ctx, tenantID, models.ChatManagedRoom{
+			Kind: change.Kind, Ref: change.Ref, Name: change.Name, IsArchived: archived,
+		}, owner)
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		change.RoomID = id
+	} else if change.Rename || change.Archive != nil {
+		var name *string
+		if change.Rename {
+			name = &change.Name
+		}
+		if _, err := s.repo.UpdateRoom(ctx, change.RoomID, name, change.Archive); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		s.publish(ctx, ChatEvent{Type: ChatEventRoomUpdated, RoomID: change.RoomID})
+	}
+
+	for _, m := range change.Add {
+		if err := s.repo.AddManagedMember(ctx, tenantID, change.RoomID, m.UserID, m.Role); err != nil {
+			return fmt.Errorf("add %s: %w", m.UserID, err)
+		}
+	}
+	for _, userID := range change.Remove {
+		removed, err := s.repo.RemoveManagedMember(ctx, change.RoomID, userID)
+		if err != nil {
+			return fmt.Errorf("remove %s: %w", userID, err)
+		}
+		if removed {
+			s.publish(ctx, ChatEvent{Type: ChatEventMemberRemoved, RoomID: change.RoomID, UserID: userID})
+		}
+	}
+	return nil
+}
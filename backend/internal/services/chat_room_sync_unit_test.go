@@ -0,0 +1,178 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func managedMember(id string) models.ChatManagedMember {
+	return models.ChatManagedMember{UserID: id, Role: models.ChatRoomMemberRoleMember}
+}
+
+func TestChatService_SyncRooms(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockChatRepository()
+	repo.ListCohortRoomSpecsFunc = func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+		return []models.ChatManagedRoom{
+			{Kind: models.ChatManagedCohort, Ref: "c1", Name: "Cohort 2024", Members: []models.ChatManagedMember{managedMember("s1"), managedMember("s2"), managedMember("s3")}},
+			{Kind: models.ChatManagedCohort, Ref: "c2", Name: "Cohort 2019", IsArchived: true},
+		}, nil
+	}
+	repo.ListAdvisoryRoomSpecsFunc = func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+		return []models.ChatManagedRoom{
+			{Kind: models.ChatManagedAdvisory, Ref: "a1", Name: "Advisory: Ann Lee", Members: []models.ChatManagedMember{managedMember("s1")}},
+		}, nil
+	}
+	repo.ListManagedRoomsFunc = func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+		return []models.ChatManagedRoom{
+			{ID: "r1", Kind: models.ChatManagedCohort, Ref: "c1", Name: "Cohort 2024 (old)", Members: []models.ChatManagedMember{
+				{UserID: "s1", Role: models.ChatRoomMemberRoleMember, Managed: true},
+				{UserID: "s3", Role: models.ChatRoomMemberRoleMember, Banned: true},
+				{UserID: "s4", Role: models.ChatRoomMemberRoleMember, Managed: true},
+				{UserID: "head", Role: models.ChatRoomMemberRoleAdmin},
+			}},
+			{ID: "r9", Kind: models.ChatManagedAdvisory, Ref: "a9", Name: "Advisory: Gone"},
+		}, nil
+	}
+	created := map[string]string{}
+	repo.CreateManagedRoomFunc = func(ctx context.Context, tenantID string, room models.ChatManagedRoom, ownerID string) (string, error) {
+		created[room.Ref] = ownerID
+		assert.Equal(t, room.Ref == "c2", room.IsArchived, "inactive cohorts get an archived room")
+		return "new-" + room.Ref, nil
+	}
+	updates := map[string]*bool{}
+	repo.UpdateRoomFunc = func(ctx context.Context, roomID string, name *string, archived *bool) (*models.ChatRoom, error) {
+		updates[roomID] = archived
+		return &models.ChatRoom{ID: roomID}, nil
+	}
+	var added, removed []string
+	repo.AddManagedMemberFunc = func(ctx context.Context, tenantID, roomID, userID string, role models.ChatRoomMemberRole) error {
+		added = append(added, roomID+":"+userID+":"+string(role))
+		return nil
+	}
+	repo.RemoveManagedMemberFunc = func(ctx context.Context, roomID, userID string) (bool, error) {
+		removed = append(removed, roomID+":"+userID)
+		return true, nil
+	}
+	svc := services.NewChatService(repo, nil, config.AppConfig{})
+
+	t.Run("Dry run only reports", func(t *testing.T) {
+		report, err := svc.SyncRooms(ctx, "t1", "admin", true)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Empty(t, created)
+		assert.Empty(t, added)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 3, report.Added, "s2 to the cohort, the advisor and s1 to the advisory room")
+		assert.Equal(t, 1, report.Removed)
+
+		require.Len(t, report.Rooms, 4)
+		cohort := report.Rooms[0]
+		assert.Equal(t, "r1", cohort.RoomID)
+		assert.True(t, cohort.Rename)
+		assert.Equal(t, []models.ChatManagedMember{managedMember("s2")}, cohort.Add, "banned s3 stays out")
+		assert.Equal(t, []string{"s4"}, cohort.Remove, "hand-added members are kept")
+
+		orphan := report.Rooms[3]
+		assert.Equal(t, "r9", orphan.RoomID)
+		require.NotNil(t, orphan.Archive)
+		assert.True(t, *orphan.Archive)
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		report, err := svc.SyncRooms(ctx, "t1", "admin", false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"c2": "admin", "a1": "a1"}, created, "advisors own their room")
+		assert.Equal(t, "new-a1", report.Rooms[2].RoomID)
+		assert.Contains(t, updates, "r1")
+		assert.True(t, *updates["r9"])
+		assert.ElementsMatch(t, []string{"r1:s2:member", "new-a1:a1:admin", "new-a1:s1:member"}, added)
+		assert.Equal(t, []string{"r1:s4"}, removed)
+	})
+}
+
+func TestChatService_SyncRooms_AdoptsExistingRooms(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockChatRepository()
+	repo.ListCohortRoomSpecsFunc = func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+		return []models.ChatManagedRoom{
+			{Kind: models.ChatManagedCohort, Ref: "c1", Name: "Cohort 2024", Members: []models.ChatManagedMember{managedMember("s1"), managedMember("s2")}},
+		}, nil
+	}
+	repo.ListAdvisoryRoomSpecsFunc = func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+		return []models.ChatManagedRoom{
+			{Kind: models.ChatManagedAdvisory, Ref: "a1", Name: "Advisory: Ann Lee", Members: []models.ChatManagedMember{managedMember("s1")}},
+		}, nil
+	}
+	repo.ListAdoptableRoomsFunc = func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error) {
+		return []models.ChatManagedRoom{
+			{ID: "r7", Kind: models.ChatManagedAdvisory, Name: "Bob's students", Members: []models.ChatManagedMember{
+				{UserID: "a2", Role: models.ChatRoomMemberRoleAdmin}, managedMember("s1"),
+			}},
+			{ID: "r5", Kind: models.ChatManagedCohort, Name: "Cohort 2024", Members: []models.ChatManagedMember{
+				managedMember("s1"), {UserID: "head", Role: models.ChatRoomMemberRoleAdmin},
+			}},
+			{ID: "r6", Kind: models.ChatManagedAdvisory, Name: "Ann's students", Members: []models.ChatManagedMember{
+				{UserID: "a1", Role: models.ChatRoomMemberRoleAdmin}, managedMember("s1"),
+			}},
+		}, nil
+	}
+	adopted := map[string][]string{}
+	repo.AdoptManagedRoomFunc = func(ctx context.Context, roomID string, kind models.ChatManagedKind, ref string, memberIDs []string) error {
+		adopted[roomID] = append([]string{string(kind) + "/" + ref}, memberIDs...)
+		return nil
+	}
+	repo.CreateManagedRoomFunc = func(ctx context.Context, tenantID string, room models.ChatManagedRoom, ownerID string) (string, error) {
+		t.Errorf("room %s was created instead of adopted", room.Ref)
+		return "", nil
+	}
+	var renamed []string
+	repo.UpdateRoomFunc = func(ctx context.Context, roomID string, name *string, archived *bool) (*models.ChatRoom, error) {
+		renamed = append(renamed, roomID)
+		return &models.ChatRoom{ID: roomID}, nil
+	}
+	var added []string
+	repo.AddManagedMemberFunc = func(ctx context.Context, tenantID, roomID, userID string, role models.ChatRoomMemberRole) error {
+		added = append(added, roomID+":"+userID)
+		return nil
+	}
+	svc := services.NewChatService(repo, nil, config.AppConfig{})
+
+	report, err := svc.SyncRooms(ctx, "t1", "admin", false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, 2, report.Adopted)
+	assert.Equal(t, map[string][]string{
+		"r5": {"cohort/c1", "s1"},
+		"r6": {"advisory/a1", "a1", "s1"},
+	}, adopted, "the room with the cohort's name, and the one with the advisor and their students")
+	assert.Equal(t, []string{"r5:s2"}, added, "hand-added members are not re-added")
+	assert.Equal(t, []string{"r6"}, renamed)
+}
+
+// recordingSyncer records the tenants it was asked to sync.
+type recordingSyncer struct{ tenants []string }
+
+func (r *recordingSyncer) SyncTenantRooms(ctx context.Context, tenantID string) {
+	r.tenants = append(r.tenants, tenantID)
+}
+
+func TestDictionaryService_SyncsCohortRooms(t *testing.T) {
+	ctx := context.Background()
+	rooms := &recordingSyncer{}
+	svc := services.NewDictionaryService(NewMockDictionaryRepository())
+	svc.SetRoomSync(rooms)
+
+	_, err := svc.CreateCohort(ctx, "t1", "Cohort 2025", "2025-09-01", "")
+	require.NoError(t, err)
+	require.NoError(t, svc.UpdateCohort(ctx, "t1", "c1", "Cohort 2025/26", "", "", nil))
+	require.NoError(t, svc.DeleteCohort(ctx, "t1", "c1"))
+	_, _ = svc.CreateProgram(ctx, "t1", "Prog", "P1")
+	assert.Equal(t, []string{"t1", "t1", "t1"}, rooms.tenants)
+}
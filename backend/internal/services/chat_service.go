@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -20,6 +21,9 @@ type ChatService struct {
 	repo         repository.ChatRepository
 	emailService EmailSender
 	cfg          config.AppConfig
+	hub          *ChatHub
+	storage      StorageClient
+	docs         *DocumentService
 }
 
 func NewChatService(repo repository.ChatRepository, emailService EmailSender, cfg config.AppConfig) *ChatService {
@@ -30,6 +34,23 @@ func NewChatService(repo repository.ChatRepository, emailService EmailSender, cf
 	}
 }
 
+// SetHub enables real-time delivery of room events. Without a hub clients
+// fall back to polling ListMessages.
+func (s *ChatService) SetHub(hub *ChatHub) {
+	s.hub = hub
+}
+
+// publish pushes an event to the room's live connections. Delivery is
+// best effort: the write already succeeded and clients resync on reconnect.
+func (s *ChatService) publish(ctx context.Context, ev ChatEvent) {
+	if s.hub == nil {
+		return
+	}
+	if err := s.hub.Publish(ctx, ev); err != nil {
+		log.Printf("[Chat] Failed to publish %s for room %s: %v", ev.Type, ev.RoomID, err)
+	}
+}
+
 // CreateRoom creates a new chat room.
 func (s *ChatService) CreateRoom(ctx context.Context, tenantID, name string, roomType models.ChatRoomType, createdBy string, meta json.RawMessage) (*models.ChatRoom, error) {
 	return s.repo.CreateRoom(ctx, tenantID, name, roomType, createdBy, meta)
@@ -60,14 +81,25 @@ func (s *ChatService) IsMember(ctx context.Context, roomID, userID string) (bool
 	return s.repo.IsMember(ctx, roomID, userID)
 }
 
-// AddMember adds a member.
+// AddMember adds a member. Users banned from the room are refused.
 func (s *ChatService) AddMember(ctx context.Context, roomID, userID string, role models.ChatRoomMemberRole) error {
+	banned, err := s.repo.IsBanned(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrChatBanned
+	}
 	return s.repo.AddMember(ctx, roomID, userID, role)
 }
 
 // RemoveMember removes a member.
 func (s *ChatService) RemoveMember(ctx context.Context, roomID, userID string) error {
-	return s.repo.RemoveMember(ctx, roomID, userID)
+	if err := s.repo.RemoveMember(ctx, roomID, userID); err != nil {
+		return err
+	}
+	s.publish(ctx, ChatEvent{Type: ChatEventMemberRemoved, RoomID: roomID, UserID: userID})
+	return nil
 }
 
 // ListMembers lists members.
@@ -75,29 +107,65 @@ func (s *ChatService) ListMembers(ctx context.Context, roomID string) ([]models.
 	return s.repo.ListMembers(ctx, roomID)
 }
 
-// CreateMessage sends a message.
+// CreateMessage sends a message. Muted members, and plain members of
+// read-only channels, cannot post.
 func (s *ChatService) CreateMessage(ctx context.Context, roomID, senderID, body string, attachments models.ChatAttachments, importance *string, meta json.RawMessage) (*models.ChatMessage, error) {
-	return s.repo.CreateMessage(ctx, roomID, senderID, body, attachments, importance, meta)
+	if err := s.checkCanPost(ctx, roomID, senderID); err != nil {
+		return nil, err
+	}
+	msg, err := s.repo.CreateMessage(ctx, roomID, senderID, body, attachments, importance, meta)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, ChatEvent{Type: ChatEventMessageCreated, RoomID: roomID, UserID: senderID, MessageID: msg.ID, Message: msg})
+	return msg, nil
 }
 
 // ListMessages gets messages.
 func (s *ChatService) ListMessages(ctx context.Context, roomID string, limit int, before, after *time.Time) ([]models.ChatMessage, error) {
-	return s.repo.ListMessages(ctx, roomID, limit, before, after)
+	msgs, err := s.repo.ListMessages(ctx, roomID, limit, before, after)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachReactions(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
 }
 
 // UpdateMessage edits a message.
 func (s *ChatService) UpdateMessage(ctx context.Context, msgID, userID, newBody string) (*models.ChatMessage, error) {
-	return s.repo.UpdateMessage(ctx, msgID, userID, newBody)
+	msg, err := s.repo.UpdateMessage(ctx, msgID, userID, newBody)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, ChatEvent{Type: ChatEventMessageUpdated, RoomID: msg.RoomID, UserID: userID, MessageID: msg.ID, Message: msg})
+	return msg, nil
 }
 
 // DeleteMessage deletes a message.
 func (s *ChatService) DeleteMessage(ctx context.Context, msgID, userID string) error {
-	return s.repo.DeleteMessage(ctx, msgID, userID)
+	roomID, err := s.repo.DeleteMessage(ctx, msgID, userID)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, ChatEvent{Type: ChatEventMessageDeleted, RoomID: roomID, UserID: userID, MessageID: msgID})
+	return nil
 }
 
 // MarkRoomAsRead marks room as read.
 func (s *ChatService) MarkRoomAsRead(ctx context.Context, roomID, userID string) error {
-	return s.repo.MarkRoomAsRead(ctx, roomID, userID)
+	if err := s.repo.MarkRoomAsRead(ctx, roomID, userID); err != nil {
+		return err
+	}
+	s.publish(ctx, ChatEvent{Type: ChatEventRead, RoomID: roomID, UserID: userID})
+	return nil
+}
+
+// Typing announces that a member is composing a message. Typing state is
+// never stored; it only reaches currently connected clients.
+func (s *ChatService) Typing(ctx context.Context, roomID, userID string) {
+	s.publish(ctx, ChatEvent{Type: ChatEventTyping, RoomID: roomID, UserID: userID})
 }
 
 // AddRoomMembersBatch adds multiple members and sends notifications.
@@ -115,10 +183,13 @@ func (s *ChatService) AddRoomMembersBatch(ctx context.Context, roomID string, us
 		return 0, nil
 	}
 
-	// 2. Add members
+	// 2. Add members; banned users are skipped
 	count := 0
 	var addedUserIDs []string
 	for _, uid := range userIDs {
+		if banned, err := s.repo.IsBanned(ctx, roomID, uid); err != nil || banned {
+			continue
+		}
 		if err := s.repo.AddMember(ctx, roomID, uid, models.ChatRoomMemberRoleMember); err == nil {
 			count++
 			addedUserIDs = append(addedUserIDs, uid)
@@ -182,6 +253,7 @@ func (s *ChatService) RemoveRoomMembersBatch(ctx context.Context, roomID string,
 	for _, uid := range userIDs {
 		if err := s.repo.RemoveMember(ctx, roomID, uid); err == nil {
 			count++
+			s.publish(ctx, ChatEvent{Type: ChatEventMemberRemoved, RoomID: roomID, UserID: uid})
 		}
 	}
 
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+)
+
+var (
+	ErrChatMessageNotFound = errors.New("message not found")
+	ErrChatNotMember       = errors.New("not a member of this room")
+	ErrChatNestedReply     = errors.New("replies can only be posted to top-level messages")
+	ErrChatInvalidReaction = errors.New("invalid reaction")
+	ErrChatPinForbidden    = errors.New("only room admins and moderators can pin messages")
+)
+
+// maxReactionRunes bounds a reaction; multi-codepoint emoji such as flags
+// and family sequences stay well below it.
+const maxReactionRunes = 16
+
+// messageForMember loads a live message and checks that userID belongs to
+// its room.
+func (s *ChatService) messageForMember(ctx context.Context, msgID, userID string) (*models.ChatMessage, error) {
+	msg, err := s.repo.GetMessage(ctx, msgID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && msg.DeletedAt != nil) {
+		return nil, ErrChatMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.IsMember(ctx, msg.RoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrChatNotMember
+	}
+	return msg, nil
+}
+
+// CreateReply posts a reply in the thread of parentID. Threads are one level
+// deep, so replying to a reply is rejected. The sender's own thread position
+// moves past the reply, which also makes them follow the thread.
+func (s *ChatService) CreateReply(ctx context.Context, parentID, senderID, body string, attachments models.ChatAttachments, meta json.RawMessage) (*models.ChatMessage, error) {
+	parent, err := s.messageForMember(ctx, parentID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.ParentID != nil {
+		return nil, ErrChatNestedReply
+	}
+	if err := s.checkCanPost(ctx, parent.RoomID, senderID); err != nil {
+		return nil, err
+	}
+	reply, err := s.repo.CreateReply(ctx, parentID, senderID, body, attachments, meta)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Parent was deleted in the meantime
+		return nil, ErrChatMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkThreadAsRead(ctx, parentID, senderID); err != nil {
+		log.Printf("[Chat] Failed to mark thread %s read for %s: %v", parentID, senderID, err)
+	}
+	s.publish(ctx, ChatEvent{Type: ChatEventMessageCreated, RoomID: reply.RoomID, UserID: senderID, MessageID: reply.ID, Message: reply})
+	return reply, nil
+}
+
+// GetThread returns a thread's parent message and its replies, oldest first.
+func (s *ChatService) GetThread(ctx context.Context, parentID, userID string, limit int, after *time.Time) (*models.ChatMessage, []models.ChatMessage, error) {
+	parent, err := s.messageForMember(ctx, parentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	replies, err := s.repo.ListReplies(ctx, parentID, limit, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	withParent := append([]models.ChatMessage{*parent}, replies...)
+	if err := s.attachReactions(ctx, withParent); err != nil {
+		return nil, nil, err
+	}
+	return &withParent[0], withParent[1:], nil
+}
+
+// ListThreads returns the threads of a room the user follows, with their
+// unread reply counts.
+func (s *ChatService) ListThreads(ctx context.Context, roomID, userID string) ([]models.ChatThread, error) {
+	return s.repo.ListThreads(ctx, roomID, userID)
+}
+
+// MarkThreadAsRead moves the user's read position in a thread to now.
+func (s *ChatService) MarkThreadAsRead(ctx context.Context, parentID, userID string) error {
+	if _, err := s.messageForMember(ctx, parentID, userID); err != nil {
+		return err
+	}
+	return s.repo.MarkThreadAsRead(ctx, parentID, userID)
+}
+
+// AddReaction adds the user's emoji to a message and returns the message's
+// reactions. Reacting twice with the same emoji is a no-op.
+func (s *ChatService) AddReaction(ctx context.Context, msgID, userID, emoji string) ([]models.ChatReaction, error) {
+	return s.react(ctx, msgID, userID, emoji, true)
+}
+
+// RemoveReaction takes the user's emoji off a message and returns the
+// message's remaining reactions.
+func (s *ChatService) RemoveReaction(ctx context.Context, msgID, userID, emoji string) ([]models.ChatReaction, error) {
+	return s.react(ctx, msgID, userID, emoji, false)
+}
+
+func (s *ChatService) react(ctx context.Context, msgID, userID, emoji string, add bool) ([]models.ChatReaction, error) {
+	emoji = strings.TrimSpace(emoji)
+	if !isValidReaction(emoji) {
+		return nil, ErrChatInvalidReaction
+	}
+	msg, err := s.messageForMember(ctx, msgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed bool
+	eventType := ChatEventReactionAdded
+	if add {
+		changed, err = s.repo.AddReaction(ctx, msgID, userID, emoji)
+	} else {
+		eventType = ChatEventReactionRemoved
+		changed, err = s.repo.RemoveReaction(ctx, msgID, userID, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := s.repo.ListReactions(ctx, []string{msgID})
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		s.publish(ctx, ChatEvent{Type: eventType, RoomID: msg.RoomID, UserID: userID, MessageID: msgID, Emoji: emoji})
+	}
+	return reactions[msgID], nil
+}
+
+// PinMessage pins a message to its room. Only room admins and moderators
+// may pin.
+func (s *ChatService) PinMessage(ctx context.Context, msgID, userID string) (*models.ChatMessage, error) {
+	return s.setPinned(ctx, msgID, userID, true)
+}
+
+// UnpinMessage removes a message from its room's pins.
+func (s *ChatService) UnpinMessage(ctx context.Context, msgID, userID string) (*models.ChatMessage, error) {
+	return s.setPinned(ctx, msgID, userID, false)
+}
+
+func (s *ChatService) setPinned(ctx context.Context, msgID, userID string, pin bool) (*models.ChatMessage, error) {
+	msg, err := s.repo.GetMessage(ctx, msgID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && msg.DeletedAt != nil) {
+		return nil, ErrChatMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.repo.GetMemberRole(ctx, msg.RoomID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChatNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role != models.ChatRoomMemberRoleAdmin && role != models.ChatRoomMemberRoleModerator {
+		return nil, ErrChatPinForbidden
+	}
+
+	var pinnedBy *string
+	eventType := ChatEventMessageUnpinned
+	if pin {
+		pinnedBy = &userID
+		eventType = ChatEventMessagePinned
+	}
+	if err := s.repo.SetPinned(ctx, msgID, pinnedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChatMessageNotFound
+		}
+		return nil, err
+	}
+	updated, err := s.repo.GetMessage(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, ChatEvent{Type: eventType, RoomID: updated.RoomID, UserID: userID, MessageID: msgID, Message: updated})
+	return updated, nil
+}
+
+// ListPinned returns a room's pinned messages, most recently pinned first.
+func (s *ChatService) ListPinned(ctx context.Context, roomID string) ([]models.ChatMessage, error) {
+	msgs, err := s.repo.ListPinned(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachReactions(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// attachReactions fills in the reactions of each message with one query.
+func (s *ChatService) attachReactions(ctx context.Context, msgs []models.ChatMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	reactions, err := s.repo.ListReactions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range msgs {
+		msgs[i].Reactions = reactions[msgs[i].ID]
+	}
+	return nil
+}
+
+// isValidReaction accepts a short token without whitespace or control
+// characters; clients send emoji, but custom shortcodes are tolerated.
+func isValidReaction(emoji string) bool {
+	if emoji == "" || !utf8.ValidString(emoji) || utf8.RuneCountInString(emoji) > maxReactionRunes {
+		return false
+	}
+	for _, r := range emoji {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
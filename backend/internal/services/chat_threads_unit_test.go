@@ -0,0 +1,182 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newThreadTestService(repo *MockChatRepository) (*services.ChatService, *services.ChatSubscription) {
+	svc := services.NewChatService(repo, nil, config.AppConfig{})
+	hub := services.NewChatHub(nil)
+	svc.SetHub(hub)
+	return svc, hub.Subscribe("r1")
+}
+
+func TestChatService_Threads(t *testing.T) {
+	ctx := context.Background()
+	parentOf := "p1"
+	messages := map[string]*models.ChatMessage{
+		"p1": {ID: "p1", RoomID: "r1"},
+		"c1": {ID: "c1", RoomID: "r1", ParentID: &parentOf},
+	}
+	repo := NewMockChatRepository()
+	repo.GetMessageFunc = func(ctx context.Context, id string) (*models.ChatMessage, error) {
+		if m, ok := messages[id]; ok {
+			copied := *m
+			return &copied, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+	repo.IsMemberFunc = func(ctx context.Context, r, u string) (bool, error) { return u != "outsider", nil }
+	var readBy []string
+	repo.MarkThreadAsReadFunc = func(ctx context.Context, p, u string) error {
+		readBy = append(readBy, p+":"+u)
+		return nil
+	}
+	repo.CreateReplyFunc = func(ctx context.Context, p, s, b string, a models.ChatAttachments, mt json.RawMessage) (*models.ChatMessage, error) {
+		return &models.ChatMessage{ID: "c2", RoomID: "r1", ParentID: &p, SenderID: s, Body: b}, nil
+	}
+	svc, sub := newThreadTestService(repo)
+	defer sub.Close()
+
+	t.Run("Reply joins the thread and is broadcast", func(t *testing.T) {
+		reply, err := svc.CreateReply(ctx, "p1", "u1", "hi", nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "p1", *reply.ParentID)
+		assert.Equal(t, []string{"p1:u1"}, readBy)
+
+		ev := <-sub.C
+		assert.Equal(t, services.ChatEventMessageCreated, ev.Type)
+		assert.Equal(t, "p1", *ev.Message.ParentID)
+	})
+
+	t.Run("Replies to replies are rejected", func(t *testing.T) {
+		_, err := svc.CreateReply(ctx, "c1", "u1", "nested", nil, nil)
+		assert.ErrorIs(t, err, services.ErrChatNestedReply)
+	})
+
+	t.Run("Missing parent and outsiders", func(t *testing.T) {
+		_, err := svc.CreateReply(ctx, "nope", "u1", "x", nil, nil)
+		assert.ErrorIs(t, err, services.ErrChatMessageNotFound)
+		_, _, err = svc.GetThread(ctx, "p1", "outsider", 50, nil)
+		assert.ErrorIs(t, err, services.ErrChatNotMember)
+		assert.ErrorIs(t, svc.MarkThreadAsRead(ctx, "p1", "outsider"), services.ErrChatNotMember)
+	})
+
+	t.Run("Thread carries reactions of parent and replies", func(t *testing.T) {
+		repo.ListRepliesFunc = func(ctx context.Context, p string, l int, a *time.Time) ([]models.ChatMessage, error) {
+			return []models.ChatMessage{{ID: "c1", RoomID: "r1", ParentID: &parentOf}}, nil
+		}
+		repo.ListReactionsFunc = func(ctx context.Context, ids []string) (map[string][]models.ChatReaction, error) {
+			assert.Equal(t, []string{"p1", "c1"}, ids)
+			return map[string][]models.ChatReaction{
+				"p1": {{Emoji: "👍", Count: 2, UserIDs: []string{"u1", "u2"}}},
+				"c1": {{Emoji: "🎉", Count: 1, UserIDs: []string{"u2"}}},
+			}, nil
+		}
+		parent, replies, err := svc.GetThread(ctx, "p1", "u1", 50, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "👍", parent.Reactions[0].Emoji)
+		require.Len(t, replies, 1)
+		assert.Equal(t, "🎉", replies[0].Reactions[0].Emoji)
+	})
+}
+
+func TestChatService_Reactions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockChatRepository()
+	repo.GetMessageFunc = func(ctx context.Context, id string) (*models.ChatMessage, error) {
+		return &models.ChatMessage{ID: id, RoomID: "r1"}, nil
+	}
+	repo.IsMemberFunc = func(ctx context.Context, r, u string) (bool, error) { return true, nil }
+	reacted := map[string]bool{}
+	repo.AddReactionFunc = func(ctx context.Context, m, u, e string) (bool, error) {
+		if reacted[u+e] {
+			return false, nil
+		}
+		reacted[u+e] = true
+		return true, nil
+	}
+	repo.ListReactionsFunc = func(ctx context.Context, ids []string) (map[string][]models.ChatReaction, error) {
+		return map[string][]models.ChatReaction{"m1": {{Emoji: "👍", Count: 1, UserIDs: []string{"u1"}}}}, nil
+	}
+	svc, sub := newThreadTestService(repo)
+	defer sub.Close()
+
+	reactions, err := svc.AddReaction(ctx, "m1", "u1", " 👍 ")
+	require.NoError(t, err)
+	assert.Equal(t, 1, reactions[0].Count)
+	ev := <-sub.C
+	assert.Equal(t, services.ChatEventReactionAdded, ev.Type)
+	assert.Equal(t, "👍", ev.Emoji)
+
+	_, err = svc.AddReaction(ctx, "m1", "u1", "👍")
+	require.NoError(t, err)
+	assert.Empty(t, sub.C, "repeated reaction is not broadcast")
+
+	_, err = svc.RemoveReaction(ctx, "m1", "u1", "👍")
+	require.NoError(t, err)
+	assert.Equal(t, services.ChatEventReactionRemoved, (<-sub.C).Type)
+
+	for _, bad := range []string{"", "two words", "a\tb", "this-is-way-too-long-for-a-reaction"} {
+		_, err := svc.AddReaction(ctx, "m1", "u1", bad)
+		assert.ErrorIs(t, err, services.ErrChatInvalidReaction, bad)
+	}
+}
+
+func TestChatService_Pins(t *testing.T) {
+	ctx := context.Background()
+	roles := map[string]models.ChatRoomMemberRole{
+		"owner": models.ChatRoomMemberRoleAdmin,
+		"mod":   models.ChatRoomMemberRoleModerator,
+		"user":  models.ChatRoomMemberRoleMember,
+	}
+	var pinnedBy *string
+	repo := NewMockChatRepository()
+	repo.GetMessageFunc = func(ctx context.Context, id string) (*models.ChatMessage, error) {
+		msg := &models.ChatMessage{ID: id, RoomID: "r1", PinnedBy: pinnedBy}
+		if pinnedBy != nil {
+			now := time.Now()
+			msg.PinnedAt = &now
+		}
+		return msg, nil
+	}
+	repo.GetMemberRoleFunc = func(ctx context.Context, r, u string) (models.ChatRoomMemberRole, error) {
+		if role, ok := roles[u]; ok {
+			return role, nil
+		}
+		return "", sql.ErrNoRows
+	}
+	repo.SetPinnedFunc = func(ctx context.Context, m string, by *string) error {
+		pinnedBy = by
+		return nil
+	}
+	svc, sub := newThreadTestService(repo)
+	defer sub.Close()
+
+	_, err := svc.PinMessage(ctx, "m1", "user")
+	assert.ErrorIs(t, err, services.ErrChatPinForbidden)
+	_, err = svc.PinMessage(ctx, "m1", "stranger")
+	assert.ErrorIs(t, err, services.ErrChatNotMember)
+	assert.Nil(t, pinnedBy)
+
+	msg, err := svc.PinMessage(ctx, "m1", "mod")
+	require.NoError(t, err)
+	assert.NotNil(t, msg.PinnedAt)
+	assert.Equal(t, "mod", *msg.PinnedBy)
+	assert.Equal(t, services.ChatEventMessagePinned, (<-sub.C).Type)
+
+	msg, err = svc.UnpinMessage(ctx, "m1", "owner")
+	require.NoError(t, err)
+	assert.Nil(t, msg.PinnedAt)
+	assert.Equal(t, services.ChatEventMessageUnpinned, (<-sub.C).Type)
+}
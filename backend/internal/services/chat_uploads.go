@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/storage"
+)
+
+var (
+	ErrChatStorageUnavailable = errors.New("file storage is not configured")
+	ErrChatInvalidUpload      = errors.New("invalid upload")
+	ErrChatFileNotFound       = errors.New("file not found")
+)
+
+const (
+	// chatAttachmentKind is the documents.kind of files shared in chat
+	chatAttachmentKind = "chat_attachment"
+	// chatDownloadTTL keeps download links short-lived; clients ask for a
+	// fresh one each time a file is opened
+	chatDownloadTTL = 5 * time.Minute
+	// legacyChatUploadPrefix marks attachments saved on the server's disk
+	legacyChatUploadPrefix = "/uploads/chat/"
+)
+
+// ChatAttachmentURL is the API path that hands out download links for a
+// stored chat file.
+func ChatAttachmentURL(versionID string) string {
+	return "/api/chat/attachments/" + versionID
+}
+
+// ChatUploadTicket is returned by PresignUpload; the client PUTs the file to
+// UploadURL and then attaches it with DocumentID and ObjectKey.
+type ChatUploadTicket struct {
+	UploadURL  string    `json:"upload_url"`
+	ObjectKey  string    `json:"object_key"`
+	DocumentID string    `json:"document_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SetStorage moves chat files to object storage. Without it uploads keep
+// going to the local upload directory.
+func (s *ChatService) SetStorage(storage StorageClient, docs *DocumentService) {
+	s.storage = storage
+	s.docs = docs
+}
+
+// StorageEnabled reports whether chat files go through object storage.
+func (s *ChatService) StorageEnabled() bool {
+	return s.storage != nil && s.docs != nil
+}
+
+// PresignUpload checks an upload against the size limit and MIME whitelist,
+// reserves a document for it and returns where to PUT the file.
+func (s *ChatService) PresignUpload(ctx context.Context, tenantID, roomID, userID, filename, contentType string, sizeBytes int64) (*ChatUploadTicket, error) {
+	if !s.StorageEnabled() {
+		return nil, ErrChatStorageUnavailable
+	}
+	if err := s.checkUpload(ctx, roomID, userID, contentType, sizeBytes); err != nil {
+		return nil, err
+	}
+	docID, err := s.docs.CreateMetadata(ctx, CreateDocumentRequest{
+		Title:    filepath.Base(filename),
+		Kind:     chatAttachmentKind,
+		TenantID: tenantID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create document: %w", err)
+	}
+	key := storage.BuildDocumentObjectKey(docID, filename)
+	expires := GetPresignExpires()
+	url, err := s.storage.PresignPut(ctx, key, contentType, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatUploadTicket{UploadURL: url, ObjectKey: key, DocumentID: docID, ExpiresAt: time.Now().Add(expires)}, nil
+}
+
+// AttachUpload records an uploaded file as a version of its document and
+// binds it to the room. The returned attachment goes into CreateMessage.
+func (s *ChatService) AttachUpload(ctx context.Context, tenantID, roomID, userID, documentID, objectKey, filename, contentType string, sizeBytes int64) (*models.ChatAttachment, error) {
+	if !s.StorageEnabled() {
+		return nil, ErrChatStorageUnavailable
+	}
+	if err := s.checkUpload(ctx, roomID, userID, contentType, sizeBytes); err != nil {
+		return nil, err
+	}
+	doc, versions, err := s.docs.GetDocumentDetails(ctx, documentID)
+	if err != nil || doc == nil || doc.UserID != userID || doc.Kind != chatAttachmentKind || len(versions) > 0 {
+		return nil, fmt.Errorf("%w: unknown or already attached document", ErrChatInvalidUpload)
+	}
+	// Keys are only accepted under the document's own prefix
+	if path.Dir(objectKey) != path.Dir(storage.BuildDocumentObjectKey(documentID, "file")) {
+		return nil, fmt.Errorf("%w: object key does not belong to the document", ErrChatInvalidUpload)
+	}
+	if exists, err := s.storage.ObjectExists(ctx, objectKey); err != nil || !exists {
+		return nil, fmt.Errorf("%w: file was not uploaded", ErrChatInvalidUpload)
+	}
+
+	verID, err := s.docs.CreateVersion(ctx, documentID, tenantID, userID, models.DocumentVersion{
+		StoragePath: objectKey,
+		MimeType:    contentType,
+		SizeBytes:   sizeBytes,
+		Bucket:      sql.NullString{String: s.storage.Bucket(), Valid: true},
+		ObjectKey:   sql.NullString{String: objectKey, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create document version: %w", err)
+	}
+	if err := s.repo.CreateFile(ctx, &models.ChatFile{
+		VersionID:  verID,
+		DocumentID: documentID,
+		RoomID:     roomID,
+		UploadedBy: &userID,
+		ObjectKey:  objectKey,
+	}); err != nil {
+		return nil, fmt.Errorf("record chat file: %w", err)
+	}
+	return &models.ChatAttachment{
+		URL:        ChatAttachmentURL(verID),
+		Type:       contentType,
+		Name:       filepath.Base(filename),
+		Size:       sizeBytes,
+		DocumentID: documentID,
+		VersionID:  verID,
+	}, nil
+}
+
+// DownloadURL returns a short-lived link to a stored chat file for a member
+// of the room it was shared in.
+func (s *ChatService) DownloadURL(ctx context.Context, versionID, userID string) (string, time.Time, error) {
+	if !s.StorageEnabled() {
+		return "", time.Time{}, ErrChatStorageUnavailable
+	}
+	file, err := s.repo.GetFile(ctx, versionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", time.Time{}, ErrChatFileNotFound
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	ok, err := s.repo.IsMember(ctx, file.RoomID, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !ok {
+		return "", time.Time{}, ErrChatNotMember
+	}
+	url, err := s.storage.PresignGet(ctx, file.ObjectKey, chatDownloadTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return url, time.Now().Add(chatDownloadTTL), nil
+}
+
+func (s *ChatService) checkUpload(ctx context.Context, roomID, userID, contentType string, sizeBytes int64) error {
+	ok, err := s.repo.IsMember(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrChatNotMember
+	}
+	if err := ValidateContentType(contentType); err != nil {
+		return fmt.Errorf("%w: %v", ErrChatInvalidUpload, err)
+	}
+	maxBytes := int64(s.cfg.FileUploadMaxMB) * 1024 * 1024
+	if sizeBytes <= 0 || sizeBytes > maxBytes {
+		return fmt.Errorf("%w: file size must be between 1 byte and %dMB", ErrChatInvalidUpload, s.cfg.FileUploadMaxMB)
+	}
+	return nil
+}
+
+// ObjectPutter uploads a file body to a presigned PUT URL.
+type ObjectPutter func(ctx context.Context, url, contentType string, body io.Reader, size int64) error
+
+// HTTPObjectPutter PUTs files with the given client.
+func HTTPObjectPutter(client *http.Client) ObjectPutter {
+	return func(ctx context.Context, url, contentType string, body io.Reader, size int64) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, body)
+		if err != nil {
+			return err
+		}
+		req.ContentLength = size
+		req.Header.Set("Content-Type", contentType)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("upload failed with status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// ChatFileMigrationReport summarises a MigrateLocalFiles run.
+type ChatFileMigrationReport struct {
+	Messages int
+	Migrated int
+	Missing  int
+	Failed   int
+}
+
+// MigrateLocalFiles copies attachments still stored in the upload directory
+// to object storage and points their messages at the stored versions. Files
+// on disk are left in place, and a failed file is retried on the next run.
+func (s *ChatService) MigrateLocalFiles(ctx context.Context, put ObjectPutter) (ChatFileMigrationReport, error) {
+	var report ChatFileMigrationReport
+	if !s.StorageEnabled() {
+		return report, ErrChatStorageUnavailable
+	}
+	msgs, err := s.repo.ListLocalFileMessages(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, msg := range msgs {
+		report.Messages++
+		changed := false
+		for i, att := range msg.Attachments {
+			if !strings.HasPrefix(att.URL, legacyChatUploadPrefix) {
+				continue
+			}
+			migrated, err := s.migrateLocalFile(ctx, msg, att, put)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				log.Printf("[Chat] Local file for %s in message %s is gone, leaving it", att.URL, msg.ID)
+				report.Missing++
+			case err != nil:
+				log.Printf("[Chat] Failed to migrate %s in message %s: %v", att.URL, msg.ID, err)
+				report.Failed++
+			default:
+				msg.Attachments[i] = *migrated
+				changed = true
+				report.Migrated++
+			}
+		}
+		if changed {
+			if err := s.repo.UpdateAttachments(ctx, msg.ID, msg.Attachments); err != nil {
+				return report, fmt.Errorf("update message %s: %w", msg.ID, err)
+			}
+		}
+	}
+	return report, nil
+}
+
+func (s *ChatService) migrateLocalFile(ctx context.Context, msg models.ChatMessage, att models.ChatAttachment, put ObjectPutter) (*models.ChatAttachment, error) {
+	roomDir, filename, ok := strings.Cut(strings.TrimPrefix(att.URL, legacyChatUploadPrefix), "/")
+	if !ok || roomDir != msg.RoomID {
+		return nil, fmt.Errorf("unexpected attachment url")
+	}
+	localPath, err := s.GetFilePath(roomDir, filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	name := att.Name
+	if name == "" {
+		// Saved as <unix>_<original name>
+		_, name, _ = strings.Cut(filename, "_")
+	}
+	contentType := att.Type
+	if !strings.Contains(contentType, "/") {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	docID, err := s.docs.CreateMetadata(ctx, CreateDocumentRequest{
+		Title:    name,
+		Kind:     chatAttachmentKind,
+		TenantID: msg.TenantID,
+		UserID:   msg.SenderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create document: %w", err)
+	}
+	key := storage.BuildDocumentObjectKey(docID, name)
+	url, err := s.storage.PresignPut(ctx, key, contentType, GetPresignExpires())
+	if err != nil {
+		return nil, err
+	}
+	if err := put(ctx, url, contentType, f, info.Size()); err != nil {
+		return nil, err
+	}
+	verID, err := s.docs.CreateVersion(ctx, docID, msg.TenantID, msg.SenderID, models.DocumentVersion{
+		StoragePath: key,
+		MimeType:    contentType,
+		SizeBytes:   info.Size(),
+		Bucket:      sql.NullString{String: s.storage.Bucket(), Valid: true},
+		ObjectKey:   sql.NullString{String: key, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create document version: %w", err)
+	}
+	sender := msg.SenderID
+	if err := s.repo.CreateFile(ctx, &models.ChatFile{
+		VersionID:  verID,
+		DocumentID: docID,
+		RoomID:     msg.RoomID,
+		UploadedBy: &sender,
+		ObjectKey:  key,
+	}); err != nil {
+		return nil, fmt.Errorf("record chat file: %w", err)
+	}
+	return &models.ChatAttachment{
+		URL:        ChatAttachmentURL(verID),
+		Type:       contentType,
+		Name:       name,
+		Size:       info.Size(),
+		DocumentID: docID,
+		VersionID:  verID,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUploadTestService(repo *MockChatRepository, store *services.MockStorageClient, uploadDir string) (*services.ChatService, *MemDocuments) {
+	mem, docRepo := NewMemDocuments()
+	cfg := config.AppConfig{FileUploadMaxMB: 1, UploadDir: uploadDir}
+	svc := services.NewChatService(repo, nil, cfg)
+	svc.SetStorage(store, services.NewDocumentService(docRepo, cfg, store))
+	return svc, mem
+}
+
+func TestChatService_Uploads(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockChatRepository()
+	repo.IsMemberFunc = func(ctx context.Context, r, u string) (bool, error) { return u == "alice", nil }
+	var files []models.ChatFile
+	repo.CreateFileFunc = func(ctx context.Context, f *models.ChatFile) error {
+		files = append(files, *f)
+		return nil
+	}
+	repo.GetFileFunc = func(ctx context.Context, v string) (*models.ChatFile, error) {
+		for _, f := range files {
+			if f.VersionID == v {
+				return &f, nil
+			}
+		}
+		return nil, sql.ErrNoRows
+	}
+	uploaded := map[string]bool{}
+	var getTTL time.Duration
+	store := &services.MockStorageClient{
+		ObjectExistsFn: func(ctx context.Context, key string) (bool, error) { return uploaded[key], nil },
+		PresignGetFn: func(ctx context.Context, key string, expires time.Duration) (string, error) {
+			getTTL = expires
+			return "https://s3.example/" + key + "?sig", nil
+		},
+	}
+	svc, mem := newUploadTestService(repo, store, t.TempDir())
+
+	t.Run("Storage is required", func(t *testing.T) {
+		plain := services.NewChatService(repo, nil, config.AppConfig{})
+		assert.False(t, plain.StorageEnabled())
+		_, err := plain.PresignUpload(ctx, "t1", "r1", "alice", "a.pdf", "application/pdf", 10)
+		assert.ErrorIs(t, err, services.ErrChatStorageUnavailable)
+	})
+
+	t.Run("Presign validates the upload", func(t *testing.T) {
+		_, err := svc.PresignUpload(ctx, "t1", "r1", "bob", "a.pdf", "application/pdf", 10)
+		assert.ErrorIs(t, err, services.ErrChatNotMember)
+		_, err = svc.PresignUpload(ctx, "t1", "r1", "alice", "a.exe", "application/x-msdownload", 10)
+		assert.ErrorIs(t, err, services.ErrChatInvalidUpload)
+		_, err = svc.PresignUpload(ctx, "t1", "r1", "alice", "big.pdf", "application/pdf", 2*1024*1024)
+		assert.ErrorIs(t, err, services.ErrChatInvalidUpload)
+		assert.Empty(t, mem.docs, "rejected uploads reserve nothing")
+	})
+
+	var att *models.ChatAttachment
+	t.Run("Presign, upload, attach, download", func(t *testing.T) {
+		ticket, err := svc.PresignUpload(ctx, "t1", "r1", "alice", "Thesis Draft.pdf", "application/pdf", 1000)
+		require.NoError(t, err)
+		assert.Equal(t, "chat_attachment", mem.docs[ticket.DocumentID].Kind)
+		assert.True(t, strings.HasPrefix(ticket.ObjectKey, "documents/"+ticket.DocumentID+"/"), ticket.ObjectKey)
+		assert.Contains(t, ticket.UploadURL, ticket.ObjectKey)
+
+		_, err = svc.AttachUpload(ctx, "t1", "r1", "alice", ticket.DocumentID, ticket.ObjectKey, "Thesis Draft.pdf", "application/pdf", 1000)
+		assert.ErrorIs(t, err, services.ErrChatInvalidUpload, "nothing was PUT yet")
+
+		uploaded[ticket.ObjectKey] = true
+		_, err = svc.AttachUpload(ctx, "t1", "r1", "alice", ticket.DocumentID, "documents/other/x.pdf", "x.pdf", "application/pdf", 1000)
+		assert.ErrorIs(t, err, services.ErrChatInvalidUpload, "key outside the document prefix")
+
+		att, err = svc.AttachUpload(ctx, "t1", "r1", "alice", ticket.DocumentID, ticket.ObjectKey, "Thesis Draft.pdf", "application/pdf", 1000)
+		require.NoError(t, err)
+		assert.Equal(t, services.ChatAttachmentURL(att.VersionID), att.URL)
+		ver := mem.versions[ticket.DocumentID][0]
+		assert.Equal(t, ticket.ObjectKey, ver.ObjectKey.String)
+		assert.Equal(t, "mock-bucket", ver.Bucket.String, "tracked for the orphan cleanup")
+		require.Len(t, files, 1)
+		assert.Equal(t, "r1", files[0].RoomID)
+
+		_, err = svc.AttachUpload(ctx, "t1", "r1", "alice", ticket.DocumentID, ticket.ObjectKey, "Thesis Draft.pdf", "application/pdf", 1000)
+		assert.ErrorIs(t, err, services.ErrChatInvalidUpload, "a document is attached once")
+	})
+
+	t.Run("Downloads are short-lived and members only", func(t *testing.T) {
+		url, expires, err := svc.DownloadURL(ctx, att.VersionID, "alice")
+		require.NoError(t, err)
+		assert.Contains(t, url, "?sig")
+		assert.LessOrEqual(t, getTTL, 5*time.Minute)
+		assert.WithinDuration(t, time.Now().Add(getTTL), expires, time.Second)
+
+		_, _, err = svc.DownloadURL(ctx, att.VersionID, "bob")
+		assert.ErrorIs(t, err, services.ErrChatNotMember)
+		_, _, err = svc.DownloadURL(ctx, "missing", "alice")
+		assert.ErrorIs(t, err, services.ErrChatFileNotFound)
+	})
+}
+
+func TestChatService_MigrateLocalFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockChatRepository()
+	store := &services.MockStorageClient{}
+	uploadDir := t.TempDir()
+	svc, mem := newUploadTestService(repo, store, uploadDir)
+
+	// Only the first file is still on disk
+	require.NoError(t, os.MkdirAll(filepath.Join(uploadDir, "chat", "r1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(uploadDir, "chat", "r1", "1700000000_notes.txt"), []byte("hello"), 0o644))
+
+	msg := models.ChatMessage{
+		ID: "m1", TenantID: "t1", RoomID: "r1", SenderID: "alice",
+		Attachments: models.ChatAttachments{
+			{URL: "/uploads/chat/r1/1700000000_notes.txt", Type: "text/plain", Name: "notes.txt", Size: 5},
+			{URL: "/uploads/chat/r1/1700000001_gone.pdf", Type: "application/pdf", Name: "gone.pdf", Size: 9},
+			{URL: services.ChatAttachmentURL("ver-x"), VersionID: "ver-x"},
+		},
+	}
+	repo.ListLocalFileMessagesFunc = func(ctx context.Context) ([]models.ChatMessage, error) {
+		return []models.ChatMessage{msg}, nil
+	}
+	var saved models.ChatAttachments
+	repo.UpdateAttachmentsFunc = func(ctx context.Context, id string, a models.ChatAttachments) error {
+		saved = a
+		return nil
+	}
+	puts := map[string]string{}
+	put := func(ctx context.Context, url, contentType string, body io.Reader, size int64) error {
+		b, _ := io.ReadAll(body)
+		puts[url] = contentType + ":" + string(b)
+		return nil
+	}
+
+	report, err := svc.MigrateLocalFiles(ctx, put)
+	require.NoError(t, err)
+	assert.Equal(t, services.ChatFileMigrationReport{Messages: 1, Migrated: 1, Missing: 1}, report)
+
+	require.Len(t, saved, 3)
+	assert.NotEmpty(t, saved[0].VersionID)
+	assert.Equal(t, services.ChatAttachmentURL(saved[0].VersionID), saved[0].URL)
+	assert.Equal(t, "notes.txt", saved[0].Name)
+	assert.Equal(t, "/uploads/chat/r1/1700000001_gone.pdf", saved[1].URL, "missing files keep their old link")
+	assert.Equal(t, "ver-x", saved[2].VersionID)
+
+	doc := mem.docs[saved[0].DocumentID]
+	assert.Equal(t, "alice", doc.UserID)
+	require.Len(t, puts, 1)
+	for url, got := range puts {
+		assert.Contains(t, url, "documents/"+doc.ID+"/")
+		assert.Equal(t, "text/plain:hello", got)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+)
+
+var (
+	ErrUnknownDeadlineNode = errors.New("node not found in playbook")
+	ErrDeadlineNotFound    = errors.New("no deadline override for this node")
+)
+
+// DeadlineSyncer brings a student's rule-derived deadlines up to date.
+// JourneyService calls it when it opens a node, since most rules are anchored
+// on the node's activation.
+type DeadlineSyncer interface {
+	Sync(ctx context.Context, tenantID, userID string) error
+}
+
+// TenantPlaybooks returns a tenant's active playbook.
+type TenantPlaybooks interface {
+	ForTenant(ctx context.Context, tenantID string) (*playbook.Manager, error)
+}
+
+// DeadlineService keeps node_deadlines in line with the playbook's deadline
+// rules and lets admins override a student's due dates.
+type DeadlineService struct {
+	repo      repository.DeadlineRepository
+	pb        *playbook.Manager
+	playbooks TenantPlaybooks
+}
+
+func NewDeadlineService(repo repository.DeadlineRepository, pb *playbook.Manager) *DeadlineService {
+	return &DeadlineService{repo: repo, pb: pb}
+}
+
+// SetPlaybooks lets SyncAll use each tenant's active playbook; without it the
+// service's own playbook applies to every tenant.
+func (s *DeadlineService) SetPlaybooks(playbooks TenantPlaybooks) {
+	s.playbooks = playbooks
+}
+
+// manager returns the playbook resolved for the request's tenant, falling
+// back to the one the service was built with.
+func (s *DeadlineService) manager(ctx context.Context) *playbook.Manager {
+	if m, ok := playbook.FromContext(ctx); ok {
+		return m
+	}
+	return s.pb
+}
+
+// Sync computes the student's rule-derived deadlines and stores those whose
+// anchor is known. Overrides are kept as they are.
+func (s *DeadlineService) Sync(ctx context.Context, tenantID, userID string) error {
+	pm := s.manager(ctx)
+	ids := pm.DeadlineNodes()
+	if len(ids) == 0 {
+		return nil
+	}
+	activated, err := s.repo.ActivationTimes(ctx, tenantID, userID)
+	if err != nil {
+		return fmt.Errorf("load activation times: %w", err)
+	}
+
+	var cohortStart *time.Time
+	cohortLoaded := false
+	for _, nodeID := range ids {
+		rule := *pm.Nodes[nodeID].Deadline
+		anchors := playbook.DeadlineAnchors{}
+		if at, ok := activated[nodeID]; ok {
+			anchors.ActivatedAt = &at
+		}
+		if rule.Anchor == playbook.AnchorCohortStart {
+			if !cohortLoaded {
+				if cohortStart, err = s.repo.CohortStart(ctx, tenantID, userID); err != nil {
+					return fmt.Errorf("load cohort start: %w", err)
+				}
+				cohortLoaded = true
+			}
+			anchors.CohortStart = cohortStart
+		}
+		due, ok := rule.DueAt(anchors)
+		if !ok {
+			continue
+		}
+		if err := s.repo.UpsertRule(ctx, tenantID, userID, nodeID, due); err != nil {
+			return fmt.Errorf("store deadline %s: %w", nodeID, err)
+		}
+	}
+	return nil
+}
+
+// SyncAll brings the rule-derived deadlines of every active student up to
+// date, so reminders reach students who never open their deadlines page. A
+// student whose sync fails is logged and skipped.
+func (s *DeadlineService) SyncAll(ctx context.Context) error {
+	students, err := s.repo.ListStudents(ctx)
+	if err != nil {
+		return fmt.Errorf("list students: %w", err)
+	}
+	managers := map[string]*playbook.Manager{}
+	for _, st := range students {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sctx := ctx
+		if s.playbooks != nil {
+			mgr, ok := managers[st.TenantID]
+			if !ok {
+				if mgr, err = s.playbooks.ForTenant(ctx, st.TenantID); err != nil {
+					log.Printf("[DeadlineService] playbook for tenant %s: %v", st.TenantID, err)
+				}
+				managers[st.TenantID] = mgr
+			}
+			if mgr != nil {
+				sctx = playbook.WithManager(ctx, mgr)
+			}
+		}
+		if err := s.Sync(sctx, st.TenantID, st.UserID); err != nil {
+			log.Printf("[DeadlineService] sync deadlines for user=%s tenant=%s: %v", st.UserID, st.TenantID, err)
+		}
+	}
+	return nil
+}
+
+// List returns the student's deadlines after bringing rule-derived ones up to date.
+func (s *DeadlineService) List(ctx context.Context, tenantID, userID string) ([]models.NodeDeadline, error) {
+	if err := s.Sync(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	deadlines, err := s.repo.ListByUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if deadlines == nil {
+		deadlines = []models.NodeDeadline{}
+	}
+	return deadlines, nil
+}
+
+// DueDates returns the student's due dates keyed by node id.
+func (s *DeadlineService) DueDates(ctx context.Context, tenantID, userID string) (map[string]time.Time, error) {
+	deadlines, err := s.List(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Time, len(deadlines))
+	for _, d := range deadlines {
+		out[d.NodeID] = d.DueAt
+	}
+	return out, nil
+}
+
+// SetOverride sets an admin due date for one of the student's nodes.
+func (s *DeadlineService) SetOverride(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time, note *string, actorID string) error {
+	if _, ok := s.manager(ctx).NodeDefinition(nodeID); !ok {
+		return ErrUnknownDeadlineNode
+	}
+	return s.repo.SetOverride(ctx, tenantID, userID, nodeID, dueAt, note, actorID)
+}
+
+// ClearOverride removes an admin due date; the playbook rule, if any, applies again.
+func (s *DeadlineService) ClearOverride(ctx context.Context, tenantID, userID, nodeID string) error {
+	found, err := s.repo.ClearOverride(ctx, tenantID, userID, nodeID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrDeadlineNotFound
+	}
+	return s.Sync(ctx, tenantID, userID)
+}
@@ -0,0 +1,101 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const deadlinePlaybook = `{"version": "1", "worlds": [{"id": "W1", "nodes": [
+	{"id": "S1", "deadline": {"anchor": "activation", "days": 14}},
+	{"id": "S2", "deadline": {"anchor": "cohort_start", "days": 30}},
+	{"id": "S3", "deadline": {"anchor": "fixed", "date": "2026-12-01"}},
+	{"id": "S4"}]}]}`
+
+func TestDeadlineService_Sync_Unit(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := playbook.Parse("v1", []byte(deadlinePlaybook))
+	require.NoError(t, err)
+	repo := NewMemDeadlineRepository()
+	svc := services.NewDeadlineService(repo, mgr)
+
+	// Only the fixed date is known before activation and without a cohort
+	due, err := svc.DueDates(ctx, "t1", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{"S3": time.Date(2026, 12, 1, 23, 59, 59, 0, time.UTC)}, due)
+
+	repo.activated["S1"] = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	repo.cohort = &start
+	due, err = svc.DueDates(ctx, "t1", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 15, 23, 59, 59, 0, time.UTC), due["S1"])
+	assert.Equal(t, time.Date(2025, 10, 1, 23, 59, 59, 0, time.UTC), due["S2"])
+	assert.NotContains(t, due, "S4")
+}
+
+func TestDeadlineService_Overrides_Unit(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := playbook.Parse("v1", []byte(deadlinePlaybook))
+	require.NoError(t, err)
+	repo := NewMemDeadlineRepository()
+	svc := services.NewDeadlineService(repo, mgr)
+
+	extended := time.Date(2027, 1, 15, 0, 0, 0, 0, time.UTC)
+	note := "extension approved"
+	require.NoError(t, svc.SetOverride(ctx, "t1", "u1", "S3", extended, &note, "admin1"))
+
+	// Syncing keeps the override
+	list, err := svc.List(ctx, "t1", "u1")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, extended, list[0].DueAt)
+	assert.Equal(t, models.DeadlineSourceOverride, list[0].Source)
+
+	// Clearing the override restores the playbook rule
+	require.NoError(t, svc.ClearOverride(ctx, "t1", "u1", "S3"))
+	list, err = svc.List(ctx, "t1", "u1")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.DeadlineSourceRule, list[0].Source)
+	assert.Equal(t, time.Date(2026, 12, 1, 23, 59, 59, 0, time.UTC), list[0].DueAt)
+
+	assert.ErrorIs(t, svc.ClearOverride(ctx, "t1", "u1", "S3"), services.ErrDeadlineNotFound)
+	assert.ErrorIs(t, svc.SetOverride(ctx, "t1", "u1", "NOPE", extended, nil, "admin1"), services.ErrUnknownDeadlineNode)
+}
+
+// tenantPlaybooks resolves a playbook per tenant.
+type tenantPlaybooks map[string]*playbook.Manager
+
+func (p tenantPlaybooks) ForTenant(ctx context.Context, tenantID string) (*playbook.Manager, error) {
+	return p[tenantID], nil
+}
+
+func TestDeadlineService_SyncAll_Unit(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := playbook.Parse("v1", []byte(deadlinePlaybook))
+	require.NoError(t, err)
+	other, err := playbook.Parse("v2", []byte(`{"version": "2", "worlds": [{"id": "W1", "nodes": [
+		{"id": "S1", "deadline": {"anchor": "activation", "days": 7}}]}]}`))
+	require.NoError(t, err)
+
+	repo := NewMemDeadlineRepository()
+	repo.students = []models.DeadlineStudent{{TenantID: "t1", UserID: "u1"}, {TenantID: "t2", UserID: "u2"}}
+	repo.activated["S1"] = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := services.NewDeadlineService(repo, mgr)
+	svc.SetPlaybooks(tenantPlaybooks{"t2": other})
+
+	// Nobody listed their deadlines; the sync alone stores them
+	require.NoError(t, svc.SyncAll(ctx))
+	assert.Equal(t, time.Date(2026, 3, 15, 23, 59, 59, 0, time.UTC), repo.rows["u1|S1"].DueAt)
+	assert.Equal(t, time.Date(2026, 12, 1, 23, 59, 59, 0, time.UTC), repo.rows["u1|S3"].DueAt)
+	// The second tenant's playbook applies to its students
+	assert.Equal(t, time.Date(2026, 3, 8, 23, 59, 59, 0, time.UTC), repo.rows["u2|S1"].DueAt)
+	assert.NotContains(t, repo.rows, "u2|S3")
+}
@@ -8,13 +8,26 @@ import (
 )
 
 type DictionaryService struct {
-	repo repository.DictionaryRepository
+	repo  repository.DictionaryRepository
+	rooms RoomSyncer
 }
 
 func NewDictionaryService(repo repository.DictionaryRepository) *DictionaryService {
 	return &DictionaryService{repo: repo}
 }
 
+// SetRoomSync keeps cohort chat rooms in step with the cohorts dictionary.
+func (s *DictionaryService) SetRoomSync(rooms RoomSyncer) {
+	s.rooms = rooms
+}
+
+// syncRooms reconciles the tenant's managed chat rooms, if configured.
+func (s *DictionaryService) syncRooms(ctx context.Context, tenantID string) {
+	if s.rooms != nil && tenantID != "" {
+		s.rooms.SyncTenantRooms(ctx, tenantID)
+	}
+}
+
 // --- Programs ---
 
 func (s *DictionaryService) ListPrograms(ctx context.Context, tenantID string, activeOnly bool) ([]models.Program, error) {
@@ -58,15 +71,28 @@ func (s *DictionaryService) ListCohorts(ctx context.Context, tenantID string, ac
 }
 
 func (s *DictionaryService) CreateCohort(ctx context.Context, tenantID, name, startDate, endDate string) (string, error) {
-	return s.repo.CreateCohort(ctx, tenantID, name, startDate, endDate)
+	id, err := s.repo.CreateCohort(ctx, tenantID, name, startDate, endDate)
+	if err != nil {
+		return "", err
+	}
+	s.syncRooms(ctx, tenantID)
+	return id, nil
 }
 
 func (s *DictionaryService) UpdateCohort(ctx context.Context, tenantID, id, name, startDate, endDate string, isActive *bool) error {
-	return s.repo.UpdateCohort(ctx, tenantID, id, name, startDate, endDate, isActive)
+	if err := s.repo.UpdateCohort(ctx, tenantID, id, name, startDate, endDate, isActive); err != nil {
+		return err
+	}
+	s.syncRooms(ctx, tenantID)
+	return nil
 }
 
 func (s *DictionaryService) DeleteCohort(ctx context.Context, tenantID, id string) error {
-	return s.repo.DeleteCohort(ctx, tenantID, id)
+	if err := s.repo.DeleteCohort(ctx, tenantID, id); err != nil {
+		return err
+	}
+	s.syncRooms(ctx, tenantID)
+	return nil
 }
 
 // --- Departments ---
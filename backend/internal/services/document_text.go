@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/textdiff"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/textextract"
+)
+
+const (
+	// documentTextBatch is how many versions one ExtractPending call handles
+	documentTextBatch = 25
+	// maxDocumentTextAttempts stops retrying files that keep failing
+	maxDocumentTextAttempts = 5
+	// maxExtractBytes keeps very large files out of memory
+	maxExtractBytes = 100 << 20
+	// documentTextFetchTTL is how long the download link of a file is valid
+	documentTextFetchTTL = 5 * time.Minute
+)
+
+var (
+	ErrDiffVersionNotFound  = errors.New("version is not in the slot's history")
+	ErrDiffNeedsTwoVersions = errors.New("slot has fewer than two versions")
+	ErrDiffTextPending      = errors.New("text of the version has not been extracted yet")
+	ErrDiffTextUnavailable  = errors.New("text of the version could not be extracted")
+)
+
+// ObjectFetcher downloads a file from a presigned GET URL.
+type ObjectFetcher func(ctx context.Context, url string) (io.ReadCloser, error)
+
+// HTTPObjectFetcher GETs files with the given client.
+func HTTPObjectFetcher(client *http.Client) ObjectFetcher {
+	return func(ctx context.Context, url string) (io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("storage returned %s", resp.Status)
+		}
+		return resp.Body, nil
+	}
+}
+
+// DocumentTextService extracts the plain text of uploaded DOCX and PDF
+// files so they can be searched and reviewers see their page and word
+// counts.
+type DocumentTextService struct {
+	repo    repository.DocumentTextRepository
+	cfg     config.AppConfig
+	storage StorageClient
+	fetch   ObjectFetcher
+}
+
+func NewDocumentTextService(repo repository.DocumentTextRepository, cfg config.AppConfig) *DocumentTextService {
+	return &DocumentTextService{repo: repo, cfg: cfg}
+}
+
+// SetStorage lets the service read files kept in object storage. Without it
+// only files in the local upload directory are extracted.
+func (s *DocumentTextService) SetStorage(storage StorageClient, fetch ObjectFetcher) {
+	s.storage = storage
+	s.fetch = fetch
+}
+
+// ExtractPending extracts a batch of versions that have no text yet and
+// retries failed ones. It returns how many versions it handled.
+func (s *DocumentTextService) ExtractPending(ctx context.Context) (int, error) {
+	pending, err := s.repo.ListPendingTexts(ctx, documentTextBatch, maxDocumentTextAttempts)
+	if err != nil {
+		return 0, err
+	}
+	for i, v := range pending {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		text := s.extract(ctx, v)
+		if err := s.repo.SaveText(ctx, text); err != nil {
+			return i, fmt.Errorf("save text of version %s: %w", v.VersionID, err)
+		}
+	}
+	return len(pending), nil
+}
+
+// extract reads one version and returns the outcome to store. The parsers
+// run on untrusted files, so a panic in them fails the version instead of
+// the batch.
+func (s *DocumentTextService) extract(ctx context.Context, v models.PendingDocumentText) (out *models.DocumentText) {
+	text := &models.DocumentText{VersionID: v.VersionID, TenantID: v.TenantID}
+	fail := func(status models.DocumentTextStatus, err error) *models.DocumentText {
+		msg := err.Error()
+		text.Status, text.Error = status, &msg
+		return text
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[DocumentTextService] extracting version %s panicked: %v", v.VersionID, r)
+			out = fail(models.DocumentTextFailed, fmt.Errorf("extraction failed: %v", r))
+		}
+	}()
+
+	for _, prefix := range []string{"image/", "video/", "audio/"} {
+		if strings.HasPrefix(v.MimeType, prefix) {
+			return fail(models.DocumentTextUnsupported, textextract.ErrUnsupported)
+		}
+	}
+	if v.SizeBytes > maxExtractBytes {
+		return fail(models.DocumentTextUnsupported, textextract.ErrTooLarge)
+	}
+
+	data, err := s.read(ctx, v)
+	if errors.Is(err, textextract.ErrTooLarge) {
+		return fail(models.DocumentTextUnsupported, err)
+	}
+	if err != nil {
+		return fail(models.DocumentTextFailed, err)
+	}
+
+	res, err := textextract.Extract(data)
+	switch {
+	case errors.Is(err, textextract.ErrUnsupported), errors.Is(err, textextract.ErrEncrypted), errors.Is(err, textextract.ErrTooLarge):
+		return fail(models.DocumentTextUnsupported, err)
+	case err != nil:
+		return fail(models.DocumentTextFailed, err)
+	}
+	format := string(res.Format)
+	now := time.Now()
+	text.Status = models.DocumentTextDone
+	text.Format = &format
+	text.Content = res.Text
+	text.PageCount = &res.Pages
+	text.WordCount = &res.Words
+	text.ExtractedAt = &now
+	return text
+}
+
+// read loads a version's file, from the upload directory when it was saved
+// there and from object storage otherwise. Versions created by journey
+// uploads keep their object key in storage_path.
+func (s *DocumentTextService) read(ctx context.Context, v models.PendingDocumentText) ([]byte, error) {
+	key := v.ObjectKey.String
+	if key == "" {
+		if path, ok := s.localPath(v.StoragePath); ok {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return readExtractLimit(f)
+		}
+		key = v.StoragePath
+	}
+	if s.storage == nil || s.fetch == nil {
+		return nil, errors.New("file storage is not configured")
+	}
+	url, err := s.storage.PresignGet(ctx, key, documentTextFetchTTL)
+	if err != nil {
+		return nil, err
+	}
+	if url == "" {
+		return nil, errors.New("file storage is not configured")
+	}
+	body, err := s.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return readExtractLimit(body)
+}
+
+// localPath returns the path of a file inside the upload directory. Paths
+// outside it are never opened.
+func (s *DocumentTextService) localPath(storagePath string) (string, bool) {
+	if storagePath == "" || s.cfg.UploadDir == "" {
+		return "", false
+	}
+	root, err := filepath.Abs(s.cfg.UploadDir)
+	if err != nil {
+		return "", false
+	}
+	path, err := filepath.Abs(storagePath)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
+func readExtractLimit(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxExtractBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxExtractBytes {
+		return nil, textextract.ErrTooLarge
+	}
+	return data, nil
+}
+
+// VersionDiff is the paragraph-level difference between two uploads of a
+// slot.
+type VersionDiff struct {
+	From models.SlotVersion `json:"from"`
+	To   models.SlotVersion `json:"to"`
+	textdiff.Diff
+}
+
+// DiffSlotVersions compares two versions in the history of a student's node
+// slot using their extracted text. Without toID the latest upload is used,
+// without fromID the one before it. contextParagraphs is how many unchanged
+// paragraphs to keep around each edit; negative keeps them all.
+func (s *DocumentTextService) DiffSlotVersions(ctx context.Context, tenantID, studentID, nodeID, slotKey, fromID, toID string, contextParagraphs int) (*VersionDiff, error) {
+	history, err := s.repo.ListSlotVersions(ctx, tenantID, studentID, nodeID, slotKey)
+	if err != nil {
+		return nil, err
+	}
+	find := func(id string) int {
+		for i, v := range history {
+			if v.VersionID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	to := len(history) - 1
+	if toID != "" {
+		if to = find(toID); to < 0 {
+			return nil, ErrDiffVersionNotFound
+		}
+	}
+	from := to - 1
+	if fromID != "" {
+		if from = find(fromID); from < 0 {
+			return nil, ErrDiffVersionNotFound
+		}
+	}
+	if from < 0 || to < 0 {
+		return nil, ErrDiffNeedsTwoVersions
+	}
+
+	oldText, err := s.diffText(ctx, history[from].VersionID)
+	if err != nil {
+		return nil, err
+	}
+	newText, err := s.diffText(ctx, history[to].VersionID)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionDiff{
+		From: history[from],
+		To:   history[to],
+		Diff: textdiff.Compare(oldText, newText, contextParagraphs),
+	}, nil
+}
+
+// diffText returns a version's text, or why it cannot be diffed yet.
+func (s *DocumentTextService) diffText(ctx context.Context, versionID string) (string, error) {
+	text, err := s.repo.GetText(ctx, versionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrDiffTextPending
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case text.Status == models.DocumentTextDone:
+		return text.Content, nil
+	case text.Status == models.DocumentTextFailed && text.Attempts < maxDocumentTextAttempts:
+		return "", ErrDiffTextPending
+	}
+	return "", ErrDiffTextUnavailable
+}
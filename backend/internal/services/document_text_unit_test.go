@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/textdiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDOCX(t *testing.T, text string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:body></w:document>`))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+const testPDF = "%PDF-1.4\n" +
+	"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+	"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+	"3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n" +
+	"4 0 obj << /Length 44 >>\nstream\nBT /F1 12 Tf 72 700 Td (Methods chapter) Tj ET\nendstream\nendobj\n" +
+	"trailer << /Root 1 0 R >>\n%%EOF\n"
+
+func TestDocumentTextService_ExtractPending(t *testing.T) {
+	uploads := t.TempDir()
+	draft := filepath.Join(uploads, "doc-1", "draft.docx")
+	require.NoError(t, os.MkdirAll(filepath.Dir(draft), 0o755))
+	require.NoError(t, os.WriteFile(draft, testDOCX(t, "Literature review of cardiology trials"), 0o644))
+
+	repo := &MemDocumentTextRepository{saved: map[string]*models.DocumentText{}, pending: []models.PendingDocumentText{
+		{VersionID: "local", TenantID: "t1", StoragePath: draft, MimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{VersionID: "stored", TenantID: "t1", StoragePath: "nodes/u1/draft", ObjectKey: sql.NullString{String: "nodes/u1/draft.pdf", Valid: true}, MimeType: "application/octet-stream"},
+		{VersionID: "image", TenantID: "t1", StoragePath: "chat/r1/photo.png", MimeType: "image/png"},
+		{VersionID: "outside", TenantID: "t1", StoragePath: "/etc/passwd", MimeType: "text/plain"},
+	}}
+	svc := services.NewDocumentTextService(repo, config.AppConfig{UploadDir: uploads})
+
+	t.Run("Without storage", func(t *testing.T) {
+		n, err := svc.ExtractPending(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 4, n)
+
+		local := repo.saved["local"]
+		assert.Equal(t, models.DocumentTextDone, local.Status)
+		assert.Equal(t, "Literature review of cardiology trials", local.Content)
+		assert.Equal(t, 5, *local.WordCount)
+		assert.Equal(t, 1, *local.PageCount)
+
+		assert.Equal(t, models.DocumentTextFailed, repo.saved["stored"].Status)
+		assert.Equal(t, models.DocumentTextUnsupported, repo.saved["image"].Status)
+		assert.Equal(t, models.DocumentTextFailed, repo.saved["outside"].Status, "files outside the upload directory are not read")
+	})
+
+	t.Run("From object storage", func(t *testing.T) {
+		var fetched []string
+		svc.SetStorage(&services.MockStorageClient{}, func(ctx context.Context, url string) (io.ReadCloser, error) {
+			fetched = append(fetched, url)
+			return io.NopCloser(bytes.NewReader([]byte(testPDF))), nil
+		})
+		repo.pending = repo.pending[1:2]
+		_, err := svc.ExtractPending(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"http://mock-s3.com/nodes/u1/draft.pdf"}, fetched)
+		stored := repo.saved["stored"]
+		assert.Equal(t, models.DocumentTextDone, stored.Status)
+		assert.Equal(t, "pdf", *stored.Format)
+		assert.Equal(t, "Methods chapter", stored.Content)
+		assert.Equal(t, 2, *stored.WordCount)
+	})
+
+	t.Run("A panic fails only its version", func(t *testing.T) {
+		svc.SetStorage(&services.MockStorageClient{}, func(ctx context.Context, url string) (io.ReadCloser, error) {
+			panic("malformed file")
+		})
+		repo.pending = []models.PendingDocumentText{repo.pending[0], {VersionID: "image", TenantID: "t1", MimeType: "image/png"}}
+		n, err := svc.ExtractPending(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		stored := repo.saved["stored"]
+		assert.Equal(t, models.DocumentTextFailed, stored.Status)
+		assert.Contains(t, *stored.Error, "malformed file")
+		assert.Equal(t, models.DocumentTextUnsupported, repo.saved["image"].Status)
+	})
+}
+
+func TestDocumentTextService_DiffSlotVersions(t *testing.T) {
+	repo := &MemDocumentTextRepository{
+		saved: map[string]*models.DocumentText{
+			"v1":   {VersionID: "v1", Status: models.DocumentTextDone, Content: "Aims\n\nWe enrolled 120 patients.\n\nSummary"},
+			"v2":   {VersionID: "v2", Status: models.DocumentTextDone, Content: "Aims\n\nWe enrolled 240 patients.\n\nSummary"},
+			"v3":   {VersionID: "v3", Status: models.DocumentTextDone, Content: "Aims\n\nWe enrolled 240 patients.\n\nLimitations\n\nSummary"},
+			"scan": {VersionID: "scan", Status: models.DocumentTextUnsupported},
+		},
+		history: []models.SlotVersion{{VersionID: "v1"}, {VersionID: "v2"}, {VersionID: "v3"}, {VersionID: "scan"}, {VersionID: "new"}},
+	}
+	svc := services.NewDocumentTextService(repo, config.AppConfig{})
+	ctx := context.Background()
+
+	diff, err := svc.DiffSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file", "v1", "v3", -1)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", diff.From.VersionID)
+	assert.Equal(t, "v3", diff.To.VersionID)
+	assert.Equal(t, textdiff.Stats{Unchanged: 2, Changed: 1, Inserted: 1}, diff.Stats)
+
+	// By default the version before the requested one
+	diff, err = svc.DiffSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file", "", "v3", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", diff.From.VersionID)
+	assert.Equal(t, textdiff.Stats{Unchanged: 3, Inserted: 1}, diff.Stats)
+
+	_, err = svc.DiffSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file", "v3", "", 2)
+	assert.ErrorIs(t, err, services.ErrDiffTextPending, "the latest upload is not extracted yet")
+	_, err = svc.DiffSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file", "v3", "scan", 2)
+	assert.ErrorIs(t, err, services.ErrDiffTextUnavailable)
+	_, err = svc.DiffSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file", "", "v1", 2)
+	assert.ErrorIs(t, err, services.ErrDiffNeedsTwoVersions)
+	_, err = svc.DiffSlotVersions(ctx, "t1", "s1", "S1_text_ready", "dissertation_draft_file", "other", "v3", 2)
+	assert.ErrorIs(t, err, services.ErrDiffVersionNotFound)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before
+// it is dropped and the client has to reconnect and resync.
+const subscriberBuffer = 64
+
+// Subscription receives events on C until Close is called. C is closed
+// when the subscription ends, including when the hub drops a subscriber
+// that stopped reading.
+type Subscription[E any] struct {
+	C <-chan E
+
+	ch     chan E
+	topics []string
+	hub    *eventHub[E]
+	once   sync.Once
+}
+
+// eventHub fans events out to the subscribers of a topic. With Redis every
+// event goes through pub/sub on prefix+topic so subscribers on all replicas
+// receive it; without Redis delivery stays within the process.
+type eventHub[E any] struct {
+	rds    *redis.Client
+	prefix string
+	name   string
+
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription[E]]struct{}
+}
+
+func newEventHub[E any](rds *redis.Client, prefix, name string) *eventHub[E] {
+	return &eventHub[E]{rds: rds, prefix: prefix, name: name, topics: map[string]map[*Subscription[E]]struct{}{}}
+}
+
+func (h *eventHub[E]) subscribe(topics ...string) *Subscription[E] {
+	ch := make(chan E, subscriberBuffer)
+	sub := &Subscription[E]{C: ch, ch: ch, topics: topics, hub: h}
+	h.mu.Lock()
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = map[*Subscription[E]]struct{}{}
+		}
+		h.topics[topic][sub] = struct{}{}
+	}
+	h.mu.Unlock()
+	return sub
+}
+
+// Close unregisters the subscription. It is safe to call more than once.
+func (s *Subscription[E]) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		for _, topic := range s.topics {
+			if subs := h.topics[topic]; subs != nil {
+				delete(subs, s)
+				if len(subs) == 0 {
+					delete(h.topics, topic)
+				}
+			}
+		}
+		h.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// publish delivers ev to every subscriber of topic. When Redis is
+// unavailable the event still reaches subscribers of this replica.
+func (h *eventHub[E]) publish(ctx context.Context, topic string, ev E) error {
+	if h.rds == nil {
+		h.dispatch(topic, ev)
+		return nil
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := h.rds.Publish(ctx, h.prefix+topic, data).Err(); err != nil {
+		h.dispatch(topic, ev)
+		return err
+	}
+	return nil
+}
+
+// run relays events published by any replica to local subscribers until
+// ctx is cancelled. It is a no-op without Redis.
+func (h *eventHub[E]) run(ctx context.Context) {
+	if h.rds == nil {
+		return
+	}
+	ps := h.rds.PSubscribe(ctx, h.prefix+"*")
+	defer ps.Close()
+
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var ev E
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				log.Printf("[%s] Dropping malformed event on %s: %v", h.name, msg.Channel, err)
+				continue
+			}
+			h.dispatch(strings.TrimPrefix(msg.Channel, h.prefix), ev)
+		}
+	}
+}
+
+func (h *eventHub[E]) subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+func (h *eventHub[E]) dispatch(topic string, ev E) {
+	var slow []*Subscription[E]
+	h.mu.RLock()
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- ev:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+	for _, sub := range slow {
+		log.Printf("[%s] Dropping slow subscriber of %s", h.name, topic)
+		sub.Close()
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+)
+
+// FieldValidationError lists the form fields of a node that do not satisfy
+// the playbook's requirements.fields schema.
+type FieldValidationError struct {
+	NodeID string
+	Fields []playbook.FieldError
+}
+
+func (e *FieldValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Message)
+	}
+	return fmt.Sprintf("invalid form for %s: %s", e.NodeID, strings.Join(msgs, "; "))
+}
+
+// validateForm checks form against the node's declared fields. Required
+// fields are only enforced when complete is set, so drafts can be saved.
+func (s *JourneyService) validateForm(ctx context.Context, studentID, nodeID string, form map[string]any, complete bool) error {
+	nodeDef, ok := s.manager(ctx).NodeDefinition(nodeID)
+	if !ok || nodeDef.Requirements == nil || len(nodeDef.Requirements.Fields) == 0 {
+		return nil
+	}
+	env, err := s.conditionEnv(ctx, studentID, form)
+	if err != nil {
+		return err
+	}
+	fieldErrs, err := s.manager(ctx).ValidateFields(nodeID, env, complete)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrs) > 0 {
+		return &FieldValidationError{NodeID: nodeID, Fields: fieldErrs}
+	}
+	return nil
+}
+
+// currentForm loads the form data of the instance's current revision.
+func (s *JourneyService) currentForm(ctx context.Context, inst *models.NodeInstance) (map[string]any, error) {
+	if inst.CurrentRev <= 0 {
+		return nil, nil
+	}
+	raw, err := s.repo.GetFormRevision(ctx, inst.ID, inst.CurrentRev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	var form map[string]any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &form); err != nil {
+			return nil, fmt.Errorf("parse form data: %w", err)
+		}
+	}
+	return form, nil
+}
+
+// instanceOwner returns the student a node instance belongs to, falling back
+// to the acting user for instances loaded without their owner.
+func instanceOwner(inst *models.NodeInstance, userID string) string {
+	if inst.UserID != "" {
+		return inst.UserID
+	}
+	return userID
+}
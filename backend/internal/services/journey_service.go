@@ -26,6 +26,8 @@ type JourneyService struct {
 	mailer  mailer.Mailer
 	storage StorageClient
 	docSvc  *DocumentService
+
+	deadlines DeadlineSyncer
 }
 
 func NewJourneyService(repo repository.JourneyRepository, pb *playbook.Manager, cfg config.AppConfig, mailer mailer.Mailer, storage StorageClient, docSvc *DocumentService) *JourneyService {
@@ -39,6 +41,32 @@ func NewJourneyService(repo repository.JourneyRepository, pb *playbook.Manager,
 	}
 }
 
+// SetDeadlines keeps the student's rule-derived deadlines in step with the
+// nodes they open.
+func (s *JourneyService) SetDeadlines(deadlines DeadlineSyncer) {
+	s.deadlines = deadlines
+}
+
+// syncDeadlines refreshes the student's deadlines, if configured. A failure
+// is logged; the node stays open either way.
+func (s *JourneyService) syncDeadlines(ctx context.Context, tenantID, userID string) {
+	if s.deadlines == nil {
+		return
+	}
+	if err := s.deadlines.Sync(ctx, tenantID, userID); err != nil {
+		log.Printf("[JourneyService] deadline sync failed for user=%s: %v", userID, err)
+	}
+}
+
+// manager returns the playbook resolved for the request's tenant, falling
+// back to the one the service was built with.
+func (s *JourneyService) manager(ctx context.Context) *playbook.Manager {
+	if m, ok := playbook.FromContext(ctx); ok {
+		return m
+	}
+	return s.pb
+}
+
 // GetState returns user's journey state map
 func (s *JourneyService) GetState(ctx context.Context, userID, tenantID string) (map[string]string, error) {
 	return s.repo.GetJourneyState(ctx, userID, tenantID)
@@ -72,9 +100,9 @@ func (s *JourneyService) GetScoreboard(ctx context.Context, tenantID, currentUse
 	userScores := make(map[string]int)
 	for _, dn := range doneNodes {
 		// Only count known nodes
-		if _, ok := s.pb.NodeDefinition(dn.NodeID); ok {
+		if _, ok := s.manager(ctx).NodeDefinition(dn.NodeID); ok {
 			// Check World ID
-			worldID := s.pb.NodeWorldID(dn.NodeID)
+			worldID := s.manager(ctx).NodeWorldID(dn.NodeID)
 			// Conditional Logic: Nodes from W3 are 0XP
 			if worldID != "W3" {
 				userScores[dn.UserID] += 100
@@ -208,16 +236,31 @@ func (s *JourneyService) GetScoreboard(ctx context.Context, tenantID, currentUse
 func (s *JourneyService) ActivateNextNodes(ctx context.Context, userID, completedNodeID, tenantID string) error {
 	log.Printf("[ActivateNextNodes] Starting for user=%s node=%s", userID, completedNodeID)
 	
-	nodeDef, ok := s.pb.NodeDefinition(completedNodeID)
+	nodeDef, ok := s.manager(ctx).NodeDefinition(completedNodeID)
 	if !ok {
 		return nil // Should not happen with valid nodeID
 	}
 	
-	if len(nodeDef.Next) == 0 {
+	// Outcome-driven branching: follow only the branch named by the recorded outcome
+	targets := nodeDef.Next
+	if outcome := s.recordedOutcome(ctx, userID, nodeDef); outcome != nil && len(outcome.Next) > 0 {
+		targets = outcome.Next
+	}
+	
+	if len(targets) == 0 {
 		return nil
 	}
 	
-	for _, nodeID := range nodeDef.Next {
+	activated := false
+	defer func() {
+		if activated {
+			s.syncDeadlines(ctx, tenantID, userID)
+		}
+	}()
+	for _, nodeID := range targets {
+		if nodeID == completedNodeID {
+			continue
+		}
 		// 1. Check if we can activate this node (all prerequisites done)
 		can, err := s.canActivate(ctx, userID, nodeID)
 		if err != nil {
@@ -225,7 +268,7 @@ func (s *JourneyService) ActivateNextNodes(ctx context.Context, userID, complete
 			continue
 		}
 		if !can {
-			log.Printf("[ActivateNextNodes] Node %s prerequisites not yet met", nodeID)
+			log.Printf("[ActivateNextNodes] Node %s prerequisites or condition not yet met", nodeID)
 			continue
 		}
 
@@ -236,16 +279,18 @@ func (s *JourneyService) ActivateNextNodes(ctx context.Context, userID, complete
 				err = s.repo.UpdateNodeInstanceState(ctx, inst.ID, "locked", "active")
 				if err == nil {
 					log.Printf("Activated existing node %s", nodeID)
+					activated = true
 					_ = s.repo.UpsertJourneyState(ctx, userID, nodeID, "active", tenantID)
 				}
 			}
 		} else if err == nil { 
 			// Create
-			id, err := s.repo.CreateNodeInstance(ctx, tenantID, userID, s.pb.VersionID, nodeID, "active", nil)
+			id, err := s.repo.CreateNodeInstance(ctx, tenantID, userID, s.manager(ctx).VersionID, nodeID, "active", nil)
 			if err != nil {
 				log.Printf("[ActivateNextNodes] Error creating instance %s: %v", nodeID, err)
 			} else {
 				log.Printf("[ActivateNextNodes] Created new node instance %s for node %s", id, nodeID)
+				activated = true
 				_ = s.repo.UpsertJourneyState(ctx, userID, nodeID, "active", tenantID)
 				
 				// Log Event
@@ -257,14 +302,48 @@ func (s *JourneyService) ActivateNextNodes(ctx context.Context, userID, complete
 	return nil
 }
 
-func (s *JourneyService) canActivate(ctx context.Context, userID, nodeID string) (bool, error) {
-	nodeDef, ok := s.pb.NodeDefinition(nodeID)
+// recordedOutcome returns the playbook outcome most recently recorded for the node, if any.
+func (s *JourneyService) recordedOutcome(ctx context.Context, userID string, nodeDef playbook.Node) *playbook.Outcome {
+	if len(nodeDef.Outcomes) == 0 {
+		return nil
+	}
+	inst, err := s.repo.GetNodeInstance(ctx, userID, nodeDef.ID)
+	if err != nil || inst == nil {
+		return nil
+	}
+	outs, err := s.repo.GetNodeOutcomes(ctx, inst.ID)
+	if err != nil || len(outs) == 0 {
+		return nil
+	}
+	// GetNodeOutcomes is ordered newest first
+	outcome, ok := s.manager(ctx).OutcomeByValue(nodeDef.ID, outs[0].OutcomeValue)
 	if !ok {
-		return false, fmt.Errorf("node %s not found in playbook", nodeID)
+		return nil
 	}
+	return &outcome
+}
 
-	if len(nodeDef.Prerequisites) == 0 {
-		return true, nil
+// selectOutcome evaluates the node's outcomes against its current form revision and the student's profile.
+func (s *JourneyService) selectOutcome(ctx context.Context, inst *models.NodeInstance, userID string) (*playbook.Outcome, error) {
+	nodeDef, ok := s.manager(ctx).NodeDefinition(inst.NodeID)
+	if !ok || len(nodeDef.Outcomes) == 0 {
+		return nil, nil
+	}
+	form, err := s.currentForm(ctx, inst)
+	if err != nil {
+		return nil, err
+	}
+	env, err := s.conditionEnv(ctx, instanceOwner(inst, userID), form)
+	if err != nil {
+		return nil, err
+	}
+	return s.manager(ctx).SelectOutcome(inst.NodeID, env)
+}
+
+func (s *JourneyService) canActivate(ctx context.Context, userID, nodeID string) (bool, error) {
+	nodeDef, ok := s.manager(ctx).NodeDefinition(nodeID)
+	if !ok {
+		return false, fmt.Errorf("node %s not found in playbook", nodeID)
 	}
 
 	// Fetch all instances for user to check states
@@ -281,7 +360,73 @@ func (s *JourneyService) canActivate(ctx context.Context, userID, nodeID string)
 		}
 	}
 
-	return true, nil
+	// Conditional stages (e.g. the RP stage) only open when their playbook condition holds
+	return s.nodeVisible(ctx, userID, nodeDef)
+}
+
+// nodeVisible evaluates a conditional stage's playbook condition against the
+// student's profile. Nodes whose condition only picks their next branch
+// always apply.
+func (s *JourneyService) nodeVisible(ctx context.Context, userID string, nodeDef playbook.Node) (bool, error) {
+	if nodeDef.Gate() == "" {
+		return true, nil
+	}
+	env, err := s.conditionEnv(ctx, userID, nil)
+	if err != nil {
+		return false, err
+	}
+	return s.manager(ctx).EvaluateCondition(nodeDef.Gate(), env)
+}
+
+// conditionEnv builds the expression environment for a student from the latest
+// S1_profile submission and, optionally, the form data of the node being evaluated.
+func (s *JourneyService) conditionEnv(ctx context.Context, userID string, form map[string]any) (playbook.Env, error) {
+	profile, err := s.profileData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return playbook.NewEnv(profile, form), nil
+}
+
+func (s *JourneyService) profileData(ctx context.Context, userID string) (map[string]any, error) {
+	inst, err := s.repo.GetNodeInstance(ctx, userID, "S1_profile")
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil || inst.CurrentRev == 0 {
+		return nil, nil
+	}
+	raw, err := s.repo.GetFormRevision(ctx, inst.ID, inst.CurrentRev)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var data map[string]any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, fmt.Errorf("parse profile form: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// GetConditions reports which playbook conditions hold for the student and
+// which conditional nodes are hidden as a result.
+func (s *JourneyService) GetConditions(ctx context.Context, userID string) (*models.JourneyConditions, error) {
+	env, err := s.conditionEnv(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	hidden := s.manager(ctx).HiddenNodes(env)
+	if hidden == nil {
+		hidden = []string{}
+	}
+	return &models.JourneyConditions{
+		Conditions:  s.manager(ctx).EvaluateConditions(env),
+		HiddenNodes: hidden,
+	}, nil
 }
 
 func (s *JourneyService) verifyRequirements(ctx context.Context, inst *models.NodeInstance) error {
@@ -408,13 +553,20 @@ func (s *JourneyService) EnsureNodeInstance(ctx context.Context, tenantID, userI
 	}
 	
 	// 2. Create
-	nodeDef, ok := s.pb.NodeDefinition(nodeID)
+	nodeDef, ok := s.manager(ctx).NodeDefinition(nodeID)
 	if !ok {
 		return nil, fmt.Errorf("node not found in playbook")
 	}
+	visible, err := s.nodeVisible(ctx, userID, nodeDef)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, fmt.Errorf("node %s is not applicable: condition %s not met", nodeID, nodeDef.Condition)
+	}
 	
 	log.Printf("[JourneyService] Creating node instance: userID=%s nodeID=%s", userID, nodeID)
-	id, err := s.repo.CreateNodeInstance(ctx, tenantID, userID, s.pb.VersionID, nodeID, "active", locale)
+	id, err := s.repo.CreateNodeInstance(ctx, tenantID, userID, s.manager(ctx).VersionID, nodeID, "active", locale)
 	if err != nil {
 		return nil, err
 	}
@@ -434,13 +586,14 @@ func (s *JourneyService) EnsureNodeInstance(ctx context.Context, tenantID, userI
 	
 	// Upsert Journey State
 	_ = s.repo.UpsertJourneyState(ctx, userID, nodeID, "active", tenantID)
+	s.syncDeadlines(ctx, tenantID, userID)
 	
 	// Return full object
 	return s.repo.GetNodeInstanceByID(ctx, id)
 }
 
 func (s *JourneyService) ensureSlots(ctx context.Context, tenantID, instanceID, nodeID string) error {
-	nodeDef, ok := s.pb.NodeDefinition(nodeID)
+	nodeDef, ok := s.manager(ctx).NodeDefinition(nodeID)
 	if !ok || nodeDef.Requirements == nil || len(nodeDef.Requirements.Uploads) == 0 {
 		return nil
 	}
@@ -476,6 +629,9 @@ func (s *JourneyService) PutSubmission(ctx context.Context, tenantID, userID, ro
 
 	// 2. Append Form Revision
 	if len(formData) > 0 {
+		if err := s.validateSubmission(ctx, inst, userID, state, formData); err != nil {
+			return err
+		}
 		inst.CurrentRev++
 		err = s.repo.InsertFormRevision(ctx, inst.ID, inst.CurrentRev, formData, userID)
 		if err != nil {
@@ -516,6 +672,22 @@ func (s *JourneyService) PutSubmission(ctx context.Context, tenantID, userID, ro
 	return nil
 }
 
+// validateSubmission checks incoming form data before it is stored. Required
+// fields are enforced up front when the same request completes the node, so a
+// failing completion does not leave a new revision behind.
+func (s *JourneyService) validateSubmission(ctx context.Context, inst *models.NodeInstance, userID, state string, formData []byte) error {
+	nodeDef, ok := s.manager(ctx).NodeDefinition(inst.NodeID)
+	if !ok || nodeDef.Requirements == nil || len(nodeDef.Requirements.Fields) == 0 {
+		return nil
+	}
+	var form map[string]any
+	if err := json.Unmarshal(formData, &form); err != nil {
+		return fmt.Errorf("parse form data: %w", err)
+	}
+	complete := state == "submitted" || state == "done"
+	return s.validateForm(ctx, instanceOwner(inst, userID), inst.NodeID, form, complete)
+}
+
 // PatchState handles state transition only
 func (s *JourneyService) PatchState(ctx context.Context, tenantID, userID, role, nodeID, state string) error {
 	inst, err := s.EnsureNodeInstance(ctx, tenantID, userID, nodeID, nil) // use existing locale
@@ -536,82 +708,239 @@ func (s *JourneyService) PatchState(ctx context.Context, tenantID, userID, role,
 	return nil
 }
 
-// transitionState validation and execution
-func (s *JourneyService) transitionState(ctx context.Context, tenantID string, inst *models.NodeInstance, userID, role, newState string) error {
-	// Check allowed roles
+// transitionDenial explains why role may not move the instance to newState,
+// or returns "" when the transition is allowed. The node_state_transitions
+// table governs every transition, and closing a node additionally requires
+// the role to be listed in the node's who_can_complete. A list naming only
+// the student limits who closes the node themselves, so reviewers still
+// approve it once submitted.
+func (s *JourneyService) transitionDenial(ctx context.Context, inst *models.NodeInstance, role, newState string) (string, error) {
 	roles, err := s.repo.GetAllowedTransitionRoles(ctx, inst.State, newState)
 	if err != nil && err != sql.ErrNoRows {
-		return err
+		return "", err
 	}
-	
 	allowed := false
-	// Logic from handler: "Iteration override: allow students to complete nodes directly to done"
-	if role == "student" && newState == "done" && (inst.State == "active" || inst.State == "submitted") {
-		allowed = true
-	} else {
-		for _, r := range roles {
-			if r == role {
-				allowed = true; break
-			}
+	for _, r := range roles {
+		if r == role {
+			allowed = true
+			break
 		}
 	}
-	
 	if !allowed {
-		return fmt.Errorf("role %s cannot transition from %s to %s", role, inst.State, newState)
+		return fmt.Sprintf("role %s cannot transition from %s to %s", role, inst.State, newState), nil
 	}
-	
+
+	nodeDef, ok := s.manager(ctx).NodeDefinition(inst.NodeID)
+	if !ok {
+		return fmt.Sprintf("node %s is not in the playbook", inst.NodeID), nil
+	}
+	review := (inst.State == "submitted" || inst.State == "under_review") && role != "student" && !nodeDef.ClosedByStaff()
+	if newState == "done" && !nodeDef.CanComplete(role) && !review {
+		return fmt.Sprintf("role %s cannot complete %s (allowed: %s)", role, inst.NodeID, strings.Join(nodeDef.Completers(), ", ")), nil
+	}
+	return "", nil
+}
+
+// ErrTransitionDenied matches the errors returned when the acting role may
+// not make a node state transition.
+var ErrTransitionDenied = errors.New("transition denied")
+
+// transitionDeniedError carries the denial reason as its message.
+type transitionDeniedError string
+
+func (e transitionDeniedError) Error() string { return string(e) }
+
+func (e transitionDeniedError) Is(target error) bool { return target == ErrTransitionDenied }
+
+// checkTransition logs a transition_denied event and returns the reason as
+// an error when role may not move the instance to newState.
+func (s *JourneyService) checkTransition(ctx context.Context, inst *models.NodeInstance, actorID, role, newState string) error {
+	reason, err := s.transitionDenial(ctx, inst, role, newState)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		return nil
+	}
+	_ = s.repo.LogNodeEvent(ctx, inst.ID, "transition_denied", actorID, map[string]any{
+		"from": inst.State, "to": newState, "role": role, "reason": reason,
+	})
+	return transitionDeniedError(reason)
+}
+
+// ErrOutcomeNotDetermined matches the errors returned when a node's form
+// does not pick an outcome that lets the journey move on.
+var ErrOutcomeNotDetermined = errors.New("outcome not determined")
+
+// completionOutcome selects the outcome closing the instance records, refusing
+// outcomes that send the student back to the same node.
+func (s *JourneyService) completionOutcome(ctx context.Context, inst *models.NodeInstance, userID string) (*playbook.Outcome, error) {
+	outcome, err := s.selectOutcome(ctx, inst, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOutcomeNotDetermined, err)
+	}
+	if outcome != nil && outcome.LoopsBack(inst.NodeID) {
+		return nil, fmt.Errorf("%w: outcome %s requires further work on %s", ErrOutcomeNotDetermined, outcome.Value, inst.NodeID)
+	}
+	return outcome, nil
+}
+
+// ErrRequirementsNotMet matches the errors returned when a node's required
+// files or form fields are missing or invalid.
+var ErrRequirementsNotMet = errors.New("requirements not met")
+
+// checkTransitionState runs every check moving the instance to newState must
+// pass and returns the outcome a completion records.
+func (s *JourneyService) checkTransitionState(ctx context.Context, inst *models.NodeInstance, userID, role, newState string) (*playbook.Outcome, error) {
+	// Check allowed roles
+	if err := s.checkTransition(ctx, inst, userID, role, newState); err != nil {
+		return nil, err
+	}
+
 	// Requirement Verification for terminal states
 	if newState == "submitted" || newState == "done" {
 		if err := s.verifyRequirements(ctx, inst); err != nil {
-			return fmt.Errorf("requirements not met: %w", err)
+			return nil, fmt.Errorf("%w: %w", ErrRequirementsNotMet, err)
+		}
+		form, err := s.currentForm(ctx, inst)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.validateForm(ctx, instanceOwner(inst, userID), inst.NodeID, form, true); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRequirementsNotMet, err)
 		}
 	}
 
-	oldState := inst.State
-	err = s.repo.UpdateNodeInstanceState(ctx, inst.ID, oldState, newState)
+	// Completing a node with outcomes picks the branch the journey will follow
+	if newState != "done" {
+		return nil, nil
+	}
+	return s.completionOutcome(ctx, inst, userID)
+}
+
+// CheckReviewCompletion runs the checks closing the node instance must pass,
+// so an approval that cannot close it is refused before it is stored.
+func (s *JourneyService) CheckReviewCompletion(ctx context.Context, instanceID, actorID, role string) error {
+	inst, err := s.repo.GetNodeInstanceByID(ctx, instanceID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("node state changed by another process (anticipated %s)", oldState)
-		}
 		return err
 	}
-	
-	// Update Journey State
-	_ = s.repo.UpsertJourneyState(ctx, userID, inst.NodeID, newState, tenantID)
-	
-	// Log Event
-	payload := map[string]any{"from": oldState, "to": newState}
-	_ = s.repo.LogNodeEvent(ctx, inst.ID, "state_changed", userID, payload)
-	
-	// Notify
-	go s.sendStateChangeEmail(context.Background(), userID, inst.NodeID, oldState, newState)
-	
+	if inst.State == "done" {
+		return nil
+	}
+	_, err = s.checkTransitionState(ctx, inst, actorID, role, "done")
+	return err
+}
+
+// CompleteReviewed closes a node instance whose documents a reviewer
+// approved, with the same checks, outcome and messages as any completion,
+// and opens the nodes that follow it.
+func (s *JourneyService) CompleteReviewed(ctx context.Context, tenantID, instanceID, actorID, role string) error {
+	inst, err := s.repo.GetNodeInstanceByID(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if inst.State == "done" {
+		return nil
+	}
+	if err := s.transitionState(ctx, tenantID, inst, actorID, role, "done"); err != nil {
+		return err
+	}
+	if err := s.ActivateNextNodes(ctx, instanceOwner(inst, actorID), inst.NodeID, tenantID); err != nil {
+		log.Printf("[CompleteReviewed] Failed to activate next nodes: %v", err)
+	}
 	return nil
 }
 
-func (s *JourneyService) sendStateChangeEmail(ctx context.Context, userID, nodeID, fromState, toState string) {
-	// Fetch user details? Repo method exists? 
-	// GetUsersByIDs
-	users, err := s.repo.GetUsersByIDs(context.Background(), []string{userID})
-	if err != nil || len(users) == 0 {
-		return
+// transitionState validation and execution
+func (s *JourneyService) transitionState(ctx context.Context, tenantID string, inst *models.NodeInstance, userID, role, newState string) error {
+	outcome, err := s.checkTransitionState(ctx, inst, userID, role, newState)
+	if err != nil {
+		return err
 	}
-	user := users[0]
-	// Logic:
-	studentName := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
-	
-	if s.mailer != nil {
-		subject := fmt.Sprintf("Node Status Change: %s (%s -> %s)", nodeID, fromState, toState)
-		body := fmt.Sprintf("Student %s changed node %s status from %s to %s.", studentName, nodeID, fromState, toState)
-		log.Printf("[JourneyService] Sending email: %s", subject)
-		_ = s.mailer.SendNotificationEmail("admin@portal.kaznmu.kz", subject, body) 
+
+	// The state change, its events and the messages it triggers commit together
+	oldState := inst.State
+	return s.repo.WithTx(ctx, func(tx repository.JourneyRepository) error {
+		err := tx.UpdateNodeInstanceState(ctx, inst.ID, oldState, newState)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("node state changed by another process (anticipated %s)", oldState)
+			}
+			return err
+		}
+
+		// Update Journey State
+		if err := tx.UpsertJourneyState(ctx, instanceOwner(inst, userID), inst.NodeID, newState, tenantID); err != nil {
+			return err
+		}
+
+		// Log Event
+		payload := map[string]any{"from": oldState, "to": newState}
+		if err := tx.LogNodeEvent(ctx, inst.ID, "state_changed", userID, payload); err != nil {
+			return err
+		}
+
+		if outcome != nil {
+			if err := tx.InsertOutcome(ctx, inst.ID, outcome.Value, userID, ""); err != nil {
+				return fmt.Errorf("record outcome: %w", err)
+			}
+			if err := tx.LogNodeEvent(ctx, inst.ID, "outcome_selected", userID, map[string]any{"outcome": outcome.Value, "next": outcome.Next}); err != nil {
+				return err
+			}
+		}
+
+		// Notify
+		return s.enqueueStateChange(ctx, tx, tenantID, userID, inst, oldState, newState)
+	})
+}
+
+// enqueueStateChange records the messages a state change sends: the state
+// change email, when a recipient is configured, and on submission the
+// advisors' review notification.
+func (s *JourneyService) enqueueStateChange(ctx context.Context, tx repository.JourneyRepository, tenantID, userID string, inst *models.NodeInstance, fromState, toState string) error {
+	if s.mailer != nil && s.cfg.StateChangeEmailTo != "" {
+		studentName := instanceOwner(inst, userID)
+		users, err := tx.GetUsersByIDs(ctx, []string{studentName})
+		if err != nil {
+			return err
+		}
+		if len(users) > 0 {
+			studentName = fmt.Sprintf("%s %s", users[0].FirstName, users[0].LastName)
+		}
+		msg, err := NewOutboxMessage(tenantID, models.OutboxKindEmail, models.EmailPayload{
+			To:      s.cfg.StateChangeEmailTo,
+			Subject: fmt.Sprintf("Node Status Change: %s (%s -> %s)", inst.NodeID, fromState, toState),
+			Body:    fmt.Sprintf("Student %s changed node %s status from %s to %s.", studentName, inst.NodeID, fromState, toState),
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.EnqueueOutbox(ctx, msg); err != nil {
+			return fmt.Errorf("enqueue state change email: %w", err)
+		}
 	}
+
+	if toState == "submitted" {
+		msg, err := NewOutboxMessage(tenantID, models.OutboxKindAdvisorSubmission, models.AdvisorSubmissionPayload{
+			StudentID:      instanceOwner(inst, userID),
+			NodeID:         inst.NodeID,
+			NodeInstanceID: inst.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.EnqueueOutbox(ctx, msg); err != nil {
+			return fmt.Errorf("enqueue advisor notification: %w", err)
+		}
+	}
+	return nil
 }
 
 // PresignUpload generates S3 URL and returns both URL and object path
 func (s *JourneyService) PresignUpload(ctx context.Context, userID, nodeID, slotKey, filename, contentType string, sizeBytes int64) (string, string, error) {
 	// 1. Validate against playbook
-	nodeDef, ok := s.pb.NodeDefinition(nodeID)
+	nodeDef, ok := s.manager(ctx).NodeDefinition(nodeID)
 	if !ok {
 		return "", "", errors.New("node not found in playbook")
 	}
@@ -166,6 +166,277 @@ func TestJourneyService_PutSubmission_Unit(t *testing.T) {
 	})
 }
 
+func TestJourneyService_PutSubmission_Outcomes_Unit(t *testing.T) {
+	when := "(form.remarks_exist == false) || (form.remarks_exist == true && form.remarks_resolved == true)"
+	pb := &playbook.Manager{
+		Nodes: map[string]playbook.Node{
+			"E3": {ID: "E3", Next: []string{"RP1", "D1"}, Condition: "rp_required", Outcomes: []playbook.Outcome{
+				{Value: "proceed_rp", When: when, Condition: "rp_required", Next: []string{"RP1"}},
+				{Value: "proceed_direct", When: when, Next: []string{"D1"}},
+				{Value: "needs_resolution", When: "form.remarks_exist == true && form.remarks_resolved != true", Next: []string{"E3"}},
+			}},
+			"RP1": {ID: "RP1", Prerequisites: []string{"E3"}, Condition: "rp_required", Conditional: true},
+			"D1":  {ID: "D1", Prerequisites: []string{"E3"}},
+		},
+		Conditions: map[string]playbook.Condition{
+			"rp_required": {ID: "rp_required", Expr: "profile.years_since_graduation > 3"},
+		},
+	}
+
+	type fixture struct {
+		svc       *services.JourneyService
+		outcomes  []models.NodeOutcome
+		activated map[string]bool
+	}
+	setup := func() *fixture {
+		f := &fixture{activated: map[string]bool{}}
+		mock := NewMockJourneyRepository()
+		e3 := &models.NodeInstance{ID: "inst_e3", NodeID: "E3", State: "active"}
+		forms := map[int][]byte{}
+		mock.GetNodeInstanceFunc = func(ctx context.Context, userID, nodeID string) (*models.NodeInstance, error) {
+			if nodeID == "E3" {
+				return e3, nil
+			}
+			return nil, nil
+		}
+		mock.GetNodeInstanceByIDFunc = func(ctx context.Context, id string) (*models.NodeInstance, error) {
+			return e3, nil
+		}
+		mock.InsertFormRevisionFunc = func(ctx context.Context, instanceID string, rev int, data []byte, editedBy string) error {
+			forms[rev] = data
+			return nil
+		}
+		mock.GetFormRevisionFunc = func(ctx context.Context, instanceID string, rev int) ([]byte, error) {
+			return forms[rev], nil
+		}
+		mock.UpdateNodeInstanceStateFunc = func(ctx context.Context, instanceID, oldState, newState string) error {
+			e3.State = newState
+			return nil
+		}
+		mock.InsertOutcomeFunc = func(ctx context.Context, instanceID, value, decidedBy, note string) error {
+			f.outcomes = append([]models.NodeOutcome{{OutcomeValue: value, DecidedBy: decidedBy}}, f.outcomes...)
+			return nil
+		}
+		mock.GetNodeOutcomesFunc = func(ctx context.Context, instanceID string) ([]models.NodeOutcome, error) {
+			return f.outcomes, nil
+		}
+		mock.CreateNodeInstanceFunc = func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error) {
+			f.activated[nodeID] = true
+			return "inst_" + nodeID, nil
+		}
+		f.svc = services.NewJourneyService(mock, pb, config.AppConfig{}, nil, nil, nil)
+		return f
+	}
+
+	t.Run("No remarks follows direct branch only", func(t *testing.T) {
+		f := setup()
+		err := f.svc.PutSubmission(context.Background(), "t1", "u1", "student", "E3", nil, "done", []byte(`{"remarks_exist": false}`))
+		assert.NoError(t, err)
+		assert.Len(t, f.outcomes, 1)
+		assert.Equal(t, "proceed_direct", f.outcomes[0].OutcomeValue)
+		assert.Equal(t, "u1", f.outcomes[0].DecidedBy)
+		assert.True(t, f.activated["D1"])
+		assert.False(t, f.activated["RP1"])
+	})
+
+	t.Run("Unresolved remarks keep node open", func(t *testing.T) {
+		f := setup()
+		err := f.svc.PutSubmission(context.Background(), "t1", "u1", "student", "E3", nil, "done", []byte(`{"remarks_exist": true}`))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "needs_resolution")
+		assert.Empty(t, f.outcomes)
+		assert.Empty(t, f.activated)
+	})
+
+	t.Run("No matching outcome", func(t *testing.T) {
+		f := setup()
+		err := f.svc.PutSubmission(context.Background(), "t1", "u1", "student", "E3", nil, "done", []byte(`{}`))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "outcome not determined")
+	})
+}
+
+func TestJourneyService_FieldValidation_Unit(t *testing.T) {
+	pb := &playbook.Manager{
+		Nodes: map[string]playbook.Node{
+			"VI2": {ID: "VI2", Requirements: &playbook.Requirements{Fields: []playbook.FieldRequirement{
+				{Key: "chk_print", Type: "boolean", Required: true},
+				{Key: "chk_letters", Type: "boolean"},
+			}}},
+		},
+	}
+
+	setup := func(form string) (*services.JourneyService, *MockJourneyRepository, *bool) {
+		mock := NewMockJourneyRepository()
+		inst := &models.NodeInstance{ID: "inst_vi2", NodeID: "VI2", State: "active", CurrentRev: 1}
+		mock.GetNodeInstanceFunc = func(ctx context.Context, userID, nodeID string) (*models.NodeInstance, error) {
+			if nodeID == "VI2" {
+				return inst, nil
+			}
+			return nil, nil
+		}
+		mock.GetFormRevisionFunc = func(ctx context.Context, instanceID string, rev int) ([]byte, error) {
+			return []byte(form), nil
+		}
+		updated := false
+		mock.UpdateNodeInstanceStateFunc = func(ctx context.Context, instanceID, oldState, newState string) error {
+			updated = true
+			return nil
+		}
+		return services.NewJourneyService(mock, pb, config.AppConfig{}, nil, nil, nil), mock, &updated
+	}
+
+	t.Run("PatchState rejects unticked required checkbox", func(t *testing.T) {
+		svc, _, updated := setup(`{"chk_letters": true}`)
+		err := svc.PatchState(context.Background(), "t1", "u1", "student", "VI2", "done")
+		var fieldErr *services.FieldValidationError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.Len(t, fieldErr.Fields, 1)
+		assert.Equal(t, "chk_print", fieldErr.Fields[0].Field)
+		assert.Equal(t, playbook.FieldErrRequired, fieldErr.Fields[0].Code)
+		assert.False(t, *updated)
+	})
+
+	t.Run("PatchState completes when checklist is ticked", func(t *testing.T) {
+		svc, _, updated := setup(`{"chk_print": true}`)
+		err := svc.PatchState(context.Background(), "t1", "u1", "student", "VI2", "done")
+		assert.NoError(t, err)
+		assert.True(t, *updated)
+	})
+
+	t.Run("PutSubmission rejects invalid values before saving", func(t *testing.T) {
+		svc, mock, _ := setup(`{}`)
+		saved := false
+		mock.InsertFormRevisionFunc = func(ctx context.Context, instanceID string, rev int, data []byte, editedBy string) error {
+			saved = true
+			return nil
+		}
+		err := svc.PutSubmission(context.Background(), "t1", "u1", "student", "VI2", nil, "", []byte(`{"chk_print": "yes"}`))
+		var fieldErr *services.FieldValidationError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, playbook.FieldErrType, fieldErr.Fields[0].Code)
+		assert.False(t, saved)
+
+		// Drafts may leave required fields empty
+		err = svc.PutSubmission(context.Background(), "t1", "u1", "student", "VI2", nil, "", []byte(`{"chk_letters": true}`))
+		assert.NoError(t, err)
+		assert.True(t, saved)
+	})
+}
+
+func TestJourneyService_WhoCanComplete_Unit(t *testing.T) {
+	pb := &playbook.Manager{
+		Nodes: map[string]playbook.Node{
+			"D3": {ID: "D3", WhoCanComplete: []string{"secretary", "chair"}},
+			"S1": {ID: "S1"},
+			"S2": {ID: "S2", WhoCanComplete: []string{"student"}},
+			"A1": {ID: "A1", WhoCanComplete: []string{"advisor"}},
+		},
+	}
+
+	type event struct {
+		eventType string
+		payload   map[string]any
+	}
+	setup := func(nodeID, state string) (*services.JourneyService, *[]event, *bool) {
+		mock := NewMockJourneyRepository()
+		mock.GetNodeInstanceFunc = func(ctx context.Context, userID, id string) (*models.NodeInstance, error) {
+			if id == nodeID {
+				return &models.NodeInstance{ID: "inst_" + nodeID, NodeID: nodeID, State: state}, nil
+			}
+			return nil, nil
+		}
+		var events []event
+		mock.LogNodeEventFunc = func(ctx context.Context, instanceID, eventType, actorID string, payload map[string]any) error {
+			events = append(events, event{eventType, payload})
+			return nil
+		}
+		updated := false
+		mock.UpdateNodeInstanceStateFunc = func(ctx context.Context, instanceID, oldState, newState string) error {
+			updated = true
+			return nil
+		}
+		return services.NewJourneyService(mock, pb, config.AppConfig{}, nil, nil, nil), &events, &updated
+	}
+
+	t.Run("Committee node rejects advisor despite transition table", func(t *testing.T) {
+		svc, events, updated := setup("D3", "submitted")
+		err := svc.PatchState(context.Background(), "t1", "u1", "advisor", "D3", "done")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot complete D3")
+		assert.False(t, *updated)
+		assert.Len(t, *events, 1)
+		assert.Equal(t, "transition_denied", (*events)[0].eventType)
+		assert.Equal(t, "advisor", (*events)[0].payload["role"])
+		assert.Equal(t, err.Error(), (*events)[0].payload["reason"])
+	})
+
+	t.Run("Committee node rejects student", func(t *testing.T) {
+		svc, _, updated := setup("D3", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "student", "D3", "done")
+		assert.Error(t, err)
+		assert.False(t, *updated)
+	})
+
+	t.Run("Secretary closes committee node", func(t *testing.T) {
+		svc, events, updated := setup("D3", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "secretary", "D3", "done")
+		assert.NoError(t, err)
+		assert.True(t, *updated)
+		assert.Equal(t, "state_changed", (*events)[0].eventType)
+	})
+
+	t.Run("Transition table still applies", func(t *testing.T) {
+		svc, events, updated := setup("S1", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "advisor", "S1", "done")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot transition from active to done")
+		assert.False(t, *updated)
+		assert.Equal(t, "transition_denied", (*events)[0].eventType)
+	})
+
+	t.Run("Admin approves a submitted student node", func(t *testing.T) {
+		svc, events, updated := setup("S2", "submitted")
+		err := svc.PatchState(context.Background(), "t1", "u1", "admin", "S2", "done")
+		assert.NoError(t, err)
+		assert.True(t, *updated)
+		assert.Equal(t, "state_changed", (*events)[0].eventType)
+	})
+
+	t.Run("Student list still keeps staff from closing an active node", func(t *testing.T) {
+		svc, _, updated := setup("S2", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "secretary", "S2", "done")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot complete S2")
+		assert.False(t, *updated)
+	})
+
+	t.Run("Nodes without the list keep student completion", func(t *testing.T) {
+		svc, _, updated := setup("S1", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "student", "S1", "done")
+		assert.NoError(t, err)
+		assert.True(t, *updated)
+	})
+
+	t.Run("Listed role still needs a transition table row", func(t *testing.T) {
+		svc, events, updated := setup("A1", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "advisor", "A1", "done")
+		assert.ErrorIs(t, err, services.ErrTransitionDenied)
+		assert.Contains(t, err.Error(), "cannot transition from active to done")
+		assert.False(t, *updated)
+		assert.Equal(t, "transition_denied", (*events)[0].eventType)
+	})
+
+	t.Run("Node missing from the playbook cannot be closed", func(t *testing.T) {
+		svc, events, updated := setup("X9", "active")
+		err := svc.PatchState(context.Background(), "t1", "u1", "student", "X9", "done")
+		assert.ErrorIs(t, err, services.ErrTransitionDenied)
+		assert.Contains(t, err.Error(), "X9 is not in the playbook")
+		assert.False(t, *updated)
+		assert.Equal(t, "transition_denied", (*events)[0].eventType)
+	})
+}
+
 func TestJourneyService_PatchState_Unit(t *testing.T) {
 	pb := &playbook.Manager{
 		Nodes: map[string]playbook.Node{
@@ -365,11 +636,113 @@ func TestJourneyService_ActivateNextNodes_Unit(t *testing.T) {
 	}
 
 	svc := services.NewJourneyService(mock, pb, config.AppConfig{}, nil, nil, nil)
+	deadlines := &deadlineSyncSpy{}
+	svc.SetDeadlines(deadlines)
 	
 	err := svc.ActivateNextNodes(context.Background(), "u1", "node1", "t1")
 	assert.NoError(t, err)
 	assert.True(t, activatedNodes["node2"])
 	assert.True(t, activatedNodes["node3"])
+	// Rule deadlines anchored on activation are stored right away
+	assert.Equal(t, []string{"t1/u1"}, deadlines.synced)
+}
+
+// deadlineSyncSpy records the students whose deadlines were synced.
+type deadlineSyncSpy struct {
+	synced []string
+}
+
+func (d *deadlineSyncSpy) Sync(ctx context.Context, tenantID, userID string) error {
+	d.synced = append(d.synced, tenantID+"/"+userID)
+	return nil
+}
+
+func TestJourneyService_ActivateNextNodes_Condition_Unit(t *testing.T) {
+	pb := &playbook.Manager{
+		Nodes: map[string]playbook.Node{
+			"E2":  {ID: "E2", Next: []string{"E3"}},
+			"E3":  {ID: "E3", Prerequisites: []string{"E2"}, Next: []string{"RP1", "D1"}, Condition: "rp_required"},
+			"RP1": {ID: "RP1", Prerequisites: []string{"E3"}, Condition: "rp_required", Conditional: true},
+			"D1":  {ID: "D1", Prerequisites: []string{"E3"}},
+		},
+		Conditions: map[string]playbook.Condition{
+			"rp_required": {ID: "rp_required", Expr: "profile.years_since_graduation > 3"},
+		},
+	}
+
+	run := func(completed, profile string) map[string]bool {
+		mock := NewMockJourneyRepository()
+		activated := make(map[string]bool)
+		mock.GetNodeInstanceFunc = func(ctx context.Context, userID, nodeID string) (*models.NodeInstance, error) {
+			switch nodeID {
+			case completed:
+				return &models.NodeInstance{State: "done"}, nil
+			case "S1_profile":
+				return &models.NodeInstance{ID: "profile", CurrentRev: 1}, nil
+			}
+			return nil, nil
+		}
+		mock.GetFormRevisionFunc = func(ctx context.Context, instanceID string, rev int) ([]byte, error) {
+			return []byte(profile), nil
+		}
+		mock.CreateNodeInstanceFunc = func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error) {
+			activated[nodeID] = true
+			return "inst_" + nodeID, nil
+		}
+		svc := services.NewJourneyService(mock, pb, config.AppConfig{}, nil, nil, nil)
+		assert.NoError(t, svc.ActivateNextNodes(context.Background(), "u1", completed, "t1"))
+		return activated
+	}
+
+	// E3's condition picks its branch; E3 itself applies to everyone
+	assert.True(t, run("E2", `{"years_since_graduation": 1}`)["E3"])
+
+	recent := run("E3", `{"years_since_graduation": 1}`)
+	assert.False(t, recent["RP1"])
+	assert.True(t, recent["D1"])
+
+	old := run("E3", `{"years_since_graduation": 5}`)
+	assert.True(t, old["RP1"])
+	assert.True(t, old["D1"])
+}
+
+func TestJourneyService_GetConditions_Unit(t *testing.T) {
+	pb := &playbook.Manager{
+		Nodes: map[string]playbook.Node{
+			"RP1": {ID: "RP1", Condition: "rp_required", Conditional: true},
+			"D1":  {ID: "D1"},
+		},
+		Conditions: map[string]playbook.Condition{
+			"rp_required": {ID: "rp_required", Expr: "profile.years_since_graduation > 3"},
+		},
+	}
+	mock := NewMockJourneyRepository()
+	svc := services.NewJourneyService(mock, pb, config.AppConfig{}, nil, nil, nil)
+
+	// No profile submitted yet: condition is false and RP1 is hidden
+	res, err := svc.GetConditions(context.Background(), "u1")
+	assert.NoError(t, err)
+	assert.False(t, res.Conditions["rp_required"])
+	assert.Equal(t, []string{"RP1"}, res.HiddenNodes)
+
+	// Hidden nodes cannot be opened directly
+	_, err = svc.EnsureNodeInstance(context.Background(), "t1", "u1", "RP1", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not applicable")
+
+	mock.GetNodeInstanceFunc = func(ctx context.Context, userID, nodeID string) (*models.NodeInstance, error) {
+		if nodeID == "S1_profile" {
+			return &models.NodeInstance{ID: "profile", CurrentRev: 2}, nil
+		}
+		return nil, nil
+	}
+	mock.GetFormRevisionFunc = func(ctx context.Context, instanceID string, rev int) ([]byte, error) {
+		return []byte(`{"graduation_date": "2001-09-01"}`), nil
+	}
+	res, err = svc.GetConditions(context.Background(), "u1")
+	assert.NoError(t, err)
+	assert.True(t, res.Conditions["rp_required"])
+	assert.Empty(t, res.HiddenNodes)
 }
 
 func TestJourneyService_PresignUpload_Unit(t *testing.T) {
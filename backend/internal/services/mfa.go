@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+const (
+	// MFAChallengeTTL is how long a sign-in waits for its second factor
+	MFAChallengeTTL = 5 * time.Minute
+	// RecoveryCodeCount is how many recovery codes a user gets at a time
+	RecoveryCodeCount = 10
+	totpIssuer        = "PhD Portal"
+	// totpSkew accepts the codes of the neighbouring time steps, for
+	// clock drift
+	totpSkew = 1
+)
+
+var (
+	ErrMFAInvalidCode    = errors.New("invalid verification code")
+	ErrMFAChallenge      = errors.New("sign-in verification expired, sign in again")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrMFARequired       = errors.New("two-factor authentication is required for your role")
+)
+
+// SecondFactor decides whether a password or SSO sign-in must be completed
+// with a second factor; MFAService implements it.
+type SecondFactor interface {
+	// Challenge returns nil when the user can sign in right away.
+	Challenge(ctx context.Context, user *models.User, role, tenantID string) (*MFAChallenge, error)
+}
+
+// MFAChallenge is a sign-in waiting for its second factor. Token proves
+// the password step; Enroll is set when the user must first set up an
+// authenticator because the tenant requires one.
+type MFAChallenge struct {
+	Token  string
+	Enroll bool
+}
+
+// TOTPEnrollment is a new secret for the user's authenticator app, which
+// is usually scanned from the otpauth URL as a QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+}
+
+// MFAStatus is the state of a user's second factor.
+type MFAStatus struct {
+	Enabled bool `json:"enabled"`
+	// Pending is an enrollment waiting for its first code
+	Pending           bool       `json:"pending"`
+	Required          bool       `json:"required"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// MFAService manages TOTP two-factor authentication: enrollment, recovery
+// codes, the second step of password and SSO sign-ins and the tenants'
+// policy.
+type MFAService struct {
+	repo repository.MFARepository
+	auth *AuthService
+	cfg  config.AppConfig
+}
+
+func NewMFAService(repo repository.MFARepository, authService *AuthService, cfg config.AppConfig) *MFAService {
+	return &MFAService{repo: repo, auth: authService, cfg: cfg}
+}
+
+// Required tells whether the role must use a second factor in the tenant.
+// Superadmins reach every tenant, so they always must; tenants decide for
+// their admins.
+func (s *MFAService) Required(ctx context.Context, role, tenantID string) (bool, error) {
+	if role == string(models.RoleSuperAdmin) {
+		return true, nil
+	}
+	if tenantID == "" || role != string(models.RoleAdmin) {
+		return false, nil
+	}
+	policy, err := s.repo.GetTenantPolicy(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return policy.RequireForAdmins, nil
+}
+
+// Challenge holds back the session of a sign-in when the user has 2FA
+// enabled, or must enroll because their role requires it.
+func (s *MFAService) Challenge(ctx context.Context, user *models.User, role, tenantID string) (*MFAChallenge, error) {
+	totp, err := s.repo.GetTOTP(ctx, user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	enroll := false
+	if !totp.Enabled() {
+		required, err := s.Required(ctx, role, tenantID)
+		if err != nil || !required {
+			return nil, err
+		}
+		enroll = true
+	}
+	token, err := auth.GenerateMFAToken(auth.MFAToken{
+		Sub:          user.ID,
+		Role:         role,
+		TenantID:     tenantID,
+		IsSuperadmin: user.Role == models.RoleSuperAdmin,
+		Enroll:       enroll,
+	}, []byte(s.cfg.JWTSecret), MFAChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{Token: token, Enroll: enroll}, nil
+}
+
+// ChallengeUser returns the user a challenge token was issued to.
+func (s *MFAService) ChallengeUser(token string) (string, error) {
+	t, err := s.parseChallenge(token)
+	if err != nil {
+		return "", err
+	}
+	return t.Sub, nil
+}
+
+// BeginChallengeEnrollment starts the enrollment a challenge demands.
+func (s *MFAService) BeginChallengeEnrollment(ctx context.Context, token string) (*TOTPEnrollment, error) {
+	t, err := s.parseChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Enroll {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return s.BeginEnrollment(ctx, t.Sub)
+}
+
+// CompleteLogin checks the second factor of a challenge and opens the
+// session. For an enrollment challenge the code confirms the new
+// authenticator, and the first recovery codes are returned too.
+func (s *MFAService) CompleteLogin(ctx context.Context, token, code string) (*LoginResponse, []string, error) {
+	t, err := s.parseChallenge(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.auth.repo.GetByID(ctx, t.Sub)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !user.IsActive) {
+		return nil, nil, ErrMFAChallenge
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var codes []string
+	if t.Enroll {
+		codes, err = s.ConfirmEnrollment(ctx, user.ID, code)
+		if errors.Is(err, ErrMFANotEnrolled) {
+			// Enrolled meanwhile, from another tab
+			err = s.verify(ctx, user.ID, code)
+		}
+	} else {
+		err = s.verify(ctx, user.ID, code)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.auth.StartSession(ctx, user.ID, t.Role, t.TenantID, t.IsSuperadmin)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("[MFAService.CompleteLogin] Second factor verified: userID=%s, tenantID=%s", user.ID, t.TenantID)
+	return resp, codes, nil
+}
+
+// Status describes the user's second factor; role and tenant are those of
+// the current session.
+func (s *MFAService) Status(ctx context.Context, userID, role, tenantID string) (*MFAStatus, error) {
+	required, err := s.Required(ctx, role, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatus{Required: required}
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled, status.Pending, status.EnabledAt = totp.Enabled(), !totp.Enabled(), totp.EnabledAt
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a secret for the user's authenticator. It only
+// takes effect once ConfirmEnrollment checks a code made from it.
+func (s *MFAService) BeginEnrollment(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	user, err := s.auth.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.SavePendingTOTP(ctx, user.ID, secret)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return &TOTPEnrollment{Secret: secret, URL: auth.TOTPURI(totpIssuer, account, secret)}, nil
+}
+
+// ConfirmEnrollment enables 2FA with the first code from the
+// authenticator and returns the user's recovery codes, shown only now.
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && totp.Enabled()) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrMFAInvalidCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.EnableTOTP(ctx, userID, step, hashes)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[MFAService] Two-factor authentication enabled: userID=%s", userID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, after
+// checking a current code.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns the user's 2FA off after checking a current code, unless
+// their role in the session's tenant requires it.
+func (s *MFAService) Disable(ctx context.Context, userID, code, role, tenantID string) error {
+	required, err := s.Required(ctx, role, tenantID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+	if err := s.verify(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("[MFAService] Two-factor authentication disabled: userID=%s", userID)
+	return nil
+}
+
+// Reset removes a user's second factor for a superadmin, when the user lost
+// their authenticator and recovery codes. If their role requires 2FA they
+// enroll again at the next sign-in.
+func (s *MFAService) Reset(ctx context.Context, userID string) error {
+	return s.repo.DeleteTOTP(ctx, userID)
+}
+
+func (s *MFAService) GetTenantPolicy(ctx context.Context, tenantID string) (*models.TenantMFAPolicy, error) {
+	return s.repo.GetTenantPolicy(ctx, tenantID)
+}
+
+func (s *MFAService) SaveTenantPolicy(ctx context.Context, tenantID string, policy *models.TenantMFAPolicy) error {
+	return s.repo.SaveTenantPolicy(ctx, tenantID, policy)
+}
+
+// verify accepts a code from the user's authenticator, once, or one of
+// their unused recovery codes.
+func (s *MFAService) verify(ctx context.Context, userID, code string) error {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !totp.Enabled()) {
+		return ErrMFANotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+	if step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now(), totpSkew); ok {
+		err := s.repo.UseTOTPStep(ctx, userID, step)
+		if errors.Is(err, repository.ErrNotFound) {
+			// Already used
+			return ErrMFAInvalidCode
+		}
+		return err
+	}
+	err = s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrMFAInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[MFAService] Recovery code used: userID=%s", userID)
+	return nil
+}
+
+func (s *MFAService) parseChallenge(token string) (*auth.MFAToken, error) {
+	t, err := auth.ParseMFAToken(token, []byte(s.cfg.JWTSecret))
+	if err != nil {
+		return nil, ErrMFAChallenge
+	}
+	return t, nil
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns fresh recovery codes, formatted like
+// "abcd-efgh", and the hashes they are stored by.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which users may type
+// differently.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return hashRefreshToken(code)
+}
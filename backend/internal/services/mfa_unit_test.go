@@ -0,0 +1,180 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// codeAt is the authenticator's code a number of steps from now.
+func codeAt(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+steps)
+	require.NoError(t, err)
+	return code
+}
+
+func TestMFAService(t *testing.T) {
+	users := NewHandwrittenMockUserRepository()
+	sessions := NewMemSessionRepository()
+	repo := NewMemMFARepository()
+	cfg := config.AppConfig{JWTSecret: "secret"}
+	authSvc := services.NewAuthService(users, sessions, nil, nil, cfg)
+	svc := services.NewMFAService(repo, authSvc, cfg)
+	authSvc.SetSecondFactor(svc)
+	ctx := context.Background()
+
+	pw, _ := auth.HashPassword("pass")
+	user := &models.User{ID: "u1", Username: "dean", Email: "dean@example.com", PasswordHash: pw, IsActive: true, Role: models.RoleAdmin}
+	users.GetByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) { return user, nil }
+	users.GetByIDFunc = func(ctx context.Context, id string) (*models.User, error) { return user, nil }
+	users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) { return "admin", nil }
+
+	t.Run("Without 2FA the session starts at once", func(t *testing.T) {
+		res, err := authSvc.Login(ctx, "dean", "pass", "t1")
+		require.NoError(t, err)
+		assert.Nil(t, res.MFA)
+		assert.NotEmpty(t, res.Token)
+	})
+
+	var secret string
+	var recovery []string
+	t.Run("Opt-in enrollment", func(t *testing.T) {
+		enrollment, err := svc.BeginEnrollment(ctx, "u1")
+		require.NoError(t, err)
+		secret = enrollment.Secret
+		assert.Contains(t, enrollment.URL, "otpauth://totp/PhD%20Portal:dean@example.com?")
+		assert.Contains(t, enrollment.URL, "secret="+secret)
+
+		status, err := svc.Status(ctx, "u1", "admin", "t1")
+		require.NoError(t, err)
+		assert.True(t, status.Pending)
+		assert.False(t, status.Enabled)
+
+		_, err = svc.ConfirmEnrollment(ctx, "u1", "000000")
+		assert.ErrorIs(t, err, services.ErrMFAInvalidCode)
+		recovery, err = svc.ConfirmEnrollment(ctx, "u1", codeAt(t, secret, -1))
+		require.NoError(t, err)
+		assert.Len(t, recovery, services.RecoveryCodeCount)
+
+		_, err = svc.BeginEnrollment(ctx, "u1")
+		assert.ErrorIs(t, err, services.ErrMFAAlreadyEnabled, "an enabled secret is not replaced")
+		status, err = svc.Status(ctx, "u1", "admin", "t1")
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, services.RecoveryCodeCount, status.RecoveryCodesLeft)
+	})
+
+	t.Run("Login asks for the code", func(t *testing.T) {
+		res, err := authSvc.Login(ctx, "dean", "pass", "t1")
+		require.NoError(t, err)
+		require.NotNil(t, res.MFA)
+		assert.False(t, res.MFA.Enroll)
+		assert.Empty(t, res.Token, "no session before the second factor")
+		before := len(sessions.sessions)
+
+		_, _, err = svc.CompleteLogin(ctx, res.MFA.Token, "000000")
+		assert.ErrorIs(t, err, services.ErrMFAInvalidCode)
+		_, _, err = svc.CompleteLogin(ctx, res.MFA.Token, codeAt(t, secret, -1))
+		assert.ErrorIs(t, err, services.ErrMFAInvalidCode, "the code confirming the enrollment is spent")
+
+		code := codeAt(t, secret, 0)
+		session, codes, err := svc.CompleteLogin(ctx, res.MFA.Token, code)
+		require.NoError(t, err)
+		assert.Nil(t, codes)
+		assert.Equal(t, "admin", session.Role)
+		assert.Len(t, sessions.sessions, before+1)
+		assert.Equal(t, "t1", *sessions.sessions[session.SessionID].TenantID)
+
+		_, _, err = svc.CompleteLogin(ctx, res.MFA.Token, code)
+		assert.ErrorIs(t, err, services.ErrMFAInvalidCode, "codes work once")
+		_, _, err = svc.CompleteLogin(ctx, "forged", code)
+		assert.ErrorIs(t, err, services.ErrMFAChallenge)
+	})
+
+	t.Run("Recovery codes work once", func(t *testing.T) {
+		res, err := authSvc.Login(ctx, "dean", "pass", "t1")
+		require.NoError(t, err)
+		_, _, err = svc.CompleteLogin(ctx, res.MFA.Token, strings.ToUpper(recovery[0]))
+		require.NoError(t, err)
+		_, _, err = svc.CompleteLogin(ctx, res.MFA.Token, recovery[0])
+		assert.ErrorIs(t, err, services.ErrMFAInvalidCode)
+
+		status, err := svc.Status(ctx, "u1", "admin", "t1")
+		require.NoError(t, err)
+		assert.Equal(t, services.RecoveryCodeCount-1, status.RecoveryCodesLeft)
+
+		fresh, err := svc.RegenerateRecoveryCodes(ctx, "u1", recovery[1])
+		require.NoError(t, err)
+		assert.NotContains(t, fresh, recovery[2])
+		_, _, err = svc.CompleteLogin(ctx, res.MFA.Token, recovery[2])
+		assert.ErrorIs(t, err, services.ErrMFAInvalidCode, "old codes are replaced")
+		recovery = fresh
+	})
+
+	t.Run("Tenant policy", func(t *testing.T) {
+		require.NoError(t, svc.SaveTenantPolicy(ctx, "t1", &models.TenantMFAPolicy{RequireForAdmins: true}))
+		assert.ErrorIs(t, svc.Disable(ctx, "u1", recovery[0], "admin", "t1"), services.ErrMFARequired)
+
+		required, err := svc.Required(ctx, "student", "t1")
+		require.NoError(t, err)
+		assert.False(t, required, "only admins")
+		required, err = svc.Required(ctx, "admin", "t2")
+		require.NoError(t, err)
+		assert.False(t, required, "only in the tenant requiring it")
+		for _, tenantID := range []string{"t2", ""} {
+			required, err = svc.Required(ctx, "superadmin", tenantID)
+			require.NoError(t, err)
+			assert.True(t, required, "superadmins always, with or without a tenant")
+		}
+	})
+
+	t.Run("Reset forces enrollment where required", func(t *testing.T) {
+		require.NoError(t, svc.Reset(ctx, "u1"))
+		assert.ErrorIs(t, svc.Reset(ctx, "u1"), repository.ErrNotFound)
+
+		res, err := authSvc.Login(ctx, "dean", "pass", "t1")
+		require.NoError(t, err)
+		require.NotNil(t, res.MFA)
+		assert.True(t, res.MFA.Enroll)
+
+		enrollment, err := svc.BeginChallengeEnrollment(ctx, res.MFA.Token)
+		require.NoError(t, err)
+		session, codes, err := svc.CompleteLogin(ctx, res.MFA.Token, codeAt(t, enrollment.Secret, 0))
+		require.NoError(t, err)
+		assert.NotEmpty(t, session.RefreshToken)
+		assert.Len(t, codes, services.RecoveryCodeCount)
+
+		// Recovery codes are stored by hash
+		for hash := range repo.codes["u1"] {
+			sum := sha256.Sum256([]byte(strings.ReplaceAll(codes[0], "-", "")))
+			if hash == hex.EncodeToString(sum[:]) {
+				return
+			}
+		}
+		t.Fatal("recovery code hash not found")
+	})
+
+	t.Run("Opt-in users can disable it", func(t *testing.T) {
+		res, err := authSvc.Login(ctx, "dean", "pass", "t2")
+		require.NoError(t, err)
+		require.NotNil(t, res.MFA, "enabled 2FA applies in every tenant")
+
+		assert.ErrorIs(t, svc.Disable(ctx, "u1", "000000", "admin", "t2"), services.ErrMFAInvalidCode)
+		require.NoError(t, svc.Disable(ctx, "u1", codeAt(t, repo.totp["u1"].Secret, 1), "admin", "t2"))
+		res, err = authSvc.Login(ctx, "dean", "pass", "t2")
+		require.NoError(t, err)
+		assert.Nil(t, res.MFA)
+	})
+}
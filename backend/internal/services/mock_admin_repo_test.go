@@ -18,7 +18,7 @@ type MockAdminRepository struct {
 	GetAdvisorsForStudentsFunc    func(ctx context.Context, studentIDs []string) (map[string][]models.AdvisorSummary, error)
 	GetDoneCountsForStudentsFunc  func(ctx context.Context, studentIDs []string) (map[string]int, error)
 	GetLastUpdatesForStudentsFunc func(ctx context.Context, studentIDs []string) (map[string]time.Time, error)
-	GetRPRequiredForStudentsFunc  func(ctx context.Context, studentIDs []string) (map[string]bool, error)
+	GetProfilesForStudentsFunc    func(ctx context.Context, studentIDs []string) (map[string]map[string]any, error)
 	GetStudentNodeInstancesFunc   func(ctx context.Context, studentID string) ([]models.NodeInstance, error)
 	GetAntiplagCountFunc          func(ctx context.Context, studentIDs []string, playbookVersionID string) (int, error)
 	GetW2DurationsFunc            func(ctx context.Context, studentIDs []string, playbookVersionID string, w2Nodes []string) ([]float64, error)
@@ -59,8 +59,8 @@ func (m *MockAdminRepository) GetDoneCountsForStudents(ctx context.Context, ids
 func (m *MockAdminRepository) GetLastUpdatesForStudents(ctx context.Context, ids []string) (map[string]time.Time, error) {
 	return m.GetLastUpdatesForStudentsFunc(ctx, ids)
 }
-func (m *MockAdminRepository) GetRPRequiredForStudents(ctx context.Context, ids []string) (map[string]bool, error) {
-	return m.GetRPRequiredForStudentsFunc(ctx, ids)
+func (m *MockAdminRepository) GetProfilesForStudents(ctx context.Context, ids []string) (map[string]map[string]any, error) {
+	return m.GetProfilesForStudentsFunc(ctx, ids)
 }
 func (m *MockAdminRepository) GetStudentNodeInstances(ctx context.Context, studentID string) ([]models.NodeInstance, error) {
 	return m.GetStudentNodeInstancesFunc(ctx, studentID)
@@ -106,7 +106,7 @@ func NewMockAdminRepository() *MockAdminRepository {
 		GetAdvisorsForStudentsFunc:    func(ctx context.Context, ids []string) (map[string][]models.AdvisorSummary, error) { return nil, nil },
 		GetDoneCountsForStudentsFunc:  func(ctx context.Context, ids []string) (map[string]int, error) { return nil, nil },
 		GetLastUpdatesForStudentsFunc: func(ctx context.Context, ids []string) (map[string]time.Time, error) { return nil, nil },
-		GetRPRequiredForStudentsFunc:  func(ctx context.Context, ids []string) (map[string]bool, error) { return nil, nil },
+		GetProfilesForStudentsFunc:    func(ctx context.Context, ids []string) (map[string]map[string]any, error) { return nil, nil },
 		GetStudentNodeInstancesFunc:   func(ctx context.Context, sid string) ([]models.NodeInstance, error) { return nil, nil },
 		GetLatestAttachmentStatusFunc: func(ctx context.Context, iid string) (string, error) { return "", nil },
 		GetAttachmentCountsFunc:       func(ctx context.Context, iid string) (int, int, int, error) { return 0, 0, 0, nil },
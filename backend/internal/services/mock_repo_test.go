@@ -2,7 +2,10 @@ package services_test
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
@@ -23,6 +26,9 @@ type MockJourneyRepository struct {
 	CreateNodeInstanceFunc        func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error)
 	UpdateNodeInstanceStateFunc   func(ctx context.Context, instanceID, oldState, newState string) error
 	GetAllowedTransitionRolesFunc func(ctx context.Context, fromState, toState string) ([]string, error)
+	ListVersionInstancesFunc      func(ctx context.Context, tenantID, versionID string) ([]models.NodeInstance, error)
+	RemapNodeInstanceFunc         func(ctx context.Context, instanceID, nodeID, versionID string) error
+	RenameJourneyStateNodeFunc    func(ctx context.Context, userID, tenantID, fromNodeID, toNodeID string) error
 	GetNodeInstanceSlotsFunc      func(ctx context.Context, instanceID string) ([]models.NodeInstanceSlot, error)
 	GetNodeInstanceAttachmentsFunc func(ctx context.Context, instanceID string) ([]models.NodeInstanceSlotAttachment, error)
 	GetFullSubmissionSlotsFunc    func(ctx context.Context, instanceID string) ([]models.SubmissionSlotDTO, error)
@@ -37,9 +43,22 @@ type MockJourneyRepository struct {
 	CreateAttachmentFunc          func(ctx context.Context, slotID, docVerID, status, filename, attachedBy string, sizeBytes int64) (string, error)
 	DeactivateSlotAttachmentsFunc func(ctx context.Context, slotID string) error
 	SyncProfileToUsersFunc        func(ctx context.Context, userID, tenantID string, fields map[string]interface{}) error
+	EnqueueOutboxFunc             func(ctx context.Context, msg *models.OutboxMessage) error
 	WithTxFunc                    func(ctx context.Context, fn func(repo repository.JourneyRepository) error) error
 }
 
+// defaultTransitionRoles mirrors the node_state_transitions rows the migrations seed.
+var defaultTransitionRoles = map[[2]string][]string{
+	{"active", "submitted"}:         {"student"},
+	{"active", "done"}:              {"student", "secretary", "chair", "admin"},
+	{"submitted", "needs_fixes"}:    {"advisor", "secretary", "chair", "admin"},
+	{"submitted", "done"}:           {"student", "advisor", "secretary", "chair", "admin"},
+	{"needs_fixes", "submitted"}:    {"student"},
+	{"done", "submitted"}:           {"admin"},
+	{"under_review", "needs_fixes"}: {"advisor", "secretary", "chair", "admin"},
+	{"under_review", "done"}:        {"advisor", "secretary", "chair", "admin"},
+}
+
 func NewMockJourneyRepository() *MockJourneyRepository {
 	return &MockJourneyRepository{
 		GetJourneyStateFunc:           func(ctx context.Context, userID, tenantID string) (map[string]string, error) { return nil, nil },
@@ -51,7 +70,10 @@ func NewMockJourneyRepository() *MockJourneyRepository {
 		GetNodeInstanceByIDFunc:       func(ctx context.Context, instanceID string) (*models.NodeInstance, error) { return nil, nil },
 		CreateNodeInstanceFunc:        func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error) { return "", nil },
 		UpdateNodeInstanceStateFunc:   func(ctx context.Context, instanceID, oldState, newState string) error { return nil },
-		GetAllowedTransitionRolesFunc: func(ctx context.Context, fromState, toState string) ([]string, error) { return nil, nil },
+		GetAllowedTransitionRolesFunc: func(ctx context.Context, fromState, toState string) ([]string, error) { return defaultTransitionRoles[[2]string{fromState, toState}], nil },
+		ListVersionInstancesFunc:      func(ctx context.Context, tenantID, versionID string) ([]models.NodeInstance, error) { return nil, nil },
+		RemapNodeInstanceFunc:         func(ctx context.Context, instanceID, nodeID, versionID string) error { return nil },
+		RenameJourneyStateNodeFunc:    func(ctx context.Context, userID, tenantID, fromNodeID, toNodeID string) error { return nil },
 		GetNodeInstanceSlotsFunc:      func(ctx context.Context, instanceID string) ([]models.NodeInstanceSlot, error) { return nil, nil },
 		GetNodeInstanceAttachmentsFunc: func(ctx context.Context, instanceID string) ([]models.NodeInstanceSlotAttachment, error) { return nil, nil },
 		GetFullSubmissionSlotsFunc:    func(ctx context.Context, instanceID string) ([]models.SubmissionSlotDTO, error) { return nil, nil },
@@ -141,6 +163,21 @@ func (m *MockJourneyRepository) DeactivateSlotAttachments(ctx context.Context, s
 func (m *MockJourneyRepository) SyncProfileToUsers(ctx context.Context, userID, tenantID string, fields map[string]interface{}) error {
 	return m.SyncProfileToUsersFunc(ctx, userID, tenantID, fields)
 }
+func (m *MockJourneyRepository) ListVersionInstances(ctx context.Context, tenantID, versionID string) ([]models.NodeInstance, error) {
+	return m.ListVersionInstancesFunc(ctx, tenantID, versionID)
+}
+func (m *MockJourneyRepository) RemapNodeInstance(ctx context.Context, instanceID, nodeID, versionID string) error {
+	return m.RemapNodeInstanceFunc(ctx, instanceID, nodeID, versionID)
+}
+func (m *MockJourneyRepository) RenameJourneyStateNode(ctx context.Context, userID, tenantID, fromNodeID, toNodeID string) error {
+	return m.RenameJourneyStateNodeFunc(ctx, userID, tenantID, fromNodeID, toNodeID)
+}
+func (m *MockJourneyRepository) EnqueueOutbox(ctx context.Context, msg *models.OutboxMessage) error {
+	if m.EnqueueOutboxFunc != nil {
+		return m.EnqueueOutboxFunc(ctx, msg)
+	}
+	return nil
+}
 func (m *MockJourneyRepository) WithTx(ctx context.Context, fn func(repository.JourneyRepository) error) error {
 	if m.WithTxFunc != nil {
 		return m.WithTxFunc(ctx, fn)
@@ -298,7 +335,7 @@ type HandwrittenMockAdminRepository struct {
 	GetAdvisorsForStudentsFunc    func(ctx context.Context, studentIDs []string) (map[string][]models.AdvisorSummary, error)
 	GetDoneCountsForStudentsFunc  func(ctx context.Context, studentIDs []string) (map[string]int, error)
 	GetLastUpdatesForStudentsFunc func(ctx context.Context, studentIDs []string) (map[string]time.Time, error)
-	GetRPRequiredForStudentsFunc  func(ctx context.Context, studentIDs []string) (map[string]bool, error)
+	GetProfilesForStudentsFunc    func(ctx context.Context, studentIDs []string) (map[string]map[string]any, error)
 	GetStudentNodeInstancesFunc   func(ctx context.Context, studentID string) ([]models.NodeInstance, error)
 	GetAntiplagCountFunc          func(ctx context.Context, studentIDs []string, playbookVersionID string) (int, error)
 	GetW2DurationsFunc            func(ctx context.Context, studentIDs []string, playbookVersionID string, w2Nodes []string) ([]float64, error)
@@ -345,8 +382,8 @@ func (m *HandwrittenMockAdminRepository) GetDoneCountsForStudents(ctx context.Co
 func (m *HandwrittenMockAdminRepository) GetLastUpdatesForStudents(ctx context.Context, s []string) (map[string]time.Time, error) {
 	return m.GetLastUpdatesForStudentsFunc(ctx, s)
 }
-func (m *HandwrittenMockAdminRepository) GetRPRequiredForStudents(ctx context.Context, s []string) (map[string]bool, error) {
-	return m.GetRPRequiredForStudentsFunc(ctx, s)
+func (m *HandwrittenMockAdminRepository) GetProfilesForStudents(ctx context.Context, s []string) (map[string]map[string]any, error) {
+	return m.GetProfilesForStudentsFunc(ctx, s)
 }
 func (m *HandwrittenMockAdminRepository) GetStudentNodeInstances(ctx context.Context, s string) ([]models.NodeInstance, error) {
 	return m.GetStudentNodeInstancesFunc(ctx, s)
@@ -427,7 +464,7 @@ func NewHandwrittenMockAdminRepository() *HandwrittenMockAdminRepository {
 		GetAdvisorsForStudentsFunc: func(ctx context.Context, s []string) (map[string][]models.AdvisorSummary, error) { return nil, nil },
 		GetDoneCountsForStudentsFunc: func(ctx context.Context, s []string) (map[string]int, error) { return nil, nil },
 		GetLastUpdatesForStudentsFunc: func(ctx context.Context, s []string) (map[string]time.Time, error) { return nil, nil },
-		GetRPRequiredForStudentsFunc: func(ctx context.Context, s []string) (map[string]bool, error) { return nil, nil },
+		GetProfilesForStudentsFunc: func(ctx context.Context, s []string) (map[string]map[string]any, error) { return nil, nil },
 		GetStudentNodeInstancesFunc: func(ctx context.Context, s string) ([]models.NodeInstance, error) { return nil, nil },
 		GetAntiplagCountFunc: func(ctx context.Context, s []string, p string) (int, error) { return 0, nil },
 		GetW2DurationsFunc: func(ctx context.Context, s []string, p string, w []string) ([]float64, error) { return nil, nil },
@@ -470,8 +507,44 @@ type MockChatRepository struct {
 	CreateMessageFunc           func(ctx context.Context, roomID, senderID, body string, attachments models.ChatAttachments, importance *string, meta json.RawMessage) (*models.ChatMessage, error)
 	ListMessagesFunc            func(ctx context.Context, roomID string, limit int, before, after *time.Time) ([]models.ChatMessage, error)
 	UpdateMessageFunc           func(ctx context.Context, msgID, userID, newBody string) (*models.ChatMessage, error)
-	DeleteMessageFunc           func(ctx context.Context, msgID, userID string) error
+	DeleteMessageFunc           func(ctx context.Context, msgID, userID string) (string, error)
 	MarkRoomAsReadFunc          func(ctx context.Context, roomID, userID string) error
+	GetMessageFunc              func(ctx context.Context, msgID string) (*models.ChatMessage, error)
+	GetMemberRoleFunc           func(ctx context.Context, roomID, userID string) (models.ChatRoomMemberRole, error)
+	CreateReplyFunc             func(ctx context.Context, parentID, senderID, body string, attachments models.ChatAttachments, meta json.RawMessage) (*models.ChatMessage, error)
+	ListRepliesFunc             func(ctx context.Context, parentID string, limit int, after *time.Time) ([]models.ChatMessage, error)
+	ListThreadsFunc             func(ctx context.Context, roomID, userID string) ([]models.ChatThread, error)
+	MarkThreadAsReadFunc        func(ctx context.Context, parentID, userID string) error
+	AddReactionFunc             func(ctx context.Context, msgID, userID, emoji string) (bool, error)
+	RemoveReactionFunc          func(ctx context.Context, msgID, userID, emoji string) (bool, error)
+	ListReactionsFunc           func(ctx context.Context, msgIDs []string) (map[string][]models.ChatReaction, error)
+	SetPinnedFunc               func(ctx context.Context, msgID string, pinnedBy *string) error
+	ListPinnedFunc              func(ctx context.Context, roomID string) ([]models.ChatMessage, error)
+	CreateFileFunc              func(ctx context.Context, file *models.ChatFile) error
+	GetFileFunc                 func(ctx context.Context, versionID string) (*models.ChatFile, error)
+	ListLocalFileMessagesFunc   func(ctx context.Context) ([]models.ChatMessage, error)
+	UpdateAttachmentsFunc       func(ctx context.Context, msgID string, attachments models.ChatAttachments) error
+	GetMemberFunc               func(ctx context.Context, roomID, userID string) (*models.ChatRoomMember, error)
+	SetMutedFunc                func(ctx context.Context, roomID, userID string, until *time.Time) error
+	BanMemberFunc               func(ctx context.Context, ban *models.ChatRoomBan) error
+	UnbanMemberFunc             func(ctx context.Context, roomID, userID string) (bool, error)
+	IsBannedFunc                func(ctx context.Context, roomID, userID string) (bool, error)
+	ListBansFunc                func(ctx context.Context, roomID string) ([]models.ChatRoomBan, error)
+	RemoveMessageFunc           func(ctx context.Context, msgID string) error
+	UpdateRoomSettingsFunc      func(ctx context.Context, roomID string, policy models.ChatPostPolicy, retentionDays *int, action models.ChatRetentionAction) (*models.ChatRoom, error)
+	CreateModerationEntryFunc   func(ctx context.Context, entry *models.ChatModerationEntry) error
+	ListModerationLogFunc       func(ctx context.Context, roomID string, limit int, before *time.Time) ([]models.ChatModerationEntry, error)
+	ListRetentionRoomsFunc      func(ctx context.Context) ([]models.ChatRoom, error)
+	PurgeExpiredMessagesFunc    func(ctx context.Context, roomID string, before time.Time, archive bool, limit int) (int, error)
+	ListCohortRoomSpecsFunc     func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	ListAdvisoryRoomSpecsFunc   func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	ListManagedRoomsFunc        func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	ListAdoptableRoomsFunc      func(ctx context.Context, tenantID string) ([]models.ChatManagedRoom, error)
+	AdoptManagedRoomFunc        func(ctx context.Context, roomID string, kind models.ChatManagedKind, ref string, memberIDs []string) error
+	CreateManagedRoomFunc       func(ctx context.Context, tenantID string, room models.ChatManagedRoom, ownerID string) (string, error)
+	AddManagedMemberFunc        func(ctx context.Context, tenantID, roomID, userID string, role models.ChatRoomMemberRole) error
+	RemoveManagedMemberFunc     func(ctx context.Context, roomID, userID string) (bool, error)
+	ListManagedRoomTenantsFunc  func(ctx context.Context) ([]string, error)
 	GetUsersByFiltersFunc       func(ctx context.Context, filters map[string]string) ([]string, error)
 	GetUsersByIDsFunc           func(ctx context.Context, ids []string) ([]models.UserInfo, error)
 }
@@ -512,12 +585,120 @@ func (m *MockChatRepository) ListMessages(ctx context.Context, r string, l int,
 func (m *MockChatRepository) UpdateMessage(ctx context.Context, mg, u, nb string) (*models.ChatMessage, error) {
 	return m.UpdateMessageFunc(ctx, mg, u, nb)
 }
-func (m *MockChatRepository) DeleteMessage(ctx context.Context, mg, u string) error {
+func (m *MockChatRepository) DeleteMessage(ctx context.Context, mg, u string) (string, error) {
 	return m.DeleteMessageFunc(ctx, mg, u)
 }
 func (m *MockChatRepository) MarkRoomAsRead(ctx context.Context, r, u string) error {
 	return m.MarkRoomAsReadFunc(ctx, r, u)
 }
+func (m *MockChatRepository) GetMessage(ctx context.Context, mg string) (*models.ChatMessage, error) {
+	return m.GetMessageFunc(ctx, mg)
+}
+func (m *MockChatRepository) GetMemberRole(ctx context.Context, r, u string) (models.ChatRoomMemberRole, error) {
+	return m.GetMemberRoleFunc(ctx, r, u)
+}
+func (m *MockChatRepository) CreateReply(ctx context.Context, p, s, b string, a models.ChatAttachments, mt json.RawMessage) (*models.ChatMessage, error) {
+	return m.CreateReplyFunc(ctx, p, s, b, a, mt)
+}
+func (m *MockChatRepository) ListReplies(ctx context.Context, p string, l int, a *time.Time) ([]models.ChatMessage, error) {
+	return m.ListRepliesFunc(ctx, p, l, a)
+}
+func (m *MockChatRepository) ListThreads(ctx context.Context, r, u string) ([]models.ChatThread, error) {
+	return m.ListThreadsFunc(ctx, r, u)
+}
+func (m *MockChatRepository) MarkThreadAsRead(ctx context.Context, p, u string) error {
+	return m.MarkThreadAsReadFunc(ctx, p, u)
+}
+func (m *MockChatRepository) AddReaction(ctx context.Context, mg, u, e string) (bool, error) {
+	return m.AddReactionFunc(ctx, mg, u, e)
+}
+func (m *MockChatRepository) RemoveReaction(ctx context.Context, mg, u, e string) (bool, error) {
+	return m.RemoveReactionFunc(ctx, mg, u, e)
+}
+func (m *MockChatRepository) ListReactions(ctx context.Context, ids []string) (map[string][]models.ChatReaction, error) {
+	return m.ListReactionsFunc(ctx, ids)
+}
+func (m *MockChatRepository) SetPinned(ctx context.Context, mg string, pb *string) error {
+	return m.SetPinnedFunc(ctx, mg, pb)
+}
+func (m *MockChatRepository) ListPinned(ctx context.Context, r string) ([]models.ChatMessage, error) {
+	return m.ListPinnedFunc(ctx, r)
+}
+func (m *MockChatRepository) CreateFile(ctx context.Context, f *models.ChatFile) error {
+	return m.CreateFileFunc(ctx, f)
+}
+func (m *MockChatRepository) GetFile(ctx context.Context, v string) (*models.ChatFile, error) {
+	return m.GetFileFunc(ctx, v)
+}
+func (m *MockChatRepository) ListLocalFileMessages(ctx context.Context) ([]models.ChatMessage, error) {
+	return m.ListLocalFileMessagesFunc(ctx)
+}
+func (m *MockChatRepository) UpdateAttachments(ctx context.Context, mg string, a models.ChatAttachments) error {
+	return m.UpdateAttachmentsFunc(ctx, mg, a)
+}
+func (m *MockChatRepository) GetMember(ctx context.Context, r, u string) (*models.ChatRoomMember, error) {
+	return m.GetMemberFunc(ctx, r, u)
+}
+func (m *MockChatRepository) SetMuted(ctx context.Context, r, u string, until *time.Time) error {
+	return m.SetMutedFunc(ctx, r, u, until)
+}
+func (m *MockChatRepository) BanMember(ctx context.Context, b *models.ChatRoomBan) error {
+	return m.BanMemberFunc(ctx, b)
+}
+func (m *MockChatRepository) UnbanMember(ctx context.Context, r, u string) (bool, error) {
+	return m.UnbanMemberFunc(ctx, r, u)
+}
+func (m *MockChatRepository) IsBanned(ctx context.Context, r, u string) (bool, error) {
+	return m.IsBannedFunc(ctx, r, u)
+}
+func (m *MockChatRepository) ListBans(ctx context.Context, r string) ([]models.ChatRoomBan, error) {
+	return m.ListBansFunc(ctx, r)
+}
+func (m *MockChatRepository) RemoveMessage(ctx context.Context, mg string) error {
+	return m.RemoveMessageFunc(ctx, mg)
+}
+func (m *MockChatRepository) UpdateRoomSettings(ctx context.Context, r string, p models.ChatPostPolicy, d *int, a models.ChatRetentionAction) (*models.ChatRoom, error) {
+	return m.UpdateRoomSettingsFunc(ctx, r, p, d, a)
+}
+func (m *MockChatRepository) CreateModerationEntry(ctx context.Context, e *models.ChatModerationEntry) error {
+	return m.CreateModerationEntryFunc(ctx, e)
+}
+func (m *MockChatRepository) ListModerationLog(ctx context.Context, r string, l int, b *time.Time) ([]models.ChatModerationEntry, error) {
+	return m.ListModerationLogFunc(ctx, r, l, b)
+}
+func (m *MockChatRepository) ListRetentionRooms(ctx context.Context) ([]models.ChatRoom, error) {
+	return m.ListRetentionRoomsFunc(ctx)
+}
+func (m *MockChatRepository) PurgeExpiredMessages(ctx context.Context, r string, b time.Time, a bool, l int) (int, error) {
+	return m.PurgeExpiredMessagesFunc(ctx, r, b, a, l)
+}
+func (m *MockChatRepository) ListCohortRoomSpecs(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+	return m.ListCohortRoomSpecsFunc(ctx, t)
+}
+func (m *MockChatRepository) ListAdvisoryRoomSpecs(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+	return m.ListAdvisoryRoomSpecsFunc(ctx, t)
+}
+func (m *MockChatRepository) ListManagedRooms(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+	return m.ListManagedRoomsFunc(ctx, t)
+}
+func (m *MockChatRepository) ListAdoptableRooms(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+	return m.ListAdoptableRoomsFunc(ctx, t)
+}
+func (m *MockChatRepository) AdoptManagedRoom(ctx context.Context, r string, k models.ChatManagedKind, ref string, ids []string) error {
+	return m.AdoptManagedRoomFunc(ctx, r, k, ref, ids)
+}
+func (m *MockChatRepository) CreateManagedRoom(ctx context.Context, t string, room models.ChatManagedRoom, o string) (string, error) {
+	return m.CreateManagedRoomFunc(ctx, t, room, o)
+}
+func (m *MockChatRepository) AddManagedMember(ctx context.Context, t, r, u string, role models.ChatRoomMemberRole) error {
+	return m.AddManagedMemberFunc(ctx, t, r, u, role)
+}
+func (m *MockChatRepository) RemoveManagedMember(ctx context.Context, r, u string) (bool, error) {
+	return m.RemoveManagedMemberFunc(ctx, r, u)
+}
+func (m *MockChatRepository) ListManagedRoomTenants(ctx context.Context) ([]string, error) {
+	return m.ListManagedRoomTenantsFunc(ctx)
+}
 func (m *MockChatRepository) GetUsersByFilters(ctx context.Context, f map[string]string) ([]string, error) {
 	return m.GetUsersByFiltersFunc(ctx, f)
 }
@@ -563,12 +744,120 @@ func NewMockChatRepository() *MockChatRepository {
 		UpdateMessageFunc: func(ctx context.Context, mg, u, nb string) (*models.ChatMessage, error) {
 			return &models.ChatMessage{}, nil
 		},
-		DeleteMessageFunc: func(ctx context.Context, mg, u string) error {
-			return nil
+		DeleteMessageFunc: func(ctx context.Context, mg, u string) (string, error) {
+			return "", nil
 		},
 		MarkRoomAsReadFunc: func(ctx context.Context, r, u string) error {
 			return nil
 		},
+		GetMessageFunc: func(ctx context.Context, mg string) (*models.ChatMessage, error) {
+			return &models.ChatMessage{ID: mg}, nil
+		},
+		GetMemberRoleFunc: func(ctx context.Context, r, u string) (models.ChatRoomMemberRole, error) {
+			return models.ChatRoomMemberRoleMember, nil
+		},
+		CreateReplyFunc: func(ctx context.Context, p, s, b string, a models.ChatAttachments, mt json.RawMessage) (*models.ChatMessage, error) {
+			return &models.ChatMessage{ParentID: &p}, nil
+		},
+		ListRepliesFunc: func(ctx context.Context, p string, l int, a *time.Time) ([]models.ChatMessage, error) {
+			return nil, nil
+		},
+		ListThreadsFunc: func(ctx context.Context, r, u string) ([]models.ChatThread, error) {
+			return nil, nil
+		},
+		MarkThreadAsReadFunc: func(ctx context.Context, p, u string) error {
+			return nil
+		},
+		AddReactionFunc: func(ctx context.Context, mg, u, e string) (bool, error) {
+			return true, nil
+		},
+		RemoveReactionFunc: func(ctx context.Context, mg, u, e string) (bool, error) {
+			return true, nil
+		},
+		ListReactionsFunc: func(ctx context.Context, ids []string) (map[string][]models.ChatReaction, error) {
+			return map[string][]models.ChatReaction{}, nil
+		},
+		SetPinnedFunc: func(ctx context.Context, mg string, pb *string) error {
+			return nil
+		},
+		ListPinnedFunc: func(ctx context.Context, r string) ([]models.ChatMessage, error) {
+			return nil, nil
+		},
+		CreateFileFunc: func(ctx context.Context, f *models.ChatFile) error {
+			return nil
+		},
+		GetFileFunc: func(ctx context.Context, v string) (*models.ChatFile, error) {
+			return nil, sql.ErrNoRows
+		},
+		ListLocalFileMessagesFunc: func(ctx context.Context) ([]models.ChatMessage, error) {
+			return nil, nil
+		},
+		UpdateAttachmentsFunc: func(ctx context.Context, mg string, a models.ChatAttachments) error {
+			return nil
+		},
+		GetMemberFunc: func(ctx context.Context, r, u string) (*models.ChatRoomMember, error) {
+			return &models.ChatRoomMember{RoomID: r, UserID: u, RoleInRoom: models.ChatRoomMemberRoleMember}, nil
+		},
+		SetMutedFunc: func(ctx context.Context, r, u string, until *time.Time) error {
+			return nil
+		},
+		BanMemberFunc: func(ctx context.Context, b *models.ChatRoomBan) error {
+			return nil
+		},
+		UnbanMemberFunc: func(ctx context.Context, r, u string) (bool, error) {
+			return true, nil
+		},
+		IsBannedFunc: func(ctx context.Context, r, u string) (bool, error) {
+			return false, nil
+		},
+		ListBansFunc: func(ctx context.Context, r string) ([]models.ChatRoomBan, error) {
+			return nil, nil
+		},
+		RemoveMessageFunc: func(ctx context.Context, mg string) error {
+			return nil
+		},
+		UpdateRoomSettingsFunc: func(ctx context.Context, r string, p models.ChatPostPolicy, d *int, a models.ChatRetentionAction) (*models.ChatRoom, error) {
+			return &models.ChatRoom{ID: r, PostPolicy: p, RetentionDays: d, RetentionAction: a}, nil
+		},
+		CreateModerationEntryFunc: func(ctx context.Context, e *models.ChatModerationEntry) error {
+			return nil
+		},
+		ListModerationLogFunc: func(ctx context.Context, r string, l int, b *time.Time) ([]models.ChatModerationEntry, error) {
+			return nil, nil
+		},
+		ListRetentionRoomsFunc: func(ctx context.Context) ([]models.ChatRoom, error) {
+			return nil, nil
+		},
+		PurgeExpiredMessagesFunc: func(ctx context.Context, r string, b time.Time, a bool, l int) (int, error) {
+			return 0, nil
+		},
+		ListCohortRoomSpecsFunc: func(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+			return nil, nil
+		},
+		ListAdvisoryRoomSpecsFunc: func(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+			return nil, nil
+		},
+		ListManagedRoomsFunc: func(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+			return nil, nil
+		},
+		ListAdoptableRoomsFunc: func(ctx context.Context, t string) ([]models.ChatManagedRoom, error) {
+			return nil, nil
+		},
+		AdoptManagedRoomFunc: func(ctx context.Context, r string, k models.ChatManagedKind, ref string, ids []string) error {
+			return nil
+		},
+		CreateManagedRoomFunc: func(ctx context.Context, t string, room models.ChatManagedRoom, o string) (string, error) {
+			return "", nil
+		},
+		AddManagedMemberFunc: func(ctx context.Context, t, r, u string, role models.ChatRoomMemberRole) error {
+			return nil
+		},
+		RemoveManagedMemberFunc: func(ctx context.Context, r, u string) (bool, error) {
+			return false, nil
+		},
+		ListManagedRoomTenantsFunc: func(ctx context.Context) ([]string, error) {
+			return nil, nil
+		},
 		GetUsersByFiltersFunc: func(ctx context.Context, f map[string]string) ([]string, error) {
 			return nil, nil
 		},
@@ -1106,6 +1395,7 @@ type MockNotificationRepository struct {
 
 	CreateFunc          func(ctx context.Context, notif *models.Notification) error
 	GetUnreadFunc       func(ctx context.Context, userID string) ([]models.Notification, error)
+	CountUnreadFunc     func(ctx context.Context, userID string) (int, error)
 	MarkAsReadFunc      func(ctx context.Context, id, userID string) error
 	MarkAllAsReadFunc   func(ctx context.Context, userID string) error
 	ListByRecipientFunc func(ctx context.Context, userID string, limit int) ([]models.Notification, error)
@@ -1117,6 +1407,9 @@ func (m *MockNotificationRepository) Create(ctx context.Context, n *models.Notif
 func (m *MockNotificationRepository) GetUnread(ctx context.Context, u string) ([]models.Notification, error) {
 	return m.GetUnreadFunc(ctx, u)
 }
+func (m *MockNotificationRepository) CountUnread(ctx context.Context, u string) (int, error) {
+	return m.CountUnreadFunc(ctx, u)
+}
 func (m *MockNotificationRepository) MarkAsRead(ctx context.Context, id, u string) error {
 	return m.MarkAsReadFunc(ctx, id, u)
 }
@@ -1131,9 +1424,675 @@ func NewMockNotificationRepository() *MockNotificationRepository {
 	return &MockNotificationRepository{
 		CreateFunc:          func(ctx context.Context, n *models.Notification) error { return nil },
 		GetUnreadFunc:       func(ctx context.Context, u string) ([]models.Notification, error) { return nil, nil },
+		CountUnreadFunc:     func(ctx context.Context, u string) (int, error) { return 0, nil },
 		MarkAsReadFunc:      func(ctx context.Context, id, u string) error { return nil },
 		MarkAllAsReadFunc:   func(ctx context.Context, u string) error { return nil },
 		ListByRecipientFunc: func(ctx context.Context, u string, l int) ([]models.Notification, error) { return nil, nil },
 	}
 }
 
+// MemAPIKeyRepository keeps API keys in memory.
+type MemAPIKeyRepository struct {
+	keys []*models.APIKey
+}
+
+func (m *MemAPIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	k.ID, k.CreatedAt = "key-"+k.Name, time.Now()
+	m.keys = append(m.keys, k)
+	return nil
+}
+
+func (m *MemAPIKeyRepository) List(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	out := []models.APIKey{}
+	for _, k := range m.keys {
+		if k.TenantID == tenantID && k.RevokedAt == nil {
+			out = append(out, *k)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemAPIKeyRepository) Revoke(ctx context.Context, tenantID, id string) error {
+	for _, k := range m.keys {
+		if k.ID == id && k.TenantID == tenantID && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// MemSessionRepository keeps sessions in memory.
+type MemSessionRepository struct {
+	sessions map[string]*models.UserSession
+}
+
+func NewMemSessionRepository() *MemSessionRepository {
+	return &MemSessionRepository{sessions: map[string]*models.UserSession{}}
+}
+
+func (m *MemSessionRepository) Create(ctx context.Context, s *models.UserSession) error {
+	s.ID = fmt.Sprintf("s%d", len(m.sessions)+1)
+	s.CreatedAt, s.LastUsedAt = time.Now(), time.Now()
+	c := *s
+	m.sessions[s.ID] = &c
+	return nil
+}
+
+func (m *MemSessionRepository) GetByTokenHash(ctx context.Context, hash string) (*models.UserSession, error) {
+	for _, s := range m.sessions {
+		if s.RefreshTokenHash == hash || (s.PreviousTokenHash != nil && *s.PreviousTokenHash == hash) {
+			c := *s
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *MemSessionRepository) Rotate(ctx context.Context, id, oldHash, newHash string) error {
+	s, ok := m.sessions[id]
+	if !ok || s.RefreshTokenHash != oldHash || s.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	s.PreviousTokenHash, s.RefreshTokenHash, s.RotatedAt, s.LastUsedAt = &oldHash, newHash, &now, now
+	return nil
+}
+
+func (m *MemSessionRepository) ListActive(ctx context.Context, userID string) ([]models.UserSession, error) {
+	var out []models.UserSession
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.Active(time.Now()) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemSessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	s.RevokedAt = &now
+	return nil
+}
+
+func (m *MemSessionRepository) RevokeAll(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			now := time.Now()
+			s.RevokedAt = &now
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids, nil
+}
+
+// MemDocuments keeps documents and versions created through DocumentService.
+type MemDocuments struct {
+	docs     map[string]*models.Document
+	versions map[string][]models.DocumentVersion
+}
+
+func NewMemDocuments() (*MemDocuments, *MockDocumentRepository) {
+	mem := &MemDocuments{docs: map[string]*models.Document{}, versions: map[string][]models.DocumentVersion{}}
+	repo := NewMockDocumentRepository()
+	repo.CreateFunc = func(ctx context.Context, doc *models.Document) (string, error) {
+		doc.ID = fmt.Sprintf("doc-%d", len(mem.docs)+1)
+		mem.docs[doc.ID] = doc
+		return doc.ID, nil
+	}
+	repo.GetByIDFunc = func(ctx context.Context, id string) (*models.Document, error) {
+		if doc, ok := mem.docs[id]; ok {
+			return doc, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+	repo.CreateVersionFunc = func(ctx context.Context, ver *models.DocumentVersion) (string, error) {
+		ver.ID = "ver-" + ver.DocumentID
+		mem.versions[ver.DocumentID] = append(mem.versions[ver.DocumentID], *ver)
+		return ver.ID, nil
+	}
+	repo.GetVersionsByDocumentIDFunc = func(ctx context.Context, docID string) ([]models.DocumentVersion, error) {
+		return mem.versions[docID], nil
+	}
+	return mem, repo
+}
+
+// MemDeadlineRepository is an in-memory DeadlineRepository for a single tenant.
+type MemDeadlineRepository struct {
+	rows      map[string]models.NodeDeadline // keyed by user|node
+	activated map[string]time.Time
+	cohort    *time.Time
+	students  []models.DeadlineStudent
+}
+
+func NewMemDeadlineRepository() *MemDeadlineRepository {
+	return &MemDeadlineRepository{rows: map[string]models.NodeDeadline{}, activated: map[string]time.Time{}}
+}
+
+func (m *MemDeadlineRepository) ListByUser(ctx context.Context, tenantID, userID string) ([]models.NodeDeadline, error) {
+	var out []models.NodeDeadline
+	for _, d := range m.rows {
+		if d.UserID == userID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemDeadlineRepository) UpsertRule(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time) error {
+	key := userID + "|" + nodeID
+	if cur, ok := m.rows[key]; ok && cur.Source == models.DeadlineSourceOverride {
+		return nil
+	}
+	m.rows[key] = models.NodeDeadline{UserID: userID, NodeID: nodeID, DueAt: dueAt, Source: models.DeadlineSourceRule}
+	return nil
+}
+
+func (m *MemDeadlineRepository) SetOverride(ctx context.Context, tenantID, userID, nodeID string, dueAt time.Time, note *string, actorID string) error {
+	m.rows[userID+"|"+nodeID] = models.NodeDeadline{UserID: userID, NodeID: nodeID, DueAt: dueAt, Note: note, CreatedBy: &actorID, Source: models.DeadlineSourceOverride}
+	return nil
+}
+
+func (m *MemDeadlineRepository) ClearOverride(ctx context.Context, tenantID, userID, nodeID string) (bool, error) {
+	key := userID + "|" + nodeID
+	if cur, ok := m.rows[key]; ok && cur.Source == models.DeadlineSourceOverride {
+		delete(m.rows, key)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (m *MemDeadlineRepository) ActivationTimes(ctx context.Context, tenantID, userID string) (map[string]time.Time, error) {
+	return m.activated, nil
+}
+
+func (m *MemDeadlineRepository) CohortStart(ctx context.Context, tenantID, userID string) (*time.Time, error) {
+	return m.cohort, nil
+}
+
+func (m *MemDeadlineRepository) ListStudents(ctx context.Context) ([]models.DeadlineStudent, error) {
+	return m.students, nil
+}
+
+// MemDocumentTextRepository hands out fixed pending versions and keeps what is saved.
+type MemDocumentTextRepository struct {
+	pending []models.PendingDocumentText
+	saved   map[string]*models.DocumentText
+	history []models.SlotVersion
+}
+
+func (m *MemDocumentTextRepository) ListPendingTexts(ctx context.Context, limit, maxAttempts int) ([]models.PendingDocumentText, error) {
+	return m.pending, nil
+}
+
+func (m *MemDocumentTextRepository) SaveText(ctx context.Context, text *models.DocumentText) error {
+	m.saved[text.VersionID] = text
+	return nil
+}
+
+func (m *MemDocumentTextRepository) GetText(ctx context.Context, versionID string) (*models.DocumentText, error) {
+	if text, ok := m.saved[versionID]; ok {
+		return text, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemDocumentTextRepository) ListSlotVersions(ctx context.Context, tenantID, studentID, nodeID, slotKey string) ([]models.SlotVersion, error) {
+	return m.history, nil
+}
+
+// MemMFARepository keeps TOTP secrets, recovery codes and tenant policies in
+// memory.
+type MemMFARepository struct {
+	totp     map[string]*models.UserTOTP
+	codes    map[string]map[string]bool // user -> hash -> used
+	policies map[string]models.TenantMFAPolicy
+}
+
+func NewMemMFARepository() *MemMFARepository {
+	return &MemMFARepository{totp: map[string]*models.UserTOTP{}, codes: map[string]map[string]bool{}, policies: map[string]models.TenantMFAPolicy{}}
+}
+
+func (m *MemMFARepository) GetTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	t, ok := m.totp[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (m *MemMFARepository) SavePendingTOTP(ctx context.Context, userID, secret string) error {
+	if t, ok := m.totp[userID]; ok && t.Enabled() {
+		return repository.ErrNotFound
+	}
+	m.totp[userID] = &models.UserTOTP{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *MemMFARepository) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	t, ok := m.totp[userID]
+	if !ok || t.Enabled() {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	t.EnabledAt, t.LastUsedStep = &now, step
+	return m.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+}
+
+func (m *MemMFARepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	t, ok := m.totp[userID]
+	if !ok || !t.Enabled() || t.LastUsedStep >= step {
+		return repository.ErrNotFound
+	}
+	t.LastUsedStep = step
+	return nil
+}
+
+func (m *MemMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	m.codes[userID] = map[string]bool{}
+	for _, h := range codeHashes {
+		m.codes[userID][h] = false
+	}
+	return nil
+}
+
+func (m *MemMFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	used, ok := m.codes[userID][codeHash]
+	if !ok || used {
+		return repository.ErrNotFound
+	}
+	m.codes[userID][codeHash] = true
+	return nil
+}
+
+func (m *MemMFARepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	n := 0
+	for _, used := range m.codes[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemMFARepository) DeleteTOTP(ctx context.Context, userID string) error {
+	if _, ok := m.totp[userID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.totp, userID)
+	delete(m.codes, userID)
+	return nil
+}
+
+func (m *MemMFARepository) GetTenantPolicy(ctx context.Context, tenantID string) (*models.TenantMFAPolicy, error) {
+	p := m.policies[tenantID]
+	return &p, nil
+}
+
+func (m *MemMFARepository) SaveTenantPolicy(ctx context.Context, tenantID string, policy *models.TenantMFAPolicy) error {
+	m.policies[tenantID] = *policy
+	return nil
+}
+
+// MemNotificationEventRepository is an in-memory notification event log.
+type MemNotificationEventRepository struct {
+	events []models.NotificationEvent
+}
+
+func (m *MemNotificationEventRepository) Append(ctx context.Context, stream, eventType string, payload json.RawMessage) (*models.NotificationEvent, error) {
+	ev := models.NotificationEvent{ID: int64(len(m.events) + 1), Stream: stream, Type: eventType, Payload: payload, CreatedAt: time.Now()}
+	m.events = append(m.events, ev)
+	return &ev, nil
+}
+
+func (m *MemNotificationEventRepository) ListSince(ctx context.Context, streams []string, afterID int64, limit int) ([]models.NotificationEvent, error) {
+	var out []models.NotificationEvent
+	for _, ev := range m.events {
+		for _, s := range streams {
+			if ev.Stream == s && ev.ID > afterID && len(out) < limit {
+				out = append(out, ev)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *MemNotificationEventRepository) ListRecent(ctx context.Context, streams []string, window time.Duration, maxID int64, limit int) ([]models.NotificationEvent, error) {
+	var out []models.NotificationEvent
+	for _, ev := range m.events {
+		for _, s := range streams {
+			if ev.Stream == s && ev.ID <= maxID && time.Since(ev.CreatedAt) <= window && len(out) < limit {
+				out = append(out, ev)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *MemNotificationEventRepository) Bounds(ctx context.Context) (int64, int64, error) {
+	if len(m.events) == 0 {
+		return 0, 0, nil
+	}
+	return m.events[0].ID, m.events[len(m.events)-1].ID, nil
+}
+
+func (m *MemNotificationEventRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// MemOIDCRepository keeps provider configurations and pending logins in memory.
+type MemOIDCRepository struct {
+	configs map[string]*models.TenantOIDCConfig
+	states  map[string]*models.OIDCLoginState
+}
+
+func NewMemOIDCRepository() *MemOIDCRepository {
+	return &MemOIDCRepository{configs: map[string]*models.TenantOIDCConfig{}, states: map[string]*models.OIDCLoginState{}}
+}
+
+func (m *MemOIDCRepository) GetConfig(ctx context.Context, tenantID string) (*models.TenantOIDCConfig, error) {
+	cfg, ok := m.configs[tenantID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *cfg
+	return &c, nil
+}
+
+func (m *MemOIDCRepository) SaveConfig(ctx context.Context, cfg *models.TenantOIDCConfig) error {
+	if old, ok := m.configs[cfg.TenantID]; ok && cfg.ClientSecret == "" {
+		cfg.ClientSecret = old.ClientSecret
+	}
+	c := *cfg
+	m.configs[cfg.TenantID] = &c
+	return nil
+}
+
+func (m *MemOIDCRepository) DeleteConfig(ctx context.Context, tenantID string) error {
+	if _, ok := m.configs[tenantID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.configs, tenantID)
+	return nil
+}
+
+func (m *MemOIDCRepository) CreateState(ctx context.Context, st *models.OIDCLoginState) error {
+	m.states[st.State] = st
+	return nil
+}
+
+func (m *MemOIDCRepository) ConsumeState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	st, ok := m.states[state]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(m.states, state)
+	return st, nil
+}
+
+// MemOutboxRepository is an in-memory OutboxRepository for the admin service.
+type MemOutboxRepository struct {
+	repository.OutboxRepository
+	msgs map[string]*models.OutboxMessage
+}
+
+func (m *MemOutboxRepository) Get(ctx context.Context, tenantID, id string) (*models.OutboxMessage, error) {
+	msg, ok := m.msgs[id]
+	if !ok || msg.TenantID == nil || *msg.TenantID != tenantID {
+		return nil, nil
+	}
+	cp := *msg
+	return &cp, nil
+}
+
+func (m *MemOutboxRepository) Replay(ctx context.Context, tenantID, id string) (bool, error) {
+	msg, ok := m.msgs[id]
+	if !ok || msg.Status != models.OutboxStatusDead {
+		return false, nil
+	}
+	msg.Status, msg.Attempts, msg.NextAttemptAt = models.OutboxStatusPending, 0, time.Now()
+	return true, nil
+}
+
+func (m *MemOutboxRepository) List(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error) {
+	return nil, nil
+}
+
+// MemPlaybookRepository is an in-memory PlaybookRepository.
+type MemPlaybookRepository struct {
+	versions    map[string]*models.PlaybookVersion
+	active      map[string]string
+	activations []models.PlaybookActivation
+	nextID      int
+}
+
+func NewMemPlaybookRepository() *MemPlaybookRepository {
+	return &MemPlaybookRepository{versions: map[string]*models.PlaybookVersion{}, active: map[string]string{}}
+}
+
+func (m *MemPlaybookRepository) ListVersions(ctx context.Context, tenantID string) ([]models.PlaybookVersion, error) {
+	var out []models.PlaybookVersion
+	for _, v := range m.versions {
+		if v.TenantID == tenantID {
+			cp := *v
+			cp.IsActive = m.active[tenantID] == v.ID
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemPlaybookRepository) GetVersion(ctx context.Context, versionID string) (*models.PlaybookVersion, error) {
+	v, ok := m.versions[versionID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *v
+	return &cp, nil
+}
+
+func (m *MemPlaybookRepository) GetVersionJSON(ctx context.Context, versionID string) ([]byte, error) {
+	v, ok := m.versions[versionID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return v.RawJSON, nil
+}
+
+func (m *MemPlaybookRepository) GetVersionByChecksum(ctx context.Context, tenantID, checksum string) (*models.PlaybookVersion, error) {
+	for _, v := range m.versions {
+		if v.TenantID == tenantID && v.Checksum == checksum {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MemPlaybookRepository) CreateVersion(ctx context.Context, v *models.PlaybookVersion) (string, error) {
+	m.nextID++
+	id := fmt.Sprintf("version-%d", m.nextID)
+	cp := *v
+	cp.ID = id
+	m.versions[id] = &cp
+	return id, nil
+}
+
+func (m *MemPlaybookRepository) GetActiveVersionID(ctx context.Context, tenantID string) (string, error) {
+	id, ok := m.active[tenantID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return id, nil
+}
+
+func (m *MemPlaybookRepository) ListActivations(ctx context.Context, tenantID string) ([]models.PlaybookActivation, error) {
+	return m.activations, nil
+}
+
+func (m *MemPlaybookRepository) ActivateVersion(ctx context.Context, tenantID, versionID, actorID string) error {
+	m.active[tenantID] = versionID
+	m.activations = append(m.activations, models.PlaybookActivation{TenantID: tenantID, PlaybookVersionID: versionID})
+	return nil
+}
+
+func (m *MemPlaybookRepository) RollbackActivation(ctx context.Context, tenantID string) (string, error) {
+	var live []int
+	for i, a := range m.activations {
+		if a.TenantID == tenantID && a.RolledBackAt == nil {
+			live = append(live, i)
+		}
+	}
+	if len(live) < 2 {
+		return "", sql.ErrNoRows
+	}
+	cur, prev := live[len(live)-1], live[len(live)-2]
+	now := m.activations[cur].ActivatedAt
+	m.activations[cur].RolledBackAt = &now
+	m.active[tenantID] = m.activations[prev].PlaybookVersionID
+	return m.active[tenantID], nil
+}
+
+// MemSAMLRepository keeps IdP configurations and pending logins in memory.
+type MemSAMLRepository struct {
+	configs  map[string]*models.TenantSAMLConfig
+	requests map[string]*models.SAMLLoginRequest
+}
+
+func NewMemSAMLRepository() *MemSAMLRepository {
+	return &MemSAMLRepository{configs: map[string]*models.TenantSAMLConfig{}, requests: map[string]*models.SAMLLoginRequest{}}
+}
+
+func (m *MemSAMLRepository) GetConfig(ctx context.Context, tenantID string) (*models.TenantSAMLConfig, error) {
+	cfg, ok := m.configs[tenantID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *cfg
+	return &c, nil
+}
+
+func (m *MemSAMLRepository) SaveConfig(ctx context.Context, tenantID string, cfg *models.TenantSAMLConfig) error {
+	c := *cfg
+	m.configs[tenantID] = &c
+	return nil
+}
+
+func (m *MemSAMLRepository) DeleteConfig(ctx context.Context, tenantID string) error {
+	if _, ok := m.configs[tenantID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.configs, tenantID)
+	return nil
+}
+
+func (m *MemSAMLRepository) CreateRequest(ctx context.Context, req *models.SAMLLoginRequest) error {
+	m.requests[req.ID] = req
+	return nil
+}
+
+func (m *MemSAMLRepository) ConsumeRequest(ctx context.Context, id string) (*models.SAMLLoginRequest, error) {
+	req, ok := m.requests[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(m.requests, id)
+	return req, nil
+}
+
+// TypedSearchRepository returns one result of each type and records the pages asked for.
+type TypedSearchRepository struct {
+	pages []int
+}
+
+func (r *TypedSearchRepository) result(kind string, limit, offset int) ([]models.SearchResult, int, error) {
+	r.pages = append(r.pages, limit, offset)
+	return []models.SearchResult{{Type: kind, ID: kind + "-1"}}, 7, nil
+}
+
+func (r *TypedSearchRepository) SearchUsers(ctx context.Context, scope repository.SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	return r.result("student", limit, offset)
+}
+
+func (r *TypedSearchRepository) SearchDocuments(ctx context.Context, scope repository.SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	return r.result("document", limit, offset)
+}
+
+func (r *TypedSearchRepository) SearchMessages(ctx context.Context, scope repository.SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	return r.result("message", limit, offset)
+}
+
+func (r *TypedSearchRepository) SearchEvents(ctx context.Context, scope repository.SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	return r.result("event", limit, offset)
+}
+
+func (r *TypedSearchRepository) SearchContacts(ctx context.Context, scope repository.SearchScope, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	return r.result("contact", limit, offset)
+}
+
+// MemSimilarityRepository keeps dissertation versions, texts and reports in memory.
+type MemSimilarityRepository struct {
+	versions []models.SimilarityCandidate
+	texts    map[string]string
+	reports  map[string]*models.SimilarityReport
+}
+
+func (m *MemSimilarityRepository) ListUnfingerprinted(ctx context.Context, slotKeys []string, limit int) ([]models.DocumentText, error) {
+	var out []models.DocumentText
+	for _, v := range m.versions {
+		if v.Minhash == nil {
+			out = append(out, models.DocumentText{VersionID: v.VersionID, Content: m.texts[v.VersionID]})
+		}
+	}
+	return out, nil
+}
+
+func (m *MemSimilarityRepository) SaveFingerprint(ctx context.Context, versionID string, minhash []byte, shingles int) error {
+	for i := range m.versions {
+		if m.versions[i].VersionID == versionID {
+			m.versions[i].Minhash, m.versions[i].ShingleCount = minhash, shingles
+		}
+	}
+	return nil
+}
+
+func (m *MemSimilarityRepository) ListPendingReports(ctx context.Context, slotKeys []string, limit int) ([]models.SimilarityCandidate, error) {
+	var out []models.SimilarityCandidate
+	for _, v := range m.versions {
+		if m.reports[v.VersionID] == nil && v.Minhash != nil {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (m *MemSimilarityRepository) ListEarlierVersions(ctx context.Context, v models.SimilarityCandidate, slotKeys []string) ([]models.SimilarityCandidate, error) {
+	var out []models.SimilarityCandidate
+	for _, e := range m.versions {
+		if e.CreatedAt.Before(v.CreatedAt) && e.Minhash != nil {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemSimilarityRepository) GetText(ctx context.Context, versionID string) (string, error) {
+	return m.texts[versionID], nil
+}
+
+func (m *MemSimilarityRepository) SaveReport(ctx context.Context, report *models.SimilarityReport) error {
+	report.ID = "rep-" + report.VersionID
+	m.reports[report.VersionID] = report
+	return nil
+}
+
+func (m *MemSimilarityRepository) ListNodeReports(ctx context.Context, tenantID, studentID, nodeID string) ([]models.SimilarityReport, error) {
+	return nil, nil
+}
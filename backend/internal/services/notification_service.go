@@ -2,35 +2,91 @@ package services
 
 import (
 	"context"
+	"log"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
 )
 
 type NotificationService struct {
-	repo repository.NotificationRepository
+	repo   repository.NotificationRepository
+	stream *NotificationStream
 }
 
 func NewNotificationService(repo repository.NotificationRepository) *NotificationService {
 	return &NotificationService{repo: repo}
 }
 
+// SetStream enables live notification events. Without a stream clients
+// keep polling the unread endpoints.
+func (s *NotificationService) SetStream(stream *NotificationStream) {
+	s.stream = stream
+}
+
 func (s *NotificationService) CreateNotification(ctx context.Context, notif *models.Notification) error {
-	return s.repo.Create(ctx, notif)
+	if err := s.repo.Create(ctx, notif); err != nil {
+		return err
+	}
+	s.NotifyCreated(ctx, notif)
+	return nil
+}
+
+// NotifyCreated announces a notification stored outside this service.
+func (s *NotificationService) NotifyCreated(ctx context.Context, notif *models.Notification) {
+	s.emit(ctx, notif.RecipientID, models.NotificationEventCreated, models.NotificationEventPayload{Notification: notif})
 }
 
 func (s *NotificationService) GetUnreadNotifications(ctx context.Context, userID string) ([]models.Notification, error) {
 	return s.repo.GetUnread(ctx, userID)
 }
 
+func (s *NotificationService) UnreadCount(ctx context.Context, userID string) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
 func (s *NotificationService) MarkAsRead(ctx context.Context, notificationID, userID string) error {
-	return s.repo.MarkAsRead(ctx, notificationID, userID)
+	if err := s.repo.MarkAsRead(ctx, notificationID, userID); err != nil {
+		return err
+	}
+	s.emit(ctx, userID, models.NotificationEventRead, models.NotificationEventPayload{ID: notificationID})
+	return nil
 }
 
 func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID string) error {
-	return s.repo.MarkAllAsRead(ctx, userID)
+	if err := s.repo.MarkAllAsRead(ctx, userID); err != nil {
+		return err
+	}
+	s.emit(ctx, userID, models.NotificationEventReadAll, models.NotificationEventPayload{})
+	return nil
 }
 
 func (s *NotificationService) ListNotifications(ctx context.Context, userID string, limit int) ([]models.Notification, error) {
 	return s.repo.ListByRecipient(ctx, userID, limit)
 }
+
+// PublishAdminEvent emits an event on the shared admin notifications stream.
+func (s *NotificationService) PublishAdminEvent(ctx context.Context, eventType string, payload models.NotificationEventPayload) {
+	if s.stream == nil {
+		return
+	}
+	if _, err := s.stream.Emit(ctx, AdminNotificationStream, eventType, payload); err != nil {
+		log.Printf("[Notifications] Failed to emit %s: %v", eventType, err)
+	}
+}
+
+// emit stamps the user's unread count on the payload and appends it to the
+// user's stream. The change itself is already stored, so failures are logged.
+func (s *NotificationService) emit(ctx context.Context, userID, eventType string, payload models.NotificationEventPayload) {
+	if s.stream == nil {
+		return
+	}
+	count, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		log.Printf("[Notifications] Failed to count unread for %s: %v", userID, err)
+		return
+	}
+	payload.UnreadCount = count
+	if _, err := s.stream.Emit(ctx, UserNotificationStream(userID), eventType, payload); err != nil {
+		log.Printf("[Notifications] Failed to emit %s: %v", eventType, err)
+	}
+}
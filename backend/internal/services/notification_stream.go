@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// AdminNotificationStream carries admin_notifications events. They are not
+// addressed to a single user, so every admin and advisor follows it.
+const AdminNotificationStream = "admin"
+
+// notificationChannelPrefix namespaces the per-stream Redis channels.
+const notificationChannelPrefix = "notify:"
+
+// UserNotificationStream names the stream of a user's own notifications.
+func UserNotificationStream(userID string) string {
+	return "user:" + userID
+}
+
+// NotificationStream persists notification events and wakes up the live
+// connections following them. Events are stored first so a client that
+// reconnects with Last-Event-ID can replay what it missed.
+type NotificationStream struct {
+	repo repository.NotificationEventRepository
+	hub  *eventHub[models.NotificationEvent]
+}
+
+func NewNotificationStream(repo repository.NotificationEventRepository, rds *redis.Client) *NotificationStream {
+	return &NotificationStream{
+		repo: repo,
+		hub:  newEventHub[models.NotificationEvent](rds, notificationChannelPrefix, "NotificationStream"),
+	}
+}
+
+// Emit appends an event to a stream and notifies its subscribers.
+func (s *NotificationStream) Emit(ctx context.Context, stream, eventType string, payload any) (*models.NotificationEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s payload: %w", eventType, err)
+	}
+	ev, err := s.repo.Append(ctx, stream, eventType, data)
+	if err != nil {
+		return nil, fmt.Errorf("append %s event: %w", eventType, err)
+	}
+	if err := s.hub.publish(ctx, stream, *ev); err != nil {
+		// Stored already; subscribers pick it up on their next poll
+		log.Printf("[NotificationStream] Failed to publish event %d: %v", ev.ID, err)
+	}
+	return ev, nil
+}
+
+// Subscribe follows the given streams. Received events only signal that
+// something changed; read the log with Since to deliver them in order.
+func (s *NotificationStream) Subscribe(streams ...string) *Subscription[models.NotificationEvent] {
+	return s.hub.subscribe(streams...)
+}
+
+// Subscribers reports how many local connections follow a stream.
+func (s *NotificationStream) Subscribers(stream string) int {
+	return s.hub.subscribers(stream)
+}
+
+// Since returns events of the streams after the given id, oldest first.
+func (s *NotificationStream) Since(ctx context.Context, streams []string, afterID int64, limit int) ([]models.NotificationEvent, error) {
+	return s.repo.ListSince(ctx, streams, afterID, limit)
+}
+
+// Recent returns events of the streams up to maxID that were stored within
+// the last window, oldest first. An id is taken before its insert commits, so
+// a reader that has moved past it can still find it here.
+func (s *NotificationStream) Recent(ctx context.Context, streams []string, window time.Duration, maxID int64, limit int) ([]models.NotificationEvent, error) {
+	return s.repo.ListRecent(ctx, streams, window, maxID, limit)
+}
+
+// Bounds returns the oldest and newest retained event ids.
+func (s *NotificationStream) Bounds(ctx context.Context) (oldest, latest int64, err error) {
+	return s.repo.Bounds(ctx)
+}
+
+// Prune drops events older than the cutoff; clients resuming from before
+// it are told to resync.
+func (s *NotificationStream) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.Prune(ctx, before)
+}
+
+// Run relays events emitted on other replicas until ctx is cancelled.
+func (s *NotificationStream) Run(ctx context.Context) {
+	s.hub.run(ctx)
+}
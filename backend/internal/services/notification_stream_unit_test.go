@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeNotificationPayload(t *testing.T, ev models.NotificationEvent) models.NotificationEventPayload {
+	t.Helper()
+	var p models.NotificationEventPayload
+	require.NoError(t, json.Unmarshal(ev.Payload, &p))
+	return p
+}
+
+func TestNotificationService_EmitsStreamEvents(t *testing.T) {
+	ctx := context.Background()
+	unread := 0
+	repo := NewMockNotificationRepository()
+	repo.CreateFunc = func(ctx context.Context, n *models.Notification) error {
+		n.ID = "n1"
+		unread++
+		return nil
+	}
+	repo.MarkAsReadFunc = func(ctx context.Context, id, u string) error {
+		unread--
+		return nil
+	}
+	repo.MarkAllAsReadFunc = func(ctx context.Context, u string) error {
+		unread = 0
+		return nil
+	}
+	repo.CountUnreadFunc = func(ctx context.Context, u string) (int, error) { return unread, nil }
+
+	events := &MemNotificationEventRepository{}
+	stream := services.NewNotificationStream(events, nil)
+	svc := services.NewNotificationService(repo)
+
+	require.NoError(t, svc.CreateNotification(ctx, &models.Notification{RecipientID: "u1", Title: "before stream"}))
+	assert.Empty(t, events.events, "no stream, no events")
+
+	svc.SetStream(stream)
+	sub := stream.Subscribe(services.UserNotificationStream("u1"))
+	defer sub.Close()
+
+	require.NoError(t, svc.CreateNotification(ctx, &models.Notification{RecipientID: "u1", Title: "Hello"}))
+	require.NoError(t, svc.MarkAsRead(ctx, "n1", "u1"))
+	require.NoError(t, svc.MarkAllAsRead(ctx, "u1"))
+
+	require.Len(t, events.events, 3)
+	for _, ev := range events.events {
+		assert.Equal(t, "user:u1", ev.Stream)
+	}
+	created := decodeNotificationPayload(t, events.events[0])
+	assert.Equal(t, models.NotificationEventCreated, events.events[0].Type)
+	assert.Equal(t, "Hello", created.Notification.Title)
+	assert.Equal(t, 2, created.UnreadCount)
+
+	read := decodeNotificationPayload(t, events.events[1])
+	assert.Equal(t, "n1", read.ID)
+	assert.Equal(t, 1, read.UnreadCount)
+	assert.Equal(t, models.NotificationEventReadAll, events.events[2].Type)
+
+	assert.Len(t, sub.C, 3, "live subscribers are woken for each event")
+}
+
+func TestAdminService_EmitsAdminNotificationEvents(t *testing.T) {
+	ctx := context.Background()
+	adminRepo := NewHandwrittenMockAdminRepository()
+	adminRepo.GetAdminUnreadCountFunc = func(ctx context.Context) (int, error) { return 4, nil }
+
+	events := &MemNotificationEventRepository{}
+	notifs := services.NewNotificationService(NewMockNotificationRepository())
+	notifs.SetStream(services.NewNotificationStream(events, nil))
+	svc := services.NewAdminService(adminRepo, nil, config.AppConfig{}, nil)
+	svc.SetNotifications(notifs)
+
+	svc.AdminNotificationCreated(ctx)
+	require.NoError(t, svc.MarkNotificationAsRead(ctx, "an1"))
+	require.NoError(t, svc.MarkAllNotificationsAsRead(ctx))
+
+	require.Len(t, events.events, 3)
+	types := []string{events.events[0].Type, events.events[1].Type, events.events[2].Type}
+	assert.Equal(t, []string{models.AdminNotificationEventCreated, models.AdminNotificationEventRead, models.AdminNotificationEventReadAll}, types)
+	for _, ev := range events.events {
+		assert.Equal(t, services.AdminNotificationStream, ev.Stream)
+		assert.Equal(t, 4, decodeNotificationPayload(t, ev).UnreadCount)
+	}
+	assert.Equal(t, "an1", decodeNotificationPayload(t, events.events[1]).ID)
+}
@@ -10,6 +10,13 @@ import (
 // assigned to the student when a document is submitted for review.
 // This creates a shared notification that all assigned advisors can see.
 func NotifyAdvisorsOnSubmission(db *sqlx.DB, studentID, nodeID, nodeInstanceID, message string) error {
+	return NotifyAdvisorsOnSubmissionOnce(db, "", studentID, nodeID, nodeInstanceID, message)
+}
+
+// NotifyAdvisorsOnSubmissionOnce is NotifyAdvisorsOnSubmission for outbox
+// deliveries, which may run more than once: the notification is created at
+// most once per outboxMessageID. An empty id is not deduplicated.
+func NotifyAdvisorsOnSubmissionOnce(db *sqlx.DB, outboxMessageID, studentID, nodeID, nodeInstanceID, message string) error {
 	// Get student name
 	var studentName string
 	err := db.Get(&studentName, `SELECT COALESCE(first_name || ' ' || last_name, email, username) 
@@ -51,9 +58,10 @@ func NotifyAdvisorsOnSubmission(db *sqlx.DB, studentID, nodeID, nodeInstanceID,
 	// All advisors will see this notification through the list endpoint
 	// The notification is associated with the student, so advisors filtering by their students will see it
 	_, err = db.Exec(`INSERT INTO admin_notifications 
-		(student_id, node_id, node_instance_id, event_type, message, metadata)
-		VALUES ($1, $2, $3, 'document_submitted', $4, '{}')`,
-		studentID, nodeID, nodeInstanceID, message)
+		(student_id, node_id, node_instance_id, event_type, message, metadata, outbox_message_id)
+		VALUES ($1, $2, $3, 'document_submitted', $4, '{}', NULLIF($5, '')::uuid)
+		ON CONFLICT (outbox_message_id) DO NOTHING`,
+		studentID, nodeID, nodeInstanceID, message, outboxMessageID)
 	if err != nil {
 		log.Printf("[NotifyAdvisors] Failed to insert notification: %v", err)
 		return err
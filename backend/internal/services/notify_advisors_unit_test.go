@@ -43,6 +43,18 @@ func TestNotifyAdvisorsOnSubmission_Unit(t *testing.T) {
 		err := services.NotifyAdvisorsOnSubmission(sqlxDB, "s1", "n1", "ni1", "")
 		assert.Error(t, err)
 	})
+
+	t.Run("Outbox deliveries are keyed by message", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COALESCE").WithArgs("s1").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Student"))
+		mock.ExpectQuery("SELECT advisor_id").WithArgs("s1").WillReturnRows(sqlmock.NewRows([]string{"advisor_id"}).AddRow("a1"))
+		mock.ExpectExec(`INSERT INTO admin_notifications .*ON CONFLICT \(outbox_message_id\) DO NOTHING`).
+			WithArgs("s1", "n1", "ni1", "Student submitted a document for review", "m1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := services.NotifyAdvisorsOnSubmissionOnce(sqlxDB, "m1", "s1", "n1", "ni1", "")
+		assert.NoError(t, err, "a notification already delivered is not an error")
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetAdvisorsForStudent_Unit(t *testing.T) {
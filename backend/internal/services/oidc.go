@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+const (
+	// OIDCCallbackPath is where providers send users back to
+	OIDCCallbackPath = "/api/auth/oidc/callback"
+	// OIDCLoginPath starts a login; the frontend links to it
+	OIDCLoginPath = "/api/auth/oidc/login"
+	// OIDCLoginTTL is how long a user has to sign in at the provider
+	OIDCLoginTTL = 10 * time.Minute
+	// oidcProviderTTL is how long a provider's discovery document is reused
+	oidcProviderTTL = time.Hour
+)
+
+var ErrOIDCInvalidConfig = errors.New("invalid single sign-on configuration")
+
+// OIDCService signs users in with their tenant's OpenID Connect provider.
+// Users are matched by email; with just-in-time provisioning unknown users
+// become students of the tenant. A completed login gets the same session
+// token as a password login.
+type OIDCService struct {
+	repo      repository.OIDCRepository
+	accounts  *ssoAccounts
+	tenants   repository.TenantRepository
+	serverURL string
+	client    *http.Client
+
+	mu        sync.Mutex
+	providers map[string]cachedProvider
+}
+
+type cachedProvider struct {
+	provider *oidc.Provider
+	at       time.Time
+}
+
+func NewOIDCService(repo repository.OIDCRepository, users repository.UserRepository, tenants repository.TenantRepository, authService *AuthService, cfg config.AppConfig) *OIDCService {
+	return &OIDCService{
+		repo:      repo,
+		accounts:  &ssoAccounts{users: users, tenants: tenants, auth: authService},
+		tenants:   tenants,
+		serverURL: strings.TrimSuffix(cfg.ServerURL, "/"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		providers: map[string]cachedProvider{},
+	}
+}
+
+// Enabled tells whether the tenant's users can sign in with SSO.
+func (s *OIDCService) Enabled(ctx context.Context, tenantID string) bool {
+	cfg, err := s.repo.GetConfig(ctx, tenantID)
+	return err == nil && cfg.Enabled
+}
+
+// LoginURL is the backend URL that starts an SSO login for the tenant.
+func (s *OIDCService) LoginURL(tenantSlug string) string {
+	return s.serverURL + OIDCLoginPath + "?" + url.Values{"tenant": {tenantSlug}}.Encode()
+}
+
+// RedirectURI is the callback URL to register with the tenant's provider.
+// It names the tenant, as the callback cannot carry the tenant header.
+func (s *OIDCService) RedirectURI(ctx context.Context, tenantID string) (string, error) {
+	t, err := s.tenants.GetByID(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	return s.serverURL + OIDCCallbackPath + "?" + url.Values{"tenant": {t.Slug}}.Encode(), nil
+}
+
+// BeginLogin starts an SSO login for the tenant and returns the provider
+// URL to send the user to, and the state the callback will carry.
+func (s *OIDCService) BeginLogin(ctx context.Context, tenantID, redirectPath string) (authURL, state string, err error) {
+	cfg, err := s.enabledConfig(ctx, tenantID)
+	if err != nil {
+		return "", "", err
+	}
+	provider, err := s.provider(ctx, cfg.Issuer)
+	if err != nil {
+		return "", "", err
+	}
+	redirectURI, err := s.RedirectURI(ctx, tenantID)
+	if err != nil {
+		return "", "", err
+	}
+	st := &models.OIDCLoginState{
+		State:        oidc.RandomString(),
+		TenantID:     tenantID,
+		Nonce:        oidc.RandomString(),
+		CodeVerifier: oidc.NewVerifier(),
+		RedirectPath: SafeRedirectPath(redirectPath),
+		ExpiresAt:    time.Now().Add(OIDCLoginTTL),
+	}
+	if err := s.repo.CreateState(ctx, st); err != nil {
+		return "", "", err
+	}
+	return provider.AuthCodeURL(s.clientConfig(cfg, redirectURI), st.State, st.Nonce, st.CodeVerifier), st.State, nil
+}
+
+// CompleteLogin handles the provider's callback to the tenant. idpError is
+// the error the provider returned instead of a code, if any.
+func (s *OIDCService) CompleteLogin(ctx context.Context, tenantID, state, code, idpError string) (*SSOLoginResult, error) {
+	st, err := s.repo.ConsumeState(ctx, state)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSSOLoginExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	if st.TenantID != tenantID {
+		return nil, ErrSSOLoginExpired
+	}
+	if idpError != "" {
+		log.Printf("[OIDCService] Provider returned error=%s for tenant=%s", idpError, st.TenantID)
+		return nil, ErrSSODeclined
+	}
+	cfg, err := s.enabledConfig(ctx, st.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.provider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	redirectURI, err := s.RedirectURI(ctx, st.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := provider.Exchange(ctx, s.clientConfig(cfg, redirectURI), code, st.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.Verify(ctx, cfg.ClientID, tok.IDToken, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	// An email the provider did not verify only counts when the tenant
+	// restricts logins to its own domains
+	emailOK := len(cfg.AllowedDomains) > 0
+	if claims.EmailVerified != nil {
+		emailOK = bool(*claims.EmailVerified)
+	}
+	if !emailOK {
+		log.Printf("[OIDCService] Unverified email=%q sub=%s for tenant=%s", claims.Email, claims.Subject, st.TenantID)
+		return nil, ErrSSOEmailNotAllowed
+	}
+
+	// OIDC providers do not vouch for staff roles, so their logins are
+	// students unless the account already belongs to the tenant
+	id := ssoIdentity{Email: claims.Email, FirstName: claims.GivenName, LastName: claims.FamilyName, Role: string(models.RoleStudent)}
+	if id.FirstName == "" && id.LastName == "" {
+		id.FirstName, id.LastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	return s.accounts.signIn(ctx, st.TenantID, st.RedirectPath, id, ssoPolicy{
+		JITProvisioning: cfg.JITProvisioning,
+		AllowedDomains:  cfg.AllowedDomains,
+	})
+}
+
+// GetConfig returns the tenant's provider configuration.
+func (s *OIDCService) GetConfig(ctx context.Context, tenantID string) (*models.TenantOIDCConfig, error) {
+	cfg, err := s.repo.GetConfig(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSSONotConfigured
+	}
+	return cfg, err
+}
+
+// SaveConfig validates and stores the tenant's provider configuration. A
+// new configuration needs a client secret.
+func (s *OIDCService) SaveConfig(ctx context.Context, cfg *models.TenantOIDCConfig) error {
+	cfg.Issuer = strings.TrimSuffix(strings.TrimSpace(cfg.Issuer), "/")
+	cfg.ClientID = strings.TrimSpace(cfg.ClientID)
+	if u, err := url.Parse(cfg.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%w: issuer must be an http(s) URL", ErrOIDCInvalidConfig)
+	}
+	if cfg.ClientID == "" {
+		return fmt.Errorf("%w: client_id is required", ErrOIDCInvalidConfig)
+	}
+	if cfg.ClientSecret == "" {
+		if _, err := s.repo.GetConfig(ctx, cfg.TenantID); errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: client_secret is required", ErrOIDCInvalidConfig)
+		}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.AllowedDomains = normalizeDomains(cfg.AllowedDomains)
+	if err := s.repo.SaveConfig(ctx, cfg); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.providers, cfg.Issuer)
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteConfig turns SSO off for the tenant for good.
+func (s *OIDCService) DeleteConfig(ctx context.Context, tenantID string) error {
+	err := s.repo.DeleteConfig(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrSSONotConfigured
+	}
+	return err
+}
+
+func (s *OIDCService) enabledConfig(ctx context.Context, tenantID string) (*models.TenantOIDCConfig, error) {
+	cfg, err := s.repo.GetConfig(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSSONotConfigured
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, ErrSSONotConfigured
+	}
+	return cfg, nil
+}
+
+func (s *OIDCService) clientConfig(cfg *models.TenantOIDCConfig, redirectURI string) oidc.Config {
+	return oidc.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  redirectURI,
+		Scopes:       cfg.Scopes,
+	}
+}
+
+// provider returns the discovered provider of an issuer, reusing it for an
+// hour.
+func (s *OIDCService) provider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	s.mu.Lock()
+	cached, ok := s.providers[issuer]
+	s.mu.Unlock()
+	if ok && time.Since(cached.at) < oidcProviderTTL {
+		return cached.provider, nil
+	}
+	p, err := oidc.Discover(ctx, s.client, issuer)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.providers[issuer] = cachedProvider{provider: p, at: time.Now()}
+	s.mu.Unlock()
+	return p, nil
+}
@@ -0,0 +1,263 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/oidc/oidctest"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// followToIdP opens the provider URL and returns the query of the callback
+// it redirects to.
+func followToIdP(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/auth/oidc/callback", loc.Path)
+	assert.Equal(t, "kaznmu", loc.Query().Get("tenant"))
+	return loc.Query()
+}
+
+func TestOIDCService_Login(t *testing.T) {
+	idp := oidctest.NewServer("portal", "s3cret")
+	defer idp.Close()
+	ctx := context.Background()
+
+	repo := NewMemOIDCRepository()
+	users := NewHandwrittenMockUserRepository()
+	var memberships []string
+	tenants := &MockTenantRepository{
+		GetByIDFunc: func(ctx context.Context, id string) (*models.Tenant, error) {
+			return &models.Tenant{ID: id, Slug: "kaznmu"}, nil
+		},
+		AddUserToTenantFunc: func(ctx context.Context, userID, tenantID, role string, isPrimary bool) error {
+			memberships = append(memberships, userID+":"+tenantID+":"+role)
+			return nil
+		},
+	}
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, ServerURL: "http://localhost:8080"}
+	authService := services.NewAuthService(users, NewMemSessionRepository(), nil, nil, cfg)
+	svc := services.NewOIDCService(repo, users, tenants, authService, cfg)
+
+	require.NoError(t, svc.SaveConfig(ctx, &models.TenantOIDCConfig{
+		TenantID: "t1", Issuer: idp.Issuer + "/", ClientID: "portal", ClientSecret: "s3cret",
+		Enabled: true, AllowedDomains: []string{" @Example.EDU "},
+	}))
+	assert.Equal(t, []string{"openid", "email", "profile"}, []string(repo.configs["t1"].Scopes))
+	assert.Equal(t, []string{"example.edu"}, []string(repo.configs["t1"].AllowedDomains))
+	assert.Equal(t, "http://localhost:8080/api/auth/oidc/login?tenant=kaznmu", svc.LoginURL("kaznmu"))
+
+	login := func(t *testing.T, redirect string) (*services.SSOLoginResult, error) {
+		authURL, state, err := svc.BeginLogin(ctx, "t1", redirect)
+		require.NoError(t, err)
+		q := followToIdP(t, authURL)
+		require.Equal(t, state, q.Get("state"))
+		return svc.CompleteLogin(ctx, "t1", q.Get("state"), q.Get("code"), q.Get("error"))
+	}
+
+	t.Run("Existing user by email", func(t *testing.T) {
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			assert.Equal(t, "student@example.edu", email)
+			return &models.User{ID: "u1", Role: models.RoleAdvisor, IsActive: true}, nil
+		}
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "advisor", nil
+		}
+		res, err := login(t, "/journey")
+		require.NoError(t, err)
+		assert.Equal(t, "u1", res.UserID)
+		assert.Equal(t, "advisor", res.Role)
+		assert.Equal(t, "/journey", res.RedirectPath)
+		assert.False(t, res.Provisioned)
+
+		claims := jwt.MapClaims{}
+		_, err = jwt.ParseWithClaims(res.Token, claims, func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, "u1", claims["sub"])
+		assert.Equal(t, "t1", claims["tenant_id"])
+	})
+
+	t.Run("Second factor", func(t *testing.T) {
+		mfaRepo := NewMemMFARepository()
+		require.NoError(t, mfaRepo.SaveTenantPolicy(ctx, "t1", &models.TenantMFAPolicy{RequireForAdmins: true}))
+		authService.SetSecondFactor(services.NewMFAService(mfaRepo, authService, cfg))
+		defer authService.SetSecondFactor(nil)
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "admin", nil
+		}
+		defer func() {
+			users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+				return "advisor", nil
+			}
+		}()
+		res, err := login(t, "/admin")
+		require.NoError(t, err)
+		require.NotNil(t, res.MFA, "the tenant requires 2FA for admins")
+		assert.True(t, res.MFA.Enroll)
+		assert.Empty(t, res.Token)
+		assert.Equal(t, "/admin", res.RedirectPath)
+	})
+
+	t.Run("State is single use and bound to the tenant", func(t *testing.T) {
+		authURL, _, err := svc.BeginLogin(ctx, "t1", "//evil.example")
+		require.NoError(t, err)
+		q := followToIdP(t, authURL)
+		_, err = svc.CompleteLogin(ctx, "t2", q.Get("state"), q.Get("code"), "")
+		assert.ErrorIs(t, err, services.ErrSSOLoginExpired)
+		_, err = svc.CompleteLogin(ctx, "t1", q.Get("state"), q.Get("code"), "")
+		assert.ErrorIs(t, err, services.ErrSSOLoginExpired)
+	})
+
+	t.Run("No membership without provisioning", func(t *testing.T) {
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "", repository.ErrNotFound
+		}
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccessDenied)
+	})
+
+	t.Run("Unknown email without provisioning", func(t *testing.T) {
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return nil, repository.ErrNotFound
+		}
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSONoAccount)
+
+		users.EmailExistsFunc = func(ctx context.Context, email, exclude string) (bool, error) { return true, nil }
+		_, err = login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccountInactive)
+		users.EmailExistsFunc = func(ctx context.Context, email, exclude string) (bool, error) { return false, nil }
+	})
+
+	t.Run("Just-in-time provisioning", func(t *testing.T) {
+		repo.configs["t1"].JITProvisioning = true
+		var created *models.User
+		users.CreateFunc = func(ctx context.Context, u *models.User) (string, error) {
+			created = u
+			return "u-new", nil
+		}
+		res, err := login(t, "")
+		require.NoError(t, err)
+		assert.True(t, res.Provisioned)
+		assert.Equal(t, "u-new", res.UserID)
+		assert.Equal(t, "student", res.Role)
+		assert.Equal(t, "/", res.RedirectPath)
+		require.NotNil(t, created)
+		assert.Equal(t, "student@example.edu", created.Email)
+		assert.Equal(t, "Mock", created.FirstName)
+		assert.Equal(t, "Student", created.LastName)
+		assert.Regexp(t, `^ms\d{4}$`, created.Username)
+		assert.NotEmpty(t, created.PasswordHash)
+		assert.Equal(t, []string{"u-new:t1:student"}, memberships)
+	})
+
+	t.Run("Provisioning does not grant staff access", func(t *testing.T) {
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{ID: "u2", Role: models.RoleAdmin, IsActive: true}, nil
+		}
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccessDenied)
+	})
+
+	t.Run("Email outside the allowed domains or unverified", func(t *testing.T) {
+		idp.User.Email = "someone@gmail.com"
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOEmailNotAllowed)
+
+		idp.User.Email, idp.User.EmailVerified = "student@example.edu", false
+		_, err = login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOEmailNotAllowed)
+		idp.User.EmailVerified = true
+	})
+
+	t.Run("Email verification", func(t *testing.T) {
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{ID: "u1", Role: models.RoleStudent, IsActive: true}, nil
+		}
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "student", nil
+		}
+		idp.Claims = func(c jwt.MapClaims) { delete(c, "email_verified") }
+		defer func() { idp.Claims = nil }()
+		_, err := login(t, "")
+		require.NoError(t, err, "the tenant's own domains need no verification claim")
+
+		repo.configs["t1"].AllowedDomains = nil
+		defer func() { repo.configs["t1"].AllowedDomains = []string{"example.edu"} }()
+		_, err = login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOEmailNotAllowed, "any domain needs email_verified")
+		idp.Claims = nil
+		_, err = login(t, "")
+		require.NoError(t, err)
+	})
+
+	t.Run("Superadmins cannot use a tenant's provider", func(t *testing.T) {
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{ID: "root", Role: models.RoleSuperAdmin, IsSuperadmin: true, IsActive: true}, nil
+		}
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccessDenied)
+	})
+
+	t.Run("Declined at the provider", func(t *testing.T) {
+		idp.Error = "access_denied"
+		defer func() { idp.Error = "" }()
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSODeclined)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		repo.configs["t1"].Enabled = false
+		_, _, err := svc.BeginLogin(ctx, "t1", "")
+		assert.ErrorIs(t, err, services.ErrSSONotConfigured)
+		assert.False(t, svc.Enabled(ctx, "t1"))
+	})
+}
+
+func TestOIDCService_SaveConfig(t *testing.T) {
+	svc := services.NewOIDCService(NewMemOIDCRepository(), nil, nil, nil, config.AppConfig{})
+	ctx := context.Background()
+
+	err := svc.SaveConfig(ctx, &models.TenantOIDCConfig{TenantID: "t1", Issuer: "idp.example", ClientID: "portal", ClientSecret: "x"})
+	assert.ErrorIs(t, err, services.ErrOIDCInvalidConfig)
+
+	err = svc.SaveConfig(ctx, &models.TenantOIDCConfig{TenantID: "t1", Issuer: "https://idp.example", ClientID: "portal"})
+	assert.ErrorIs(t, err, services.ErrOIDCInvalidConfig, "a new configuration needs a secret")
+
+	require.NoError(t, svc.SaveConfig(ctx, &models.TenantOIDCConfig{TenantID: "t1", Issuer: "https://idp.example", ClientID: "portal", ClientSecret: "x"}))
+	require.NoError(t, svc.SaveConfig(ctx, &models.TenantOIDCConfig{TenantID: "t1", Issuer: "https://idp.example", ClientID: "portal-2"}))
+	cfg, err := svc.GetConfig(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, "portal-2", cfg.ClientID)
+	assert.Equal(t, "x", cfg.ClientSecret)
+
+	require.NoError(t, svc.DeleteConfig(ctx, "t1"))
+	assert.ErrorIs(t, svc.DeleteConfig(ctx, "t1"), services.ErrSSONotConfigured)
+}
+
+func TestSafeRedirectPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/journey?tab=2":         "/journey?tab=2",
+		"":                       "/",
+		"https://evil.example/":  "/",
+		"//evil.example":         "/",
+		"/\\evil.example":        "/",
+		"javascript:alert(1)":    "/",
+		"/login\r\nSet-Cookie:x": "/",
+	} {
+		assert.Equal(t, want, services.SafeRedirectPath(in), in)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+var (
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+	ErrOutboxNotReplayable   = errors.New("only dead messages can be replayed")
+)
+
+// NewOutboxMessage builds an outbox message of kind with payload encoded as JSON.
+func NewOutboxMessage(tenantID, kind string, payload any) (*models.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	msg := &models.OutboxMessage{Kind: kind, Payload: data}
+	if tenantID != "" {
+		msg.TenantID = &tenantID
+	}
+	return msg, nil
+}
+
+// OutboxService lets admins inspect the tenant's outbox and replay dead messages.
+type OutboxService struct {
+	repo repository.OutboxRepository
+}
+
+func NewOutboxService(repo repository.OutboxRepository) *OutboxService {
+	return &OutboxService{repo: repo}
+}
+
+func (s *OutboxService) List(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error) {
+	msgs, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if msgs == nil {
+		msgs = []models.OutboxMessage{}
+	}
+	return msgs, nil
+}
+
+func (s *OutboxService) Get(ctx context.Context, tenantID, id string) (*models.OutboxMessage, error) {
+	msg, err := s.repo.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return msg, nil
+}
+
+// Replay requeues a dead message. Messages that are not dead are refused so a
+// pending or delivered message is never sent twice.
+func (s *OutboxService) Replay(ctx context.Context, tenantID, id string) (*models.OutboxMessage, error) {
+	msg, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Status != models.OutboxStatusDead {
+		return nil, ErrOutboxNotReplayable
+	}
+	ok, err := s.repo.Replay(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return s.Get(ctx, tenantID, id)
+}
+
+// ReplayDead requeues all of the tenant's dead messages, optionally of one kind.
+func (s *OutboxService) ReplayDead(ctx context.Context, tenantID, kind string) (int64, error) {
+	return s.repo.ReplayDead(ctx, tenantID, kind)
+}
@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJourneyService_TransitionEnqueuesOutbox_Unit(t *testing.T) {
+	pb := &playbook.Manager{Nodes: map[string]playbook.Node{"S1": {ID: "S1"}}}
+
+	setup := func() (*MockJourneyRepository, *[]*models.OutboxMessage, *int) {
+		mock := NewMockJourneyRepository()
+		mock.GetNodeInstanceFunc = func(ctx context.Context, userID, nodeID string) (*models.NodeInstance, error) {
+			return &models.NodeInstance{ID: "inst1", UserID: "stud1", NodeID: "S1", State: "active"}, nil
+		}
+		mock.GetAllowedTransitionRolesFunc = func(ctx context.Context, from, to string) ([]string, error) {
+			return []string{"student"}, nil
+		}
+		mock.GetUsersByIDsFunc = func(ctx context.Context, ids []string) ([]models.User, error) {
+			return []models.User{{ID: ids[0], FirstName: "Aru", LastName: "K"}}, nil
+		}
+		var queued []*models.OutboxMessage
+		mock.EnqueueOutboxFunc = func(ctx context.Context, msg *models.OutboxMessage) error {
+			queued = append(queued, msg)
+			return nil
+		}
+		txs := 0
+		mock.WithTxFunc = func(ctx context.Context, fn func(repository.JourneyRepository) error) error {
+			txs++
+			return fn(mock)
+		}
+		return mock, &queued, &txs
+	}
+
+	t.Run("Submission queues email and advisor notice in the transaction", func(t *testing.T) {
+		mock, queued, txs := setup()
+		svc := services.NewJourneyService(mock, pb, config.AppConfig{StateChangeEmailTo: "office@example.com"}, NewManualEmailSender(), nil, nil)
+
+		require.NoError(t, svc.PatchState(context.Background(), "t1", "stud1", "student", "S1", "submitted"))
+		assert.Equal(t, 1, *txs)
+		require.Len(t, *queued, 2)
+
+		email := (*queued)[0]
+		assert.Equal(t, models.OutboxKindEmail, email.Kind)
+		assert.Equal(t, "t1", *email.TenantID)
+		var ep models.EmailPayload
+		require.NoError(t, json.Unmarshal(email.Payload, &ep))
+		assert.Equal(t, "office@example.com", ep.To)
+		assert.Contains(t, ep.Subject, "S1 (active -> submitted)")
+		assert.Contains(t, ep.Body, "Aru K")
+
+		notice := (*queued)[1]
+		assert.Equal(t, models.OutboxKindAdvisorSubmission, notice.Kind)
+		var ap models.AdvisorSubmissionPayload
+		require.NoError(t, json.Unmarshal(notice.Payload, &ap))
+		assert.Equal(t, models.AdvisorSubmissionPayload{StudentID: "stud1", NodeID: "S1", NodeInstanceID: "inst1"}, ap)
+	})
+
+	t.Run("No mailer, no email", func(t *testing.T) {
+		mock, queued, _ := setup()
+		svc := services.NewJourneyService(mock, pb, config.AppConfig{StateChangeEmailTo: "office@example.com"}, nil, nil, nil)
+		require.NoError(t, svc.PatchState(context.Background(), "t1", "stud1", "student", "S1", "submitted"))
+		require.Len(t, *queued, 1)
+		assert.Equal(t, models.OutboxKindAdvisorSubmission, (*queued)[0].Kind)
+	})
+
+	t.Run("No recipient, no email", func(t *testing.T) {
+		mock, queued, _ := setup()
+		svc := services.NewJourneyService(mock, pb, config.AppConfig{}, NewManualEmailSender(), nil, nil)
+		require.NoError(t, svc.PatchState(context.Background(), "t1", "stud1", "student", "S1", "submitted"))
+		require.Len(t, *queued, 1)
+		assert.Equal(t, models.OutboxKindAdvisorSubmission, (*queued)[0].Kind)
+	})
+
+	t.Run("Enqueue failure fails the transition", func(t *testing.T) {
+		mock, _, _ := setup()
+		mock.EnqueueOutboxFunc = func(ctx context.Context, msg *models.OutboxMessage) error {
+			return errors.New("db down")
+		}
+		svc := services.NewJourneyService(mock, pb, config.AppConfig{}, NewManualEmailSender(), nil, nil)
+		err := svc.PatchState(context.Background(), "t1", "stud1", "student", "S1", "submitted")
+		assert.ErrorContains(t, err, "db down")
+	})
+
+	t.Run("Journey state failure is no longer swallowed", func(t *testing.T) {
+		mock, queued, _ := setup()
+		mock.UpsertJourneyStateFunc = func(ctx context.Context, userID, nodeID, state, tenantID string) error {
+			return errors.New("constraint")
+		}
+		svc := services.NewJourneyService(mock, pb, config.AppConfig{}, NewManualEmailSender(), nil, nil)
+		assert.Error(t, svc.PatchState(context.Background(), "t1", "stud1", "student", "S1", "submitted"))
+		assert.Empty(t, *queued)
+	})
+}
+
+func TestOutboxService_Unit(t *testing.T) {
+	tenant := "t1"
+	repo := &MemOutboxRepository{msgs: map[string]*models.OutboxMessage{
+		"dead": {ID: "dead", TenantID: &tenant, Status: models.OutboxStatusDead, Attempts: 8},
+		"sent": {ID: "sent", TenantID: &tenant, Status: models.OutboxStatusSent, Attempts: 1},
+	}}
+	svc := services.NewOutboxService(repo)
+	ctx := context.Background()
+
+	msg, err := svc.Replay(ctx, "t1", "dead")
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxStatusPending, msg.Status)
+	assert.Zero(t, msg.Attempts)
+
+	_, err = svc.Replay(ctx, "t1", "sent")
+	assert.ErrorIs(t, err, services.ErrOutboxNotReplayable)
+
+	_, err = svc.Replay(ctx, "other", "dead")
+	assert.ErrorIs(t, err, services.ErrOutboxMessageNotFound)
+
+	list, err := svc.List(ctx, models.OutboxFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	assert.NotNil(t, list)
+
+	built, err := services.NewOutboxMessage("", models.OutboxKindEmail, models.EmailPayload{To: "a@b.c"})
+	require.NoError(t, err)
+	assert.Nil(t, built.TenantID)
+	assert.JSONEq(t, `{"to":"a@b.c","subject":"","body":""}`, string(built.Payload))
+}
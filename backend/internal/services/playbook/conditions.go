@@ -0,0 +1,131 @@
+package playbook
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Condition is a named predicate declared in the playbook's top-level
+// "conditions" list and referenced by nodes via their "condition" field.
+type Condition struct {
+	ID          string            `json:"id"`
+	Expr        string            `json:"expr"`
+	Description map[string]string `json:"description,omitempty"`
+
+	compiled *Expr
+}
+
+// now is swapped in tests to make derived profile facts deterministic.
+var now = time.Now
+
+func indexConditions(pb Playbook) (map[string]Condition, error) {
+	out := make(map[string]Condition, len(pb.Conditions))
+	for _, c := range pb.Conditions {
+		compiled, err := CompileExpr(c.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("compile condition %s: %w", c.ID, err)
+		}
+		c.compiled = compiled
+		out[c.ID] = c
+	}
+	return out, nil
+}
+
+// NewEnv builds an evaluation environment from a student's profile and the
+// current node's form data. Either map may be nil.
+func NewEnv(profile, form map[string]any) Env {
+	return Env{
+		"profile": ProfileFacts(profile),
+		"form":    nonNilMap(form),
+	}
+}
+
+// ProfileFacts returns a copy of the profile form data enriched with values the
+// playbook relies on but the form does not store directly. Currently this is
+// years_since_graduation, derived from graduation_date when present (matching
+// the frontend's useConditions hook).
+func ProfileFacts(profile map[string]any) map[string]any {
+	out := make(map[string]any, len(profile)+1)
+	for k, v := range profile {
+		out[k] = v
+	}
+	if raw, ok := profile["graduation_date"].(string); ok && raw != "" {
+		if grad, ok := parseProfileDate(raw); ok {
+			out["years_since_graduation"] = now().Sub(grad).Hours() / 24 / 365.25
+		}
+	}
+	return out
+}
+
+func parseProfileDate(s string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "02.01.2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func nonNilMap(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
+
+// EvaluateCondition evaluates the named playbook condition against env.
+func (m *Manager) EvaluateCondition(id string, env Env) (bool, error) {
+	c, ok := m.Conditions[id]
+	if !ok {
+		return false, fmt.Errorf("unknown condition %q", id)
+	}
+	expr := c.compiled
+	if expr == nil {
+		var err error
+		if expr, err = CompileExpr(c.Expr); err != nil {
+			return false, fmt.Errorf("compile condition %s: %w", id, err)
+		}
+	}
+	return expr.EvalBool(env), nil
+}
+
+// EvaluateConditions evaluates every declared condition against env.
+// Conditions that fail to evaluate are reported as false.
+func (m *Manager) EvaluateConditions(env Env) map[string]bool {
+	out := make(map[string]bool, len(m.Conditions))
+	for id := range m.Conditions {
+		ok, _ := m.EvaluateCondition(id, env)
+		out[id] = ok
+	}
+	return out
+}
+
+// NodeVisible reports whether a node applies to the student described by env.
+// Only conditional stages can be hidden.
+func (m *Manager) NodeVisible(nodeID string, env Env) (bool, error) {
+	n, ok := m.Nodes[nodeID]
+	if !ok {
+		return false, fmt.Errorf("node %s not found in playbook", nodeID)
+	}
+	if n.Gate() == "" {
+		return true, nil
+	}
+	return m.EvaluateCondition(n.Gate(), env)
+}
+
+// HiddenNodes lists the conditional stages whose condition is not satisfied
+// for env.
+func (m *Manager) HiddenNodes(env Env) []string {
+	var hidden []string
+	for id, n := range m.Nodes {
+		if n.Gate() == "" {
+			continue
+		}
+		if ok, _ := m.EvaluateCondition(n.Gate(), env); !ok {
+			hidden = append(hidden, id)
+		}
+	}
+	sort.Strings(hidden)
+	return hidden
+}
@@ -0,0 +1,111 @@
+package playbook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionsManager(t *testing.T) *Manager {
+	t.Helper()
+	pb := Playbook{
+		Conditions: []Condition{{ID: "rp_required", Expr: "profile.years_since_graduation > 3"}},
+		Worlds: []World{{
+			ID: "W3",
+			Nodes: []Node{
+				{ID: "RP1", Condition: "rp_required", Conditional: true},
+				{ID: "D1"},
+			},
+		}},
+	}
+	mgr, err := newManager("v1", "1.0.0", "sum", nil, pb)
+	require.NoError(t, err)
+	return mgr
+}
+
+func TestProfileFacts_DerivesYearsSinceGraduation(t *testing.T) {
+	orig := now
+	now = func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = orig }()
+
+	facts := ProfileFacts(map[string]any{"graduation_date": "2020-06-01", "program": "PhD"})
+	assert.InDelta(t, 5.0, facts["years_since_graduation"], 0.01)
+	assert.Equal(t, "PhD", facts["program"])
+
+	// An explicit value is kept when there is no parsable date
+	facts = ProfileFacts(map[string]any{"years_since_graduation": 2.0, "graduation_date": "soon"})
+	assert.Equal(t, 2.0, facts["years_since_graduation"])
+
+	assert.Empty(t, ProfileFacts(nil))
+}
+
+func TestManager_EvaluateCondition(t *testing.T) {
+	mgr := conditionsManager(t)
+
+	ok, err := mgr.EvaluateCondition("rp_required", NewEnv(map[string]any{"years_since_graduation": 4.0}, nil))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = mgr.EvaluateCondition("rp_required", NewEnv(nil, nil))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = mgr.EvaluateCondition("unknown", NewEnv(nil, nil))
+	assert.Error(t, err)
+
+	// Managers built without newManager compile lazily
+	lazy := &Manager{Conditions: map[string]Condition{"c": {ID: "c", Expr: "form.x == 1"}}}
+	ok, err = lazy.EvaluateCondition("c", NewEnv(nil, map[string]any{"x": 1.0}))
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestManager_NodeVisibility(t *testing.T) {
+	mgr := conditionsManager(t)
+	recent := NewEnv(map[string]any{"years_since_graduation": 1.0}, nil)
+	old := NewEnv(map[string]any{"years_since_graduation": 6.0}, nil)
+
+	visible, err := mgr.NodeVisible("RP1", recent)
+	require.NoError(t, err)
+	assert.False(t, visible)
+
+	visible, err = mgr.NodeVisible("D1", recent)
+	require.NoError(t, err)
+	assert.True(t, visible)
+
+	_, err = mgr.NodeVisible("missing", recent)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"RP1"}, mgr.HiddenNodes(recent))
+	assert.Empty(t, mgr.HiddenNodes(old))
+	assert.Equal(t, map[string]bool{"rp_required": true}, mgr.EvaluateConditions(old))
+}
+
+func TestIndexConditions_InvalidExpr(t *testing.T) {
+	_, err := newManager("v1", "1.0.0", "sum", nil, Playbook{
+		Conditions: []Condition{{ID: "broken", Expr: "profile.x >"}},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "compile condition broken")
+}
+
+func TestShippedPlaybook_ConditionsCompile(t *testing.T) {
+	_, b, _, _ := runtime.Caller(0)
+	raw, err := os.ReadFile(filepath.Join(filepath.Dir(b), "../../../playbooks/playbook.json"))
+	require.NoError(t, err)
+
+	var pb Playbook
+	require.NoError(t, json.Unmarshal(raw, &pb))
+	mgr, err := newManager("v1", pb.Version, "sum", raw, pb)
+	require.NoError(t, err)
+
+	require.Contains(t, mgr.Conditions, "rp_required")
+	assert.Equal(t, "rp_required", mgr.Nodes["RP1_overview_actualization"].Gate())
+	assert.Empty(t, mgr.Nodes["E3_hearing_nk"].Gate(), "E3's condition only picks its next node")
+}
@@ -0,0 +1,85 @@
+package playbook
+
+import (
+	"fmt"
+	"time"
+)
+
+// Deadline anchors: what a node's due date is counted from.
+const (
+	AnchorActivation  = "activation"   // when the node opened for the student
+	AnchorCohortStart = "cohort_start" // the start date of the student's cohort
+	AnchorFixed       = "fixed"        // a calendar date
+)
+
+// DeadlineRule declares how a node's due date is derived, e.g.
+// {"anchor": "activation", "days": 14} or {"anchor": "fixed", "date": "2026-06-01"}.
+type DeadlineRule struct {
+	Anchor string `json:"anchor"`
+	Days   int    `json:"days,omitempty"`
+	Date   string `json:"date,omitempty"`
+}
+
+// DeadlineAnchors are the per-student dates rules are resolved against.
+// Nil anchors are unknown.
+type DeadlineAnchors struct {
+	ActivatedAt *time.Time
+	CohortStart *time.Time
+}
+
+// Validate checks that the rule is well formed.
+func (r DeadlineRule) Validate() error {
+	switch r.Anchor {
+	case AnchorActivation, AnchorCohortStart:
+		if r.Days < 0 {
+			return fmt.Errorf("deadline days must not be negative")
+		}
+	case AnchorFixed:
+		if _, err := time.Parse("2006-01-02", r.Date); err != nil {
+			return fmt.Errorf("fixed deadline needs a YYYY-MM-DD date")
+		}
+	default:
+		return fmt.Errorf("unknown deadline anchor %q", r.Anchor)
+	}
+	return nil
+}
+
+// DueAt resolves the rule. It reports false when the anchor is not known yet
+// (e.g. the node has not been activated) or the rule is invalid.
+func (r DeadlineRule) DueAt(a DeadlineAnchors) (time.Time, bool) {
+	if r.Validate() != nil {
+		return time.Time{}, false
+	}
+	var base *time.Time
+	switch r.Anchor {
+	case AnchorFixed:
+		d, _ := time.Parse("2006-01-02", r.Date)
+		return endOfDay(d), true
+	case AnchorActivation:
+		base = a.ActivatedAt
+	case AnchorCohortStart:
+		base = a.CohortStart
+	}
+	if base == nil {
+		return time.Time{}, false
+	}
+	return endOfDay(base.UTC().AddDate(0, 0, r.Days)), true
+}
+
+// endOfDay moves t to the last second of its (UTC) day, so a deadline covers
+// the whole due date.
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 23, 59, 59, 0, time.UTC)
+}
+
+// DeadlineNodes returns the ids of nodes that declare a deadline rule.
+func (m *Manager) DeadlineNodes() []string {
+	var ids []string
+	for _, id := range sortedNodeIDs(m) {
+		if m.Nodes[id].Deadline != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
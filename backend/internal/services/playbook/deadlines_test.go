@@ -0,0 +1,54 @@
+package playbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineRule_DueAt(t *testing.T) {
+	opened := time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
+	cohort := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	anchors := DeadlineAnchors{ActivatedAt: &opened, CohortStart: &cohort}
+
+	due, ok := DeadlineRule{Anchor: AnchorActivation, Days: 14}.DueAt(anchors)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 24, 23, 59, 59, 0, time.UTC), due)
+
+	due, ok = DeadlineRule{Anchor: AnchorCohortStart, Days: 365}.DueAt(anchors)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 9, 1, 23, 59, 59, 0, time.UTC), due)
+
+	due, ok = DeadlineRule{Anchor: AnchorFixed, Date: "2026-06-01"}.DueAt(DeadlineAnchors{})
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 6, 1, 23, 59, 59, 0, time.UTC), due)
+
+	// Unknown anchors and invalid rules do not resolve
+	_, ok = DeadlineRule{Anchor: AnchorActivation, Days: 14}.DueAt(DeadlineAnchors{})
+	assert.False(t, ok)
+	_, ok = DeadlineRule{Anchor: "semester", Days: 1}.DueAt(anchors)
+	assert.False(t, ok)
+}
+
+func TestDeadlineRule_Validate(t *testing.T) {
+	assert.NoError(t, DeadlineRule{Anchor: AnchorActivation}.Validate())
+	assert.Error(t, DeadlineRule{Anchor: AnchorActivation, Days: -1}.Validate())
+	assert.Error(t, DeadlineRule{Anchor: AnchorFixed, Date: "01.06.2026"}.Validate())
+	assert.Error(t, DeadlineRule{}.Validate())
+
+	r := Lint([]byte(`{"version": "1", "worlds": [{"id": "W1", "nodes": [
+		{"id": "A", "next": ["END"], "deadline": {"anchor": "fixed", "date": "soon"}}]}]}`))
+	assert.Equal(t, []string{"A"}, lintCodes(r, SeverityError)[LintInvalidDeadline])
+}
+
+func TestManager_DeadlineNodes(t *testing.T) {
+	mgr, err := newManager("v1", "1", "sum", nil, Playbook{Worlds: []World{{ID: "W1", Nodes: []Node{
+		{ID: "B", Deadline: &DeadlineRule{Anchor: AnchorActivation, Days: 7}},
+		{ID: "C"},
+		{ID: "A", Deadline: &DeadlineRule{Anchor: AnchorFixed, Date: "2026-01-01"}},
+	}}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B"}, mgr.DeadlineNodes())
+}
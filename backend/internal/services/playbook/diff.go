@@ -0,0 +1,140 @@
+package playbook
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// NodeChange lists the attributes of a node that differ between two versions.
+type NodeChange struct {
+	NodeID string   `json:"node_id"`
+	Fields []string `json:"fields"`
+}
+
+// VersionDiff summarises how one playbook version differs from another.
+type VersionDiff struct {
+	FromVersionID     string       `json:"from_version_id"`
+	ToVersionID       string       `json:"to_version_id"`
+	FromVersion       string       `json:"from_version"`
+	ToVersion         string       `json:"to_version"`
+	AddedNodes        []string     `json:"added_nodes"`
+	RemovedNodes      []string     `json:"removed_nodes"`
+	ChangedNodes      []NodeChange `json:"changed_nodes"`
+	AddedConditions   []string     `json:"added_conditions"`
+	RemovedConditions []string     `json:"removed_conditions"`
+	ChangedConditions []string     `json:"changed_conditions"`
+}
+
+// Empty reports whether the versions are equivalent node- and condition-wise.
+func (d VersionDiff) Empty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.ChangedNodes) == 0 &&
+		len(d.AddedConditions) == 0 && len(d.RemovedConditions) == 0 && len(d.ChangedConditions) == 0
+}
+
+// Diff compares two playbooks. Nodes are compared attribute by attribute on
+// their raw JSON, so attributes the backend does not model (descriptions,
+// screens, ...) are reported too; moving a node between worlds shows up as a
+// "world" change.
+func Diff(from, to *Manager) VersionDiff {
+	d := VersionDiff{
+		FromVersionID: from.VersionID,
+		ToVersionID:   to.VersionID,
+		FromVersion:   from.Version,
+		ToVersion:     to.Version,
+		AddedNodes:    []string{},
+		RemovedNodes:  []string{},
+		ChangedNodes:  []NodeChange{},
+	}
+
+	fromNodes, toNodes := rawNodes(from), rawNodes(to)
+	for id := range toNodes {
+		if _, ok := fromNodes[id]; !ok {
+			d.AddedNodes = append(d.AddedNodes, id)
+		}
+	}
+	for id, before := range fromNodes {
+		after, ok := toNodes[id]
+		if !ok {
+			d.RemovedNodes = append(d.RemovedNodes, id)
+			continue
+		}
+		if fields := changedKeys(before, after); len(fields) > 0 {
+			d.ChangedNodes = append(d.ChangedNodes, NodeChange{NodeID: id, Fields: fields})
+		}
+	}
+	sort.Strings(d.AddedNodes)
+	sort.Strings(d.RemovedNodes)
+	sort.Slice(d.ChangedNodes, func(i, j int) bool { return d.ChangedNodes[i].NodeID < d.ChangedNodes[j].NodeID })
+
+	d.AddedConditions, d.RemovedConditions, d.ChangedConditions = diffConditions(from.Conditions, to.Conditions)
+	return d
+}
+
+// rawNodes returns each node's JSON attributes keyed by node ID, with the
+// owning world recorded under "world".
+func rawNodes(m *Manager) map[string]map[string]any {
+	out := make(map[string]map[string]any, len(m.Nodes))
+	var doc struct {
+		Worlds []struct {
+			ID    string           `json:"id"`
+			Nodes []map[string]any `json:"nodes"`
+		} `json:"worlds"`
+	}
+	if len(m.Raw) > 0 && json.Unmarshal(m.Raw, &doc) == nil {
+		for _, w := range doc.Worlds {
+			for _, n := range w.Nodes {
+				id, _ := n["id"].(string)
+				n["world"] = w.ID
+				out[id] = n
+			}
+		}
+		return out
+	}
+	// Managers built in code have no raw JSON; fall back to the modelled fields
+	for id, n := range m.Nodes {
+		var attrs map[string]any
+		b, _ := json.Marshal(n)
+		_ = json.Unmarshal(b, &attrs)
+		attrs["world"] = m.NodeWorlds[id]
+		out[id] = attrs
+	}
+	return out
+}
+
+func changedKeys(before, after map[string]any) []string {
+	var keys []string
+	for k, v := range before {
+		if w, ok := after[k]; !ok || !reflect.DeepEqual(v, w) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffConditions(from, to map[string]Condition) (added, removed, changed []string) {
+	added, removed, changed = []string{}, []string{}, []string{}
+	for id := range to {
+		if _, ok := from[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	for id, c := range from {
+		n, ok := to[id]
+		if !ok {
+			removed = append(removed, id)
+		} else if c.Expr != n.Expr {
+			changed = append(changed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
@@ -0,0 +1,59 @@
+package playbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	from, err := Parse("v1", []byte(`{
+		"version": "1.0.0",
+		"conditions": [{"id": "rp_required", "expr": "profile.years_since_graduation > 3"}, {"id": "old", "expr": "true"}],
+		"worlds": [
+			{"id": "W1", "nodes": [
+				{"id": "S1", "type": "form", "next": ["S2"]},
+				{"id": "S2", "type": "info"},
+				{"id": "S3", "type": "info", "title": {"en": "Old"}}
+			]}
+		]}`))
+	require.NoError(t, err)
+	to, err := Parse("v2", []byte(`{
+		"version": "1.1.0",
+		"conditions": [{"id": "rp_required", "expr": "profile.years_since_graduation >= 3"}, {"id": "new", "expr": "false"}],
+		"worlds": [
+			{"id": "W1", "nodes": [
+				{"id": "S1", "type": "form", "next": ["S4"]},
+				{"id": "S4", "type": "info"}
+			]},
+			{"id": "W2", "nodes": [
+				{"id": "S3", "type": "info", "title": {"en": "Old"}}
+			]}
+		]}`))
+	require.NoError(t, err)
+
+	d := Diff(from, to)
+	assert.Equal(t, "v1", d.FromVersionID)
+	assert.Equal(t, "1.1.0", d.ToVersion)
+	assert.Equal(t, []string{"S4"}, d.AddedNodes)
+	assert.Equal(t, []string{"S2"}, d.RemovedNodes)
+	assert.Equal(t, []NodeChange{
+		{NodeID: "S1", Fields: []string{"next"}},
+		{NodeID: "S3", Fields: []string{"world"}},
+	}, d.ChangedNodes)
+	assert.Equal(t, []string{"new"}, d.AddedConditions)
+	assert.Equal(t, []string{"old"}, d.RemovedConditions)
+	assert.Equal(t, []string{"rp_required"}, d.ChangedConditions)
+	assert.False(t, d.Empty())
+
+	assert.True(t, Diff(from, from).Empty())
+}
+
+func TestDiff_ManagersWithoutRaw(t *testing.T) {
+	from := &Manager{Nodes: map[string]Node{"A": {ID: "A", Next: []string{"B"}}}, NodeWorlds: map[string]string{"A": "W1"}}
+	to := &Manager{Nodes: map[string]Node{"A": {ID: "A", Next: []string{"C"}}}, NodeWorlds: map[string]string{"A": "W1"}}
+
+	d := Diff(from, to)
+	assert.Equal(t, []NodeChange{{NodeID: "A", Fields: []string{"next"}}}, d.ChangedNodes)
+}
@@ -0,0 +1,456 @@
+package playbook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits that keep playbook-authored expressions cheap to evaluate.
+const (
+	maxExprLength = 2048
+	maxExprDepth  = 32
+)
+
+// Env is the data an expression is evaluated against. Top-level keys are
+// namespaces such as "profile" and "form"; dotted paths in an expression
+// walk nested maps (e.g. "profile.years_since_graduation").
+type Env map[string]any
+
+// Expr is a compiled playbook expression such as
+// "profile.years_since_graduation > 3" or
+// "form.remarks_exist == true && form.plan_prepared != true".
+//
+// The grammar is deliberately tiny: dotted identifiers, number/string/bool/null
+// literals, comparisons (== != > >= < <=), && || ! and parentheses. There are no
+// function calls, assignments or index expressions, so an expression can only
+// read the Env it is given.
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// CompileExpr parses src into an Expr.
+func CompileExpr(src string) (*Expr, error) {
+	if len(src) > maxExprLength {
+		return nil, fmt.Errorf("expression too long (%d > %d bytes)", len(src), maxExprLength)
+	}
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %w", src, err)
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("parse %q: unexpected %q", src, p.peek().text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source the expression was compiled from.
+func (e *Expr) String() string { return e.src }
+
+// Eval evaluates the expression and returns its raw value.
+func (e *Expr) Eval(env Env) any {
+	return e.root.eval(env)
+}
+
+// EvalBool evaluates the expression and reports whether the result is truthy.
+func (e *Expr) EvalBool(env Env) bool {
+	return truthy(e.root.eval(env))
+}
+
+// EvalBool compiles and evaluates src in one step.
+func EvalBool(src string, env Env) (bool, error) {
+	e, err := CompileExpr(src)
+	if err != nil {
+		return false, err
+	}
+	return e.EvalBool(env), nil
+}
+
+// --- lexer ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+}
+
+func lexExpr(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")"})
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			toks = append(toks, token{tokString, sb.String()})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j]})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && (isIdentStart(src[j]) || src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, cand := range []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "!", "-"} {
+				if strings.HasPrefix(src[i:], cand) {
+					op = cand
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, token{tokOp, op})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// --- parser ---
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) peek() token { return p.toks[p.pos] }
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr(depth int) (exprNode, error) {
+	if depth > maxExprDepth {
+		return nil, fmt.Errorf("expression nested too deeply")
+	}
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd(depth int) (exprNode, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary(depth int) (exprNode, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		if depth+1 > maxExprDepth {
+			return nil, fmt.Errorf("expression nested too deeply")
+		}
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseCompare(depth)
+}
+
+func (p *exprParser) parseCompare(depth int) (exprNode, error) {
+	left, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp("==", "!=", ">=", "<=", ">", "<")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parsePrimary(depth int) (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return inner, nil
+	case tokNumber:
+		return parseNumber(t.text, false)
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		}
+		parts := strings.Split(t.text, ".")
+		for _, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("invalid identifier %q", t.text)
+			}
+		}
+		return &pathNode{parts: parts}, nil
+	case tokOp:
+		if t.text == "-" && p.peek().kind == tokNumber {
+			return parseNumber(p.next().text, true)
+		}
+		return nil, fmt.Errorf("unexpected operator %q", t.text)
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func parseNumber(text string, negative bool) (exprNode, error) {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", text)
+	}
+	if negative {
+		f = -f
+	}
+	return &literalNode{value: f}, nil
+}
+
+// --- evaluation ---
+
+type exprNode interface {
+	eval(env Env) any
+}
+
+type literalNode struct{ value any }
+
+func (n *literalNode) eval(Env) any { return n.value }
+
+type pathNode struct{ parts []string }
+
+func (n *pathNode) eval(env Env) any {
+	var cur any = map[string]any(env)
+	for _, part := range n.parts {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			if e, isEnv := cur.(Env); isEnv {
+				m = e
+			} else {
+				return nil
+			}
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+type notNode struct{ operand exprNode }
+
+func (n *notNode) eval(env Env) any { return !truthy(n.operand.eval(env)) }
+
+type logicalNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *logicalNode) eval(env Env) any {
+	l := truthy(n.left.eval(env))
+	if n.op == "&&" {
+		return l && truthy(n.right.eval(env))
+	}
+	return l || truthy(n.right.eval(env))
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(env Env) any {
+	l, r := n.left.eval(env), n.right.eval(env)
+	switch n.op {
+	case "==":
+		return looseEqual(l, r)
+	case "!=":
+		return !looseEqual(l, r)
+	}
+	if lf, ok := toNumber(l); ok {
+		if rf, ok := toNumber(r); ok {
+			switch n.op {
+			case ">":
+				return lf > rf
+			case ">=":
+				return lf >= rf
+			case "<":
+				return lf < rf
+			case "<=":
+				return lf <= rf
+			}
+		}
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		switch n.op {
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		}
+	}
+	// Ordering against missing or incompatible values is never satisfied.
+	return false
+}
+
+func looseEqual(l, r any) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if lf, ok := toNumber(l); ok {
+		if rf, ok := toNumber(r); ok {
+			return lf == rf
+		}
+	}
+	switch lv := l.(type) {
+	case bool:
+		rv, ok := r.(bool)
+		return ok && lv == rv
+	case string:
+		rv, ok := r.(string)
+		return ok && lv == rv
+	}
+	return false
+}
+
+// toNumber converts JSON numbers and numeric strings (form inputs often
+// arrive as strings) to float64.
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func truthy(v any) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	case string:
+		return b != ""
+	case float64:
+		return b != 0
+	case int:
+		return b != 0
+	}
+	return true
+}
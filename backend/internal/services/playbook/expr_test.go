@@ -0,0 +1,67 @@
+package playbook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalBool_PlaybookExpressions(t *testing.T) {
+	hearing := "(form.remarks_exist == false) || (form.remarks_exist == true && form.plan_prepared == true && form.remarks_resolved == true)"
+
+	tests := []struct {
+		name string
+		expr string
+		env  Env
+		want bool
+	}{
+		{"rp required", "profile.years_since_graduation > 3", Env{"profile": map[string]any{"years_since_graduation": 4.5}}, true},
+		{"rp not required", "profile.years_since_graduation > 3", Env{"profile": map[string]any{"years_since_graduation": 3.0}}, false},
+		{"numeric string", "profile.years_since_graduation > 3", Env{"profile": map[string]any{"years_since_graduation": "5"}}, true},
+		{"missing value", "profile.years_since_graduation > 3", Env{}, false},
+		{"no remarks", hearing, Env{"form": map[string]any{"remarks_exist": false}}, true},
+		{"remarks resolved", hearing, Env{"form": map[string]any{"remarks_exist": true, "plan_prepared": true, "remarks_resolved": true}}, true},
+		{"remarks pending", hearing, Env{"form": map[string]any{"remarks_exist": true, "plan_prepared": true}}, false},
+		{"not equal missing", "form.plan_prepared != true", Env{"form": map[string]any{}}, true},
+		{"negation", "!form.flag", Env{"form": map[string]any{"flag": false}}, true},
+		{"string literal", "form.kind == 'series'", Env{"form": map[string]any{"kind": "series"}}, true},
+		{"null literal", "form.kind == null", Env{"form": map[string]any{}}, true},
+		{"negative number", "form.delta >= -1", Env{"form": map[string]any{"delta": -1.0}}, true},
+		{"bare truthy path", "form.flag", Env{"form": map[string]any{"flag": true}}, true},
+		{"path through scalar", "form.flag.deeper == true", Env{"form": map[string]any{"flag": true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalBool(tt.expr, tt.env)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompileExpr_Errors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"form.a ==",
+		"(form.a == true",
+		"form.a == true)",
+		"form.a = true",
+		"'unterminated",
+		"form..a",
+		"len(form.a) > 1",
+		strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40),
+		strings.Repeat("a", maxExprLength+1),
+	} {
+		_, err := CompileExpr(src)
+		assert.Error(t, err, "expected error for %q", src)
+	}
+}
+
+func TestExpr_Eval(t *testing.T) {
+	e, err := CompileExpr("profile.years_since_graduation")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, e.Eval(Env{"profile": map[string]any{"years_since_graduation": 2.0}}))
+	assert.Equal(t, "profile.years_since_graduation", e.String())
+}
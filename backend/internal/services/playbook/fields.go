@@ -0,0 +1,208 @@
+package playbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Field types understood by the validator. Fields without a type are text.
+const (
+	FieldText     = "text"
+	FieldTextarea = "textarea"
+	FieldNumber   = "number"
+	FieldNote     = "note"
+	FieldBoolean  = "boolean"
+	FieldDate     = "date"
+	FieldSelect   = "select"
+	FieldArray    = "array"
+)
+
+// FieldRequirement describes one form field declared in a node's
+// requirements.fields list.
+type FieldRequirement struct {
+	Key         string            `json:"key"`
+	Type        string            `json:"type,omitempty"`
+	Required    bool              `json:"required,omitempty"`
+	Label       map[string]string `json:"label,omitempty"`
+	VisibleWhen string            `json:"visible_when,omitempty"`
+	OtherKey    string            `json:"other_key,omitempty"`
+	// Options is a list of {value, label} for selects and a label map for
+	// booleans, so it is kept raw and only decoded where it matters.
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// FieldError is a validation failure for a single form field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Field error codes.
+const (
+	FieldErrRequired = "required"
+	FieldErrType     = "invalid_type"
+	FieldErrOption   = "invalid_option"
+)
+
+// FieldType returns the effective type of the field.
+func (f FieldRequirement) FieldType() string {
+	if f.Type == "" {
+		return FieldText
+	}
+	return f.Type
+}
+
+func (f FieldRequirement) otherKey() string {
+	if f.OtherKey != "" {
+		return f.OtherKey
+	}
+	return f.Key + "_other"
+}
+
+func (f FieldRequirement) optionValues() []string {
+	var opts []struct {
+		Value string `json:"value"`
+	}
+	if len(f.Options) == 0 || json.Unmarshal(f.Options, &opts) != nil {
+		return nil
+	}
+	values := make([]string, 0, len(opts))
+	for _, o := range opts {
+		values = append(values, o.Value)
+	}
+	return values
+}
+
+// ValidateFields checks the form in env["form"] against the node's declared
+// fields. Values that are present must match the field type; when complete is
+// true, required fields must also be filled in (required checkboxes must be
+// ticked). Fields hidden by visible_when and note fields are skipped.
+func (m *Manager) ValidateFields(nodeID string, env Env, complete bool) ([]FieldError, error) {
+	n, ok := m.Nodes[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("node %s not found in playbook", nodeID)
+	}
+	if n.Requirements == nil || len(n.Requirements.Fields) == 0 {
+		return nil, nil
+	}
+	form, _ := env["form"].(map[string]any)
+
+	var errs []FieldError
+	for _, f := range n.Requirements.Fields {
+		if f.FieldType() == FieldNote {
+			continue
+		}
+		if f.VisibleWhen != "" {
+			visible, err := EvalBool(f.VisibleWhen, env)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Key, err)
+			}
+			if !visible {
+				continue
+			}
+		}
+		value, present := form[f.Key]
+		if present && value != nil {
+			if fe := checkFieldValue(f, value); fe != nil {
+				errs = append(errs, *fe)
+				continue
+			}
+		}
+		if !complete {
+			continue
+		}
+		if f.Required && isEmptyFieldValue(f, value) {
+			errs = append(errs, FieldError{Field: f.Key, Code: FieldErrRequired, Message: fmt.Sprintf("%s is required", f.Key)})
+			continue
+		}
+		if f.FieldType() == FieldSelect && value == "other" {
+			ok := f.otherKey()
+			if isEmptyFieldValue(FieldRequirement{Type: FieldText}, form[ok]) {
+				errs = append(errs, FieldError{Field: ok, Code: FieldErrRequired, Message: fmt.Sprintf("%s is required when %s is \"other\"", ok, f.Key)})
+			}
+		}
+	}
+	return errs, nil
+}
+
+func checkFieldValue(f FieldRequirement, value any) *FieldError {
+	typeErr := func(want string) *FieldError {
+		return &FieldError{Field: f.Key, Code: FieldErrType, Message: fmt.Sprintf("%s must be %s", f.Key, want)}
+	}
+	switch f.FieldType() {
+	case FieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return typeErr("a boolean")
+		}
+	case FieldArray:
+		// The UI edits arrays as one entry per line of a textarea.
+		switch value.(type) {
+		case []any, string:
+		default:
+			return typeErr("a list")
+		}
+	case FieldNumber:
+		if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+			return nil
+		}
+		if _, ok := toNumber(value); !ok {
+			return typeErr("a number")
+		}
+	case FieldDate:
+		s, ok := value.(string)
+		if !ok {
+			return typeErr("a date")
+		}
+		if strings.TrimSpace(s) == "" {
+			return nil
+		}
+		if _, ok := parseProfileDate(s); !ok {
+			return typeErr("a date")
+		}
+	case FieldSelect:
+		s, ok := value.(string)
+		if !ok {
+			return typeErr("a string")
+		}
+		values := f.optionValues()
+		if s == "" || len(values) == 0 {
+			return nil
+		}
+		for _, v := range values {
+			if v == s {
+				return nil
+			}
+		}
+		return &FieldError{Field: f.Key, Code: FieldErrOption, Message: fmt.Sprintf("%s must be one of %s", f.Key, strings.Join(values, ", "))}
+	case FieldText, FieldTextarea:
+		switch value.(type) {
+		case string, float64:
+		default:
+			return typeErr("a string")
+		}
+	}
+	return nil
+}
+
+func isEmptyFieldValue(f FieldRequirement, value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		// A required checkbox only counts once it is ticked; a yes/no question
+		// (a boolean with options) is answered either way.
+		return f.FieldType() == FieldBoolean && len(f.Options) == 0 && !v
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); !ok || strings.TrimSpace(s) != "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package playbook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldsManager(t *testing.T) *Manager {
+	t.Helper()
+	pb := Playbook{
+		Worlds: []World{{
+			ID: "W1",
+			Nodes: []Node{
+				{ID: "F1", Requirements: &Requirements{Fields: []FieldRequirement{
+					{Key: "full_name", Required: true},
+					{Key: "graduation_date", Type: FieldDate, Required: true},
+					{Key: "confirmed", Type: FieldBoolean, Required: true},
+					{Key: "optional_flag", Type: FieldBoolean},
+					{Key: "form_kind", Type: FieldSelect, Options: json.RawMessage(`[{"value":"classic"},{"value":"other"}]`)},
+					{Key: "details", Required: true, VisibleWhen: "form.optional_flag == true"},
+					{Key: "intro", Type: FieldNote, Required: true},
+				}}},
+				{ID: "F2"},
+			},
+		}},
+	}
+	mgr, err := newManager("v1", "1.0.0", "sum", nil, pb)
+	require.NoError(t, err)
+	return mgr
+}
+
+func fieldCodes(errs []FieldError) map[string]string {
+	out := make(map[string]string, len(errs))
+	for _, e := range errs {
+		out[e.Field] = e.Code
+	}
+	return out
+}
+
+func TestManager_ValidateFields(t *testing.T) {
+	mgr := fieldsManager(t)
+
+	t.Run("complete form passes", func(t *testing.T) {
+		form := map[string]any{"full_name": "A B", "graduation_date": "2020-06-30", "confirmed": true}
+		errs, err := mgr.ValidateFields("F1", NewEnv(nil, form), true)
+		require.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("drafts skip required checks", func(t *testing.T) {
+		errs, err := mgr.ValidateFields("F1", NewEnv(nil, map[string]any{}), false)
+		require.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("missing required fields", func(t *testing.T) {
+		form := map[string]any{"full_name": "  ", "confirmed": false}
+		errs, err := mgr.ValidateFields("F1", NewEnv(nil, form), true)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"full_name":       FieldErrRequired,
+			"graduation_date": FieldErrRequired,
+			"confirmed":       FieldErrRequired,
+		}, fieldCodes(errs))
+	})
+
+	t.Run("type errors are reported for drafts too", func(t *testing.T) {
+		form := map[string]any{"confirmed": "yes", "graduation_date": "someday", "form_kind": "poster"}
+		errs, err := mgr.ValidateFields("F1", NewEnv(nil, form), false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"confirmed":       FieldErrType,
+			"graduation_date": FieldErrType,
+			"form_kind":       FieldErrOption,
+		}, fieldCodes(errs))
+	})
+
+	t.Run("visible_when makes hidden fields optional", func(t *testing.T) {
+		form := map[string]any{"full_name": "A B", "graduation_date": "2020-06-30", "confirmed": true, "optional_flag": true}
+		errs, err := mgr.ValidateFields("F1", NewEnv(nil, form), true)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"details": FieldErrRequired}, fieldCodes(errs))
+	})
+
+	t.Run("other option needs its text", func(t *testing.T) {
+		form := map[string]any{"full_name": "A B", "graduation_date": "2020-06-30", "confirmed": true, "form_kind": "other"}
+		errs, err := mgr.ValidateFields("F1", NewEnv(nil, form), true)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"form_kind_other": FieldErrRequired}, fieldCodes(errs))
+	})
+
+	t.Run("nodes without fields", func(t *testing.T) {
+		errs, err := mgr.ValidateFields("F2", NewEnv(nil, nil), true)
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+
+		_, err = mgr.ValidateFields("missing", NewEnv(nil, nil), true)
+		assert.Error(t, err)
+	})
+}
+
+func TestShippedPlaybook_LibraryDepositsRequireChecklist(t *testing.T) {
+	_, b, _, _ := runtime.Caller(0)
+	raw, err := os.ReadFile(filepath.Join(filepath.Dir(b), "../../../playbooks/playbook.json"))
+	require.NoError(t, err)
+
+	var pb Playbook
+	require.NoError(t, json.Unmarshal(raw, &pb))
+	mgr, err := newManager("v1", pb.Version, "sum", raw, pb)
+	require.NoError(t, err)
+
+	errs, err := mgr.ValidateFields("VI2_library_deposits", NewEnv(nil, map[string]any{}), true)
+	require.NoError(t, err)
+	codes := fieldCodes(errs)
+	assert.Equal(t, FieldErrRequired, codes["chk_print_5_hardbound"])
+	assert.NotContains(t, codes, "chk_salem_letters_secretary")
+
+	// E3's follow-up questions only apply once the hearing has happened
+	errs, err = mgr.ValidateFields("E3_hearing_nk", NewEnv(nil, map[string]any{"hearing_happened": false}), true)
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
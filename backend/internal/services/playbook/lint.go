@@ -0,0 +1,370 @@
+package playbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lint severities. Errors make a playbook unusable and block activation;
+// warnings point at likely mistakes.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Lint issue codes.
+const (
+	LintInvalidJSON      = "invalid_json"
+	LintMissingID        = "missing_id"
+	LintDuplicateNode    = "duplicate_node"
+	LintUnknownNext      = "unknown_next"
+	LintUnknownPrereq    = "unknown_prerequisite"
+	LintUnknownCondition = "unknown_condition"
+	LintDuplicateCond    = "duplicate_condition"
+	LintInvalidExpr      = "invalid_expression"
+	LintPrereqCycle      = "prerequisite_cycle"
+	LintDuplicateUpload  = "duplicate_upload_key"
+	LintDuplicateField   = "duplicate_field_key"
+	LintUnreachable      = "unreachable_node"
+	LintNextCycle        = "next_cycle"
+	LintDeadEnd          = "dead_end"
+	LintMissingLabel     = "missing_label"
+	LintInvalidDeadline  = "invalid_deadline"
+)
+
+// EndNode is the pseudo node id a "next" list uses to mark the end of the journey.
+const EndNode = "END"
+
+// LintIssue is a single finding of the playbook linter.
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	NodeID   string `json:"node_id,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintReport is the machine-readable result of linting a playbook.
+type LintReport struct {
+	Version  string      `json:"version,omitempty"`
+	Nodes    int         `json:"nodes"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// HasErrors reports whether the playbook has fatal issues.
+func (r LintReport) HasErrors() bool { return r.Errors > 0 }
+
+func (r *LintReport) add(severity, code, nodeID, path, format string, args ...any) {
+	r.Issues = append(r.Issues, LintIssue{Severity: severity, Code: code, NodeID: nodeID, Path: path, Message: fmt.Sprintf(format, args...)})
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// LintError is returned when a playbook with fatal lint issues is loaded.
+type LintError struct {
+	Report LintReport
+}
+
+func (e *LintError) Error() string {
+	var msgs []string
+	for _, is := range e.Report.Issues {
+		if is.Severity == SeverityError {
+			msgs = append(msgs, is.Message)
+		}
+	}
+	return fmt.Sprintf("playbook has %d lint error(s): %s", e.Report.Errors, strings.Join(msgs, "; "))
+}
+
+// Lint statically checks playbook JSON: broken next/prerequisite references,
+// unknown or invalid conditions, prerequisite cycles, duplicate ids or keys and
+// malformed deadline rules are errors; unreachable nodes, next cycles, dead
+// ends and missing locale labels are warnings.
+func Lint(raw []byte) LintReport {
+	rep := LintReport{Issues: []LintIssue{}}
+	var pb Playbook
+	if err := json.Unmarshal(raw, &pb); err != nil {
+		rep.add(SeverityError, LintInvalidJSON, "", "", "parse playbook: %v", err)
+		return rep
+	}
+	rep.Version = pb.Version
+
+	conds := map[string]bool{}
+	for i, c := range pb.Conditions {
+		path := fmt.Sprintf("conditions[%d]", i)
+		if conds[c.ID] {
+			rep.add(SeverityError, LintDuplicateCond, "", path, "condition %s is defined more than once", c.ID)
+		}
+		conds[c.ID] = true
+		if _, err := CompileExpr(c.Expr); err != nil {
+			rep.add(SeverityError, LintInvalidExpr, "", path, "condition %s: %v", c.ID, err)
+		}
+	}
+
+	nodes := map[string]Node{}
+	var order []string
+	paths := map[string]string{}
+	for wi, w := range pb.Worlds {
+		for ni, n := range w.Nodes {
+			path := fmt.Sprintf("worlds[%d].nodes[%d]", wi, ni)
+			if n.ID == "" {
+				rep.add(SeverityError, LintMissingID, "", path, "node in world %s has no id", w.ID)
+				continue
+			}
+			if _, dup := nodes[n.ID]; dup {
+				rep.add(SeverityError, LintDuplicateNode, n.ID, path, "node %s is defined more than once", n.ID)
+				continue
+			}
+			nodes[n.ID] = n
+			paths[n.ID] = path
+			order = append(order, n.ID)
+		}
+	}
+	rep.Nodes = len(nodes)
+
+	locales := playbookLocales(pb, nodes)
+	for _, id := range order {
+		n, path := nodes[id], paths[id]
+		for _, next := range n.Next {
+			if _, ok := nodes[next]; !ok && next != EndNode {
+				rep.add(SeverityError, LintUnknownNext, id, path+".next", "%s: next node %s does not exist", id, next)
+			}
+		}
+		for _, pre := range n.Prerequisites {
+			if _, ok := nodes[pre]; !ok {
+				rep.add(SeverityError, LintUnknownPrereq, id, path+".prerequisites", "%s: prerequisite %s does not exist", id, pre)
+			}
+		}
+		if n.Condition != "" && !conds[n.Condition] {
+			rep.add(SeverityError, LintUnknownCondition, id, path+".condition", "%s: condition %s is not defined", id, n.Condition)
+		}
+		if n.Conditional && n.Condition == "" {
+			rep.add(SeverityError, LintUnknownCondition, id, path+".conditional", "%s: conditional stage has no condition", id)
+		}
+		for oi, o := range n.Outcomes {
+			opath := fmt.Sprintf("%s.outcomes[%d]", path, oi)
+			for _, next := range o.Next {
+				if _, ok := nodes[next]; !ok && next != EndNode {
+					rep.add(SeverityError, LintUnknownNext, id, opath+".next", "%s: outcome %s routes to unknown node %s", id, o.Value, next)
+				}
+			}
+			if o.Condition != "" && !conds[o.Condition] {
+				rep.add(SeverityError, LintUnknownCondition, id, opath+".condition", "%s: outcome %s uses undefined condition %s", id, o.Value, o.Condition)
+			}
+			if o.When != "" {
+				if _, err := CompileExpr(o.When); err != nil {
+					rep.add(SeverityError, LintInvalidExpr, id, opath+".when", "%s: outcome %s: %v", id, o.Value, err)
+				}
+			}
+		}
+		if n.Deadline != nil {
+			if err := n.Deadline.Validate(); err != nil {
+				rep.add(SeverityError, LintInvalidDeadline, id, path+".deadline", "%s: %v", id, err)
+			}
+		}
+		lintRequirements(&rep, n, path, locales)
+		if missing := missingLocales(n.Title, locales); len(missing) > 0 {
+			rep.add(SeverityWarning, LintMissingLabel, id, path+".title", "%s: title is missing locale(s) %s", id, strings.Join(missing, ", "))
+		}
+	}
+
+	for _, cycle := range prerequisiteCycles(nodes, order) {
+		rep.add(SeverityError, LintPrereqCycle, cycle[0], paths[cycle[0]]+".prerequisites", "prerequisite cycle: %s", strings.Join(cycle, " -> "))
+	}
+	lintGraph(&rep, nodes, order, paths)
+	return rep
+}
+
+func lintRequirements(rep *LintReport, n Node, path string, locales []string) {
+	if n.Requirements == nil {
+		return
+	}
+	uploads := map[string]bool{}
+	for i, u := range n.Requirements.Uploads {
+		upath := fmt.Sprintf("%s.requirements.uploads[%d]", path, i)
+		if uploads[u.Key] {
+			rep.add(SeverityError, LintDuplicateUpload, n.ID, upath, "%s: upload key %s is used more than once", n.ID, u.Key)
+		}
+		uploads[u.Key] = true
+		if missing := missingLocales(u.Label, locales); len(missing) > 0 {
+			rep.add(SeverityWarning, LintMissingLabel, n.ID, upath+".label", "%s: upload %s label is missing locale(s) %s", n.ID, u.Key, strings.Join(missing, ", "))
+		}
+	}
+	fields := map[string]bool{}
+	for i, f := range n.Requirements.Fields {
+		fpath := fmt.Sprintf("%s.requirements.fields[%d]", path, i)
+		if fields[f.Key] {
+			rep.add(SeverityError, LintDuplicateField, n.ID, fpath, "%s: field key %s is used more than once", n.ID, f.Key)
+		}
+		fields[f.Key] = true
+		if f.VisibleWhen != "" {
+			if _, err := CompileExpr(f.VisibleWhen); err != nil {
+				rep.add(SeverityError, LintInvalidExpr, n.ID, fpath+".visible_when", "%s: field %s: %v", n.ID, f.Key, err)
+			}
+		}
+		if len(f.Label) == 0 {
+			continue
+		}
+		if missing := missingLocales(f.Label, locales); len(missing) > 0 {
+			rep.add(SeverityWarning, LintMissingLabel, n.ID, fpath+".label", "%s: field %s label is missing locale(s) %s", n.ID, f.Key, strings.Join(missing, ", "))
+		}
+	}
+}
+
+// playbookLocales is the default locale plus every locale used in a node title.
+func playbookLocales(pb Playbook, nodes map[string]Node) []string {
+	set := map[string]bool{}
+	if pb.LocaleDefault != "" {
+		set[pb.LocaleDefault] = true
+	}
+	for _, n := range nodes {
+		for loc := range n.Title {
+			set[loc] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for loc := range set {
+		out = append(out, loc)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func missingLocales(label map[string]string, locales []string) []string {
+	var missing []string
+	for _, loc := range locales {
+		if strings.TrimSpace(label[loc]) == "" {
+			missing = append(missing, loc)
+		}
+	}
+	return missing
+}
+
+// prerequisiteCycles returns the cycles of the prerequisite graph. Each cycle
+// starts and ends with the same node and is reported once.
+func prerequisiteCycles(nodes map[string]Node, order []string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var stack []string
+	var cycles [][]string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, pre := range nodes[id].Prerequisites {
+			if _, ok := nodes[pre]; !ok {
+				continue
+			}
+			switch state[pre] {
+			case unvisited:
+				visit(pre)
+			case visiting:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == pre {
+						cycle := append(append([]string{}, stack[i:]...), pre)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+	}
+	for _, id := range order {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+// lintGraph reports nodes that cannot be reached from an entry node (a node
+// without prerequisites), nodes that lead nowhere, and cycles of next links
+// that no outcome routes out of.
+func lintGraph(rep *LintReport, nodes map[string]Node, order []string, paths map[string]string) {
+	edges := map[string][]string{}
+	referenced := map[string]bool{}
+	for _, id := range order {
+		n := nodes[id]
+		targets := append([]string{}, n.Next...)
+		for _, o := range n.Outcomes {
+			targets = append(targets, o.Next...)
+		}
+		for _, t := range targets {
+			if _, ok := nodes[t]; ok {
+				edges[id] = append(edges[id], t)
+				referenced[id] = true
+			} else if t == EndNode {
+				referenced[id] = true
+			}
+		}
+		for _, pre := range n.Prerequisites {
+			if _, ok := nodes[pre]; ok {
+				edges[pre] = append(edges[pre], id)
+				referenced[pre] = true
+			}
+		}
+	}
+
+	reached := map[string]bool{}
+	var queue []string
+	for _, id := range order {
+		if len(nodes[id].Prerequisites) == 0 {
+			reached[id] = true
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, t := range edges[id] {
+			if !reached[t] {
+				reached[t] = true
+				queue = append(queue, t)
+			}
+		}
+	}
+	for _, id := range order {
+		if !reached[id] {
+			rep.add(SeverityWarning, LintUnreachable, id, paths[id], "%s is not reachable from any entry node", id)
+		}
+		if !referenced[id] {
+			rep.add(SeverityWarning, LintDeadEnd, id, paths[id]+".next", "%s has no next node and nothing depends on it; use %q to end the journey", id, EndNode)
+		}
+	}
+
+	// Static next links must not form a loop; loops belong in outcomes, which
+	// the journey guards against.
+	next := map[string][]string{}
+	for _, id := range order {
+		for _, t := range nodes[id].Next {
+			if _, ok := nodes[t]; ok {
+				next[id] = append(next[id], t)
+			}
+		}
+	}
+	for _, cycle := range prerequisiteCycles(reverseAsPrereqs(nodes, next), order) {
+		rep.add(SeverityWarning, LintNextCycle, cycle[0], paths[cycle[0]]+".next", "next links form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+}
+
+// reverseAsPrereqs wraps an adjacency list as nodes whose prerequisites are
+// the edges, so prerequisiteCycles can walk it.
+func reverseAsPrereqs(nodes map[string]Node, adj map[string][]string) map[string]Node {
+	out := make(map[string]Node, len(nodes))
+	for id := range nodes {
+		out[id] = Node{ID: id, Prerequisites: adj[id]}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package playbook
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintCodes(r LintReport, severity string) map[string][]string {
+	out := map[string][]string{}
+	for _, is := range r.Issues {
+		if is.Severity == severity {
+			out[is.Code] = append(out[is.Code], is.NodeID)
+		}
+	}
+	return out
+}
+
+func TestLint_Errors(t *testing.T) {
+	raw := []byte(`{
+		"version": "1",
+		"conditions": [{"id": "c1", "expr": "profile.x > 1"}, {"id": "bad", "expr": "profile.x >"}],
+		"worlds": [{"id": "W1", "nodes": [
+			{"id": "A", "title": {"en": "A"}, "next": ["B", "MISSING"]},
+			{"id": "B", "title": {"en": "B"}, "prerequisites": ["A", "GHOST"], "next": ["END"], "condition": "nope",
+			 "requirements": {"uploads": [{"key": "f", "label": {"en": "F"}}, {"key": "f", "label": {"en": "F"}}],
+			                  "fields": [{"key": "x"}, {"key": "x", "visible_when": "form.y =="}]}},
+			{"id": "A", "title": {"en": "dup"}},
+			{"id": "C", "title": {"en": "C"}, "prerequisites": ["D"], "next": ["D"]},
+			{"id": "D", "title": {"en": "D"}, "prerequisites": ["C"],
+			 "outcomes": [{"value": "ok", "next": ["NOWHERE"], "condition": "unknown"}]}
+		]}]
+	}`)
+
+	r := Lint(raw)
+	assert.True(t, r.HasErrors())
+	assert.Equal(t, "1", r.Version)
+	assert.Equal(t, 4, r.Nodes)
+
+	errs := lintCodes(r, SeverityError)
+	assert.Equal(t, []string{"A"}, errs[LintDuplicateNode])
+	assert.Equal(t, []string{"A", "D"}, errs[LintUnknownNext])
+	assert.Equal(t, []string{"B"}, errs[LintUnknownPrereq])
+	assert.Equal(t, []string{"B", "D"}, errs[LintUnknownCondition])
+	assert.Equal(t, []string{"B"}, errs[LintDuplicateUpload])
+	assert.Equal(t, []string{"B"}, errs[LintDuplicateField])
+	assert.Len(t, errs[LintInvalidExpr], 2)
+	assert.Len(t, errs[LintPrereqCycle], 1)
+
+	var lintErr error = &LintError{Report: r}
+	assert.Contains(t, lintErr.Error(), "A: next node MISSING does not exist")
+}
+
+func TestLint_Warnings(t *testing.T) {
+	raw := []byte(`{
+		"version": "1",
+		"locale_default": "ru",
+		"worlds": [{"id": "W1", "nodes": [
+			{"id": "A", "title": {"ru": "А", "en": "A"}, "next": ["B"]},
+			{"id": "B", "title": {"ru": "Б"}, "prerequisites": ["A"], "next": ["C"]},
+			{"id": "C", "title": {"ru": "В", "en": "C"}, "prerequisites": ["B"], "next": ["B"]},
+			{"id": "ORPHAN", "title": {"ru": "О", "en": "O"}, "prerequisites": ["ORPHAN2"]},
+			{"id": "ORPHAN2", "title": {"ru": "О", "en": "O"}, "prerequisites": ["ORPHAN"]}
+		]}]
+	}`)
+
+	r := Lint(raw)
+	warns := lintCodes(r, SeverityWarning)
+	assert.Equal(t, []string{"B"}, warns[LintMissingLabel])
+	assert.ElementsMatch(t, []string{"ORPHAN", "ORPHAN2"}, warns[LintUnreachable])
+	assert.Len(t, warns[LintNextCycle], 1)
+	assert.Empty(t, warns[LintDeadEnd])
+	assert.Equal(t, r.Warnings, len(r.Issues)-r.Errors)
+}
+
+func TestLint_InvalidJSON(t *testing.T) {
+	r := Lint([]byte(`{"worlds": [`))
+	require.Len(t, r.Issues, 1)
+	assert.Equal(t, LintInvalidJSON, r.Issues[0].Code)
+	assert.Contains(t, r.Issues[0].Message, "parse playbook")
+}
+
+func TestLint_ShippedPlaybookHasNoErrors(t *testing.T) {
+	_, b, _, _ := runtime.Caller(0)
+	raw, err := os.ReadFile(filepath.Join(filepath.Dir(b), "../../../playbooks/playbook.json"))
+	require.NoError(t, err)
+
+	r := Lint(raw)
+	for _, is := range r.Issues {
+		if is.Severity == SeverityError {
+			t.Errorf("%s %s: %s", is.Code, is.Path, is.Message)
+		}
+	}
+}
+
+func TestEnsureActive_RefusesLintErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "playbook.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": "1", "worlds": [{"id": "W1", "nodes": [{"id": "A", "prerequisites": ["B"]}]}]}`), 0644))
+
+	// The playbook is rejected before the database is touched
+	mgr, err := EnsureActive(nil, path)
+	assert.Nil(t, mgr)
+	var lintErr *LintError
+	require.ErrorAs(t, err, &lintErr)
+	assert.Equal(t, 1, lintErr.Report.Errors)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 
 	"github.com/jmoiron/sqlx"
@@ -22,6 +23,7 @@ type UploadRequirement struct {
 
 type Requirements struct {
 	Uploads []UploadRequirement `json:"uploads"`
+	Fields  []FieldRequirement  `json:"fields,omitempty"`
 }
 
 type Node struct {
@@ -31,6 +33,57 @@ type Node struct {
 	Requirements  *Requirements     `json:"requirements"`
 	Prerequisites []string          `json:"prerequisites"`
 	Next          []string          `json:"next"`
+	Condition     string            `json:"condition,omitempty"`
+	// Conditional marks a stage that only applies when Condition holds.
+	// Other nodes use Condition to choose between their next nodes.
+	Conditional bool      `json:"conditional,omitempty"`
+	Outcomes    []Outcome `json:"outcomes,omitempty"`
+	// WhoCanComplete lists the roles allowed to close the node. Nodes that do
+	// not declare it are completed by the student.
+	WhoCanComplete []string `json:"who_can_complete,omitempty"`
+	// Replaces lists node ids from earlier playbook versions that this node
+	// supersedes; the migration planner maps them onto it.
+	Replaces []string `json:"replaces,omitempty"`
+	// Deadline derives the student's due date for the node.
+	Deadline *DeadlineRule `json:"deadline,omitempty"`
+}
+
+// Gate returns the condition the node applies under, or "" when it always
+// applies.
+func (n Node) Gate() string {
+	if !n.Conditional {
+		return ""
+	}
+	return n.Condition
+}
+
+// Completers returns the roles allowed to close the node.
+func (n Node) Completers() []string {
+	if len(n.WhoCanComplete) == 0 {
+		return []string{"student"}
+	}
+	return n.WhoCanComplete
+}
+
+// ClosedByStaff reports whether the node names a role other than the student
+// among those allowed to close it.
+func (n Node) ClosedByStaff() bool {
+	for _, r := range n.Completers() {
+		if r != "student" {
+			return true
+		}
+	}
+	return false
+}
+
+// CanComplete reports whether role may close the node.
+func (n Node) CanComplete(role string) bool {
+	for _, r := range n.Completers() {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 type World struct {
@@ -41,8 +94,9 @@ type World struct {
 type Playbook struct {
 	PlaybookID    string  `json:"playbook_id"`
 	Version       string  `json:"version"`
-	LocaleDefault string  `json:"locale_default"`
-	Worlds        []World `json:"worlds"`
+	LocaleDefault string      `json:"locale_default"`
+	Conditions    []Condition `json:"conditions"`
+	Worlds        []World     `json:"worlds"`
 }
 
 type Manager struct {
@@ -52,6 +106,7 @@ type Manager struct {
 	Raw           json.RawMessage
 	Nodes         map[string]Node
 	NodeWorlds    map[string]string // map[nodeID]worldID
+	Conditions    map[string]Condition
 	DefaultLocale string
 }
 
@@ -68,58 +123,115 @@ func ensureActiveCommon(db *sqlx.DB, path string, tenantID string) (*Manager, er
 	if err != nil {
 		return nil, fmt.Errorf("read playbook: %w", err)
 	}
-	sum := sha256.Sum256(raw)
-	checksum := hex.EncodeToString(sum[:])
+	// Refuse to activate a playbook with broken references, cycles or invalid conditions
+	report := Lint(raw)
+	if report.HasErrors() {
+		return nil, &LintError{Report: report}
+	}
+	if report.Warnings > 0 {
+		log.Printf("[Playbook] %s has %d lint warning(s); run cmd/playbook_lint for details", path, report.Warnings)
+	}
+	checksum := Checksum(raw)
 
 	var versionID string
 	err = db.QueryRowx(`SELECT id FROM playbook_versions WHERE checksum=$1 AND tenant_id=$2`, checksum, tenantID).Scan(&versionID)
 	if err != nil {
-		var pb Playbook
-		if err := json.Unmarshal(raw, &pb); err != nil {
-			return nil, fmt.Errorf("parse playbook: %w", err)
+		mgr, err := Parse("", raw)
+		if err != nil {
+			return nil, err
 		}
 		err = db.QueryRowx(`INSERT INTO playbook_versions (version, checksum, raw_json, tenant_id)
-            VALUES ($1,$2,$3,$4) RETURNING id`, pb.Version, checksum, raw, tenantID).Scan(&versionID)
+            VALUES ($1,$2,$3,$4) RETURNING id`, mgr.Version, checksum, raw, tenantID).Scan(&versionID)
 		if err != nil {
 			return nil, fmt.Errorf("insert playbook version: %w", err)
 		}
-		nodes, nodeWorlds := indexNodes(pb)
-        
-        // Mark as active
-        if err := setActiveVersion(db, versionID, tenantID); err != nil {
-             return nil, err
-        }
+		mgr.VersionID = versionID
+		if pinned, ok := pinnedVersion(db, tenantID); ok && pinned != versionID {
+			return loadVersion(db, pinned)
+		}
 
-		return &Manager{VersionID: versionID, Version: pb.Version, Checksum: checksum, Raw: raw, Nodes: nodes, NodeWorlds: nodeWorlds, DefaultLocale: pb.LocaleDefault}, nil
+		// Mark as active
+		if err := setActiveVersion(db, versionID, tenantID); err != nil {
+			return nil, err
+		}
+		return mgr, nil
+	}
+
+	// Existing version found - ensure it is active unless an admin chose another one
+	if pinned, ok := pinnedVersion(db, tenantID); ok && pinned != versionID {
+		return loadVersion(db, pinned)
 	}
-	
-    // Existing version found - ensure it is active
-    if err := setActiveVersion(db, versionID, tenantID); err != nil {
-         return nil, err
-    }
+	if err := setActiveVersion(db, versionID, tenantID); err != nil {
+		return nil, err
+	}
+	return loadVersion(db, versionID)
+}
+
+// pinnedVersion returns the version an admin activated for the tenant through
+// the playbook API. Such tenants keep it across restarts; the playbook file
+// only seeds tenants without activation history.
+func pinnedVersion(db *sqlx.DB, tenantID string) (string, bool) {
+	var id string
+	err := db.QueryRowx(`SELECT playbook_version_id FROM playbook_activations
+		WHERE tenant_id=$1 AND rolled_back_at IS NULL
+		ORDER BY activated_at DESC LIMIT 1`, tenantID).Scan(&id)
+	if err != nil {
+		return "", false
+	}
+	log.Printf("[Playbook] tenant %s uses admin-activated version %s", tenantID, id)
+	return id, true
+}
 
+func loadVersion(db *sqlx.DB, versionID string) (*Manager, error) {
 	var rawJSON []byte
-	var version string
-	err = db.QueryRowx(`SELECT version, raw_json FROM playbook_versions WHERE id=$1`, versionID).Scan(&version, &rawJSON)
+	err := db.QueryRowx(`SELECT raw_json FROM playbook_versions WHERE id=$1`, versionID).Scan(&rawJSON)
 	if err != nil {
 		return nil, fmt.Errorf("load playbook raw: %w", err)
 	}
+	return Parse(versionID, rawJSON)
+}
+
+// Checksum returns the content hash playbook versions are deduplicated by.
+func Checksum(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Parse builds a Manager from playbook JSON.
+func Parse(versionID string, raw []byte) (*Manager, error) {
 	var pb Playbook
-	if err := json.Unmarshal(rawJSON, &pb); err != nil {
+	if err := json.Unmarshal(raw, &pb); err != nil {
 		return nil, fmt.Errorf("parse playbook: %w", err)
 	}
+	return newManager(versionID, pb.Version, Checksum(raw), raw, pb)
+}
+
+func newManager(versionID, version, checksum string, raw []byte, pb Playbook) (*Manager, error) {
 	nodes, nodeWorlds := indexNodes(pb)
-	return &Manager{VersionID: versionID, Version: version, Checksum: checksum, Raw: rawJSON, Nodes: nodes, NodeWorlds: nodeWorlds, DefaultLocale: pb.LocaleDefault}, nil
+	conditions, err := indexConditions(pb)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		VersionID:     versionID,
+		Version:       version,
+		Checksum:      checksum,
+		Raw:           raw,
+		Nodes:         nodes,
+		NodeWorlds:    nodeWorlds,
+		Conditions:    conditions,
+		DefaultLocale: pb.LocaleDefault,
+	}, nil
 }
 
 func setActiveVersion(db *sqlx.DB, versionID, tenantID string) error {
-	_, err := db.Exec(`INSERT INTO playbook_active_version (id, playbook_version_id, tenant_id)
-        VALUES (TRUE,$1,$2)
-        ON CONFLICT (id) DO UPDATE SET playbook_version_id=EXCLUDED.playbook_version_id, tenant_id=EXCLUDED.tenant_id, updated_at=now()`, versionID, tenantID)
+	_, err := db.Exec(`INSERT INTO playbook_active_version (tenant_id, playbook_version_id)
+        VALUES ($1,$2)
+        ON CONFLICT (tenant_id) DO UPDATE SET playbook_version_id=EXCLUDED.playbook_version_id, updated_at=now()`, tenantID, versionID)
 	if err != nil {
 		return fmt.Errorf("update active playbook: %w", err)
 	}
-    return nil
+	return nil
 }
 
 func indexNodes(pb Playbook) (map[string]Node, map[string]string) {
@@ -203,3 +203,19 @@ func TestNodeWithRequirements(t *testing.T) {
 	assert.Equal(t, "doc1", node.Requirements.Uploads[0].Key)
 	assert.True(t, node.Requirements.Uploads[0].Required)
 }
+
+func TestNodeWhoCanComplete(t *testing.T) {
+	var n Node
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "D3", "who_can_complete": ["secretary", "chair"]}`), &n))
+
+	assert.True(t, n.CanComplete("secretary"))
+	assert.True(t, n.CanComplete("chair"))
+	assert.False(t, n.CanComplete("advisor"))
+	assert.False(t, n.CanComplete("student"))
+
+	// Nodes without the list are completed by the student
+	legacy := Node{ID: "S1"}
+	assert.Equal(t, []string{"student"}, legacy.Completers())
+	assert.True(t, legacy.CanComplete("student"))
+	assert.False(t, legacy.CanComplete("admin"))
+}
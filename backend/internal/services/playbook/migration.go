@@ -0,0 +1,238 @@
+package playbook
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Mapping kinds produced by PlanMigration.
+const (
+	MappingSame     = "same"     // node id exists in both versions
+	MappingRenamed  = "renamed"  // matched through "replaces" or an identical world/type/title
+	MappingOverride = "override" // mapping supplied by an admin
+	MappingRemoved  = "removed"  // no counterpart in the target version
+)
+
+// NodeMapping maps a node of the source version onto the target version.
+// To is empty for removed nodes.
+type NodeMapping struct {
+	From string `json:"from"`
+	To   string `json:"to,omitempty"`
+	Kind string `json:"kind"`
+}
+
+// MigrationPlan is the node mapping between two playbook versions.
+type MigrationPlan struct {
+	FromVersionID string        `json:"from_version_id"`
+	ToVersionID   string        `json:"to_version_id"`
+	Mappings      []NodeMapping `json:"mappings"`
+	// AddedNodes are target nodes no source node maps onto.
+	AddedNodes []string `json:"added_nodes"`
+}
+
+// Target returns the node id a source node maps to and whether it survives.
+func (p MigrationPlan) Target(nodeID string) (string, bool) {
+	for _, m := range p.Mappings {
+		if m.From == nodeID {
+			return m.To, m.To != ""
+		}
+	}
+	return "", false
+}
+
+// PlanMigration proposes how nodes of from map onto to. Overrides (source id
+// to target id, or "" to drop the node) take precedence and may merge several
+// source nodes into one target; otherwise nodes keep
+// their id, then follow an explicit "replaces" declaration, and finally
+// unmatched nodes are paired when world, type and title are identical.
+func PlanMigration(from, to *Manager, overrides map[string]string) MigrationPlan {
+	plan := MigrationPlan{FromVersionID: from.VersionID, ToVersionID: to.VersionID, Mappings: []NodeMapping{}, AddedNodes: []string{}}
+
+	mapped := map[string]NodeMapping{}
+	claimed := map[string]bool{}
+	assign := func(src, dst, kind string) {
+		mapped[src] = NodeMapping{From: src, To: dst, Kind: kind}
+		if dst != "" {
+			claimed[dst] = true
+		}
+	}
+
+	for src, dst := range overrides {
+		if _, ok := from.Nodes[src]; !ok {
+			continue
+		}
+		if _, ok := to.Nodes[dst]; !ok && dst != "" {
+			continue
+		}
+		assign(src, dst, MappingOverride)
+	}
+	for _, id := range sortedNodeIDs(from) {
+		if _, done := mapped[id]; done {
+			continue
+		}
+		if _, ok := to.Nodes[id]; ok && !claimed[id] {
+			assign(id, id, MappingSame)
+		}
+	}
+	for _, dst := range sortedNodeIDs(to) {
+		for _, src := range to.Nodes[dst].Replaces {
+			if _, ok := from.Nodes[src]; !ok || claimed[dst] {
+				continue
+			}
+			if _, done := mapped[src]; !done {
+				assign(src, dst, MappingRenamed)
+			}
+		}
+	}
+	for _, src := range sortedNodeIDs(from) {
+		if _, done := mapped[src]; done {
+			continue
+		}
+		for _, dst := range sortedNodeIDs(to) {
+			if claimed[dst] {
+				continue
+			}
+			if _, exists := from.Nodes[dst]; exists {
+				continue
+			}
+			if sameShape(from, src, to, dst) {
+				assign(src, dst, MappingRenamed)
+				break
+			}
+		}
+		if _, done := mapped[src]; !done {
+			assign(src, "", MappingRemoved)
+		}
+	}
+
+	for _, id := range sortedNodeIDs(from) {
+		plan.Mappings = append(plan.Mappings, mapped[id])
+	}
+	for _, id := range sortedNodeIDs(to) {
+		if !claimed[id] {
+			plan.AddedNodes = append(plan.AddedNodes, id)
+		}
+	}
+	return plan
+}
+
+func sameShape(from *Manager, src string, to *Manager, dst string) bool {
+	a, b := from.Nodes[src], to.Nodes[dst]
+	return len(a.Title) > 0 && a.Type == b.Type &&
+		from.NodeWorlds[src] == to.NodeWorlds[dst] &&
+		reflect.DeepEqual(a.Title, b.Title)
+}
+
+func sortedNodeIDs(m *Manager) []string {
+	ids := make([]string, 0, len(m.Nodes))
+	for id := range m.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Instance actions produced by PlanStudent.
+const (
+	InstanceKeep   = "keep"   // same node id, only the version changes
+	InstanceRename = "rename" // moved onto a renamed node
+	InstanceRetire = "retire" // node removed, or its target is already taken
+)
+
+// InstanceRef is the part of a node instance the planner needs.
+type InstanceRef struct {
+	InstanceID string `json:"instance_id"`
+	NodeID     string `json:"node_id"`
+	State      string `json:"state"`
+}
+
+// InstanceAction is what happens to one instance during a migration.
+type InstanceAction struct {
+	InstanceID string `json:"instance_id"`
+	FromNodeID string `json:"from_node_id"`
+	ToNodeID   string `json:"to_node_id,omitempty"`
+	State      string `json:"state"`
+	Action     string `json:"action"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// StudentMigration is the per-student part of a migration.
+type StudentMigration struct {
+	UserID    string           `json:"user_id"`
+	Instances []InstanceAction `json:"instances"`
+	// Activate lists added nodes that become available to the student
+	// because all of their prerequisites are already done.
+	Activate []string `json:"activate"`
+}
+
+// stateRank orders node states by progress so that, when two instances land
+// on the same target node, the one furthest along is kept.
+var stateRank = map[string]int{
+	"locked":      0,
+	"active":      1,
+	"needs_fixes": 2,
+	"waiting":     3,
+	"submitted":   4,
+	"done":        5,
+}
+
+// PlanStudent applies plan to one student's instances of the source version.
+// existing are the student's instances already pinned to the target version;
+// they always win over migrated instances for the same node.
+func (p MigrationPlan) PlanStudent(to *Manager, userID string, instances, existing []InstanceRef) StudentMigration {
+	sm := StudentMigration{UserID: userID, Instances: []InstanceAction{}, Activate: []string{}}
+
+	states := map[string]string{}
+	winners := map[string]InstanceRef{}
+	for _, inst := range existing {
+		winners[inst.NodeID] = inst
+		states[inst.NodeID] = inst.State
+	}
+	for _, inst := range instances {
+		dst, ok := p.Target(inst.NodeID)
+		if !ok {
+			continue
+		}
+		if _, pinned := states[dst]; pinned {
+			continue
+		}
+		if cur, taken := winners[dst]; !taken || stateRank[inst.State] > stateRank[cur.State] {
+			winners[dst] = inst
+		}
+	}
+
+	for _, inst := range instances {
+		act := InstanceAction{InstanceID: inst.InstanceID, FromNodeID: inst.NodeID, State: inst.State}
+		dst, ok := p.Target(inst.NodeID)
+		switch {
+		case !ok:
+			act.Action, act.Reason = InstanceRetire, "node removed"
+		case winners[dst].InstanceID != inst.InstanceID:
+			act.Action, act.ToNodeID, act.Reason = InstanceRetire, dst, "superseded by "+winners[dst].NodeID
+		case dst == inst.NodeID:
+			act.Action, act.ToNodeID = InstanceKeep, dst
+			states[dst] = inst.State
+		default:
+			act.Action, act.ToNodeID = InstanceRename, dst
+			states[dst] = inst.State
+		}
+		sm.Instances = append(sm.Instances, act)
+	}
+
+	for _, id := range p.AddedNodes {
+		if _, has := states[id]; has {
+			continue
+		}
+		ready := true
+		for _, pre := range to.Nodes[id].Prerequisites {
+			if states[pre] != "done" {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			sm.Activate = append(sm.Activate, id)
+		}
+	}
+	return sm
+}
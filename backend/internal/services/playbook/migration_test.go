@@ -0,0 +1,94 @@
+package playbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func migrationManagers(t *testing.T) (*Manager, *Manager) {
+	t.Helper()
+	title := func(s string) map[string]string { return map[string]string{"en": s} }
+	from, err := newManager("v1", "1.0.0", "a", nil, Playbook{Worlds: []World{{
+		ID: "W1",
+		Nodes: []Node{
+			{ID: "S1", Title: title("Profile"), Type: "form"},
+			{ID: "S2_old", Title: title("Advisor"), Type: "form"},
+			{ID: "S3_draft", Title: title("Draft"), Type: "upload"},
+			{ID: "S4", Title: title("Obsolete"), Type: "form"},
+			{ID: "S5", Title: title("Checklist"), Type: "form"},
+		},
+	}}})
+	require.NoError(t, err)
+	to, err := newManager("v2", "2.0.0", "b", nil, Playbook{Worlds: []World{{
+		ID: "W1",
+		Nodes: []Node{
+			{ID: "S1", Title: title("Profile"), Type: "form"},
+			{ID: "S2_advisor", Title: title("Advisor"), Type: "form"},
+			{ID: "S3_manuscript", Title: title("Manuscript"), Type: "upload", Replaces: []string{"S3_draft"}},
+			{ID: "S6_ethics", Title: title("Ethics"), Type: "form", Prerequisites: []string{"S1"}},
+			{ID: "S7_review", Title: title("Review"), Type: "form", Prerequisites: []string{"S3_manuscript"}},
+			{ID: "S8_checklist", Title: title("New checklist"), Type: "form"},
+		},
+	}}})
+	require.NoError(t, err)
+	return from, to
+}
+
+func TestPlanMigration(t *testing.T) {
+	from, to := migrationManagers(t)
+
+	plan := PlanMigration(from, to, nil)
+	assert.Equal(t, "v1", plan.FromVersionID)
+	assert.Equal(t, "v2", plan.ToVersionID)
+	assert.Equal(t, []NodeMapping{
+		{From: "S1", To: "S1", Kind: MappingSame},
+		{From: "S2_old", To: "S2_advisor", Kind: MappingRenamed},
+		{From: "S3_draft", To: "S3_manuscript", Kind: MappingRenamed},
+		{From: "S4", Kind: MappingRemoved},
+		{From: "S5", Kind: MappingRemoved},
+	}, plan.Mappings)
+	assert.Equal(t, []string{"S6_ethics", "S7_review", "S8_checklist"}, plan.AddedNodes)
+
+	// Overrides win; unknown targets are ignored
+	plan = PlanMigration(from, to, map[string]string{"S5": "S8_checklist", "S4": "nope", "S1": ""})
+	target, ok := plan.Target("S5")
+	assert.True(t, ok)
+	assert.Equal(t, "S8_checklist", target)
+	_, ok = plan.Target("S1")
+	assert.False(t, ok)
+	assert.Contains(t, plan.AddedNodes, "S1")
+	assert.NotContains(t, plan.AddedNodes, "S8_checklist")
+}
+
+func TestMigrationPlan_PlanStudent(t *testing.T) {
+	from, to := migrationManagers(t)
+	// Merge the obsolete step into the advisor step
+	plan := PlanMigration(from, to, map[string]string{"S4": "S2_advisor", "S2_old": "S2_advisor"})
+
+	sm := plan.PlanStudent(to, "u1", []InstanceRef{
+		{InstanceID: "i1", NodeID: "S1", State: "done"},
+		{InstanceID: "i2", NodeID: "S2_old", State: "done"},
+		{InstanceID: "i3", NodeID: "S4", State: "active"},
+		{InstanceID: "i4", NodeID: "S3_draft", State: "submitted"},
+		{InstanceID: "i5", NodeID: "S5", State: "active"},
+	}, nil)
+
+	assert.Equal(t, "u1", sm.UserID)
+	assert.Equal(t, []InstanceAction{
+		{InstanceID: "i1", FromNodeID: "S1", ToNodeID: "S1", State: "done", Action: InstanceKeep},
+		{InstanceID: "i2", FromNodeID: "S2_old", ToNodeID: "S2_advisor", State: "done", Action: InstanceRename},
+		{InstanceID: "i3", FromNodeID: "S4", ToNodeID: "S2_advisor", State: "active", Action: InstanceRetire, Reason: "superseded by S2_old"},
+		{InstanceID: "i4", FromNodeID: "S3_draft", ToNodeID: "S3_manuscript", State: "submitted", Action: InstanceRename},
+		{InstanceID: "i5", FromNodeID: "S5", State: "active", Action: InstanceRetire, Reason: "node removed"},
+	}, sm.Instances)
+	// S7 waits for the manuscript to be done
+	assert.Equal(t, []string{"S6_ethics", "S8_checklist"}, sm.Activate)
+
+	// Instances already on the target version take precedence
+	sm = plan.PlanStudent(to, "u2", []InstanceRef{{InstanceID: "i6", NodeID: "S1", State: "done"}},
+		[]InstanceRef{{InstanceID: "i7", NodeID: "S1", State: "active"}})
+	assert.Equal(t, InstanceRetire, sm.Instances[0].Action)
+	assert.NotContains(t, sm.Activate, "S6_ethics")
+}
@@ -0,0 +1,74 @@
+package playbook
+
+import "fmt"
+
+// Outcome is one of the results a node can complete with. The first outcome
+// whose When expression and Condition both hold is selected, and its Next
+// list replaces the node's static Next when the journey advances.
+type Outcome struct {
+	Value     string            `json:"value"`
+	Label     map[string]string `json:"label,omitempty"`
+	When      string            `json:"when,omitempty"`
+	Condition string            `json:"condition,omitempty"`
+	Next      []string          `json:"next,omitempty"`
+}
+
+// LoopsBack reports whether the outcome only routes back to the given node,
+// i.e. the student has more work to do before the node can be completed.
+func (o Outcome) LoopsBack(nodeID string) bool {
+	if len(o.Next) == 0 {
+		return false
+	}
+	for _, n := range o.Next {
+		if n != nodeID {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectOutcome returns the first outcome of the node that matches env, or nil
+// when the node declares no outcomes. It returns an error when outcomes are
+// declared but none of them match.
+func (m *Manager) SelectOutcome(nodeID string, env Env) (*Outcome, error) {
+	n, ok := m.Nodes[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("node %s not found in playbook", nodeID)
+	}
+	if len(n.Outcomes) == 0 {
+		return nil, nil
+	}
+	for i := range n.Outcomes {
+		o := n.Outcomes[i]
+		if o.Condition != "" {
+			ok, err := m.EvaluateCondition(o.Condition, env)
+			if err != nil {
+				return nil, fmt.Errorf("outcome %s: %w", o.Value, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		if o.When != "" {
+			ok, err := EvalBool(o.When, env)
+			if err != nil {
+				return nil, fmt.Errorf("outcome %s: %w", o.Value, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		return &o, nil
+	}
+	return nil, fmt.Errorf("no outcome of node %s matches the submitted form", nodeID)
+}
+
+// OutcomeByValue looks up a declared outcome of a node by its value.
+func (m *Manager) OutcomeByValue(nodeID, value string) (Outcome, bool) {
+	for _, o := range m.Nodes[nodeID].Outcomes {
+		if o.Value == value {
+			return o, true
+		}
+	}
+	return Outcome{}, false
+}
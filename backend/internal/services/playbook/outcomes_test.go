@@ -0,0 +1,88 @@
+package playbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func outcomesManager(t *testing.T) *Manager {
+	t.Helper()
+	resolved := "(form.remarks_exist == false) || (form.remarks_exist == true && form.plan_prepared == true && form.remarks_resolved == true)"
+	pb := Playbook{
+		Conditions: []Condition{{ID: "rp_required", Expr: "profile.years_since_graduation > 3"}},
+		Worlds: []World{{
+			ID: "W2",
+			Nodes: []Node{
+				{ID: "E3", Outcomes: []Outcome{
+					{Value: "proceed_rp", When: resolved, Condition: "rp_required", Next: []string{"RP1"}},
+					{Value: "proceed_direct", When: resolved, Next: []string{"D1"}},
+					{Value: "needs_plan", When: "form.remarks_exist == true && form.plan_prepared != true", Next: []string{"E3"}},
+				}},
+				{ID: "S1", Outcomes: []Outcome{{Value: "done", Next: []string{"S2"}}}},
+				{ID: "S2"},
+			},
+		}},
+	}
+	mgr, err := newManager("v1", "1.0.0", "sum", nil, pb)
+	require.NoError(t, err)
+	return mgr
+}
+
+func TestManager_SelectOutcome(t *testing.T) {
+	mgr := outcomesManager(t)
+
+	tests := []struct {
+		name    string
+		profile map[string]any
+		form    map[string]any
+		want    string
+	}{
+		{"rp student without remarks", map[string]any{"years_since_graduation": 5.0}, map[string]any{"remarks_exist": false}, "proceed_rp"},
+		{"direct student without remarks", map[string]any{"years_since_graduation": 1.0}, map[string]any{"remarks_exist": false}, "proceed_direct"},
+		{"resolved remarks", nil, map[string]any{"remarks_exist": true, "plan_prepared": true, "remarks_resolved": true}, "proceed_direct"},
+		{"missing plan", nil, map[string]any{"remarks_exist": true}, "needs_plan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := mgr.SelectOutcome("E3", NewEnv(tt.profile, tt.form))
+			require.NoError(t, err)
+			require.NotNil(t, o)
+			assert.Equal(t, tt.want, o.Value)
+		})
+	}
+
+	// An outcome without "when" always matches
+	o, err := mgr.SelectOutcome("S1", NewEnv(nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "done", o.Value)
+
+	// Nodes without outcomes select nothing
+	o, err = mgr.SelectOutcome("S2", NewEnv(nil, nil))
+	assert.NoError(t, err)
+	assert.Nil(t, o)
+
+	// Declared outcomes that all fail to match are an error
+	_, err = mgr.SelectOutcome("E3", NewEnv(nil, map[string]any{}))
+	assert.Error(t, err)
+
+	_, err = mgr.SelectOutcome("missing", NewEnv(nil, nil))
+	assert.Error(t, err)
+}
+
+func TestOutcome_LoopsBack(t *testing.T) {
+	assert.True(t, Outcome{Next: []string{"E3"}}.LoopsBack("E3"))
+	assert.False(t, Outcome{Next: []string{"E3", "D1"}}.LoopsBack("E3"))
+	assert.False(t, Outcome{}.LoopsBack("E3"))
+}
+
+func TestManager_OutcomeByValue(t *testing.T) {
+	mgr := outcomesManager(t)
+	o, ok := mgr.OutcomeByValue("E3", "proceed_direct")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"D1"}, o.Next)
+
+	_, ok = mgr.OutcomeByValue("E3", "unknown")
+	assert.False(t, ok)
+}
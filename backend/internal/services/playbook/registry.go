@@ -0,0 +1,137 @@
+package playbook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// VersionStore is the persistence the Registry needs to resolve a tenant's
+// active playbook.
+type VersionStore interface {
+	GetActiveVersionID(ctx context.Context, tenantID string) (string, error)
+	GetVersionJSON(ctx context.Context, versionID string) ([]byte, error)
+}
+
+// DefaultRefreshInterval bounds how long another server instance may keep
+// serving a playbook after it was activated or rolled back elsewhere.
+const DefaultRefreshInterval = 30 * time.Second
+
+type registryEntry struct {
+	mgr       *Manager
+	checkedAt time.Time
+}
+
+// Registry resolves the active playbook Manager per tenant. Managers are
+// cached and re-validated against the store every refresh interval, so new
+// activations take effect without restarting the server. Tenants without an
+// active version use the fallback manager loaded at startup.
+type Registry struct {
+	store    VersionStore
+	fallback *Manager
+	refresh  time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]registryEntry
+}
+
+func NewRegistry(store VersionStore, fallback *Manager) *Registry {
+	return &Registry{
+		store:    store,
+		fallback: fallback,
+		refresh:  DefaultRefreshInterval,
+		entries:  make(map[string]registryEntry),
+	}
+}
+
+// SetRefreshInterval overrides DefaultRefreshInterval.
+func (r *Registry) SetRefreshInterval(d time.Duration) {
+	r.mu.Lock()
+	r.refresh = d
+	r.mu.Unlock()
+}
+
+// Fallback returns the manager used for tenants without an active version.
+func (r *Registry) Fallback() *Manager { return r.fallback }
+
+// ForTenant returns the tenant's active playbook.
+func (r *Registry) ForTenant(ctx context.Context, tenantID string) (*Manager, error) {
+	if tenantID == "" {
+		return r.fallback, nil
+	}
+	r.mu.RLock()
+	entry, ok := r.entries[tenantID]
+	fresh := ok && time.Since(entry.checkedAt) < r.refresh
+	r.mu.RUnlock()
+	if fresh {
+		return entry.mgr, nil
+	}
+
+	versionID, err := r.store.GetActiveVersionID(ctx, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		r.put(tenantID, r.fallback)
+		return r.fallback, nil
+	}
+	if err != nil {
+		if ok {
+			// Keep serving the cached playbook if the store is unavailable
+			log.Printf("[Playbook] refresh for tenant %s failed: %v", tenantID, err)
+			return entry.mgr, nil
+		}
+		return nil, fmt.Errorf("resolve active playbook: %w", err)
+	}
+
+	if ok && entry.mgr != nil && entry.mgr.VersionID == versionID {
+		r.put(tenantID, entry.mgr)
+		return entry.mgr, nil
+	}
+	if r.fallback != nil && r.fallback.VersionID == versionID {
+		r.put(tenantID, r.fallback)
+		return r.fallback, nil
+	}
+	mgr, err := r.load(ctx, versionID)
+	if err != nil {
+		return nil, err
+	}
+	r.put(tenantID, mgr)
+	return mgr, nil
+}
+
+func (r *Registry) load(ctx context.Context, versionID string) (*Manager, error) {
+	raw, err := r.store.GetVersionJSON(ctx, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("load playbook %s: %w", versionID, err)
+	}
+	return Parse(versionID, raw)
+}
+
+// Invalidate drops the cached playbook of a tenant so the next request
+// re-reads its active version.
+func (r *Registry) Invalidate(tenantID string) {
+	r.mu.Lock()
+	delete(r.entries, tenantID)
+	r.mu.Unlock()
+}
+
+func (r *Registry) put(tenantID string, mgr *Manager) {
+	r.mu.Lock()
+	r.entries[tenantID] = registryEntry{mgr: mgr, checkedAt: time.Now()}
+	r.mu.Unlock()
+}
+
+type managerKey struct{}
+
+// WithManager returns a context carrying the playbook resolved for a request.
+func WithManager(ctx context.Context, m *Manager) context.Context {
+	return context.WithValue(ctx, managerKey{}, m)
+}
+
+// FromContext returns the playbook stored by WithManager, if any.
+func FromContext(ctx context.Context) (*Manager, bool) {
+	m, ok := ctx.Value(managerKey{}).(*Manager)
+	return m, ok && m != nil
+}
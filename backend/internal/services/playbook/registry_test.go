@@ -0,0 +1,129 @@
+package playbook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVersionStore struct {
+	active map[string]string
+	raw    map[string][]byte
+	err    error
+	loads  int
+}
+
+func (f *fakeVersionStore) GetActiveVersionID(ctx context.Context, tenantID string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	id, ok := f.active[tenantID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return id, nil
+}
+
+func (f *fakeVersionStore) GetVersionJSON(ctx context.Context, versionID string) ([]byte, error) {
+	f.loads++
+	raw, ok := f.raw[versionID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return raw, nil
+}
+
+func registryPlaybook(version, nodeID string) []byte {
+	return []byte(`{"version": "` + version + `", "worlds": [{"id": "W1", "nodes": [{"id": "` + nodeID + `"}]}]}`)
+}
+
+func TestRegistry_ForTenant(t *testing.T) {
+	fallback, err := Parse("v-default", registryPlaybook("1.0.0", "S1"))
+	require.NoError(t, err)
+	store := &fakeVersionStore{
+		active: map[string]string{"tenant-a": "v-a1"},
+		raw: map[string][]byte{
+			"v-a1": registryPlaybook("2.0.0", "A1"),
+			"v-a2": registryPlaybook("2.1.0", "A2"),
+		},
+	}
+	reg := NewRegistry(store, fallback)
+	ctx := context.Background()
+
+	t.Run("tenant without active version uses fallback", func(t *testing.T) {
+		mgr, err := reg.ForTenant(ctx, "tenant-b")
+		require.NoError(t, err)
+		assert.Same(t, fallback, mgr)
+
+		mgr, err = reg.ForTenant(ctx, "")
+		require.NoError(t, err)
+		assert.Same(t, fallback, mgr)
+	})
+
+	t.Run("tenant version is loaded once and cached", func(t *testing.T) {
+		mgr, err := reg.ForTenant(ctx, "tenant-a")
+		require.NoError(t, err)
+		assert.Equal(t, "v-a1", mgr.VersionID)
+		assert.Contains(t, mgr.Nodes, "A1")
+
+		again, err := reg.ForTenant(ctx, "tenant-a")
+		require.NoError(t, err)
+		assert.Same(t, mgr, again)
+		assert.Equal(t, 1, store.loads)
+	})
+
+	t.Run("activation is picked up after invalidation", func(t *testing.T) {
+		store.active["tenant-a"] = "v-a2"
+		reg.Invalidate("tenant-a")
+		mgr, err := reg.ForTenant(ctx, "tenant-a")
+		require.NoError(t, err)
+		assert.Equal(t, "v-a2", mgr.VersionID)
+	})
+
+	t.Run("activation on another instance is picked up after refresh", func(t *testing.T) {
+		reg.SetRefreshInterval(0)
+		defer reg.SetRefreshInterval(DefaultRefreshInterval)
+		store.active["tenant-a"] = "v-a1"
+		mgr, err := reg.ForTenant(ctx, "tenant-a")
+		require.NoError(t, err)
+		assert.Equal(t, "v-a1", mgr.VersionID)
+
+		// Store outages keep serving the cached playbook
+		store.err = errors.New("connection refused")
+		defer func() { store.err = nil }()
+		mgr, err = reg.ForTenant(ctx, "tenant-a")
+		require.NoError(t, err)
+		assert.Equal(t, "v-a1", mgr.VersionID)
+
+		_, err = reg.ForTenant(ctx, "tenant-c")
+		assert.Error(t, err)
+	})
+}
+
+func TestRegistry_RefreshInterval(t *testing.T) {
+	store := &fakeVersionStore{active: map[string]string{"t": "v1"}, raw: map[string][]byte{"v1": registryPlaybook("1", "N")}}
+	reg := NewRegistry(store, nil)
+	reg.SetRefreshInterval(time.Hour)
+
+	_, err := reg.ForTenant(context.Background(), "t")
+	require.NoError(t, err)
+	store.active["t"] = "v2"
+	mgr, err := reg.ForTenant(context.Background(), "t")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", mgr.VersionID, "cached entry is served until it expires")
+}
+
+func TestManagerContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	mgr := &Manager{VersionID: "v1"}
+	got, ok := FromContext(WithManager(context.Background(), mgr))
+	assert.True(t, ok)
+	assert.Same(t, mgr, got)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+)
+
+var ErrSameMigrationVersion = errors.New("source and target playbook versions are the same")
+
+// MigrationReport describes, per student, what migrating between two
+// playbook versions does. Applied is false for dry runs.
+type MigrationReport struct {
+	Plan     playbook.MigrationPlan      `json:"plan"`
+	Students []playbook.StudentMigration `json:"students"`
+	Applied  bool                        `json:"applied"`
+}
+
+// PlaybookMigrationService moves in-flight students from one playbook version
+// to another: instances are re-pinned (and renamed where the node id changed),
+// instances of removed nodes are retired, and newly inserted nodes whose
+// prerequisites are already done are opened.
+type PlaybookMigrationService struct {
+	repo      repository.JourneyRepository
+	journey   *JourneyService
+	playbooks *PlaybookService
+}
+
+func NewPlaybookMigrationService(repo repository.JourneyRepository, journey *JourneyService, playbooks *PlaybookService) *PlaybookMigrationService {
+	return &PlaybookMigrationService{repo: repo, journey: journey, playbooks: playbooks}
+}
+
+// Plan builds the dry-run report for migrating the tenant's students from
+// fromID (the active version when empty) to toID. overrides maps source node
+// ids to target node ids ("" drops the node) and wins over the proposal.
+func (s *PlaybookMigrationService) Plan(ctx context.Context, tenantID, fromID, toID string, overrides map[string]string) (*MigrationReport, error) {
+	var from *playbook.Manager
+	var err error
+	if fromID == "" {
+		from, err = s.playbooks.registry.ForTenant(ctx, tenantID)
+	} else {
+		from, err = s.playbooks.loadVersion(ctx, tenantID, fromID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.playbooks.loadVersion(ctx, tenantID, toID)
+	if err != nil {
+		return nil, err
+	}
+	if from.VersionID == to.VersionID {
+		return nil, ErrSameMigrationVersion
+	}
+
+	plan := playbook.PlanMigration(from, to, overrides)
+	current, err := s.repo.ListVersionInstances(ctx, tenantID, from.VersionID)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+	existing, err := s.repo.ListVersionInstances(ctx, tenantID, to.VersionID)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+
+	byUser, order := groupInstances(current)
+	already, _ := groupInstances(existing)
+
+	// Conditions of added nodes are evaluated against the target playbook
+	toCtx := playbook.WithManager(ctx, to)
+	report := &MigrationReport{Plan: plan, Students: []playbook.StudentMigration{}}
+	for _, userID := range order {
+		sm := plan.PlanStudent(to, userID, byUser[userID], already[userID])
+		activate := sm.Activate[:0]
+		for _, nodeID := range sm.Activate {
+			visible, err := s.journey.nodeVisible(toCtx, userID, to.Nodes[nodeID])
+			if err != nil {
+				return nil, fmt.Errorf("evaluate %s for %s: %w", nodeID, userID, err)
+			}
+			if visible {
+				activate = append(activate, nodeID)
+			}
+		}
+		sm.Activate = activate
+		report.Students = append(report.Students, sm)
+	}
+	return report, nil
+}
+
+// Apply plans the migration and executes it in a single transaction. Every
+// remapped, retired or opened instance gets a node event.
+func (s *PlaybookMigrationService) Apply(ctx context.Context, tenantID, fromID, toID, actorID string, overrides map[string]string) (*MigrationReport, error) {
+	report, err := s.Plan(ctx, tenantID, fromID, toID, overrides)
+	if err != nil {
+		return nil, err
+	}
+	from, to := report.Plan.FromVersionID, report.Plan.ToVersionID
+
+	err = s.repo.WithTx(ctx, func(tx repository.JourneyRepository) error {
+		for _, sm := range report.Students {
+			for _, act := range sm.Instances {
+				payload := map[string]any{
+					"from_version": from,
+					"to_version":   to,
+					"from_node":    act.FromNodeID,
+					"to_node":      act.ToNodeID,
+				}
+				if act.Action == playbook.InstanceRetire {
+					payload["reason"] = act.Reason
+					if err := tx.LogNodeEvent(ctx, act.InstanceID, "node_retired", actorID, payload); err != nil {
+						return fmt.Errorf("log retirement of %s: %w", act.InstanceID, err)
+					}
+					continue
+				}
+				if err := tx.RemapNodeInstance(ctx, act.InstanceID, act.ToNodeID, to); err != nil {
+					return fmt.Errorf("remap instance %s: %w", act.InstanceID, err)
+				}
+				if act.Action == playbook.InstanceRename {
+					if err := tx.RenameJourneyStateNode(ctx, sm.UserID, tenantID, act.FromNodeID, act.ToNodeID); err != nil {
+						return fmt.Errorf("rename journey state %s: %w", act.FromNodeID, err)
+					}
+				}
+				if err := tx.LogNodeEvent(ctx, act.InstanceID, "playbook_migrated", actorID, payload); err != nil {
+					return fmt.Errorf("log migration of %s: %w", act.InstanceID, err)
+				}
+			}
+			for _, nodeID := range sm.Activate {
+				id, err := tx.CreateNodeInstance(ctx, tenantID, sm.UserID, to, nodeID, "active", nil)
+				if err != nil {
+					return fmt.Errorf("open %s for %s: %w", nodeID, sm.UserID, err)
+				}
+				if err := tx.UpsertJourneyState(ctx, sm.UserID, nodeID, "active", tenantID); err != nil {
+					return err
+				}
+				payload := map[string]any{"from_version": from, "to_version": to, "reason": "inserted by playbook migration"}
+				if err := tx.LogNodeEvent(ctx, id, "node_activated", actorID, payload); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// groupInstances groups instances by student, keeping the students in the
+// order they first appear.
+func groupInstances(insts []models.NodeInstance) (map[string][]playbook.InstanceRef, []string) {
+	byUser := map[string][]playbook.InstanceRef{}
+	var order []string
+	for _, inst := range insts {
+		if _, seen := byUser[inst.UserID]; !seen {
+			order = append(order, inst.UserID)
+		}
+		byUser[inst.UserID] = append(byUser[inst.UserID], playbook.InstanceRef{
+			InstanceID: inst.ID,
+			NodeID:     inst.NodeID,
+			State:      inst.State,
+		})
+	}
+	return byUser, order
+}
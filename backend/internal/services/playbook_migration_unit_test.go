@@ -0,0 +1,142 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	migrationPlaybookV1 = `{"version": "1.0.0", "worlds": [{"id": "W1", "nodes": [
+		{"id": "S1_profile"}, {"id": "S2_old"}, {"id": "S4_gone"}]}]}`
+	migrationPlaybookV2 = `{"version": "2.0.0",
+		"conditions": [{"id": "senior", "expr": "profile.years_since_graduation > 3"}],
+		"worlds": [{"id": "W1", "nodes": [
+		{"id": "S1_profile"}, {"id": "S2_new", "replaces": ["S2_old"]},
+		{"id": "S5_ethics", "prerequisites": ["S1_profile"]},
+		{"id": "S6_rp", "prerequisites": ["S1_profile"], "condition": "senior", "conditional": true}]}]}`
+)
+
+type migrationFixture struct {
+	svc     *services.PlaybookMigrationService
+	repo    *MockJourneyRepository
+	v1, v2  string
+	events  []string
+	remaps  []string
+	renames []string
+	created []string
+}
+
+func newMigrationFixture(t *testing.T) *migrationFixture {
+	ctx := context.Background()
+	pbRepo := NewMemPlaybookRepository()
+	pbSvc := services.NewPlaybookService(pbRepo, playbook.NewRegistry(pbRepo, nil))
+	v1, err := pbSvc.Upload(ctx, "t1", "admin", []byte(migrationPlaybookV1))
+	require.NoError(t, err)
+	v2, err := pbSvc.Upload(ctx, "t1", "admin", []byte(migrationPlaybookV2))
+	require.NoError(t, err)
+	require.NoError(t, pbSvc.Activate(ctx, "t1", v1.ID, "admin"))
+
+	f := &migrationFixture{repo: NewMockJourneyRepository(), v1: v1.ID, v2: v2.ID}
+	f.repo.ListVersionInstancesFunc = func(ctx context.Context, tenantID, versionID string) ([]models.NodeInstance, error) {
+		if versionID != v1.ID {
+			return nil, nil
+		}
+		return []models.NodeInstance{
+			{ID: "i1", UserID: "u1", NodeID: "S1_profile", State: "done"},
+			{ID: "i2", UserID: "u1", NodeID: "S2_old", State: "active"},
+			{ID: "i3", UserID: "u1", NodeID: "S4_gone", State: "submitted"},
+		}, nil
+	}
+	f.repo.RemapNodeInstanceFunc = func(ctx context.Context, instanceID, nodeID, versionID string) error {
+		f.remaps = append(f.remaps, instanceID+"->"+nodeID+"@"+versionID)
+		return nil
+	}
+	f.repo.RenameJourneyStateNodeFunc = func(ctx context.Context, userID, tenantID, fromNodeID, toNodeID string) error {
+		f.renames = append(f.renames, fromNodeID+"->"+toNodeID)
+		return nil
+	}
+	f.repo.CreateNodeInstanceFunc = func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error) {
+		f.created = append(f.created, nodeID+"@"+versionID)
+		return "new-" + nodeID, nil
+	}
+	f.repo.LogNodeEventFunc = func(ctx context.Context, instanceID, eventType, actorID string, payload map[string]any) error {
+		f.events = append(f.events, eventType+":"+instanceID)
+		return nil
+	}
+	journey := services.NewJourneyService(f.repo, nil, config.AppConfig{}, nil, nil, nil)
+	f.svc = services.NewPlaybookMigrationService(f.repo, journey, pbSvc)
+	return f
+}
+
+func TestPlaybookMigrationService_DryRun_Unit(t *testing.T) {
+	f := newMigrationFixture(t)
+
+	report, err := f.svc.Plan(context.Background(), "t1", "", f.v2, nil)
+	require.NoError(t, err)
+	assert.False(t, report.Applied)
+	assert.Equal(t, f.v1, report.Plan.FromVersionID)
+	require.Len(t, report.Students, 1)
+
+	sm := report.Students[0]
+	assert.Equal(t, "u1", sm.UserID)
+	actions := map[string]string{}
+	for _, a := range sm.Instances {
+		actions[a.InstanceID] = a.Action
+	}
+	assert.Equal(t, map[string]string{"i1": playbook.InstanceKeep, "i2": playbook.InstanceRename, "i3": playbook.InstanceRetire}, actions)
+	// S6_rp is hidden by its condition for a student without a profile
+	assert.Equal(t, []string{"S5_ethics"}, sm.Activate)
+
+	// Dry runs do not write
+	assert.Empty(t, f.remaps)
+	assert.Empty(t, f.events)
+
+	_, err = f.svc.Plan(context.Background(), "t1", f.v1, f.v1, nil)
+	assert.ErrorIs(t, err, services.ErrSameMigrationVersion)
+	_, err = f.svc.Plan(context.Background(), "t2", "", f.v2, nil)
+	assert.ErrorIs(t, err, services.ErrPlaybookVersionNotFound)
+}
+
+func TestPlaybookMigrationService_Apply_Unit(t *testing.T) {
+	f := newMigrationFixture(t)
+	txUsed := false
+	f.repo.WithTxFunc = func(ctx context.Context, fn func(repository.JourneyRepository) error) error {
+		txUsed = true
+		return fn(f.repo)
+	}
+
+	report, err := f.svc.Apply(context.Background(), "t1", "", f.v2, "admin", nil)
+	require.NoError(t, err)
+	assert.True(t, report.Applied)
+	assert.True(t, txUsed)
+
+	assert.Equal(t, []string{"i1->S1_profile@" + f.v2, "i2->S2_new@" + f.v2}, f.remaps)
+	assert.Equal(t, []string{"S2_old->S2_new"}, f.renames)
+	assert.Equal(t, []string{"S5_ethics@" + f.v2}, f.created)
+	assert.Equal(t, []string{
+		"playbook_migrated:i1",
+		"playbook_migrated:i2",
+		"node_retired:i3",
+		"node_activated:new-S5_ethics",
+	}, f.events)
+}
+
+func TestPlaybookMigrationService_ApplyFailureAborts_Unit(t *testing.T) {
+	f := newMigrationFixture(t)
+	f.repo.RemapNodeInstanceFunc = func(ctx context.Context, instanceID, nodeID, versionID string) error {
+		return errors.New("boom")
+	}
+
+	_, err := f.svc.Apply(context.Background(), "t1", "", f.v2, "admin", nil)
+	assert.ErrorContains(t, err, "remap instance i1")
+	assert.Empty(t, f.events)
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+)
+
+var (
+	ErrPlaybookVersionNotFound = errors.New("playbook version not found")
+	ErrNoPlaybookRollback      = errors.New("no earlier playbook activation to roll back to")
+)
+
+// PlaybookValidationError reports why an uploaded playbook was rejected.
+type PlaybookValidationError struct {
+	Errors []string
+}
+
+func (e *PlaybookValidationError) Error() string {
+	return "invalid playbook: " + strings.Join(e.Errors, "; ")
+}
+
+// PlaybookValidation is the result of checking a playbook document.
+type PlaybookValidation struct {
+	Valid    bool     `json:"valid"`
+	Version  string   `json:"version,omitempty"`
+	Checksum string   `json:"checksum,omitempty"`
+	Nodes    int      `json:"nodes"`
+	Errors   []string `json:"errors"`
+	// Issues is the full lint report, including warnings
+	Issues []playbook.LintIssue `json:"issues"`
+}
+
+// PlaybookService manages a tenant's playbook versions: upload, validation,
+// diffing, activation and rollback. Activations invalidate the registry so the
+// new version is served without a restart.
+type PlaybookService struct {
+	repo     repository.PlaybookRepository
+	registry *playbook.Registry
+}
+
+func NewPlaybookService(repo repository.PlaybookRepository, registry *playbook.Registry) *PlaybookService {
+	return &PlaybookService{repo: repo, registry: registry}
+}
+
+// Validate checks that raw is a loadable playbook without lint errors.
+func (s *PlaybookService) Validate(raw []byte) *PlaybookValidation {
+	report := playbook.Lint(raw)
+	res := &PlaybookValidation{Errors: []string{}, Issues: report.Issues}
+	for _, is := range report.Issues {
+		if is.Severity == playbook.SeverityError {
+			res.Errors = append(res.Errors, is.Message)
+		}
+	}
+	mgr, err := playbook.Parse("", raw)
+	if err != nil {
+		if !report.HasErrors() {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		return res
+	}
+	res.Version = mgr.Version
+	res.Checksum = mgr.Checksum
+	res.Nodes = len(mgr.Nodes)
+	if mgr.Version == "" {
+		res.Errors = append(res.Errors, "version is required")
+	}
+	if len(mgr.Nodes) == 0 {
+		res.Errors = append(res.Errors, "playbook has no nodes")
+	}
+	res.Valid = len(res.Errors) == 0
+	return res
+}
+
+// Upload stores a validated playbook as a new, inactive version. Uploading
+// content the tenant already has returns the existing version.
+func (s *PlaybookService) Upload(ctx context.Context, tenantID, actorID string, raw []byte) (*models.PlaybookVersion, error) {
+	res := s.Validate(raw)
+	if !res.Valid {
+		return nil, &PlaybookValidationError{Errors: res.Errors}
+	}
+	existing, err := s.repo.GetVersionByChecksum(ctx, tenantID, res.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	v := &models.PlaybookVersion{
+		TenantID: tenantID,
+		Version:  res.Version,
+		Checksum: res.Checksum,
+		RawJSON:  raw,
+	}
+	if actorID != "" {
+		v.CreatedBy = &actorID
+	}
+	id, err := s.repo.CreateVersion(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("store playbook version: %w", err)
+	}
+	v.ID = id
+	v.RawJSON = nil
+	return v, nil
+}
+
+func (s *PlaybookService) ListVersions(ctx context.Context, tenantID string) ([]models.PlaybookVersion, error) {
+	return s.repo.ListVersions(ctx, tenantID)
+}
+
+func (s *PlaybookService) ListActivations(ctx context.Context, tenantID string) ([]models.PlaybookActivation, error) {
+	return s.repo.ListActivations(ctx, tenantID)
+}
+
+// GetVersion returns a version of the tenant including its JSON.
+func (s *PlaybookService) GetVersion(ctx context.Context, tenantID, versionID string) (*models.PlaybookVersion, error) {
+	v, err := s.repo.GetVersion(ctx, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || v.TenantID != tenantID {
+		return nil, ErrPlaybookVersionNotFound
+	}
+	return v, nil
+}
+
+// Diff compares version toID against fromID. An empty fromID compares against
+// the tenant's active playbook.
+func (s *PlaybookService) Diff(ctx context.Context, tenantID, fromID, toID string) (*playbook.VersionDiff, error) {
+	var from *playbook.Manager
+	var err error
+	if fromID == "" {
+		from, err = s.registry.ForTenant(ctx, tenantID)
+	} else {
+		from, err = s.loadVersion(ctx, tenantID, fromID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.loadVersion(ctx, tenantID, toID)
+	if err != nil {
+		return nil, err
+	}
+	d := playbook.Diff(from, to)
+	return &d, nil
+}
+
+// Activate makes a version the tenant's active playbook.
+func (s *PlaybookService) Activate(ctx context.Context, tenantID, versionID, actorID string) error {
+	if _, err := s.loadVersion(ctx, tenantID, versionID); err != nil {
+		return err
+	}
+	if err := s.repo.ActivateVersion(ctx, tenantID, versionID, actorID); err != nil {
+		return err
+	}
+	s.registry.Invalidate(tenantID)
+	return nil
+}
+
+// Rollback re-activates the version that was active before the latest activation.
+func (s *PlaybookService) Rollback(ctx context.Context, tenantID string) (string, error) {
+	versionID, err := s.repo.RollbackActivation(ctx, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoPlaybookRollback
+	}
+	if err != nil {
+		return "", err
+	}
+	s.registry.Invalidate(tenantID)
+	return versionID, nil
+}
+
+// loadVersion parses a stored version after checking it belongs to the tenant.
+func (s *PlaybookService) loadVersion(ctx context.Context, tenantID, versionID string) (*playbook.Manager, error) {
+	v, err := s.GetVersion(ctx, tenantID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return playbook.Parse(v.ID, v.RawJSON)
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPlaybookV1 = `{"version": "1.0.0", "worlds": [{"id": "W1", "nodes": [{"id": "S1"}, {"id": "S2"}]}]}`
+	testPlaybookV2 = `{"version": "1.1.0", "worlds": [{"id": "W1", "nodes": [{"id": "S1"}, {"id": "S3"}]}]}`
+)
+
+func TestPlaybookService_Validate_Unit(t *testing.T) {
+	svc := services.NewPlaybookService(NewMemPlaybookRepository(), playbook.NewRegistry(NewMemPlaybookRepository(), nil))
+
+	res := svc.Validate([]byte(testPlaybookV1))
+	assert.True(t, res.Valid)
+	assert.Equal(t, "1.0.0", res.Version)
+	assert.Equal(t, 2, res.Nodes)
+	assert.NotEmpty(t, res.Checksum)
+
+	res = svc.Validate([]byte(`{"version": "1", "worlds": [`))
+	assert.False(t, res.Valid)
+	assert.NotEmpty(t, res.Errors)
+
+	res = svc.Validate([]byte(`{"worlds": []}`))
+	assert.False(t, res.Valid)
+	assert.Contains(t, res.Errors, "version is required")
+	assert.Contains(t, res.Errors, "playbook has no nodes")
+
+	res = svc.Validate([]byte(`{"version": "1", "conditions": [{"id": "c", "expr": "a >"}], "worlds": [{"id": "W1", "nodes": [{"id": "S1"}]}]}`))
+	assert.False(t, res.Valid)
+
+	// Lint errors block validation; warnings are reported but allowed
+	res = svc.Validate([]byte(`{"version": "1", "worlds": [{"id": "W1", "nodes": [{"id": "S1", "next": ["S9"]}]}]}`))
+	assert.False(t, res.Valid)
+	assert.Contains(t, res.Errors, "S1: next node S9 does not exist")
+	res = svc.Validate([]byte(testPlaybookV1))
+	assert.True(t, res.Valid)
+	assert.NotEmpty(t, res.Issues)
+}
+
+func TestPlaybookService_Lifecycle_Unit(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemPlaybookRepository()
+	fallback, err := playbook.Parse("default", []byte(testPlaybookV1))
+	require.NoError(t, err)
+	registry := playbook.NewRegistry(repo, fallback)
+	svc := services.NewPlaybookService(repo, registry)
+
+	// Upload is stored but not active
+	v1, err := svc.Upload(ctx, "t1", "admin1", []byte(testPlaybookV1))
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", v1.Version)
+	assert.Equal(t, "admin1", *v1.CreatedBy)
+	mgr, _ := registry.ForTenant(ctx, "t1")
+	assert.Same(t, fallback, mgr)
+
+	// Re-uploading identical content is idempotent
+	again, err := svc.Upload(ctx, "t1", "admin1", []byte(testPlaybookV1))
+	require.NoError(t, err)
+	assert.Equal(t, v1.ID, again.ID)
+
+	// Invalid uploads are rejected with details
+	_, err = svc.Upload(ctx, "t1", "admin1", []byte(`{"worlds": []}`))
+	var invalid *services.PlaybookValidationError
+	assert.ErrorAs(t, err, &invalid)
+
+	v2, err := svc.Upload(ctx, "t1", "admin1", []byte(testPlaybookV2))
+	require.NoError(t, err)
+
+	// Diff against the active playbook
+	d, err := svc.Diff(ctx, "t1", "", v2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"S3"}, d.AddedNodes)
+	assert.Equal(t, []string{"S2"}, d.RemovedNodes)
+
+	// Activation is served immediately
+	require.NoError(t, svc.Activate(ctx, "t1", v1.ID, "admin1"))
+	require.NoError(t, svc.Activate(ctx, "t1", v2.ID, "admin1"))
+	mgr, err = registry.ForTenant(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, v2.ID, mgr.VersionID)
+
+	// Rollback restores the previous activation
+	back, err := svc.Rollback(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, v1.ID, back)
+	mgr, err = registry.ForTenant(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, v1.ID, mgr.VersionID)
+
+	_, err = svc.Rollback(ctx, "t1")
+	assert.True(t, errors.Is(err, services.ErrNoPlaybookRollback))
+}
+
+func TestPlaybookService_TenantIsolation_Unit(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemPlaybookRepository()
+	svc := services.NewPlaybookService(repo, playbook.NewRegistry(repo, nil))
+
+	v, err := svc.Upload(ctx, "t1", "", []byte(testPlaybookV1))
+	require.NoError(t, err)
+	assert.Nil(t, v.CreatedBy)
+
+	_, err = svc.GetVersion(ctx, "t2", v.ID)
+	assert.ErrorIs(t, err, services.ErrPlaybookVersionNotFound)
+	err = svc.Activate(ctx, "t2", v.ID, "admin2")
+	assert.ErrorIs(t, err, services.ErrPlaybookVersionNotFound)
+	_, err = svc.Diff(ctx, "t2", v.ID, v.ID)
+	assert.ErrorIs(t, err, services.ErrPlaybookVersionNotFound)
+}
+
+func TestJourneyService_UsesTenantPlaybookFromContext_Unit(t *testing.T) {
+	startup := &playbook.Manager{Nodes: map[string]playbook.Node{"S1": {ID: "S1"}}}
+	tenant := &playbook.Manager{VersionID: "tenant-v", Nodes: map[string]playbook.Node{"T1": {ID: "T1"}}}
+
+	mock := NewMockJourneyRepository()
+	var createdVersion string
+	mock.CreateNodeInstanceFunc = func(ctx context.Context, tenantID, userID, versionID, nodeID, state string, locale *string) (string, error) {
+		createdVersion = versionID
+		return "inst", nil
+	}
+	svc := services.NewJourneyService(mock, startup, config.AppConfig{}, nil, nil, nil)
+
+	_, err := svc.EnsureNodeInstance(context.Background(), "t1", "u1", "T1", nil)
+	assert.Error(t, err, "node unknown to the startup playbook")
+
+	ctx := playbook.WithManager(context.Background(), tenant)
+	_, err = svc.EnsureNodeInstance(ctx, "t1", "u1", "T1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-v", createdVersion)
+}
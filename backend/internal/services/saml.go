@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml"
+)
+
+const (
+	// SAMLMetadataPath serves the SP metadata; with the tenant query it is
+	// also the SP's entity ID
+	SAMLMetadataPath = "/api/auth/saml/metadata"
+	// SAMLLoginPath starts a login; the frontend links to it
+	SAMLLoginPath = "/api/auth/saml/login"
+	// SAMLACSPath is the assertion consumer service IdPs post responses to
+	SAMLACSPath = "/api/auth/saml/acs"
+	// SAMLLoginTTL is how long a user has to sign in at the IdP
+	SAMLLoginTTL = 10 * time.Minute
+)
+
+var ErrSAMLInvalidConfig = errors.New("invalid saml configuration")
+
+// Default attribute names, as eduPerson IdPs, Azure AD and ADFS send them.
+var (
+	samlEmailAttributes = []string{
+		"urn:oid:0.9.2342.19200300.100.1.3", "mail", "email", "emailaddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	}
+	samlFirstNameAttributes = []string{
+		"urn:oid:2.5.4.42", "givenName", "firstName",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname",
+	}
+	samlLastNameAttributes = []string{
+		"urn:oid:2.5.4.4", "sn", "surname", "lastName",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname",
+	}
+)
+
+// samlRoleRank orders the roles an IdP may grant; a user with several
+// mapped attribute values gets the highest. Admins are only made in the
+// portal, so a tenant's IdP cannot hand out control of the tenant.
+var samlRoleRank = map[string]int{
+	string(models.RoleStudent): 1,
+	string(models.RoleAdvisor): 2,
+	string(models.RoleChair):   3,
+}
+
+// SAMLService signs users in with their tenant's SAML 2.0 identity
+// provider. The portal is the SP; each tenant is its own SP entity, so
+// universities register the portal once per tenant. Only SP-initiated
+// logins are accepted: a response must answer an AuthnRequest we sent.
+// Users are matched and get their session exactly as with OIDC.
+type SAMLService struct {
+	repo      repository.SAMLRepository
+	accounts  *ssoAccounts
+	tenants   repository.TenantRepository
+	serverURL string
+}
+
+func NewSAMLService(repo repository.SAMLRepository, users repository.UserRepository, tenants repository.TenantRepository, authService *AuthService, cfg config.AppConfig) *SAMLService {
+	return &SAMLService{
+		repo:      repo,
+		accounts:  &ssoAccounts{users: users, tenants: tenants, auth: authService},
+		tenants:   tenants,
+		serverURL: strings.TrimSuffix(cfg.ServerURL, "/"),
+	}
+}
+
+// Enabled tells whether the tenant's users can sign in with SAML.
+func (s *SAMLService) Enabled(ctx context.Context, tenantID string) bool {
+	cfg, err := s.repo.GetConfig(ctx, tenantID)
+	return err == nil && cfg.Enabled
+}
+
+// LoginURL is the backend URL that starts a SAML login for the tenant.
+func (s *SAMLService) LoginURL(tenantSlug string) string {
+	return s.serverURL + SAMLLoginPath + "?" + url.Values{"tenant": {tenantSlug}}.Encode()
+}
+
+// ServiceProvider describes the portal as the tenant's IdP sees it. The
+// URLs name the tenant, as the IdP's requests cannot carry the tenant
+// header.
+func (s *SAMLService) ServiceProvider(ctx context.Context, tenantID string) (saml.ServiceProvider, error) {
+	t, err := s.tenants.GetByID(ctx, tenantID)
+	if err != nil {
+		return saml.ServiceProvider{}, err
+	}
+	q := "?" + url.Values{"tenant": {t.Slug}}.Encode()
+	return saml.ServiceProvider{
+		EntityID: s.serverURL + SAMLMetadataPath + q,
+		ACSURL:   s.serverURL + SAMLACSPath + q,
+	}, nil
+}
+
+// BeginLogin starts a SAML login for the tenant and returns the IdP URL to
+// send the user to.
+func (s *SAMLService) BeginLogin(ctx context.Context, tenantID, redirectPath string) (string, error) {
+	_, idp, err := s.enabledProvider(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	sp, err := s.ServiceProvider(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	req := &models.SAMLLoginRequest{
+		ID:           saml.NewRequestID(),
+		TenantID:     tenantID,
+		RedirectPath: SafeRedirectPath(redirectPath),
+		ExpiresAt:    time.Now().Add(SAMLLoginTTL),
+	}
+	if err := s.repo.CreateRequest(ctx, req); err != nil {
+		return "", err
+	}
+	// The request ID doubles as the relay state that comes back with the
+	// response
+	return sp.AuthnRequestURL(idp, req.ID, req.ID, time.Now())
+}
+
+// CompleteLogin handles a response the IdP posted to the tenant's ACS.
+func (s *SAMLService) CompleteLogin(ctx context.Context, tenantID, samlResponse, relayState string) (*SSOLoginResult, error) {
+	req, err := s.repo.ConsumeRequest(ctx, relayState)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSSOLoginExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.TenantID != tenantID {
+		return nil, ErrSSOLoginExpired
+	}
+	cfg, idp, err := s.enabledProvider(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := s.ServiceProvider(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := sp.ParseResponse(samlResponse, idp, req.ID, time.Now())
+	var statusErr *saml.StatusError
+	if errors.As(err, &statusErr) {
+		log.Printf("[SAMLService] IdP returned status=%s for tenant=%s: %s", statusErr.Code, tenantID, statusErr.Message)
+		return nil, ErrSSODeclined
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.accounts.signIn(ctx, tenantID, req.RedirectPath, samlIdentity(cfg, assertion), ssoPolicy{
+		JITProvisioning: cfg.JITProvisioning,
+		AllowedDomains:  cfg.AllowedDomains,
+		SyncRole:        cfg.SyncRole,
+	})
+}
+
+// samlIdentity reads the user from the assertion's attributes, falling
+// back to an email-like NameID for the email.
+func samlIdentity(cfg *models.TenantSAMLConfig, a *saml.Assertion) ssoIdentity {
+	id := ssoIdentity{
+		Email:     a.Attribute(withConfigured(cfg.EmailAttribute, samlEmailAttributes)...),
+		FirstName: a.Attribute(withConfigured(cfg.FirstNameAttribute, samlFirstNameAttributes)...),
+		LastName:  a.Attribute(withConfigured(cfg.LastNameAttribute, samlLastNameAttributes)...),
+		Role:      cfg.DefaultRole,
+	}
+	if id.Email == "" && (a.NameIDFormat == saml.NameIDFormatEmail || strings.Contains(a.NameID, "@")) {
+		id.Email = a.NameID
+	}
+	if cfg.RoleAttribute != "" {
+		best := ""
+		for _, v := range a.AttributeValues(cfg.RoleAttribute) {
+			for key, role := range cfg.RoleMap {
+				if strings.EqualFold(key, strings.TrimSpace(v)) && samlRoleRank[role] > samlRoleRank[best] {
+					best = role
+				}
+			}
+		}
+		if best != "" {
+			id.Role = best
+		}
+	}
+	return id
+}
+
+// withConfigured returns the attribute name the tenant set, or the
+// defaults when it set none.
+func withConfigured(name string, defaults []string) []string {
+	if name != "" {
+		return []string{name}
+	}
+	return defaults
+}
+
+// GetConfig returns the tenant's IdP configuration.
+func (s *SAMLService) GetConfig(ctx context.Context, tenantID string) (*models.TenantSAMLConfig, error) {
+	cfg, err := s.repo.GetConfig(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSSONotConfigured
+	}
+	return cfg, err
+}
+
+// SaveConfig validates and stores the tenant's IdP configuration and
+// returns the IdP read from its metadata.
+func (s *SAMLService) SaveConfig(ctx context.Context, tenantID string, cfg *models.TenantSAMLConfig) (*saml.IdentityProvider, error) {
+	cfg.IdPMetadata = strings.TrimSpace(cfg.IdPMetadata)
+	idp, err := saml.ParseIdPMetadata([]byte(cfg.IdPMetadata))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSAMLInvalidConfig, err)
+	}
+	cfg.EmailAttribute = strings.TrimSpace(cfg.EmailAttribute)
+	cfg.FirstNameAttribute = strings.TrimSpace(cfg.FirstNameAttribute)
+	cfg.LastNameAttribute = strings.TrimSpace(cfg.LastNameAttribute)
+	cfg.RoleAttribute = strings.TrimSpace(cfg.RoleAttribute)
+	roleMap := map[string]string{}
+	for value, role := range cfg.RoleMap {
+		value = strings.TrimSpace(value)
+		if value == "" || samlRoleRank[role] == 0 {
+			return nil, fmt.Errorf("%w: role_map entry %q maps to %q; roles are student, advisor and chair", ErrSAMLInvalidConfig, value, role)
+		}
+		roleMap[value] = role
+	}
+	if len(roleMap) > 0 && cfg.RoleAttribute == "" {
+		return nil, fmt.Errorf("%w: role_map needs a role_attribute", ErrSAMLInvalidConfig)
+	}
+	cfg.RoleMap = roleMap
+	if cfg.DefaultRole != "" && samlRoleRank[cfg.DefaultRole] == 0 {
+		return nil, fmt.Errorf("%w: unknown default_role %q", ErrSAMLInvalidConfig, cfg.DefaultRole)
+	}
+	cfg.AllowedDomains = normalizeDomains(cfg.AllowedDomains)
+
+	if err := s.repo.SaveConfig(ctx, tenantID, cfg); err != nil {
+		return nil, err
+	}
+	return idp, nil
+}
+
+// DeleteConfig turns SAML off for the tenant for good.
+func (s *SAMLService) DeleteConfig(ctx context.Context, tenantID string) error {
+	err := s.repo.DeleteConfig(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrSSONotConfigured
+	}
+	return err
+}
+
+func (s *SAMLService) enabledProvider(ctx context.Context, tenantID string) (*models.TenantSAMLConfig, *saml.IdentityProvider, error) {
+	cfg, err := s.repo.GetConfig(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrSSONotConfigured
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cfg.Enabled {
+		return nil, nil, ErrSSONotConfigured
+	}
+	idp, err := saml.ParseIdPMetadata([]byte(cfg.IdPMetadata))
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, idp, nil
+}
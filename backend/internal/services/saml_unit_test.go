@@ -0,0 +1,240 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/config"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/saml/samltest"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSAMLService_Login(t *testing.T) {
+	idp, err := samltest.New("https://idp.example.edu/saml")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	repo := NewMemSAMLRepository()
+	users := NewHandwrittenMockUserRepository()
+	var memberships []string
+	tenants := &MockTenantRepository{
+		GetByIDFunc: func(ctx context.Context, id string) (*models.Tenant, error) {
+			return &models.Tenant{ID: id, Slug: "kaznmu"}, nil
+		},
+		AddUserToTenantFunc: func(ctx context.Context, userID, tenantID, role string, isPrimary bool) error {
+			memberships = append(memberships, userID+":"+tenantID+":"+role)
+			return nil
+		},
+	}
+	cfg := config.AppConfig{JWTSecret: "secret", JWTExpDays: 1, ServerURL: "http://localhost:8080"}
+	svc := services.NewSAMLService(repo, users, tenants, services.NewAuthService(users, NewMemSessionRepository(), nil, nil, cfg), cfg)
+
+	_, err = svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{
+		Enabled:       true,
+		IdPMetadata:   string(idp.Metadata()),
+		RoleAttribute: samltest.AttrAffiliation,
+		RoleMap:       map[string]string{"student": "student", "faculty": "advisor", "staff": "chair"},
+	})
+	require.NoError(t, err)
+
+	sp, err := svc.ServiceProvider(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/api/auth/saml/metadata?tenant=kaznmu", sp.EntityID)
+	assert.Equal(t, "http://localhost:8080/api/auth/saml/acs?tenant=kaznmu", sp.ACSURL)
+
+	// atIdP runs a login at the IdP and returns the form it posts back
+	atIdP := func(t *testing.T, redirect string) url.Values {
+		t.Helper()
+		authURL, err := svc.BeginLogin(ctx, "t1", redirect)
+		require.NoError(t, err)
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		acs, form, err := idp.Respond(u.Query())
+		require.NoError(t, err)
+		require.Equal(t, sp.ACSURL, acs)
+		return form
+	}
+	login := func(t *testing.T, redirect string) (*services.SSOLoginResult, error) {
+		t.Helper()
+		form := atIdP(t, redirect)
+		return svc.CompleteLogin(ctx, "t1", form.Get("SAMLResponse"), form.Get("RelayState"))
+	}
+	users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+		assert.Equal(t, "student@example.edu", email)
+		return &models.User{ID: "u1", Role: models.RoleStudent, IsActive: true}, nil
+	}
+	users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+		return "student", nil
+	}
+
+	t.Run("Existing member", func(t *testing.T) {
+		res, err := login(t, "/journey")
+		require.NoError(t, err)
+		assert.Equal(t, "u1", res.UserID)
+		assert.Equal(t, "student", res.Role)
+		assert.Equal(t, "/journey", res.RedirectPath)
+		assert.NotEmpty(t, res.Token)
+		assert.Empty(t, memberships)
+	})
+
+	t.Run("A response is accepted once and only by its tenant", func(t *testing.T) {
+		form := atIdP(t, "")
+		_, err := svc.CompleteLogin(ctx, "t2", form.Get("SAMLResponse"), form.Get("RelayState"))
+		assert.ErrorIs(t, err, services.ErrSSOLoginExpired)
+		_, err = svc.CompleteLogin(ctx, "t1", form.Get("SAMLResponse"), form.Get("RelayState"))
+		assert.ErrorIs(t, err, services.ErrSSOLoginExpired)
+
+		form = atIdP(t, "")
+		_, err = svc.CompleteLogin(ctx, "t1", form.Get("SAMLResponse"), "")
+		assert.ErrorIs(t, err, services.ErrSSOLoginExpired, "IdP-initiated logins are not accepted")
+	})
+
+	t.Run("Role sync keeps the primary tenant", func(t *testing.T) {
+		repo.configs["t1"].SyncRole = true
+		idp.User.Attributes[samltest.AttrAffiliation] = []string{"member", "faculty", "student"}
+		tenants.GetUserMembershipFunc = func(ctx context.Context, userID, tenantID string) (*models.TenantMembershipView, error) {
+			return &models.TenantMembershipView{Role: "student", IsPrimary: true}, nil
+		}
+		var primary bool
+		tenants.AddUserToTenantFunc = func(ctx context.Context, userID, tenantID, role string, isPrimary bool) error {
+			memberships = append(memberships, userID+":"+tenantID+":"+role)
+			primary = isPrimary
+			return nil
+		}
+		res, err := login(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, "advisor", res.Role, "the highest mapped role wins")
+		assert.Equal(t, []string{"u1:t1:advisor"}, memberships)
+		assert.True(t, primary)
+
+		idp.User.Attributes[samltest.AttrAffiliation] = []string{"alum"}
+		_, err = login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccessDenied, "no mapped role and no default")
+		memberships = nil
+
+		// The IdP can neither demote nor replace a tenant admin
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "admin", nil
+		}
+		idp.User.Attributes[samltest.AttrAffiliation] = []string{"student"}
+		res, err = login(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", res.Role)
+		assert.Empty(t, memberships)
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "student", nil
+		}
+		repo.configs["t1"].SyncRole = false
+	})
+
+	t.Run("A superadmin's email is refused", func(t *testing.T) {
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{ID: "root", Role: models.RoleSuperAdmin, IsSuperadmin: true, IsActive: true}, nil
+		}
+		idp.User.Attributes[samltest.AttrAffiliation] = []string{"staff"}
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccessDenied)
+		assert.Empty(t, memberships)
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{ID: "u1", Role: models.RoleStudent, IsActive: true}, nil
+		}
+	})
+
+	t.Run("Just-in-time provisioning with the mapped role", func(t *testing.T) {
+		repo.configs["t1"].JITProvisioning = true
+		idp.User.Attributes[samltest.AttrAffiliation] = []string{"faculty"}
+		users.GetByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+			return nil, repository.ErrNotFound
+		}
+		users.GetTenantRoleFunc = func(ctx context.Context, userID, tenantID string) (string, error) {
+			return "", repository.ErrNotFound
+		}
+		var created *models.User
+		users.CreateFunc = func(ctx context.Context, u *models.User) (string, error) {
+			created = u
+			return "u-new", nil
+		}
+		res, err := login(t, "")
+		require.NoError(t, err)
+		assert.True(t, res.Provisioned)
+		assert.Equal(t, "advisor", res.Role)
+		require.NotNil(t, created)
+		assert.Equal(t, models.RoleAdvisor, created.Role)
+		assert.Equal(t, "Mock", created.FirstName)
+		assert.Equal(t, "Student", created.LastName)
+		assert.Equal(t, []string{"u-new:t1:advisor"}, memberships)
+
+		idp.User.Attributes[samltest.AttrAffiliation] = []string{"alum"}
+		_, err = login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOAccessDenied, "unknown users need a role")
+
+		repo.configs["t1"].DefaultRole = "student"
+		res, err = login(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, "student", res.Role)
+	})
+
+	t.Run("Email from the NameID", func(t *testing.T) {
+		delete(idp.User.Attributes, samltest.AttrMail)
+		idp.User.NameID = "someone@gmail.com"
+		repo.configs["t1"].AllowedDomains = []string{"example.edu"}
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSOEmailNotAllowed)
+	})
+
+	t.Run("Declined at the IdP", func(t *testing.T) {
+		idp.Status = "urn:oasis:names:tc:SAML:2.0:status:AuthnFailed"
+		defer func() { idp.Status = "" }()
+		_, err := login(t, "")
+		assert.ErrorIs(t, err, services.ErrSSODeclined)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		repo.configs["t1"].Enabled = false
+		_, err := svc.BeginLogin(ctx, "t1", "")
+		assert.ErrorIs(t, err, services.ErrSSONotConfigured)
+		assert.False(t, svc.Enabled(ctx, "t1"))
+	})
+}
+
+func TestSAMLService_SaveConfig(t *testing.T) {
+	idp, err := samltest.New("https://idp.example.edu/saml")
+	require.NoError(t, err)
+	svc := services.NewSAMLService(NewMemSAMLRepository(), nil, nil, nil, config.AppConfig{})
+	ctx := context.Background()
+
+	_, err = svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{IdPMetadata: "<md:EntityDescriptor/>"})
+	assert.ErrorIs(t, err, services.ErrSAMLInvalidConfig)
+
+	_, err = svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{
+		IdPMetadata: string(idp.Metadata()), RoleAttribute: "eduPersonAffiliation", RoleMap: map[string]string{"staff": "superadmin"},
+	})
+	assert.ErrorIs(t, err, services.ErrSAMLInvalidConfig, "superadmin cannot be granted by an IdP")
+
+	_, err = svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{
+		IdPMetadata: string(idp.Metadata()), RoleAttribute: "eduPersonAffiliation", RoleMap: map[string]string{"staff": "admin"},
+	})
+	assert.ErrorIs(t, err, services.ErrSAMLInvalidConfig, "admin cannot be granted by an IdP")
+	_, err = svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{IdPMetadata: string(idp.Metadata()), DefaultRole: "admin"})
+	assert.ErrorIs(t, err, services.ErrSAMLInvalidConfig)
+
+	_, err = svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{IdPMetadata: string(idp.Metadata()), RoleMap: map[string]string{"staff": "chair"}})
+	assert.ErrorIs(t, err, services.ErrSAMLInvalidConfig, "role_map needs a role_attribute")
+
+	info, err := svc.SaveConfig(ctx, "t1", &models.TenantSAMLConfig{
+		IdPMetadata: string(idp.Metadata()), DefaultRole: "student", AllowedDomains: []string{" @Example.EDU "},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example.edu/saml/metadata", info.EntityID)
+	cfg, err := svc.GetConfig(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.edu"}, cfg.AllowedDomains)
+
+	require.NoError(t, svc.DeleteConfig(ctx, "t1"))
+	assert.ErrorIs(t, svc.DeleteConfig(ctx, "t1"), services.ErrSSONotConfigured)
+}
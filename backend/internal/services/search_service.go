@@ -2,11 +2,25 @@ package services
 
 import (
 	"context"
+	"errors"
 
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
 	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
 )
 
+var ErrSearchUnknownType = errors.New("unknown search type")
+
+// SearchTypes are the searchable result types, in the order GlobalSearch
+// lists them.
+var SearchTypes = []string{"users", "documents", "messages", "events", "contacts"}
+
+const (
+	// globalSearchLimit is how many results GlobalSearch returns per type
+	globalSearchLimit = 5
+	// maxSearchPage bounds the page size of Search
+	maxSearchPage = 50
+)
+
 type SearchService struct {
 	repo repository.SearchRepository
 }
@@ -15,34 +29,54 @@ func NewSearchService(repo repository.SearchRepository) *SearchService {
 	return &SearchService{repo: repo}
 }
 
-func (s *SearchService) GlobalSearch(ctx context.Context, query string, role string, userID string) ([]models.SearchResult, error) {
-	// 5 items per category
-	limit := 5
-	
-	// 1. Search Users
-	users, err := s.repo.SearchUsers(ctx, query, role, userID, limit)
-	if err != nil {
-		// Log error but stick to partial results? Or return error?
-		// Handler usually handles it. We'll return partial + error or just error.
-		return nil, err
+// searchFunc returns the repository search for a result type.
+func (s *SearchService) searchFunc(kind string) func(context.Context, repository.SearchScope, string, int, int) ([]models.SearchResult, int, error) {
+	switch kind {
+	case "users":
+		return s.repo.SearchUsers
+	case "documents":
+		return s.repo.SearchDocuments
+	case "messages":
+		return s.repo.SearchMessages
+	case "events":
+		return s.repo.SearchEvents
+	case "contacts":
+		return s.repo.SearchContacts
 	}
-	if users == nil {
-		users = []models.SearchResult{}
+	return nil
+}
+
+// GlobalSearch returns the best few matches of every type, grouped by type.
+func (s *SearchService) GlobalSearch(ctx context.Context, scope repository.SearchScope, query string) ([]models.SearchResult, error) {
+	results := []models.SearchResult{}
+	for _, kind := range SearchTypes {
+		found, _, err := s.searchFunc(kind)(ctx, scope, query, globalSearchLimit, 0)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
 	}
+	return results, nil
+}
 
-	// 2. Search Documents
-	docs, err := s.repo.SearchDocuments(ctx, query, role, userID, limit)
+// Search returns one page of matches of a single type.
+func (s *SearchService) Search(ctx context.Context, scope repository.SearchScope, kind, query string, limit, offset int) (*models.SearchPage, error) {
+	search := s.searchFunc(kind)
+	if search == nil {
+		return nil, ErrSearchUnknownType
+	}
+	if limit <= 0 || limit > maxSearchPage {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	results, total, err := search(ctx, scope, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	if docs == nil {
-		docs = []models.SearchResult{}
+	if results == nil {
+		results = []models.SearchResult{}
 	}
-
-	// 3. Search Messages
-	// Not implemented in repo yet, logic skipped.
-
-	// Combine
-	results := append(users, docs...)
-	return results, nil
+	return &models.SearchPage{Type: kind, Query: query, Results: results, Total: total, Limit: limit, Offset: offset}, nil
 }
@@ -60,7 +60,7 @@ func TestSearchService_GlobalSearch(t *testing.T) {
 
 	// Perform Search
 	// Search as admin to see users
-	results, err := svc.GlobalSearch(ctx, "Target", "admin", uID)
+	results, err := svc.GlobalSearch(ctx, repository.SearchScope{TenantID: tenantID, UserID: uID, Role: "admin"}, "Target")
 	require.NoError(t, err)
 
 	// Verify we got results
@@ -0,0 +1,43 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchService_Unit(t *testing.T) {
+	ctx := context.Background()
+	scope := repository.SearchScope{TenantID: "t1", UserID: "u1", Role: "student"}
+
+	t.Run("Global search groups by type", func(t *testing.T) {
+		repo := &TypedSearchRepository{}
+		results, err := services.NewSearchService(repo).GlobalSearch(ctx, scope, "thesis")
+		require.NoError(t, err)
+		var kinds []string
+		for _, r := range results {
+			kinds = append(kinds, r.Type)
+		}
+		assert.Equal(t, []string{"student", "document", "message", "event", "contact"}, kinds)
+		assert.Equal(t, []int{5, 0}, repo.pages[:2])
+	})
+
+	t.Run("Search pages one type", func(t *testing.T) {
+		repo := &TypedSearchRepository{}
+		page, err := services.NewSearchService(repo).Search(ctx, scope, "messages", "thesis", 500, -3)
+		require.NoError(t, err)
+		assert.Equal(t, 7, page.Total)
+		assert.Equal(t, 20, page.Limit)
+		assert.Equal(t, 0, page.Offset)
+		assert.Equal(t, "message", page.Results[0].Type)
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		_, err := services.NewSearchService(&TypedSearchRepository{}).Search(ctx, scope, "grades", "thesis", 10, 0)
+		assert.ErrorIs(t, err, services.ErrSearchUnknownType)
+	})
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/similarity"
+)
+
+// DissertationSlotKeys are the upload slots that hold dissertation
+// manuscripts; versions attached to them are similarity checked.
+var DissertationSlotKeys = []string{"dissertation_draft_file", "dissertation_docx"}
+
+const (
+	// similarityBatch is how many versions one CheckPending call fingerprints
+	// and how many it reports on
+	similarityBatch = 10
+	// similaritySources is how many likely sources are compared passage by
+	// passage
+	similaritySources = 10
+	// minSourceContainment is the estimated share of the text an earlier
+	// version must contain to be compared at all
+	minSourceContainment = 0.02
+	// reportPassages is how many passages a report keeps
+	reportPassages = 20
+	// passageExcerptBytes shortens long passages in reports
+	passageExcerptBytes = 1200
+)
+
+// SimilarityService checks dissertation versions against the earlier
+// dissertation versions of their tenant, the student's own and other
+// students', and attaches a report to the node instance each version was
+// submitted to.
+type SimilarityService struct {
+	repo repository.SimilarityRepository
+}
+
+func NewSimilarityService(repo repository.SimilarityRepository) *SimilarityService {
+	return &SimilarityService{repo: repo}
+}
+
+// CheckPending fingerprints newly extracted dissertation texts and builds
+// reports for versions that have none. It returns how many reports it built.
+func (s *SimilarityService) CheckPending(ctx context.Context) (int, error) {
+	texts, err := s.repo.ListUnfingerprinted(ctx, DissertationSlotKeys, similarityBatch)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range texts {
+		fp := similarity.NewDocument(t.Content).Fingerprint()
+		if err := s.repo.SaveFingerprint(ctx, t.VersionID, fp.Bytes(), fp.Shingles); err != nil {
+			return 0, fmt.Errorf("save fingerprint of version %s: %w", t.VersionID, err)
+		}
+	}
+
+	pending, err := s.repo.ListPendingReports(ctx, DissertationSlotKeys, similarityBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i, v := range pending {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		report, err := s.Check(ctx, v)
+		if err != nil {
+			return i, fmt.Errorf("check version %s: %w", v.VersionID, err)
+		}
+		if err := s.repo.SaveReport(ctx, report); err != nil {
+			return i, fmt.Errorf("save report of version %s: %w", v.VersionID, err)
+		}
+	}
+	return len(pending), nil
+}
+
+// Check compares a version with the earlier ones. Fingerprints pick the
+// likely sources; only those are compared in full.
+func (s *SimilarityService) Check(ctx context.Context, v models.SimilarityCandidate) (*models.SimilarityReport, error) {
+	text, err := s.repo.GetText(ctx, v.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	doc := similarity.NewDocument(text)
+	fp := doc.Fingerprint()
+
+	earlier, err := s.repo.ListEarlierVersions(ctx, v, DissertationSlotKeys)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		models.SimilarityCandidate
+		estimate float64
+	}
+	var candidates []candidate
+	for _, e := range earlier {
+		efp, err := similarity.ParseFingerprint(e.Minhash, e.ShingleCount)
+		if err != nil {
+			continue
+		}
+		if c := similarity.Containment(fp, efp); c >= minSourceContainment {
+			candidates = append(candidates, candidate{e, c})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].estimate > candidates[j].estimate })
+	if len(candidates) > similaritySources {
+		candidates = candidates[:similaritySources]
+	}
+
+	report := &models.SimilarityReport{
+		TenantID:       v.TenantID,
+		NodeInstanceID: v.NodeInstanceID,
+		VersionID:      v.VersionID,
+		ComparedCount:  len(earlier),
+		Sources:        models.SimilaritySources{},
+		Passages:       models.SimilarityPassages{},
+	}
+	var others, own []similarity.Passage
+	for _, c := range candidates {
+		sourceText, err := s.repo.GetText(ctx, c.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		source := similarity.NewDocument(sourceText)
+		passages := similarity.Compare(doc, source)
+		if len(passages) == 0 {
+			continue
+		}
+		isOwn := c.StudentID == v.StudentID
+		if isOwn {
+			own = append(own, passages...)
+		} else {
+			others = append(others, passages...)
+		}
+		report.Sources = append(report.Sources, models.SimilaritySource{
+			VersionID:   c.VersionID,
+			Filename:    c.Filename,
+			StudentID:   c.StudentID,
+			StudentName: c.StudentName,
+			Own:         isOwn,
+			Score:       similarity.Coverage(doc, passages),
+			Passages:    len(passages),
+		})
+		for _, p := range passages {
+			report.Passages = append(report.Passages, models.SimilarityPassage{
+				SourceVersionID: c.VersionID,
+				Text:            similarity.Excerpt(p.Text(doc), passageExcerptBytes),
+				SourceText:      similarity.Excerpt(p.SourceText(source), passageExcerptBytes),
+				Words:           p.Words,
+			})
+		}
+	}
+	report.Score = similarity.Coverage(doc, others)
+	report.SelfScore = similarity.Coverage(doc, own)
+
+	sort.SliceStable(report.Sources, func(i, j int) bool { return report.Sources[i].Score > report.Sources[j].Score })
+	// Passages from other students come first: matches with the student's
+	// own drafts are expected
+	ownSource := map[string]bool{}
+	for _, src := range report.Sources {
+		ownSource[src.VersionID] = src.Own
+	}
+	sort.SliceStable(report.Passages, func(i, j int) bool {
+		a, b := report.Passages[i], report.Passages[j]
+		if ownSource[a.SourceVersionID] != ownSource[b.SourceVersionID] {
+			return !ownSource[a.SourceVersionID]
+		}
+		return a.Words > b.Words
+	})
+	if len(report.Passages) > reportPassages {
+		report.Passages = report.Passages[:reportPassages]
+	}
+	return report, nil
+}
+
+// ListNodeReports returns the similarity reports of a student's node,
+// newest first.
+func (s *SimilarityService) ListNodeReports(ctx context.Context, tenantID, studentID, nodeID string) ([]models.SimilarityReport, error) {
+	return s.repo.ListNodeReports(ctx, tenantID, studentID, nodeID)
+}
@@ -0,0 +1,77 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func words(prefix string, n int) string {
+	w := make([]string, n)
+	for i := range w {
+		w[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return strings.Join(w, " ")
+}
+
+func TestSimilarityService_CheckPending(t *testing.T) {
+	draft := words("own", 200)
+	borrowed := words("borrowed", 50)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &MemSimilarityRepository{
+		reports: map[string]*models.SimilarityReport{},
+		texts: map[string]string{
+			"other":     words("other", 100) + ". " + borrowed + ". " + words("more", 100),
+			"unrelated": words("unrelated", 300),
+			"draft-1":   draft,
+			"draft-2":   draft + "\n\n" + borrowed,
+		},
+		versions: []models.SimilarityCandidate{
+			{VersionID: "draft-2", TenantID: "t1", NodeInstanceID: "ni-2", StudentID: "s1", CreatedAt: base.Add(3 * time.Hour)},
+			{VersionID: "other", TenantID: "t1", NodeInstanceID: "ni-o", StudentID: "s2", StudentName: "Bob Ray", Filename: "thesis.pdf", CreatedAt: base},
+			{VersionID: "unrelated", TenantID: "t1", NodeInstanceID: "ni-u", StudentID: "s3", CreatedAt: base.Add(time.Hour)},
+			{VersionID: "draft-1", TenantID: "t1", NodeInstanceID: "ni-1", StudentID: "s1", Filename: "draft.docx", CreatedAt: base.Add(2 * time.Hour)},
+		},
+	}
+	svc := services.NewSimilarityService(repo)
+
+	n, err := svc.CheckPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	first := repo.reports["other"]
+	assert.Equal(t, 0, first.ComparedCount)
+	assert.Zero(t, first.Score)
+	assert.Empty(t, first.Passages)
+
+	report := repo.reports["draft-2"]
+	assert.Equal(t, "ni-2", report.NodeInstanceID)
+	assert.Equal(t, 3, report.ComparedCount)
+	// draft-2 has 246 shingles: 196 from the own draft, 46 from the other thesis
+	assert.InDelta(t, 46.0/246, report.Score, 1e-9)
+	assert.InDelta(t, 196.0/246, report.SelfScore, 1e-9)
+
+	require.Len(t, report.Sources, 2, "the unrelated thesis is not a source")
+	assert.Equal(t, "draft-1", report.Sources[0].VersionID)
+	assert.True(t, report.Sources[0].Own)
+	assert.Equal(t, "other", report.Sources[1].VersionID)
+	assert.Equal(t, "Bob Ray", report.Sources[1].StudentName)
+	assert.False(t, report.Sources[1].Own)
+
+	require.Len(t, report.Passages, 2)
+	assert.Equal(t, "other", report.Passages[0].SourceVersionID, "other students' passages come first")
+	assert.Equal(t, borrowed, report.Passages[0].Text)
+	assert.Equal(t, 50, report.Passages[0].Words)
+	assert.Equal(t, 200, report.Passages[1].Words)
+
+	n, err = svc.CheckPending(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "every version has a report")
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/auth"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+)
+
+// Errors shared by the single sign-on protocols; handlers turn them into
+// codes the login page can show.
+var (
+	ErrSSONotConfigured   = errors.New("single sign-on is not configured")
+	ErrSSOLoginExpired    = errors.New("sign-on request expired or was already used")
+	ErrSSODeclined        = errors.New("sign-on was declined at the identity provider")
+	ErrSSOEmailNotAllowed = errors.New("email is missing, unverified or not allowed")
+	ErrSSONoAccount       = errors.New("no account with this email")
+	ErrSSOAccountInactive = errors.New("account inactive")
+	ErrSSOAccessDenied    = errors.New("access denied to this portal")
+)
+
+// SSOLoginResult is a completed SSO login.
+type SSOLoginResult struct {
+	LoginResponse
+	TenantID     string
+	RedirectPath string
+	// Provisioned is set when the login created the account
+	Provisioned bool
+}
+
+// ssoIdentity is who the identity provider says signed in.
+type ssoIdentity struct {
+	Email     string
+	FirstName string
+	LastName  string
+	// Role is the tenant role the provider grants, or "" for none
+	Role string
+}
+
+// ssoPolicy is what a tenant allows SSO logins to do.
+type ssoPolicy struct {
+	JITProvisioning bool
+	AllowedDomains  []string
+	// SyncRole updates existing non-admin memberships to the provider's role
+	SyncRole bool
+}
+
+// ssoAccounts turns an identity vouched for by a tenant's provider into a
+// session, the same way for every protocol: users are matched by email and
+// get the token a password login would, after the same second factor.
+type ssoAccounts struct {
+	users   repository.UserRepository
+	tenants repository.TenantRepository
+	auth    *AuthService
+}
+
+func (a *ssoAccounts) signIn(ctx context.Context, tenantID, redirectPath string, id ssoIdentity, p ssoPolicy) (*SSOLoginResult, error) {
+	email := strings.TrimSpace(id.Email)
+	if email == "" || !emailDomainAllowed(email, p.AllowedDomains) {
+		log.Printf("[SSO] Rejected email=%q for tenant=%s", email, tenantID)
+		return nil, ErrSSOEmailNotAllowed
+	}
+
+	result := &SSOLoginResult{TenantID: tenantID, RedirectPath: redirectPath}
+	user, err := a.users.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		// GetByEmail skips inactive accounts; those must not get a twin
+		if taken, err := a.users.EmailExists(ctx, email, ""); err != nil {
+			return nil, err
+		} else if taken {
+			return nil, ErrSSOAccountInactive
+		}
+		if !p.JITProvisioning {
+			return nil, ErrSSONoAccount
+		}
+		if id.Role == "" {
+			return nil, ErrSSOAccessDenied
+		}
+		user, err = a.provision(ctx, email, id)
+		if err != nil {
+			return nil, err
+		}
+		result.Provisioned = true
+	} else if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrSSOAccountInactive
+	}
+	// Each tenant runs its own provider, so none of them may vouch for a
+	// platform-wide account; superadmins sign in with their password
+	if user.Role == models.RoleSuperAdmin || user.IsSuperadmin {
+		log.Printf("[SSO] Refused superadmin userID=%s for tenant=%s", user.ID, tenantID)
+		return nil, ErrSSOAccessDenied
+	}
+
+	role, err := a.users.GetTenantRole(ctx, user.ID, tenantID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		// Only accounts whose role the provider agrees with join the tenant
+		if !p.JITProvisioning || id.Role == "" || string(user.Role) != id.Role {
+			return nil, ErrSSOAccessDenied
+		}
+		role = id.Role
+		err = a.tenants.AddUserToTenant(ctx, user.ID, tenantID, role, result.Provisioned)
+	case err == nil && p.SyncRole && role == string(models.RoleAdmin):
+		// Admin memberships are managed in the portal, not by the provider
+	case err == nil && p.SyncRole && id.Role == "":
+		return nil, ErrSSOAccessDenied
+	case err == nil && p.SyncRole && id.Role != role:
+		role = id.Role
+		err = a.syncRole(ctx, user.ID, tenantID, role)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The tenant's 2FA policy applies to SSO logins as to password ones
+	session, err := a.auth.beginSession(ctx, user, role, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[SSO] Login: userID=%s, role=%s, tenantID=%s, provisioned=%v, mfa=%v", user.ID, role, tenantID, result.Provisioned, session.MFA != nil)
+	result.LoginResponse = *session
+	return result, nil
+}
+
+// provision creates an account for an email the provider vouched for. Its
+// random password is never told to anyone; the user can set one with a
+// password reset.
+func (a *ssoAccounts) provision(ctx context.Context, email string, id ssoIdentity) (*models.User, error) {
+	first, last := strings.TrimSpace(id.FirstName), strings.TrimSpace(id.LastName)
+	if first == "" {
+		first, _, _ = strings.Cut(email, "@")
+	}
+	username, err := generateUsername(ctx, a.users, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate username: %w", err)
+	}
+	hash, err := auth.HashPassword(auth.GeneratePass())
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username:     username,
+		Email:        email,
+		FirstName:    first,
+		LastName:     last,
+		Role:         models.Role(id.Role),
+		PasswordHash: hash,
+		IsActive:     true,
+	}
+	if user.ID, err = a.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// syncRole moves an existing membership to the provider's role, keeping
+// whether it is the user's primary tenant.
+func (a *ssoAccounts) syncRole(ctx context.Context, userID, tenantID, role string) error {
+	m, err := a.tenants.GetUserMembership(ctx, userID, tenantID)
+	if err != nil {
+		return err
+	}
+	isPrimary := m != nil && m.IsPrimary
+	log.Printf("[SSO] Role of userID=%s in tenantID=%s set to %s by the identity provider", userID, tenantID, role)
+	return a.tenants.AddUserToTenant(ctx, userID, tenantID, role, isPrimary)
+}
+
+// normalizeDomains lowercases allowed email domains and drops a leading "@".
+func normalizeDomains(domains []string) []string {
+	out := []string{}
+	for _, d := range domains {
+		if d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@")); d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func emailDomainAllowed(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	return at >= 0 && slices.Contains(domains, strings.ToLower(email[at+1:]))
+}
+
+// SafeRedirectPath keeps a post-login redirect on the frontend: anything but
+// a local absolute path becomes "/".
+func SafeRedirectPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.ContainsAny(p, "\\\r\n") {
+		return "/"
+	}
+	return p
+}
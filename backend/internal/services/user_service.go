@@ -35,6 +35,8 @@ type UserService struct {
 	cfg      config.AppConfig
 	emailSvc EmailSender
 	storage  StorageClient
+	rooms    RoomSyncer
+	sessions SessionRevoker
 }
 
 func NewUserService(repo repository.UserRepository, rds *redis.Client, cfg config.AppConfig, emailSvc EmailSender, storage StorageClient) *UserService {
@@ -47,6 +49,32 @@ func NewUserService(repo repository.UserRepository, rds *redis.Client, cfg confi
 	}
 }
 
+// SetRoomSync keeps cohort and advisory chat rooms in step with user changes.
+func (s *UserService) SetRoomSync(rooms RoomSyncer) {
+	s.rooms = rooms
+}
+
+// SetSessionRevoker lets the service sign users out when they are
+// deactivated or an admin resets their password.
+func (s *UserService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+// revokeSessions signs the user out everywhere, if a revoker is set.
+func (s *UserService) revokeSessions(ctx context.Context, userID string) error {
+	if s.sessions == nil {
+		return nil
+	}
+	return s.sessions.RevokeAllSessions(ctx, userID)
+}
+
+// syncRooms reconciles the tenant's managed chat rooms, if configured.
+func (s *UserService) syncRooms(ctx context.Context, tenantID string) {
+	if s.rooms != nil && tenantID != "" {
+		s.rooms.SyncTenantRooms(ctx, tenantID)
+	}
+}
+
 // CreateUser generates username, password, hashes it, and stores the user.
 // Returns the created user object and the temporary password (plain text).
 func (s *UserService) CreateUser(ctx context.Context, req CreateUserRequest) (*models.User, string, error) {
@@ -94,6 +122,9 @@ func (s *UserService) CreateUser(ctx context.Context, req CreateUserRequest) (*m
 	// 5. Invalidate List Cache (if applicable)
 	s.invalidateListCache(ctx)
 
+	// 6. Join cohort and advisory rooms
+	s.syncRooms(ctx, req.TenantID)
+
 	return user, tempPass, nil
 }
 
@@ -141,6 +172,9 @@ func (s *UserService) SetActive(ctx context.Context, userID string, active bool)
 	if err != nil { return err }
 	
 	if s.rds != nil { s.rds.Del(ctx, "user:"+userID) }
+	if !active {
+		return s.revokeSessions(ctx, userID)
+	}
 	return nil
 }
 
@@ -170,6 +204,7 @@ func (s *UserService) ResetPasswordForUser(ctx context.Context, id string) (stri
 	
 	err = s.repo.UpdatePassword(ctx, id, hash)
 	if s.rds != nil { s.rds.Del(ctx, "user:"+id) }
+	if err := s.revokeSessions(ctx, id); err != nil { return "", "", err }
 	return user.Username, tempPass, nil
 }
 
@@ -343,6 +378,9 @@ func (s *UserService) AdminUpdateUser(ctx context.Context, req AdminUpdateUserRe
 		err = s.repo.ReplaceAdvisors(ctx, req.TargetUserID, req.AdvisorIDs, req.TenantID)
 		if err != nil { return err }
 	}
+
+	// Cohort, advisors, role or name may have moved the user between rooms
+	s.syncRooms(ctx, req.TenantID)
 	
 	return nil
 }
@@ -426,6 +464,12 @@ func (s *UserService) invalidateListCache(ctx context.Context) {
 }
 
 func (s *UserService) generateUsername(ctx context.Context, firstName, lastName string) (string, error) {
+	return generateUsername(ctx, s.repo, firstName, lastName)
+}
+
+// generateUsername picks an unused username from the initials and four
+// random digits.
+func generateUsername(ctx context.Context, repo repository.UserRepository, firstName, lastName string) (string, error) {
 	first := firstLatinInitial(firstName)
 	if first == "" {
 		first = "x"
@@ -443,7 +487,7 @@ func (s *UserService) generateUsername(ctx context.Context, firstName, lastName
 			return "", err
 		}
 		candidate := base + suffix
-		exists, err := s.repo.Exists(ctx, candidate)
+		exists, err := repo.Exists(ctx, candidate)
 		if err != nil {
 			return "", err
 		}
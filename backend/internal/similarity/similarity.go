@@ -0,0 +1,304 @@
+// Package similarity compares texts by word shingles. MinHash fingerprints
+// estimate how much two texts overlap cheaply enough to check a text
+// against every earlier submission; the few likely sources are then
+// compared exactly to find the passages they share.
+package similarity
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// ShingleSize is how many consecutive words make up a shingle.
+	ShingleSize = 5
+	// SignatureSize is the number of MinHash values in a fingerprint.
+	SignatureSize = 128
+	// MinPassageWords is the shortest shared run of words reported as a
+	// passage.
+	MinPassageWords = 8
+	// maxPositions bounds how many occurrences of one shingle in a source
+	// are tried when aligning, so boilerplate cannot make Compare quadratic.
+	maxPositions = 16
+)
+
+// ErrBadFingerprint is returned for stored fingerprints of the wrong size.
+var ErrBadFingerprint = errors.New("similarity: malformed fingerprint")
+
+// seeds salt the MinHash functions. Stored fingerprints depend on them, so
+// they must never change.
+var seeds = func() [SignatureSize]uint64 {
+	var s [SignatureSize]uint64
+	x := uint64(0x5eed_d155_e47a_7105)
+	for i := range s {
+		x += 0x9e3779b97f4a7c15
+		s[i] = mix(x)
+	}
+	return s
+}()
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+type token struct {
+	start, end int // byte offsets in the text
+}
+
+// Document is a text prepared for comparison.
+type Document struct {
+	text   string
+	tokens []token
+	// hashes[i] is the shingle of the words starting at tokens[i]
+	hashes []uint64
+}
+
+// NewDocument splits text into words, case-folded, and hashes its shingles.
+// Punctuation and spacing do not matter.
+func NewDocument(text string) *Document {
+	d := &Document{text: text}
+	var words []string
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			d.tokens = append(d.tokens, token{start, i})
+			words = append(words, fold(text[start:i]))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		d.tokens = append(d.tokens, token{start, len(text)})
+		words = append(words, fold(text[start:]))
+	}
+
+	if len(words) < ShingleSize {
+		return d
+	}
+	d.hashes = make([]uint64, len(words)-ShingleSize+1)
+	h := fnv.New64a()
+	for i := range d.hashes {
+		h.Reset()
+		for _, w := range words[i : i+ShingleSize] {
+			h.Write([]byte(w))
+			h.Write([]byte{0})
+		}
+		d.hashes[i] = h.Sum64()
+	}
+	return d
+}
+
+// fold lower-cases a word and treats ё as е, which Russian texts use
+// interchangeably.
+func fold(w string) string {
+	return strings.ReplaceAll(strings.ToLower(w), "ё", "е")
+}
+
+// Words is the number of words in the document.
+func (d *Document) Words() int {
+	return len(d.tokens)
+}
+
+// Fingerprint is the MinHash signature of a document's shingle set.
+type Fingerprint struct {
+	Signature [SignatureSize]uint64
+	// Shingles is the number of distinct shingles
+	Shingles int
+}
+
+// Fingerprint computes the document's MinHash signature.
+func (d *Document) Fingerprint() Fingerprint {
+	var f Fingerprint
+	for i := range f.Signature {
+		f.Signature[i] = ^uint64(0)
+	}
+	seen := make(map[uint64]struct{}, len(d.hashes))
+	for _, h := range d.hashes {
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+		for i, seed := range seeds {
+			if v := mix(h ^ seed); v < f.Signature[i] {
+				f.Signature[i] = v
+			}
+		}
+	}
+	f.Shingles = len(seen)
+	return f
+}
+
+// Bytes encodes the signature for storage.
+func (f Fingerprint) Bytes() []byte {
+	b := make([]byte, 8*SignatureSize)
+	for i, v := range f.Signature {
+		binary.BigEndian.PutUint64(b[8*i:], v)
+	}
+	return b
+}
+
+// ParseFingerprint decodes a signature written by Bytes.
+func ParseFingerprint(b []byte, shingles int) (Fingerprint, error) {
+	f := Fingerprint{Shingles: shingles}
+	if len(b) != 8*SignatureSize {
+		return f, ErrBadFingerprint
+	}
+	for i := range f.Signature {
+		f.Signature[i] = binary.BigEndian.Uint64(b[8*i:])
+	}
+	return f, nil
+}
+
+// Jaccard estimates |A∩B| / |A∪B| of the two shingle sets.
+func Jaccard(a, b Fingerprint) float64 {
+	if a.Shingles == 0 || b.Shingles == 0 {
+		return 0
+	}
+	same := 0
+	for i := range a.Signature {
+		if a.Signature[i] == b.Signature[i] {
+			same++
+		}
+	}
+	return float64(same) / SignatureSize
+}
+
+// Containment estimates the share of a's shingles that also occur in b. It
+// stays high when a copies a chapter of a much longer b, where the Jaccard
+// index would be small.
+func Containment(a, b Fingerprint) float64 {
+	j := Jaccard(a, b)
+	if j == 0 {
+		return 0
+	}
+	c := j * float64(a.Shingles+b.Shingles) / ((1 + j) * float64(a.Shingles))
+	return min(c, 1)
+}
+
+// Passage is a run of text a document shares with a source.
+type Passage struct {
+	// Start and End are byte offsets of the passage in the document
+	Start, End int
+	// SourceStart and SourceEnd are byte offsets of it in the source
+	SourceStart, SourceEnd int
+	// Words is the length of the passage in the document
+	Words int
+
+	pos, n int // first shingle and shingle count in the document
+}
+
+// Text returns the passage as written in the document.
+func (p Passage) Text(doc *Document) string {
+	return doc.text[p.Start:p.End]
+}
+
+// SourceText returns the passage as written in the source.
+func (p Passage) SourceText(source *Document) string {
+	return source.text[p.SourceStart:p.SourceEnd]
+}
+
+// Compare finds the passages of doc that also occur in source, in document
+// order. Runs interrupted by a word or two of edits are reported as one
+// passage.
+func Compare(doc, source *Document) []Passage {
+	if len(doc.hashes) == 0 || len(source.hashes) == 0 {
+		return nil
+	}
+	index := make(map[uint64][]int, len(source.hashes))
+	for j, h := range source.hashes {
+		if len(index[h]) < maxPositions {
+			index[h] = append(index[h], j)
+		}
+	}
+
+	minRun := MinPassageWords - ShingleSize + 1
+	var passages []Passage
+	var last *Passage
+	lastSource := -1 // shingle after the previous passage in the source
+	for i := 0; i < len(doc.hashes); {
+		best, bestJ := 0, 0
+		for _, j := range index[doc.hashes[i]] {
+			n := 1
+			for i+n < len(doc.hashes) && j+n < len(source.hashes) && doc.hashes[i+n] == source.hashes[j+n] {
+				n++
+			}
+			if n > best {
+				best, bestJ = n, j
+			}
+		}
+		if best < minRun {
+			i++
+			continue
+		}
+
+		// A word changed between two runs breaks ShingleSize shingles
+		gap := 2 * ShingleSize
+		if last != nil && i-(last.pos+last.n) <= gap && bestJ >= lastSource && bestJ-lastSource <= gap {
+			last.n = i + best - last.pos
+		} else {
+			passages = append(passages, Passage{pos: i, n: best, SourceStart: source.tokens[bestJ].start})
+			last = &passages[len(passages)-1]
+		}
+		last.SourceEnd = source.tokens[bestJ+best+ShingleSize-2].end
+		lastSource = bestJ + best
+		i += best
+	}
+
+	for k := range passages {
+		p := &passages[k]
+		p.Start = doc.tokens[p.pos].start
+		p.End = doc.tokens[p.pos+p.n+ShingleSize-2].end
+		p.Words = p.n + ShingleSize - 1
+	}
+	return passages
+}
+
+// Coverage is the share of doc's shingles inside the given passages, which
+// may come from several sources and overlap.
+func Coverage(doc *Document, passages []Passage) float64 {
+	if len(doc.hashes) == 0 {
+		return 0
+	}
+	covered := make([]bool, len(doc.hashes))
+	n := 0
+	for _, p := range passages {
+		for i := p.pos; i < p.pos+p.n && i < len(covered); i++ {
+			if !covered[i] {
+				covered[i] = true
+				n++
+			}
+		}
+	}
+	return float64(n) / float64(len(doc.hashes))
+}
+
+// Excerpt shortens a passage for display to about max bytes, cutting at a
+// word boundary.
+func Excerpt(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if i := strings.LastIndexAny(s[:cut], " \n\t"); i > max/2 {
+		cut = i
+	}
+	return strings.TrimSpace(s[:cut]) + "…"
+}
@@ -0,0 +1,92 @@
+package similarity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filler returns n distinct words so unrelated text shares no shingles.
+func filler(prefix string, n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return strings.Join(words, " ")
+}
+
+const copied = "The prevalence of arterial hypertension among rural adults in Kazakhstan remains underestimated by routine screening."
+
+func TestCompare(t *testing.T) {
+	doc := NewDocument(filler("a", 40) + ". " + copied + " " + filler("b", 40))
+	// The source capitalises differently, punctuates and spells ё
+	source := NewDocument(filler("c", 20) + " " + strings.ToUpper(copied[:40]) + ",\n" + copied[40:] + " " + filler("d", 20))
+
+	passages := Compare(doc, source)
+	require.Len(t, passages, 1)
+	p := passages[0]
+	assert.Equal(t, strings.TrimSuffix(copied, "."), p.Text(doc))
+	assert.Equal(t, strings.ToUpper(copied[:40])+",\n"+strings.TrimSuffix(copied[40:], "."), p.SourceText(source))
+	assert.Equal(t, 15, p.Words)
+
+	// 11 of the document's 91 shingles lie inside the passage
+	assert.InDelta(t, 11/float64(doc.Words()-ShingleSize+1), Coverage(doc, passages), 1e-9)
+
+	t.Run("Edited word keeps one passage", func(t *testing.T) {
+		text := copied + " It was confirmed by three independent cohort studies in Almaty."
+		edited := NewDocument(strings.Replace(text, "remains", "is", 1))
+		passages := Compare(edited, NewDocument(text))
+		require.Len(t, passages, 1)
+		assert.Equal(t, 25, passages[0].Words)
+	})
+
+	t.Run("Short runs are ignored", func(t *testing.T) {
+		short := NewDocument(filler("x", 10) + " the prevalence of arterial hypertension among " + filler("y", 10))
+		assert.Empty(t, Compare(short, NewDocument(copied)))
+	})
+
+	t.Run("Russian text folds case and ё", func(t *testing.T) {
+		ru := NewDocument("Ещё раз отметим, что распространённость гипертонии среди сельского населения остаётся высокой")
+		src := NewDocument("ЕЩЕ РАЗ ОТМЕТИМ что распространенность гипертонии среди сельского населения остается высокой.")
+		passages := Compare(ru, src)
+		require.Len(t, passages, 1)
+		assert.Equal(t, 11, passages[0].Words)
+		assert.Equal(t, 1.0, Coverage(ru, passages))
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	base := filler("w", 2000)
+	a := NewDocument(base).Fingerprint()
+	assert.Equal(t, 1996, a.Shingles)
+
+	t.Run("Round trip", func(t *testing.T) {
+		b, err := ParseFingerprint(a.Bytes(), a.Shingles)
+		require.NoError(t, err)
+		assert.Equal(t, a, b)
+		_, err = ParseFingerprint([]byte{1, 2, 3}, 1)
+		assert.ErrorIs(t, err, ErrBadFingerprint)
+	})
+
+	t.Run("Estimates", func(t *testing.T) {
+		assert.Equal(t, 1.0, Jaccard(a, a))
+
+		// A chapter of a quarter of the text copied into a new document
+		chapter := NewDocument(strings.Join(strings.Fields(base)[:500], " ")).Fingerprint()
+		assert.InDelta(t, 1.0, Containment(chapter, a), 0.25)
+		assert.InDelta(t, 0.25, Jaccard(chapter, a), 0.1)
+
+		other := NewDocument(filler("z", 2000)).Fingerprint()
+		assert.Less(t, Containment(other, a), 0.05)
+		assert.Zero(t, Containment(Fingerprint{}, a))
+	})
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "short", Excerpt("short", 10))
+	assert.Equal(t, "one two…", Excerpt("one two three", 9))
+	assert.Equal(t, "ааа…", Excerpt("аааааааа", 7))
+}
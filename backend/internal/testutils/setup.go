@@ -146,7 +146,7 @@ func cleanupDB(db *sqlx.DB, schema string) {
 		"user_tenant_memberships",
 		"profile_submissions", "profile_audit_log", "email_verification_tokens", "rate_limit_events",
 		"specialty_programs",
-		"playbook_active_version", "playbook_versions",
+		"playbook_activations", "playbook_active_version", "playbook_versions",
 		"contacts",
 		// Parent tables last
 		"users",
@@ -164,13 +164,16 @@ func cleanupDB(db *sqlx.DB, schema string) {
 	// Truncate node_state_transitions separately (it's often handled differently)
 	db.Exec("TRUNCATE TABLE node_state_transitions CASCADE")
 
-	// Seed default transitions (from migration 0006)
+	// Seed default transitions (from migrations 0006, 0080 and 0081)
 	db.Exec(`INSERT INTO node_state_transitions(from_state, to_state, allowed_roles) VALUES
 		('active','submitted', ARRAY['student']),
 		('submitted','needs_fixes', ARRAY['advisor','secretary','chair','admin']),
-		('submitted','done', ARRAY['advisor','secretary','chair','admin']),
+		('submitted','done', ARRAY['student','advisor','secretary','chair','admin']),
+		('active','done', ARRAY['student','secretary','chair','admin']),
 		('needs_fixes','submitted', ARRAY['student']),
-		('done','submitted', ARRAY['admin'])
+		('done','submitted', ARRAY['admin']),
+		('under_review','needs_fixes', ARRAY['advisor','secretary','chair','admin']),
+		('under_review','done', ARRAY['advisor','secretary','chair','admin'])
 		ON CONFLICT DO NOTHING`)
 
 	// Seed default tenant for tests
@@ -0,0 +1,351 @@
+// Package textdiff compares two versions of a document paragraph by
+// paragraph. Paragraphs that were edited rather than replaced come back as
+// changes with a word-level diff.
+package textdiff
+
+import "strings"
+
+// Op is what happened to a paragraph or a run of words.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+	// Change is an edited paragraph; its segments show the edit
+	Change Op = "change"
+	// Skip stands for unchanged paragraphs left out around the edits
+	Skip Op = "skip"
+)
+
+const (
+	// maxCells bounds the comparison table; larger inputs are reported as
+	// replaced wholesale
+	maxCells = 4_000_000
+	// minChangeSimilarity is how alike a deleted and an inserted paragraph
+	// must be to count as one edited paragraph
+	minChangeSimilarity = 0.5
+)
+
+// Segment is a run of words of an edited paragraph.
+type Segment struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Block is one paragraph of the diff, or a run of skipped ones.
+type Block struct {
+	Op Op `json:"op"`
+	// OldIndex and NewIndex are the paragraph's positions in the two
+	// versions; an inserted paragraph has the old position it follows and a
+	// deleted one the new position it preceded
+	OldIndex int `json:"old_index"`
+	NewIndex int `json:"new_index"`
+	// Text is the paragraph as it is now, or as it was if deleted
+	Text string `json:"text,omitempty"`
+	// OldText is the text an edited paragraph had before
+	OldText  string    `json:"old_text,omitempty"`
+	Segments []Segment `json:"segments,omitempty"`
+	// Count is the number of paragraphs a skip stands for
+	Count int `json:"count,omitempty"`
+}
+
+// Stats counts paragraphs by what happened to them.
+type Stats struct {
+	Unchanged int `json:"unchanged"`
+	Inserted  int `json:"inserted"`
+	Deleted   int `json:"deleted"`
+	Changed   int `json:"changed"`
+}
+
+// Diff is the paragraph-level difference between two texts.
+type Diff struct {
+	Stats  Stats   `json:"stats"`
+	Blocks []Block `json:"blocks"`
+}
+
+// Paragraphs splits text at blank lines. Line breaks and runs of spaces
+// inside a paragraph become single spaces, so re-wrapped lines compare
+// equal.
+func Paragraphs(text string) []string {
+	var out []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.Join(strings.Fields(p), " "); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Compare diffs two texts by paragraph. Only context unchanged paragraphs
+// are kept on each side of an edit, the rest are skipped; a negative context
+// keeps them all.
+func Compare(oldText, newText string, context int) Diff {
+	a, b := Paragraphs(oldText), Paragraphs(newText)
+	var d Diff
+	for _, blk := range blocks(a, b) {
+		switch blk.Op {
+		case Equal:
+			d.Stats.Unchanged++
+		case Insert:
+			d.Stats.Inserted++
+		case Delete:
+			d.Stats.Deleted++
+		case Change:
+			d.Stats.Changed++
+		}
+		d.Blocks = append(d.Blocks, blk)
+	}
+	if context >= 0 {
+		d.Blocks = collapse(d.Blocks, context)
+	}
+	if d.Blocks == nil {
+		d.Blocks = []Block{}
+	}
+	return d
+}
+
+// blocks returns one block per paragraph. Runs of deleted and inserted
+// paragraphs are searched for edited ones.
+func blocks(a, b []string) []Block {
+	var out []Block
+	var dels, ins []int
+	i, j := 0, 0
+	flush := func() {
+		out = append(out, hunk(a, b, dels, ins, i, j)...)
+		dels, ins = nil, nil
+	}
+	for _, op := range align(a, b) {
+		switch op {
+		case Equal:
+			flush()
+			out = append(out, Block{Op: Equal, OldIndex: i, NewIndex: j, Text: b[j]})
+			i++
+			j++
+		case Delete:
+			dels = append(dels, i)
+			i++
+		case Insert:
+			ins = append(ins, j)
+			j++
+		}
+	}
+	flush()
+	return out
+}
+
+// hunk pairs the deleted and inserted paragraphs between two unchanged ones
+// into edits, in order, and reports the rest as they are. nextOld and
+// nextNew are the positions after the hunk.
+func hunk(a, b []string, dels, ins []int, nextOld, nextNew int) []Block {
+	if len(dels) == 0 && len(ins) == 0 {
+		return nil
+	}
+	pairs := map[int]int{}
+	if len(dels)*len(ins) <= maxCells/100 {
+		from := 0
+		for _, di := range dels {
+			for k := from; k < len(ins); k++ {
+				if similarity(a[di], b[ins[k]]) >= minChangeSimilarity {
+					pairs[di] = k
+					from = k + 1
+					break
+				}
+			}
+		}
+	}
+
+	var out []Block
+	newAt := func(k int) int {
+		if k < len(ins) {
+			return ins[k]
+		}
+		return nextNew
+	}
+	oldAt := func(d int) int {
+		if d < len(dels) {
+			return dels[d]
+		}
+		return nextOld
+	}
+	d, k := 0, 0
+	emit := func(toDel, toIns int) {
+		for ; d < toDel; d++ {
+			out = append(out, Block{Op: Delete, OldIndex: dels[d], NewIndex: newAt(k), Text: a[dels[d]]})
+		}
+		for ; k < toIns; k++ {
+			out = append(out, Block{Op: Insert, OldIndex: oldAt(d), NewIndex: ins[k], Text: b[ins[k]]})
+		}
+	}
+	for n, di := range dels {
+		pk, ok := pairs[di]
+		if !ok {
+			continue
+		}
+		emit(n, pk)
+		out = append(out, Block{
+			Op: Change, OldIndex: di, NewIndex: ins[pk],
+			Text: b[ins[pk]], OldText: a[di],
+			Segments: wordDiff(a[di], b[ins[pk]]),
+		})
+		d, k = n+1, pk+1
+	}
+	emit(len(dels), len(ins))
+	return out
+}
+
+// align returns the edit script turning a into b, keeping the longest
+// common subsequence.
+func align(a, b []string) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]Op, 0, len(a)+len(b))
+	for k := 0; k < prefix; k++ {
+		ops = append(ops, Equal)
+	}
+	ops = append(ops, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, Equal)
+	}
+	return ops
+}
+
+func lcs(a, b []string) []Op {
+	n, m := len(a), len(b)
+	var ops []Op
+	if n*m > maxCells || n == 0 || m == 0 {
+		for k := 0; k < n; k++ {
+			ops = append(ops, Delete)
+		}
+		for k := 0; k < m; k++ {
+			ops = append(ops, Insert)
+		}
+		return ops
+	}
+	// t[i][j] is the LCS length of a[i:] and b[j:]
+	w := m + 1
+	t := make([]int32, (n+1)*w)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				t[i*w+j] = t[(i+1)*w+j+1] + 1
+			case t[(i+1)*w+j] >= t[i*w+j+1]:
+				t[i*w+j] = t[(i+1)*w+j]
+			default:
+				t[i*w+j] = t[i*w+j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Equal)
+			i++
+			j++
+		case t[(i+1)*w+j] >= t[i*w+j+1]:
+			ops = append(ops, Delete)
+			i++
+		default:
+			ops = append(ops, Insert)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, Delete)
+	}
+	for ; j < m; j++ {
+		ops = append(ops, Insert)
+	}
+	return ops
+}
+
+// similarity is the Dice coefficient of the two paragraphs' words.
+func similarity(x, y string) float64 {
+	xs, ys := strings.Fields(strings.ToLower(x)), strings.Fields(strings.ToLower(y))
+	if len(xs)+len(ys) == 0 {
+		return 1
+	}
+	count := map[string]int{}
+	for _, w := range xs {
+		count[w]++
+	}
+	common := 0
+	for _, w := range ys {
+		if count[w] > 0 {
+			count[w]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(xs)+len(ys))
+}
+
+// wordDiff splits an edited paragraph into runs of kept, removed and added
+// words.
+func wordDiff(oldPara, newPara string) []Segment {
+	a, b := strings.Fields(oldPara), strings.Fields(newPara)
+	var segs []Segment
+	i, j := 0, 0
+	for _, op := range align(a, b) {
+		var word string
+		switch op {
+		case Equal, Insert:
+			word = b[j]
+		case Delete:
+			word = a[i]
+		}
+		if op != Insert {
+			i++
+		}
+		if op != Delete {
+			j++
+		}
+		if n := len(segs); n > 0 && segs[n-1].Op == op {
+			segs[n-1].Text += " " + word
+		} else {
+			segs = append(segs, Segment{Op: op, Text: word})
+		}
+	}
+	return segs
+}
+
+// collapse replaces the unchanged paragraphs further than context from any
+// edit with skips.
+func collapse(in []Block, context int) []Block {
+	var out []Block
+	for start := 0; start < len(in); {
+		if in[start].Op != Equal {
+			out = append(out, in[start])
+			start++
+			continue
+		}
+		end := start
+		for end < len(in) && in[end].Op == Equal {
+			end++
+		}
+		keepHead, keepTail := context, context
+		if start == 0 {
+			keepHead = 0
+		}
+		if end == len(in) {
+			keepTail = 0
+		}
+		if end-start <= keepHead+keepTail {
+			out = append(out, in[start:end]...)
+		} else {
+			out = append(out, in[start:start+keepHead]...)
+			first := in[start+keepHead]
+			out = append(out, Block{Op: Skip, OldIndex: first.OldIndex, NewIndex: first.NewIndex, Count: end - start - keepHead - keepTail})
+			out = append(out, in[end-keepTail:end]...)
+		}
+		start = end
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParagraphs(t *testing.T) {
+	assert.Equal(t, []string{"one line wrapped", "two"}, Paragraphs("one line\nwrapped  \r\n\r\n\n\n two\n\n"))
+	assert.Empty(t, Paragraphs(" \n\n "))
+}
+
+func TestCompare(t *testing.T) {
+	oldText := strings.Join([]string{
+		"Introduction",
+		"The study enrolled 120 patients in Almaty.",
+		"Methods were described elsewhere.",
+		"Results",
+		"Conclusion",
+	}, "\n\n")
+	newText := strings.Join([]string{
+		"Introduction",
+		"The study enrolled 240 patients in Almaty and Astana.",
+		"Results",
+		"Table 1 summarises the cohort.",
+		"Conclusion",
+	}, "\n\n")
+
+	d := Compare(oldText, newText, -1)
+	assert.Equal(t, Stats{Unchanged: 3, Inserted: 1, Deleted: 1, Changed: 1}, d.Stats)
+	assert.Equal(t, []Block{
+		{Op: Equal, OldIndex: 0, NewIndex: 0, Text: "Introduction"},
+		{Op: Change, OldIndex: 1, NewIndex: 1,
+			Text:    "The study enrolled 240 patients in Almaty and Astana.",
+			OldText: "The study enrolled 120 patients in Almaty.",
+			Segments: []Segment{
+				{Op: Equal, Text: "The study enrolled"},
+				{Op: Delete, Text: "120"},
+				{Op: Insert, Text: "240"},
+				{Op: Equal, Text: "patients in"},
+				{Op: Delete, Text: "Almaty."},
+				{Op: Insert, Text: "Almaty and Astana."},
+			}},
+		{Op: Delete, OldIndex: 2, NewIndex: 2, Text: "Methods were described elsewhere."},
+		{Op: Equal, OldIndex: 3, NewIndex: 2, Text: "Results"},
+		{Op: Insert, OldIndex: 4, NewIndex: 3, Text: "Table 1 summarises the cohort."},
+		{Op: Equal, OldIndex: 4, NewIndex: 4, Text: "Conclusion"},
+	}, d.Blocks)
+
+	t.Run("Unrelated paragraphs are not edits", func(t *testing.T) {
+		d := Compare("Alpha beta gamma", "Something else entirely", -1)
+		assert.Equal(t, Stats{Inserted: 1, Deleted: 1}, d.Stats)
+		assert.Equal(t, Delete, d.Blocks[0].Op)
+		assert.Equal(t, Insert, d.Blocks[1].Op)
+	})
+
+	t.Run("Context skips unchanged paragraphs", func(t *testing.T) {
+		var paras []string
+		for _, p := range strings.Split("a b c d e f g h i j", " ") {
+			paras = append(paras, "Paragraph "+p)
+		}
+		before := strings.Join(paras, "\n\n")
+		paras[5] = "Rewritten"
+		d := Compare(before, strings.Join(paras, "\n\n"), 1)
+
+		var ops []Op
+		for _, b := range d.Blocks {
+			ops = append(ops, b.Op)
+		}
+		assert.Equal(t, []Op{Skip, Equal, Delete, Insert, Equal, Skip}, ops)
+		assert.Equal(t, Block{Op: Skip, OldIndex: 0, NewIndex: 0, Count: 4}, d.Blocks[0])
+		assert.Equal(t, Block{Op: Skip, OldIndex: 7, NewIndex: 7, Count: 3}, d.Blocks[5])
+		assert.Equal(t, 9, d.Stats.Unchanged)
+
+		same := Compare(before, before, 2)
+		assert.Equal(t, []Block{{Op: Skip, Count: 10}}, same.Blocks)
+	})
+}
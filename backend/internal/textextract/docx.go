@@ -0,0 +1,160 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// maxDOCXPart bounds how far one XML part of a DOCX may decompress.
+const maxDOCXPart = 64 << 20
+
+// extractDOCX reads the main document part of a DOCX. The page count is the
+// one Word stored in docProps/app.xml when it last saved the file; without
+// it the page breaks are counted.
+func extractDOCX(data []byte) (*Result, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	var body, app *zip.File
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			body = f
+		case "docProps/app.xml":
+			app = f
+		}
+	}
+	if body == nil {
+		return nil, ErrUnsupported
+	}
+
+	rc, err := body.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	doc, err := readDOCXBody(&limitedReader{r: rc, n: maxDOCXPart})
+	if err != nil {
+		return nil, err
+	}
+
+	pages := 0
+	if app != nil {
+		pages = docxAppPages(app)
+	}
+	if pages == 0 {
+		pages = max(doc.renderedBreaks, doc.pageBreaks) + 1
+	}
+	return &Result{Format: FormatDOCX, Text: doc.text.String(), Pages: pages}, nil
+}
+
+type docxBody struct {
+	text strings.Builder
+	// pageBreaks are the explicit breaks typed by the author
+	pageBreaks int
+	// renderedBreaks are where Word last laid out a new page
+	renderedBreaks int
+}
+
+// readDOCXBody walks word/document.xml. Text lives in w:t runs; deleted
+// text of tracked changes is in w:delText and is skipped, as are the
+// fallback copies of text boxes in mc:Fallback.
+func readDOCXBody(r io.Reader) (*docxBody, error) {
+	doc := &docxBody{}
+	dec := xml.NewDecoder(r)
+	inText, fallback := false, 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "Fallback" {
+				fallback++
+			}
+			if fallback > 0 {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab", "ptab":
+				doc.text.WriteByte('\t')
+			case "br", "cr":
+				if attr(t, "type") == "page" {
+					doc.pageBreaks++
+				}
+				doc.text.WriteByte('\n')
+			case "lastRenderedPageBreak":
+				doc.renderedBreaks++
+			case "noBreakHyphen":
+				doc.text.WriteByte('-')
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Local == "Fallback":
+				fallback--
+			case fallback > 0:
+			case t.Name.Local == "t":
+				inText = false
+			case t.Name.Local == "p":
+				doc.text.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if inText && fallback == 0 {
+				doc.text.Write(t)
+			}
+		}
+	}
+}
+
+func attr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxAppPages returns the page count stored in docProps/app.xml, or 0.
+func docxAppPages(f *zip.File) int {
+	rc, err := f.Open()
+	if err != nil {
+		return 0
+	}
+	defer rc.Close()
+	var props struct {
+		Pages int `xml:"Pages"`
+	}
+	if err := xml.NewDecoder(&limitedReader{r: rc, n: maxDOCXPart}).Decode(&props); err != nil {
+		return 0
+	}
+	return props.Pages
+}
+
+// limitedReader fails with ErrTooLarge instead of silently truncating.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,393 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxPDFDecoded bounds the decompressed size of all streams of a file
+	maxPDFDecoded = 256 << 20
+	// maxPDFPages bounds the page tree walk
+	maxPDFPages = 20000
+	// maxPDFText stops extraction once this much text was found
+	maxPDFText = 16 << 20
+)
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfDoc is a PDF read by scanning for "n g obj" headers rather than
+// trusting the cross-reference table, so damaged and incrementally updated
+// files still open; later definitions of an object win.
+type pdfDoc struct {
+	data     []byte
+	objects  map[int]any
+	trailers []pdfDict
+	decoded  int
+	fonts    map[int]*pdfFont
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// extractPDF returns the text of every page, pages separated by a blank
+// line.
+func extractPDF(data []byte) (*Result, error) {
+	doc := &pdfDoc{data: data, objects: map[int]any{}, fonts: map[int]*pdfFont{}}
+	doc.scan()
+	for _, t := range doc.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			return nil, ErrEncrypted
+		}
+	}
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return nil, errors.New("pdf: no pages found")
+	}
+
+	var text strings.Builder
+	for _, page := range pages {
+		if text.Len() < maxPDFText {
+			text.WriteString(doc.pageText(page))
+			text.WriteString("\n\n")
+		}
+		if doc.decoded > maxPDFDecoded {
+			return nil, ErrTooLarge
+		}
+	}
+	return &Result{Format: FormatPDF, Text: text.String(), Pages: len(pages)}, nil
+}
+
+// scan collects every object, trailer and object stream of the file.
+func (d *pdfDoc) scan() {
+	for pos := 0; pos < len(d.data); {
+		loc := pdfObjectHeader.FindSubmatchIndex(d.data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(d.data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{data: d.data, pos: pos + loc[1]}
+		obj := l.object()
+		end := l.pos
+		if dict, ok := obj.(pdfDict); ok {
+			if tok, ok := l.next(); ok && tok == pdfKeyword("stream") {
+				stream := &pdfStream{dict: dict}
+				stream.raw, end = d.streamData(dict, l.pos)
+				obj = stream
+				if dict["Type"] == pdfName("XRef") {
+					d.trailers = append(d.trailers, dict)
+				}
+			}
+		}
+		d.objects[num] = obj
+		pos = max(end, pos+loc[1])
+	}
+
+	for pos := 0; ; {
+		i := bytes.Index(d.data[pos:], []byte("trailer"))
+		if i < 0 {
+			break
+		}
+		l := &pdfLexer{data: d.data, pos: pos + i + len("trailer")}
+		if dict, ok := l.object().(pdfDict); ok {
+			d.trailers = append(d.trailers, dict)
+		}
+		pos += i + len("trailer")
+	}
+
+	var streams []*pdfStream
+	for _, obj := range d.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, s)
+		}
+	}
+	for _, s := range streams {
+		d.loadObjectStream(s)
+	}
+}
+
+// streamData returns the bytes of a stream starting at pos, just after the
+// stream keyword, and where its endstream keyword ends. A wrong /Length is
+// common enough that the keyword is searched for when it does not fit.
+func (d *pdfDoc) streamData(dict pdfDict, pos int) ([]byte, int) {
+	if pos < len(d.data) && d.data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(d.data) && d.data[pos] == '\n' {
+		pos++
+	}
+	if n, ok := d.resolve(dict["Length"]).(float64); ok && n >= 0 && pos+int(n) <= len(d.data) {
+		end := pos + int(n)
+		l := &pdfLexer{data: d.data, pos: end}
+		if tok, ok := l.next(); ok && tok == pdfKeyword("endstream") {
+			return d.data[pos:end], l.pos
+		}
+	}
+	i := bytes.Index(d.data[pos:], []byte("endstream"))
+	if i < 0 {
+		return d.data[pos:], len(d.data)
+	}
+	raw := bytes.TrimRight(d.data[pos:pos+i], "\r\n")
+	return raw, pos + i + len("endstream")
+}
+
+// loadObjectStream adds the objects packed into an /ObjStm that were not
+// also written directly.
+func (d *pdfDoc) loadObjectStream(s *pdfStream) {
+	data, err := d.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := s.dict["N"].(float64)
+	first, _ := s.dict["First"].(float64)
+	header := &pdfLexer{data: data}
+	for i := 0; i < int(n); i++ {
+		num, ok1 := header.next()
+		off, ok2 := header.next()
+		objNum, isNum := num.(float64)
+		offset, isOff := off.(float64)
+		if !ok1 || !ok2 || !isNum || !isOff {
+			return
+		}
+		if _, exists := d.objects[int(objNum)]; exists {
+			continue
+		}
+		at := int(first) + int(offset)
+		if at < 0 || at >= len(data) {
+			continue
+		}
+		d.objects[int(objNum)] = (&pdfLexer{data: data, pos: at}).object()
+	}
+}
+
+// resolve follows references to the object they point at.
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 16; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+// dict returns v as a dictionary; for a stream that is its dictionary.
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+func (d *pdfDoc) array(v any) pdfArray {
+	arr, _ := d.resolve(v).(pdfArray)
+	return arr
+}
+
+func (d *pdfDoc) number(v any) (float64, bool) {
+	n, ok := d.resolve(v).(float64)
+	return n, ok
+}
+
+// pages lists the pages in reading order, each with the resources it
+// inherits from the page tree.
+func (d *pdfDoc) pages() []pdfPage {
+	var pages []pdfPage
+	seen := map[int]bool{}
+	var walk func(node any, resources pdfDict, depth int)
+	walk = func(node any, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref.num] {
+				return
+			}
+			seen[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > maxPDFNesting || len(pages) >= maxPDFPages {
+			return
+		}
+		if res := d.dict(dict["Resources"]); res != nil {
+			resources = res
+		}
+		kids := d.array(dict["Kids"])
+		if dict["Type"] == pdfName("Pages") || (kids != nil && dict["Type"] != pdfName("Page")) {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: dict, resources: resources})
+	}
+
+	for i := len(d.trailers) - 1; i >= 0 && len(pages) == 0; i-- {
+		if root := d.dict(d.trailers[i]["Root"]); root != nil {
+			walk(root["Pages"], nil, 0)
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// No usable page tree: take the page objects in object order
+	var nums []int
+	for num, obj := range d.objects {
+		if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if len(pages) >= maxPDFPages {
+			break
+		}
+		dict := d.objects[num].(pdfDict)
+		pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+	}
+	return pages
+}
+
+// pageText runs the page's content streams and returns the text they draw.
+func (d *pdfDoc) pageText(page pdfPage) string {
+	var content []byte
+	contents := d.resolve(page.dict["Contents"])
+	parts := pdfArray{contents}
+	if arr, ok := contents.(pdfArray); ok {
+		parts = arr
+	}
+	for _, part := range parts {
+		s, ok := d.resolve(part).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.decode(s)
+		if err != nil {
+			continue
+		}
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+	w := &pageWriter{doc: d}
+	w.run(content, page.resources, pdfGraphicsState{ctm: identityMatrix, text: defaultTextState}, 0)
+	return w.out.String()
+}
+
+// decode applies a stream's filters.
+func (d *pdfDoc) decode(s *pdfStream) ([]byte, error) {
+	filters := d.resolve(s.dict["Filter"])
+	list, ok := filters.(pdfArray)
+	if !ok {
+		list = pdfArray{filters}
+	}
+	params := d.resolve(s.dict["DecodeParms"])
+	paramList, ok := params.(pdfArray)
+	if !ok {
+		paramList = pdfArray{params}
+	}
+
+	data := s.raw
+	for i, f := range list {
+		if f == nil {
+			continue
+		}
+		var err error
+		switch name, _ := d.resolve(f).(pdfName); name {
+		case "FlateDecode", "Fl":
+			if i < len(paramList) {
+				if p, _ := d.number(d.dict(paramList[i])["Predictor"]); p > 1 {
+					return nil, fmt.Errorf("pdf: unsupported predictor %v", p)
+				}
+			}
+			data, err = d.inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			data = (&pdfLexer{data: data}).hex()
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("pdf: unsupported filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses a FlateDecode stream within the file's budget.
+// Truncated streams are common; whatever decompressed before the error is
+// kept.
+func (d *pdfDoc) inflate(data []byte) ([]byte, error) {
+	var r io.Reader
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err == nil {
+		defer zr.Close()
+		r = zr
+	} else if len(data) > 2 {
+		r = flate.NewReader(bytes.NewReader(data[2:]))
+	} else {
+		return nil, err
+	}
+	var out bytes.Buffer
+	remaining := int64(maxPDFDecoded - d.decoded)
+	_, err = io.Copy(&out, io.LimitReader(r, remaining+1))
+	d.decoded += out.Len()
+	if d.decoded > maxPDFDecoded {
+		return nil, ErrTooLarge
+	}
+	if err != nil && out.Len() == 0 {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	var out []byte
+	var group [5]byte
+	n := 0
+	flush := func(count int) {
+		var v uint32
+		for i := 0; i < 5; i++ {
+			v = v*85 + uint32(group[i]-'!')
+		}
+		b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, b[:count-1]...)
+	}
+	for _, c := range data {
+		switch {
+		case c == 'z' && n == 0:
+			out = append(out, 0, 0, 0, 0)
+		case c >= '!' && c <= 'u':
+			group[n] = c
+			if n++; n == 5 {
+				flush(5)
+				n = 0
+			}
+		case isPDFSpace(c):
+		default:
+			return nil, fmt.Errorf("pdf: bad ascii85 byte %q", c)
+		}
+	}
+	if n > 0 {
+		for i := n; i < 5; i++ {
+			group[i] = 'u'
+		}
+		flush(n)
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+package textextract
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PDF objects. Numbers are float64, booleans bool and null nil.
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// maxPDFNesting bounds how deep arrays and dictionaries may nest.
+const maxPDFNesting = 64
+
+// pdfLexer reads PDF syntax from data starting at pos. It never fails: junk
+// comes back as keywords for the caller to ignore.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// next returns the next token: a number, name, string or keyword. The
+// delimiters << >> [ ] come back as keywords. ok is false at the end.
+func (l *pdfLexer) next() (tok any, ok bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return l.name(), true
+	case c == '(':
+		l.pos++
+		return l.literal(), true
+	case c == '<' || c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == c {
+			l.pos += 2
+			return pdfKeyword([]byte{c, c}), true
+		}
+		l.pos++
+		if c == '>' {
+			return pdfKeyword(">"), true
+		}
+		return l.hex(), true
+	case isPDFDelim(c):
+		l.pos++
+		return pdfKeyword([]byte{c}), true
+	}
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, ok := parsePDFNumber(word); ok {
+		return n, true
+	}
+	return pdfKeyword(word), true
+}
+
+func parsePDFNumber(word string) (float64, bool) {
+	if word == "" || strings.Trim(word, "0123456789+-.") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(word, 64)
+	return n, err == nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	var b strings.Builder
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '#' && l.pos+1 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos:l.pos+2]), 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				l.pos += 2
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return pdfName(b.String())
+}
+
+// literal reads a (string) after its opening parenthesis.
+func (l *pdfLexer) literal() pdfString {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hex reads a <hex string> after its opening bracket.
+func (l *pdfLexer) hex() pdfString {
+	var out []byte
+	var digits []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		if strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	for i := 0; i < len(digits); i += 2 {
+		v, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+// object reads the next complete object.
+func (l *pdfLexer) object() any {
+	tok, ok := l.next()
+	if !ok {
+		return nil
+	}
+	return l.parse(tok, 0)
+}
+
+// parse completes the object that starts with tok: arrays, dictionaries and
+// "num gen R" references are read to their end.
+func (l *pdfLexer) parse(tok any, depth int) any {
+	switch t := tok.(type) {
+	case float64:
+		save := l.pos
+		if gen, ok := l.next(); ok {
+			if g, isNum := gen.(float64); isNum {
+				if r, ok := l.next(); ok && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(g)}
+				}
+			}
+		}
+		l.pos = save
+		return t
+	case pdfKeyword:
+		switch t {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		case "[":
+			if depth > maxPDFNesting {
+				return nil
+			}
+			arr := pdfArray{}
+			for {
+				tok, ok := l.next()
+				if !ok || tok == pdfKeyword("]") {
+					return arr
+				}
+				arr = append(arr, l.parse(tok, depth+1))
+			}
+		case "<<":
+			if depth > maxPDFNesting {
+				return nil
+			}
+			dict := pdfDict{}
+			for {
+				tok, ok := l.next()
+				if !ok || tok == pdfKeyword(">>") {
+					return dict
+				}
+				key, isName := tok.(pdfName)
+				if !isName {
+					continue
+				}
+				val, ok := l.next()
+				if !ok || val == pdfKeyword(">>") {
+					return dict
+				}
+				dict[key] = l.parse(val, depth+1)
+			}
+		}
+	}
+	return tok
+}
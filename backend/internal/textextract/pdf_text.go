@@ -0,0 +1,648 @@
+package textextract
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFFormDepth bounds how deep form XObjects may draw each other.
+const maxPDFFormDepth = 8
+
+// pdfFont maps the character codes of a font to text and advance widths.
+type pdfFont struct {
+	// twoByte fonts (composite Type0 fonts) use two bytes per code
+	twoByte   bool
+	toUnicode map[uint32]string
+	// encoding maps the codes of simple fonts without a ToUnicode entry
+	encoding *[256]string
+	widths   map[uint32]float64
+	// defaultWidth is used for codes missing from widths
+	defaultWidth float64
+	// widthScale turns widths into text space units, 1/1000 but for Type3
+	widthScale float64
+}
+
+var fallbackFont = &pdfFont{encoding: &winAnsiEncoding, defaultWidth: 500, widthScale: 0.001}
+
+// font loads the font dictionary v, caching fonts shared through references.
+func (d *pdfDoc) font(v any) *pdfFont {
+	if ref, ok := v.(pdfRef); ok {
+		if f, ok := d.fonts[ref.num]; ok {
+			return f
+		}
+		f := d.loadFont(v)
+		d.fonts[ref.num] = f
+		return f
+	}
+	return d.loadFont(v)
+}
+
+func (d *pdfDoc) loadFont(v any) *pdfFont {
+	fd := d.dict(v)
+	if fd == nil {
+		return fallbackFont
+	}
+	f := &pdfFont{widths: map[uint32]float64{}, defaultWidth: 500, widthScale: 0.001}
+	subtype, _ := fd["Subtype"].(pdfName)
+	if subtype == "Type0" {
+		f.twoByte = true
+		f.defaultWidth = 1000
+		if desc := d.array(fd["DescendantFonts"]); len(desc) > 0 {
+			cid := d.dict(desc[0])
+			if dw, ok := d.number(cid["DW"]); ok {
+				f.defaultWidth = dw
+			}
+			d.cidWidths(f, d.array(cid["W"]))
+		}
+	} else {
+		f.encoding = d.simpleEncoding(fd)
+		first, _ := d.number(fd["FirstChar"])
+		for i, w := range d.array(fd["Widths"]) {
+			if n, ok := d.number(w); ok {
+				f.widths[uint32(int(first)+i)] = n
+			}
+		}
+		if desc := d.dict(fd["FontDescriptor"]); desc != nil {
+			if mw, ok := d.number(desc["MissingWidth"]); ok && mw > 0 {
+				f.defaultWidth = mw
+			}
+		}
+		if subtype == "Type3" {
+			if m := d.array(fd["FontMatrix"]); len(m) > 0 {
+				if sx, ok := d.number(m[0]); ok {
+					f.widthScale = sx
+				}
+			}
+		}
+	}
+	if s, ok := d.resolve(fd["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(s); err == nil {
+			var codeLen int
+			f.toUnicode, codeLen = parseCMap(data)
+			if subtype == "Type0" && codeLen == 1 {
+				f.twoByte = false
+			}
+		}
+	}
+	return f
+}
+
+// cidWidths reads the W array of a CID font:
+// [c [w1 w2 ...]] gives widths from c on, [first last w] one width for a range.
+func (d *pdfDoc) cidWidths(f *pdfFont, w pdfArray) {
+	for i := 0; i+1 < len(w); {
+		c, ok := d.number(w[i])
+		if !ok {
+			return
+		}
+		if list := d.array(w[i+1]); list != nil {
+			for j, v := range list {
+				if n, ok := d.number(v); ok {
+					f.widths[uint32(int(c)+j)] = n
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, ok1 := d.number(w[i+1])
+		width, ok2 := d.number(w[i+2])
+		if !ok1 || !ok2 || last < c || last-c > 0xFFFF {
+			return
+		}
+		for code := int(c); code <= int(last); code++ {
+			f.widths[uint32(code)] = width
+		}
+		i += 3
+	}
+}
+
+// simpleEncoding builds the code table of a simple font from its base
+// encoding and /Differences. Standard and Mac encodings are close enough
+// to WinAnsi for text search.
+func (d *pdfDoc) simpleEncoding(fd pdfDict) *[256]string {
+	table := winAnsiEncoding
+	enc, ok := d.resolve(fd["Encoding"]).(pdfDict)
+	if !ok {
+		return &table
+	}
+	code := 0
+	for _, v := range d.array(enc["Differences"]) {
+		switch t := d.resolve(v).(type) {
+		case float64:
+			code = int(t)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				table[code] = glyphText(string(t))
+			}
+			code++
+		}
+	}
+	return &table
+}
+
+// decode splits a shown string into glyphs.
+func (f *pdfFont) decode(s pdfString) []pdfGlyph {
+	step := 1
+	if f.twoByte {
+		step = 2
+	}
+	glyphs := make([]pdfGlyph, 0, len(s)/step)
+	for i := 0; i+step <= len(s); i += step {
+		code := uint32(s[i])
+		if step == 2 {
+			code = code<<8 | uint32(s[i+1])
+		}
+		text, ok := f.toUnicode[code]
+		if !ok && f.encoding != nil && code < 256 {
+			text = f.encoding[code]
+		}
+		width, ok := f.widths[code]
+		if !ok {
+			width = f.defaultWidth
+		}
+		glyphs = append(glyphs, pdfGlyph{text: text, width: width * f.widthScale, space: step == 1 && code == ' '})
+	}
+	return glyphs
+}
+
+type pdfGlyph struct {
+	text  string
+	width float64
+	// space glyphs also get the word spacing
+	space bool
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap and
+// the code length of its first codespace range.
+func parseCMap(data []byte) (map[uint32]string, int) {
+	m := map[uint32]string{}
+	codeLen := 0
+	l := &pdfLexer{data: data}
+	str := func() (pdfString, bool) {
+		tok, ok := l.next()
+		s, isStr := tok.(pdfString)
+		return s, ok && isStr
+	}
+	for {
+		tok, ok := l.next()
+		if !ok {
+			return m, codeLen
+		}
+		switch tok {
+		case pdfKeyword("begincodespacerange"):
+			if lo, ok := str(); ok && codeLen == 0 {
+				codeLen = len(lo)
+			}
+		case pdfKeyword("beginbfchar"):
+			for {
+				src, ok := str()
+				if !ok {
+					break
+				}
+				dst, ok := str()
+				if !ok {
+					break
+				}
+				m[cmapCode(src)] = utf16Text(dst)
+			}
+		case pdfKeyword("beginbfrange"):
+			for {
+				lo, ok := str()
+				if !ok {
+					break
+				}
+				hi, ok := str()
+				if !ok {
+					break
+				}
+				tok, ok := l.next()
+				if !ok {
+					break
+				}
+				first, last := cmapCode(lo), cmapCode(hi)
+				if last < first || last-first > 0xFFFF {
+					continue
+				}
+				switch dst := l.parse(tok, 0).(type) {
+				case pdfString:
+					base := []rune(utf16Text(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for c := first; c <= last; c++ {
+						r := append([]rune(nil), base...)
+						r[len(r)-1] += rune(c - first)
+						m[c] = string(r)
+					}
+				case pdfArray:
+					for i, v := range dst {
+						if s, ok := v.(pdfString); ok && first+uint32(i) <= last {
+							m[first+uint32(i)] = utf16Text(s)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func cmapCode(b []byte) uint32 {
+	var c uint32
+	for _, x := range b {
+		c = c<<8 | uint32(x)
+	}
+	return c
+}
+
+// utf16Text decodes the UTF-16BE text of a CMap destination.
+func utf16Text(b []byte) string {
+	if len(b)%2 == 1 {
+		return string(bytes.Runes(b))
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfMatrix is [a b c d e f].
+type pdfMatrix [6]float64
+
+var identityMatrix = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m×n, i.e. m applied first.
+func (m pdfMatrix) mul(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(x, y float64) pdfMatrix {
+	return pdfMatrix{1, 0, 0, 1, x, y}
+}
+
+type pdfTextState struct {
+	font                       *pdfFont
+	size, charSpace, wordSpace float64
+	leading, scale             float64
+}
+
+var defaultTextState = pdfTextState{font: fallbackFont, size: 12, scale: 1}
+
+type pdfGraphicsState struct {
+	ctm  pdfMatrix
+	text pdfTextState
+}
+
+// pageWriter turns text showing operators into lines. Glyph positions decide
+// where words and lines break: a jump to another baseline starts a line, a
+// gap wider than a fraction of the font size becomes a space, and a gap
+// clearly wider than the usual line spacing ends a paragraph.
+type pageWriter struct {
+	doc *pdfDoc
+	out strings.Builder
+
+	hasLast      bool
+	lastX, lastY float64
+	lastSize     float64
+	lineGap      float64
+}
+
+// run interprets a content stream.
+func (w *pageWriter) run(content []byte, resources pdfDict, gs pdfGraphicsState, depth int) {
+	d := w.doc
+	l := &pdfLexer{data: content}
+	var stack []pdfGraphicsState
+	var operands []any
+	tm, tlm := identityMatrix, identityMatrix
+	fonts := d.dict(resources["Font"])
+
+	nums := func(n int) ([]float64, bool) {
+		if len(operands) < n {
+			return nil, false
+		}
+		out := make([]float64, n)
+		for i, v := range operands[len(operands)-n:] {
+			f, ok := v.(float64)
+			if !ok {
+				return nil, false
+			}
+			out[i] = f
+		}
+		return out, true
+	}
+	nextLine := func() {
+		tlm = translate(0, -gs.text.leading).mul(tlm)
+		tm = tlm
+	}
+	show := func(v any) {
+		if s, ok := v.(pdfString); ok {
+			tm = w.show(s, gs, tm)
+		}
+	}
+
+	for {
+		tok, ok := l.next()
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp || op == "[" || op == "<<" || op == "true" || op == "false" || op == "null" {
+			operands = append(operands, l.parse(tok, 0))
+			continue
+		}
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if n, ok := nums(6); ok {
+				gs.ctm = pdfMatrix(n).mul(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					gs.text.font = d.font(fonts[name])
+				}
+				if size, ok := operands[len(operands)-1].(float64); ok {
+					gs.text.size = size
+				}
+			}
+		case "Tc":
+			if n, ok := nums(1); ok {
+				gs.text.charSpace = n[0]
+			}
+		case "Tw":
+			if n, ok := nums(1); ok {
+				gs.text.wordSpace = n[0]
+			}
+		case "Tz":
+			if n, ok := nums(1); ok {
+				gs.text.scale = n[0] / 100
+			}
+		case "TL":
+			if n, ok := nums(1); ok {
+				gs.text.leading = n[0]
+			}
+		case "Td", "TD":
+			if n, ok := nums(2); ok {
+				if op == "TD" {
+					gs.text.leading = -n[1]
+				}
+				tlm = translate(n[0], n[1]).mul(tlm)
+				tm = tlm
+			}
+		case "Tm":
+			if n, ok := nums(6); ok {
+				tlm = pdfMatrix(n)
+				tm = tlm
+			}
+		case "T*":
+			nextLine()
+		case "Tj":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'":
+			nextLine()
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "\"":
+			if len(operands) >= 3 {
+				if ws, ok := operands[len(operands)-3].(float64); ok {
+					gs.text.wordSpace = ws
+				}
+				if cs, ok := operands[len(operands)-2].(float64); ok {
+					gs.text.charSpace = cs
+				}
+				nextLine()
+				show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			arr, _ := operands[len(operands)-1].(pdfArray)
+			for _, v := range arr {
+				switch t := v.(type) {
+				case pdfString:
+					tm = w.show(t, gs, tm)
+				case float64:
+					tm = translate(-t/1000*gs.text.size*gs.text.scale, 0).mul(tm)
+				}
+			}
+		case "Do":
+			if len(operands) == 0 || depth >= maxPDFFormDepth {
+				break
+			}
+			name, _ := operands[len(operands)-1].(pdfName)
+			form, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+			if !ok || form.dict["Subtype"] != pdfName("Form") {
+				break
+			}
+			data, err := d.decode(form)
+			if err != nil {
+				break
+			}
+			inner := gs
+			if m := d.array(form.dict["Matrix"]); len(m) == 6 {
+				var fm pdfMatrix
+				for i := range fm {
+					fm[i], _ = d.number(m[i])
+				}
+				inner.ctm = fm.mul(gs.ctm)
+			}
+			formResources := d.dict(form.dict["Resources"])
+			if formResources == nil {
+				formResources = resources
+			}
+			w.run(data, formResources, inner, depth+1)
+		case "ID":
+			// Inline image data runs to the next EI standing on its own
+			l.pos = skipInlineImage(content, l.pos)
+		}
+		operands = operands[:0]
+	}
+}
+
+// show writes one string and returns the text matrix moved past it.
+func (w *pageWriter) show(s pdfString, gs pdfGraphicsState, tm pdfMatrix) pdfMatrix {
+	ts := gs.text
+	font := ts.font
+	if font == nil {
+		font = fallbackFont
+	}
+	glyphs := font.decode(s)
+	if len(glyphs) == 0 {
+		return tm
+	}
+
+	trm := pdfMatrix{ts.size * ts.scale, 0, 0, ts.size, 0, 0}.mul(tm).mul(gs.ctm)
+	x, y := trm[4], trm[5]
+	size := math.Hypot(trm[2], trm[3])
+	if size == 0 {
+		size = 1
+	}
+
+	var text strings.Builder
+	for _, g := range glyphs {
+		text.WriteString(g.text)
+		tx := g.width*ts.size + ts.charSpace
+		if g.space {
+			tx += ts.wordSpace
+		}
+		tm = translate(tx*ts.scale, 0).mul(tm)
+	}
+	w.separate(x, y, size, text.String())
+	w.out.WriteString(text.String())
+
+	end := pdfMatrix{ts.size * ts.scale, 0, 0, ts.size, 0, 0}.mul(tm).mul(gs.ctm)
+	w.hasLast, w.lastX, w.lastY, w.lastSize = true, end[4], end[5], size
+	return tm
+}
+
+// separate writes the break, if any, between the previous text and text
+// starting at x, y.
+func (w *pageWriter) separate(x, y, size float64, text string) {
+	if !w.hasLast || text == "" {
+		return
+	}
+	size = math.Max(size, w.lastSize)
+	gap := math.Abs(y - w.lastY)
+	if gap > size/2 {
+		if w.lineGap > 0 && gap > w.lineGap*1.5 {
+			w.out.WriteString("\n\n")
+			return
+		}
+		w.lineGap = gap
+		w.out.WriteByte('\n')
+		return
+	}
+	dx := x - w.lastX
+	if (dx > size*0.15 || dx < -size) && !strings.HasPrefix(text, " ") && !strings.HasSuffix(w.out.String(), " ") {
+		w.out.WriteByte(' ')
+	}
+}
+
+// skipInlineImage returns the position after the EI that ends the inline
+// image whose data starts after the ID operator at pos.
+func skipInlineImage(data []byte, pos int) int {
+	for i := pos + 1; i+2 <= len(data); i++ {
+		if data[i] == 'E' && data[i+1] == 'I' && isPDFSpace(data[i-1]) &&
+			(i+2 == len(data) || isPDFSpace(data[i+2])) {
+			return i + 2
+		}
+	}
+	return len(data)
+}
+
+// glyphText maps a glyph name from a /Differences array to its text.
+func glyphText(name string) string {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	if strings.Contains(name, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(name, "_") {
+			b.WriteString(glyphText(part))
+		}
+		return b.String()
+	}
+	if len(name) == 1 {
+		return name
+	}
+	if t, ok := glyphNames[name]; ok {
+		return t
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 {
+		var b strings.Builder
+		for i := 3; i+4 <= len(name); i += 4 {
+			v, err := strconv.ParseUint(name[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			b.WriteRune(rune(v))
+		}
+		return b.String()
+	}
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	// Cyrillic names of the Adobe glyph list
+	if strings.HasPrefix(name, "afii") {
+		n, err := strconv.Atoi(name[4:])
+		if err != nil {
+			return ""
+		}
+		switch {
+		case n >= 10017 && n <= 10022:
+			return string(rune(0x0410 + n - 10017))
+		case n == 10023:
+			return "Ё"
+		case n >= 10024 && n <= 10049:
+			return string(rune(0x0416 + n - 10024))
+		case n >= 10065 && n <= 10070:
+			return string(rune(0x0430 + n - 10065))
+		case n == 10071:
+			return "ё"
+		case n >= 10072 && n <= 10097:
+			return string(rune(0x0436 + n - 10072))
+		}
+	}
+	return ""
+}
+
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "quoteright": "’", "quoteleft": "‘",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+", "comma": ",",
+	"hyphen": "-", "minus": "−", "period": ".", "slash": "/", "colon": ":", "semicolon": ";",
+	"less": "<", "equal": "=", "greater": ">", "question": "?", "at": "@",
+	"bracketleft": "[", "backslash": "\\", "bracketright": "]", "underscore": "_",
+	"braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"quotedblleft": "“", "quotedblright": "”", "quotesinglbase": "‚", "quotedblbase": "„",
+	"guillemotleft": "«", "guillemotright": "»", "endash": "–", "emdash": "—",
+	"bullet": "•", "ellipsis": "…", "degree": "°", "section": "§", "numero": "№",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+}
+
+// winAnsiEncoding is Windows-1252, the usual encoding of simple fonts.
+var winAnsiEncoding = func() [256]string {
+	var t [256]string
+	for c := 0x20; c < 0x7F; c++ {
+		t[c] = string(rune(c))
+	}
+	for c := 0xA0; c <= 0xFF; c++ {
+		t[c] = string(rune(c))
+	}
+	high := "€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ"
+	c := 0x80
+	for _, r := range high {
+		if r != 0 {
+			t[c] = string(r)
+		}
+		c++
+	}
+	t['\t'], t['\n'], t['\r'] = " ", "\n", "\n"
+	return t
+}()
@@ -0,0 +1,120 @@
+// Package textextract pulls plain text, page and word counts out of DOCX and
+// PDF files. It is pure Go and needs no external tools.
+//
+// Paragraphs in the returned text are separated by a blank line and lines
+// within a paragraph by a single newline.
+package textextract
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnsupported is returned for files that are neither DOCX nor PDF
+	ErrUnsupported = errors.New("unsupported file format")
+	// ErrEncrypted is returned for password protected PDFs
+	ErrEncrypted = errors.New("document is encrypted")
+	// ErrTooLarge is returned when a file expands past the extraction limits
+	ErrTooLarge = errors.New("document is too large to extract")
+)
+
+// Format is a file format the package understands.
+type Format string
+
+const (
+	FormatPDF  Format = "pdf"
+	FormatDOCX Format = "docx"
+)
+
+// Result is what Extract found in a file.
+type Result struct {
+	Format Format
+	Text   string
+	Pages  int
+	Words  int
+}
+
+// Detect sniffs the format of a file from its content, ignoring whatever
+// name or MIME type it was uploaded with. It returns "" for other files.
+func Detect(data []byte) Format {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	switch {
+	case bytes.Contains(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return FormatDOCX
+	}
+	return ""
+}
+
+// Extract returns the text of a DOCX or PDF file with its page and word
+// counts.
+func Extract(data []byte) (*Result, error) {
+	var (
+		res *Result
+		err error
+	)
+	switch Detect(data) {
+	case FormatPDF:
+		res, err = extractPDF(data)
+	case FormatDOCX:
+		res, err = extractDOCX(data)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Text = Normalize(res.Text)
+	res.Words = CountWords(res.Text)
+	return res, nil
+}
+
+// CountWords counts whitespace separated tokens that contain a letter or a
+// digit, the way word processors do.
+func CountWords(text string) int {
+	n := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	blankRuns = regexp.MustCompile(`[ \t\p{Zs}]+`)
+	lineRuns  = regexp.MustCompile(`\n{3,}`)
+)
+
+// Normalize makes extracted text safe to store: valid UTF-8 without control
+// characters, single spaces within lines and at most one blank line in a
+// row.
+func Normalize(text string) string {
+	text = strings.ReplaceAll(strings.ToValidUTF8(text, ""), "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r' || r == '\f' || r == '\v' || r == '\u2028' || r == '\u2029':
+			return '\n'
+		case unicode.IsControl(r) || r == utf8.RuneError || r == '\ufeff' || r == '\u00ad':
+			return -1
+		}
+		return r
+	}, text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(blankRuns.ReplaceAllString(line, " "))
+	}
+	text = lineRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
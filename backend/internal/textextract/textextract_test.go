@@ -0,0 +1,149 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildDOCX(t *testing.T, body, app string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+  xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"><w:body>` + body + `</w:body></w:document>`,
+	}
+	if app != "" {
+		parts["docProps/app.xml"] = `<?xml version="1.0"?><Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties">` + app + `</Properties>`
+	}
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractDOCX(t *testing.T) {
+	body := `
+<w:p><w:r><w:t>Глава 1.</w:t></w:r><w:r><w:t xml:space="preserve"> Введение</w:t></w:r></w:p>
+<w:p><w:r><w:t>Thesis</w:t><w:tab/><w:t>draft</w:t><w:br/><w:t>second line</w:t></w:r></w:p>
+<w:p><w:del><w:r><w:delText>removed words</w:delText></w:r></w:del><w:r><w:lastRenderedPageBreak/><w:t>Kept</w:t></w:r></w:p>
+<w:p><w:r><mc:AlternateContent><mc:Choice><w:t>Box</w:t></mc:Choice><mc:Fallback><w:t>Box</w:t></mc:Fallback></mc:AlternateContent></w:r></w:p>
+<w:p><w:r><w:br w:type="page"/><w:t>Appendix</w:t></w:r></w:p>`
+
+	t.Run("Pages from app.xml", func(t *testing.T) {
+		res, err := Extract(buildDOCX(t, body, "<Pages>14</Pages><Words>9000</Words>"))
+		require.NoError(t, err)
+		assert.Equal(t, FormatDOCX, res.Format)
+		assert.Equal(t, "Глава 1. Введение\n\nThesis draft\nsecond line\n\nKept\n\nBox\n\nAppendix", res.Text)
+		assert.Equal(t, 14, res.Pages)
+		assert.Equal(t, 10, res.Words)
+	})
+
+	t.Run("Pages from breaks", func(t *testing.T) {
+		res, err := Extract(buildDOCX(t, body, ""))
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Pages)
+	})
+
+	t.Run("Other zip files", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		_, _ = zw.Create("xl/workbook.xml")
+		require.NoError(t, zw.Close())
+		_, err := Extract(buf.Bytes())
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+}
+
+func deflate(s string) string {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, _ = zw.Write([]byte(s))
+	_ = zw.Close()
+	return buf.String()
+}
+
+// buildPDF writes objects 1..n in order with a trailer; the extractor scans
+// for objects, so no cross-reference table is needed.
+func buildPDF(objects ...string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R /Size 20 >>\n%%EOF\n")
+	return []byte(b.String())
+}
+
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func TestExtractPDF(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar <0003> <0020> <0010> <0414> endbfchar
+1 beginbfrange <0020> <0022> <0430> endbfrange
+endcmap end end`
+	// Page 1 uses a simple font with widths; TJ kerning below -150/1000
+	// of the font size is a word gap
+	page1 := `BT /F1 10 Tf 72 720 Td [(Hel) 20 (lo) -400 (world)] TJ 0 -14 Td (second) Tj
+0 -40 Td (New paragraph) Tj ET`
+	// Page 2 draws Cyrillic through a Type0 font and text inside a form
+	page2 := `BT /F2 12 Tf 72 700 Td <001000200021000300220020> Tj ET /Fm1 Do`
+	form := `BT /F1 10 Tf 72 600 Td (Footer) Tj ET`
+
+	data := buildPDF(
+		`<< /Type /Catalog /Pages 2 0 R >>`,
+		`<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>`,
+		`<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>`,
+		`<< /Type /Page /Parent 2 0 R /Contents [8 0 R] /Resources << /Font << /F2 6 0 R >> /XObject << /Fm1 9 0 R >> >> >>`,
+		`<< /Type /Font /Subtype /TrueType /FirstChar 32 /LastChar 122 /Widths 12 0 R /Encoding /WinAnsiEncoding >>`,
+		`<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /DescendantFonts [<< /Subtype /CIDFontType2 /DW 600 >>] /ToUnicode 10 0 R >>`,
+		stream("/Filter /FlateDecode", deflate(page1)),
+		stream("/Filter /FlateDecode", deflate(page2)),
+		stream("/Type /XObject /Subtype /Form /Resources << /Font << /F1 13 0 R >> >>", form),
+		stream("/Filter /FlateDecode", deflate(cmap)),
+		// Object 13 only lives in this object stream
+		stream("/Type /ObjStm /N 1 /First 5 /Filter /FlateDecode",
+			deflate("13 0 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")),
+		"["+strings.Repeat("500 ", 91)+"]",
+	)
+
+	res, err := Extract(data)
+	require.NoError(t, err)
+	assert.Equal(t, FormatPDF, res.Format)
+	assert.Equal(t, 2, res.Pages)
+	assert.Equal(t, "Hello world\nsecond\n\nNew paragraph\n\nДаб ва\nFooter", res.Text)
+	assert.Equal(t, 8, res.Words)
+}
+
+func TestExtractPDF_Encrypted(t *testing.T) {
+	data := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R /Encrypt 2 0 R >>\n")
+	_, err := Extract(data)
+	assert.ErrorIs(t, err, ErrEncrypted)
+}
+
+func TestExtract_Unsupported(t *testing.T) {
+	_, err := Extract([]byte("\x89PNG\r\n\x1a\n"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "a b\n\nc", Normalize("  a \t  b \r\n\r\n\r\n\f c\x00­ "))
+	assert.Equal(t, 3, CountWords("state-of-the-art — 2024 draft"))
+}
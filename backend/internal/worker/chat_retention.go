@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+)
+
+// JobChatRetention is the chat retention job's lease name.
+const JobChatRetention = "chat_retention"
+
+// ChatRetentionJob deletes or archives chat messages past their room's
+// retention policy.
+func ChatRetentionJob(chat *services.ChatService) Job {
+	return Job{
+		Name:     JobChatRetention,
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			n, err := chat.ApplyRetention(ctx, time.Now())
+			if n > 0 {
+				log.Printf("[Scheduler] Removed %d chat messages past retention", n)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+)
+
+// JobChatRoomSync is the chat room sync job's lease name.
+const JobChatRoomSync = "chat_room_sync"
+
+// ChatRoomSyncJob reconciles every tenant's cohort and advisory rooms. Most
+// changes sync right away; this catches the rest, such as deactivated users.
+func ChatRoomSyncJob(chat *services.ChatService) Job {
+	return Job{
+		Name:     JobChatRoomSync,
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			return chat.SyncAllRooms(ctx)
+		},
+	}
+}
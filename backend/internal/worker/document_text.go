@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+)
+
+// JobDocumentText is the document text extraction job's lease name.
+const JobDocumentText = "document_text"
+
+// DocumentTextJob extracts the text of newly uploaded documents for search
+// and for the page and word counts reviewers see.
+func DocumentTextJob(texts *services.DocumentTextService) Job {
+	return Job{
+		Name:     JobDocumentText,
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			n, err := texts.ExtractPending(ctx)
+			if n > 0 {
+				log.Printf("[Scheduler] Extracted text of %d document versions", n)
+			}
+			return err
+		},
+	}
+}
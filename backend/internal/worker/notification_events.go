@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+)
+
+// JobNotificationEventsPrune is the retention job's lease name.
+const JobNotificationEventsPrune = "notification_events_prune"
+
+// NotificationEventRetention bounds how far back a client can resume the
+// live notification stream.
+const NotificationEventRetention = 7 * day
+
+// NotificationEventsPruneJob deletes notification stream events past retention.
+func NotificationEventsPruneJob(stream *services.NotificationStream) Job {
+	return Job{
+		Name:     JobNotificationEventsPrune,
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			n, err := stream.Prune(ctx, time.Now().Add(-NotificationEventRetention))
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				log.Printf("[Scheduler] Pruned %d notification events", n)
+			}
+			return nil
+		},
+	}
+}
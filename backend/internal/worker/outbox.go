@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/mailer"
+	"github.com/jmoiron/sqlx"
+)
+
+// JobOutbox is the dispatcher's job and lease name.
+const JobOutbox = "outbox_dispatch"
+
+// OutboxHandler delivers one message. A returned error schedules a retry;
+// errors wrapped with Permanent go straight to the dead letters.
+type OutboxHandler func(ctx context.Context, msg models.OutboxMessage) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error { return permanentError{err} }
+
+// OutboxDispatcher delivers outbox messages with exponential backoff. A
+// message that keeps failing is marked dead after its max_attempts and stays
+// there until an admin replays it.
+type OutboxDispatcher struct {
+	repo     repository.OutboxRepository
+	handlers map[string]OutboxHandler
+
+	BatchSize   int
+	LockFor     time.Duration
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	now func() time.Time
+}
+
+func NewOutboxDispatcher(repo repository.OutboxRepository) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		repo:        repo,
+		handlers:    map[string]OutboxHandler{},
+		BatchSize:   50,
+		LockFor:     5 * time.Minute,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		now:         time.Now,
+	}
+}
+
+// Handle registers the handler for kind.
+func (d *OutboxDispatcher) Handle(kind string, h OutboxHandler) {
+	d.handlers[kind] = h
+}
+
+// Job returns the dispatcher's job for the Scheduler.
+func (d *OutboxDispatcher) Job() Job {
+	return Job{Name: JobOutbox, Interval: 5 * time.Second, Run: d.Run}
+}
+
+// Run claims and delivers one batch of due messages.
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	msgs, err := d.repo.Claim(ctx, d.BatchSize, d.LockFor)
+	if err != nil {
+		return fmt.Errorf("claim outbox messages: %w", err)
+	}
+	for _, msg := range msgs {
+		if err := d.deliver(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *OutboxDispatcher) deliver(ctx context.Context, msg models.OutboxMessage) error {
+	h, ok := d.handlers[msg.Kind]
+	var herr error
+	if !ok {
+		herr = Permanent(fmt.Errorf("no handler for kind %q", msg.Kind))
+	} else {
+		herr = h(ctx, msg)
+	}
+	if herr == nil {
+		if err := d.repo.MarkSent(ctx, msg.ID); err != nil {
+			return fmt.Errorf("mark %s sent: %w", msg.ID, err)
+		}
+		return nil
+	}
+
+	attempt := msg.Attempts + 1
+	var perm permanentError
+	dead := errors.As(herr, &perm) || attempt >= msg.MaxAttempts
+	next := d.now().Add(d.Backoff(attempt))
+	if dead {
+		log.Printf("[Outbox] %s message %s dead after %d attempts: %v", msg.Kind, msg.ID, attempt, herr)
+	} else {
+		log.Printf("[Outbox] %s message %s failed (attempt %d), retrying at %s: %v", msg.Kind, msg.ID, attempt, next.Format(time.RFC3339), herr)
+	}
+	if err := d.repo.MarkFailed(ctx, msg.ID, herr.Error(), next, dead); err != nil {
+		return fmt.Errorf("mark %s failed: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Backoff is the delay before retrying after the given (1-based) attempt:
+// BaseBackoff doubled per attempt, capped at MaxBackoff.
+func (d *OutboxDispatcher) Backoff(attempt int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempt && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.MaxBackoff)
+}
+
+// decodePayload unmarshals the message payload; malformed payloads never succeed.
+func decodePayload(msg models.OutboxMessage, v any) error {
+	if err := json.Unmarshal(msg.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("decode %s payload: %w", msg.Kind, err))
+	}
+	return nil
+}
+
+// EmailHandler sends OutboxKindEmail messages.
+func EmailHandler(m mailer.Mailer) OutboxHandler {
+	return func(ctx context.Context, msg models.OutboxMessage) error {
+		var p models.EmailPayload
+		if err := decodePayload(msg, &p); err != nil {
+			return err
+		}
+		if p.To == "" {
+			return Permanent(errors.New("email without recipient"))
+		}
+		return m.SendNotificationEmail(p.To, p.Subject, p.Body)
+	}
+}
+
+// NotificationHandler creates the in-app notification of OutboxKindNotification messages.
+func NotificationHandler(notifications NotificationCreator) OutboxHandler {
+	return func(ctx context.Context, msg models.OutboxMessage) error {
+		var n models.Notification
+		if err := decodePayload(msg, &n); err != nil {
+			return err
+		}
+		if n.TenantID == "" && msg.TenantID != nil {
+			n.TenantID = *msg.TenantID
+		}
+		return notifications.CreateNotification(ctx, &n)
+	}
+}
+
+// AdminNotifier announces new admin notifications to live clients;
+// *services.AdminService implements it.
+type AdminNotifier interface {
+	AdminNotificationCreated(ctx context.Context)
+}
+
+// AdvisorSubmissionHandler tells a student's advisors about a new submission.
+// The notification is keyed by the message id, so a redelivery after a lost
+// MarkSent or an expired claim does not create it again. admins may be nil.
+func AdvisorSubmissionHandler(db *sqlx.DB, admins AdminNotifier) OutboxHandler {
+	return func(ctx context.Context, msg models.OutboxMessage) error {
+		var p models.AdvisorSubmissionPayload
+		if err := decodePayload(msg, &p); err != nil {
+			return err
+		}
+		if err := services.NotifyAdvisorsOnSubmissionOnce(db, msg.ID, p.StudentID, p.NodeID, p.NodeInstanceID, p.Message); err != nil {
+			return err
+		}
+		if admins != nil {
+			admins.AdminNotificationCreated(ctx)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failedCall struct {
+	id, lastError string
+	next          time.Time
+	dead          bool
+}
+
+type fakeOutboxRepo struct {
+	repository.OutboxRepository
+	due    []models.OutboxMessage
+	sent   []string
+	failed []failedCall
+}
+
+func (f *fakeOutboxRepo) Claim(ctx context.Context, limit int, lockFor time.Duration) ([]models.OutboxMessage, error) {
+	out := f.due
+	f.due = nil
+	return out, nil
+}
+
+func (f *fakeOutboxRepo) MarkSent(ctx context.Context, id string) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeOutboxRepo) MarkFailed(ctx context.Context, id, lastError string, next time.Time, dead bool) error {
+	f.failed = append(f.failed, failedCall{id, lastError, next, dead})
+	return nil
+}
+
+func outboxMsg(id, kind string, attempts int, payload any) models.OutboxMessage {
+	data, _ := json.Marshal(payload)
+	return models.OutboxMessage{ID: id, Kind: kind, Payload: data, Attempts: attempts, MaxAttempts: 3, Status: models.OutboxStatusProcessing}
+}
+
+func TestOutboxDispatcher_Run(t *testing.T) {
+	now := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	repo := &fakeOutboxRepo{due: []models.OutboxMessage{
+		outboxMsg("ok", models.OutboxKindEmail, 0, models.EmailPayload{To: "a@example.com", Subject: "Hi"}),
+		outboxMsg("retry", models.OutboxKindEmail, 1, models.EmailPayload{To: "down@example.com"}),
+		outboxMsg("last", models.OutboxKindEmail, 2, models.EmailPayload{To: "down@example.com"}),
+		outboxMsg("bad", models.OutboxKindEmail, 0, "not an object"),
+		outboxMsg("unknown", "fax", 0, nil),
+	}}
+	mail := &fakeMailer{}
+	d := NewOutboxDispatcher(repo)
+	d.now = func() time.Time { return now }
+	d.Handle(models.OutboxKindEmail, func(ctx context.Context, msg models.OutboxMessage) error {
+		var p models.EmailPayload
+		if err := decodePayload(msg, &p); err != nil {
+			return err
+		}
+		if p.To == "down@example.com" {
+			return errors.New("smtp unavailable")
+		}
+		return EmailHandler(mail)(ctx, msg)
+	})
+
+	require.NoError(t, d.Run(context.Background()))
+	assert.Equal(t, []string{"ok"}, repo.sent)
+	assert.Equal(t, []string{"a@example.com"}, mail.to)
+
+	require.Len(t, repo.failed, 4)
+	assert.Equal(t, failedCall{"retry", "smtp unavailable", now.Add(time.Minute), false}, repo.failed[0])
+	assert.True(t, repo.failed[1].dead, "third attempt reaches max_attempts")
+	assert.True(t, repo.failed[2].dead, "malformed payload is permanent")
+	assert.Contains(t, repo.failed[2].lastError, "decode email payload")
+	assert.True(t, repo.failed[3].dead)
+	assert.Contains(t, repo.failed[3].lastError, `no handler for kind "fax"`)
+}
+
+func TestOutboxDispatcher_Backoff(t *testing.T) {
+	d := NewOutboxDispatcher(&fakeOutboxRepo{})
+	assert.Equal(t, 30*time.Second, d.Backoff(1))
+	assert.Equal(t, time.Minute, d.Backoff(2))
+	assert.Equal(t, 4*time.Minute, d.Backoff(4))
+	assert.Equal(t, 6*time.Hour, d.Backoff(20))
+}
+
+func TestOutboxHandlers(t *testing.T) {
+	ctx := context.Background()
+
+	err := EmailHandler(&fakeMailer{})(ctx, outboxMsg("e", models.OutboxKindEmail, 0, models.EmailPayload{Subject: "x"}))
+	var perm permanentError
+	assert.ErrorAs(t, err, &perm, "email without recipient is not retried")
+
+	notifs := &fakeNotifications{}
+	tenant := "t1"
+	msg := outboxMsg("n", models.OutboxKindNotification, 0, models.Notification{RecipientID: "u1", Title: "Hello", Type: "info"})
+	msg.TenantID = &tenant
+	require.NoError(t, NotificationHandler(notifs)(ctx, msg))
+	require.Len(t, notifs.created, 1)
+	assert.Equal(t, "t1", notifs.created[0].TenantID)
+	assert.Equal(t, "u1", notifs.created[0].RecipientID)
+}
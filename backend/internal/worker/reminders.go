@@ -0,0 +1,369 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/mailer"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services/playbook"
+)
+
+// Job names, also used as lease names and escalation_log.job.
+const (
+	JobDeadlineReminders = "deadline_reminders"
+	JobPendingReminders  = "pending_reminders"
+	JobStaleReviews      = "stale_reviews"
+)
+
+// Escalation audiences.
+const (
+	AudienceStudent  = "student"
+	AudienceAdvisors = "advisors"
+	AudienceAdmins   = "admins"
+)
+
+// EscalationTier is one step of a reminder ladder. It is reached Offset after
+// the anchor (the due date, or when the submission started waiting); negative
+// offsets fire before the anchor.
+type EscalationTier struct {
+	Name     string
+	Offset   time.Duration
+	Audience string
+	Type     string // notification type shown in the UI
+}
+
+const day = 24 * time.Hour
+
+// DefaultDeadlineTiers remind the student ahead of and on the due date, then
+// escalate an overdue node to the advisors and finally the tenant admins.
+var DefaultDeadlineTiers = []EscalationTier{
+	{Name: "due_in_3_days", Offset: -3 * day, Audience: AudienceStudent, Type: "deadline_reminder"},
+	{Name: "due_in_1_day", Offset: -1 * day, Audience: AudienceStudent, Type: "deadline_reminder"},
+	{Name: "overdue", Offset: 0, Audience: AudienceStudent, Type: "deadline_overdue"},
+	{Name: "overdue_advisors", Offset: 3 * day, Audience: AudienceAdvisors, Type: "deadline_escalation"},
+	{Name: "overdue_admins", Offset: 7 * day, Audience: AudienceAdmins, Type: "deadline_escalation"},
+}
+
+// DefaultReviewTiers nudge the advisors about a submission nobody reviewed,
+// then escalate it to the tenant admins.
+var DefaultReviewTiers = []EscalationTier{
+	{Name: "review_waiting", Offset: 3 * day, Audience: AudienceAdvisors, Type: "review_reminder"},
+	{Name: "review_overdue", Offset: 7 * day, Audience: AudienceAdmins, Type: "review_escalation"},
+}
+
+// MaxOverdue is how long after the last tier a deadline or submission is
+// still scanned; older ones are left alone.
+const MaxOverdue = 30 * day
+
+// PlaybookResolver returns a tenant's active playbook; it is used for node titles.
+type PlaybookResolver interface {
+	ForTenant(ctx context.Context, tenantID string) (*playbook.Manager, error)
+}
+
+// NotificationCreator stores an in-app notification; *services.NotificationService
+// implements it and also pushes the notification to the live stream.
+type NotificationCreator interface {
+	CreateNotification(ctx context.Context, notif *models.Notification) error
+}
+
+// DeadlineRefresher stores every student's rule-derived deadlines;
+// *services.DeadlineService implements it.
+type DeadlineRefresher interface {
+	SyncAll(ctx context.Context) error
+}
+
+// ReminderWorker delivers admin reminders and walks node deadlines and stale
+// reviews up their escalation tiers, sending an in-app notification and an
+// email for each tier. Every tier is sent at most once per anchor.
+type ReminderWorker struct {
+	repo          repository.SchedulerRepository
+	notifications NotificationCreator
+	mailer        mailer.Mailer
+	playbooks     PlaybookResolver
+	deadlines     DeadlineRefresher
+
+	DeadlineTiers []EscalationTier
+	ReviewTiers   []EscalationTier
+	FrontendURL   string
+
+	now func() time.Time
+}
+
+func NewReminderWorker(repo repository.SchedulerRepository, notifications NotificationCreator, m mailer.Mailer, playbooks PlaybookResolver, frontendURL string) *ReminderWorker {
+	return &ReminderWorker{
+		repo:          repo,
+		notifications: notifications,
+		mailer:        m,
+		playbooks:     playbooks,
+		DeadlineTiers: DefaultDeadlineTiers,
+		ReviewTiers:   DefaultReviewTiers,
+		FrontendURL:   frontendURL,
+		now:           time.Now,
+	}
+}
+
+// SetDeadlines makes RunDeadlines store rule-derived deadlines before it
+// scans them; otherwise only deadlines already in node_deadlines are seen.
+func (w *ReminderWorker) SetDeadlines(deadlines DeadlineRefresher) {
+	w.deadlines = deadlines
+}
+
+// Jobs returns the worker's jobs for the Scheduler.
+func (w *ReminderWorker) Jobs() []Job {
+	return []Job{
+		{Name: JobPendingReminders, Interval: time.Minute, Run: w.RunPendingReminders},
+		{Name: JobDeadlineReminders, Interval: 15 * time.Minute, Run: w.RunDeadlines},
+		{Name: JobStaleReviews, Interval: time.Hour, Run: w.RunStaleReviews},
+	}
+}
+
+// currentTier returns the last tier reached at now, if any.
+func currentTier(tiers []EscalationTier, anchor, now time.Time) (EscalationTier, bool) {
+	var out EscalationTier
+	found := false
+	for _, t := range tiers {
+		if !anchor.Add(t.Offset).After(now) && (!found || t.Offset > out.Offset) {
+			out, found = t, true
+		}
+	}
+	return out, found
+}
+
+// window is the anchor range in which some tier can fire at now.
+func window(tiers []EscalationTier, now time.Time) (from, to time.Time) {
+	if len(tiers) == 0 {
+		return now, now
+	}
+	lo, hi := tiers[0].Offset, tiers[0].Offset
+	for _, t := range tiers[1:] {
+		lo, hi = min(lo, t.Offset), max(hi, t.Offset)
+	}
+	return now.Add(-hi - MaxOverdue), now.Add(-lo)
+}
+
+// RunPendingReminders delivers reminders admins created for students.
+func (w *ReminderWorker) RunPendingReminders(ctx context.Context) error {
+	reminders, err := w.repo.ListPendingReminders(ctx, 500)
+	if err != nil {
+		return fmt.Errorf("list pending reminders: %w", err)
+	}
+	sent := 0
+	for _, r := range reminders {
+		if r.TenantID == "" {
+			log.Printf("[Scheduler] Reminder %s has no tenant, skipping", r.ID)
+			continue
+		}
+		message := r.Title
+		if r.Message != nil && *r.Message != "" {
+			message = *r.Message
+		}
+		if r.DueAt != nil {
+			message += fmt.Sprintf(" (due %s)", r.DueAt.UTC().Format("2006-01-02"))
+		}
+		student, err := w.repo.GetRecipient(ctx, r.StudentID)
+		if err != nil {
+			return fmt.Errorf("load student %s: %w", r.StudentID, err)
+		}
+		if student != nil {
+			actor := r.CreatedBy
+			w.deliver(ctx, r.TenantID, []models.Recipient{*student}, &actor, r.Title, message, "/journey", "reminder")
+		}
+		// Reminders for deactivated students are closed too, so they do not pile up
+		if err := w.repo.MarkReminderSent(ctx, r.ID); err != nil {
+			return fmt.Errorf("mark reminder %s: %w", r.ID, err)
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("[Scheduler] Delivered %d reminders", sent)
+	}
+	return nil
+}
+
+// RunDeadlines sends the current tier for every open deadline in range.
+func (w *ReminderWorker) RunDeadlines(ctx context.Context) error {
+	if w.deadlines != nil {
+		if err := w.deadlines.SyncAll(ctx); err != nil {
+			log.Printf("[Scheduler] Deadline sync failed: %v", err)
+		}
+	}
+	now := w.now()
+	from, to := window(w.DeadlineTiers, now)
+	deadlines, err := w.repo.ListOpenDeadlines(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("list deadlines: %w", err)
+	}
+	for _, d := range deadlines {
+		tier, ok := currentTier(w.DeadlineTiers, d.DueAt, now)
+		if !ok || d.TenantID == "" {
+			continue
+		}
+		claimed, err := w.repo.ClaimEscalation(ctx, JobDeadlineReminders, d.ID, d.DueAt, tier.Name)
+		if err != nil {
+			return fmt.Errorf("claim %s for deadline %s: %w", tier.Name, d.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		node := w.nodeTitle(ctx, d.TenantID, d.NodeID)
+		due := d.DueAt.UTC().Format("2006-01-02")
+		var title, message string
+		switch {
+		case tier.Audience == AudienceStudent && tier.Offset < 0:
+			title = "Upcoming deadline: " + node
+			message = fmt.Sprintf("\"%s\" is due on %s.", node, due)
+		case tier.Audience == AudienceStudent:
+			title = "Deadline passed: " + node
+			message = fmt.Sprintf("\"%s\" was due on %s and is not complete yet.", node, due)
+		default:
+			student := w.studentName(ctx, d.UserID)
+			title = "Overdue step: " + node
+			message = fmt.Sprintf("%s has not completed \"%s\", which was due on %s.", student, node, due)
+		}
+		if err := w.escalate(ctx, tier, d.TenantID, d.UserID, title, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunStaleReviews escalates submissions that have waited too long for review.
+func (w *ReminderWorker) RunStaleReviews(ctx context.Context) error {
+	if len(w.ReviewTiers) == 0 {
+		return nil
+	}
+	now := w.now()
+	first := w.ReviewTiers[0].Offset
+	for _, t := range w.ReviewTiers[1:] {
+		first = min(first, t.Offset)
+	}
+	reviews, err := w.repo.ListStaleReviews(ctx, now.Add(-first))
+	if err != nil {
+		return fmt.Errorf("list stale reviews: %w", err)
+	}
+	for _, r := range reviews {
+		tier, ok := currentTier(w.ReviewTiers, r.WaitingSince, now)
+		if !ok || r.TenantID == "" {
+			continue
+		}
+		claimed, err := w.repo.ClaimEscalation(ctx, JobStaleReviews, r.InstanceID, r.WaitingSince, tier.Name)
+		if err != nil {
+			return fmt.Errorf("claim %s for submission %s: %w", tier.Name, r.InstanceID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		node := w.nodeTitle(ctx, r.TenantID, r.NodeID)
+		student := w.studentName(ctx, r.UserID)
+		days := int(now.Sub(r.WaitingSince) / day)
+		title := "Submission awaiting review: " + node
+		message := fmt.Sprintf("%s submitted \"%s\" %d days ago and it has not been reviewed yet.", student, node, days)
+		if err := w.escalate(ctx, tier, r.TenantID, r.UserID, title, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escalate sends one tier to its audience.
+func (w *ReminderWorker) escalate(ctx context.Context, tier EscalationTier, tenantID, studentID, title, message string) error {
+	var recipients []models.Recipient
+	link := "/admin/students-monitor/" + studentID
+	switch tier.Audience {
+	case AudienceStudent:
+		link = "/journey"
+		student, err := w.repo.GetRecipient(ctx, studentID)
+		if err != nil {
+			return fmt.Errorf("load student %s: %w", studentID, err)
+		}
+		if student != nil {
+			recipients = append(recipients, *student)
+		}
+	case AudienceAdvisors:
+		advisors, err := w.repo.ListStudentAdvisors(ctx, studentID)
+		if err != nil {
+			return fmt.Errorf("load advisors of %s: %w", studentID, err)
+		}
+		recipients = advisors
+		if len(recipients) == 0 {
+			// Nobody to nudge; go straight to the admins
+			if recipients, err = w.repo.ListTenantAdmins(ctx, tenantID); err != nil {
+				return fmt.Errorf("load admins of %s: %w", tenantID, err)
+			}
+		}
+	case AudienceAdmins:
+		admins, err := w.repo.ListTenantAdmins(ctx, tenantID)
+		if err != nil {
+			return fmt.Errorf("load admins of %s: %w", tenantID, err)
+		}
+		recipients = admins
+	}
+	if len(recipients) == 0 {
+		log.Printf("[Scheduler] No recipients for %s about student %s", tier.Name, studentID)
+		return nil
+	}
+	w.deliver(ctx, tenantID, recipients, nil, title, message, link, tier.Type)
+	return nil
+}
+
+// deliver creates an in-app notification and sends an email to each
+// recipient. Failures are logged and do not stop the others.
+func (w *ReminderWorker) deliver(ctx context.Context, tenantID string, recipients []models.Recipient, actorID *string, title, message, link, nType string) {
+	body := fmt.Sprintf("<p>%s</p>", html.EscapeString(message))
+	if w.FrontendURL != "" {
+		body += fmt.Sprintf(`<p><a href="%s%s">Open the portal</a></p>`, html.EscapeString(w.FrontendURL), html.EscapeString(link))
+	}
+	for _, r := range recipients {
+		notif := &models.Notification{
+			TenantID:    tenantID,
+			RecipientID: r.ID,
+			ActorID:     actorID,
+			Title:       title,
+			Message:     message,
+			Link:        &link,
+			Type:        nType,
+		}
+		if err := w.notifications.CreateNotification(ctx, notif); err != nil {
+			log.Printf("[Scheduler] Failed to notify %s: %v", r.ID, err)
+		}
+		if r.Email != "" && w.mailer != nil {
+			if err := w.mailer.SendNotificationEmail(r.Email, title, body); err != nil {
+				log.Printf("[Scheduler] Failed to email %s: %v", r.ID, err)
+			}
+		}
+	}
+}
+
+func (w *ReminderWorker) nodeTitle(ctx context.Context, tenantID, nodeID string) string {
+	if w.playbooks == nil {
+		return nodeID
+	}
+	pm, err := w.playbooks.ForTenant(ctx, tenantID)
+	if err != nil || pm == nil {
+		return nodeID
+	}
+	node, ok := pm.NodeDefinition(nodeID)
+	if !ok {
+		return nodeID
+	}
+	for _, locale := range []string{"en", pm.DefaultLocale} {
+		if t := node.Title[locale]; t != "" {
+			return t
+		}
+	}
+	return nodeID
+}
+
+func (w *ReminderWorker) studentName(ctx context.Context, studentID string) string {
+	if s, err := w.repo.GetRecipient(ctx, studentID); err == nil && s != nil && s.Name != "" {
+		return s.Name
+	}
+	return "A student"
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// LeaseStore hands out per-job leases so that, with several replicas running,
+// each job runs on one of them at a time.
+type LeaseStore interface {
+	AcquireLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, job, holder string) error
+}
+
+// Job is a task the Scheduler runs every Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs registered jobs on their own tickers. Before every run it
+// takes or renews the job's lease; the lease outlives the interval, so the
+// replica holding it keeps the job until it stops or dies.
+type Scheduler struct {
+	leases LeaseStore
+	holder string
+
+	mu   sync.Mutex
+	jobs []Job
+}
+
+func NewScheduler(leases LeaseStore, holder string) *Scheduler {
+	if holder == "" {
+		holder = DefaultHolder()
+	}
+	return &Scheduler{leases: leases, holder: holder}
+}
+
+// DefaultHolder identifies this process as a lease holder.
+func DefaultHolder() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
+// Register adds jobs. It must be called before Start.
+func (s *Scheduler) Register(jobs ...Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, jobs...)
+}
+
+// Start runs every job immediately and then on its interval until ctx is
+// cancelled. Leases held by this process are released on the way out.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	log.Printf("[Scheduler] Started %d jobs as %s", len(jobs), s.holder)
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+
+	// ctx is done; release with a fresh one so another replica can take over at once
+	releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, job := range jobs {
+		if err := s.leases.ReleaseLease(releaseCtx, job.Name, s.holder); err != nil {
+			log.Printf("[Scheduler] Failed to release lease %s: %v", job.Name, err)
+		}
+	}
+	log.Println("[Scheduler] Stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	s.RunOnce(ctx, job)
+	for {
+		select {
+		case <-ticker.C:
+			s.RunOnce(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunOnce runs job if this process holds, or can take, its lease. It reports
+// whether the job ran.
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) bool {
+	ok, err := s.leases.AcquireLease(ctx, job.Name, s.holder, leaseTTL(job.Interval))
+	if err != nil {
+		log.Printf("[Scheduler] Lease %s: %v", job.Name, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	started := time.Now()
+	if err := runJob(ctx, job); err != nil {
+		log.Printf("[Scheduler] Job %s failed after %v: %v", job.Name, time.Since(started), err)
+	}
+	return true
+}
+
+// runJob runs job once, turning a panic into an error so one bad run does
+// not take the scheduler and the server down with it.
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// leaseTTL keeps the lease past the next tick so the holder renews it before
+// anybody else can take it, while a dead holder is replaced within two intervals.
+func leaseTTL(interval time.Duration) time.Duration {
+	return 2 * interval
+}
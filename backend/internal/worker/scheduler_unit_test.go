@@ -0,0 +1,302 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/models"
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memLeases mimics job_leases: a lease is free when unheld, expired or already ours.
+type memLeases struct {
+	mu      sync.Mutex
+	now     time.Time
+	holders map[string]string
+	expires map[string]time.Time
+}
+
+func newMemLeases(now time.Time) *memLeases {
+	return &memLeases{now: now, holders: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (m *memLeases) AcquireLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.holders[job]; ok && cur != holder && m.expires[job].After(m.now) {
+		return false, nil
+	}
+	m.holders[job], m.expires[job] = holder, m.now.Add(ttl)
+	return true, nil
+}
+
+func (m *memLeases) ReleaseLease(ctx context.Context, job, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holders[job] == holder {
+		delete(m.holders, job)
+	}
+	return nil
+}
+
+func TestScheduler_RunOnce_LeaseSingleReplica(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	leases := newMemLeases(now)
+	a, b := NewScheduler(leases, "a"), NewScheduler(leases, "b")
+
+	runs := map[string]int{}
+	job := func(name string) Job {
+		return Job{Name: "job", Interval: time.Minute, Run: func(ctx context.Context) error { runs[name]++; return nil }}
+	}
+
+	assert.True(t, a.RunOnce(context.Background(), job("a")))
+	assert.False(t, b.RunOnce(context.Background(), job("b")), "lease held by a")
+	assert.True(t, a.RunOnce(context.Background(), job("a")), "holder renews its lease")
+
+	// a dies; once its lease (two intervals) runs out b takes over
+	leases.now = now.Add(3 * time.Minute)
+	assert.True(t, b.RunOnce(context.Background(), job("b")))
+	assert.False(t, a.RunOnce(context.Background(), job("a")))
+	assert.Equal(t, map[string]int{"a": 2, "b": 1}, runs)
+}
+
+func TestScheduler_RunOnce_RecoversPanic(t *testing.T) {
+	s := NewScheduler(newMemLeases(time.Now()), "a")
+	job := Job{Name: "job", Interval: time.Minute, Run: func(ctx context.Context) error {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	}}
+	assert.NotPanics(t, func() { assert.True(t, s.RunOnce(context.Background(), job)) })
+
+	err := runJob(context.Background(), job)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "assignment to entry in nil map")
+}
+
+func TestScheduler_StartReleasesLeases(t *testing.T) {
+	leases := newMemLeases(time.Now())
+	s := NewScheduler(leases, "a")
+	ran := make(chan struct{}, 1)
+	s.Register(Job{Name: "job", Interval: time.Hour, Run: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return errors.New("boom")
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.Start(ctx); close(done) }()
+	<-ran
+	cancel()
+	<-done
+	assert.Empty(t, leases.holders)
+}
+
+func TestCurrentTier(t *testing.T) {
+	due := time.Date(2025, 3, 10, 23, 59, 59, 0, time.UTC)
+	cases := []struct {
+		now  time.Time
+		want string
+	}{
+		{due.Add(-4 * day), ""},
+		{due.Add(-3 * day), "due_in_3_days"},
+		{due.Add(-2 * day), "due_in_3_days"},
+		{due.Add(-time.Hour), "due_in_1_day"},
+		{due.Add(time.Hour), "overdue"},
+		{due.Add(4 * day), "overdue_advisors"},
+		{due.Add(20 * day), "overdue_admins"},
+	}
+	for _, c := range cases {
+		tier, ok := currentTier(DefaultDeadlineTiers, due, c.now)
+		assert.Equal(t, c.want != "", ok, c.now)
+		assert.Equal(t, c.want, tier.Name, c.now)
+	}
+}
+
+// fakeSchedulerRepo is an in-memory SchedulerRepository.
+type fakeSchedulerRepo struct {
+	repository.SchedulerRepository
+	deadlines  []models.DueDeadline
+	reviews    []models.StaleReview
+	reminders  []models.PendingReminder
+	sent       []string
+	claimed    map[string]bool
+	users      map[string]models.Recipient
+	advisors   map[string][]models.Recipient
+	admins     map[string][]models.Recipient
+	lastWindow [2]time.Time
+}
+
+func newFakeSchedulerRepo() *fakeSchedulerRepo {
+	return &fakeSchedulerRepo{
+		claimed: map[string]bool{},
+		users: map[string]models.Recipient{
+			"s1":  {ID: "s1", Name: "Aigerim", Email: "s1@example.com"},
+			"a1":  {ID: "a1", Name: "Advisor", Email: "a1@example.com"},
+			"adm": {ID: "adm", Name: "Admin", Email: ""},
+		},
+		advisors: map[string][]models.Recipient{},
+		admins:   map[string][]models.Recipient{},
+	}
+}
+
+func (f *fakeSchedulerRepo) ListOpenDeadlines(ctx context.Context, from, to time.Time) ([]models.DueDeadline, error) {
+	f.lastWindow = [2]time.Time{from, to}
+	return f.deadlines, nil
+}
+
+func (f *fakeSchedulerRepo) ListStaleReviews(ctx context.Context, before time.Time) ([]models.StaleReview, error) {
+	var out []models.StaleReview
+	for _, r := range f.reviews {
+		if !r.WaitingSince.After(before) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSchedulerRepo) ListPendingReminders(ctx context.Context, limit int) ([]models.PendingReminder, error) {
+	return f.reminders, nil
+}
+
+func (f *fakeSchedulerRepo) MarkReminderSent(ctx context.Context, id string) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeSchedulerRepo) ClaimEscalation(ctx context.Context, job, subjectID string, anchor time.Time, tier string) (bool, error) {
+	key := job + "|" + subjectID + "|" + anchor.String() + "|" + tier
+	if f.claimed[key] {
+		return false, nil
+	}
+	f.claimed[key] = true
+	return true, nil
+}
+
+func (f *fakeSchedulerRepo) GetRecipient(ctx context.Context, userID string) (*models.Recipient, error) {
+	if u, ok := f.users[userID]; ok {
+		return &u, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeSchedulerRepo) ListStudentAdvisors(ctx context.Context, studentID string) ([]models.Recipient, error) {
+	return f.advisors[studentID], nil
+}
+
+func (f *fakeSchedulerRepo) ListTenantAdmins(ctx context.Context, tenantID string) ([]models.Recipient, error) {
+	return f.admins[tenantID], nil
+}
+
+type fakeNotifications struct {
+	created []models.Notification
+}
+
+func (f *fakeNotifications) CreateNotification(ctx context.Context, n *models.Notification) error {
+	f.created = append(f.created, *n)
+	return nil
+}
+
+type fakeMailer struct{ to []string }
+
+func (m *fakeMailer) SendNotificationEmail(to, subject, body string) error {
+	m.to = append(m.to, to)
+	return nil
+}
+
+func (m *fakeMailer) SendStateChangeNotification(to, studentName, nodeID, oldState, newState, frontendURL string) error {
+	return nil
+}
+
+func newTestReminderWorker(repo *fakeSchedulerRepo, now time.Time) (*ReminderWorker, *fakeNotifications, *fakeMailer) {
+	notifs, mail := &fakeNotifications{}, &fakeMailer{}
+	w := NewReminderWorker(repo, notifs, mail, nil, "http://portal")
+	w.now = func() time.Time { return now }
+	return w, notifs, mail
+}
+
+func TestReminderWorker_DeadlineEscalation(t *testing.T) {
+	due := time.Date(2025, 3, 10, 23, 59, 59, 0, time.UTC)
+	repo := newFakeSchedulerRepo()
+	repo.deadlines = []models.DueDeadline{{ID: "d1", TenantID: "t1", UserID: "s1", NodeID: "S1_profile", DueAt: due}}
+	repo.advisors["s1"] = []models.Recipient{repo.users["a1"]}
+	repo.admins["t1"] = []models.Recipient{repo.users["adm"]}
+
+	now := due.Add(-2 * day)
+	w, notifs, mail := newTestReminderWorker(repo, now)
+	ctx := context.Background()
+
+	require.NoError(t, w.RunDeadlines(ctx))
+	assert.Equal(t, now.Add(-7*day-MaxOverdue), repo.lastWindow[0])
+	assert.Equal(t, now.Add(3*day), repo.lastWindow[1])
+	require.Len(t, notifs.created, 1)
+	assert.Equal(t, "s1", notifs.created[0].RecipientID)
+	assert.Equal(t, "t1", notifs.created[0].TenantID)
+	assert.Equal(t, "deadline_reminder", notifs.created[0].Type)
+	assert.Contains(t, notifs.created[0].Message, "2025-03-10")
+	assert.Equal(t, []string{"s1@example.com"}, mail.to)
+
+	// Same tier is not sent twice
+	require.NoError(t, w.RunDeadlines(ctx))
+	assert.Len(t, notifs.created, 1)
+
+	// Four days late: the advisors hear about it
+	w.now = func() time.Time { return due.Add(4 * day) }
+	require.NoError(t, w.RunDeadlines(ctx))
+	require.Len(t, notifs.created, 2)
+	assert.Equal(t, "a1", notifs.created[1].RecipientID)
+	assert.Equal(t, "/admin/students-monitor/s1", *notifs.created[1].Link)
+	assert.Contains(t, notifs.created[1].Message, "Aigerim")
+
+	// A week late: admins; no email without an address
+	w.now = func() time.Time { return due.Add(8 * day) }
+	require.NoError(t, w.RunDeadlines(ctx))
+	require.Len(t, notifs.created, 3)
+	assert.Equal(t, "adm", notifs.created[2].RecipientID)
+	assert.Equal(t, []string{"s1@example.com", "a1@example.com"}, mail.to)
+}
+
+func TestReminderWorker_StaleReviews(t *testing.T) {
+	now := time.Date(2025, 3, 20, 9, 0, 0, 0, time.UTC)
+	repo := newFakeSchedulerRepo()
+	repo.reviews = []models.StaleReview{
+		{InstanceID: "i1", TenantID: "t1", UserID: "s1", NodeID: "S1_text_ready", WaitingSince: now.Add(-4 * day)},
+		{InstanceID: "i2", TenantID: "t1", UserID: "s1", NodeID: "S1_antiplag", WaitingSince: now.Add(-time.Hour)},
+	}
+	// No advisor assigned: the advisor tier falls through to the admins
+	repo.admins["t1"] = []models.Recipient{repo.users["adm"]}
+
+	w, notifs, _ := newTestReminderWorker(repo, now)
+	require.NoError(t, w.RunStaleReviews(context.Background()))
+	require.Len(t, notifs.created, 1)
+	assert.Equal(t, "adm", notifs.created[0].RecipientID)
+	assert.Equal(t, "review_reminder", notifs.created[0].Type)
+	assert.Contains(t, notifs.created[0].Message, "4 days ago")
+}
+
+func TestReminderWorker_PendingReminders(t *testing.T) {
+	due := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	msg := "Please upload the draft"
+	repo := newFakeSchedulerRepo()
+	repo.reminders = []models.PendingReminder{
+		{ID: "r1", TenantID: "t1", StudentID: "s1", Title: "Draft", Message: &msg, DueAt: &due, CreatedBy: "adm"},
+		{ID: "r2", TenantID: "", StudentID: "s1", Title: "Orphan", CreatedBy: "adm"},
+		{ID: "r3", TenantID: "t1", StudentID: "gone", Title: "Inactive", CreatedBy: "adm"},
+	}
+
+	w, notifs, mail := newTestReminderWorker(repo, time.Now())
+	require.NoError(t, w.RunPendingReminders(context.Background()))
+	require.Len(t, notifs.created, 1)
+	n := notifs.created[0]
+	assert.Equal(t, "Draft", n.Title)
+	assert.Equal(t, "Please upload the draft (due 2025-04-01)", n.Message)
+	assert.Equal(t, "adm", *n.ActorID)
+	assert.Equal(t, "reminder", n.Type)
+	assert.Equal(t, []string{"s1@example.com"}, mail.to)
+	assert.Equal(t, []string{"r1", "r3"}, repo.sent)
+}
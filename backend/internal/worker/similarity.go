@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AlmatJuvashev/phd-students-portal/backend/internal/services"
+)
+
+// JobSimilarity is the dissertation similarity check's lease name.
+const JobSimilarity = "similarity"
+
+// SimilarityJob checks newly extracted dissertation versions against the
+// earlier ones of their tenant.
+func SimilarityJob(checks *services.SimilarityService) Job {
+	return Job{
+		Name:     JobSimilarity,
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			n, err := checks.CheckPending(ctx)
+			if n > 0 {
+				log.Printf("[Scheduler] Built %d similarity reports", n)
+			}
+			return err
+		},
+	}
+}
@@ -18,6 +18,41 @@ export const API_URL = getBaseUrl();
 
 import { getTenantHeaders } from '@/lib/tenant';
 
+// Access tokens are short-lived; on a 401 the refresh token cookie renews
+// them. Concurrent requests share one refresh, as each refresh token works once.
+let refreshing: Promise<boolean> | null = null;
+
+function refreshSession(): Promise<boolean> {
+  if (!refreshing) {
+    refreshing = fetch(`${API_URL}/auth/refresh`, {
+      method: "POST",
+      credentials: "include",
+      headers: getTenantHeaders(),
+    })
+      .then((res) => res.ok)
+      .catch(() => false)
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+}
+
+// authFetch sends a request with the session cookies and, on a 401, retries
+// it once after refreshing the session. It returns the raw response for
+// callers that handle statuses and errors themselves; most should use api().
+export async function authFetch(
+  path: string,
+  opts: RequestInit = {}
+): Promise<Response> {
+  const init: RequestInit = { ...opts, credentials: "include" };
+  let res = await fetch(`${API_URL}${path}`, init);
+  if (res.status === 401 && !path.startsWith("/auth/") && (await refreshSession())) {
+    res = await fetch(`${API_URL}${path}`, init);
+  }
+  return res;
+}
+
 export async function api<T = any>(
   path: string,
   opts: RequestInit = {}
@@ -60,7 +95,7 @@ export async function api<T = any>(
     isFormData: opts.body instanceof FormData,
   });
   
-  const res = await fetch(`${API_URL}${path}`, { ...opts, headers });
+  const res = await authFetch(path, { ...opts, headers });
   
   console.log("[API Debug] Response:", {
     status: res.status,
@@ -1,4 +1,4 @@
-import { api, API_URL } from "./client";
+import { api, authFetch } from "./client";
 
 export type NodeSubmissionDTO = {
   node_id: string;
@@ -99,7 +99,7 @@ export async function patchNodeState(
 
 export async function getProfileSnapshot() {
   const token = localStorage.getItem("token");
-  const res = await fetch(`${API_URL}/journey/profile`, {
+  const res = await authFetch("/journey/profile", {
     headers: {
       "Content-Type": "application/json",
       ...(token ? { Authorization: `Bearer ${token}` } : {}),
@@ -25,11 +25,21 @@ export interface User {
   cohort?: string
 }
 
+// LoginResult of a password sign-in. With mfa set there is no session yet:
+// the code from the user's authenticator goes to verifyMfa.
+export interface LoginResult {
+  role: string
+  is_superadmin: boolean
+  mfa?: { token: string; enrollment_required: boolean }
+  recovery_codes?: string[]
+}
+
 interface AuthContextType {
   user: User | null
   isLoading: boolean
   token: string | null
-  login: (credentials: { username?: string; email?: string; password: string }) => Promise<{ role: string; is_superadmin: boolean }>
+  login: (credentials: { username?: string; email?: string; password: string }) => Promise<LoginResult>
+  verifyMfa: (mfaToken: string, code: string) => Promise<LoginResult>
   logout: () => void
 }
 
@@ -56,11 +66,26 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
           password: credentials.password,
         }),
       })
+      if (res.mfa_required) {
+        return {
+          role: '',
+          is_superadmin: false,
+          mfa: { token: res.mfa_token, enrollment_required: res.enrollment_required },
+        }
+      }
       // Token is set in cookie by server
       // Refresh user info
       await qc.invalidateQueries({ queryKey: ['me'] })
       return { role: res.role, is_superadmin: res.is_superadmin }
     },
+    verifyMfa: async (mfaToken, code) => {
+      const res = await api('/auth/2fa/verify', {
+        method: 'POST',
+        body: JSON.stringify({ mfa_token: mfaToken, code }),
+      })
+      await qc.invalidateQueries({ queryKey: ['me'] })
+      return { role: res.role, is_superadmin: res.is_superadmin, recovery_codes: res.recovery_codes }
+    },
     logout: async () => {
       console.log("[AuthContext] Logout initiated");
       try {
@@ -8,7 +8,7 @@ import "react-big-calendar/lib/css/react-big-calendar.css";
 import "./calendar-mobile.css";
 import { useQuery, useQueryClient } from "@tanstack/react-query";
 import { Button } from "@/components/ui/button";
-import { API_URL } from "@/api/client";
+import { authFetch } from "@/api/client";
 import { Plus } from "lucide-react";
 import { EventModal } from "./EventModal";
 import { useAuth } from "@/contexts/AuthContext";
@@ -88,7 +88,7 @@ export const CalendarView = () => {
   const fetchEvents = async () => {
     const start = new Date(new Date().getFullYear(), 0, 1).toISOString();
     const end = new Date(new Date().getFullYear() + 1, 0, 1).toISOString();
-    const res = await fetch(`${API_URL}/events?start=${start}&end=${end}`, {
+    const res = await authFetch(`/events?start=${start}&end=${end}`, {
       headers: { Authorization: `Bearer ${token}` },
     });
     if (!res.ok)
@@ -23,7 +23,7 @@ import {
 import { useAuth } from "@/contexts/AuthContext";
 import { useMutation } from "@tanstack/react-query";
 import { useTranslation } from "react-i18next";
-import { API_URL } from "@/api/client";
+import { authFetch } from "@/api/client";
 import { Video, MapPin } from "lucide-react";
 import { cn } from "@/lib/utils";
 
@@ -171,10 +171,10 @@ export const EventModal: React.FC<EventModalProps> = ({
 
   const mutation = useMutation({
     mutationFn: async (data: EventFormValues) => {
-      const url = event ? `${API_URL}/events/${event.id}` : `${API_URL}/events`;
+      const path = event ? `/events/${event.id}` : "/events";
       const method = event ? "PUT" : "POST";
 
-      const res = await fetch(url, {
+      const res = await authFetch(path, {
         method,
         headers: {
           "Content-Type": "application/json",
@@ -215,7 +215,7 @@ export const EventModal: React.FC<EventModalProps> = ({
       return;
 
     try {
-      const res = await fetch(`${API_URL}/events/${event.id}`, {
+      const res = await authFetch(`/events/${event.id}`, {
         method: "DELETE",
         headers: { Authorization: `Bearer ${token}` },
       });
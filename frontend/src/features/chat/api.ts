@@ -21,6 +21,10 @@ export type ChatAttachment = {
   type: string;
   name: string;
   size: number;
+  // Set for files in object storage; url is then an API endpoint that
+  // returns a short-lived download link
+  document_id?: string;
+  version_id?: string;
 };
 
 export type ChatMessage = {
@@ -116,36 +120,58 @@ export async function deleteMessage(messageId: string) {
   });
 }
 
+// uploadFile stores a file for a message in the room: presigned PUT to
+// object storage, then attach. Servers without object storage still take
+// the file directly.
 export async function uploadFile(roomId: string, file: File): Promise<ChatAttachment> {
-  console.log("[uploadFile] Starting upload:", {
-    roomId,
-    fileName: file.name,
-    fileSize: file.size,
-    fileType: file.type,
-  });
-  
-  const formData = new FormData();
-  formData.append("file", file);
-  
-  console.log("[uploadFile] FormData created, entries:");
-  for (const [key, value] of formData.entries()) {
-    console.log(`  ${key}:`, value);
-  }
-  
+  const contentType = file.type || "application/octet-stream";
+  let ticket: { upload_url: string; object_key: string; document_id: string };
   try {
-    const res = await api(`/chat/rooms/${roomId}/upload`, {
+    ticket = await api(`/chat/rooms/${roomId}/uploads/presign`, {
       method: "POST",
-      body: formData,
+      body: JSON.stringify({ filename: file.name, content_type: contentType, size_bytes: file.size }),
     });
-    console.log("[uploadFile] Upload response:", res);
-    return res as ChatAttachment;
-  } catch (error) {
-    console.error("[uploadFile] Upload failed:", error);
+  } catch (error: any) {
+    if (String(error?.message).includes("file storage is not configured")) {
+      return uploadFileDirect(roomId, file);
+    }
     throw error;
   }
+
+  const put = await fetch(ticket.upload_url, {
+    method: "PUT",
+    body: file,
+    headers: { "Content-Type": contentType },
+  });
+  if (!put.ok) throw new Error(`Upload failed: ${put.status}`);
+
+  const res = await api<{ attachment: ChatAttachment }>(`/chat/rooms/${roomId}/uploads/attach`, {
+    method: "POST",
+    body: JSON.stringify({
+      document_id: ticket.document_id,
+      object_key: ticket.object_key,
+      filename: file.name,
+      content_type: contentType,
+      size_bytes: file.size,
+    }),
+  });
+  return res.attachment;
 }
 
+async function uploadFileDirect(roomId: string, file: File): Promise<ChatAttachment> {
+  const formData = new FormData();
+  formData.append("file", file);
+  return api<ChatAttachment>(`/chat/rooms/${roomId}/upload`, {
+    method: "POST",
+    body: formData,
+  });
+}
 
+// attachmentDownloadUrl resolves a stored file to a short-lived link.
+export async function attachmentDownloadUrl(versionId: string): Promise<string> {
+  const res = await api<{ url: string }>(`/chat/attachments/${versionId}`);
+  return res.url;
+}
 
 export async function addMember(roomId: string, userId: string, role: string = "member") {
   return api(`/chat/rooms/${roomId}/members`, {
@@ -2,7 +2,7 @@ import React, { useState, useRef, useEffect } from 'react';
 import { motion, AnimatePresence } from 'framer-motion';
 import { ArrowLeft, Send, Paperclip, MoreHorizontal, Image as ImageIcon, FileText, Check, Clock, CloudDownload, Archive, Reply, Trash2, Edit2, Info, Share, ExternalLink, X, CornerUpRight } from 'lucide-react';
 import { useMutation, useQueryClient } from "@tanstack/react-query";
-import { archiveRoom, updateMessage, deleteMessage, createMessage, getRoomMembers } from "../api";
+import { archiveRoom, updateMessage, deleteMessage, createMessage, getRoomMembers, attachmentDownloadUrl } from "../api";
 import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from "@/components/ui/dialog";
 import { format } from "date-fns";
 import { cn } from "@/lib/utils";
@@ -29,6 +29,8 @@ export interface ChatMessageDisplay {
         id?: string;
         name: string;
         url: string;
+        // Stored files resolve to a short-lived link when opened
+        versionId?: string;
         type: string;
         size?: string;
     }[];
@@ -112,6 +114,21 @@ export const ChatWindow: React.FC<ChatWindowProps> = ({
       }
   };
 
+  // Stored files have no permanent link: the tab opens right away, so it is
+  // not blocked as a popup, and goes to the link once the API returns it
+  const openStoredAttachment = async (e: React.MouseEvent, versionId: string) => {
+      e.preventDefault();
+      const tab = window.open("", "_blank");
+      try {
+          const url = await attachmentDownloadUrl(versionId);
+          if (tab) tab.location.href = url;
+          else window.location.href = url;
+      } catch (err) {
+          tab?.close();
+          console.error("[ChatWindow] Failed to open attachment", err);
+      }
+  };
+
   const isAdmin = currentUser.role === 'admin' || currentUser.role === 'superadmin';
 
   const scrollToBottom = () => {
@@ -351,9 +368,10 @@ export const ChatWindow: React.FC<ChatWindowProps> = ({
                 {msg.attachments.map((att, idx) => (
                   <a 
                     key={idx} 
-                    href={att.url} 
+                    href={att.versionId ? "#" : att.url} 
                     target="_blank" 
                     rel="noopener noreferrer"
+                    onClick={att.versionId ? (e) => openStoredAttachment(e, att.versionId!) : undefined}
                     className={cn(
                       "flex items-center gap-3 p-3 rounded-xl transition-all border",
                       isMe 
@@ -1,4 +1,4 @@
-import { API_URL } from '@/api/client';
+import { authFetch } from '@/api/client';
 import { getTenantHeaders } from '@/lib/tenant';
 
 // Types for Superadmin API
@@ -99,7 +99,7 @@ async function superadminFetch<T>(
   options: RequestInit = {}
 ): Promise<T> {
   const token = localStorage.getItem('token');
-  const response = await fetch(`${API_URL}${endpoint}`, {
+  const response = await authFetch(endpoint, {
     ...options,
     headers: {
       'Content-Type': 'application/json',
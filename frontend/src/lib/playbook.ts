@@ -61,6 +61,9 @@ export type NodeDef = {
     next: string[];
   }>;
   condition?: string; // like "rp_required"
+  // A conditional stage only applies when its condition holds; on other
+  // nodes the condition picks between their next nodes
+  conditional?: boolean;
   timer?: { duration_days: number; start_on: string };
   requirements?: {
     fields?: FieldDef[];
@@ -143,8 +146,8 @@ export type NodeVM = NodeDef & { worldId: string; state: NodeState };
 
 /**
  * computeNodeStates — applies prerequisite-based unlocking logic.
- * Only unlocks nodes whose prerequisites are all "done" AND that have no runtime conditions,
- * OR nodes with conditions that are satisfied by the provided activeConditions set.
+ * Only unlocks nodes whose prerequisites are all "done" AND that are not conditional stages,
+ * OR conditional stages whose condition is satisfied by the provided activeConditions set.
  */
 export function computeNodeStates(
   pb: Playbook,
@@ -184,8 +187,8 @@ export function computeNodeStates(
 
         // Only process locked nodes
         if (currentState === "locked") {
-          // Check if node has a condition
-          if (node.condition) {
+          // Check if node is a conditional stage
+          if (node.conditional && node.condition) {
             // Only activate if condition is satisfied AND prerequisites are met
             if (
               activeConditions.has(node.condition) &&
@@ -90,6 +90,7 @@ export function ChatPage() {
           attachments: msg.attachments?.map(att => ({
               name: att.name,
               url: att.url,
+              versionId: att.version_id,
               type: att.type,
               size: att.size + ' B'
           }))
@@ -8,7 +8,7 @@ import { Button } from "../components/ui/button";
 import { Card } from "../components/ui/card";
 import { Label } from "../components/ui/label";
 import { useTranslation } from "react-i18next";
-import { useAuth } from "@/contexts/AuthContext";
+import { useAuth, type LoginResult } from "@/contexts/AuthContext";
 import { useLocation, useNavigate } from "react-router-dom";
 import { Eye, EyeOff } from "lucide-react";
 
@@ -20,7 +20,7 @@ type Form = z.infer<typeof Schema>;
 
 export function LoginPage() {
   const { t: T } = useTranslation("common");
-  const { login } = useAuth();
+  const { login, verifyMfa } = useAuth();
   const navigate = useNavigate();
   const location = useLocation();
   const [showPassword, setShowPassword] = React.useState(false);
@@ -30,29 +30,164 @@ export function LoginPage() {
     setError,
     formState: { errors, isSubmitting },
   } = useForm<Form>({ resolver: zodResolver(Schema) });
+  // Second step, when the account has two-factor authentication
+  const [mfa, setMfa] = React.useState<LoginResult["mfa"]>();
+  const [enrollment, setEnrollment] = React.useState<{ secret: string; otpauth_url: string }>();
+  const [code, setCode] = React.useState("");
+  const [codeError, setCodeError] = React.useState("");
+  const [verifying, setVerifying] = React.useState(false);
+  const [recovery, setRecovery] = React.useState<LoginResult>();
+
+  // An SSO login that needs a code comes back here with its challenge
+  const [ssoRedirect, setSsoRedirect] = React.useState<string>();
+  const ssoChallenge = React.useRef(false);
+
+  const startMfa = React.useCallback(async (challenge: NonNullable<LoginResult["mfa"]>) => {
+    if (challenge.enrollment_required) {
+      setEnrollment(
+        await api("/auth/2fa/enroll", {
+          method: "POST",
+          body: JSON.stringify({ mfa_token: challenge.token }),
+        })
+      );
+    }
+    setMfa(challenge);
+  }, []);
+
+  React.useEffect(() => {
+    const params = new URLSearchParams(location.search);
+    const token = params.get("mfa_token");
+    if (!token || ssoChallenge.current) return;
+    ssoChallenge.current = true;
+    setSsoRedirect(params.get("redirect") || "/");
+    // Keep the challenge out of the history
+    navigate(location.pathname, { replace: true, state: location.state });
+    startMfa({ token, enrollment_required: params.get("enrollment_required") === "1" }).catch((e) =>
+      setError("password", { type: "manual", message: errorMessage(e) })
+    );
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  const finish = (res: LoginResult) => {
+    if (res.is_superadmin) {
+      navigate("/superadmin", { replace: true });
+      return;
+    }
+    const from = ssoRedirect || (location.state as any)?.from || "/";
+    navigate(from, { replace: true });
+  };
+  const errorMessage = (e: any) => {
+    let message = e?.message || T("auth.failed");
+    try {
+      // Try to parse JSON error message from backend
+      const parsed = JSON.parse(message);
+      if (parsed.error) message = parsed.error;
+    } catch (err) {
+      // Not JSON, use original string
+    }
+    return message;
+  };
   const onSubmit = async (data: Form) => {
     try {
       // Use AuthContext for login to refresh user state
       const res = await login({ username: data.username, password: data.password });
-      if (res.is_superadmin) {
-        navigate("/superadmin", { replace: true });
+      if (res.mfa) {
+        await startMfa(res.mfa);
         return;
       }
-      const from = (location.state as any)?.from || "/";
-      navigate(from, { replace: true });
+      finish(res);
     } catch (e: any) {
       console.error("Login failed", e);
-      let message = e?.message || T("auth.failed");
-      try {
-        // Try to parse JSON error message from backend
-        const parsed = JSON.parse(message);
-        if (parsed.error) message = parsed.error;
-      } catch (err) {
-        // Not JSON, use original string
+      setError("password", { type: "manual", message: errorMessage(e) });
+    }
+  };
+  const onVerify = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!mfa) return;
+    setVerifying(true);
+    setCodeError("");
+    try {
+      const res = await verifyMfa(mfa.token, code.trim());
+      if (res.recovery_codes?.length) {
+        setRecovery(res);
+        return;
       }
-      setError("password", { type: "manual", message });
+      finish(res);
+    } catch (e: any) {
+      setCodeError(errorMessage(e));
+    } finally {
+      setVerifying(false);
     }
   };
+
+  if (recovery) {
+    return (
+      <div className="min-h-screen flex items-center justify-center p-4">
+        <Card className="w-full max-w-sm p-6 shadow-md space-y-4">
+          <h2 className="text-xl font-semibold">
+            {T("auth.mfa.recovery_title", { defaultValue: "Save your recovery codes" })}
+          </h2>
+          <p className="text-sm text-muted-foreground">
+            {T("auth.mfa.recovery_hint", {
+              defaultValue: "Each code signs you in once if you lose your authenticator. They are shown only now.",
+            })}
+          </p>
+          <pre className="grid grid-cols-2 gap-1 rounded bg-muted p-3 text-sm font-mono">
+            {recovery.recovery_codes!.map((c) => (
+              <span key={c}>{c}</span>
+            ))}
+          </pre>
+          <Button className="w-full h-11" onClick={() => finish(recovery)}>
+            {T("auth.mfa.continue", { defaultValue: "Continue" })}
+          </Button>
+        </Card>
+      </div>
+    );
+  }
+
+  if (mfa) {
+    return (
+      <div className="min-h-screen flex items-center justify-center p-4">
+        <Card className="w-full max-w-sm p-6 shadow-md">
+          <h2 className="text-xl font-semibold mb-4">
+            {T("auth.mfa.title", { defaultValue: "Two-factor authentication" })}
+          </h2>
+          {enrollment && (
+            <div className="mb-4 space-y-2 text-sm">
+              <p className="text-muted-foreground">
+                {T("auth.mfa.enroll_hint", {
+                  defaultValue: "Your portal requires two-factor authentication. Add this key to your authenticator app, then enter the code it shows.",
+                })}
+              </p>
+              <a href={enrollment.otpauth_url} className="block break-all rounded bg-muted p-2 font-mono">
+                {enrollment.secret}
+              </a>
+            </div>
+          )}
+          <form className="space-y-4" onSubmit={onVerify}>
+            <div className="grid gap-1">
+              <Label htmlFor="code">
+                {T("auth.mfa.code", { defaultValue: "Authentication or recovery code" })}
+              </Label>
+              <Input
+                id="code"
+                autoComplete="one-time-code"
+                inputMode="text"
+                autoFocus
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                aria-invalid={!!codeError}
+              />
+              {codeError && <div className="text-xs text-rose-600">{codeError}</div>}
+            </div>
+            <Button type="submit" className="w-full h-11" disabled={verifying || !code.trim()} aria-busy={verifying}>
+              {T("auth.mfa.verify", { defaultValue: "Verify" })}
+            </Button>
+          </form>
+        </Card>
+      </div>
+    );
+  }
+
   return (
     <div className="min-h-screen flex items-center justify-center p-4">
       <Card className="w-full max-w-sm p-6 shadow-md">